@@ -52,7 +52,48 @@ func main() {
 			logger.Info("cannot get version")
 			os.Exit(1)
 		}
-		fmt.Println(ver.Build + " " + ver.Golang)
+		fmt.Println(ver.String())
+		os.Exit(0)
+	}
+
+	if params.DoDebug {
+		if err := command.Debug(ctx, params, logger); err != nil {
+			logger.Error(err, "debug failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if params.DoDrain {
+		if err := command.Drain(ctx, params, logger); err != nil {
+			logger.Error(err, "drain failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if params.DoUndrain {
+		if err := command.Undrain(ctx, params, logger); err != nil {
+			logger.Error(err, "undrain failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if params.DoActuator {
+		params.DumpFlags(logger)
+		if err := command.RunActuator(ctx, params, logger); err != nil {
+			logger.Error(err, "actuator failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if params.DoSelftest {
+		if err := command.Selftest(params, logger); err != nil {
+			logger.Error(err, "selftest failed")
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -80,6 +121,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if params.DoRenderSlices {
+		if err := command.RenderSlices(params, logger); err != nil {
+			logger.Error(err, "slices rendering failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	params.DumpFlags(logger)
 	if err := command.RunDaemon(ctx, params, logger); err != nil {
 		logger.Error(err, "daemon failed")
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+)
+
+// The actuator is the privileged helper of the driver. The driver talking to the API server and to the runtime
+// can run unprivileged, and delegate the few writes which need privileges: the limits of the pod cgroups, the
+// size of the hugepages pools and the hugetlbfs instances of the claims. The helper serves them on a local unix
+// socket, and accepts only the writes the driver does, so a compromised driver can't use the helper to write
+// anything else: the cgroups outside the pods, or the mounts outside the hugetlbfs base directory.
+
+const (
+	CgroupValuePath      = "/cgroup/value"
+	PoolPagesPath        = "/hugepages/pages"
+	HugeTLBFSMountPath   = "/hugetlbfs/mount"
+	HugeTLBFSFilePath    = "/hugetlbfs/file"
+	HugeTLBFSUnmountPath = "/hugetlbfs/unmount"
+	HealthzPath          = "/healthz"
+)
+
+// CgroupValue is the write of a value on a cgroup file. -1 is `max`.
+type CgroupValue struct {
+	// Dir is the absolute path of the cgroup, below the cgroup mount point
+	Dir   string `json:"dir"`
+	File  string `json:"file"`
+	Value int64  `json:"value"`
+}
+
+// PoolPages is the resize of the pool of the hugepages of size PageSize, in bytes, on the NUMA zone.
+type PoolPages struct {
+	NUMAZone int64  `json:"numaZone"`
+	PageSize uint64 `json:"pageSize"`
+	Pages    int64  `json:"pages"`
+}
+
+// HugeTLBFSMount is the mount of the hugetlbfs instance of the claim for the resource, like `hugepages-2Mi`,
+// reserving Reserved bytes of hugepages in the pool, if any. The helper mounts it below its hugetlbfs base directory.
+type HugeTLBFSMount struct {
+	ClaimUID     string `json:"claimUID"`
+	ResourceName string `json:"resourceName"`
+	PageSize     uint64 `json:"pageSize"`
+	Size         int64  `json:"size"`
+	Reserved     int64  `json:"reserved,omitempty"`
+}
+
+// HugeTLBFSFile is the creation of the file of Size bytes on the hugetlbfs instance of the claim.
+type HugeTLBFSFile struct {
+	ClaimUID     string `json:"claimUID"`
+	ResourceName string `json:"resourceName"`
+	FileName     string `json:"fileName"`
+	Size         int64  `json:"size"`
+}
+
+// HugeTLBFSUnmount is the unmount of all the hugetlbfs instances of the claim.
+type HugeTLBFSUnmount struct {
+	ClaimUID string `json:"claimUID"`
+}
+
+// Failure is the answer to a failed write.
+type Failure struct {
+	Message string `json:"message"`
+	// Class is the class of the cgroup error, if any, like ErrLimitFileMissing. Empty if unrecognized.
+	Class string `json:"class,omitempty"`
+	// Path is the path of the cgroup file, if the write reached it.
+	Path string `json:"path,omitempty"`
+}
+
+var (
+	ErrNotAllowed = errors.New("write not allowed")
+
+	// allowedCgroupFiles are the files the driver writes: the hugetlb limits, the reservation limits and the swap limits.
+	allowedCgroupFiles = regexp.MustCompile(`^(hugetlb\.[0-9]+[KMG]B(\.rsvd)?\.max|memory\.swap\.max)$`)
+	// kubepodsCgroup is the cgroup of all the pods, like `kubepods.slice`, `kubepods`, or `kubelet-kubepods.slice`
+	// with a kubelet cgroup root. The driver writes only on it, on the QoS classes, the pods and the containers below it.
+	kubepodsCgroup = regexp.MustCompile(`^(.+-)?kubepods(\.slice)?$`)
+	// claimUIDs are the UIDs of the API objects
+	claimUIDs = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	// hugepagesResourceNames are the names of the hugepages resources, like `hugepages-2Mi`
+	hugepagesResourceNames = regexp.MustCompile(`^hugepages-[0-9]+[KMGT]i$`)
+
+	// classes are the cgroup error classes, preserved across the socket so the driver can tell retriable failures
+	classes = map[string]error{
+		"missing":       cgroups.ErrLimitFileMissing,
+		"rejected":      cgroups.ErrValueRejected,
+		"not-delegated": cgroups.ErrCgroupNotDelegated,
+	}
+)
+
+// Check returns an error if the helper must not do the write, for the cgroup mount point `cgroupMount`.
+func (cv CgroupValue) Check(cgroupMount string) error {
+	if !allowedCgroupFiles.MatchString(cv.File) {
+		return fmt.Errorf("%w: file %q", ErrNotAllowed, cv.File)
+	}
+	if !filepath.IsAbs(cv.Dir) || filepath.Clean(cv.Dir) != cv.Dir {
+		return fmt.Errorf("%w: path %q not absolute and clean", ErrNotAllowed, cv.Dir)
+	}
+	rel, err := filepath.Rel(cgroupMount, cv.Dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		// the cgroup root holds no pods, the driver never writes there
+		return fmt.Errorf("%w: path %q not below %q", ErrNotAllowed, cv.Dir, cgroupMount)
+	}
+	if !isBelowKubepods(rel) {
+		return fmt.Errorf("%w: path %q not below the pods cgroup", ErrNotAllowed, cv.Dir)
+	}
+	if cv.Value < -1 {
+		return fmt.Errorf("%w: value %d", ErrNotAllowed, cv.Value)
+	}
+	return nil
+}
+
+// isBelowKubepods returns true if the cgroup path `rel`, relative to the mount point, is the cgroup of the pods or below it.
+// The limits watchdog repairs the ancestors of the pods up to the cgroup of the pods included.
+func isBelowKubepods(rel string) bool {
+	elems := strings.Split(rel, "/")
+	for idx := range elems {
+		if kubepodsCgroup.MatchString(elems[idx]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if the helper must not do the write.
+func (pp PoolPages) Check() error {
+	if pp.NUMAZone < 0 {
+		return fmt.Errorf("%w: NUMA zone %d", ErrNotAllowed, pp.NUMAZone)
+	}
+	// the page sizes are powers of two, at least 4KiB
+	if pp.PageSize < 4096 || pp.PageSize&(pp.PageSize-1) != 0 {
+		return fmt.Errorf("%w: page size %d", ErrNotAllowed, pp.PageSize)
+	}
+	if pp.Pages < 0 {
+		return fmt.Errorf("%w: pages %d", ErrNotAllowed, pp.Pages)
+	}
+	return nil
+}
+
+// Check returns an error if the helper must not do the mount.
+func (hm HugeTLBFSMount) Check() error {
+	err := checkClaimResource(hm.ClaimUID, hm.ResourceName)
+	if err != nil {
+		return err
+	}
+	if hm.PageSize < 4096 || hm.PageSize&(hm.PageSize-1) != 0 {
+		return fmt.Errorf("%w: page size %d", ErrNotAllowed, hm.PageSize)
+	}
+	if hm.Size <= 0 {
+		return fmt.Errorf("%w: size %d", ErrNotAllowed, hm.Size)
+	}
+	// the instance can't hold more pages than its size
+	if hm.Reserved < 0 || hm.Reserved > hm.Size {
+		return fmt.Errorf("%w: reserved %d", ErrNotAllowed, hm.Reserved)
+	}
+	return nil
+}
+
+// Check returns an error if the helper must not create the file.
+func (hf HugeTLBFSFile) Check() error {
+	err := checkClaimResource(hf.ClaimUID, hf.ResourceName)
+	if err != nil {
+		return err
+	}
+	if hf.FileName == "" || hf.FileName == "." || hf.FileName == ".." || filepath.Base(hf.FileName) != hf.FileName {
+		return fmt.Errorf("%w: file name %q", ErrNotAllowed, hf.FileName)
+	}
+	if hf.Size <= 0 {
+		return fmt.Errorf("%w: size %d", ErrNotAllowed, hf.Size)
+	}
+	return nil
+}
+
+// Check returns an error if the helper must not do the unmount.
+func (hu HugeTLBFSUnmount) Check() error {
+	if !claimUIDs.MatchString(hu.ClaimUID) {
+		return fmt.Errorf("%w: claim UID %q", ErrNotAllowed, hu.ClaimUID)
+	}
+	return nil
+}
+
+// checkClaimResource checks the claim UID and the resource name, which make the path of the hugetlbfs instance.
+func checkClaimResource(claimUID, resourceName string) error {
+	if !claimUIDs.MatchString(claimUID) {
+		return fmt.Errorf("%w: claim UID %q", ErrNotAllowed, claimUID)
+	}
+	if !hugepagesResourceNames.MatchString(resourceName) {
+		return fmt.Errorf("%w: resource %q", ErrNotAllowed, resourceName)
+	}
+	return nil
+}
+
+func classOf(err error) string {
+	for name, class := range classes {
+		if errors.Is(err, class) {
+			return name
+		}
+	}
+	return ""
+}
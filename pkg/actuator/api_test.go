@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupValueCheck(t *testing.T) {
+	const mnt = "/sys/fs/cgroup"
+	testcases := []struct {
+		name    string
+		req     CgroupValue
+		allowed bool
+	}{
+		{
+			name:    "hugetlb limit",
+			req:     CgroupValue{Dir: mnt + "/kubepods.slice/kubepods-pod1.slice", File: "hugetlb.2MB.max", Value: 4194304},
+			allowed: true,
+		},
+		{
+			name:    "hugetlb reservation limit to max",
+			req:     CgroupValue{Dir: mnt + "/kubepods.slice/kubepods-pod1.slice", File: "hugetlb.1GB.rsvd.max", Value: -1},
+			allowed: true,
+		},
+		{
+			name:    "swap limit",
+			req:     CgroupValue{Dir: mnt + "/kubepods.slice/kubepods-pod1.slice", File: "memory.swap.max", Value: 0},
+			allowed: true,
+		},
+		{
+			name:    "cgroupfs pod",
+			req:     CgroupValue{Dir: mnt + "/kubepods/burstable/pod1", File: "hugetlb.2MB.max", Value: 4194304},
+			allowed: true,
+		},
+		{
+			name:    "kubelet cgroup root",
+			req:     CgroupValue{Dir: mnt + "/kubelet.slice/kubelet-kubepods.slice/kubelet-kubepods-pod1.slice", File: "hugetlb.2MB.max", Value: 4194304},
+			allowed: true,
+		},
+		{
+			name:    "pods cgroup",
+			req:     CgroupValue{Dir: mnt + "/kubepods.slice", File: "hugetlb.2MB.max", Value: 1},
+			allowed: true,
+		},
+		{
+			name:    "pods cgroup with a kubelet cgroup root",
+			req:     CgroupValue{Dir: mnt + "/kubelet.slice/kubelet-kubepods.slice", File: "hugetlb.2MB.max", Value: 1},
+			allowed: true,
+		},
+		{
+			name: "above the pods cgroup",
+			req:  CgroupValue{Dir: mnt + "/kubelet.slice", File: "hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "outside the pods",
+			req:  CgroupValue{Dir: mnt + "/system.slice/sshd.service", File: "memory.swap.max", Value: 0},
+		},
+		{
+			name: "other file",
+			req:  CgroupValue{Dir: mnt + "/kubepods.slice", File: "cgroup.procs", Value: 1},
+		},
+		{
+			name: "file escaping the dir",
+			req:  CgroupValue{Dir: mnt + "/kubepods.slice", File: "../hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "cgroup root",
+			req:  CgroupValue{Dir: mnt, File: "hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "outside the mount",
+			req:  CgroupValue{Dir: "/proc/sys/vm", File: "hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "escaping the mount",
+			req:  CgroupValue{Dir: mnt + "/../../etc", File: "hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "relative",
+			req:  CgroupValue{Dir: "kubepods.slice", File: "hugetlb.2MB.max", Value: 1},
+		},
+		{
+			name: "negative value",
+			req:  CgroupValue{Dir: mnt + "/kubepods.slice", File: "hugetlb.2MB.max", Value: -2},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := tcase.req.Check(mnt)
+			if tcase.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrNotAllowed)
+		})
+	}
+}
+
+func TestPoolPagesCheck(t *testing.T) {
+	require.NoError(t, PoolPages{NUMAZone: 1, PageSize: 2 << 20, Pages: 512}.Check())
+	require.NoError(t, PoolPages{NUMAZone: 0, PageSize: 1 << 30, Pages: 0}.Check())
+	require.ErrorIs(t, PoolPages{NUMAZone: -1, PageSize: 2 << 20, Pages: 1}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, PoolPages{NUMAZone: 0, PageSize: 3 << 20, Pages: 1}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, PoolPages{NUMAZone: 0, PageSize: 2 << 20, Pages: -1}.Check(), ErrNotAllowed)
+}
+
+func TestHugeTLBFSCheck(t *testing.T) {
+	require.NoError(t, HugeTLBFSMount{ClaimUID: "0b1c-2d3e", ResourceName: "hugepages-2Mi", PageSize: 2 << 20, Size: 4 << 20}.Check())
+	require.ErrorIs(t, HugeTLBFSMount{ClaimUID: "../etc", ResourceName: "hugepages-2Mi", PageSize: 2 << 20, Size: 4 << 20}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, HugeTLBFSMount{ClaimUID: "0b1c", ResourceName: "memory", PageSize: 2 << 20, Size: 4 << 20}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, HugeTLBFSMount{ClaimUID: "0b1c", ResourceName: "hugepages-2Mi", PageSize: 3 << 20, Size: 4 << 20}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, HugeTLBFSMount{ClaimUID: "0b1c", ResourceName: "hugepages-2Mi", PageSize: 2 << 20}.Check(), ErrNotAllowed)
+	require.NoError(t, HugeTLBFSMount{ClaimUID: "0b1c", ResourceName: "hugepages-2Mi", PageSize: 2 << 20, Size: 4 << 20, Reserved: 4 << 20}.Check())
+	require.ErrorIs(t, HugeTLBFSMount{ClaimUID: "0b1c", ResourceName: "hugepages-2Mi", PageSize: 2 << 20, Size: 4 << 20, Reserved: 8 << 20}.Check(), ErrNotAllowed)
+
+	require.NoError(t, HugeTLBFSFile{ClaimUID: "0b1c", ResourceName: "hugepages-1Gi", FileName: "pages", Size: 1 << 30}.Check())
+	require.ErrorIs(t, HugeTLBFSFile{ClaimUID: "0b1c", ResourceName: "hugepages-1Gi", FileName: "../pages", Size: 1 << 30}.Check(), ErrNotAllowed)
+	require.ErrorIs(t, HugeTLBFSFile{ClaimUID: "0b1c", ResourceName: "hugepages-1Gi", FileName: "..", Size: 1 << 30}.Check(), ErrNotAllowed)
+
+	require.NoError(t, HugeTLBFSUnmount{ClaimUID: "0b1c"}.Check())
+	require.ErrorIs(t, HugeTLBFSUnmount{ClaimUID: "0b1c/.."}.Check(), ErrNotAllowed)
+}
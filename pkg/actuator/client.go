@@ -0,0 +1,167 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+)
+
+// requestTimeout bounds the requests of the client; the writes are called from the NRI hooks, which must not block
+const requestTimeout = 5 * time.Second
+
+// Client delegates the privileged writes to the helper listening on a unix socket.
+// It implements cgroups.Writer, reserve.PoolWriter and hugetlbfs.Mounter.
+type Client struct {
+	socketPath string
+	httpClient *http.Client
+}
+
+func NewClient(socketPath string) *Client {
+	return &Client{
+		socketPath: socketPath,
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// WriteValue asks the helper to write the value on the cgroup file. The errors are *cgroups.Error
+// of the same class the helper found, so the callers can tell the permanent failures.
+func (cli *Client) WriteValue(lh logr.Logger, dir, file string, val int64) error {
+	lh.V(4).Info("delegating cgroup write", "dir", dir, "file", file, "value", val)
+	err := cli.post(context.Background(), CgroupValuePath, CgroupValue{Dir: dir, File: file, Value: val})
+	var cgErr *cgroups.Error
+	if errors.As(err, &cgErr) && cgErr.Path == "" {
+		cgErr.Path = filepath.Join(dir, file) // refused before reaching the file
+	}
+	return err
+}
+
+// SetPoolPages asks the helper to resize the hugepages pool.
+func (cli *Client) SetPoolPages(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) error {
+	lh.V(4).Info("delegating hugepages pool write", "numaZone", numaZone, "pageSize", pagesize, "pages", pages)
+	return cli.post(context.Background(), PoolPagesPath, PoolPages{NUMAZone: numaZone, PageSize: pagesize, Pages: pages})
+}
+
+// Mount asks the helper to mount the hugetlbfs instance of the claim for the resource.
+func (cli *Client) Mount(lh logr.Logger, claimUID k8stypes.UID, resourceName string, pagesize uint64, sizeInBytes, reservedBytes int64) error {
+	lh.V(4).Info("delegating hugetlbfs mount", "claimUID", claimUID, "resource", resourceName, "pageSize", pagesize, "size", sizeInBytes, "reserved", reservedBytes)
+	return cli.post(context.Background(), HugeTLBFSMountPath, HugeTLBFSMount{ClaimUID: string(claimUID), ResourceName: resourceName, PageSize: pagesize, Size: sizeInBytes, Reserved: reservedBytes})
+}
+
+// CreateFile asks the helper to create the file of the hugetlbfs instance of the claim.
+func (cli *Client) CreateFile(lh logr.Logger, claimUID k8stypes.UID, resourceName, fileName string, sizeInBytes int64) error {
+	lh.V(4).Info("delegating hugetlbfs file creation", "claimUID", claimUID, "resource", resourceName, "fileName", fileName, "size", sizeInBytes)
+	return cli.post(context.Background(), HugeTLBFSFilePath, HugeTLBFSFile{ClaimUID: string(claimUID), ResourceName: resourceName, FileName: fileName, Size: sizeInBytes})
+}
+
+// UnmountAll asks the helper to unmount all the hugetlbfs instances of the claim.
+func (cli *Client) UnmountAll(lh logr.Logger, claimUID k8stypes.UID) error {
+	lh.V(4).Info("delegating hugetlbfs unmount", "claimUID", claimUID)
+	return cli.post(context.Background(), HugeTLBFSUnmountPath, HugeTLBFSUnmount{ClaimUID: string(claimUID)})
+}
+
+// Ping checks the helper is serving.
+func (cli *Client) Ping(ctx context.Context) error {
+	// the host is ignored, we always dial the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+HealthzPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting to the actuator on %q: %w", cli.socketPath, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response %q", resp.Status)
+	}
+	return nil
+}
+
+func (cli *Client) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cli.httpClient.Do(req)
+	if err != nil {
+		// not classified, so retried like the transient cgroup failures
+		return fmt.Errorf("connecting to the actuator on %q: %w", cli.socketPath, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var fail Failure
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxRequestBytes)).Decode(&fail)
+	if err != nil {
+		return fmt.Errorf("unexpected response %q", resp.Status)
+	}
+	return failureError(path, resp.StatusCode, fail)
+}
+
+func failureError(path string, status int, fail Failure) error {
+	err := errors.New(fail.Message)
+	if status == http.StatusForbidden {
+		err = fmt.Errorf("%w: %s", ErrNotAllowed, fail.Message)
+	}
+	if path != CgroupValuePath {
+		return err
+	}
+	class := classes[fail.Class]
+	if status == http.StatusForbidden {
+		// writing again won't help
+		class = cgroups.ErrValueRejected
+	}
+	return &cgroups.Error{
+		Op:    "write",
+		Path:  fail.Path,
+		Class: class,
+		Err:   err,
+	}
+}
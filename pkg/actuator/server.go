@@ -0,0 +1,246 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/hugetlbfs"
+)
+
+// maxRequestBytes bounds the requests, which are a handful of fields
+const maxRequestBytes = 4096
+
+// Server serves the privileged writes on a unix socket.
+type Server struct {
+	socketPath  string
+	socketGID   int
+	cgroupMount string
+	cgw         cgroups.Writer
+	pw          reserve.PoolWriter
+	hm          hugetlbfs.Mounter
+}
+
+// NewServer creates a server writing the cgroups below `cgroupMount`, the hugepages pools on the sysfs tree at `sysRoot`
+// and the hugetlbfs instances below hugetlbfs.BaseDir. The socket is accessible to root, and to the group `socketGID`
+// if not negative, for the driver running as another user.
+func NewServer(socketPath string, socketGID int, cgroupMount, sysRoot string) *Server {
+	return &Server{
+		socketPath:  socketPath,
+		socketGID:   socketGID,
+		cgroupMount: cgroupMount,
+		cgw:         cgroups.LocalWriter{},
+		pw:          reserve.LocalPoolWriter{SysRoot: sysRoot},
+		hm:          hugetlbfs.LocalMounter{},
+	}
+}
+
+// Run serves the requests until the context is done.
+func (srv *Server) Run(ctx context.Context, lh logr.Logger) error {
+	// a socket left behind by a previous, killed instance makes listen fail
+	err := os.Remove(srv.socketPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %q: %w", srv.socketPath, err)
+	}
+	listener, err := srv.listen()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("POST "+CgroupValuePath, func(w http.ResponseWriter, r *http.Request) {
+		var req CgroupValue
+		if !decodeRequest(lh, w, r, &req) {
+			return
+		}
+		err := req.Check(srv.cgroupMount)
+		if err != nil {
+			lh.Info("refused cgroup write", "dir", req.Dir, "file", req.File, "value", req.Value, "reason", err.Error())
+			writeFailure(lh, w, http.StatusForbidden, err)
+			return
+		}
+		lh.V(2).Info("cgroup write", "dir", req.Dir, "file", req.File, "value", req.Value)
+		err = srv.cgw.WriteValue(lh, req.Dir, req.File, req.Value)
+		if err != nil {
+			writeFailure(lh, w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+PoolPagesPath, func(w http.ResponseWriter, r *http.Request) {
+		var req PoolPages
+		if !decodeRequest(lh, w, r, &req) {
+			return
+		}
+		err := req.Check()
+		if err != nil {
+			lh.Info("refused hugepages pool write", "numaZone", req.NUMAZone, "pageSize", req.PageSize, "pages", req.Pages, "reason", err.Error())
+			writeFailure(lh, w, http.StatusForbidden, err)
+			return
+		}
+		lh.V(2).Info("hugepages pool write", "numaZone", req.NUMAZone, "pageSize", req.PageSize, "pages", req.Pages)
+		err = srv.pw.SetPoolPages(lh, req.NUMAZone, req.PageSize, req.Pages)
+		if err != nil {
+			writeFailure(lh, w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+HugeTLBFSMountPath, func(w http.ResponseWriter, r *http.Request) {
+		var req HugeTLBFSMount
+		if !decodeRequest(lh, w, r, &req) {
+			return
+		}
+		err := req.Check()
+		if err != nil {
+			lh.Info("refused hugetlbfs mount", "claimUID", req.ClaimUID, "resource", req.ResourceName, "reason", err.Error())
+			writeFailure(lh, w, http.StatusForbidden, err)
+			return
+		}
+		err = srv.hm.Mount(lh, k8stypes.UID(req.ClaimUID), req.ResourceName, req.PageSize, req.Size, req.Reserved)
+		if err != nil {
+			writeFailure(lh, w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+HugeTLBFSFilePath, func(w http.ResponseWriter, r *http.Request) {
+		var req HugeTLBFSFile
+		if !decodeRequest(lh, w, r, &req) {
+			return
+		}
+		err := req.Check()
+		if err != nil {
+			lh.Info("refused hugetlbfs file creation", "claimUID", req.ClaimUID, "resource", req.ResourceName, "fileName", req.FileName, "reason", err.Error())
+			writeFailure(lh, w, http.StatusForbidden, err)
+			return
+		}
+		err = srv.hm.CreateFile(lh, k8stypes.UID(req.ClaimUID), req.ResourceName, req.FileName, req.Size)
+		if err != nil {
+			writeFailure(lh, w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST "+HugeTLBFSUnmountPath, func(w http.ResponseWriter, r *http.Request) {
+		var req HugeTLBFSUnmount
+		if !decodeRequest(lh, w, r, &req) {
+			return
+		}
+		err := req.Check()
+		if err != nil {
+			lh.Info("refused hugetlbfs unmount", "claimUID", req.ClaimUID, "reason", err.Error())
+			writeFailure(lh, w, http.StatusForbidden, err)
+			return
+		}
+		err = srv.hm.UnmountAll(lh, k8stypes.UID(req.ClaimUID))
+		if err != nil {
+			writeFailure(lh, w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	lh.Info("serving actuator API", "socketPath", srv.socketPath, "cgroupMount", srv.cgroupMount)
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listen creates the socket accessible to root only, then opens it to the group of the driver, if any.
+// The writes change the limits of all the pods, so no other user may connect, not even briefly.
+func (srv *Server) listen() (net.Listener, error) {
+	// the umask is of the process, but the helper serves nothing else yet
+	oldMask := unix.Umask(0177)
+	listener, err := net.Listen("unix", srv.socketPath)
+	unix.Umask(oldMask)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %q: %w", srv.socketPath, err)
+	}
+	if srv.socketGID < 0 {
+		return listener, nil
+	}
+	err = os.Chown(srv.socketPath, -1, srv.socketGID)
+	if err == nil {
+		err = os.Chmod(srv.socketPath, 0660)
+	}
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("opening socket %q to group %d: %w", srv.socketPath, srv.socketGID, err)
+	}
+	return listener, nil
+}
+
+func decodeRequest(lh logr.Logger, w http.ResponseWriter, r *http.Request, req any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(req)
+	if err != nil {
+		writeFailure(lh, w, http.StatusBadRequest, fmt.Errorf("malformed request: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeFailure(lh logr.Logger, w http.ResponseWriter, status int, err error) {
+	fail := Failure{
+		Message: err.Error(),
+		Class:   classOf(err),
+	}
+	var cgErr *cgroups.Error
+	if errors.As(err, &cgErr) {
+		// the client rebuilds the cgroup error, don't repeat its context
+		fail.Message = cgErr.Err.Error()
+		fail.Path = cgErr.Path
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(fail)
+	if err != nil {
+		lh.Error(err, "encoding failure")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/hugetlbfs"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+func TestServerRoundtrip(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "act")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "actuator.sock")
+
+	cgroupMount := t.TempDir()
+	podPath := filepath.Join(cgroupMount, "kubepods", "pod-a")
+	require.NoError(t, os.MkdirAll(podPath, 0o755))
+	sysRoot := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 8, Free: 8}}},
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(socketPath, -1, cgroupMount, sysRoot)
+	hm := &fakeMounter{}
+	srv.hm = hm
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx, testr.New(t))
+	}()
+
+	lh := testr.New(t)
+	cli := NewClient(socketPath)
+	require.Eventually(t, func() bool {
+		err = cli.Ping(ctx)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "actuator not serving: %v", err)
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the client is a drop-in replacement of the local writers
+	var cgw cgroups.Writer = cli
+	require.NoError(t, cgw.WriteValue(lh, podPath, "hugetlb.2MB.max", 4194304))
+	require.Equal(t, "4194304", readValue(t, filepath.Join(podPath, "hugetlb.2MB.max")))
+	require.NoError(t, cgw.WriteValue(lh, podPath, "hugetlb.2MB.rsvd.max", -1))
+	require.Equal(t, "max", readValue(t, filepath.Join(podPath, "hugetlb.2MB.rsvd.max")))
+
+	err = cgw.WriteValue(lh, podPath, "cgroup.procs", 1)
+	require.ErrorIs(t, err, ErrNotAllowed)
+	require.True(t, cgroups.IsPermanent(err), "refused writes must not be retried")
+	require.NoFileExists(t, filepath.Join(podPath, "cgroup.procs"))
+
+	err = cgw.WriteValue(lh, filepath.Join(cgroupMount, "kubepods", "pod-gone"), "hugetlb.2MB.max", 0)
+	require.ErrorIs(t, err, cgroups.ErrLimitFileMissing, "the class of the failure must be preserved")
+
+	var pw reserve.PoolWriter = cli
+	require.NoError(t, pw.SetPoolPages(lh, 0, 2<<20, 16))
+	require.Equal(t, "16", readValue(t, filepath.Join(sysRoot, "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-2048kB", "nr_hugepages")))
+	require.ErrorIs(t, cli.SetPoolPages(lh, 0, 2<<20, -1), ErrNotAllowed)
+
+	var mnt hugetlbfs.Mounter = cli
+	require.NoError(t, mnt.Mount(lh, "claim-UID", "hugepages-2Mi", 2<<20, 4<<20, 4<<20))
+	require.NoError(t, mnt.CreateFile(lh, "claim-UID", "hugepages-2Mi", "pages", 4<<20))
+	require.NoError(t, mnt.UnmountAll(lh, "claim-UID"))
+	require.Equal(t, []string{"mount claim-UID/hugepages-2Mi", "create claim-UID/hugepages-2Mi/pages", "unmount claim-UID"}, hm.calls)
+	require.ErrorIs(t, mnt.Mount(lh, "../../etc", "hugepages-2Mi", 2<<20, 4<<20, 0), ErrNotAllowed)
+	require.ErrorIs(t, mnt.CreateFile(lh, "claim-UID", "hugepages-2Mi", "../pages", 4<<20), ErrNotAllowed)
+	require.ErrorIs(t, mnt.UnmountAll(lh, ""), ErrNotAllowed)
+	require.Len(t, hm.calls, 3, "refused requests must not reach the mounter")
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestServerSocketGroup(t *testing.T) {
+	socketDir, err := os.MkdirTemp("", "act")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "actuator.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(socketPath, os.Getgid(), t.TempDir(), t.TempDir())
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx, testr.New(t))
+	}()
+
+	cli := NewClient(socketPath)
+	require.Eventually(t, func() bool {
+		err = cli.Ping(ctx)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "actuator not serving: %v", err)
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0660), info.Mode().Perm())
+	st, ok := info.Sys().(*syscall.Stat_t)
+	require.True(t, ok)
+	require.Equal(t, uint32(os.Getgid()), st.Gid)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestClientNoActuator(t *testing.T) {
+	cli := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+	err := cli.WriteValue(testr.New(t), "/sys/fs/cgroup/kubepods", "hugetlb.2MB.max", 0)
+	require.ErrorContains(t, err, "connecting to the actuator")
+	require.False(t, cgroups.IsPermanent(err), "the actuator may be restarting, worth a retry")
+}
+
+// fakeMounter records the calls, mounting needs privileges
+type fakeMounter struct {
+	calls []string
+}
+
+func (fm *fakeMounter) Mount(_ logr.Logger, claimUID k8stypes.UID, resourceName string, _ uint64, _, _ int64) error {
+	fm.calls = append(fm.calls, "mount "+string(claimUID)+"/"+resourceName)
+	return nil
+}
+
+func (fm *fakeMounter) CreateFile(_ logr.Logger, claimUID k8stypes.UID, resourceName, fileName string, _ int64) error {
+	fm.calls = append(fm.calls, "create "+string(claimUID)+"/"+resourceName+"/"+fileName)
+	return nil
+}
+
+func (fm *fakeMounter) UnmountAll(_ logr.Logger, claimUID k8stypes.UID) error {
+	fm.calls = append(fm.calls, "unmount "+string(claimUID))
+	return nil
+}
+
+func readValue(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(data))
+}
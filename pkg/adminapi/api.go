@@ -0,0 +1,36 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package adminapi
+
+import (
+	"encoding/json"
+)
+
+// The admin API lets the node admins change the node through the running driver, like provisioning the hugepages,
+// so the driver publishes the changes at once. Unlike the debug API, it changes the node: it is served on a local
+// unix socket, and only to the clients running as root, checked on the credentials of the peer.
+
+const (
+	ProvisionPath = "/hugepages/provision"
+)
+
+// ProvisionResponse is the outcome of the provisioning of the hugepages through the daemon.
+type ProvisionResponse struct {
+	// Result is the outcome on each NUMA node, like the setup-hugepages tool reports it. Empty if the provisioning didn't run.
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
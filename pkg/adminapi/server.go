@@ -0,0 +1,231 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package adminapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// provisionTimeout bounds the provisioning requests: the kernel can take long to compact the memory for the hugepages
+	provisionTimeout = 5 * time.Minute
+	// maxProvisionConfigSize bounds the provisioning configurations, which are a handful of lines
+	maxProvisionConfigSize = 1 << 20
+)
+
+// allowedUID is the user the clients must run as.
+// Can be changed by unit tests.
+var allowedUID uint32 = 0
+
+// ProvisionFunc provisions the hugepages with the configuration `config`, and returns the outcome, encoded
+// as JSON in the response. The error is reported along the outcome, which can be partial.
+type ProvisionFunc func(ctx context.Context, config []byte) (any, error)
+
+// Server serves the admin API on a unix socket.
+type Server struct {
+	socketPath string
+	provision  ProvisionFunc
+}
+
+func NewServer(socketPath string, provision ProvisionFunc) *Server {
+	return &Server{
+		socketPath: socketPath,
+		provision:  provision,
+	}
+}
+
+type connKey struct{}
+
+// Run serves the requests until the context is done.
+func (srv *Server) Run(ctx context.Context, lh logr.Logger) error {
+	// a socket left behind by a previous, killed instance makes listen fail
+	err := os.Remove(srv.socketPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %q: %w", srv.socketPath, err)
+	}
+	// the driver may run as another user, so the peer is checked as well
+	listener, err := listen(ctx, srv.socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %q: %w", srv.socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+ProvisionPath, func(w http.ResponseWriter, r *http.Request) {
+		srv.serveProvision(lh, w, r)
+	})
+	server := &http.Server{
+		Handler:           authenticate(lh, mux),
+		ReadHeaderTimeout: 5 * time.Second,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, conn)
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	lh.Info("serving admin API", "socketPath", srv.socketPath)
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authenticate serves only the requests of the peers running as allowedUID.
+func authenticate(lh logr.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cred, err := peerCredentials(r.Context())
+		if err != nil {
+			lh.Error(err, "reading the peer credentials")
+			http.Error(w, "cannot identify the peer", http.StatusForbidden)
+			return
+		}
+		if cred.Uid != allowedUID {
+			lh.Info("refused request", "path", r.URL.Path, "uid", cred.Uid, "pid", cred.Pid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		lh.V(2).Info("admin request", "path", r.URL.Path, "pid", cred.Pid)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func peerCredentials(ctx context.Context) (*unix.Ucred, error) {
+	conn, ok := ctx.Value(connKey{}).(*net.UnixConn)
+	if !ok {
+		return nil, errors.New("not a unix socket connection")
+	}
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	err = rawConn.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cred, credErr
+}
+
+func (srv *Server) serveProvision(lh logr.Logger, w http.ResponseWriter, r *http.Request) {
+	config, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProvisionConfigSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading the configuration: %v", err), http.StatusBadRequest)
+		return
+	}
+	result, err := srv.provision(r.Context(), config)
+	resp := ProvisionResponse{}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	if result != nil {
+		resp.Result, err = json.Marshal(result)
+		if err != nil {
+			lh.Error(err, "encoding provisioning result")
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		lh.Error(err, "encoding provisioning response")
+	}
+}
+
+// Provision asks the daemon listening on `socketPath` to provision the hugepages with the configuration `config`,
+// and decodes the outcome in `result`. The returned error is the one of the provisioning, if it ran.
+func Provision(ctx context.Context, socketPath string, config []byte, result any) error {
+	client := &http.Client{
+		Timeout: provisionTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	// the host is ignored, we always dial the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+ProvisionPath, bytes.NewReader(config))
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting to the daemon on %q: %w", socketPath, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response %q: %s", resp.Status, msg)
+	}
+	var data ProvisionResponse
+	err = json.NewDecoder(resp.Body).Decode(&data)
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	if len(data.Result) > 0 {
+		err = json.Unmarshal(data.Result, result)
+		if err != nil {
+			return fmt.Errorf("malformed result: %w", err)
+		}
+	}
+	if data.Error != "" {
+		return errors.New(data.Error)
+	}
+	return nil
+}
+
+// listen creates the socket accessible to root only. The mode is set on the socket before binding it, so no other
+// user can connect, not even briefly, and the umask of the process, shared by the files the driver writes, is left alone.
+func listen(ctx context.Context, socketPath string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, conn syscall.RawConn) error {
+			var chmodErr error
+			err := conn.Control(func(fd uintptr) {
+				// the bound socket file takes the mode of the socket
+				chmodErr = unix.Fchmod(int(fd), 0600)
+			})
+			if err != nil {
+				return err
+			}
+			return chmodErr
+		},
+	}
+	return lc.Listen(ctx, "unix", socketPath)
+}
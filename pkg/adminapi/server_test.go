@@ -0,0 +1,121 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package adminapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerProvision(t *testing.T) {
+	socketPath := startServer(t, uint32(os.Getuid()), func(_ context.Context, config []byte) (any, error) {
+		res := []nodeResult{{Node: 0, Achieved: 512}, {Node: 1, Achieved: 510}}
+		if bytes.Contains(config, []byte("conflict")) {
+			return res, errors.New("node 1 size 2M: conflict with the pages in use")
+		}
+		return res, nil
+	})
+
+	var got []nodeResult
+	require.NoError(t, Provision(context.Background(), socketPath, []byte("kind: HugePageProvision"), &got))
+	require.Equal(t, []nodeResult{{Node: 0, Achieved: 512}, {Node: 1, Achieved: 510}}, got)
+
+	got = nil
+	err := Provision(context.Background(), socketPath, []byte("conflict"), &got)
+	require.ErrorContains(t, err, "conflict with the pages in use")
+	require.Len(t, got, 2, "the outcome must be reported along the error")
+}
+
+func TestServerProvisionRefusesOtherUsers(t *testing.T) {
+	provisioned := false
+	socketPath := startServer(t, uint32(os.Getuid())+1, func(_ context.Context, _ []byte) (any, error) {
+		provisioned = true
+		return nil, nil
+	})
+
+	var got any
+	err := Provision(context.Background(), socketPath, []byte("kind: HugePageProvision"), &got)
+	require.ErrorContains(t, err, "403")
+	require.False(t, provisioned)
+}
+
+func TestProvisionNoDaemon(t *testing.T) {
+	var got any
+	err := Provision(context.Background(), filepath.Join(t.TempDir(), "missing.sock"), nil, &got)
+	require.ErrorContains(t, err, "connecting to the daemon")
+}
+
+type nodeResult struct {
+	Node     int `json:"node"`
+	Achieved int `json:"achieved"`
+}
+
+// startServer serves the admin API to the clients running as `uid` until the test ends, and returns its socket.
+func startServer(t *testing.T, uid uint32, provision ProvisionFunc) string {
+	t.Helper()
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "adm")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "admin.sock")
+
+	saveAllowedUID := allowedUID
+	t.Cleanup(func() {
+		allowedUID = saveAllowedUID
+	})
+	allowedUID = uid
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(socketPath, provision)
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx, testr.New(t))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(socketPath)
+		return err == nil && info.Mode().Perm() == 0600
+	}, 5*time.Second, 10*time.Millisecond, "the socket must be restricted to its owner")
+	return socketPath
+}
+
+func TestListenRestricted(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "adm")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "test.sock")
+
+	// the socket must be restricted right when it appears, whatever the umask
+	listener, err := listen(context.Background(), socketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The admission policy caps the memory and the hugepages the claims of a namespace, or of the pods
+// of a priority class, can hold on the node. The driver rejects the preparation of the claims beyond
+// the limits. This is a stop-gap until ResourceQuota fully covers the consumable capacity of DRA.
+// The usage is learned from the claims prepared, and checkpointed to survive the restarts of the driver.
+
+const (
+	APIVersion = "dra.memory/v1alpha1"
+	Kind       = "AdmissionPolicy"
+)
+
+var ErrLimitExceeded = errors.New("admission limit exceeded")
+
+// Policy is the content of the admission policy file.
+type Policy struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Namespaces are the limits of the claims of the namespaces
+	// +optional
+	Namespaces []Rule `json:"namespaces,omitempty"`
+
+	// PriorityClasses are the limits of the claims of the pods by priority class
+	// +optional
+	PriorityClasses []Rule `json:"priorityClasses,omitempty"`
+}
+
+// Rule caps the amounts held by the claims of a namespace, or of the pods of a priority class.
+type Rule struct {
+	Name string `json:"name"`
+	// Limits are by resource name: memory, hugepages-2Mi, hugepages-1Gi...
+	Limits map[string]resource.Quantity `json:"limits"`
+}
+
+// LoadPolicy reads the admission policy from `path`.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pol Policy
+	err = yaml.UnmarshalStrict(data, &pol)
+	if err != nil {
+		return nil, fmt.Errorf("malformed admission policy %q: %w", path, err)
+	}
+	err = pol.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid admission policy %q: %w", path, err)
+	}
+	return &pol, nil
+}
+
+func (pol *Policy) Validate() error {
+	if pol.APIVersion != APIVersion || pol.Kind != Kind {
+		return fmt.Errorf("unsupported %s %s, expected %s %s", pol.APIVersion, pol.Kind, APIVersion, Kind)
+	}
+	return errors.Join(
+		validateRules("namespace", pol.Namespaces),
+		validateRules("priority class", pol.PriorityClasses),
+	)
+}
+
+func validateRules(what string, rules []Rule) error {
+	var names []string
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("%s rule without name", what)
+		}
+		if slices.Contains(names, rule.Name) {
+			return fmt.Errorf("duplicate %s rule %q", what, rule.Name)
+		}
+		names = append(names, rule.Name)
+		for resourceName, qty := range rule.Limits {
+			if err := validateResourceName(resourceName); err != nil {
+				return fmt.Errorf("%s rule %q: %w", what, rule.Name, err)
+			}
+			if qty.Sign() < 0 {
+				return fmt.Errorf("%s rule %q: %s limit %s must be not negative", what, rule.Name, resourceName, qty.String())
+			}
+		}
+	}
+	return nil
+}
+
+func validateResourceName(name string) error {
+	if name == string(types.Memory) {
+		return nil
+	}
+	ri, err := types.ResourceIdentFromName(name)
+	if err != nil || ri.Kind != types.Hugepages || ri.Name() != name {
+		return fmt.Errorf("unsupported resource %q, expected memory or hugepages-<size> like hugepages-2Mi", name)
+	}
+	return nil
+}
+
+// NeedsPriorityClass tells if the priority class of the pods is needed to admit the claims.
+func (pol *Policy) NeedsPriorityClass() bool {
+	return pol != nil && len(pol.PriorityClasses) > 0
+}
+
+// Request describes a claim to admit.
+type Request struct {
+	ClaimUID      k8stypes.UID `json:"claimUID"`
+	Namespace     string       `json:"namespace"`
+	PriorityClass string       `json:"priorityClass,omitempty"`
+	// Amounts are the bytes by resource name
+	Amounts map[string]int64 `json:"amounts"`
+}
+
+// Controller admits the claims according to the policy, and tracks the amounts held by the admitted ones.
+type Controller struct {
+	mu        sync.Mutex
+	policy    *Policy
+	held      map[k8stypes.UID]Request
+	statePath string
+}
+
+// NewController creates a controller. A nil policy admits all the claims.
+func NewController(pol *Policy) *Controller {
+	return &Controller{
+		policy: pol,
+		held:   make(map[k8stypes.UID]Request),
+	}
+}
+
+func (ctl *Controller) NeedsPriorityClass() bool {
+	return ctl.policy.NeedsPriorityClass()
+}
+
+// LoadState restores the amounts held by the claims admitted by a previous run, checkpointed in `statePath`,
+// if any, and checkpoints there the amounts from now on. Must be called before admitting the claims.
+// The claims released while the driver was down are released again by the kubelet, which frees their amounts.
+func (ctl *Controller) LoadState(lh logr.Logger, statePath string) error {
+	if ctl.policy == nil {
+		return nil
+	}
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	ctl.statePath = statePath
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	held := make(map[k8stypes.UID]Request)
+	err = json.Unmarshal(data, &held)
+	if err != nil {
+		return fmt.Errorf("decoding %q: %w", statePath, err)
+	}
+	ctl.held = held
+	lh.V(2).Info("restored admitted claims", "path", statePath, "claims", len(held))
+	return nil
+}
+
+// Admit checks the claim fits the limits, and if so records its amounts. Admitting again the same claim replaces its amounts.
+func (ctl *Controller) Admit(lh logr.Logger, req Request) error {
+	if ctl.policy == nil {
+		return nil
+	}
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	for _, rule := range ctl.policy.Namespaces {
+		if rule.Name != req.Namespace {
+			continue
+		}
+		err := ctl.check(rule, req, func(held Request) bool { return held.Namespace == rule.Name })
+		if err != nil {
+			return fmt.Errorf("namespace %q: %w", rule.Name, err)
+		}
+	}
+	for _, rule := range ctl.policy.PriorityClasses {
+		if rule.Name != req.PriorityClass {
+			continue
+		}
+		err := ctl.check(rule, req, func(held Request) bool { return held.PriorityClass == rule.Name })
+		if err != nil {
+			return fmt.Errorf("priority class %q: %w", rule.Name, err)
+		}
+	}
+	ctl.held[req.ClaimUID] = req
+	ctl.checkpoint(lh)
+	return nil
+}
+
+// Release forgets the amounts of the claim, if admitted.
+func (ctl *Controller) Release(lh logr.Logger, claimUID k8stypes.UID) {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	if _, ok := ctl.held[claimUID]; !ok {
+		return
+	}
+	delete(ctl.held, claimUID)
+	ctl.checkpoint(lh)
+}
+
+// checkpoint writes the held amounts on the state file, replacing it atomically. A failed checkpoint is not fatal:
+// the amounts are still tracked, unless the driver restarts in the meantime.
+func (ctl *Controller) checkpoint(lh logr.Logger) {
+	if ctl.statePath == "" {
+		return
+	}
+	err := writeState(ctl.statePath, ctl.held)
+	if err != nil {
+		lh.Error(err, "checkpointing admitted claims", "path", ctl.statePath)
+	}
+}
+
+func writeState(statePath string, held map[k8stypes.UID]Request) error {
+	data, err := json.Marshal(held)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(statePath), "."+filepath.Base(statePath)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after the rename
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), statePath)
+}
+
+func (ctl *Controller) check(rule Rule, req Request, matches func(Request) bool) error {
+	for resourceName, amount := range req.Amounts {
+		limit, ok := rule.Limits[resourceName]
+		if !ok {
+			continue
+		}
+		var used int64
+		for claimUID, held := range ctl.held {
+			if claimUID == req.ClaimUID || !matches(held) {
+				continue
+			}
+			used += held.Amounts[resourceName]
+		}
+		if used+amount > limit.Value() {
+			return fmt.Errorf("%w: %s requested %d bytes, held %d bytes, limit %s", ErrLimitExceeded, resourceName, amount, used, limit.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admission
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	type testcase struct {
+		name        string
+		content     string
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name: "valid",
+			content: `apiVersion: dra.memory/v1alpha1
+kind: AdmissionPolicy
+namespaces:
+- name: batch
+  limits:
+    memory: 64Gi
+    hugepages-2Mi: 8Gi
+priorityClasses:
+- name: low-priority
+  limits:
+    hugepages-1Gi: 16Gi
+`,
+		},
+		{
+			name:        "wrong kind",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: Quota\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\nnodes: []\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown resource",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\nnamespaces:\n- name: batch\n  limits:\n    cpu: 4\n",
+			expectedErr: true,
+		},
+		{
+			name:        "non canonical resource",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\nnamespaces:\n- name: batch\n  limits:\n    hugepages-2M: 1Gi\n",
+			expectedErr: true,
+		},
+		{
+			name:        "duplicate rule",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\nnamespaces:\n- name: batch\n- name: batch\n",
+			expectedErr: true,
+		},
+		{
+			name:        "negative limit",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\npriorityClasses:\n- name: low\n  limits:\n    memory: -1Gi\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tcase.content), 0644))
+			pol, err := LoadPolicy(path)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, pol.Namespaces, 1)
+			require.True(t, pol.NeedsPriorityClass())
+			qty := pol.Namespaces[0].Limits["hugepages-2Mi"]
+			require.Equal(t, int64(8<<30), qty.Value())
+		})
+	}
+}
+
+func TestLoadPolicyMissing(t *testing.T) {
+	_, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestAdmit(t *testing.T) {
+	lh := testr.New(t)
+	ctl := NewController(&Policy{
+		Namespaces: []Rule{
+			{Name: "batch", Limits: map[string]resource.Quantity{"hugepages-2Mi": resource.MustParse("1Gi")}},
+		},
+		PriorityClasses: []Rule{
+			{Name: "low", Limits: map[string]resource.Quantity{"memory": resource.MustParse("4Gi")}},
+		},
+	})
+	require.True(t, ctl.NeedsPriorityClass())
+
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-1", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 512 << 20}}))
+	// admitting again replaces the amounts
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-1", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 768 << 20}}))
+
+	err := ctl.Admit(lh, Request{ClaimUID: "claim-2", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 512 << 20}})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	// other namespaces and other resources are not limited
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-3", Namespace: "default", Amounts: map[string]int64{"hugepages-2Mi": 2 << 30}}))
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-4", Namespace: "batch", Amounts: map[string]int64{"memory": 8 << 30}}))
+
+	ctl.Release(lh, "claim-1")
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-2", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 512 << 20}}))
+
+	// the priority class limits hold across namespaces
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-5", Namespace: "ns1", PriorityClass: "low", Amounts: map[string]int64{"memory": 3 << 30}}))
+	err = ctl.Admit(lh, Request{ClaimUID: "claim-6", Namespace: "ns2", PriorityClass: "low", Amounts: map[string]int64{"memory": 2 << 30}})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func TestAdmitNoPolicy(t *testing.T) {
+	lh := testr.New(t)
+	ctl := NewController(nil)
+	require.False(t, ctl.NeedsPriorityClass())
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-1", Namespace: "batch", Amounts: map[string]int64{"memory": 1 << 40}}))
+}
+
+func TestAdmitAfterRestart(t *testing.T) {
+	lh := testr.New(t)
+	statePath := filepath.Join(t.TempDir(), "admitted-claims.json")
+	pol := &Policy{
+		Namespaces: []Rule{
+			{Name: "batch", Limits: map[string]resource.Quantity{"hugepages-2Mi": resource.MustParse("1Gi")}},
+		},
+	}
+
+	ctl := NewController(pol)
+	require.NoError(t, ctl.LoadState(lh, statePath), "missing state must be fine")
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-1", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 768 << 20}}))
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-2", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 128 << 20}}))
+	ctl.Release(lh, "claim-2")
+
+	// the driver restarts: the claims prepared before still hold their amounts
+	ctl = NewController(pol)
+	require.NoError(t, ctl.LoadState(lh, statePath))
+	err := ctl.Admit(lh, Request{ClaimUID: "claim-3", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 512 << 20}})
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	// and the kubelet releases them afterwards
+	ctl.Release(lh, "claim-1")
+	require.NoError(t, ctl.Admit(lh, Request{ClaimUID: "claim-3", Namespace: "batch", Amounts: map[string]int64{"hugepages-2Mi": 512 << 20}}))
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentapi
+
+// The agent API shares the view of the driver with the other agents running on the node, like CPU drivers,
+// NUMA-aware schedulers and monitoring, so they don't need to scan sysfs again. Unlike the debug API,
+// it is meant for automation: the schema is versioned, and only grows backward compatible.
+// It is served read-only on a local unix socket.
+
+const (
+	// Version is the version of the schema, part of the paths.
+	Version = "v1"
+
+	MachinePath     = "/" + Version + "/machine"
+	AllocationsPath = "/" + Version + "/allocations"
+)
+
+// Machine is the memory topology of the node, as the driver discovered it at the last refresh.
+type Machine struct {
+	NodeName string `json:"nodeName"`
+	// PageSize is the size of the regular pages, in bytes
+	PageSize uint64 `json:"pageSize"`
+	// HugepageSizes are the hugepage sizes the kernel supports, in bytes
+	HugepageSizes []uint64 `json:"hugepageSizes,omitempty"`
+	Zones         []Zone   `json:"zones"`
+}
+
+// Zone is a NUMA zone of the node.
+type Zone struct {
+	ID int64 `json:"id"`
+	// Distances are the distances to the zones, indexed by zone ID
+	Distances []int `json:"distances,omitempty"`
+	// CPUSocketID is the physical package of the CPUs of the zone. -1 if unknown, e.g. on CPU-less zones.
+	CPUSocketID int `json:"cpuSocketID"`
+	// PCIeRoots are the PCIe root complexes attached to the zone, sorted by name
+	PCIeRoots []string `json:"pcieRoots,omitempty"`
+	// UsableBytes is the memory of the zone the kernel can use, including the hugepages
+	UsableBytes int64 `json:"usableBytes"`
+	// Hugepages are the pools of the zone, sorted by page size
+	Hugepages []HugepagesPool `json:"hugepages,omitempty"`
+}
+
+// HugepagesPool is the pool of the hugepages of a size on a NUMA zone, counted in pages.
+type HugepagesPool struct {
+	PageSize uint64 `json:"pageSize"`
+	Total    int64  `json:"total"`
+	Free     int64  `json:"free"`
+}
+
+// Allocations are the claims prepared by the driver on the node.
+type Allocations struct {
+	NodeName string `json:"nodeName"`
+	// Claims are sorted by UID
+	Claims []Claim `json:"claims"`
+}
+
+type Claim struct {
+	UID string `json:"uid"`
+	// PodUID is the pod the claim is reserved for. Empty if the claim was not prepared by this run of the driver.
+	PodUID string `json:"podUID,omitempty"`
+	// Allocations are sorted by resource
+	Allocations []Allocation `json:"allocations"`
+}
+
+type Allocation struct {
+	// Resource is the canonical name, like `memory` or `hugepages-2Mi`
+	Resource string `json:"resource"`
+	// PageSize is the size of the pages of the resource, in bytes
+	PageSize        uint64          `json:"pageSize"`
+	Bytes           int64           `json:"bytes"`
+	BytesByNUMAZone map[int64]int64 `json:"bytesByNUMAZone"`
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+// requestTimeout bounds the requests of the client, the daemon answers from memory
+const requestTimeout = 10 * time.Second
+
+// MachineFunc returns the current memory topology. Must be safe to call concurrently with the driver.
+type MachineFunc func() Machine
+
+// AllocationsFunc returns the current allocations. Must be safe to call concurrently with the driver hooks.
+type AllocationsFunc func() Allocations
+
+// Server serves the agent API on a unix socket.
+type Server struct {
+	socketPath  string
+	machine     MachineFunc
+	allocations AllocationsFunc
+}
+
+func NewServer(socketPath string, machine MachineFunc, allocations AllocationsFunc) *Server {
+	return &Server{
+		socketPath:  socketPath,
+		machine:     machine,
+		allocations: allocations,
+	}
+}
+
+// Run serves the requests until the context is done.
+func (srv *Server) Run(ctx context.Context, lh logr.Logger) error {
+	// a socket left behind by a previous, killed instance makes listen fail
+	err := os.Remove(srv.socketPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %q: %w", srv.socketPath, err)
+	}
+	// the allocations include the pods of all the namespaces, so root only
+	listener, err := listen(ctx, srv.socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %q: %w", srv.socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+MachinePath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(lh, w, srv.machine())
+	})
+	mux.HandleFunc("GET "+AllocationsPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(lh, w, srv.allocations())
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	lh.Info("serving agent API", "socketPath", srv.socketPath)
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func writeJSON(lh logr.Logger, w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		lh.Error(err, "encoding response")
+	}
+}
+
+// GetMachine fetches the memory topology from the daemon listening on `socketPath`.
+func GetMachine(ctx context.Context, socketPath string) (Machine, error) {
+	var data Machine
+	err := get(ctx, socketPath, MachinePath, &data)
+	return data, err
+}
+
+// GetAllocations fetches the allocations from the daemon listening on `socketPath`.
+func GetAllocations(ctx context.Context, socketPath string) (Allocations, error) {
+	var data Allocations
+	err := get(ctx, socketPath, AllocationsPath, &data)
+	return data, err
+}
+
+func get(ctx context.Context, socketPath, path string, data any) error {
+	client := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	// the host is ignored, we always dial the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting to the daemon on %q: %w", socketPath, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected response %q: %s", resp.Status, msg)
+	}
+	err = json.NewDecoder(resp.Body).Decode(data)
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	return nil
+}
+
+// listen creates the socket accessible to root only. The mode is set on the socket before binding it, so no other
+// user can connect, not even briefly, and the umask of the process, shared by the files the driver writes, is left alone.
+func listen(ctx context.Context, socketPath string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, conn syscall.RawConn) error {
+			var chmodErr error
+			err := conn.Control(func(fd uintptr) {
+				// the bound socket file takes the mode of the socket
+				chmodErr = unix.Fchmod(int(fd), 0600)
+			})
+			if err != nil {
+				return err
+			}
+			return chmodErr
+		},
+	}
+	return lc.Listen(ctx, "unix", socketPath)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agentapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerRoundtrip(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "agt")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "agent.sock")
+	// left behind by a killed instance
+	require.NoError(t, os.WriteFile(socketPath, nil, 0600))
+
+	machine := Machine{
+		NodeName:      "node-0",
+		PageSize:      4 << 10,
+		HugepageSizes: []uint64{2 << 20, 1 << 30},
+		Zones: []Zone{
+			{
+				ID:          0,
+				Distances:   []int{10, 21},
+				CPUSocketID: 0,
+				PCIeRoots:   []string{"pci0000:00"},
+				UsableBytes: 16 << 30,
+				Hugepages: []HugepagesPool{
+					{PageSize: 2 << 20, Total: 512, Free: 496},
+				},
+			},
+			{
+				ID:          1,
+				Distances:   []int{21, 10},
+				CPUSocketID: -1,
+				UsableBytes: 16 << 30,
+			},
+		},
+	}
+	allocations := Allocations{
+		NodeName: "node-0",
+		Claims: []Claim{
+			{
+				UID:    "claim-A",
+				PodUID: "pod-A",
+				Allocations: []Allocation{
+					{Resource: "hugepages-2Mi", PageSize: 2 << 20, Bytes: 32 << 20, BytesByNUMAZone: map[int64]int64{0: 32 << 20}},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(socketPath, func() Machine { return machine }, func() Allocations { return allocations })
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx, testr.New(t))
+	}()
+
+	var gotMachine Machine
+	require.Eventually(t, func() bool {
+		gotMachine, err = GetMachine(ctx, socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "cannot get machine: %v", err)
+	require.Equal(t, machine, gotMachine)
+
+	gotAllocations, err := GetAllocations(ctx, socketPath)
+	require.NoError(t, err)
+	require.Equal(t, allocations, gotAllocations)
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	err = get(ctx, socketPath, "/v0/machine", &gotMachine)
+	require.Error(t, err, "unknown path")
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestListenRestricted(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "agt")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "test.sock")
+
+	// the socket must be restricted right when it appears, whatever the umask
+	listener, err := listen(context.Background(), socketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
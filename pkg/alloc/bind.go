@@ -18,6 +18,7 @@ package alloc
 
 import (
 	"fmt"
+	"maps"
 	"sync"
 
 	"github.com/go-logr/logr"
@@ -95,6 +96,13 @@ func (bnd *Binder) Cleanup(lh logr.Logger, claimUIDs ...k8stypes.UID) {
 	}
 }
 
+// Owners returns a copy of the owners of all the bound claims.
+func (bnd *Binder) Owners() map[k8stypes.UID]OwnerIdent {
+	bnd.mu.Lock()
+	defer bnd.mu.Unlock()
+	return maps.Clone(bnd.ownerByClaimUID)
+}
+
 func (bnd *Binder) Len() int {
 	bnd.mu.Lock()
 	defer bnd.mu.Unlock()
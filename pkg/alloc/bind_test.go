@@ -186,6 +186,10 @@ func TestLen(t *testing.T) {
 	}
 	require.Equal(t, bnd.Len(), len(bindings))
 
+	owners := bnd.Owners()
+	require.Len(t, owners, len(bindings))
+	require.Equal(t, OwnerIdent{PodUID: "pod-BBB", ContainerName: "cnt-1"}, owners["claim-456"])
+
 	bnd.Cleanup(logger, "claim-123", "claim-456", "claim-789")
 	require.Equal(t, bnd.Len(), 0)
 }
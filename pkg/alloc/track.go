@@ -18,6 +18,8 @@ package alloc
 
 import (
 	"maps"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/go-logr/logr"
@@ -30,25 +32,45 @@ import (
 
 type podItem struct {
 	ClaimUIDs sets.Set[k8stypes.UID]
+	// containerName -> claims consumed by the container
+	ClaimUIDsByContainer map[string]sets.Set[k8stypes.UID]
 }
 
+func newPodItem() podItem {
+	return podItem{
+		ClaimUIDs:            sets.New[k8stypes.UID](),
+		ClaimUIDsByContainer: make(map[string]sets.Set[k8stypes.UID]),
+	}
+}
+
+// Tracker keeps the claims, learned by the DRA hooks, separate from the pods, learned by the NRI hooks,
+// each with its own lock, so the two sides of the driver don't contend when many pods start at once.
+// When both locks are needed, podsMu is taken first.
 type Tracker struct {
-	rwMu sync.RWMutex
+	claimsMu sync.RWMutex
 	// claim -> resourceType (can be `hugepages-1g`) -> allocation
 	allocationsByClaimUID map[k8stypes.UID]map[string]types.Allocation
-	claimsByPodSandboxID  map[string]podItem
+	// claim -> podUID, learned at prepare time from the claim reservation
+	podUIDByClaimUID map[k8stypes.UID]string
+	// podUID -> claims, the reverse of podUIDByClaimUID
+	claimUIDsByPodUID map[string]sets.Set[k8stypes.UID]
+
+	podsMu               sync.RWMutex
+	claimsByPodSandboxID map[string]podItem
 }
 
 func NewTracker() *Tracker {
 	return &Tracker{
 		allocationsByClaimUID: make(map[k8stypes.UID]map[string]types.Allocation),
+		podUIDByClaimUID:      make(map[k8stypes.UID]string),
+		claimUIDsByPodUID:     make(map[string]sets.Set[k8stypes.UID]),
 		claimsByPodSandboxID:  make(map[string]podItem),
 	}
 }
 
 func (trk *Tracker) RegisterClaim(claimUID k8stypes.UID, claimAllocs map[string]types.Allocation) {
-	trk.rwMu.Lock()
-	defer trk.rwMu.Unlock()
+	trk.claimsMu.Lock()
+	defer trk.claimsMu.Unlock()
 	alloc, ok := trk.allocationsByClaimUID[claimUID]
 	if !ok {
 		trk.allocationsByClaimUID[claimUID] = maps.Clone(claimAllocs)
@@ -61,14 +83,14 @@ func (trk *Tracker) RegisterClaim(claimUID k8stypes.UID, claimAllocs map[string]
 }
 
 func (trk *Tracker) UnregisterClaim(claimUID k8stypes.UID) {
-	trk.rwMu.Lock()
-	defer trk.rwMu.Unlock()
+	trk.claimsMu.Lock()
+	defer trk.claimsMu.Unlock()
 	trk.unregisterClaimUnlocked(claimUID)
 }
 
 func (trk *Tracker) GetAllocationsForClaim(claimUID k8stypes.UID) (map[string]types.Allocation, bool) {
-	trk.rwMu.RLock()
-	defer trk.rwMu.RUnlock()
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
 	allocs, ok := trk.allocationsByClaimUID[claimUID]
 	if !ok {
 		return nil, false
@@ -76,47 +98,298 @@ func (trk *Tracker) GetAllocationsForClaim(claimUID k8stypes.UID) (map[string]ty
 	return maps.Clone(allocs), true
 }
 
+// ReserveClaim records the pod the claim is reserved for. This is known way before
+// any container consuming the claim is created, so we can use it to find the
+// allocations of a pod even from containers which don't consume any claim.
+func (trk *Tracker) ReserveClaim(claimUID k8stypes.UID, podUID string) {
+	trk.claimsMu.Lock()
+	defer trk.claimsMu.Unlock()
+	trk.unreserveClaimUnlocked(claimUID)
+	trk.podUIDByClaimUID[claimUID] = podUID
+	claimUIDs, ok := trk.claimUIDsByPodUID[podUID]
+	if !ok {
+		claimUIDs = sets.New[k8stypes.UID]()
+		trk.claimUIDsByPodUID[podUID] = claimUIDs
+	}
+	claimUIDs.Insert(claimUID)
+}
+
+// GetAllocationsForPod returns all the allocations of all the claims reserved for the given pod.
+func (trk *Tracker) GetAllocationsForPod(podUID string) []types.Allocation {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	var allocs []types.Allocation
+	for claimUID := range trk.claimUIDsByPodUID[podUID] {
+		for _, alloc := range trk.allocationsByClaimUID[claimUID] {
+			allocs = append(allocs, alloc)
+		}
+	}
+	return allocs
+}
+
+// GetClaimsForPod returns the sorted UIDs of the claims reserved for the given pod.
+func (trk *Tracker) GetClaimsForPod(podUID string) []k8stypes.UID {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	claimUIDs, ok := trk.claimUIDsByPodUID[podUID]
+	if !ok {
+		return []k8stypes.UID{}
+	}
+	return sets.List(claimUIDs)
+}
+
+// AllocatedBytes returns the bytes allocated to all the registered claims,
+// by resource name (e.g. `hugepages-2Mi`) and by NUMA zone.
+func (trk *Tracker) AllocatedBytes() map[string]map[int64]int64 {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	ret := make(map[string]map[int64]int64)
+	for _, allocs := range trk.allocationsByClaimUID {
+		for _, alloc := range allocs {
+			byZone, ok := ret[alloc.Name()]
+			if !ok {
+				byZone = make(map[int64]int64)
+				ret[alloc.Name()] = byZone
+			}
+			for numaZone, amount := range alloc.AmountByZone {
+				byZone[numaZone] += amount
+			}
+		}
+	}
+	return ret
+}
+
+// RemainingBytes returns the bytes of each device in `spans` not allocated to the registered claims,
+// by device name. The device names change at each discovery, so the allocations map to the devices
+// by resource name and NUMA zone, and by sub-pool if the pool of the zone is split.
+func (trk *Tracker) RemainingBytes(spans map[string]types.Span) map[string]int64 {
+	allocated := trk.AllocatedBytes()
+	allocatedByPool := trk.allocatedBytesByPool()
+	ret := make(map[string]int64, len(spans))
+	for devName, span := range spans {
+		used := allocated[span.Name()][span.NUMAZone]
+		if span.Pool != "" {
+			used = allocatedByPool[span.Name()][span.Pool][span.NUMAZone]
+		}
+		ret[devName] = max(span.Amount-used, 0)
+	}
+	return ret
+}
+
+// allocatedBytesByPool returns the bytes allocated from the sub-pools, by resource name, pool name and NUMA zone.
+func (trk *Tracker) allocatedBytesByPool() map[string]map[string]map[int64]int64 {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	ret := make(map[string]map[string]map[int64]int64)
+	for _, allocs := range trk.allocationsByClaimUID {
+		for _, alloc := range allocs {
+			for pool, byZone := range alloc.AmountByPool {
+				byPool, ok := ret[alloc.Name()]
+				if !ok {
+					byPool = make(map[string]map[int64]int64)
+					ret[alloc.Name()] = byPool
+				}
+				if byPool[pool] == nil {
+					byPool[pool] = make(map[int64]int64)
+				}
+				for numaZone, amount := range byZone {
+					byPool[pool][numaZone] += amount
+				}
+			}
+		}
+	}
+	return ret
+}
+
 func (trk *Tracker) BindClaim(lh logr.Logger, claimUID k8stypes.UID, podSandboxID string) {
-	trk.rwMu.Lock()
-	defer trk.rwMu.Unlock()
+	trk.podsMu.Lock()
+	defer trk.podsMu.Unlock()
 	info, ok := trk.claimsByPodSandboxID[podSandboxID]
 	if !ok {
 		lh.V(5).Info("podItem created", "podSandboxID", podSandboxID, "claimUID", claimUID)
-		info = podItem{
-			ClaimUIDs: sets.New[k8stypes.UID](),
-		}
+		info = newPodItem()
 	}
 	info.ClaimUIDs.Insert(claimUID)
 	trk.claimsByPodSandboxID[podSandboxID] = info
 	lh.V(4).Info("podItem bound", "claimUID", claimUID, "podSandboxID", podSandboxID)
 }
 
+// BindContainer records the claims consumed by a container, so we can recover
+// the container allocations when it is recreated, e.g. after a crash.
+func (trk *Tracker) BindContainer(lh logr.Logger, podSandboxID, containerName string, claimUIDs ...k8stypes.UID) {
+	trk.podsMu.Lock()
+	defer trk.podsMu.Unlock()
+	info, ok := trk.claimsByPodSandboxID[podSandboxID]
+	if !ok {
+		lh.V(5).Info("podItem created", "podSandboxID", podSandboxID, "containerName", containerName)
+		info = newPodItem()
+	}
+	info.ClaimUIDs.Insert(claimUIDs...)
+	info.ClaimUIDsByContainer[containerName] = sets.New(claimUIDs...)
+	trk.claimsByPodSandboxID[podSandboxID] = info
+	lh.V(4).Info("container bound", "podSandboxID", podSandboxID, "containerName", containerName, "claimsCount", len(claimUIDs))
+}
+
+// HasContainer tells if the container was previously bound to the claims.
+func (trk *Tracker) HasContainer(podSandboxID, containerName string) bool {
+	trk.podsMu.RLock()
+	defer trk.podsMu.RUnlock()
+	info, ok := trk.claimsByPodSandboxID[podSandboxID]
+	if !ok {
+		return false
+	}
+	_, ok = info.ClaimUIDsByContainer[containerName]
+	return ok
+}
+
+// GetAllocationsForContainer returns the allocations, by claim, of the claims previously bound to the container.
+func (trk *Tracker) GetAllocationsForContainer(podSandboxID, containerName string) (map[k8stypes.UID]map[string]types.Allocation, bool) {
+	trk.podsMu.RLock()
+	defer trk.podsMu.RUnlock()
+	info, ok := trk.claimsByPodSandboxID[podSandboxID]
+	if !ok {
+		return nil, false
+	}
+	claimUIDs, ok := info.ClaimUIDsByContainer[containerName]
+	if !ok {
+		return nil, false
+	}
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	ret := make(map[k8stypes.UID]map[string]types.Allocation, claimUIDs.Len())
+	for claimUID := range claimUIDs {
+		allocs, ok := trk.allocationsByClaimUID[claimUID]
+		if !ok {
+			continue // unprepared meanwhile
+		}
+		ret[claimUID] = maps.Clone(allocs)
+	}
+	return ret, len(ret) > 0
+}
+
+// GetAllocationsForSandbox returns the allocations of the claims bound to the containers of the pod sandbox
+// created so far. Each claim counts once, even if consumed by many containers.
+func (trk *Tracker) GetAllocationsForSandbox(podSandboxID string) []types.Allocation {
+	trk.podsMu.RLock()
+	defer trk.podsMu.RUnlock()
+	info, ok := trk.claimsByPodSandboxID[podSandboxID]
+	if !ok {
+		return nil
+	}
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	var allocs []types.Allocation
+	for _, claimUID := range sets.List(info.ClaimUIDs) {
+		for _, resourceName := range slices.Sorted(maps.Keys(trk.allocationsByClaimUID[claimUID])) {
+			allocs = append(allocs, trk.allocationsByClaimUID[claimUID][resourceName])
+		}
+	}
+	return allocs
+}
+
 func (trk *Tracker) CleanupPod(lh logr.Logger, podSandboxID string) []k8stypes.UID {
-	trk.rwMu.Lock()
-	defer trk.rwMu.Unlock()
+	trk.podsMu.Lock()
+	defer trk.podsMu.Unlock()
 	info, ok := trk.claimsByPodSandboxID[podSandboxID]
 	if !ok {
 		return nil
 	}
 	claimUIDs := info.ClaimUIDs.UnsortedList()
 	lh.V(4).Info("cleaning claims", "podSandboxID", podSandboxID, "claimsCount", len(claimUIDs))
+	trk.claimsMu.Lock()
 	for _, claimUID := range claimUIDs {
 		trk.unregisterClaimUnlocked(claimUID)
 	}
+	trk.claimsMu.Unlock()
 	trk.unbindClaimUnlocked(podSandboxID)
 	return claimUIDs
 }
 
+// ClaimState is a copy of the tracked state of a claim.
+type ClaimState struct {
+	UID k8stypes.UID
+	// PodUID is the pod the claim is reserved for, empty if unknown
+	PodUID string
+	// resourceType (can be `hugepages-1g`) -> allocation
+	Allocations map[string]types.Allocation
+}
+
+// PodState is a copy of the tracked state of a pod sandbox.
+type PodState struct {
+	SandboxID string
+	ClaimUIDs []k8stypes.UID
+	// containerName -> sorted claims consumed by the container
+	ClaimUIDsByContainer map[string][]k8stypes.UID
+}
+
+// Claims returns the state of all the registered claims, sorted by UID.
+func (trk *Tracker) Claims() []ClaimState {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
+	ret := make([]ClaimState, 0, len(trk.allocationsByClaimUID))
+	for claimUID, allocs := range trk.allocationsByClaimUID {
+		ret = append(ret, ClaimState{
+			UID:         claimUID,
+			PodUID:      trk.podUIDByClaimUID[claimUID],
+			Allocations: maps.Clone(allocs),
+		})
+	}
+	slices.SortFunc(ret, func(a, b ClaimState) int {
+		return strings.Compare(string(a.UID), string(b.UID))
+	})
+	return ret
+}
+
+// Pods returns the state of all the pod sandboxes bound to claims, sorted by sandbox ID.
+func (trk *Tracker) Pods() []PodState {
+	trk.podsMu.RLock()
+	defer trk.podsMu.RUnlock()
+	ret := make([]PodState, 0, len(trk.claimsByPodSandboxID))
+	for podSandboxID, info := range trk.claimsByPodSandboxID {
+		pod := PodState{
+			SandboxID:            podSandboxID,
+			ClaimUIDs:            sets.List(info.ClaimUIDs),
+			ClaimUIDsByContainer: make(map[string][]k8stypes.UID, len(info.ClaimUIDsByContainer)),
+		}
+		for containerName, claimUIDs := range info.ClaimUIDsByContainer {
+			pod.ClaimUIDsByContainer[containerName] = sets.List(claimUIDs)
+		}
+		ret = append(ret, pod)
+	}
+	slices.SortFunc(ret, func(a, b PodState) int {
+		return strings.Compare(a.SandboxID, b.SandboxID)
+	})
+	return ret
+}
+
 func (trk *Tracker) CountClaims() int {
+	trk.claimsMu.RLock()
+	defer trk.claimsMu.RUnlock()
 	return len(trk.allocationsByClaimUID)
 }
 
 func (trk *Tracker) CountPods() int {
+	trk.podsMu.RLock()
+	defer trk.podsMu.RUnlock()
 	return len(trk.claimsByPodSandboxID)
 }
 
 func (trk *Tracker) unregisterClaimUnlocked(claimUID k8stypes.UID) {
 	delete(trk.allocationsByClaimUID, claimUID)
+	trk.unreserveClaimUnlocked(claimUID)
+}
+
+func (trk *Tracker) unreserveClaimUnlocked(claimUID k8stypes.UID) {
+	podUID, ok := trk.podUIDByClaimUID[claimUID]
+	if !ok {
+		return
+	}
+	delete(trk.podUIDByClaimUID, claimUID)
+	claimUIDs := trk.claimUIDsByPodUID[podUID]
+	claimUIDs.Delete(claimUID)
+	if claimUIDs.Len() == 0 {
+		delete(trk.claimUIDsByPodUID, podUID)
+	}
 }
 
 func (trk *Tracker) unbindClaimUnlocked(podSandboxID string) {
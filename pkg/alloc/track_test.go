@@ -18,6 +18,8 @@ package alloc
 
 import (
 	"maps"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/go-logr/logr/testr"
@@ -42,8 +44,8 @@ func TestRegisterUnregister(t *testing.T) {
 				Kind:     types.Memory,
 				Pagesize: 4 * 1024,
 			},
-			Amount:   16 * 4 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
 		},
 	}
 	trk := NewTracker()
@@ -63,8 +65,8 @@ func TestRegisterGetClones(t *testing.T) {
 				Kind:     types.Memory,
 				Pagesize: 4 * 1024,
 			},
-			Amount:   16 * 4 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
 		},
 	}
 	expected := maps.Clone(claimAllocs)
@@ -91,8 +93,8 @@ func TestRegisterUpdatesExistingData(t *testing.T) {
 				Kind:     types.Memory,
 				Pagesize: 4 * 1024,
 			},
-			Amount:   16 * 4 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
 		},
 	}
 
@@ -104,8 +106,8 @@ func TestRegisterUpdatesExistingData(t *testing.T) {
 			Kind:     types.Hugepages,
 			Pagesize: 2 * 1024 * 1024,
 		},
-		Amount:   16 * 2 * 1024 * 1024,
-		NUMAZone: 1,
+		Amount:       16 * 2 * 1024 * 1024,
+		AmountByZone: map[int64]int64{1: 16 * 2 * 1024 * 1024},
 	}
 	trk.RegisterClaim(k8stypes.UID("foobar"), claimAllocs)
 
@@ -126,8 +128,8 @@ func TestCannotDeleteIfUnbounded(t *testing.T) {
 				Kind:     types.Memory,
 				Pagesize: 4 * 1024,
 			},
-			Amount:   16 * 4 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
 		},
 	}
 	expected := maps.Clone(claimAllocs)
@@ -158,8 +160,8 @@ func TestUnregisterByPod(t *testing.T) {
 				Kind:     types.Memory,
 				Pagesize: 4 * 1024,
 			},
-			Amount:   16 * 4 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
 		},
 	})
 	trk.BindClaim(lh, k8stypes.UID("foo"), "pod-SandboxID")
@@ -170,8 +172,8 @@ func TestUnregisterByPod(t *testing.T) {
 				Kind:     types.Hugepages,
 				Pagesize: 2 * 1024 * 1024,
 			},
-			Amount:   16 * 2 * 1024 * 1024,
-			NUMAZone: 1,
+			Amount:       16 * 2 * 1024 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 2 * 1024 * 1024},
 		},
 	})
 	trk.BindClaim(lh, k8stypes.UID("bar"), "pod-SandboxID")
@@ -186,3 +188,313 @@ func TestUnregisterByPod(t *testing.T) {
 	_, ok = trk.GetAllocationsForClaim("bar")
 	require.False(t, ok, "claim should be removed by podId")
 }
+
+func TestGetAllocationsForPod(t *testing.T) {
+	lh := testr.New(t)
+	trk := NewTracker()
+
+	memAlloc := types.Allocation{
+		ResourceIdent: types.ResourceIdent{
+			Kind:     types.Memory,
+			Pagesize: 4 * 1024,
+		},
+		Amount:       16 * 4 * 1024,
+		AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
+	}
+	trk.RegisterClaim(k8stypes.UID("foo"), map[string]types.Allocation{
+		"memory": memAlloc,
+	})
+	trk.ReserveClaim(k8stypes.UID("foo"), "pod-UID-A")
+
+	trk.RegisterClaim(k8stypes.UID("bar"), map[string]types.Allocation{
+		"hugepages-2m": {
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * 1024 * 1024,
+			},
+			Amount:       16 * 2 * 1024 * 1024,
+			AmountByZone: map[int64]int64{0: 16 * 2 * 1024 * 1024},
+		},
+	})
+	trk.ReserveClaim(k8stypes.UID("bar"), "pod-UID-B")
+
+	got := trk.GetAllocationsForPod("pod-UID-A")
+	if diff := cmp.Diff(got, []types.Allocation{memAlloc}); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+	require.Empty(t, trk.GetAllocationsForPod("pod-UID-C"))
+	require.Equal(t, []k8stypes.UID{"foo"}, trk.GetClaimsForPod("pod-UID-A"))
+	require.Empty(t, trk.GetClaimsForPod("pod-UID-C"))
+
+	trk.BindClaim(lh, k8stypes.UID("foo"), "pod-SandboxID-A")
+	trk.CleanupPod(lh, "pod-SandboxID-A")
+	require.Empty(t, trk.GetAllocationsForPod("pod-UID-A"))
+	require.Len(t, trk.GetAllocationsForPod("pod-UID-B"), 1)
+}
+
+func TestGetAllocationsForContainer(t *testing.T) {
+	lh := testr.New(t)
+	trk := NewTracker()
+
+	claimAllocs := map[string]types.Allocation{
+		"memory": {
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Memory,
+				Pagesize: 4 * 1024,
+			},
+			Amount:       16 * 4 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 4 * 1024},
+		},
+		"hugepages-2m": {
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * 1024 * 1024,
+			},
+			Amount:       16 * 2 * 1024 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 2 * 1024 * 1024},
+		},
+	}
+	trk.RegisterClaim(k8stypes.UID("foo"), claimAllocs)
+
+	require.False(t, trk.HasContainer("pod-SandboxID", "app"))
+	_, ok := trk.GetAllocationsForContainer("pod-SandboxID", "app")
+	require.False(t, ok, "found allocations for unbound container")
+
+	trk.BindClaim(lh, k8stypes.UID("foo"), "pod-SandboxID")
+	trk.BindContainer(lh, "pod-SandboxID", "app", k8stypes.UID("foo"))
+	require.True(t, trk.HasContainer("pod-SandboxID", "app"))
+	require.False(t, trk.HasContainer("pod-SandboxID", "sidecar"))
+
+	got, ok := trk.GetAllocationsForContainer("pod-SandboxID", "app")
+	require.True(t, ok, "missing allocations for bound container")
+	expected := map[k8stypes.UID]map[string]types.Allocation{
+		k8stypes.UID("foo"): maps.Clone(claimAllocs),
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+
+	trk.CleanupPod(lh, "pod-SandboxID")
+	require.False(t, trk.HasContainer("pod-SandboxID", "app"))
+	_, ok = trk.GetAllocationsForContainer("pod-SandboxID", "app")
+	require.False(t, ok, "found allocations for cleaned up container")
+}
+
+func TestGetAllocationsForSandbox(t *testing.T) {
+	lh := testr.New(t)
+	trk := NewTracker()
+
+	hpAlloc := func(amount int64) types.Allocation {
+		return types.Allocation{
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * 1024 * 1024,
+			},
+			Amount:       amount,
+			AmountByZone: map[int64]int64{0: amount},
+		}
+	}
+	for idx, claimUID := range []k8stypes.UID{"claim-a", "claim-b", "claim-c"} {
+		trk.RegisterClaim(claimUID, map[string]types.Allocation{
+			"hugepages-2m": hpAlloc(int64(idx+1) * 2 * 1024 * 1024),
+		})
+		trk.ReserveClaim(claimUID, "pod-UID")
+	}
+	require.Empty(t, trk.GetAllocationsForSandbox("pod-SandboxID"))
+
+	// two containers sharing a claim, the third claim consumed by a container not created yet
+	trk.BindContainer(lh, "pod-SandboxID", "app", "claim-a", "claim-b")
+	trk.BindContainer(lh, "pod-SandboxID", "helper", "claim-b")
+	got := trk.GetAllocationsForSandbox("pod-SandboxID")
+	expected := []types.Allocation{hpAlloc(2 * 1024 * 1024), hpAlloc(4 * 1024 * 1024)}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+	require.Len(t, trk.GetAllocationsForPod("pod-UID"), 3)
+
+	trk.CleanupPod(lh, "pod-SandboxID")
+	require.Empty(t, trk.GetAllocationsForSandbox("pod-SandboxID"))
+}
+
+func TestClaimsAndPods(t *testing.T) {
+	lh := testr.New(t)
+	trk := NewTracker()
+	require.Empty(t, trk.Claims())
+	require.Empty(t, trk.Pods())
+
+	claimAllocs := map[string]types.Allocation{
+		"hugepages-2m": {
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * 1024 * 1024,
+			},
+			Amount:       16 * 2 * 1024 * 1024,
+			AmountByZone: map[int64]int64{1: 16 * 2 * 1024 * 1024},
+		},
+	}
+	trk.RegisterClaim(k8stypes.UID("foo"), claimAllocs)
+	trk.RegisterClaim(k8stypes.UID("bar"), claimAllocs)
+	trk.ReserveClaim(k8stypes.UID("foo"), "pod-UID")
+	trk.BindContainer(lh, "pod-SandboxID", "app", k8stypes.UID("foo"))
+
+	expectedClaims := []ClaimState{
+		{
+			UID:         k8stypes.UID("bar"),
+			Allocations: maps.Clone(claimAllocs),
+		},
+		{
+			UID:         k8stypes.UID("foo"),
+			PodUID:      "pod-UID",
+			Allocations: maps.Clone(claimAllocs),
+		},
+	}
+	if diff := cmp.Diff(trk.Claims(), expectedClaims); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+
+	expectedPods := []PodState{
+		{
+			SandboxID: "pod-SandboxID",
+			ClaimUIDs: []k8stypes.UID{"foo"},
+			ClaimUIDsByContainer: map[string][]k8stypes.UID{
+				"app": {"foo"},
+			},
+		},
+	}
+	pods := trk.Pods()
+	if diff := cmp.Diff(pods, expectedPods); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+
+	// the state is a copy
+	pods[0].ClaimUIDsByContainer["sidecar"] = nil
+	require.Len(t, trk.Pods()[0].ClaimUIDsByContainer, 1)
+}
+
+func TestAllocatedBytes(t *testing.T) {
+	trk := NewTracker()
+	require.Empty(t, trk.AllocatedBytes())
+
+	makeAlloc := func(pages, numaZone int64) types.Allocation {
+		return types.Allocation{
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * 1024 * 1024,
+			},
+			Amount:       pages * 2 * 1024 * 1024,
+			AmountByZone: map[int64]int64{numaZone: pages * 2 * 1024 * 1024},
+		}
+	}
+	trk.RegisterClaim(k8stypes.UID("foo"), map[string]types.Allocation{
+		"hugepages-2m": makeAlloc(16, 0),
+	})
+	trk.RegisterClaim(k8stypes.UID("bar"), map[string]types.Allocation{
+		"hugepages-2m": makeAlloc(8, 0),
+	})
+	trk.RegisterClaim(k8stypes.UID("baz"), map[string]types.Allocation{
+		"hugepages-2m": makeAlloc(4, 1),
+	})
+
+	expected := map[string]map[int64]int64{
+		"hugepages-2Mi": {
+			0: 24 * 2 * 1024 * 1024,
+			1: 4 * 2 * 1024 * 1024,
+		},
+	}
+	if diff := cmp.Diff(trk.AllocatedBytes(), expected); diff != "" {
+		t.Fatalf("unexpected diff: %s", diff)
+	}
+
+	trk.UnregisterClaim(k8stypes.UID("baz"))
+	require.Equal(t, map[int64]int64{0: 24 * 2 * 1024 * 1024}, trk.AllocatedBytes()["hugepages-2Mi"])
+}
+
+func TestRemainingBytes(t *testing.T) {
+	trk := NewTracker()
+	hp2m := types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * 1024 * 1024}
+	spans := map[string]types.Span{
+		"hugepages-2mi-aaaaaa": {ResourceIdent: hp2m, Amount: 64 * 1024 * 1024, NUMAZone: 0},
+		"hugepages-2mi-bbbbbb": {ResourceIdent: hp2m, Amount: 32 * 1024 * 1024, NUMAZone: 1},
+		"memory-cccccc":        {ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4096}, Amount: 1 << 30, NUMAZone: 0},
+	}
+	require.Equal(t, map[string]int64{
+		"hugepages-2mi-aaaaaa": 64 * 1024 * 1024,
+		"hugepages-2mi-bbbbbb": 32 * 1024 * 1024,
+		"memory-cccccc":        1 << 30,
+	}, trk.RemainingBytes(spans))
+
+	trk.RegisterClaim(k8stypes.UID("foo"), map[string]types.Allocation{
+		"hugepages-2m": {
+			ResourceIdent: hp2m,
+			Amount:        24 * 1024 * 1024,
+			AmountByZone:  map[int64]int64{0: 24 * 1024 * 1024},
+		},
+	})
+	// the allocations can exceed the span if it shrank after a refresh
+	trk.RegisterClaim(k8stypes.UID("bar"), map[string]types.Allocation{
+		"hugepages-2m": {
+			ResourceIdent: hp2m,
+			Amount:        48 * 1024 * 1024,
+			AmountByZone:  map[int64]int64{1: 48 * 1024 * 1024},
+		},
+	})
+	require.Equal(t, map[string]int64{
+		"hugepages-2mi-aaaaaa": 40 * 1024 * 1024,
+		"hugepages-2mi-bbbbbb": 0,
+		"memory-cccccc":        1 << 30,
+	}, trk.RemainingBytes(spans))
+
+	// the split pools are accounted by sub-pool
+	dpdk := types.Span{ResourceIdent: hp2m, Amount: 16 * 1024 * 1024, NUMAZone: 0, Pool: "dpdk"}
+	general := types.Span{ResourceIdent: hp2m, Amount: 48 * 1024 * 1024, NUMAZone: 0, Pool: "general"}
+	trk.UnregisterClaim(k8stypes.UID("foo"))
+	trk.RegisterClaim(k8stypes.UID("baz"), map[string]types.Allocation{
+		"hugepages-2m": dpdk.MakeAllocation(8 * 1024 * 1024),
+	})
+	require.Equal(t, map[string]int64{
+		"hugepages-2mi-dddddd": 8 * 1024 * 1024,
+		"hugepages-2mi-eeeeee": 48 * 1024 * 1024,
+	}, trk.RemainingBytes(map[string]types.Span{
+		"hugepages-2mi-dddddd": dpdk,
+		"hugepages-2mi-eeeeee": general,
+	}))
+}
+
+func TestConcurrentClaimsAndPods(t *testing.T) {
+	lh := testr.New(t)
+	trk := NewTracker()
+
+	var wg sync.WaitGroup
+	for idx := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suffix := strconv.Itoa(idx)
+			claimUID := k8stypes.UID("claim-" + suffix)
+			trk.RegisterClaim(claimUID, map[string]types.Allocation{
+				"memory": {
+					ResourceIdent: types.ResourceIdent{
+						Kind:     types.Memory,
+						Pagesize: 4 * 1024,
+					},
+					Amount:       4 * 1024,
+					AmountByZone: map[int64]int64{0: 4 * 1024},
+				},
+			})
+			trk.ReserveClaim(claimUID, "pod-UID-"+suffix)
+			trk.BindContainer(lh, "pod-SandboxID-"+suffix, "ctr", claimUID)
+			_, _ = trk.GetAllocationsForContainer("pod-SandboxID-"+suffix, "ctr")
+			_ = trk.GetAllocationsForPod("pod-UID-" + suffix)
+			_ = trk.AllocatedBytes()
+			if idx%2 == 0 {
+				trk.CleanupPod(lh, "pod-SandboxID-"+suffix)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 32, trk.CountClaims())
+	require.Equal(t, 32, trk.CountPods())
+	require.Empty(t, trk.GetClaimsForPod("pod-UID-0"))
+	require.Equal(t, []k8stypes.UID{"claim-1"}, trk.GetClaimsForPod("pod-UID-1"))
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capabilities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The capabilities document what the driver publishes on a node: the resources, the attributes and the
+// capacities of the devices, and the optional features enabled. They are meant for the platform teams,
+// to generate the user documentation and the validation tooling; the ResourceSlices remain the source of truth.
+// Each driver instance maintains the ConfigMap of its node, owned by the node so it goes away with it.
+
+const (
+	// DataKey is the key of the ConfigMap data holding the capabilities, as JSON.
+	DataKey = "capabilities.json"
+	// LabelNode is set on the ConfigMaps to the name of their node, to select them all or the one of a node.
+	LabelNode = "dra.memory/capabilities-node"
+
+	configMapPrefix = "dramemory-capabilities-"
+)
+
+// Resource is a resource published on the node, like memory or hugepages-2Mi.
+type Resource struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	PageSize string `json:"pageSize"`
+	// NUMAZones are the NUMA zones with devices of the resource
+	NUMAZones []int64 `json:"numaZones"`
+	Devices   int     `json:"devices"`
+}
+
+type Capabilities struct {
+	Driver    string     `json:"driver"`
+	Node      string     `json:"node"`
+	Resources []Resource `json:"resources"`
+	// Attributes and Capacities are the names of the ones published by any device
+	Attributes []string `json:"attributes"`
+	Capacities []string `json:"capacities"`
+	// Features tells which optional features of the driver are enabled, by name
+	Features map[string]bool `json:"features"`
+}
+
+// FromDevices returns the capabilities of the published devices, whose spans are found by device name in `spans`.
+// The devices without span are not counted in the resources.
+func FromDevices(driverName, nodeName string, devices []resourceapi.Device, spans map[string]types.Span, features map[string]bool) Capabilities {
+	caps := Capabilities{
+		Driver:     driverName,
+		Node:       nodeName,
+		Resources:  []Resource{},
+		Attributes: []string{},
+		Capacities: []string{},
+		Features:   maps.Clone(features),
+	}
+	if caps.Features == nil {
+		caps.Features = map[string]bool{}
+	}
+	attrNames := make(map[string]struct{})
+	capNames := make(map[string]struct{})
+	resByName := make(map[string]*Resource)
+	for _, dev := range devices {
+		for name := range dev.Attributes {
+			attrNames[string(name)] = struct{}{}
+		}
+		for name := range dev.Capacity {
+			capNames[string(name)] = struct{}{}
+		}
+		span, ok := spans[dev.Name]
+		if !ok {
+			continue
+		}
+		res, ok := resByName[span.Name()]
+		if !ok {
+			res = &Resource{
+				Name:     span.Name(),
+				Kind:     string(span.Kind),
+				PageSize: span.PagesizeString(),
+			}
+			resByName[span.Name()] = res
+		}
+		res.Devices++
+		if !slices.Contains(res.NUMAZones, span.NUMAZone) {
+			res.NUMAZones = append(res.NUMAZones, span.NUMAZone)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(resByName)) {
+		res := resByName[name]
+		slices.Sort(res.NUMAZones)
+		caps.Resources = append(caps.Resources, *res)
+	}
+	caps.Attributes = append(caps.Attributes, slices.Sorted(maps.Keys(attrNames))...)
+	caps.Capacities = append(caps.Capacities, slices.Sorted(maps.Keys(capNames))...)
+	return caps
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the capabilities of the node.
+func ConfigMapName(nodeName string) string {
+	return configMapPrefix + nodeName
+}
+
+// Publish creates or updates the ConfigMap holding the capabilities of the node in `namespace`.
+func Publish(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, namespace string, caps Capabilities) error {
+	data, err := json.MarshalIndent(caps, "", "  ")
+	if err != nil {
+		return err
+	}
+	node, err := cli.CoreV1().Nodes().Get(ctx, caps.Node, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting the node %q: %w", caps.Node, err)
+	}
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName(caps.Node),
+			Namespace: namespace,
+			Labels: map[string]string{
+				LabelNode: caps.Node,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       node.Name,
+					UID:        node.UID,
+				},
+			},
+		},
+		Data: map[string]string{
+			DataKey: string(data),
+		},
+	}
+
+	cms := cli.CoreV1().ConfigMaps(namespace)
+	_, err = cms.Create(ctx, desired, metav1.CreateOptions{})
+	if err == nil {
+		lh.V(2).Info("capabilities created", "namespace", namespace, "name", desired.Name)
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	current, err := cms.Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if maps.Equal(current.Data, desired.Data) && maps.Equal(current.Labels, desired.Labels) && slices.Equal(current.OwnerReferences, desired.OwnerReferences) {
+		lh.V(4).Info("capabilities up to date", "namespace", namespace, "name", desired.Name)
+		return nil
+	}
+	current.Data = desired.Data
+	current.Labels = desired.Labels
+	current.OwnerReferences = desired.OwnerReferences
+	_, err = cms.Update(ctx, current, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("capabilities updated", "namespace", namespace, "name", desired.Name)
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capabilities
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func makeDevice(name string, attrs ...resourceapi.QualifiedName) resourceapi.Device {
+	dev := resourceapi.Device{
+		Name:       name,
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+		Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
+			types.CapacityNameSize: {},
+		},
+	}
+	for _, attr := range attrs {
+		dev.Attributes[attr] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	return dev
+}
+
+func TestFromDevices(t *testing.T) {
+	memIdent := types.ResourceIdent{Kind: types.Memory, Pagesize: 4 * (1 << 10)}
+	hpIdent := types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)}
+	spans := map[string]types.Span{
+		"memory-1":        {ResourceIdent: memIdent, NUMAZone: 1},
+		"memory-0":        {ResourceIdent: memIdent, NUMAZone: 0},
+		"hugepages-2m-0":  {ResourceIdent: hpIdent, NUMAZone: 0},
+		"hugepages-2m-0b": {ResourceIdent: hpIdent, NUMAZone: 0, Pool: "dpdk"},
+	}
+	devices := []resourceapi.Device{
+		makeDevice("memory-1", "numaNode", "softLimit"),
+		makeDevice("memory-0", "numaNode", "softLimit"),
+		makeDevice("hugepages-2m-0", "numaNode"),
+		makeDevice("hugepages-2m-0b", "numaNode", "pool"),
+		makeDevice("unknown", "mystery"),
+	}
+	features := map[string]bool{"admissionPolicy": true, "pagesCapacity": false}
+
+	got := FromDevices("dra.memory", "node-0", devices, spans, features)
+	require.Equal(t, Capabilities{
+		Driver: "dra.memory",
+		Node:   "node-0",
+		Resources: []Resource{
+			{Name: "hugepages-2Mi", Kind: "hugepages", PageSize: "2Mi", NUMAZones: []int64{0}, Devices: 2},
+			{Name: "memory", Kind: "memory", PageSize: "4Ki", NUMAZones: []int64{0, 1}, Devices: 2},
+		},
+		Attributes: []string{"mystery", "numaNode", "pool", "softLimit"},
+		Capacities: []string{"size"},
+		Features:   features,
+	}, got)
+
+	empty := FromDevices("dra.memory", "node-0", nil, nil, nil)
+	data, err := json.Marshal(empty)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"driver":"dra.memory","node":"node-0","resources":[],"attributes":[],"capacities":[],"features":{}}`, string(data))
+}
+
+func TestPublish(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+	cli := fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-0",
+			UID:  k8stypes.UID("node-uid"),
+		},
+	})
+	caps := Capabilities{
+		Driver:    "dra.memory",
+		Node:      "node-0",
+		Resources: []Resource{{Name: "memory", Kind: "memory", PageSize: "4Ki", NUMAZones: []int64{0}, Devices: 1}},
+		Features:  map[string]bool{"admissionPolicy": false},
+	}
+
+	getCaps := func() (*corev1.ConfigMap, Capabilities) {
+		t.Helper()
+		cm, err := cli.CoreV1().ConfigMaps("kube-system").Get(ctx, ConfigMapName("node-0"), metav1.GetOptions{})
+		require.NoError(t, err)
+		var got Capabilities
+		require.NoError(t, json.Unmarshal([]byte(cm.Data[DataKey]), &got))
+		return cm, got
+	}
+
+	require.NoError(t, Publish(ctx, lh, cli, "kube-system", caps))
+	cm, got := getCaps()
+	require.Equal(t, caps, got)
+	require.Equal(t, "node-0", cm.Labels[LabelNode])
+	require.Len(t, cm.OwnerReferences, 1)
+	require.Equal(t, k8stypes.UID("node-uid"), cm.OwnerReferences[0].UID)
+
+	// unchanged, nothing to do
+	require.NoError(t, Publish(ctx, lh, cli, "kube-system", caps))
+
+	caps.Features["admissionPolicy"] = true
+	require.NoError(t, Publish(ctx, lh, cli, "kube-system", caps))
+	_, got = getCaps()
+	require.True(t, got.Features["admissionPolicy"])
+
+	require.Error(t, Publish(ctx, lh, cli, "kube-system", Capabilities{Node: "missing"}))
+}
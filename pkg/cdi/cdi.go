@@ -20,14 +20,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 
 	"github.com/go-logr/logr"
 	cdiSpec "tags.cncf.io/container-device-interface/specs-go"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const (
@@ -39,21 +43,34 @@ const (
 
 var (
 	SpecDir = "/var/run/cdi"
+
+	// envNameRE matches the names portable to all the shells, per POSIX.1-2017 section 8.1
+	envNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 )
 
-// Manager manages a single CDI JSON spec file using a mutex for thread safety.
+// lockShards is the number of locks serializing the updates of the device spec files.
+// Updates of different devices rarely contend; the locks only order the updates of the same device.
+const lockShards = 64
+
+// Manager manages the CDI JSON spec files of the driver. Each device lives in its own spec file,
+// so preparing many claims concurrently doesn't serialize on the rewrite of a shared file.
+// The shared spec file is kept for the devices added by older versions of the driver.
 type Manager struct {
 	path       string
-	mutex      sync.Mutex
 	cdiKind    string
 	driverName string
+	devLocks   [lockShards]sync.Mutex
+	// mutex protects the shared spec file and legacyDevices
+	mutex sync.Mutex
+	// legacyDevices are the devices found in the shared spec file
+	legacyDevices sets.Set[string]
 }
 
 func MakeKind(vendor, class string) string {
 	return vendor + "/" + class
 }
 
-// NewManager creates a manager for the driver's CDI spec file.
+// NewManager creates a manager for the driver's CDI spec files.
 func NewManager(driverName string, lh logr.Logger) (*Manager, error) {
 	path := filepath.Join(SpecDir, fmt.Sprintf("%s.json", driverName))
 	lh = lh.WithValues("path", path)
@@ -63,68 +80,79 @@ func NewManager(driverName string, lh logr.Logger) (*Manager, error) {
 	}
 
 	mgr := &Manager{
-		path:       path,
-		cdiKind:    MakeKind(Vendor, Class),
-		driverName: driverName,
+		path:          path,
+		cdiKind:       MakeKind(Vendor, Class),
+		driverName:    driverName,
+		legacyDevices: sets.New[string](),
 	}
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if err := mgr.writeSpecToFile(lh, mgr.EmptySpec()); err != nil {
+		if err := mgr.writeSpecToFile(lh, path, mgr.EmptySpec()); err != nil {
 			return nil, err
 		}
 	} else if err != nil {
 		return nil, fmt.Errorf("error accessing CDI spec file %q: %w", path, err)
+	} else if spec, err := mgr.readSpecFromFile(lh, path); err != nil {
+		lh.Error(err, "cannot read the existing devices")
+	} else {
+		for _, dev := range spec.Devices {
+			mgr.legacyDevices.Insert(dev.Name)
+		}
 	}
 
-	lh.Info("Initialized CDI file manager")
+	lh.Info("Initialized CDI file manager", "legacyDevices", mgr.legacyDevices.Len())
 	return mgr, nil
 }
 
-// AddDevice adds a device to the CDI spec file.
+// AddDevice adds a device to the CDI specs.
 func (mgr *Manager) AddDevice(lh logr.Logger, deviceName string, envVars ...string) error {
-	mgr.mutex.Lock()
-	defer mgr.mutex.Unlock()
+	return mgr.AddDeviceWithEdits(lh, deviceName, cdiSpec.ContainerEdits{
+		Env: envVars,
+	})
+}
 
-	lh = lh.WithName("cdi").WithValues("path", mgr.path, "device", deviceName)
+// AddDeviceWithEdits adds a device with arbitrary container edits to the CDI specs.
+func (mgr *Manager) AddDeviceWithEdits(lh logr.Logger, deviceName string, edits cdiSpec.ContainerEdits) error {
+	devLock := mgr.deviceLock(deviceName)
+	devLock.Lock()
+	defer devLock.Unlock()
 
-	spec, err := mgr.readSpecFromFile(lh)
-	if err != nil {
-		return err
+	for _, env := range edits.Env {
+		if err := ValidateEnv(env); err != nil {
+			return fmt.Errorf("device %q: %w", deviceName, err)
+		}
 	}
 
-	// Remove any existing device with the same name to make this call idempotent.
-	removeDeviceFromSpec(spec, deviceName)
-	newDevice := cdiSpec.Device{
-		Name: deviceName,
-		ContainerEdits: cdiSpec.ContainerEdits{
-			Env: envVars,
-		},
+	path := mgr.devicePath(deviceName)
+	lh = lh.WithName("cdi").WithValues("path", path, "device", deviceName)
+
+	// The same device in two spec files is a conflict for the CDI registry.
+	if err := mgr.removeLegacyDevice(lh, deviceName); err != nil {
+		return err
 	}
 
-	spec.Devices = append(spec.Devices, newDevice)
-	return mgr.writeSpecToFile(lh, spec)
+	spec := mgr.EmptySpec()
+	spec.Devices = append(spec.Devices, cdiSpec.Device{
+		Name:           deviceName,
+		ContainerEdits: edits,
+	})
+	return mgr.writeSpecToFile(lh, path, spec)
 }
 
-// RemoveDevice removes a device from the CDI spec file.
+// RemoveDevice removes a device from the CDI specs.
 func (mgr *Manager) RemoveDevice(lh logr.Logger, deviceName string) error {
-	mgr.mutex.Lock()
-	defer mgr.mutex.Unlock()
+	devLock := mgr.deviceLock(deviceName)
+	devLock.Lock()
+	defer devLock.Unlock()
 
-	lh = lh.WithName("cdi").WithValues("path", mgr.path, "device", deviceName)
+	path := mgr.devicePath(deviceName)
+	lh = lh.WithName("cdi").WithValues("path", path, "device", deviceName)
 
-	spec, err := mgr.readSpecFromFile(lh)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil // File already gone, nothing to do.
-		}
-		return err
-	}
-
-	if removeDeviceFromSpec(spec, deviceName) {
-		return mgr.writeSpecToFile(lh, spec)
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error removing CDI spec file %q: %w", path, err)
 	}
-
-	return nil
+	return mgr.removeLegacyDevice(lh, deviceName)
 }
 
 func (mgr *Manager) EmptySpec() *cdiSpec.Spec {
@@ -135,9 +163,76 @@ func (mgr *Manager) EmptySpec() *cdiSpec.Spec {
 	}
 }
 
+// GetSpec returns all the devices of the driver as a single spec: first the devices of
+// the shared spec file, then the devices of the device spec files, sorted by file name.
 func (mgr *Manager) GetSpec(lh logr.Logger) (*cdiSpec.Spec, error) {
 	lh = lh.WithName("cdi").WithValues("path", mgr.path)
-	return mgr.readSpecFromFile(lh)
+
+	mgr.mutex.Lock()
+	spec, err := mgr.readSpecFromFile(lh, mgr.path)
+	mgr.mutex.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		spec, err = mgr.EmptySpec(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(SpecDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CDI spec directory %q: %w", SpecDir, err)
+	}
+	prefix := mgr.driverName + "_"
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		devSpec, err := mgr.readSpecFromFile(lh, filepath.Join(SpecDir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue // removed meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		spec.Devices = append(spec.Devices, devSpec.Devices...)
+	}
+	return spec, nil
+}
+
+func (mgr *Manager) devicePath(deviceName string) string {
+	return filepath.Join(SpecDir, mgr.driverName+"_"+deviceName+".json")
+}
+
+func (mgr *Manager) deviceLock(deviceName string) *sync.Mutex {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(deviceName))
+	return &mgr.devLocks[hash.Sum32()%lockShards]
+}
+
+// removeLegacyDevice removes the device from the shared spec file, if it was added there by an older version.
+func (mgr *Manager) removeLegacyDevice(lh logr.Logger, deviceName string) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if !mgr.legacyDevices.Has(deviceName) {
+		return nil
+	}
+
+	spec, err := mgr.readSpecFromFile(lh, mgr.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			mgr.legacyDevices.Delete(deviceName)
+			return nil // File already gone, nothing to do.
+		}
+		return err
+	}
+
+	if removeDeviceFromSpec(spec, deviceName) {
+		if err := mgr.writeSpecToFile(lh, mgr.path, spec); err != nil {
+			return err
+		}
+	}
+	mgr.legacyDevices.Delete(deviceName)
+	return nil
 }
 
 func removeDeviceFromSpec(spec *cdiSpec.Spec, deviceName string) bool {
@@ -154,10 +249,10 @@ func removeDeviceFromSpec(spec *cdiSpec.Spec, deviceName string) bool {
 	return deviceFound
 }
 
-func (c *Manager) readSpecFromFile(lh logr.Logger) (*cdiSpec.Spec, error) {
-	data, err := os.ReadFile(c.path)
+func (c *Manager) readSpecFromFile(lh logr.Logger, path string) (*cdiSpec.Spec, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("error reading CDI spec file %q: %w", c.path, err)
+		return nil, fmt.Errorf("error reading CDI spec file %q: %w", path, err)
 	}
 
 	if len(data) == 0 {
@@ -170,14 +265,14 @@ func (c *Manager) readSpecFromFile(lh logr.Logger) (*cdiSpec.Spec, error) {
 
 	spec := &cdiSpec.Spec{}
 	if err := json.Unmarshal(data, spec); err != nil {
-		return nil, fmt.Errorf("error unmarshaling CDI spec from %q: %w", c.path, err)
+		return nil, fmt.Errorf("error unmarshaling CDI spec from %q: %w", path, err)
 	}
 	lh.V(2).Info("Read CDI spec", "spec", spec)
 	return spec, nil
 }
 
-func (c *Manager) writeSpecToFile(lh logr.Logger, spec *cdiSpec.Spec) (err error) {
-	lh.V(2).Info("updating CDI spec file", "path", c.path)
+func (c *Manager) writeSpecToFile(lh logr.Logger, path string, spec *cdiSpec.Spec) (err error) {
+	lh.V(2).Info("updating CDI spec file", "path", path)
 
 	tmpFile, err := os.CreateTemp(SpecDir, c.driverName)
 	if err != nil {
@@ -213,7 +308,7 @@ func (c *Manager) writeSpecToFile(lh logr.Logger, spec *cdiSpec.Spec) (err error
 		return fmt.Errorf("failed to close temporary CDI spec: %w", err)
 	}
 
-	if err := os.Rename(tmpFile.Name(), c.path); err != nil {
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
 		return fmt.Errorf("failed to rename temporary CDI spec: %w", err)
 	}
 
@@ -221,6 +316,19 @@ func (c *Manager) writeSpecToFile(lh logr.Logger, spec *cdiSpec.Spec) (err error
 	return nil
 }
 
+// ValidateEnv returns an error if `env` is not a NAME=value variable with a portable name.
+// The runtimes pass along any name, but the shells of the containers may drop or mangle the others.
+func ValidateEnv(env string) error {
+	name, _, ok := strings.Cut(env, "=")
+	if !ok {
+		return fmt.Errorf("malformed env %q: missing value", env)
+	}
+	if !envNameRE.MatchString(name) {
+		return fmt.Errorf("malformed env %q: name not portable", env)
+	}
+	return nil
+}
+
 func MakeDeviceName(uid types.UID) string {
 	return fmt.Sprintf("claim-%s", uid)
 }
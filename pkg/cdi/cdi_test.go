@@ -17,8 +17,11 @@
 package cdi
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/go-logr/logr/testr"
@@ -137,6 +140,49 @@ func TestAddDevice(t *testing.T) {
 	}
 }
 
+func TestAddDeviceWithEdits(t *testing.T) {
+	saveCDIDir := SpecDir
+	t.Cleanup(func() {
+		SpecDir = saveCDIDir
+	})
+	SpecDir = t.TempDir()
+	logger := testr.New(t)
+
+	mgr, err := NewManager(testDriverName, logger)
+	require.NoError(t, err)
+
+	edits := cdiSpec.ContainerEdits{
+		Env: []string{
+			"FOO=42",
+		},
+		Mounts: []*cdiSpec.Mount{
+			{
+				HostPath:      "/run/foo",
+				ContainerPath: "/dev/foo",
+				Type:          "bind",
+				Options:       []string{"rbind", "rw"},
+			},
+		},
+	}
+	require.NoError(t, mgr.AddDeviceWithEdits(logger, "foodev", edits))
+
+	got, err := mgr.GetSpec(logger)
+	require.NoError(t, err)
+	expectedSpec := &cdiSpec.Spec{
+		Version: SpecVersion,
+		Kind:    Vendor + "/" + Class,
+		Devices: []cdiSpec.Device{
+			{
+				Name:           "foodev",
+				ContainerEdits: edits,
+			},
+		},
+	}
+	if diff := cmp.Diff(got, expectedSpec); diff != "" {
+		t.Errorf("unexpected spec: %v", diff)
+	}
+}
+
 func TestRemoveDevice(t *testing.T) {
 	type testcase struct {
 		name         string
@@ -178,18 +224,18 @@ func TestRemoveDevice(t *testing.T) {
 				Kind:    Vendor + "/" + Class,
 				Devices: []cdiSpec.Device{
 					{
-						Name: "foodev",
+						Name: "bardev",
 						ContainerEdits: cdiSpec.ContainerEdits{
 							Env: []string{
-								"FOO=42",
+								"GO=1",
 							},
 						},
 					},
 					{
-						Name: "bardev",
+						Name: "foodev",
 						ContainerEdits: cdiSpec.ContainerEdits{
 							Env: []string{
-								"GO=1",
+								"FOO=42",
 							},
 						},
 					},
@@ -227,6 +273,106 @@ func TestRemoveDevice(t *testing.T) {
 	}
 }
 
+func TestDeviceSpecFiles(t *testing.T) {
+	saveCDIDir := SpecDir
+	t.Cleanup(func() {
+		SpecDir = saveCDIDir
+	})
+	SpecDir = t.TempDir()
+	logger := testr.New(t)
+
+	mgr, err := NewManager(testDriverName, logger)
+	require.NoError(t, err)
+
+	require.NoError(t, mgr.AddDevice(logger, "foodev", "FOO=42"))
+	devPath := filepath.Join(SpecDir, testDriverName+"_foodev.json")
+	_, err = os.Stat(devPath)
+	require.NoError(t, err)
+
+	// the shared spec file must not be touched
+	data, err := os.ReadFile(filepath.Join(SpecDir, testDriverName+".json"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "foodev")
+
+	require.NoError(t, mgr.RemoveDevice(logger, "foodev"))
+	_, err = os.Stat(devPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestLegacyDevices(t *testing.T) {
+	saveCDIDir := SpecDir
+	t.Cleanup(func() {
+		SpecDir = saveCDIDir
+	})
+	SpecDir = t.TempDir()
+	logger := testr.New(t)
+
+	// devices added in the shared spec file by older versions
+	legacy := `{"cdiVersion":"0.8.0","kind":"dra.k8s.io/memory","devices":[` +
+		`{"name":"olddev","containerEdits":{"env":["OLD=1"]}},` +
+		`{"name":"foodev","containerEdits":{"env":["FOO=1"]}}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(SpecDir, testDriverName+".json"), []byte(legacy), 0644))
+
+	mgr, err := NewManager(testDriverName, logger)
+	require.NoError(t, err)
+
+	// adding again a legacy device moves it in its own file
+	require.NoError(t, mgr.AddDevice(logger, "foodev", "FOO=42"))
+	require.NoError(t, mgr.RemoveDevice(logger, "olddev"))
+
+	got, err := mgr.GetSpec(logger)
+	require.NoError(t, err)
+	expectedSpec := &cdiSpec.Spec{
+		Version: SpecVersion,
+		Kind:    Vendor + "/" + Class,
+		Devices: []cdiSpec.Device{
+			{
+				Name: "foodev",
+				ContainerEdits: cdiSpec.ContainerEdits{
+					Env: []string{
+						"FOO=42",
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(got, expectedSpec); diff != "" {
+		t.Errorf("unexpected spec: %v", diff)
+	}
+}
+
+func TestConcurrentDevices(t *testing.T) {
+	saveCDIDir := SpecDir
+	t.Cleanup(func() {
+		SpecDir = saveCDIDir
+	})
+	SpecDir = t.TempDir()
+	logger := testr.New(t)
+
+	mgr, err := NewManager(testDriverName, logger)
+	require.NoError(t, err)
+
+	errs := make([]error, 32)
+	var wg sync.WaitGroup
+	for idx := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devName := "dev" + strconv.Itoa(idx)
+			errs[idx] = mgr.AddDevice(logger, devName, "IDX="+strconv.Itoa(idx))
+			if errs[idx] == nil && idx%2 == 0 {
+				errs[idx] = mgr.RemoveDevice(logger, devName)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, errors.Join(errs...))
+
+	got, err := mgr.GetSpec(logger)
+	require.NoError(t, err)
+	require.Len(t, got.Devices, 16)
+}
+
 func TestRemoveDeviceNotFound(t *testing.T) {
 	saveCDIDir := SpecDir
 	t.Cleanup(func() {
@@ -303,3 +449,28 @@ func TestEmptySpec(t *testing.T) {
 	require.Equal(t, Vendor+"/"+Class, spec.Kind)
 	require.Empty(t, spec.Devices)
 }
+
+func TestValidateEnv(t *testing.T) {
+	for _, env := range []string{"FOO=42", "FIZZ_42=buzz", "_X=", "DRAMEMORY_C5D41402ABC4B2A76_NUMA_NODES=0,1"} {
+		require.NoError(t, ValidateEnv(env), "env %q", env)
+	}
+	for _, env := range []string{"FOO", "=42", "42FOO=1", "DRAMEMORY_3f2b8e2a-6c1d_NUMANodes=0", "FOO.BAR=1"} {
+		require.Error(t, ValidateEnv(env), "env %q", env)
+	}
+}
+
+func TestAddDeviceMalformedEnv(t *testing.T) {
+	saveCDIDir := SpecDir
+	t.Cleanup(func() {
+		SpecDir = saveCDIDir
+	})
+	SpecDir = t.TempDir()
+	logger := testr.New(t)
+
+	mgr, err := NewManager(testDriverName, logger)
+	require.NoError(t, err)
+
+	require.Error(t, mgr.AddDevice(logger, "foodev", "FOO=42", "BAR-BAZ=1"))
+	_, err = os.Stat(mgr.devicePath("foodev"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
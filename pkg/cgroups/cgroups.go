@@ -94,10 +94,23 @@ func WriteValue(lh logr.Logger, dir, file string, val int64) error {
 	return WriteFile(lh, dir, file, value)
 }
 
+// Writer writes the values of the cgroup files, like WriteValue. The driver running unprivileged
+// delegates the writes to the privileged helper.
+type Writer interface {
+	WriteValue(lh logr.Logger, dir, file string, val int64) error
+}
+
+// LocalWriter writes the values from the calling process.
+type LocalWriter struct{}
+
+func (LocalWriter) WriteValue(lh logr.Logger, dir, file string, val int64) error {
+	return WriteValue(lh, dir, file, val)
+}
+
 func ParseValue(lh logr.Logger, dir, file string) (int64, error) {
 	contentRaw, err := ReadFile(lh, dir, file)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
+		if errors.Is(err, ErrLimitFileMissing) {
 			// assume no controller enabled or mounted -> no limits
 			return -1, nil
 		}
@@ -113,3 +126,29 @@ func ParseValue(lh logr.Logger, dir, file string) (int64, error) {
 	}
 	return val, nil
 }
+
+// ParseKeyValues parses flat keyed files like `memory.events` or `hugetlb.<size>.events`,
+// whose lines are in the form "<key> <value>".
+func ParseKeyValues(lh logr.Logger, dir, file string) (map[string]int64, error) {
+	contentRaw, err := ReadFile(lh, dir, file)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(contentRaw))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line %q in %q", scanner.Text(), file)
+		}
+		val, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse value %q of key %q in %q: %w", fields[1], fields[0], file, err)
+		}
+		ret[fields[0]] = val
+	}
+	return ret, scanner.Err()
+}
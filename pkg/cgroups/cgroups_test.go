@@ -359,3 +359,69 @@ func TestOpenFileEmptyDir(t *testing.T) {
 	_, err := OpenFile(lh, "", "somefile", os.O_RDONLY)
 	require.Error(t, err)
 }
+
+func TestParseKeyValues(t *testing.T) {
+	TestMode = true
+	t.Cleanup(func() { TestMode = false })
+
+	type testcase struct {
+		name        string
+		content     string
+		expected    map[string]int64
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:    "memory events",
+			content: "low 0\nhigh 0\nmax 12\noom 1\noom_kill 1\noom_group_kill 0\n",
+			expected: map[string]int64{
+				"low":            0,
+				"high":           0,
+				"max":            12,
+				"oom":            1,
+				"oom_kill":       1,
+				"oom_group_kill": 0,
+			},
+		},
+		{
+			name:    "hugetlb events",
+			content: "max 3\n",
+			expected: map[string]int64{
+				"max": 3,
+			},
+		},
+		{
+			name:     "empty",
+			content:  "",
+			expected: map[string]int64{},
+		},
+		{
+			name:        "malformed line",
+			content:     "max 3 4\n",
+			expectedErr: true,
+		},
+		{
+			name:        "malformed value",
+			content:     "max foo\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			lh := testr.New(t)
+			tmpDir := t.TempDir()
+			err := os.WriteFile(filepath.Join(tmpDir, "test.events"), []byte(tcase.content), 0644)
+			require.NoError(t, err)
+
+			got, err := ParseKeyValues(lh, tmpDir, "test.events")
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
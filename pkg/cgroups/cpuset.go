@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/utils/cpuset"
+)
+
+const (
+	CPUSetMemsEffective = "cpuset.mems.effective"
+)
+
+// MemsNotAvailable is returned when a cgroup in the hierarchy does not
+// allow some of the requested memory nodes. The kernel would silently
+// intersect the requested set with the effective one, which may lead
+// to an empty or wrong allocation.
+type MemsNotAvailable struct {
+	Path      string
+	Requested cpuset.CPUSet
+	Effective cpuset.CPUSet
+}
+
+func (mna MemsNotAvailable) Error() string {
+	return fmt.Sprintf("memory nodes %q not available in cgroup %q (%s=%q): check the kubelet/cpuset configuration", mna.Requested.Difference(mna.Effective).String(), mna.Path, CPUSetMemsEffective, mna.Effective.String())
+}
+
+// ReadMemsEffective returns the effective memory nodes of the cgroup `dir`.
+// Returns the wrapped ErrLimitFileMissing if the cpuset controller is not enabled.
+func ReadMemsEffective(lh logr.Logger, dir string) (cpuset.CPUSet, error) {
+	content, err := ReadFile(lh, dir, CPUSetMemsEffective)
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	mems, err := cpuset.Parse(strings.TrimSpace(content))
+	if err != nil {
+		return cpuset.CPUSet{}, fmt.Errorf("failed to parse %q in %q: %w", CPUSetMemsEffective, dir, err)
+	}
+	return mems, nil
+}
+
+// ValidateMemsHierarchy walks the cgroup hierarchy starting from `root/cgPath` up to `root`
+// and verifies that all the `numaNodes` are present in the `cpuset.mems.effective` of each
+// level. Levels on which the cpuset controller is not enabled are skipped.
+func ValidateMemsHierarchy(lh logr.Logger, root, cgPath string, numaNodes cpuset.CPUSet) error {
+	root = filepath.Clean(root)
+	dir := filepath.Join(root, filepath.Clean("/"+cgPath))
+	for {
+		mems, err := ReadMemsEffective(lh, dir)
+		switch {
+		case errors.Is(err, ErrLimitFileMissing):
+			lh.V(4).Info("no cpuset controller, skipped", "path", dir)
+		case err != nil:
+			return err
+		case !numaNodes.IsSubsetOf(mems):
+			return MemsNotAvailable{
+				Path:      dir,
+				Requested: numaNodes,
+				Effective: mems,
+			}
+		default:
+			lh.V(4).Info("cpuset memory nodes verified", "path", dir, "effective", mems.String(), "requested", numaNodes.String())
+		}
+		if dir == root {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgroups
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/utils/cpuset"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+func TestValidateMemsHierarchy(t *testing.T) {
+	TestMode = true
+	t.Cleanup(func() { TestMode = false })
+
+	type testcase struct {
+		name        string
+		memsByPath  map[string]string // relative path -> cpuset.mems.effective content
+		cgPath      string
+		numaNodes   cpuset.CPUSet
+		expectedErr bool
+		failingPath string
+	}
+
+	testcases := []testcase{
+		{
+			name: "all levels allow the nodes",
+			memsByPath: map[string]string{
+				".":                 "0-1\n",
+				"kubepods":          "0-1\n",
+				"kubepods/pod1234":  "0-1\n",
+				"kubepods/pod1234x": "0\n",
+			},
+			cgPath:    "kubepods/pod1234",
+			numaNodes: cpuset.New(1),
+		},
+		{
+			name: "missing controller levels are skipped",
+			memsByPath: map[string]string{
+				".":                "0-1\n",
+				"kubepods/pod1234": "0-1\n",
+			},
+			cgPath:    "kubepods/pod1234",
+			numaNodes: cpuset.New(0, 1),
+		},
+		{
+			name: "intermediate level excludes a node",
+			memsByPath: map[string]string{
+				".":                "0-1\n",
+				"kubepods":         "0\n",
+				"kubepods/pod1234": "0-1\n",
+			},
+			cgPath:      "kubepods/pod1234",
+			numaNodes:   cpuset.New(1),
+			expectedErr: true,
+			failingPath: "kubepods",
+		},
+		{
+			name: "malformed content",
+			memsByPath: map[string]string{
+				".": "foo\n",
+			},
+			cgPath:      "",
+			numaNodes:   cpuset.New(0),
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			lh := testr.New(t)
+			spec := fakesys.Spec{
+				Cgroups: []fakesys.Cgroup{{Path: tcase.cgPath}},
+			}
+			for relPath, content := range tcase.memsByPath {
+				spec.Cgroups = append(spec.Cgroups, fakesys.Cgroup{
+					Path:  relPath,
+					Files: map[string]string{CPUSetMemsEffective: content},
+				})
+			}
+			root := filepath.Join(fakesys.Make(t, spec), "sys", "fs", "cgroup")
+
+			err := ValidateMemsHierarchy(lh, root, tcase.cgPath, tcase.numaNodes)
+			if !tcase.expectedErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			if tcase.failingPath == "" {
+				return
+			}
+			var mna MemsNotAvailable
+			require.True(t, errors.As(err, &mna), "unexpected error type: %v", err)
+			require.Equal(t, filepath.Join(root, tcase.failingPath), mna.Path)
+		})
+	}
+}
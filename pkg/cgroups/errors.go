@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgroups
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// The classes of the failures accessing the cgroup files. The callers can tell apart the permanent
+// failures, which need a fix in the node configuration, from the transient ones worth a retry.
+var (
+	// ErrLimitFileMissing: the file does not exist, usually because the controller is not enabled in the cgroup.
+	ErrLimitFileMissing = errors.New("cgroup file missing")
+	// ErrValueRejected: the kernel refused the value written, e.g. out of range or conflicting with the usage.
+	ErrValueRejected = errors.New("cgroup value rejected")
+	// ErrCgroupNotDelegated: the cgroup is not writable by the driver, e.g. read-only mount or missing delegation.
+	ErrCgroupNotDelegated = errors.New("cgroup not delegated")
+)
+
+// Error is a failure accessing a cgroup file. It matches both its class, if recognized, and the underlying error.
+type Error struct {
+	Op   string
+	Path string
+	// Class is one of the Err* errors of this package, or nil if unrecognized, like transient I/O errors.
+	Class error
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Class == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Class, e.Err}
+}
+
+// IsPermanent returns true if the error won't go away retrying the operation.
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrLimitFileMissing) || errors.Is(err, ErrValueRejected) || errors.Is(err, ErrCgroupNotDelegated)
+}
+
+func newError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		Op:    op,
+		Path:  path,
+		Class: classify(op, err),
+		Err:   err,
+	}
+}
+
+func classify(op string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrLimitFileMissing
+	case errors.Is(err, unix.EACCES), errors.Is(err, unix.EPERM), errors.Is(err, unix.EROFS):
+		return ErrCgroupNotDelegated
+	case op == opWrite && (errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ERANGE) || errors.Is(err, unix.EBUSY) || errors.Is(err, unix.EOPNOTSUPP)):
+		return ErrValueRejected
+	default:
+		return nil
+	}
+}
+
+const (
+	opRead  = "read"
+	opWrite = "write"
+)
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgroups
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestClassify(t *testing.T) {
+	type testcase struct {
+		name     string
+		op       string
+		err      error
+		expected error
+	}
+
+	testcases := []testcase{
+		{
+			name:     "missing file on read",
+			op:       opRead,
+			err:      &fs.PathError{Op: "openat2", Path: "hugetlb.2MB.max", Err: unix.ENOENT},
+			expected: ErrLimitFileMissing,
+		},
+		{
+			name:     "missing file on write",
+			op:       opWrite,
+			err:      &fs.PathError{Op: "openat2", Path: "memory.swap.max", Err: unix.ENOENT},
+			expected: ErrLimitFileMissing,
+		},
+		{
+			name:     "read-only mount",
+			op:       opWrite,
+			err:      &fs.PathError{Op: "write", Path: "hugetlb.2MB.max", Err: unix.EROFS},
+			expected: ErrCgroupNotDelegated,
+		},
+		{
+			name:     "permission denied",
+			op:       opWrite,
+			err:      &fs.PathError{Op: "openat2", Path: "hugetlb.2MB.max", Err: unix.EACCES},
+			expected: ErrCgroupNotDelegated,
+		},
+		{
+			name:     "invalid value",
+			op:       opWrite,
+			err:      &fs.PathError{Op: "write", Path: "hugetlb.2MB.max", Err: unix.EINVAL},
+			expected: ErrValueRejected,
+		},
+		{
+			name:     "busy on write",
+			op:       opWrite,
+			err:      &fs.PathError{Op: "write", Path: "cpuset.mems", Err: unix.EBUSY},
+			expected: ErrValueRejected,
+		},
+		{
+			name: "invalid on read",
+			op:   opRead,
+			err:  &fs.PathError{Op: "read", Path: "hugetlb.2MB.max", Err: unix.EINVAL},
+		},
+		{
+			name: "transient I/O error",
+			op:   opWrite,
+			err:  &fs.PathError{Op: "write", Path: "hugetlb.2MB.max", Err: unix.EINTR},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := newError(tcase.op, "/sys/fs/cgroup/test", tcase.err)
+			require.ErrorIs(t, err, tcase.err)
+			var cgErr *Error
+			require.ErrorAs(t, err, &cgErr)
+			require.Equal(t, tcase.expected, cgErr.Class)
+			if tcase.expected != nil {
+				require.ErrorIs(t, err, tcase.expected)
+			}
+			require.Equal(t, tcase.expected != nil, IsPermanent(err))
+		})
+	}
+}
+
+func TestNewErrorNil(t *testing.T) {
+	require.NoError(t, newError(opRead, "/sys/fs/cgroup/test", nil))
+}
+
+func TestReadFileMissing(t *testing.T) {
+	lh := testr.New(t)
+	_, err := ReadFile(lh, t.TempDir(), "hugetlb.2MB.max")
+	require.ErrorIs(t, err, ErrLimitFileMissing)
+	// the callers checking the underlying error keep working
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.True(t, IsPermanent(err))
+}
+
+func TestIsPermanentWrapped(t *testing.T) {
+	err := newError(opWrite, "/sys/fs/cgroup/test", &fs.PathError{Op: "write", Path: "hugetlb.2MB.max", Err: unix.EINVAL})
+	require.True(t, IsPermanent(errors.Join(errors.New("setting the limits"), err)))
+	require.False(t, IsPermanent(errors.New("unrelated")))
+}
@@ -5,6 +5,7 @@
 // - dropped WriteFileByLine
 // - golangci-lint fixes
 // - more logs and wrapped errors
+// - errors classified by ReadFile and WriteFile
 
 package cgroups
 
@@ -37,29 +38,31 @@ func OpenFile(lh logr.Logger, dir, file string, flags int) (*os.File, error) {
 
 // ReadFile reads data from a cgroup file in dir.
 // It is supposed to be used for cgroup files only.
+// The errors are *Error, matching the class of the failure.
 func ReadFile(lh logr.Logger, dir, file string) (string, error) {
 	fd, err := OpenFile(lh, dir, file, unix.O_RDONLY)
 	if err != nil {
-		return "", err
+		return "", newError(opRead, filepath.Join(dir, file), err)
 	}
 	defer fd.Close() //nolint:errcheck
 	var buf bytes.Buffer
 
 	_, err = buf.ReadFrom(fd)
-	return buf.String(), err
+	return buf.String(), newError(opRead, fd.Name(), err)
 }
 
 // WriteFile writes data to a cgroup file in dir.
 // It is supposed to be used for cgroup files only.
+// The errors are *Error, matching the class of the failure.
 func WriteFile(lh logr.Logger, dir, file, data string) error {
 	fd, err := OpenFile(lh, dir, file, unix.O_WRONLY)
 	if err != nil {
-		return err
+		return newError(opWrite, filepath.Join(dir, file), err)
 	}
 	defer fd.Close() //nolint:errcheck
 	if _, err := fd.WriteString(data); err != nil {
 		// Having data in the error message helps in debugging.
-		return fmt.Errorf("failed to write %q: %w", data, err)
+		return newError(opWrite, fd.Name(), fmt.Errorf("failed to write %q: %w", data, err))
 	}
 	return nil
 }
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package claimconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The claims can carry opaque configuration parameters for the driver, in the device
+// class or in the claim itself. The configuration is meant for consumers, like KubeVirt,
+// which need more than plain memory pinning and limits.
+
+const (
+	APIVersion = "dra.memory/v1alpha1"
+
+	KindVirtualMachineMemory = "VirtualMachineMemoryConfig"
+	KindClaimLifetime        = "ClaimLifetimeConfig"
+	KindBurstableMemory      = "BurstableMemoryConfig"
+	KindHugepagesFile        = "HugepagesFileConfig"
+)
+
+// VirtualMachineMemoryConfig describes how the hugepages of a claim back the guest memory of a VM.
+type VirtualMachineMemoryConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// HugeTLBFSMountPath is the path in the container on which a hugetlbfs, sized as the claim, is mounted.
+	// The VMM (e.g. QEMU) can back the guest memory with files on this mount.
+	// +optional
+	HugeTLBFSMountPath string `json:"hugetlbfsMountPath,omitempty"`
+
+	// GuestMemoryOverhead is the part of the claim not given to the guest, e.g. for the VMM internal usage.
+	// +optional
+	GuestMemoryOverhead *resource.Quantity `json:"guestMemoryOverhead,omitempty"`
+
+	// GuestMemoryAlignment is the alignment the guest memory must have, e.g. 1Gi.
+	// The preparation of the claim fails if the guest memory is not aligned.
+	// +optional
+	GuestMemoryAlignment *resource.Quantity `json:"guestMemoryAlignment,omitempty"`
+}
+
+func (cfg *VirtualMachineMemoryConfig) Validate() error {
+	if cfg.HugeTLBFSMountPath != "" && !filepath.IsAbs(cfg.HugeTLBFSMountPath) {
+		return fmt.Errorf("hugetlbfs mount path %q must be absolute", cfg.HugeTLBFSMountPath)
+	}
+	if cfg.GuestMemoryOverhead != nil && cfg.GuestMemoryOverhead.Sign() < 0 {
+		return fmt.Errorf("guest memory overhead %s must be not negative", cfg.GuestMemoryOverhead.String())
+	}
+	if cfg.GuestMemoryAlignment != nil && cfg.GuestMemoryAlignment.Sign() <= 0 {
+		return fmt.Errorf("guest memory alignment %s must be positive", cfg.GuestMemoryAlignment.String())
+	}
+	return nil
+}
+
+// GuestMemory returns the amount of bytes of the claim available to the guest,
+// given the `allocated` bytes of the claim and the `pagesize` backing them.
+func (cfg *VirtualMachineMemoryConfig) GuestMemory(allocated int64, pagesize uint64) (int64, error) {
+	guestMemory := allocated
+	if cfg.GuestMemoryOverhead != nil {
+		guestMemory -= cfg.GuestMemoryOverhead.Value()
+	}
+	if guestMemory <= 0 {
+		return 0, fmt.Errorf("guest memory overhead %s exceeds the claim size %d", cfg.GuestMemoryOverhead.String(), allocated)
+	}
+	if rem := guestMemory % int64(pagesize); rem != 0 {
+		return 0, fmt.Errorf("guest memory %d is not multiple of the page size %d", guestMemory, pagesize)
+	}
+	if cfg.GuestMemoryAlignment != nil {
+		if alignment := cfg.GuestMemoryAlignment.Value(); guestMemory%alignment != 0 {
+			return 0, fmt.Errorf("guest memory %d is not aligned to %s", guestMemory, cfg.GuestMemoryAlignment.String())
+		}
+	}
+	return guestMemory, nil
+}
+
+// ClaimLifetimeConfig tells when the driver considers the claim expired, e.g. leaked by a batch workload.
+type ClaimLifetimeConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// MaxLifetime is the maximum age of the claim, counted from its creation.
+	// +optional
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+
+	// IdleTimeout is the maximum time the prepared claim can stay unused by any container.
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+
+	// TaintForCleanup asks the driver to label the claim once expired, so cleanup tools can select it.
+	// +optional
+	TaintForCleanup bool `json:"taintForCleanup,omitempty"`
+}
+
+func (cfg *ClaimLifetimeConfig) Validate() error {
+	if cfg.MaxLifetime == nil && cfg.IdleTimeout == nil {
+		return errors.New("at least one of maxLifetime and idleTimeout must be set")
+	}
+	if cfg.MaxLifetime != nil && cfg.MaxLifetime.Duration <= 0 {
+		return fmt.Errorf("max lifetime %v must be positive", cfg.MaxLifetime.Duration)
+	}
+	if cfg.IdleTimeout != nil && cfg.IdleTimeout.Duration <= 0 {
+		return fmt.Errorf("idle timeout %v must be positive", cfg.IdleTimeout.Duration)
+	}
+	return nil
+}
+
+// BurstableMemoryConfig makes the memory of the claim a soft limit of the containers consuming it,
+// like the burstable QoS: past the size of the claim the containers are throttled and their memory
+// reclaimed (memory.high), rather than killed. The hard limit is still the one set by the kubelet.
+type BurstableMemoryConfig struct {
+	metav1.TypeMeta `json:",inline"`
+}
+
+// DefaultHugepagesFileName is the name of the file holding the hugepages of the claim, if not set.
+const DefaultHugepagesFileName = "pages"
+
+// HugepagesFileConfig allocates the hugepages of the claim to a file at prepare time, for the consumers
+// backing their memory with files. The pages are taken from the pool before the containers start, so
+// mapping the file can't fail later for lack of pages, whatever the pressure on the pool.
+type HugepagesFileConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// MountPath is the path in the container on which the hugetlbfs holding the file is mounted.
+	MountPath string `json:"mountPath"`
+
+	// FileName is the name of the file on the mount. Defaults to DefaultHugepagesFileName.
+	// +optional
+	FileName string `json:"fileName,omitempty"`
+}
+
+func (cfg *HugepagesFileConfig) Validate() error {
+	if !filepath.IsAbs(cfg.MountPath) {
+		return fmt.Errorf("hugepages file mount path %q must be absolute", cfg.MountPath)
+	}
+	if cfg.FileName != "" && (cfg.FileName != filepath.Base(cfg.FileName) || cfg.FileName == "." || cfg.FileName == "..") {
+		return fmt.Errorf("hugepages file name %q must be a plain file name", cfg.FileName)
+	}
+	return nil
+}
+
+// Name returns the name of the file holding the hugepages.
+func (cfg *HugepagesFileConfig) Name() string {
+	if cfg.FileName == "" {
+		return DefaultHugepagesFileName
+	}
+	return cfg.FileName
+}
+
+// Configs holds the decoded configurations of a claim.
+type Configs struct {
+	// VirtualMachine is the configuration of the VM memory, if any
+	VirtualMachine *VirtualMachineMemoryConfig
+	// Lifetime is the expiration policy of the claim, if any
+	Lifetime *ClaimLifetimeConfig
+	// Burstable makes the memory of the claim a soft limit, if set
+	Burstable *BurstableMemoryConfig
+	// HugepagesFile allocates the hugepages of the claim to a file, if set
+	HugepagesFile *HugepagesFileConfig
+}
+
+// Decode extracts the configuration for the driver `driverName` from the allocated claim.
+// The claim configuration takes precedence over the class configuration, so the last
+// configuration wins, as the list is ordered.
+func Decode(driverName string, claim *resourceapi.ResourceClaim) (Configs, error) {
+	var cfgs Configs
+	if claim.Status.Allocation == nil {
+		return cfgs, nil
+	}
+	for _, devCfg := range claim.Status.Allocation.Devices.Config {
+		if devCfg.Opaque == nil || devCfg.Opaque.Driver != driverName {
+			continue
+		}
+		var meta metav1.TypeMeta
+		err := json.Unmarshal(devCfg.Opaque.Parameters.Raw, &meta)
+		if err != nil {
+			return cfgs, fmt.Errorf("malformed configuration: %w", err)
+		}
+		if meta.APIVersion != APIVersion {
+			return cfgs, fmt.Errorf("unsupported configuration API version %q", meta.APIVersion)
+		}
+		switch meta.Kind {
+		case KindVirtualMachineMemory:
+			vmCfg := VirtualMachineMemoryConfig{}
+			err = json.Unmarshal(devCfg.Opaque.Parameters.Raw, &vmCfg)
+			if err != nil {
+				return cfgs, fmt.Errorf("malformed %s: %w", meta.Kind, err)
+			}
+			err = vmCfg.Validate()
+			if err != nil {
+				return cfgs, fmt.Errorf("invalid %s: %w", meta.Kind, err)
+			}
+			cfgs.VirtualMachine = &vmCfg
+		case KindClaimLifetime:
+			ltCfg := ClaimLifetimeConfig{}
+			err = json.Unmarshal(devCfg.Opaque.Parameters.Raw, &ltCfg)
+			if err != nil {
+				return cfgs, fmt.Errorf("malformed %s: %w", meta.Kind, err)
+			}
+			err = ltCfg.Validate()
+			if err != nil {
+				return cfgs, fmt.Errorf("invalid %s: %w", meta.Kind, err)
+			}
+			cfgs.Lifetime = &ltCfg
+		case KindBurstableMemory:
+			bmCfg := BurstableMemoryConfig{}
+			err = json.Unmarshal(devCfg.Opaque.Parameters.Raw, &bmCfg)
+			if err != nil {
+				return cfgs, fmt.Errorf("malformed %s: %w", meta.Kind, err)
+			}
+			cfgs.Burstable = &bmCfg
+		case KindHugepagesFile:
+			hfCfg := HugepagesFileConfig{}
+			err = json.Unmarshal(devCfg.Opaque.Parameters.Raw, &hfCfg)
+			if err != nil {
+				return cfgs, fmt.Errorf("malformed %s: %w", meta.Kind, err)
+			}
+			err = hfCfg.Validate()
+			if err != nil {
+				return cfgs, fmt.Errorf("invalid %s: %w", meta.Kind, err)
+			}
+			cfgs.HugepagesFile = &hfCfg
+		default:
+			return cfgs, errors.New("unsupported configuration kind: " + meta.Kind)
+		}
+	}
+	// the file takes all the pages of the hugetlbfs, nothing would be left for the VMM
+	if cfgs.HugepagesFile != nil && cfgs.VirtualMachine != nil && cfgs.VirtualMachine.HugeTLBFSMountPath != "" {
+		return cfgs, fmt.Errorf("%s and the hugetlbfs of %s are mutually exclusive", KindHugepagesFile, KindVirtualMachineMemory)
+	}
+	return cfgs, nil
+}
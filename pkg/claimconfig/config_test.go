@@ -0,0 +1,280 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package claimconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func makeClaim(driverName, params string) *resourceapi.ResourceClaim {
+	return &resourceapi.ResourceClaim{
+		Status: resourceapi.ResourceClaimStatus{
+			Allocation: &resourceapi.AllocationResult{
+				Devices: resourceapi.DeviceAllocationResult{
+					Config: []resourceapi.DeviceAllocationConfiguration{
+						{
+							Source: resourceapi.AllocationConfigSourceClaim,
+							DeviceConfiguration: resourceapi.DeviceConfiguration{
+								Opaque: &resourceapi.OpaqueDeviceConfiguration{
+									Driver: driverName,
+									Parameters: runtime.RawExtension{
+										Raw: []byte(params),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type testcase struct {
+		name        string
+		claim       *resourceapi.ResourceClaim
+		expected    Configs
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:  "no allocation",
+			claim: &resourceapi.ResourceClaim{},
+		},
+		{
+			name:  "other driver",
+			claim: makeClaim("gpu.example.com", `{"apiVersion": "gpu.example.com/v1", "kind": "GPUConfig"}`),
+		},
+		{
+			name:  "virtual machine",
+			claim: makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "VirtualMachineMemoryConfig", "hugetlbfsMountPath": "/dev/hugepages-vm", "guestMemoryAlignment": "1Gi"}`),
+			expected: Configs{
+				VirtualMachine: &VirtualMachineMemoryConfig{
+					HugeTLBFSMountPath:   "/dev/hugepages-vm",
+					GuestMemoryAlignment: ptr.To(resource.MustParse("1Gi")),
+				},
+			},
+		},
+		{
+			name:        "unknown version",
+			claim:       makeClaim("dra.memory", `{"apiVersion": "dra.memory/v42", "kind": "VirtualMachineMemoryConfig"}`),
+			expectedErr: true,
+		},
+		{
+			name:        "unknown kind",
+			claim:       makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "FooConfig"}`),
+			expectedErr: true,
+		},
+		{
+			name:        "relative mount path",
+			claim:       makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "VirtualMachineMemoryConfig", "hugetlbfsMountPath": "hugepages"}`),
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Decode("dra.memory", tcase.claim)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tcase.expected.VirtualMachine == nil {
+				require.Nil(t, got.VirtualMachine)
+				return
+			}
+			require.NotNil(t, got.VirtualMachine)
+			require.Equal(t, tcase.expected.VirtualMachine.HugeTLBFSMountPath, got.VirtualMachine.HugeTLBFSMountPath)
+			require.True(t, tcase.expected.VirtualMachine.GuestMemoryAlignment.Equal(*got.VirtualMachine.GuestMemoryAlignment))
+		})
+	}
+}
+
+func TestGuestMemory(t *testing.T) {
+	type testcase struct {
+		name        string
+		cfg         VirtualMachineMemoryConfig
+		allocated   int64
+		pagesize    uint64
+		expected    int64
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:      "no overhead",
+			allocated: 2 << 30,
+			pagesize:  1 << 30,
+			expected:  2 << 30,
+		},
+		{
+			name: "overhead, aligned",
+			cfg: VirtualMachineMemoryConfig{
+				GuestMemoryOverhead:  ptr.To(resource.MustParse("1Gi")),
+				GuestMemoryAlignment: ptr.To(resource.MustParse("1Gi")),
+			},
+			allocated: 3 << 30,
+			pagesize:  2 << 20,
+			expected:  2 << 30,
+		},
+		{
+			name: "overhead, misaligned",
+			cfg: VirtualMachineMemoryConfig{
+				GuestMemoryOverhead:  ptr.To(resource.MustParse("256Mi")),
+				GuestMemoryAlignment: ptr.To(resource.MustParse("1Gi")),
+			},
+			allocated:   3 << 30,
+			pagesize:    2 << 20,
+			expectedErr: true,
+		},
+		{
+			name: "overhead not multiple of the page size",
+			cfg: VirtualMachineMemoryConfig{
+				GuestMemoryOverhead: ptr.To(resource.MustParse("1Mi")),
+			},
+			allocated:   64 << 20,
+			pagesize:    2 << 20,
+			expectedErr: true,
+		},
+		{
+			name: "overhead exceeds the claim",
+			cfg: VirtualMachineMemoryConfig{
+				GuestMemoryOverhead: ptr.To(resource.MustParse("1Gi")),
+			},
+			allocated:   1 << 30,
+			pagesize:    1 << 30,
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := tcase.cfg.GuestMemory(tcase.allocated, tcase.pagesize)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
+func TestDecodeLifetime(t *testing.T) {
+	type testcase struct {
+		name        string
+		params      string
+		expected    *ClaimLifetimeConfig
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:   "max lifetime",
+			params: `{"apiVersion": "dra.memory/v1alpha1", "kind": "ClaimLifetimeConfig", "maxLifetime": "24h", "taintForCleanup": true}`,
+			expected: &ClaimLifetimeConfig{
+				MaxLifetime:     &metav1.Duration{Duration: 24 * time.Hour},
+				TaintForCleanup: true,
+			},
+		},
+		{
+			name:   "idle timeout",
+			params: `{"apiVersion": "dra.memory/v1alpha1", "kind": "ClaimLifetimeConfig", "idleTimeout": "30m"}`,
+			expected: &ClaimLifetimeConfig{
+				IdleTimeout: &metav1.Duration{Duration: 30 * time.Minute},
+			},
+		},
+		{
+			name:        "no timeouts",
+			params:      `{"apiVersion": "dra.memory/v1alpha1", "kind": "ClaimLifetimeConfig", "taintForCleanup": true}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative idle timeout",
+			params:      `{"apiVersion": "dra.memory/v1alpha1", "kind": "ClaimLifetimeConfig", "idleTimeout": "-1m"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "malformed duration",
+			params:      `{"apiVersion": "dra.memory/v1alpha1", "kind": "ClaimLifetimeConfig", "maxLifetime": "one day"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Decode("dra.memory", makeClaim("dra.memory", tcase.params))
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Nil(t, got.VirtualMachine)
+			require.NotNil(t, got.Lifetime)
+			got.Lifetime.TypeMeta = metav1.TypeMeta{}
+			require.Equal(t, tcase.expected, got.Lifetime)
+		})
+	}
+}
+
+func TestDecodeBurstable(t *testing.T) {
+	got, err := Decode("dra.memory", makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "BurstableMemoryConfig"}`))
+	require.NoError(t, err)
+	require.NotNil(t, got.Burstable)
+	require.Nil(t, got.VirtualMachine)
+	require.Nil(t, got.Lifetime)
+}
+
+func TestDecodeHugepagesFile(t *testing.T) {
+	got, err := Decode("dra.memory", makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app"}`))
+	require.NoError(t, err)
+	require.NotNil(t, got.HugepagesFile)
+	require.Equal(t, "/dev/hugepages-app", got.HugepagesFile.MountPath)
+	require.Equal(t, DefaultHugepagesFileName, got.HugepagesFile.Name())
+
+	got, err = Decode("dra.memory", makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app", "fileName": "heap"}`))
+	require.NoError(t, err)
+	require.Equal(t, "heap", got.HugepagesFile.Name())
+
+	for _, params := range []string{
+		`{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig"}`,
+		`{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app", "fileName": "../heap"}`,
+		`{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app", "fileName": ".."}`,
+	} {
+		_, err = Decode("dra.memory", makeClaim("dra.memory", params))
+		require.Error(t, err, "params %s", params)
+	}
+
+	// both would use the same hugetlbfs instance
+	claim := makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app"}`)
+	vmClaim := makeClaim("dra.memory", `{"apiVersion": "dra.memory/v1alpha1", "kind": "VirtualMachineMemoryConfig", "hugetlbfsMountPath": "/dev/hugepages-vm"}`)
+	claim.Status.Allocation.Devices.Config = append(claim.Status.Allocation.Devices.Config, vmClaim.Status.Allocation.Devices.Config...)
+	_, err = Decode("dra.memory", claim)
+	require.Error(t, err)
+}
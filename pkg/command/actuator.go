@@ -0,0 +1,67 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/ffromani/dra-driver-memory/pkg/actuator"
+)
+
+const (
+	ActuatorCommand = "actuator"
+
+	// actuatorWaitTimeout bounds the wait for the helper at startup; the containers of the pod start concurrently
+	actuatorWaitTimeout = 30 * time.Second
+)
+
+// RunActuator runs the privileged helper, like `dramemory actuator`: it serves the cgroup and hugepages pool
+// writes and the hugetlbfs mounts the daemon running unprivileged delegates, and nothing else. It talks neither to the API server
+// nor to the runtime, so it needs no credentials.
+func RunActuator(ctx context.Context, params Params, logger logr.Logger) error {
+	if params.ActuatorSocket == "" {
+		return errors.New("missing actuator socket")
+	}
+	err := params.ResolveCgroupMount(logger)
+	if err != nil {
+		return err
+	}
+	srv := actuator.NewServer(params.ActuatorSocket, params.ActuatorSocketGID, params.CgroupMount, params.SysRoot)
+	return srv.Run(ctx, logger.WithName("actuator"))
+}
+
+// connectActuator waits for the helper on the actuator socket to be serving.
+func connectActuator(ctx context.Context, logger logr.Logger, socketPath string) (*actuator.Client, error) {
+	cli := actuator.NewClient(socketPath)
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, time.Second, actuatorWaitTimeout, true, func(ctx context.Context) (bool, error) {
+		lastErr = cli.Ping(ctx)
+		return lastErr == nil, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("actuator not serving: %w", errors.Join(err, lastErr))
+	}
+	logger.Info("privileged writes delegated to the actuator", "socketPath", socketPath)
+	return cli, nil
+}
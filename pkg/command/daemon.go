@@ -18,6 +18,7 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -25,6 +26,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/errgroup"
 
@@ -34,7 +36,16 @@ import (
 	nodeutil "k8s.io/component-helpers/node/util"
 	"k8s.io/klog/v2/textlogger"
 
+	"github.com/ffromani/dra-driver-memory/pkg/adminapi"
+	"github.com/ffromani/dra-driver-memory/pkg/admission"
+	"github.com/ffromani/dra-driver-memory/pkg/agentapi"
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
 	"github.com/ffromani/dra-driver-memory/pkg/driver"
+	"github.com/ffromani/dra-driver-memory/pkg/enforcement"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/subpools"
+	"github.com/ffromani/dra-driver-memory/pkg/hugetlbfs"
 	"github.com/ffromani/dra-driver-memory/pkg/kloglevel"
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
 )
@@ -53,16 +64,24 @@ func (f SysinfoDiscovererFunc) Discover() (sysinfo.MachineData, error) {
 
 func RunDaemon(ctx context.Context, params Params, drvLogger logr.Logger) error {
 	var ready atomic.Bool
+	var dramem *driver.MemoryDriver
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		if !ready.Load() {
+		// dramem is set before ready, so it's safe to access once ready
+		if !ready.Load() || !dramem.IsNRIConnected() {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		} else {
 			w.WriteHeader(http.StatusOK)
 		}
 	})
 	mux.Handle("/metrics", promhttp.Handler())
+	ver, _ := GetVersion()
+	registerBuildInfo(ver)
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ver) // the client went away, nothing to do
+	})
 	server := &http.Server{
 		Addr:              params.BindAddress,
 		Handler:           mux,
@@ -90,56 +109,207 @@ func RunDaemon(ctx context.Context, params Params, drvLogger logr.Logger) error
 		return server.Shutdown(shutdownCtx)
 	})
 
-	var err error
-	var config *rest.Config
-	if params.Kubeconfig != "" {
-		config, err = clientcmd.BuildConfigFromFlags("", params.Kubeconfig)
-	} else {
-		config, err = rest.InClusterConfig()
-	}
+	clientset, err := newClientset(params)
 	if err != nil {
-		return fmt.Errorf("cannot create client-go configuration: %w", err)
+		return err
 	}
 
-	// use protobuf for better performance at scale
-	// https://kubernetes.io/docs/reference/using-api/api-concepts/#alternate-representations-of-resources
-	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
-	config.ContentType = "application/vnd.kubernetes.protobuf"
+	nodeName, err := getNodeName(params)
+	if err != nil {
+		return err
+	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	// params is our own copy, the resolved mount point is seen by the runtime checks too
+	err = params.ResolveCgroupMount(drvLogger)
 	if err != nil {
-		return fmt.Errorf("cannot create client-go client: %w", err)
+		return err
 	}
 
-	nodeName, err := nodeutil.GetHostname(params.HostnameOverride)
+	var admissionPolicy *admission.Policy
+	if params.AdmissionPolicy != "" {
+		admissionPolicy, err = admission.LoadPolicy(params.AdmissionPolicy)
+		if err != nil {
+			return fmt.Errorf("cannot load the admission policy: %w", err)
+		}
+	}
+
+	var hpPools *subpools.Config
+	if params.HugepagesPools != "" {
+		hpPools, err = subpools.Load(params.HugepagesPools)
+		if err != nil {
+			return fmt.Errorf("cannot load the hugepages pools: %w", err)
+		}
+	}
+
+	hpExclusions, err := params.HugepagesExclusions()
 	if err != nil {
-		return fmt.Errorf("cannot obtain the node name, use the hostname-override flag if you want to set it to a specific value: %w", err)
+		return fmt.Errorf("cannot read the excluded hugepages: %w", err)
+	}
+
+	var cgWriter cgroups.Writer
+	var poolWriter reserve.PoolWriter
+	var hpMounter hugetlbfs.Mounter
+	if params.ActuatorSocket != "" {
+		cli, err := connectActuator(ctx, drvLogger, params.ActuatorSocket)
+		if err != nil {
+			return err
+		}
+		cgWriter, poolWriter, hpMounter = cli, cli, cli
 	}
 
 	driverEnv := driver.Environment{
-		DriverName:  driver.Name,
-		NodeName:    nodeName,
-		Clientset:   clientset,
-		Logger:      drvLogger,
-		SysRoot:     params.SysRoot,
-		CgroupMount: params.CgroupMount,
+		DriverName:        driver.Name,
+		NodeName:          nodeName,
+		Clientset:         clientset,
+		Logger:            drvLogger,
+		SysRoot:           params.SysRoot,
+		ProcRoot:          params.ProcRoot,
+		CgroupMount:       params.CgroupMount,
+		ContainerPolicy:   params.ContainerPolicy,
+		OOMWatchInterval:  params.OOMWatchInterval,
+		NRI:               params.NRI,
+		SwapPolicy:        params.SwapPolicy,
+		PublishInterval:   params.PublishInterval,
+		PublishWindow:     params.PublishWindow,
+		PublishStaleAfter: params.PublishStaleAfter,
+		RoundingPolicy:    params.RoundingPolicy,
+		NodeLabels:        params.NodeLabels,
+		AlignAttributes:   params.AlignAttributes,
+		PagesCapacity:     params.PagesCapacity,
+		DeviceNaming:      params.DeviceNaming,
+		HPReservation:     params.HPReservation,
+		HugepagesPools:    hpPools,
+		HPExclusions:      hpExclusions,
+		MemoryGuardBands:  params.MemoryGuardBands,
+		Failpoints:        params.Failpoints,
+		EnforcementStatus: params.EnforcementStatus,
+		Unpublish:         params.UnpublishOnExit,
+		AdmissionPolicy:   admissionPolicy,
+		AnnotatePods:      params.AnnotatePods,
+		LimitsWatchdog:    params.LimitsWatchdog,
+		WatchdogInterval:  params.WatchdogInterval,
+		AdjustConflicts:   params.AdjustConflicts,
+		ZoneGone:          params.ZoneGone,
+		RsvdLimits:        params.RsvdLimits,
+		StrictEnvs:        params.StrictEnvs,
+		CapsNamespace:     params.CapsNamespace,
+		CgroupWriter:      cgWriter,
+		PoolWriter:        poolWriter,
+		HugeTLBFSMounter:  hpMounter,
 		SysVerifier: SysinfoVerifierFunc(func() error {
-			return sysinfo.Validate(drvLogger, params.ProcRoot)
+			if err := sysinfo.Validate(drvLogger, params.ProcRoot); err != nil {
+				return err
+			}
+			return sysinfo.ValidateRuntime(drvLogger, params.RuntimeConfig())
 		}),
 	}
-	dramem, err := driver.Start(egCtx, driverEnv)
+	dramem, err = driver.Start(egCtx, driverEnv)
 	if err != nil {
-		return fmt.Errorf("driver failed to start: %w", err)
+		err = fmt.Errorf("driver failed to start: %w", err)
+		if params.EnforcementStatus {
+			// the devices published by a previous run may still be allocated, but nothing enforces them
+			annotateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if aerr := enforcement.Annotate(annotateCtx, drvLogger, clientset, nodeName, enforcement.Degraded(err.Error())); aerr != nil {
+				drvLogger.Error(aerr, "publishing enforcement status")
+			}
+		}
+		return err
 	}
 	defer drvLogger.Info("driver stopped") // ensure correct ordering of logs
 	defer dramem.Stop()
 
+	if params.DebugSocket != "" {
+		debugSrv := debugapi.NewServer(params.DebugSocket, dramem.DebugClaims)
+		eg.Go(func() error {
+			// the debug API is a convenience, the driver keeps running without it
+			err := debugSrv.Run(egCtx, drvLogger.WithName("debug"))
+			if err != nil {
+				drvLogger.Error(err, "debug API failed")
+			}
+			return nil
+		})
+	}
+
+	if params.AdminSocket != "" {
+		adminSrv := adminapi.NewServer(params.AdminSocket, dramem.ProvisionHugepages)
+		eg.Go(func() error {
+			// the hugepages can still be provisioned by the tool, and published at the next refresh
+			err := adminSrv.Run(egCtx, drvLogger.WithName("admin"))
+			if err != nil {
+				drvLogger.Error(err, "admin API failed")
+			}
+			return nil
+		})
+	}
+
+	if params.AgentSocket != "" {
+		agentSrv := agentapi.NewServer(params.AgentSocket, dramem.AgentMachine, dramem.AgentAllocations)
+		eg.Go(func() error {
+			// like the debug API, the agents can fall back to their own discovery
+			err := agentSrv.Run(egCtx, drvLogger.WithName("agent"))
+			if err != nil {
+				drvLogger.Error(err, "agent API failed")
+			}
+			return nil
+		})
+	}
+
 	ready.Store(true)
 	drvLogger.Info("driver started")
 
 	return eg.Wait()
 }
 
+// newClientset creates the client of the API server, from the kubeconfig if given, or the in-cluster configuration.
+func newClientset(params Params) (*kubernetes.Clientset, error) {
+	var err error
+	var config *rest.Config
+	if params.Kubeconfig != "" {
+		config, err = clientcmd.BuildConfigFromFlags("", params.Kubeconfig)
+	} else {
+		config, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client-go configuration: %w", err)
+	}
+
+	// use protobuf for better performance at scale
+	// https://kubernetes.io/docs/reference/using-api/api-concepts/#alternate-representations-of-resources
+	config.AcceptContentTypes = "application/vnd.kubernetes.protobuf,application/json"
+	config.ContentType = "application/vnd.kubernetes.protobuf"
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client-go client: %w", err)
+	}
+	return clientset, nil
+}
+
+func getNodeName(params Params) (string, error) {
+	nodeName, err := nodeutil.GetHostname(params.HostnameOverride)
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain the node name, use the hostname-override flag if you want to set it to a specific value: %w", err)
+	}
+	return nodeName, nil
+}
+
+// registerBuildInfo exposes the version as labels of a constant metric, the usual way to join it with the other metrics.
+func registerBuildInfo(ver Version) {
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "dramemory",
+		Name:      "build_info",
+		Help:      "Build information of the driver, as labels. The value is always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":   ver.Version,
+			"revision":  ver.Build,
+			"goversion": ver.Golang,
+		},
+	})
+	buildInfo.Set(1)
+	prometheus.MustRegister(buildInfo)
+}
+
 func MakeLogger(setupLogger logr.Logger) (logr.Logger, error) {
 	lev, err := kloglevel.Get()
 	if err != nil {
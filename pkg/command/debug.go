@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+)
+
+const (
+	DebugCommand = "debug"
+
+	debugClaims = "claims"
+)
+
+// Debug queries the running daemon through the debug API, like `dramemory debug claims`.
+func Debug(ctx context.Context, params Params, logger logr.Logger) error {
+	if params.DebugSocket == "" {
+		return errors.New("debug API disabled: missing debug socket")
+	}
+	if len(params.DebugArgs) != 1 || params.DebugArgs[0] != debugClaims {
+		return fmt.Errorf("unsupported debug arguments %q, supported: %q", params.DebugArgs, debugClaims)
+	}
+	logger.V(2).Info("querying the daemon", "socketPath", params.DebugSocket)
+	data, err := debugapi.GetClaims(ctx, params.DebugSocket)
+	if err != nil {
+		return err
+	}
+	return debugapi.WriteClaims(os.Stdout, data, time.Now())
+}
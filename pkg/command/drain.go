@@ -0,0 +1,136 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/provision"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/unpublish"
+)
+
+const (
+	DrainCommand   = "drain"
+	UndrainCommand = "undrain"
+
+	// drainPollInterval is the interval to check the claims still tracked by the daemon
+	drainPollInterval = 5 * time.Second
+)
+
+// Drain puts the node in maintenance, like `dramemory drain`: the daemon withdraws the resources, so no new
+// claims are allocated on the node, then waits for the claims tracked by the daemon to be released, and
+// optionally returns the hugepages to the kernel. The node stays in maintenance until Undrain.
+func Drain(ctx context.Context, params Params, logger logr.Logger) error {
+	if params.DebugSocket == "" {
+		return errors.New("cannot track the claims: debug API disabled: missing debug socket")
+	}
+	clientset, err := newClientset(params)
+	if err != nil {
+		return err
+	}
+	nodeName, err := getNodeName(params)
+	if err != nil {
+		return err
+	}
+	err = unpublish.SetMaintenance(ctx, logger, clientset, nodeName, true, time.Now())
+	if err != nil {
+		return fmt.Errorf("cannot put the node %q in maintenance: %w", nodeName, err)
+	}
+	fmt.Fprintf(os.Stdout, "node %q in maintenance, the resources are being withdrawn\n", nodeName)
+
+	claimed, err := waitClaimsReleased(ctx, os.Stdout, params.DebugSocket, params.DrainTimeout)
+	if err != nil {
+		return err
+	}
+	if !params.DrainDeprovision {
+		return nil
+	}
+
+	machine, err := sysinfo.GetMachineData(logger, params.SysRoot)
+	if err != nil {
+		return fmt.Errorf("cannot discover the hugepages: %w", err)
+	}
+	res, err := provision.Deprovision(logger, params.SysRoot, len(machine.Zones), machine.Hugepagesizes, claimed)
+	for _, nodeRes := range res.Nodes {
+		fmt.Fprintf(os.Stdout, "NUMA zone %d hugepages-%s: %d pages left\n", nodeRes.Node, nodeRes.Size, nodeRes.Achieved)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot deprovision the hugepages: %w", err)
+	}
+	return nil
+}
+
+// Undrain takes the node out of maintenance, like `dramemory undrain`, so the daemon publishes the resources again.
+// The hugepages deprovisioned by Drain must be provisioned again by the admin, like on the first setup.
+func Undrain(ctx context.Context, params Params, logger logr.Logger) error {
+	clientset, err := newClientset(params)
+	if err != nil {
+		return err
+	}
+	nodeName, err := getNodeName(params)
+	if err != nil {
+		return err
+	}
+	err = unpublish.SetMaintenance(ctx, logger, clientset, nodeName, false, time.Now())
+	if err != nil {
+		return fmt.Errorf("cannot take the node %q out of maintenance: %w", nodeName, err)
+	}
+	fmt.Fprintf(os.Stdout, "node %q out of maintenance, the resources are being published\n", nodeName)
+	return nil
+}
+
+// waitClaimsReleased polls the daemon until it tracks no claims, or `timeout` expires, reporting the progress on `out`.
+// Returns the hugepages still claimed, which are none unless the wait failed.
+func waitClaimsReleased(ctx context.Context, out io.Writer, socketPath string, timeout time.Duration) (provision.ClaimedPages, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	start := time.Now()
+	lastCount := -1
+	for {
+		data, err := debugapi.GetClaims(ctx, socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get the claims of the daemon: %w", err)
+		}
+		if len(data.Claims) == 0 {
+			fmt.Fprintf(out, "all the claims released after %s\n", time.Since(start).Round(time.Second))
+			return provision.ClaimedPagesFromDebug(data), nil
+		}
+		if len(data.Claims) != lastCount {
+			fmt.Fprintf(out, "waiting for %d claims to be released (%s elapsed)\n", len(data.Claims), time.Since(start).Round(time.Second))
+			lastCount = len(data.Claims)
+		}
+		select {
+		case <-ctx.Done():
+			return provision.ClaimedPagesFromDebug(data), fmt.Errorf("%d claims not released: %w", len(data.Claims), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,393 @@
+/*
+ * Copyright 2025 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/provision"
+)
+
+const (
+	// HugepagesProfileLabel is the node label selecting the hugepages provisioning profile of the node.
+	// The nodes without the label get the driver, but their hugepages are left untouched.
+	HugepagesProfileLabel = "dra.memory/hugepages-profile"
+	// HugepagesProfilesConfigMap holds the provisioning configuration of each profile, keyed by profile name.
+	HugepagesProfilesConfigMap = "dramemory-hugepages"
+	// DefaultManifestImage is the image of the driver and of the setup tools in the install manifests.
+	DefaultManifestImage = "quay.io/ffromani/dramem:latest"
+
+	installNamespace     = "kube-system"
+	installName          = "dramemory"
+	hugepagesProfilesDir = "/etc/dramemory/hugepages"
+)
+
+// hugepagesProfile is a provisioning configuration, applied to the nodes labeled with its name.
+type hugepagesProfile struct {
+	Name string
+	Data string
+}
+
+// makeInstall emits the objects to run the driver on the nodes: the RBAC objects, the ConfigMap of the hugepages
+// profiles, if any, and the DaemonSets, whose init containers configure the container runtime and provision
+// the hugepages before the driver starts. Node-specific configuration is expressed as one DaemonSet per profile.
+func makeInstall(params Params, logger logr.Logger) error {
+	profiles, err := readHugepagesProfiles(params.ManifestProfiles)
+	if err != nil {
+		return err
+	}
+	objs := []any{
+		installServiceAccount(),
+		installClusterRole(),
+		installClusterRoleBinding(),
+	}
+	if len(profiles) > 0 {
+		objs = append(objs, hugepagesProfilesConfigMap(profiles))
+	}
+	objs = append(objs, installDaemonSet(params.ManifestImage, nil, len(profiles) > 0))
+	for idx := range profiles {
+		objs = append(objs, installDaemonSet(params.ManifestImage, &profiles[idx], true))
+	}
+	for _, obj := range objs {
+		fmt.Println("---")
+		logYAML(logger, obj)
+	}
+	return nil
+}
+
+// readHugepagesProfiles reads the provisioning configurations in `dir`, one per file, named after the file
+// without the extension. The names must be valid node label values, and object names.
+func readHugepagesProfiles(dir string) ([]hugepagesProfile, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the hugepages profiles: %w", err)
+	}
+	var profiles []hugepagesProfile
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid hugepages profile name %q: %s", name, strings.Join(errs, ", "))
+		}
+		source := filepath.Join(dir, entry.Name())
+		// fail now rather than in the init containers of the nodes
+		if _, err := provision.ReadConfiguration(source); err != nil {
+			return nil, fmt.Errorf("malformed hugepages profile %q: %w", source, err)
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, hugepagesProfile{Name: name, Data: string(data)})
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	for idx := 1; idx < len(profiles); idx++ {
+		if profiles[idx].Name == profiles[idx-1].Name {
+			return nil, fmt.Errorf("duplicate hugepages profile %q", profiles[idx].Name)
+		}
+	}
+	return profiles, nil
+}
+
+func hugepagesProfilesConfigMap(profiles []hugepagesProfile) corev1.ConfigMap {
+	cm := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HugepagesProfilesConfigMap,
+			Namespace: installNamespace,
+		},
+		Data: map[string]string{},
+	}
+	for _, profile := range profiles {
+		cm.Data[profile.Name] = profile.Data
+	}
+	return cm
+}
+
+func installServiceAccount() corev1.ServiceAccount {
+	return corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      installName,
+			Namespace: installNamespace,
+		},
+	}
+}
+
+func installClusterRole() rbacv1.ClusterRole {
+	return rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "ClusterRole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: installName,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"nodes"},
+				Verbs:     []string{"get", "list", "watch", "patch"},
+			},
+			{
+				APIGroups: []string{"resource.k8s.io"},
+				Resources: []string{"resourceslices"},
+				Verbs:     []string{"list", "watch", "create", "update", "delete"},
+			},
+			{
+				APIGroups: []string{"resource.k8s.io"},
+				Resources: []string{"resourceclaims", "deviceclasses"},
+				Verbs:     []string{"get"},
+			},
+			{
+				APIGroups: []string{"resource.k8s.io"},
+				Resources: []string{"resourceclaims"},
+				Verbs:     []string{"patch"},
+			},
+			{
+				APIGroups: []string{"resource.k8s.io"},
+				Resources: []string{"resourceclaims/status"},
+				Verbs:     []string{"patch", "update"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch", "update"},
+			},
+		},
+	}
+}
+
+func installClusterRoleBinding() rbacv1.ClusterRoleBinding {
+	return rbacv1.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "ClusterRoleBinding",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: installName,
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     installName,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      installName,
+				Namespace: installNamespace,
+			},
+		},
+	}
+}
+
+// installDaemonSet runs the driver on the nodes labeled with the hugepages profile, provisioning its hugepages
+// in an init container, or, if `profile` is nil, on the nodes without profile. The selectors of the DaemonSets
+// must not overlap, so if `profiled` the DaemonSet without profile skips the nodes labeled with any profile.
+func installDaemonSet(image string, profile *hugepagesProfile, profiled bool) appsv1.DaemonSet {
+	name := installName
+	labels := map[string]string{
+		"tier":    "node",
+		"app":     installName,
+		"k8s-app": installName,
+	}
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app": installName,
+		},
+	}
+	if profile != nil {
+		name += "-" + profile.Name
+		labels[HugepagesProfileLabel] = profile.Name
+		selector.MatchLabels[HugepagesProfileLabel] = profile.Name
+	} else if profiled {
+		selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{
+				Key:      HugepagesProfileLabel,
+				Operator: metav1.LabelSelectorOpDoesNotExist,
+			},
+		}
+	}
+
+	podSpec := corev1.PodSpec{
+		NodeSelector: map[string]string{
+			"kubernetes.io/os": "linux",
+		},
+		PriorityClassName: "system-node-critical",
+		HostNetwork:       true,
+		HostPID:           true,
+		Tolerations: []corev1.Toleration{
+			{
+				Operator: corev1.TolerationOpExists,
+				Effect:   corev1.TaintEffectNoSchedule,
+			},
+		},
+		ServiceAccountName: installName,
+		InitContainers: []corev1.Container{
+			{
+				Name:    "setup-runtime",
+				Image:   image,
+				Command: []string{"/bin/setup-runtime"},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: ptr.To(true),
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "etc", MountPath: "/etc"},
+				},
+			},
+		},
+		Containers: []corev1.Container{
+			{
+				Name:            installName,
+				Image:           image,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         []string{"/bin/dramemory"},
+				Args: []string{
+					"--cgroup-mount=/sys/fs/cgroup",
+				},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("200Mi"),
+					},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					Privileged: ptr.To(true),
+					RunAsUser:  ptr.To(int64(0)),
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "device-plugin", MountPath: "/var/lib/kubelet/plugins"},
+					{Name: "plugin-registry", MountPath: "/var/lib/kubelet/plugins_registry"},
+					{Name: "nri-plugin", MountPath: "/var/run/nri"},
+					{Name: "cdi-dir", MountPath: "/var/run/cdi"},
+					{Name: "cgroupfs", MountPath: "/sys/fs/cgroup", MountPropagation: ptr.To(corev1.MountPropagationHostToContainer)},
+					{Name: "hugetlbfs-dir", MountPath: "/var/run/dramemory/hugetlbfs", MountPropagation: ptr.To(corev1.MountPropagationBidirectional)},
+				},
+			},
+		},
+		Volumes: []corev1.Volume{
+			hostPathVolume("device-plugin", "/var/lib/kubelet/plugins", nil),
+			hostPathVolume("plugin-registry", "/var/lib/kubelet/plugins_registry", nil),
+			hostPathVolume("nri-plugin", "/var/run/nri", nil),
+			hostPathVolume("cdi-dir", "/var/run/cdi", ptr.To(corev1.HostPathDirectoryOrCreate)),
+			hostPathVolume("cgroupfs", "/sys/fs/cgroup", nil),
+			hostPathVolume("hugetlbfs-dir", "/var/run/dramemory/hugetlbfs", ptr.To(corev1.HostPathDirectoryOrCreate)),
+			hostPathVolume("etc", "/etc", nil),
+		},
+	}
+	if profile != nil {
+		podSpec.InitContainers = append(podSpec.InitContainers, hugepagesInitContainer(image, profile.Name))
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "hugepages-profile",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: HugepagesProfilesConfigMap},
+					Items: []corev1.KeyToPath{
+						{Key: profile.Name, Path: profile.Name + ".yaml"},
+					},
+				},
+			},
+		})
+	}
+
+	return appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: installNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: selector,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// hugepagesInitContainer provisions the hugepages of the profile before the driver discovers them.
+func hugepagesInitContainer(image, profileName string) corev1.Container {
+	return corev1.Container{
+		Name:    "setup-hugepages",
+		Image:   image,
+		Command: []string{"/bin/setup-hugepages"},
+		Args: []string{
+			"--output=json",
+			path.Join(hugepagesProfilesDir, profileName+".yaml"),
+		},
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: ptr.To(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "hugepages-profile", MountPath: hugepagesProfilesDir, ReadOnly: true},
+		},
+	}
+}
+
+func hostPathVolume(name, hostPath string, pathType *corev1.HostPathType) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: hostPath,
+				Type: pathType,
+			},
+		},
+	}
+}
@@ -18,12 +18,16 @@ package command
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/go-logr/logr"
 
+	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
 
 	"github.com/ffromani/dra-driver-memory/pkg/driver"
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
@@ -56,10 +60,50 @@ func MakeManifests(params Params, logger logr.Logger) error {
 		}
 		devClasses = append(devClasses, deviceClass(driver.Name, hugepage))
 	}
+	if hpSizes.Len() > 0 {
+		devClasses = append(devClasses, defaultHugepagesDeviceClass(driver.Name))
+	}
 	for _, devClass := range devClasses {
 		fmt.Println("---")
 		logYAML(logger, devClass)
 	}
+	if params.ManifestInstall {
+		if err := makeInstall(params, logger); err != nil {
+			return err
+		}
+	}
+	if !params.ManifestExamples {
+		return nil
+	}
+
+	// the test pod exercises only the hugepages actually provisioned, the others could never be allocated
+	provisioned := sets.New[uint64]()
+	for _, zone := range machine.Zones {
+		if zone.Memory == nil {
+			continue
+		}
+		for hpSize, amounts := range zone.Memory.HugePageAmountsBySize {
+			if amounts != nil && amounts.Total > 0 {
+				provisioned.Insert(hpSize)
+			}
+		}
+	}
+	fmt.Println("---")
+	logYAML(logger, exampleClaimTemplate(memory))
+	examples := []types.ResourceIdent{memory}
+	for _, hpSize := range sets.List(hpSizes) {
+		hugepage := types.ResourceIdent{
+			Kind:     types.Hugepages,
+			Pagesize: hpSize,
+		}
+		fmt.Println("---")
+		logYAML(logger, exampleClaimTemplate(hugepage))
+		if provisioned.Has(hpSize) {
+			examples = append(examples, hugepage)
+		}
+	}
+	fmt.Println("---")
+	logYAML(logger, examplePod(examples))
 	return nil
 }
 
@@ -70,7 +114,7 @@ func deviceClass(driverName string, ri types.ResourceIdent) resourceapi.DeviceCl
 			Kind:       "DeviceClass",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: "dra." + ri.Name(),
+			Name: deviceClassName(ri),
 		},
 		Spec: resourceapi.DeviceClassSpec{
 			Selectors: []resourceapi.DeviceSelector{
@@ -84,6 +128,137 @@ func deviceClass(driverName string, ri types.ResourceIdent) resourceapi.DeviceCl
 	}
 }
 
+// deviceClassName returns the name of the DeviceClass of the resource, like "dra.hugepages-2m".
+func deviceClassName(ri types.ResourceIdent) string {
+	return "dra." + manifestName(ri)
+}
+
+// DefaultHugepagesDeviceClassName is the DeviceClass of the hugepages of the default size of the node,
+// to let the portable workloads ask for hugepages without knowing their size.
+const DefaultHugepagesDeviceClassName = "dra.hugepages"
+
+func defaultHugepagesDeviceClass(driverName string) resourceapi.DeviceClass {
+	return resourceapi.DeviceClass{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "resource.k8s.io/v1",
+			Kind:       "DeviceClass",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: DefaultHugepagesDeviceClassName,
+		},
+		Spec: resourceapi.DeviceClassSpec{
+			Selectors: []resourceapi.DeviceSelector{
+				{
+					CEL: &resourceapi.CELDeviceSelector{
+						Expression: defaultHugepagesCELExpr(driverName),
+					},
+				},
+			},
+		},
+	}
+}
+
+// manifestName returns the name of the resource in the object names, which must be lowercase
+// ("memory", "hugepages-2m", "hugepages-1g").
+func manifestName(ri types.ResourceIdent) string {
+	if !ri.NeedsHugeTLB() {
+		return ri.Name()
+	}
+	return string(types.Hugepages) + "-" + strings.ToLower(strings.TrimSuffix(ri.PagesizeString(), "i"))
+}
+
+// exampleImage is the dramemtester image of the test pod, which allocates the claimed memory and checks the placement.
+const exampleImage = "quay.io/fromani/dramemtester:v0.0.20251203"
+
+// exampleSize is small enough to be available on any node exposing the resource, but at least a page.
+func exampleSize(ri types.ResourceIdent) *resource.Quantity {
+	size := int64(64 << 20) // 64 MiB
+	if pagesize := int64(ri.Pagesize); ri.NeedsHugeTLB() && pagesize > size {
+		size = pagesize
+	}
+	return resource.NewQuantity(size, resource.BinarySI)
+}
+
+func exampleClaimTemplate(ri types.ResourceIdent) resourceapi.ResourceClaimTemplate {
+	return resourceapi.ResourceClaimTemplate{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "resource.k8s.io/v1",
+			Kind:       "ResourceClaimTemplate",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "example-" + manifestName(ri),
+		},
+		Spec: resourceapi.ResourceClaimTemplateSpec{
+			Spec: resourceapi.ResourceClaimSpec{
+				Devices: resourceapi.DeviceClaim{
+					Requests: []resourceapi.DeviceRequest{
+						{
+							Name: manifestName(ri),
+							Exactly: &resourceapi.ExactDeviceRequest{
+								DeviceClassName: deviceClassName(ri),
+								Capacity: &resourceapi.CapacityRequirements{
+									Requests: map[resourceapi.QualifiedName]resource.Quantity{
+										ri.CapacityName(): *exampleSize(ri),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// examplePod consumes a claim from each example template in `ris`, with a container allocating the claimed memory.
+func examplePod(ris []types.ResourceIdent) corev1.Pod {
+	pod := corev1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "dramemtester-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+		},
+	}
+	for _, ri := range ris {
+		size := exampleSize(ri)
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:    manifestName(ri),
+			Image:   exampleImage,
+			Command: []string{"/bin/dramemtester"},
+			Args: []string{
+				"-numa-align=any",
+				fmt.Sprintf("-use-hugetlb=%v", ri.NeedsHugeTLB()),
+				"-alloc-size=" + size.String(),
+			},
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+				Claims: []corev1.ResourceClaim{
+					{Name: manifestName(ri)},
+				},
+			},
+		})
+		pod.Spec.ResourceClaims = append(pod.Spec.ResourceClaims, corev1.PodResourceClaim{
+			Name:                      manifestName(ri),
+			ResourceClaimTemplateName: ptr.To("example-" + manifestName(ri)),
+		})
+	}
+	return pod
+}
+
 func celExpr(driverName string, ri types.ResourceIdent) string {
 	return fmt.Sprintf("device.driver == %q && device.attributes[\"resource.kubernetes.io\"].pageSize == %q && device.attributes[\"resource.kubernetes.io\"].hugeTLB == %v", driverName, ri.PagesizeString(), ri.NeedsHugeTLB())
 }
+
+// defaultHugepagesCELExpr matches the hugepages whose size is the default of the node. The devices published
+// by older drivers lack the attribute, and are not matched.
+func defaultHugepagesCELExpr(driverName string) string {
+	return fmt.Sprintf("device.driver == %q && device.attributes[\"resource.kubernetes.io\"].hugeTLB == true && has(device.attributes[%q].%s) && device.attributes[\"resource.kubernetes.io\"].pageSize == device.attributes[%q].%s", driverName, driverName, sysinfo.DefaultHugePageSizeAttribute, driverName, sysinfo.DefaultHugePageSizeAttribute)
+}
@@ -17,12 +17,33 @@
 package command
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 
 	"k8s.io/klog/v2"
+
+	"github.com/ffromani/dra-driver-memory/pkg/capabilities"
+	"github.com/ffromani/dra-driver-memory/pkg/driver"
+	"github.com/ffromani/dra-driver-memory/pkg/failpoint"
+	"github.com/ffromani/dra-driver-memory/pkg/guardband"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/exclude"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/limitwatch"
+	"github.com/ffromani/dra-driver-memory/pkg/nodelabels"
+	"github.com/ffromani/dra-driver-memory/pkg/nriconflict"
+	"github.com/ffromani/dra-driver-memory/pkg/policy"
+	"github.com/ffromani/dra-driver-memory/pkg/setup/containerd"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unpublish"
+	"github.com/ffromani/dra-driver-memory/pkg/zonegone"
 )
 
 const (
@@ -30,22 +51,106 @@ const (
 )
 
 type Params struct {
-	HostnameOverride string
-	Kubeconfig       string
-	BindAddress      string
-	ProcRoot         string
-	SysRoot          string
-	CgroupMount      string
-	DoValidation     bool
-	DoManifests      bool
-	DoVersion        bool
-	InspectMode      InspectMode
+	HostnameOverride  string
+	Kubeconfig        string
+	BindAddress       string
+	ProcRoot          string
+	SysRoot           string
+	CgroupMount       string
+	DisableCgroups    bool
+	ContainerdConfig  string
+	DoValidation      bool
+	DoManifests       bool
+	ManifestExamples  bool
+	ManifestInstall   bool
+	ManifestImage     string
+	ManifestProfiles  string
+	DoRenderSlices    bool
+	RenderOutput      string
+	DoVersion         bool
+	InspectMode       InspectMode
+	ContainerPolicy   policy.Containers
+	OOMWatchInterval  time.Duration
+	NRI               driver.NRIConfig
+	SwapPolicy        policy.SwapPolicy
+	PublishInterval   time.Duration
+	PublishWindow     time.Duration
+	PublishStaleAfter time.Duration
+	RoundingPolicy    types.RoundingPolicy
+	NodeLabels        nodelabels.Config
+	AlignAttributes   bool
+	PagesCapacity     bool
+	DeviceNaming      sysinfo.DeviceNaming
+	HPReservation     reserve.Policy
+	Failpoints        []failpoint.Name
+	EnforcementStatus bool
+	UnpublishOnExit   unpublish.Mode
+	DebugSocket       string
+	AdminSocket       string
+	AgentSocket       string
+	ActuatorSocket    string
+	ActuatorSocketGID int
+	AdmissionPolicy   string
+	HugepagesPools    string
+	KubeletConfig     string
+	HPExclusions      exclude.Exclusions
+	MemoryGuardBands  guardband.Bands
+	AnnotatePods      bool
+	LimitsWatchdog    limitwatch.Mode
+	WatchdogInterval  time.Duration
+	AdjustConflicts   nriconflict.Mode
+	ZoneGone          zonegone.Mode
+	RsvdLimits        hugepages.ReservationMode
+	StrictEnvs        bool
+	CapsNamespace     string
+	DrainTimeout      time.Duration
+	DrainDeprovision  bool
+	// DoDebug runs the `debug` subcommand against the running daemon, with DebugArgs as arguments
+	DoDebug   bool
+	DebugArgs []string
+	// DoDrain and DoUndrain run the `drain` and `undrain` subcommands, putting the node in and out of maintenance
+	DoDrain   bool
+	DoUndrain bool
+	// DoActuator runs the `actuator` subcommand, the privileged helper of the daemon
+	DoActuator bool
+	// DoSelftest runs the `selftest` subcommand, checking the enforcement on the node
+	DoSelftest     bool
+	SelftestParent string
 }
 
 func DefaultParams() Params {
 	return Params{
-		ProcRoot: "/",
-		SysRoot:  "/",
+		ProcRoot:          "/",
+		SysRoot:           "/",
+		ContainerdConfig:  containerd.DefaultConfigPath,
+		ContainerPolicy:   policy.DefaultContainers(),
+		OOMWatchInterval:  5 * time.Second,
+		SwapPolicy:        policy.SwapPolicyUnmanaged,
+		PublishInterval:   1 * time.Minute,
+		PublishWindow:     2 * time.Second,
+		RoundingPolicy:    types.RoundingPolicyRoundUp,
+		HPReservation:     reserve.PolicyNone,
+		DeviceNaming:      sysinfo.DeviceNamingRandom,
+		DebugSocket:       driver.DefaultDebugSocketPath,
+		EnforcementStatus: true,
+		UnpublishOnExit:   unpublish.ModeNone,
+		LimitsWatchdog:    limitwatch.ModeNone,
+		ActuatorSocketGID: -1,
+		WatchdogInterval:  30 * time.Second,
+		AdjustConflicts:   nriconflict.ModeNone,
+		ZoneGone:          zonegone.ModeFail,
+		RsvdLimits:        hugepages.ReservationSync,
+		DrainTimeout:      10 * time.Minute,
+		ManifestImage:     DefaultManifestImage,
+		NodeLabels: nodelabels.Config{
+			Mode:           nodelabels.ModeNone,
+			NFDFeaturesDir: nodelabels.DefaultNFDFeaturesDir,
+		},
+		NRI: driver.NRIConfig{
+			PluginIndex: driver.DefaultNRIPluginIndex,
+			LogInterval: 1 * time.Minute,
+			LogBurst:    20,
+		},
 	}
 }
 
@@ -55,15 +160,130 @@ func (par *Params) InitFlags() {
 	flag.StringVar(&par.HostnameOverride, "hostname-override", par.HostnameOverride, "If non-empty, will be used as the name of the Node that kube-network-policies is running on. If unset, the node name is assumed to be the same as the node's hostname.")
 	flag.StringVar(&par.ProcRoot, "procfs-root", par.ProcRoot, "root point where procfs is mounted.")
 	flag.StringVar(&par.SysRoot, "sysfs-root", par.SysRoot, "root point where sysfs is mounted.")
-	flag.StringVar(&par.CgroupMount, "cgroup-mount", par.CgroupMount, "cgroupfs mount point. Set empty to DISABLE direct cgroup settings.")
+	flag.StringVar(&par.CgroupMount, "cgroup-mount", par.CgroupMount, "cgroupfs mount point. Set empty to detect the cgroup2 mount point.")
+	flag.BoolVar(&par.DisableCgroups, "disable-cgroup-enforcement", par.DisableCgroups, "run without the direct cgroup settings: the hugetlb limits of the pods, the memory events, the limits watchdog and the swap limits. The containers are still pinned through the runtime.")
+	flag.StringVar(&par.ContainerdConfig, "containerd-config", par.ContainerdConfig, "containerd configuration file to validate, if found. Set empty to skip the check.")
+	flag.DurationVar(&par.OOMWatchInterval, "oom-watch-interval", par.OOMWatchInterval, "polling interval of the memory events of the pods holding claims. Set zero to disable.")
+	flag.StringVar(&par.NRI.PluginIndex, "nri-plugin-index", par.NRI.PluginIndex, "two-digit index of the NRI plugin. Plugins are invoked in increasing index order.")
+	flag.StringVar(&par.NRI.SocketPath, "nri-socket-path", par.NRI.SocketPath, "NRI socket path of the container runtime. Leave empty to use the NRI default.")
+	flag.DurationVar(&par.NRI.ConnectTimeout, "nri-connect-timeout", par.NRI.ConnectTimeout, "timeout to connect to the NRI socket of the container runtime. Set zero to disable.")
+	flag.DurationVar(&par.NRI.LogInterval, "nri-log-interval", par.NRI.LogInterval, "interval of the throttling of the logs of the NRI hooks: at most nri-log-burst messages about each pod, and each distinct error once. Set zero to disable. Verbosity 6 or higher disables the throttling.")
+	flag.IntVar(&par.NRI.LogBurst, "nri-log-burst", par.NRI.LogBurst, "messages logged about each pod by the NRI hooks every nri-log-interval, the others are dropped and counted. Set zero to disable the throttling.")
+	flag.DurationVar(&par.PublishInterval, "publish-interval", par.PublishInterval, "interval to refresh the free capacity attributes of the published resources. Set zero to publish only at startup.")
+	flag.DurationVar(&par.PublishWindow, "publish-window", par.PublishWindow, "window to coalesce the requests to publish the resources (discovery, periodic refresh, claims changes) into a single publication. Set zero to publish without delay.")
+	flag.DurationVar(&par.PublishStaleAfter, "publish-stale-threshold", par.PublishStaleAfter, "age of the last successful publication of the resources after which the dramemory_resourceslices_stale metric flips to 1. Set zero to use three times the publish interval.")
+	flag.DurationVar(&par.DrainTimeout, "drain-timeout", par.DrainTimeout, "with the drain subcommand, how long to wait for the claims of the node to be released. Set zero to wait forever.")
+	flag.StringVar(&par.SelftestParent, "selftest-cgroup-parent", par.SelftestParent, "with the selftest subcommand, cgroup to create the scratch cgroup into, relative to the cgroup mount, like kubepods.slice. Set empty to use the root cgroup.")
+	flag.BoolVar(&par.DrainDeprovision, "drain-deprovision-hugepages", par.DrainDeprovision, "with the drain subcommand, return the hugepages of all the pools to the kernel once the claims are released. The pages still in use are kept.")
+	flag.DurationVar(&par.WatchdogInterval, "limits-watchdog-interval", par.WatchdogInterval, "interval of the checks of the hugetlb limits of the pods holding claims and of their ancestors. Used only if limits-watchdog is enabled.")
+	flag.StringVar(&par.NodeLabels.NFDFeaturesDir, "nfd-features-dir", par.NodeLabels.NFDFeaturesDir, "directory of the node-feature-discovery local features. Used only if node-labels is nfd.")
+	flag.BoolVar(&par.AlignAttributes, "alignment-attributes", par.AlignAttributes, "publish the CPU socket and PCIe root attributes, to align the memory with the devices of other drivers like GPUs and NICs.")
+	flag.BoolVar(&par.PagesCapacity, "pages-capacity", par.PagesCapacity, "publish the capacity of the hugepages devices also in pages, to let the claims request pages rather than bytes.")
+	flag.StringVar(&par.DebugSocket, "debug-socket", par.DebugSocket, "unix socket of the debug API: served by the daemon, used by the debug subcommand. Set empty to disable.")
+	flag.StringVar(&par.AdminSocket, "admin-socket", par.AdminSocket, "unix socket of the admin API, served by the daemon to the clients running as root, to provision the hugepages through the daemon, which publishes the new pools at once. Set empty to disable.")
+	flag.StringVar(&par.AgentSocket, "agent-socket", par.AgentSocket, "unix socket of the read-only agent API, serving the memory topology and the allocations to the other agents of the node. Set empty to disable.")
+	flag.StringVar(&par.ActuatorSocket, "actuator-socket", par.ActuatorSocket, "unix socket of the privileged helper: served by the actuator subcommand, used by the daemon to delegate the cgroup and hugepages pool writes and the hugetlbfs mounts, so it can run unprivileged. Set empty for the daemon to write by itself.")
+	flag.IntVar(&par.ActuatorSocketGID, "actuator-socket-gid", par.ActuatorSocketGID, "with the actuator subcommand, group allowed to connect to the actuator socket, for the daemon running as a non-root user. Set negative to allow root only.")
+	flag.StringVar(&par.AdmissionPolicy, "admission-policy", par.AdmissionPolicy, "file of the policy capping the memory and hugepages the claims of a namespace or priority class can hold on the node. Set empty to admit all the claims.")
+	flag.StringVar(&par.HugepagesPools, "hugepages-pools", par.HugepagesPools, "file splitting the hugepages pools of the NUMA zones into named sub-pools, published as separate devices. Set empty to publish the whole pools.")
+	flag.StringVar(&par.KubeletConfig, "kubelet-config", par.KubeletConfig, "kubelet configuration file to read the hugepages reserved to the extended resources from (reservedMemory), which are left out of the published devices. Set empty to skip.")
+	flag.Var(&HPExclusionsValue{Exclusions: &par.HPExclusions}, "hugepages-excluded", "comma-separated hugepages left out of the published devices, like resource[@numaZone]=size: hugepages-1Gi=4Gi,hugepages-2Mi@0=512Mi. Without the NUMA zone, spread across the zones. Added to the kubelet-config ones.")
+	flag.Var(&GuardBandsValue{Bands: &par.MemoryGuardBands}, "memory-guard-bands", "comma-separated memory of each NUMA zone left out of the published devices, for the kernel, the DMA zones and the device drivers, like [numaZone=]size: 256Mi,0=2Gi. Without the NUMA zone, applied to each zone without its own.")
+	flag.BoolVar(&par.AnnotatePods, "annotate-pods", par.AnnotatePods, "annotate the pods with the summary of the allocations of their claims (NUMA zones, sizes per resource), visible with kubectl describe pod. Requires the RBAC permission to patch the pods.")
+	flag.StringVar(&par.CapsNamespace, "capabilities-namespace", par.CapsNamespace, "namespace of the ConfigMap documenting the resources, attributes and features of the driver on the node, named "+capabilities.ConfigMapName("<node>")+". Requires the RBAC permission to create, get and update the ConfigMaps of the namespace. Set empty to disable.")
+	flag.BoolVar(&par.StrictEnvs, "strict-envs", par.StrictEnvs, "fail the creation of the containers with malformed driver variables, rather than leaving out their claims and reporting them. Meant for the CI.")
+	flag.BoolVar(&par.EnforcementStatus, "enforcement-status", par.EnforcementStatus, "annotate the node with the enforcement status of the claims (active, degraded), reflecting the NRI connection and the preflight checks.")
 	flag.BoolVar(&par.DoValidation, "validate", par.DoValidation, "validate machine properties and exit.")
 	flag.BoolVar(&par.DoManifests, "make-manifests", par.DoManifests, "emit DRA manifests based on hardware discovery.")
+	flag.BoolVar(&par.ManifestExamples, "manifests-examples", par.ManifestExamples, "with make-manifests, emit also example ResourceClaimTemplates and a test pod consuming them, to validate the installation.")
+	flag.BoolVar(&par.ManifestInstall, "manifests-install", par.ManifestInstall, "with make-manifests, emit also the objects to run the driver: RBAC and DaemonSets, whose init containers configure the container runtime and provision the hugepages.")
+	flag.StringVar(&par.ManifestImage, "manifests-image", par.ManifestImage, "with manifests-install, image of the driver and of the init containers.")
+	flag.StringVar(&par.ManifestProfiles, "manifests-hugepages-profiles", par.ManifestProfiles, "with manifests-install, directory of the hugepages provisioning files, one per profile named after the file, applied to the nodes labeled "+HugepagesProfileLabel+"=<profile>. Set empty to leave the hugepages untouched.")
+	flag.BoolVar(&par.DoRenderSlices, "render-slices", par.DoRenderSlices, "emit the ResourceSlices the driver would publish on this node, without connecting to the API server or the container runtime, and exit.")
+	flag.StringVar(&par.RenderOutput, "render-output", par.RenderOutput, "with render-slices, file to write the ResourceSlices to. Set empty to write them to the standard output.")
 	flag.BoolVar(&par.DoVersion, "version", par.DoVersion, "print program version and exit.")
 	flag.Var(&InspectValue{Mode: &par.InspectMode}, "inspect", "inspect machine properties and exit.")
+	flag.Var(&ContainerPolicyValue{Policy: &par.ContainerPolicy.Init}, "init-container-policy", "what init containers of pods with memory claims inherit from the claims: none, mems, full (mems and hugetlb limits), mems-zero-hugetlb (mems and zero hugetlb limits).")
+	flag.Var(&ContainerPolicyValue{Policy: &par.ContainerPolicy.Sidecar}, "sidecar-container-policy", "what sidecar containers of pods with memory claims inherit from the claims: none, mems, full (mems and hugetlb limits), mems-zero-hugetlb (mems and zero hugetlb limits).")
+	flag.Var(&ContainerPolicyValue{Policy: &par.ContainerPolicy.Ephemeral}, "ephemeral-container-policy", "what ephemeral containers, like the kubectl debug ones, of pods with memory claims inherit from the claims: none, mems, full (mems and hugetlb limits), mems-zero-hugetlb (mems and zero hugetlb limits).")
+	flag.Var(&SwapPolicyValue{Policy: &par.SwapPolicy}, "swap-policy", "swap limit of the containers holding memory claims on nodes with swap: unmanaged (left to the kubelet), none, proportional (to the claimed memory).")
+	flag.Var(&DeviceNamingValue{Naming: &par.DeviceNaming}, "device-naming", "naming of the published devices: random (changing at each restart), stable (derived from the node, the NUMA zone and the resource).")
+	flag.Var(&NodeLabelsModeValue{Mode: &par.NodeLabels.Mode}, "node-labels", "mirror the discovery facts into node labels: none, labels (label the node directly), nfd (write a node-feature-discovery feature file).")
+	flag.Var(&HPReservationValue{Policy: &par.HPReservation}, "hugepages-reservation", "check the free hugepages when preparing the claims: none, grow (the pool of the zone lacking pages), move (the pages from the other zones).")
+	flag.Var(&RoundingPolicyValue{Policy: &par.RoundingPolicy}, "rounding-policy", "handling of the requests which are not multiple of the page size: round-up (to the next page), exact (fail the request).")
+	flag.Var(&UnpublishModeValue{Mode: &par.UnpublishOnExit}, "unpublish-on-exit", "withdraw the resources when the driver stops cleanly or the node is cordoned for draining: none, delete (the ResourceSlices), taint (the devices; requires the DRADeviceTaints feature gate).")
+	flag.Var(&LimitsWatchdogValue{Mode: &par.LimitsWatchdog}, "limits-watchdog", "check the hugetlb limits of the pods holding claims and of their ancestor cgroups, which can be set too low by other agents: none, alert (report the limits too low), repair (raise them and report).")
+	flag.Var(&AdjustConflictsValue{Mode: &par.AdjustConflicts}, "adjustment-conflicts", "check the settings of the containers consuming the claims (cpuset.mems, hugepage limits) changed by other NRI plugins: none, alert (report the conflicts), reject (report, and fail the creation of the containers; requires a runtime supporting the NRI adjustment validation).")
+	flag.Var(&RsvdLimitsValue{Mode: &par.RsvdLimits}, "hugetlb-rsvd-limits", "set the hugetlb reservation limits of the pods and the containers consuming the claims: sync (like the usage limits, so the mappings exceeding the claims fail with ENOMEM), unlimited (enforce only the usage limits, for the applications reserving more than they use, e.g. with MAP_NORESERVE; the pages faulted exceeding the claims fail with SIGBUS).")
+	flag.Var(&ZoneGoneValue{Mode: &par.ZoneGone}, "zone-gone", "handle the claims allocated on a NUMA zone gone, e.g. after a memory hot-unplug: fail (fail their preparation, and withdraw the devices of the zone), taint (also taint the devices of the zone, so the pods consuming them are evicted and placed again; requires the DRADeviceTaints feature gate).")
+	flag.Var(&FailpointsValue{Names: &par.Failpoints}, "failpoints", "TESTING ONLY: comma-separated failpoints which kill the driver the first time they are hit: prepare-after-cdi-write.")
+}
+
+// RuntimeConfig returns the settings of the container runtime to validate.
+func (par *Params) RuntimeConfig() sysinfo.RuntimeConfig {
+	return sysinfo.RuntimeConfig{
+		CgroupMount:          par.CgroupMount,
+		NRISocketPath:        par.NRI.EffectiveSocketPath(),
+		ContainerdConfigPath: par.ContainerdConfig,
+	}
+}
+
+// ResolveCgroupMount sets the cgroup mount point to the detected cgroup2 one, unless set explicitly
+// or the direct cgroup settings are disabled. Failing to detect it is an error, not to run without enforcing silently.
+func (par *Params) ResolveCgroupMount(lh logr.Logger) error {
+	if par.DisableCgroups {
+		if par.CgroupMount != "" {
+			return errors.New("conflicting flags: cgroup-mount is set, but the cgroup enforcement is disabled")
+		}
+		lh.Info("direct cgroup settings disabled", "flag", "disable-cgroup-enforcement")
+		return nil
+	}
+	if par.CgroupMount != "" {
+		lh.Info("cgroup mount configured", "mountPoint", par.CgroupMount)
+		return nil
+	}
+	mountPoint, err := sysinfo.DetectCgroupMount(lh, par.ProcRoot)
+	if err != nil {
+		return fmt.Errorf("cannot detect the cgroup mount, set cgroup-mount, or disable-cgroup-enforcement to run without the direct cgroup settings: %w", err)
+	}
+	lh.Info("cgroup mount detected", "mountPoint", mountPoint)
+	par.CgroupMount = mountPoint
+	return nil
+}
+
+// HugepagesExclusions returns the hugepages to leave out of the devices: the ones reserved in the kubelet
+// configuration, if any, plus the ones given explicitly.
+func (par *Params) HugepagesExclusions() (exclude.Exclusions, error) {
+	var exs exclude.Exclusions
+	if par.KubeletConfig != "" {
+		var err error
+		exs, err = exclude.FromKubeletConfig(par.KubeletConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(exs, par.HPExclusions...), nil
 }
 
 func (par *Params) ParseFlags() {
 	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		return
+	}
+	switch args[0] {
+	case DebugCommand:
+		par.DoDebug = true
+		par.DebugArgs = args[1:]
+	case DrainCommand:
+		par.DoDrain = true
+	case UndrainCommand:
+		par.DoUndrain = true
+	case ActuatorCommand:
+		par.DoActuator = true
+	case SelftestCommand:
+		par.DoSelftest = true
+	}
 }
 
 func (par *Params) DumpFlags(lh logr.Logger) {
@@ -73,26 +293,309 @@ func (par *Params) DumpFlags(lh logr.Logger) {
 	})
 }
 
+type ContainerPolicyValue struct {
+	Policy *policy.ContainerPolicy
+}
+
+func (v ContainerPolicyValue) String() string {
+	if v.Policy == nil {
+		return ""
+	}
+	return string(*v.Policy)
+}
+
+func (v ContainerPolicyValue) Set(s string) error {
+	cp, err := policy.ParseContainerPolicy(s)
+	if err != nil {
+		return err
+	}
+	*v.Policy = cp
+	return nil
+}
+
+type SwapPolicyValue struct {
+	Policy *policy.SwapPolicy
+}
+
+func (v SwapPolicyValue) String() string {
+	if v.Policy == nil {
+		return ""
+	}
+	return string(*v.Policy)
+}
+
+func (v SwapPolicyValue) Set(s string) error {
+	sp, err := policy.ParseSwapPolicy(s)
+	if err != nil {
+		return err
+	}
+	*v.Policy = sp
+	return nil
+}
+
+type RoundingPolicyValue struct {
+	Policy *types.RoundingPolicy
+}
+
+func (v RoundingPolicyValue) String() string {
+	if v.Policy == nil {
+		return ""
+	}
+	return string(*v.Policy)
+}
+
+func (v RoundingPolicyValue) Set(s string) error {
+	rp, err := types.ParseRoundingPolicy(s)
+	if err != nil {
+		return err
+	}
+	*v.Policy = rp
+	return nil
+}
+
+type DeviceNamingValue struct {
+	Naming *sysinfo.DeviceNaming
+}
+
+func (v DeviceNamingValue) String() string {
+	if v.Naming == nil {
+		return ""
+	}
+	return string(*v.Naming)
+}
+
+func (v DeviceNamingValue) Set(s string) error {
+	dn, err := sysinfo.ParseDeviceNaming(s)
+	if err != nil {
+		return err
+	}
+	*v.Naming = dn
+	return nil
+}
+
+type NodeLabelsModeValue struct {
+	Mode *nodelabels.Mode
+}
+
+func (v NodeLabelsModeValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v NodeLabelsModeValue) Set(s string) error {
+	md, err := nodelabels.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = md
+	return nil
+}
+
+type UnpublishModeValue struct {
+	Mode *unpublish.Mode
+}
+
+func (v UnpublishModeValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v UnpublishModeValue) Set(s string) error {
+	md, err := unpublish.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = md
+	return nil
+}
+
+type HPReservationValue struct {
+	Policy *reserve.Policy
+}
+
+func (v HPReservationValue) String() string {
+	if v.Policy == nil {
+		return ""
+	}
+	return string(*v.Policy)
+}
+
+func (v HPReservationValue) Set(s string) error {
+	pol, err := reserve.ParsePolicy(s)
+	if err != nil {
+		return err
+	}
+	*v.Policy = pol
+	return nil
+}
+
+type LimitsWatchdogValue struct {
+	Mode *limitwatch.Mode
+}
+
+func (v LimitsWatchdogValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v LimitsWatchdogValue) Set(s string) error {
+	md, err := limitwatch.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = md
+	return nil
+}
+
+type AdjustConflictsValue struct {
+	Mode *nriconflict.Mode
+}
+
+func (v AdjustConflictsValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v AdjustConflictsValue) Set(s string) error {
+	md, err := nriconflict.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = md
+	return nil
+}
+
+type RsvdLimitsValue struct {
+	Mode *hugepages.ReservationMode
+}
+
+func (v RsvdLimitsValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v RsvdLimitsValue) Set(s string) error {
+	rm, err := hugepages.ParseReservationMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = rm
+	return nil
+}
+
+type ZoneGoneValue struct {
+	Mode *zonegone.Mode
+}
+
+func (v ZoneGoneValue) String() string {
+	if v.Mode == nil {
+		return ""
+	}
+	return string(*v.Mode)
+}
+
+func (v ZoneGoneValue) Set(s string) error {
+	md, err := zonegone.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*v.Mode = md
+	return nil
+}
+
+type HPExclusionsValue struct {
+	Exclusions *exclude.Exclusions
+}
+
+func (v HPExclusionsValue) String() string {
+	if v.Exclusions == nil {
+		return ""
+	}
+	return v.Exclusions.String()
+}
+
+func (v HPExclusionsValue) Set(s string) error {
+	exs, err := exclude.Parse(s)
+	if err != nil {
+		return err
+	}
+	*v.Exclusions = exs
+	return nil
+}
+
+type GuardBandsValue struct {
+	Bands *guardband.Bands
+}
+
+func (v GuardBandsValue) String() string {
+	if v.Bands == nil {
+		return ""
+	}
+	return v.Bands.String()
+}
+
+func (v GuardBandsValue) Set(s string) error {
+	bds, err := guardband.Parse(s)
+	if err != nil {
+		return err
+	}
+	*v.Bands = bds
+	return nil
+}
+
+// version is the semantic version of the driver, set at build time with
+// -ldflags "-X github.com/ffromani/dra-driver-memory/pkg/command.version=v1.2.3"
+var version string
+
 type Version struct {
-	Golang string
-	Build  string
+	// Version is the semantic version, empty if unknown
+	Version string `json:"version"`
+	Golang  string `json:"golang"`
+	Build   string `json:"build"`
 }
 
+// String returns the known parts of the version, separated by spaces.
+func (ver Version) String() string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{ver.Version, ver.Build, ver.Golang} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// GetVersion returns the version of the running binary. Returns false if neither
+// the semantic version nor the VCS revision are known.
 func GetVersion() (Version, bool) {
+	ver := Version{
+		Version: version,
+	}
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
-		return Version{}, false
+		return ver, ver.Version != ""
 	}
-	ver := Version{
-		Golang: info.GoVersion,
+	ver.Golang = info.GoVersion
+	if ver.Version == "" && info.Main.Version != "(devel)" {
+		// installed with `go install module@version`
+		ver.Version = info.Main.Version
 	}
 	for _, f := range info.Settings {
 		if f.Key == "vcs.revision" {
 			ver.Build = f.Value
-			return ver, true
 		}
 	}
-	return ver, false
+	return ver, ver.Version != "" || ver.Build != ""
 }
 
 func printVersion(lh logr.Logger) {
@@ -100,5 +603,25 @@ func printVersion(lh logr.Logger) {
 	if !ok {
 		return
 	}
-	lh.Info(ProgramName, "golang", ver.Golang, "build", ver.Build)
+	lh.Info(ProgramName, "version", ver.Version, "golang", ver.Golang, "build", ver.Build)
+}
+
+type FailpointsValue struct {
+	Names *[]failpoint.Name
+}
+
+func (v FailpointsValue) String() string {
+	if v.Names == nil {
+		return ""
+	}
+	return failpoint.String(*v.Names)
+}
+
+func (v FailpointsValue) Set(s string) error {
+	names, err := failpoint.Parse(s)
+	if err != nil {
+		return err
+	}
+	*v.Names = names
+	return nil
 }
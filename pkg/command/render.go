@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ffromani/dra-driver-memory/pkg/driver"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/subpools"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+)
+
+// RenderSlices runs the discovery only, and writes the ResourceSlices the driver would publish
+// on this node, with no claims allocated. Neither the API server nor the container runtime are involved,
+// so the output can be shared to report the topology of the node.
+func RenderSlices(params Params, logger logr.Logger) error {
+	nodeName, err := nodeutil.GetHostname(params.HostnameOverride)
+	if err != nil {
+		return fmt.Errorf("cannot obtain the node name, use the hostname-override flag if you want to set it to a specific value: %w", err)
+	}
+
+	discoverer := sysinfo.NewDiscoverer(params.SysRoot)
+	discoverer.AlignmentAttributes = params.AlignAttributes
+	discoverer.PagesCapacity = params.PagesCapacity
+	if params.DeviceNaming != "" {
+		discoverer.DeviceNaming = params.DeviceNaming
+	}
+	discoverer.NodeName = nodeName
+	if params.HugepagesPools != "" {
+		discoverer.SubPools, err = subpools.Load(params.HugepagesPools)
+		if err != nil {
+			return fmt.Errorf("cannot load the hugepages pools: %w", err)
+		}
+	}
+	discoverer.ExcludedHugepages, err = params.HugepagesExclusions()
+	if err != nil {
+		return fmt.Errorf("cannot read the excluded hugepages: %w", err)
+	}
+	discoverer.MemoryGuardBands = params.MemoryGuardBands
+	if err := discoverer.Refresh(logger); err != nil {
+		return err
+	}
+	pools := sysinfo.ReadHugepagesPools(logger, params.SysRoot, discoverer.GetCachedMachineData())
+
+	var buf bytes.Buffer
+	for _, obj := range makeResourceSlices(driver.Name, nodeName, discoverer.ResourceSlicesWithFreeCapacity(nil, pools)) {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("marshaling ResourceSlice %q: %w", obj.GenerateName, err)
+		}
+		fmt.Fprintf(&buf, "---\n%s", data)
+	}
+
+	if params.RenderOutput == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(params.RenderOutput, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	logger.Info("rendered the ResourceSlices", "node", nodeName, "path", params.RenderOutput)
+	return nil
+}
+
+// makeResourceSlices fills the slices of the node pool like the kubelet plugin does when publishing them.
+// The slices are sorted by the name of their first device, for the output to be stable across runs.
+func makeResourceSlices(driverName, nodeName string, nodeSlices []resourceslice.Slice) []resourceapi.ResourceSlice {
+	slices.SortFunc(nodeSlices, func(a, b resourceslice.Slice) int {
+		return strings.Compare(firstDeviceName(a), firstDeviceName(b))
+	})
+	ret := make([]resourceapi.ResourceSlice, 0, len(nodeSlices))
+	for _, slice := range nodeSlices {
+		ret = append(ret, resourceapi.ResourceSlice{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: resourceapi.SchemeGroupVersion.String(),
+				Kind:       "ResourceSlice",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: nodeName + "-" + driverName + "-",
+			},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver:   driverName,
+				NodeName: &nodeName,
+				Pool: resourceapi.ResourcePool{
+					Name:               nodeName,
+					Generation:         1,
+					ResourceSliceCount: int64(len(nodeSlices)),
+				},
+				Devices: slice.Devices,
+			},
+		})
+	}
+	return ret
+}
+
+func firstDeviceName(slice resourceslice.Slice) string {
+	if len(slice.Devices) == 0 {
+		return ""
+	}
+	return slice.Devices[0].Name
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/driver"
+	"github.com/ffromani/dra-driver-memory/pkg/selftest"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+)
+
+const SelftestCommand = "selftest"
+
+// Selftest runs the enforcement pipeline on this node, like `dramemory selftest`, to check the driver
+// can enforce the claims before scheduling real workloads. Neither the API server nor the container
+// runtime are involved. The cgroup steps need the privileges of the daemon writing by itself.
+func Selftest(params Params, logger logr.Logger) error {
+	err := params.ResolveCgroupMount(logger)
+	if err != nil {
+		return err
+	}
+	machine, err := sysinfo.GetMachineData(logger, params.SysRoot)
+	if err != nil {
+		return fmt.Errorf("cannot discover the memory: %w", err)
+	}
+	rep := selftest.Run(logger, machine, selftest.Config{
+		DriverName:   driver.Name,
+		CgroupMount:  params.CgroupMount,
+		CgroupParent: params.SelftestParent,
+	})
+	rep.Print(os.Stdout)
+	return rep.Err()
+}
@@ -28,6 +28,12 @@ func Validate(params Params, setupLogger logr.Logger) error {
 	if err := sysinfo.Validate(setupLogger, params.ProcRoot); err != nil {
 		return err
 	}
+	if err := params.ResolveCgroupMount(setupLogger); err != nil {
+		return err
+	}
+	if err := sysinfo.ValidateRuntime(setupLogger, params.RuntimeConfig()); err != nil {
+		return err
+	}
 	fmt.Println("PASS")
 	return nil
 }
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debounce
+
+import (
+	"context"
+	"time"
+)
+
+// The Debouncer coalesces the requests to run an expensive action, like publishing the
+// ResourceSlices, fired by independent triggers. The first request opens a window; all
+// the requests received until the window closes are served by a single run of the action.
+// Requests received while the action runs are served by the next run.
+
+// RunFunc is the action. The triggers are the reasons of the coalesced requests, in arrival order.
+type RunFunc func(ctx context.Context, triggers []string)
+
+type Debouncer struct {
+	window  time.Duration
+	pending chan string
+}
+
+// New creates a Debouncer with the given window. A zero window runs the action as soon as
+// possible, still coalescing the requests received while the action runs.
+func New(window time.Duration) *Debouncer {
+	return &Debouncer{
+		window:  window,
+		pending: make(chan string, 1),
+	}
+}
+
+// Request asks to run the action. Never blocks. Returns false if the request was coalesced
+// with a pending one, which will serve both. A nil Debouncer accepts and discards all the requests.
+func (db *Debouncer) Request(trigger string) bool {
+	if db == nil {
+		return true
+	}
+	select {
+	case db.pending <- trigger:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run serves the requests until the context is done.
+func (db *Debouncer) Run(ctx context.Context, fn RunFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case trigger := <-db.pending:
+			if !db.wait(ctx) {
+				return
+			}
+			fn(ctx, append([]string{trigger}, db.drain()...))
+		}
+	}
+}
+
+func (db *Debouncer) wait(ctx context.Context) bool {
+	if db.window <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(db.window)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (db *Debouncer) drain() []string {
+	var triggers []string
+	for {
+		select {
+		case trigger := <-db.pending:
+			triggers = append(triggers, trigger)
+		default:
+			return triggers
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debounce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	runs := make(chan []string, 8)
+	db := New(200 * time.Millisecond)
+	go db.Run(ctx, func(_ context.Context, triggers []string) {
+		runs <- triggers
+	})
+
+	accepted := 0
+	for _, trigger := range []string{"periodic", "claims", "claims", "claims", "discovery"} {
+		if db.Request(trigger) {
+			accepted++
+		}
+	}
+	// the first request may be picked up before the others are sent
+	require.GreaterOrEqual(t, accepted, 1)
+	require.LessOrEqual(t, accepted, 2)
+
+	got := <-runs
+	require.Len(t, got, accepted)
+	require.Equal(t, "periodic", got[0])
+	require.Never(t, func() bool { return len(runs) > 0 }, 400*time.Millisecond, 20*time.Millisecond, "requests served twice")
+
+	require.True(t, db.Request("periodic"))
+	require.Equal(t, []string{"periodic"}, <-runs)
+}
+
+func TestZeroWindow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	runs := make(chan []string)
+	db := New(0)
+	go db.Run(ctx, func(_ context.Context, triggers []string) {
+		runs <- triggers
+	})
+
+	require.True(t, db.Request("claims"))
+	require.Equal(t, []string{"claims"}, <-runs)
+}
+
+func TestStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := New(time.Hour)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		db.Run(ctx, func(_ context.Context, _ []string) {
+			t.Errorf("unexpected run")
+		})
+	}()
+	require.True(t, db.Request("claims"))
+	cancel()
+	<-done
+}
+
+func TestNil(t *testing.T) {
+	var db *Debouncer
+	require.True(t, db.Request("claims"))
+}
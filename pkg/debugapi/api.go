@@ -0,0 +1,91 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"time"
+)
+
+// The debug API exposes the internal view of the driver, for the humans troubleshooting a node.
+// It is served on a local unix socket only, and is not meant to be stable: don't build automation on it.
+
+// Claims is the state of the claims tracked by the driver, and of the pods they are bound to.
+type Claims struct {
+	NodeName string `json:"nodeName"`
+	// Devices are the devices currently published, sorted by name
+	Devices []Device `json:"devices,omitempty"`
+	Claims  []Claim  `json:"claims"`
+	Pods    []Pod    `json:"pods"`
+}
+
+// Device is a published device, and its capacity not yet allocated to the claims.
+type Device struct {
+	Name string `json:"name"`
+	// Resource is the canonical name, like `memory` or `hugepages-2Mi`
+	Resource string `json:"resource"`
+	// Pool is the sub-pool of the device, if the pool of its zone is split
+	Pool           string `json:"pool,omitempty"`
+	NUMAZone       int64  `json:"numaZone"`
+	Bytes          int64  `json:"bytes"`
+	RemainingBytes int64  `json:"remainingBytes"`
+}
+
+type Claim struct {
+	UID string `json:"uid"`
+	// ReservedFor is the UID of the pod the claim is reserved for, learned when the claim was prepared
+	ReservedFor string `json:"reservedFor,omitempty"`
+	// Owner is the container the claim is bound to, learned when the container was created
+	Owner       *Owner       `json:"owner,omitempty"`
+	Allocations []Allocation `json:"allocations"`
+}
+
+type Owner struct {
+	PodUID        string `json:"podUID"`
+	ContainerName string `json:"containerName"`
+}
+
+type Allocation struct {
+	// Resource is the canonical name, like `memory` or `hugepages-2Mi`
+	Resource        string          `json:"resource"`
+	Bytes           int64           `json:"bytes"`
+	BytesByNUMAZone map[int64]int64 `json:"bytesByNUMAZone"`
+}
+
+type Pod struct {
+	UID          string `json:"uid,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Name         string `json:"name,omitempty"`
+	SandboxID    string `json:"sandboxID,omitempty"`
+	CgroupParent string `json:"cgroupParent,omitempty"`
+	// Claims are the UIDs of the claims of the pod, sorted
+	Claims []string `json:"claims"`
+	// Containers maps the containers to the sorted UIDs of the claims they consume
+	Containers map[string][]string `json:"containers,omitempty"`
+	// Limits are the hugetlb limits of the pod cgroup, like `2MB=64MB`. Empty if the driver doesn't manage the cgroups.
+	Limits []string `json:"limits,omitempty"`
+	// LastEvent is the last NRI event the driver received for the pod
+	LastEvent *Event `json:"lastEvent,omitempty"`
+}
+
+// Event is a NRI event received by the driver.
+type Event struct {
+	// Name is the NRI hook, like `CreateContainer`
+	Name string `json:"name"`
+	// ContainerName is empty for the events of the pod sandbox
+	ContainerName string    `json:"containerName,omitempty"`
+	Time          time.Time `json:"time"`
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+const none = "<none>"
+
+// WriteClaims prints the state of the claims for humans, in the style of `kubectl describe`.
+// `now` is used to show the age of the events.
+func WriteClaims(out io.Writer, data Claims, now time.Time) error {
+	podNames := make(map[string]string, len(data.Pods))
+	for _, pod := range data.Pods {
+		if pod.UID != "" && pod.Name != "" {
+			podNames[pod.UID] = pod.Namespace + "/" + pod.Name
+		}
+	}
+	podName := func(podUID string) string {
+		if name, ok := podNames[podUID]; ok {
+			return name + " (" + podUID + ")"
+		}
+		return podUID
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Node:\t%s\n", data.NodeName)
+
+	if len(data.Devices) > 0 {
+		fmt.Fprintf(w, "\nDevices:\n")
+		for _, dev := range data.Devices {
+			resourceName := dev.Resource
+			if dev.Pool != "" {
+				resourceName += " (pool " + dev.Pool + ")"
+			}
+			fmt.Fprintf(w, "  %s\t%s\tNUMA zone: %d\tremaining: %s of %s\n", dev.Name, resourceName, dev.NUMAZone, sizeString(dev.RemainingBytes), sizeString(dev.Bytes))
+		}
+	}
+
+	fmt.Fprintf(w, "\nClaims:\n")
+	if len(data.Claims) == 0 {
+		fmt.Fprintf(w, "  %s\n", none)
+	}
+	for _, claim := range data.Claims {
+		fmt.Fprintf(w, "  UID:\t%s\n", claim.UID)
+		fmt.Fprintf(w, "  Reserved For:\t%s\n", orNone(podName(claim.ReservedFor)))
+		if claim.Owner != nil {
+			fmt.Fprintf(w, "  Bound To:\t%s container %s\n", podName(claim.Owner.PodUID), claim.Owner.ContainerName)
+		} else {
+			fmt.Fprintf(w, "  Bound To:\t%s\n", none)
+		}
+		fmt.Fprintf(w, "  Allocations:\n")
+		for _, alloc := range claim.Allocations {
+			fmt.Fprintf(w, "    %s\t%s\tNUMA zones: %s\n", alloc.Resource, sizeString(alloc.Bytes), bytesByNUMAZoneString(alloc.BytesByNUMAZone))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	fmt.Fprintf(w, "Pods:\n")
+	if len(data.Pods) == 0 {
+		fmt.Fprintf(w, "  %s\n", none)
+	}
+	for _, pod := range data.Pods {
+		name := ""
+		if pod.Name != "" {
+			name = pod.Namespace + "/" + pod.Name
+		}
+		fmt.Fprintf(w, "  Name:\t%s\n", orNone(name))
+		fmt.Fprintf(w, "  UID:\t%s\n", orNone(pod.UID))
+		fmt.Fprintf(w, "  Sandbox ID:\t%s\n", orNone(pod.SandboxID))
+		fmt.Fprintf(w, "  Cgroup Parent:\t%s\n", orNone(pod.CgroupParent))
+		fmt.Fprintf(w, "  Claims:\t%s\n", orNone(strings.Join(pod.Claims, ", ")))
+		fmt.Fprintf(w, "  Limits:\t%s\n", orNone(strings.Join(pod.Limits, ", ")))
+		fmt.Fprintf(w, "  Last NRI Event:\t%s\n", eventString(pod.LastEvent, now))
+		// the nested lists go last, or they would break the alignment of the fields
+		fmt.Fprintf(w, "  Containers:\n")
+		if len(pod.Containers) == 0 {
+			fmt.Fprintf(w, "    %s\n", none)
+		}
+		for _, containerName := range slices.Sorted(maps.Keys(pod.Containers)) {
+			fmt.Fprintf(w, "    %s:\t%s\n", containerName, strings.Join(pod.Containers[containerName], ", "))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return w.Flush()
+}
+
+func eventString(ev *Event, now time.Time) string {
+	if ev == nil {
+		return none
+	}
+	name := ev.Name
+	if ev.ContainerName != "" {
+		name += " (container " + ev.ContainerName + ")"
+	}
+	age := now.Sub(ev.Time).Truncate(time.Second)
+	return fmt.Sprintf("%s %s ago", name, age)
+}
+
+func bytesByNUMAZoneString(bytesByZone map[int64]int64) string {
+	items := make([]string, 0, len(bytesByZone))
+	for _, numaZone := range slices.Sorted(maps.Keys(bytesByZone)) {
+		items = append(items, fmt.Sprintf("%d=%s", numaZone, sizeString(bytesByZone[numaZone])))
+	}
+	return orNone(strings.Join(items, ", "))
+}
+
+func sizeString(size int64) string {
+	return unitconv.SizeInBytesToMinimizedString(uint64(size))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteClaims(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	data := Claims{
+		NodeName: "node-0",
+		Claims: []Claim{
+			{
+				UID:         "claim-A",
+				ReservedFor: "pod-A",
+				Owner:       &Owner{PodUID: "pod-A", ContainerName: "app"},
+				Allocations: []Allocation{
+					{Resource: "hugepages-2Mi", Bytes: 64 << 20, BytesByNUMAZone: map[int64]int64{1: 32 << 20, 0: 32 << 20}},
+				},
+			},
+			{
+				UID:         "claim-B",
+				ReservedFor: "pod-B",
+				Allocations: []Allocation{
+					{Resource: "memory", Bytes: 1 << 30, BytesByNUMAZone: map[int64]int64{0: 1 << 30}},
+				},
+			},
+		},
+		Pods: []Pod{
+			{
+				UID:          "pod-A",
+				Namespace:    "ns",
+				Name:         "app",
+				SandboxID:    "sandbox-A",
+				CgroupParent: "/kubepods/podA",
+				Claims:       []string{"claim-A"},
+				Containers:   map[string][]string{"app": {"claim-A"}},
+				Limits:       []string{"2MB=64MB"},
+				LastEvent:    &Event{Name: "CreateContainer", ContainerName: "app", Time: now.Add(-90 * time.Second)},
+			},
+		},
+	}
+
+	var sb strings.Builder
+	require.NoError(t, WriteClaims(&sb, data, now))
+	out := sb.String()
+	for _, expected := range []string{
+		"Reserved For:  ns/app (pod-A)\n",
+		"Bound To:      ns/app (pod-A) container app\n",
+		"hugepages-2Mi  64Mi  NUMA zones: 0=32Mi, 1=32Mi\n",
+		"Reserved For:  pod-B\n",
+		"Bound To:      <none>\n",
+		"Cgroup Parent:   /kubepods/podA\n",
+		"Limits:          2MB=64MB\n",
+		"Last NRI Event:  CreateContainer (container app) 1m30s ago\n",
+		"Containers:\n    app:  claim-A\n",
+	} {
+		require.Contains(t, out, expected)
+	}
+}
+
+func TestWriteClaimsDevices(t *testing.T) {
+	data := Claims{
+		NodeName: "node-0",
+		Devices: []Device{
+			{Name: "hugepages-2mi-aaaaaa", Resource: "hugepages-2Mi", NUMAZone: 1, Bytes: 64 << 20, RemainingBytes: 32 << 20},
+			{Name: "memory-bbbbbb", Resource: "memory", NUMAZone: 0, Bytes: 4 << 30, RemainingBytes: 4 << 30},
+			{Name: "hugepages-1gi-cccccc", Resource: "hugepages-1Gi", Pool: "dpdk", NUMAZone: 0, Bytes: 8 << 30, RemainingBytes: 8 << 30},
+		},
+	}
+
+	var sb strings.Builder
+	require.NoError(t, WriteClaims(&sb, data, time.Now()))
+	out := sb.String()
+	for _, expected := range []string{
+		"Devices:\n",
+		"hugepages-2mi-aaaaaa  hugepages-2Mi              NUMA zone: 1  remaining: 32Mi of 64Mi\n",
+		"memory-bbbbbb         memory                     NUMA zone: 0  remaining: 4Gi of 4Gi\n",
+		"hugepages-1gi-cccccc  hugepages-1Gi (pool dpdk)  NUMA zone: 0  remaining: 8Gi of 8Gi\n",
+	} {
+		require.Contains(t, out, expected)
+	}
+}
+
+func TestWriteClaimsEmpty(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, WriteClaims(&sb, Claims{NodeName: "node-0"}, time.Now()))
+	require.Equal(t, "Node:  node-0\n\nClaims:\n  <none>\nPods:\n  <none>\n", sb.String())
+}
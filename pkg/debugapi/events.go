@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"sync"
+	"time"
+)
+
+// PodEvent is the last NRI event received for a pod sandbox.
+type PodEvent struct {
+	SandboxID    string
+	PodUID       string
+	Namespace    string
+	Name         string
+	CgroupParent string
+	Event        Event
+}
+
+// EventLog keeps the last NRI event of each pod sandbox. Safe for concurrent use.
+type EventLog struct {
+	mu                 sync.Mutex
+	lastEventBySandbox map[string]PodEvent
+}
+
+func NewEventLog() *EventLog {
+	return &EventLog{
+		lastEventBySandbox: make(map[string]PodEvent),
+	}
+}
+
+// Record replaces the last event of the pod sandbox. Sets the event time if missing.
+func (el *EventLog) Record(podEv PodEvent) {
+	if podEv.Event.Time.IsZero() {
+		podEv.Event.Time = time.Now()
+	}
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	el.lastEventBySandbox[podEv.SandboxID] = podEv
+}
+
+// Forget drops the pod sandbox, once removed.
+func (el *EventLog) Forget(sandboxID string) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	delete(el.lastEventBySandbox, sandboxID)
+}
+
+// Get returns the last event of the pod sandbox, if any.
+func (el *EventLog) Get(sandboxID string) (PodEvent, bool) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	podEv, ok := el.lastEventBySandbox[sandboxID]
+	return podEv, ok
+}
+
+// FindByPodUID returns the last event of the most recent pod sandbox of the pod, if any.
+func (el *EventLog) FindByPodUID(podUID string) (PodEvent, bool) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	var ret PodEvent
+	found := false
+	for _, podEv := range el.lastEventBySandbox {
+		if podEv.PodUID != podUID {
+			continue
+		}
+		if !found || podEv.Event.Time.After(ret.Event.Time) {
+			ret = podEv
+			found = true
+		}
+	}
+	return ret, found
+}
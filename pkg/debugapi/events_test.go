@@ -0,0 +1,57 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLog(t *testing.T) {
+	el := NewEventLog()
+	_, ok := el.Get("sandbox-A")
+	require.False(t, ok, "found event on empty log")
+
+	el.Record(PodEvent{SandboxID: "sandbox-A", PodUID: "pod-A", Event: Event{Name: "RunPodSandbox"}})
+	el.Record(PodEvent{SandboxID: "sandbox-A", PodUID: "pod-A", Event: Event{Name: "CreateContainer", ContainerName: "app"}})
+	podEv, ok := el.Get("sandbox-A")
+	require.True(t, ok, "missing event")
+	require.Equal(t, "CreateContainer", podEv.Event.Name)
+	require.Equal(t, "app", podEv.Event.ContainerName)
+	require.False(t, podEv.Event.Time.IsZero(), "event time not set")
+
+	el.Forget("sandbox-A")
+	_, ok = el.Get("sandbox-A")
+	require.False(t, ok, "found event of forgotten sandbox")
+}
+
+func TestEventLogFindByPodUID(t *testing.T) {
+	now := time.Now()
+	el := NewEventLog()
+	el.Record(PodEvent{SandboxID: "sandbox-old", PodUID: "pod-A", Event: Event{Name: "StopPodSandbox", Time: now.Add(-time.Minute)}})
+	el.Record(PodEvent{SandboxID: "sandbox-new", PodUID: "pod-A", Event: Event{Name: "RunPodSandbox", Time: now}})
+	el.Record(PodEvent{SandboxID: "sandbox-B", PodUID: "pod-B", Event: Event{Name: "RunPodSandbox", Time: now.Add(time.Minute)}})
+
+	podEv, ok := el.FindByPodUID("pod-A")
+	require.True(t, ok, "missing event")
+	require.Equal(t, "sandbox-new", podEv.SandboxID)
+
+	_, ok = el.FindByPodUID("pod-C")
+	require.False(t, ok, "found event of unknown pod")
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ClaimsPath = "/claims"
+
+	// requestTimeout bounds the requests of the client, the daemon answers from memory
+	requestTimeout = 10 * time.Second
+)
+
+// ClaimsFunc returns the current state of the claims. Must be safe to call concurrently with the driver hooks.
+type ClaimsFunc func() Claims
+
+// Server serves the debug API on a unix socket.
+type Server struct {
+	socketPath string
+	claims     ClaimsFunc
+}
+
+func NewServer(socketPath string, claims ClaimsFunc) *Server {
+	return &Server{
+		socketPath: socketPath,
+		claims:     claims,
+	}
+}
+
+// Run serves the requests until the context is done.
+func (srv *Server) Run(ctx context.Context, lh logr.Logger) error {
+	// a socket left behind by a previous, killed instance makes listen fail
+	err := os.Remove(srv.socketPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing stale socket %q: %w", srv.socketPath, err)
+	}
+	// the state includes the pods of all the namespaces, so root only
+	listener, err := listen(ctx, srv.socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %q: %w", srv.socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+ClaimsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(srv.claims())
+		if err != nil {
+			lh.Error(err, "encoding claims")
+		}
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	lh.Info("serving debug API", "socketPath", srv.socketPath)
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// GetClaims fetches the state of the claims from the daemon listening on `socketPath`.
+func GetClaims(ctx context.Context, socketPath string) (Claims, error) {
+	client := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	// the host is ignored, we always dial the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost"+ClaimsPath, nil)
+	if err != nil {
+		return Claims{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Claims{}, fmt.Errorf("connecting to the daemon on %q: %w", socketPath, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Claims{}, fmt.Errorf("unexpected response %q: %s", resp.Status, msg)
+	}
+	var data Claims
+	err = json.NewDecoder(resp.Body).Decode(&data)
+	if err != nil {
+		return Claims{}, fmt.Errorf("malformed response: %w", err)
+	}
+	return data, nil
+}
+
+// listen creates the socket accessible to root only. The mode is set on the socket before binding it, so no other
+// user can connect, not even briefly, and the umask of the process, shared by the files the driver writes, is left alone.
+func listen(ctx context.Context, socketPath string) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(_, _ string, conn syscall.RawConn) error {
+			var chmodErr error
+			err := conn.Control(func(fd uintptr) {
+				// the bound socket file takes the mode of the socket
+				chmodErr = unix.Fchmod(int(fd), 0600)
+			})
+			if err != nil {
+				return err
+			}
+			return chmodErr
+		},
+	}
+	return lc.Listen(ctx, "unix", socketPath)
+}
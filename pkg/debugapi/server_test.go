@@ -0,0 +1,101 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debugapi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerRoundtrip(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "dbg")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "debug.sock")
+	// left behind by a killed instance
+	require.NoError(t, os.WriteFile(socketPath, nil, 0600))
+
+	expected := Claims{
+		NodeName: "node-0",
+		Claims: []Claim{
+			{
+				UID:         "claim-A",
+				ReservedFor: "pod-A",
+				Owner:       &Owner{PodUID: "pod-A", ContainerName: "app"},
+				Allocations: []Allocation{
+					{Resource: "hugepages-2Mi", Bytes: 32 << 20, BytesByNUMAZone: map[int64]int64{0: 32 << 20}},
+				},
+			},
+		},
+		Pods: []Pod{
+			{
+				UID:        "pod-A",
+				Claims:     []string{"claim-A"},
+				Containers: map[string][]string{"app": {"claim-A"}},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := NewServer(socketPath, func() Claims { return expected })
+	done := make(chan error)
+	go func() {
+		done <- srv.Run(ctx, testr.New(t))
+	}()
+
+	var got Claims
+	require.Eventually(t, func() bool {
+		got, err = GetClaims(ctx, socketPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "cannot get claims: %v", err)
+	require.Equal(t, expected, got)
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestGetClaimsNoDaemon(t *testing.T) {
+	_, err := GetClaims(context.Background(), filepath.Join(t.TempDir(), "missing.sock"))
+	require.ErrorContains(t, err, "connecting to the daemon")
+}
+
+func TestListenRestricted(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "dbg")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	socketPath := filepath.Join(socketDir, "test.sock")
+
+	// the socket must be restricted right when it appears, whatever the umask
+	listener, err := listen(context.Background(), socketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
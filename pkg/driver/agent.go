@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/ffromani/dra-driver-memory/pkg/agentapi"
+)
+
+// AgentMachine returns the memory topology discovered at the last refresh, for the agent API.
+func (mdrv *MemoryDriver) AgentMachine() agentapi.Machine {
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	data := agentapi.Machine{
+		NodeName:      mdrv.nodeName,
+		PageSize:      machineData.Pagesize,
+		HugepageSizes: slices.Clone(machineData.Hugepagesizes),
+		Zones:         make([]agentapi.Zone, 0, len(machineData.Zones)),
+	}
+	for _, zone := range machineData.Zones {
+		agentZone := agentapi.Zone{
+			ID:          int64(zone.ID),
+			Distances:   slices.Clone(zone.Distances),
+			CPUSocketID: -1,
+		}
+		if zone.Locality != nil {
+			agentZone.CPUSocketID = zone.Locality.CPUSocketID
+			agentZone.PCIeRoots = slices.Clone(zone.Locality.PCIeRoots)
+		}
+		if zone.Memory != nil {
+			agentZone.UsableBytes = zone.Memory.TotalUsableBytes
+			for _, pageSize := range slices.Sorted(maps.Keys(zone.Memory.HugePageAmountsBySize)) {
+				amounts := zone.Memory.HugePageAmountsBySize[pageSize]
+				if amounts == nil {
+					continue
+				}
+				agentZone.Hugepages = append(agentZone.Hugepages, agentapi.HugepagesPool{
+					PageSize: pageSize,
+					Total:    amounts.Total,
+					Free:     amounts.Free,
+				})
+			}
+		}
+		data.Zones = append(data.Zones, agentZone)
+	}
+	return data
+}
+
+// AgentAllocations returns the claims prepared by the driver, for the agent API.
+func (mdrv *MemoryDriver) AgentAllocations() agentapi.Allocations {
+	data := agentapi.Allocations{
+		NodeName: mdrv.nodeName,
+		Claims:   []agentapi.Claim{},
+	}
+	// the claims are sorted by UID already
+	for _, claimState := range mdrv.allocMgr.Claims() {
+		claim := agentapi.Claim{
+			UID:         string(claimState.UID),
+			PodUID:      claimState.PodUID,
+			Allocations: []agentapi.Allocation{},
+		}
+		for _, resourceName := range slices.Sorted(maps.Keys(claimState.Allocations)) {
+			alloc := claimState.Allocations[resourceName]
+			claim.Allocations = append(claim.Allocations, agentapi.Allocation{
+				Resource:        alloc.Name(),
+				PageSize:        alloc.Pagesize,
+				Bytes:           alloc.Amount,
+				BytesByNUMAZone: maps.Clone(alloc.AmountByZone),
+			})
+		}
+		data.Claims = append(data.Claims, claim)
+	}
+	return data
+}
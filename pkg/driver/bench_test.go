@@ -0,0 +1,270 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/go-logr/logr"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/dynamic-resource-allocation/kubeletplugin"
+
+	"github.com/ffromani/dra-driver-memory/pkg/admission"
+	"github.com/ffromani/dra-driver-memory/pkg/alloc"
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/lifetime"
+	"github.com/ffromani/dra-driver-memory/pkg/policy"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The benchmarks run the DRA and NRI hooks against a fake machine, with the CDI spec
+// in a temporary directory and the direct cgroup settings disabled, to measure the
+// cost of the driver bookkeeping: the CDI spec writes and the env parsing.
+// Run with: go test -run=^$ -bench=. ./pkg/driver/
+
+var benchClaimCounts = []int{1, 50, 250}
+
+func BenchmarkPrepareResourceClaims(b *testing.B) {
+	for _, claimCount := range benchClaimCounts {
+		b.Run(fmt.Sprintf("claims=%d", claimCount), func(b *testing.B) {
+			mdrv, devName := newBenchDriver(b)
+			ctx := context.Background()
+			claims := make([]*resourceapi.ResourceClaim, 0, claimCount)
+			objs := make([]kubeletplugin.NamespacedObject, 0, claimCount)
+			for idx := range claimCount {
+				claim := makeBenchClaim(idx, devName)
+				claims = append(claims, claim)
+				objs = append(objs, kubeletplugin.NamespacedObject{UID: claim.UID})
+			}
+
+			b.ResetTimer()
+			for b.Loop() {
+				_, err := mdrv.PrepareResourceClaims(ctx, claims)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, err = mdrv.UnprepareResourceClaims(ctx, objs)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPrepareResourceClaimsParallel(b *testing.B) {
+	mdrv, devName := newBenchDriver(b)
+	ctx := context.Background()
+
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			claim := makeBenchClaim(int(seq.Add(1)), devName)
+			res, err := mdrv.PrepareResourceClaims(ctx, []*resourceapi.ResourceClaim{claim})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if res[claim.UID].Err != nil {
+				b.Fatal(res[claim.UID].Err)
+			}
+			_, err = mdrv.UnprepareResourceClaims(ctx, []kubeletplugin.NamespacedObject{{UID: claim.UID}})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCDIAddDevice measures the cost of writing the CDI spec of a device, which must not grow with the prepared claims.
+func BenchmarkCDIAddDevice(b *testing.B) {
+	for _, claimCount := range benchClaimCounts {
+		b.Run(fmt.Sprintf("claims=%d", claimCount), func(b *testing.B) {
+			mdrv, devName := newBenchDriver(b)
+			ctx := context.Background()
+			claims := make([]*resourceapi.ResourceClaim, 0, claimCount)
+			for idx := range claimCount {
+				claims = append(claims, makeBenchClaim(idx, devName))
+			}
+			_, err := mdrv.PrepareResourceClaims(ctx, claims)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			deviceName := cdi.MakeDeviceName("bench-extra-claim")
+			b.ResetTimer()
+			for b.Loop() {
+				err := mdrv.cdiMgr.AddDevice(mdrv.logger, deviceName, "FOO=bar")
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCreateContainer(b *testing.B) {
+	for _, claimCount := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("claimsPerContainer=%d", claimCount), func(b *testing.B) {
+			mdrv, devName := newBenchDriver(b)
+			ctx := context.Background()
+
+			claims := make([]*resourceapi.ResourceClaim, 0, claimCount)
+			for idx := range claimCount {
+				claims = append(claims, makeBenchClaim(idx, devName))
+			}
+			_, err := mdrv.PrepareResourceClaims(ctx, claims)
+			if err != nil {
+				b.Fatal(err)
+			}
+			spec, err := mdrv.cdiMgr.GetSpec(mdrv.logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var envs []string
+			for _, dev := range spec.Devices {
+				envs = append(envs, dev.ContainerEdits.Env...)
+			}
+
+			pod := &api.PodSandbox{
+				Id:        "bench-sandbox",
+				Uid:       "bench-pod-uid",
+				Name:      "bench-pod",
+				Namespace: "default",
+			}
+			ctr := &api.Container{
+				Id:           "bench-container",
+				PodSandboxId: pod.Id,
+				Name:         "bench",
+				Env:          append([]string{"PATH=/usr/bin:/bin", "HOME=/root"}, envs...),
+			}
+
+			b.ResetTimer()
+			for b.Loop() {
+				_, _, err := mdrv.CreateContainer(ctx, pod, ctr)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func newBenchDriver(b testing.TB) (*MemoryDriver, string) {
+	b.Helper()
+	savedSpecDir := cdi.SpecDir
+	cdi.SpecDir = b.TempDir()
+	b.Cleanup(func() {
+		cdi.SpecDir = savedSpecDir
+	})
+
+	lh := logr.Discard()
+	disc := sysinfo.NewDiscoverer(b.TempDir())
+	disc.GetMachineData = func(_ logr.Logger, _ string) (sysinfo.MachineData, error) {
+		return sysinfo.MachineData{
+			Pagesize: 4096,
+			Zones: []sysinfo.Zone{
+				{
+					ID:        0,
+					Distances: []int{10},
+					Memory: &ghwmemory.Area{
+						TotalUsableBytes: 1 << 40,
+					},
+				},
+			},
+		}, nil
+	}
+	err := disc.Refresh(lh)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cdiMgr, err := cdi.NewManager(Name, lh)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	mdrv := &MemoryDriver{
+		driverName:     Name,
+		nodeName:       "bench-node",
+		logger:         lh,
+		allocMgr:       alloc.NewTracker(),
+		bindMgr:        alloc.NewBinder(),
+		discoverer:     disc,
+		cdiMgr:         cdiMgr,
+		cgPathByPodUID: make(map[string]string),
+		podBaselines:   hugepages.NewBaselines(),
+		ctrPolicy:      policy.DefaultContainers(),
+		swapPolicy:     policy.SwapPolicyUnmanaged,
+		roundingPolicy: types.RoundingPolicyRoundUp,
+		lifetimes:      lifetime.NewTracker(),
+		admission:      admission.NewController(nil),
+	}
+	for _, slice := range disc.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			return mdrv, dev.Name
+		}
+	}
+	b.Fatal("no devices discovered")
+	return nil, ""
+}
+
+func makeBenchClaim(idx int, devName string) *resourceapi.ResourceClaim {
+	suffix := strconv.Itoa(idx)
+	return &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "bench-claim-" + suffix,
+			UID:       k8stypes.UID("bench-claim-uid-" + suffix),
+		},
+		Status: resourceapi.ResourceClaimStatus{
+			ReservedFor: []resourceapi.ResourceClaimConsumerReference{
+				{
+					Resource: "pods",
+					Name:     "bench-pod-" + suffix,
+					UID:      k8stypes.UID("bench-pod-uid-" + suffix),
+				},
+			},
+			Allocation: &resourceapi.AllocationResult{
+				Devices: resourceapi.DeviceAllocationResult{
+					Results: []resourceapi.DeviceRequestAllocationResult{
+						{
+							Request: "mem",
+							Driver:  Name,
+							Pool:    "bench-node",
+							Device:  devName,
+							ConsumedCapacity: map[resourceapi.QualifiedName]resource.Quantity{
+								"size": resource.MustParse("1Gi"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+)
+
+// DebugClaims returns the internal view of the claims and of the pods they are bound to, for the debug API.
+// The state is collected from the sub-components one at a time, so it may be slightly inconsistent
+// while claims are prepared or pods are created.
+func (mdrv *MemoryDriver) DebugClaims() debugapi.Claims {
+	lh := mdrv.logger.WithName("DebugClaims")
+	data := debugapi.Claims{
+		NodeName: mdrv.nodeName,
+		Claims:   []debugapi.Claim{},
+		Pods:     []debugapi.Pod{},
+	}
+
+	spans := mdrv.discoverer.Spans()
+	remaining := mdrv.allocMgr.RemainingBytes(spans)
+	for _, devName := range slices.Sorted(maps.Keys(spans)) {
+		span := spans[devName]
+		data.Devices = append(data.Devices, debugapi.Device{
+			Name:           devName,
+			Resource:       span.Name(),
+			Pool:           span.Pool,
+			NUMAZone:       span.NUMAZone,
+			Bytes:          span.Amount,
+			RemainingBytes: remaining[devName],
+		})
+	}
+
+	owners := mdrv.bindMgr.Owners()
+	// the pods are learned from the claims reservations (DRA) and from the sandboxes (NRI)
+	podsByUID := make(map[string]*debugapi.Pod)
+	var unknownPods []*debugapi.Pod
+	getPod := func(podUID string) *debugapi.Pod {
+		pod, ok := podsByUID[podUID]
+		if !ok {
+			pod = &debugapi.Pod{UID: podUID, Claims: []string{}}
+			podsByUID[podUID] = pod
+		}
+		return pod
+	}
+
+	for _, claimState := range mdrv.allocMgr.Claims() {
+		claim := debugapi.Claim{
+			UID:         string(claimState.UID),
+			ReservedFor: claimState.PodUID,
+		}
+		if owner, ok := owners[claimState.UID]; ok {
+			claim.Owner = &debugapi.Owner{
+				PodUID:        owner.PodUID,
+				ContainerName: owner.ContainerName,
+			}
+		}
+		for _, resourceName := range slices.Sorted(maps.Keys(claimState.Allocations)) {
+			alloc := claimState.Allocations[resourceName]
+			claim.Allocations = append(claim.Allocations, debugapi.Allocation{
+				Resource:        alloc.Name(),
+				Bytes:           alloc.Amount,
+				BytesByNUMAZone: maps.Clone(alloc.AmountByZone),
+			})
+		}
+		data.Claims = append(data.Claims, claim)
+		if claimState.PodUID != "" {
+			pod := getPod(claimState.PodUID)
+			pod.Claims = append(pod.Claims, claim.UID)
+		}
+	}
+
+	for _, podState := range mdrv.allocMgr.Pods() {
+		var pod *debugapi.Pod
+		podEv, ok := mdrv.nriEvents.Get(podState.SandboxID)
+		if ok {
+			pod = getPod(podEv.PodUID)
+			setPodEvent(pod, podEv)
+		} else {
+			pod = &debugapi.Pod{Claims: []string{}}
+			unknownPods = append(unknownPods, pod)
+		}
+		pod.SandboxID = podState.SandboxID
+		for _, claimUID := range podState.ClaimUIDs {
+			if !slices.Contains(pod.Claims, string(claimUID)) {
+				pod.Claims = append(pod.Claims, string(claimUID))
+			}
+		}
+		pod.Containers = make(map[string][]string, len(podState.ClaimUIDsByContainer))
+		for containerName, claimUIDs := range podState.ClaimUIDsByContainer {
+			pod.Containers[containerName] = claimUIDsToStrings(claimUIDs)
+		}
+	}
+
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	for _, podUID := range slices.Sorted(maps.Keys(podsByUID)) {
+		pod := podsByUID[podUID]
+		if pod.SandboxID == "" {
+			// the claims are prepared before the sandbox is created
+			if podEv, ok := mdrv.nriEvents.FindByPodUID(podUID); ok {
+				pod.SandboxID = podEv.SandboxID
+				setPodEvent(pod, podEv)
+			}
+		}
+		if mdrv.cgMount != "" && pod.CgroupParent != "" {
+			limits, err := hugepages.LimitsFromSystemPath(lh, machineData, filepath.Join(mdrv.cgMount, pod.CgroupParent))
+			if err != nil {
+				lh.V(2).Error(err, "reading pod cgroup limits", "podUID", podUID, "cgroupParent", pod.CgroupParent)
+			}
+			for _, limit := range limits {
+				pod.Limits = append(pod.Limits, limit.String())
+			}
+		}
+		slices.Sort(pod.Claims)
+		data.Pods = append(data.Pods, *pod)
+	}
+	for _, pod := range unknownPods {
+		slices.Sort(pod.Claims)
+		data.Pods = append(data.Pods, *pod)
+	}
+	return data
+}
+
+func setPodEvent(pod *debugapi.Pod, podEv debugapi.PodEvent) {
+	pod.Namespace = podEv.Namespace
+	pod.Name = podEv.Name
+	pod.CgroupParent = podEv.CgroupParent
+	lastEvent := podEv.Event
+	pod.LastEvent = &lastEvent
+}
+
+func claimUIDsToStrings(claimUIDs []k8stypes.UID) []string {
+	ret := make([]string, 0, len(claimUIDs))
+	for _, claimUID := range claimUIDs {
+		ret = append(ret, string(claimUID))
+	}
+	return ret
+}
@@ -18,22 +18,46 @@ package driver
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
+	"path/filepath"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	cdiparser "tags.cncf.io/container-device-interface/pkg/parser"
+	cdiSpec "tags.cncf.io/container-device-interface/specs-go"
 
+	corev1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	resourcev1ac "k8s.io/client-go/applyconfigurations/resource/v1"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
+	"k8s.io/utils/ptr"
 
+	"github.com/ffromani/dra-driver-memory/pkg/admission"
+	"github.com/ffromani/dra-driver-memory/pkg/capabilities"
 	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/claimconfig"
 	"github.com/ffromani/dra-driver-memory/pkg/env"
+	"github.com/ffromani/dra-driver-memory/pkg/failpoint"
+	"github.com/ffromani/dra-driver-memory/pkg/hugetlbfs"
+	"github.com/ffromani/dra-driver-memory/pkg/nodelabels"
+	"github.com/ffromani/dra-driver-memory/pkg/podannotation"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unpublish"
+	"github.com/ffromani/dra-driver-memory/pkg/zonegone"
 )
 
 // This is the DRA frontend. Allocation, if and when required, will happen at this layer.
@@ -49,20 +73,207 @@ func (mdrv *MemoryDriver) PublishResources(ctx context.Context) {
 	err := mdrv.discoverer.Refresh(lh)
 	if err != nil {
 		lh.Error(err, "enumerating memory resources")
+		publicationFailuresTotal.WithLabelValues(publishStageDiscovery).Inc()
 		return
 	}
 
-	resources := resourceslice.DriverResources{
-		Pools: map[string]resourceslice.Pool{
-			mdrv.nodeName: {
-				Slices: mdrv.discoverer.ResourceSlices(),
-			},
-		},
+	mdrv.requestPublish(lh, publishTriggerDiscovery)
+	mdrv.publishNodeFacts(ctx, lh)
+	mdrv.publishCapabilities(ctx, lh)
+}
+
+// The triggers of the publication of the ResourceSlices.
+const (
+	publishTriggerDiscovery   = "discovery"
+	publishTriggerPeriodic    = "periodic"
+	publishTriggerClaims      = "claims"
+	publishTriggerMaintenance = "maintenance"
+	publishTriggerZoneGone    = "zonegone"
+)
+
+// requestPublish asks to publish the ResourceSlices. The requests are coalesced by the
+// publisher, so the triggers firing together cause a single publication.
+func (mdrv *MemoryDriver) requestPublish(lh logr.Logger, trigger string) {
+	if mdrv.publisher.Request(trigger) {
+		return
+	}
+	lh.V(4).Info("publication coalesced", "trigger", trigger)
+	publicationsSuppressedTotal.WithLabelValues(trigger).Inc()
+}
+
+// runPublisher publishes the ResourceSlices on request until the context is done.
+func (mdrv *MemoryDriver) runPublisher(ctx context.Context) {
+	lh := mdrv.logrFromContext(ctx)
+	lh = lh.WithName("Publisher")
+	mdrv.publisher.Run(ctx, func(ctx context.Context, triggers []string) {
+		lh.V(2).Info("publishing", "triggers", triggers)
+		for _, trigger := range triggers[1:] {
+			publicationsSuppressedTotal.WithLabelValues(trigger).Inc()
+		}
+		mdrv.publishSlices(ctx, lh)
+		publicationsTotal.Inc()
+	})
+}
+
+// checkDraining updates the draining state of the node. The resources are withdrawn while the node
+// is in maintenance, or while it is cordoned if the unpublish mode is enabled.
+// Must be called only by the publisher.
+func (mdrv *MemoryDriver) checkDraining(ctx context.Context, lh logr.Logger) {
+	st, err := unpublish.GetNodeState(ctx, mdrv.kubeClient, mdrv.nodeName)
+	if err != nil {
+		lh.Error(err, "checking if the node is draining")
+		return
+	}
+	draining := st.Withdrawn(mdrv.unpublishMode)
+	switch {
+	case draining && mdrv.drainingSince == nil:
+		now := time.Now()
+		mdrv.drainingSince = &now
+		lh.Info("node draining, withdrawing resources", "mode", unpublish.WithdrawMode(mdrv.unpublishMode), "maintenance", st.Maintenance)
+	case !draining && mdrv.drainingSince != nil:
+		mdrv.drainingSince = nil
+		lh.Info("node not draining anymore, publishing resources")
+	}
+}
+
+// onNodeUpdate asks to publish the resources when the node enters or leaves the maintenance, or is cordoned
+// or uncordoned with the unpublish mode enabled, so the change takes effect without waiting for the next
+// periodic publication.
+func (mdrv *MemoryDriver) onNodeUpdate(lh logr.Logger, oldNode, newNode *corev1.Node) {
+	oldSt, newSt := unpublish.NodeStateOf(oldNode), unpublish.NodeStateOf(newNode)
+	withdrawn := newSt.Withdrawn(mdrv.unpublishMode)
+	if withdrawn == oldSt.Withdrawn(mdrv.unpublishMode) {
+		return
+	}
+	lh.V(2).Info("node state changed", "withdrawn", withdrawn, "maintenance", newSt.Maintenance, "cordoned", newSt.Cordoned)
+	mdrv.requestPublish(lh, publishTriggerMaintenance)
+}
+
+// publishNodeFacts mirrors the discovery facts into node labels, if enabled.
+// This is a convenience for the admins, so failures are not fatal.
+func (mdrv *MemoryDriver) publishNodeFacts(ctx context.Context, lh logr.Logger) {
+	if !mdrv.nodeLabels.IsEnabled() {
+		return
+	}
+	facts := nodelabels.FactsFromMachine(mdrv.discoverer.GetCachedMachineData(), sysinfo.HasCXL(lh, mdrv.sysRoot))
+	err := nodelabels.Publish(ctx, lh, mdrv.nodeLabels, mdrv.kubeClient, mdrv.nodeName, facts.Labels())
+	if err != nil {
+		lh.Error(err, "publishing node facts", "mode", mdrv.nodeLabels.Mode)
+	}
+}
+
+// publishCapabilities documents the resources and the features of the driver on the node in a ConfigMap, if enabled.
+// Like the node facts, this is a convenience, so failures are not fatal.
+func (mdrv *MemoryDriver) publishCapabilities(ctx context.Context, lh logr.Logger) {
+	if mdrv.capsNamespace == "" {
+		return
 	}
+	var devices []resourceapi.Device
+	for _, slice := range mdrv.currentSlices(lh) {
+		devices = append(devices, slice.Devices...)
+	}
+	caps := capabilities.FromDevices(mdrv.driverName, mdrv.nodeName, devices, mdrv.discoverer.Spans(), mdrv.features)
+	err := capabilities.Publish(ctx, lh, mdrv.kubeClient, mdrv.capsNamespace, caps)
+	if err != nil {
+		lh.Error(err, "publishing capabilities", "namespace", mdrv.capsNamespace)
+	}
+}
+
+// RefreshResources keeps publishing the resources every `interval` until the context is done,
+// so the free capacity attributes stay fresh. The hardware is not discovered again.
+func (mdrv *MemoryDriver) RefreshResources(ctx context.Context, interval time.Duration) {
+	lh := mdrv.logrFromContext(ctx)
+	lh = lh.WithName("RefreshResources")
+	lh.V(2).Info("start", "interval", interval)
+	defer lh.V(2).Info("done")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mdrv.requestPublish(lh, publishTriggerPeriodic)
+		}
+	}
+}
+
+// currentSlices returns the slices of the node with the current free capacity.
+func (mdrv *MemoryDriver) currentSlices(lh logr.Logger) []resourceslice.Slice {
+	hpPools := sysinfo.ReadHugepagesPools(lh, mdrv.sysRoot, mdrv.discoverer.GetCachedMachineData())
+	return mdrv.discoverer.ResourceSlicesWithFreeCapacity(mdrv.allocMgr.RemainingBytes(mdrv.discoverer.Spans()), hpPools)
+}
+
+// withoutZonesGone leaves out of the slices, or taints according to the mode, the devices of the NUMA zones
+// gone. The zones back online are forgotten first, so their devices are published again as usual.
+func (mdrv *MemoryDriver) withoutZonesGone(lh logr.Logger, nodeSlices []resourceslice.Slice) []resourceslice.Slice {
+	back := mdrv.zonesGone.Recheck(func(zoneID int64) bool {
+		return sysinfo.IsZoneGone(mdrv.sysRoot, zoneID)
+	})
+	for _, zoneID := range back {
+		lh.Info("NUMA zone back, publishing its devices", "numaNode", zoneID)
+	}
+	zonesGoneGauge.Set(float64(len(mdrv.zonesGone.Zones())))
 
-	err = mdrv.draPlugin.PublishResources(ctx, resources)
+	spans := mdrv.discoverer.Spans()
+	zoneOf := func(devName string) (int64, bool) {
+		span, ok := spans[devName]
+		return span.NUMAZone, ok
+	}
+	for idx := range nodeSlices {
+		nodeSlices[idx].Devices = mdrv.zonesGone.Devices(nodeSlices[idx].Devices, zoneOf)
+	}
+	return nodeSlices
+}
+
+func (mdrv *MemoryDriver) publishSlices(ctx context.Context, lh logr.Logger) {
+	nodeSlices := mdrv.withoutZonesGone(lh, mdrv.currentSlices(lh))
+	resources := resourceslice.DriverResources{}
+
+	mdrv.checkDraining(ctx, lh)
+	switch {
+	case mdrv.drainingSince == nil:
+		resources.Pools = map[string]resourceslice.Pool{mdrv.nodeName: {Slices: nodeSlices}}
+	case unpublish.WithdrawMode(mdrv.unpublishMode) == unpublish.ModeTaint:
+		for idx := range nodeSlices {
+			nodeSlices[idx].Devices = unpublish.TaintDevices(nodeSlices[idx].Devices, *mdrv.drainingSince)
+		}
+		resources.Pools = map[string]resourceslice.Pool{mdrv.nodeName: {Slices: nodeSlices}}
+	}
+	// otherwise the pool is gone, and the kubelet plugin deletes its slices
+
+	err := mdrv.draPlugin.PublishResources(ctx, resources)
 	if err != nil {
 		lh.Error(err, "publishing resources through DRA")
+		publicationFailuresTotal.WithLabelValues(publishStagePublish).Inc()
+		return
+	}
+	now := time.Now()
+	publicationHealth.Succeeded(now)
+	lastPublicationTimestamp.Set(float64(now.Unix()))
+	mdrv.recordPublishedDevices(resources)
+}
+
+// recordPublishedDevices updates the metrics of the devices published, by resource.
+func (mdrv *MemoryDriver) recordPublishedDevices(resources resourceslice.DriverResources) {
+	counts := make(map[string]int)
+	for resourceName := range mdrv.discoverer.AllResourceNames() {
+		counts[resourceName] = 0 // the resources withdrawn must be reported as such
+	}
+	for _, pool := range resources.Pools {
+		for _, slice := range pool.Slices {
+			for _, dev := range slice.Devices {
+				span, err := mdrv.discoverer.GetSpanForDevice(logr.Discard(), dev.Name)
+				if err != nil {
+					continue
+				}
+				counts[span.Name()]++
+			}
+		}
+	}
+	for resourceName, count := range counts {
+		publishedDevices.WithLabelValues(resourceName).Set(float64(count))
 	}
 }
 
@@ -78,8 +289,10 @@ func (mdrv *MemoryDriver) PrepareResourceClaims(ctx context.Context, claims []*r
 	}
 
 	for _, claim := range claims {
-		result[claim.UID] = mdrv.prepareResourceClaim(lh, claim)
+		result[claim.UID] = mdrv.prepareResourceClaim(ctx, lh, claim)
 	}
+	// the free capacity changed, and the hugepages pools may have grown
+	mdrv.requestPublish(lh, publishTriggerClaims)
 	return result, nil
 }
 
@@ -96,12 +309,13 @@ func (mdrv *MemoryDriver) UnprepareResourceClaims(ctx context.Context, claims []
 	}
 
 	for _, claim := range claims {
-		err := mdrv.unprepareResourceClaim(lh, claim)
+		err := mdrv.unprepareResourceClaim(ctx, lh, claim)
 		result[claim.UID] = err
 		if err != nil {
 			lh.Error(err, "unpreparing resources", "claim", claim.String())
 		}
 	}
+	mdrv.requestPublish(lh, publishTriggerClaims)
 	return result, nil
 }
 
@@ -112,7 +326,7 @@ func (mdrv *MemoryDriver) HandleError(ctx context.Context, err error, msg string
 	lh.Error(err, msg)
 }
 
-func (mdrv *MemoryDriver) prepareResourceClaim(lh logr.Logger, claim *resourceapi.ResourceClaim) kubeletplugin.PrepareResult {
+func (mdrv *MemoryDriver) prepareResourceClaim(ctx context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim) kubeletplugin.PrepareResult {
 	lh = lh.WithValues("claim", claim.String())
 
 	// Get pod info from claim
@@ -139,14 +353,20 @@ func (mdrv *MemoryDriver) prepareResourceClaim(lh logr.Logger, claim *resourceap
 	lh.V(4).Info("CDI data", "DeviceName", deviceName, "qualifiedName", qualifiedName)
 
 	var envs []string
+	var devStatuses []resourceapi.AllocatedDeviceStatus
 	preparedDevices := []kubeletplugin.Device{}
 	claimAllocs := make(map[string]types.Allocation)
 	claimNodes := sets.New[int64]()
+	var adminDevices []env.AdminDevice
 	for _, devRes := range claim.Status.Allocation.Devices.Results {
 		if devRes.Driver != mdrv.driverName {
 			continue
 		}
 
+		// the devices of a gone zone can be withdrawn already, so the zone is checked before the span
+		if zoneID, ok := mdrv.discoverer.ZoneOfDevice(devRes.Device); ok && sysinfo.IsZoneGone(mdrv.sysRoot, zoneID) {
+			return mdrv.failZoneGone(ctx, lh, claim, devRes, zoneID)
+		}
 		span, err := mdrv.discoverer.GetSpanForDevice(lh, devRes.Device)
 		if err != nil {
 			return kubeletplugin.PrepareResult{
@@ -154,7 +374,25 @@ func (mdrv *MemoryDriver) prepareResourceClaim(lh logr.Logger, claim *resourceap
 			}
 		}
 
-		capName := span.CapacityName()
+		if ptr.Deref(devRes.AdminAccess, false) {
+			// the device is observed, not consumed: no capacity is accounted and nothing is enforced
+			lh.V(2).Info("prepareResourceClaim admin access", "device", devRes.Device, "resource", span.Name(), "numaNode", span.NUMAZone)
+			adminDevices = append(adminDevices, env.AdminDevice{Name: devRes.Device, Span: span})
+			preparedDevices = append(preparedDevices, kubeletplugin.Device{
+				PoolName:     devRes.Pool,
+				DeviceName:   devRes.Device,
+				CDIDeviceIDs: []string{qualifiedName},
+			})
+			continue
+		}
+
+		// the requests can be in bytes or, for hugepages, in pages
+		capName, requested, requestFound, err := span.ResolveRequest(requestedCapacities(claim, devRes.Request))
+		if err != nil {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("device %q request %q: %w", devRes.Device, devRes.Request, err),
+			}
+		}
 		capList := slices.Collect(maps.Keys(devRes.ConsumedCapacity))
 		lh.V(4).Info("consumed capacity", "expected", capName, "effective", capList)
 		res, ok := devRes.ConsumedCapacity[capName]
@@ -163,19 +401,44 @@ func (mdrv *MemoryDriver) prepareResourceClaim(lh logr.Logger, claim *resourceap
 				Err: fmt.Errorf("device %q not matches consumed capacity. Expected: %q Consumed: %q", devRes.Device, capName, capList),
 			}
 		}
-		amount, ok := res.AsInt64()
+		value, ok := res.AsInt64()
 		if !ok {
 			return kubeletplugin.PrepareResult{
 				Err: fmt.Errorf("device %q not matches consumed capacity. Expected: %q Consumed: %q", devRes.Device, capName, capList),
 			}
 		}
+		amount := span.CapacityBytes(capName, value)
+		// the cgroups can be programmed only with amounts which make sense for the resource
+		if err := span.ValidateAmount(amount); err != nil {
+			return mdrv.failDevice(ctx, lh, claim, devRes, fmt.Errorf("device %q consumed capacity: %w", devRes.Device, err))
+		}
 
-		alloc := span.MakeAllocation(amount)
-		envs = append(envs, env.CreateAlloc(lh, claim.UID, alloc))
+		// the scheduler rounds the requests according to the capacity request policy, so we need to check
+		// the original request to apply the rounding policy.
+		if !requestFound {
+			requested = amount
+		}
+		alloc, err := span.MakeRoundedAllocation(requested, mdrv.roundingPolicy)
+		if err != nil {
+			return mdrv.failDevice(ctx, lh, claim, devRes, fmt.Errorf("device %q request %q: %w", devRes.Device, devRes.Request, err))
+		}
+		if alloc.Amount != amount {
+			// the scheduler already accounted the consumed capacity, which is thus authoritative
+			lh.V(2).Info("rounded amount differs from the consumed capacity", "device", devRes.Device, "requested", requested, "rounded", alloc.Amount, "consumed", amount)
+			alloc = span.MakeAllocation(amount)
+		}
+		devStatuses = append(devStatuses, makeDeviceStatus(devRes, requested, alloc.Amount, mdrv.roundingPolicy))
 
-		lh.V(2).Info("prepareResourceClaim", "device", devRes.Device, "resource", alloc.Name(), "amountBytes", alloc.Amount, "amount", alloc.ToQuantityString(), "numaNode", alloc.NUMAZone)
+		lh.V(2).Info("prepareResourceClaim", "device", devRes.Device, "resource", alloc.Name(), "amountBytes", alloc.Amount, "amount", alloc.ToQuantityString(), "numaNodes", alloc.NUMAZones())
+		if prev, ok := claimAllocs[alloc.Name()]; ok {
+			merged, err := prev.Merge(alloc)
+			if err != nil {
+				return kubeletplugin.PrepareResult{Err: err}
+			}
+			alloc = merged
+		}
 		claimAllocs[alloc.Name()] = alloc
-		claimNodes.Insert(alloc.NUMAZone)
+		claimNodes.Insert(alloc.NUMAZones()...)
 		preparedDevices = append(preparedDevices, kubeletplugin.Device{
 			PoolName:     devRes.Pool,
 			DeviceName:   devRes.Device,
@@ -183,29 +446,515 @@ func (mdrv *MemoryDriver) prepareResourceClaim(lh logr.Logger, claim *resourceap
 		})
 	}
 
-	if len(claimAllocs) == 0 {
+	if len(claimAllocs) == 0 && len(adminDevices) == 0 {
 		lh.V(2).Info("no valid allocation for this driver")
 		return kubeletplugin.PrepareResult{}
 	}
+	if len(claimAllocs) == 0 {
+		return mdrv.prepareAdminAccessClaim(lh, claim, deviceName, adminDevices, preparedDevices)
+	}
+
+	err := mdrv.admitClaim(ctx, lh, claim, claimAllocs)
+	if err != nil {
+		return kubeletplugin.PrepareResult{
+			Err: fmt.Errorf("claim %s admission: %w", claim.String(), err),
+		}
+	}
+	// fail early if the hugepages are missing, rather than later in the pod at mmap time
+	resized, err := mdrv.hpReserver.Reserve(lh, claim.UID, slices.Collect(maps.Values(claimAllocs)))
+	if err != nil {
+		mdrv.admission.Release(lh, claim.UID)
+		return kubeletplugin.PrepareResult{
+			Err: fmt.Errorf("claim %s hugepages reservation: %w", claim.String(), err),
+		}
+	}
+	if resized {
+		mdrv.publishResizedPools(ctx, lh)
+	}
+	prepared := false
+	defer func() {
+		if prepared {
+			return
+		}
+		mdrv.admission.Release(lh, claim.UID)
+		// the hugetlbfs instances hold the hugepages they reserved until unmounted
+		err := mdrv.hpMounter.UnmountAll(lh, claim.UID)
+		if err != nil {
+			lh.Error(err, "unmounting hugetlbfs instances")
+		}
+		resized, err := mdrv.hpReserver.Release(lh, claim.UID)
+		if err != nil {
+			lh.Error(err, "releasing hugepages reservation")
+		}
+		if resized {
+			mdrv.publishResizedPools(ctx, lh)
+		}
+	}()
+
+	// multiple devices of the same resource are merged, so we need to emit the envs only once per resource
+	for _, resourceName := range slices.Sorted(maps.Keys(claimAllocs)) {
+		envs = append(envs, env.CreateAlloc(lh, claim.UID, claimAllocs[resourceName]))
+	}
+	envs = append(envs, env.CreateClaim(lh, claim.UID), env.CreateNUMANodes(lh, claim.UID, claimNodes))
+	envs = append(envs, env.CreateApp(lh, slices.Collect(maps.Values(claimAllocs)), claimNodes)...)
+	if len(adminDevices) > 0 {
+		envs = append(envs, env.CreateAdminAccess(lh, claim.UID, adminDevices))
+	}
 
-	envs = append(envs, env.CreateNUMANodes(lh, claim.UID, claimNodes))
+	cfgs, err := claimconfig.Decode(mdrv.driverName, claim)
+	if err != nil {
+		return kubeletplugin.PrepareResult{
+			Err: fmt.Errorf("claim %s configuration: %w", claim.String(), err),
+		}
+	}
+	if cfgs.Burstable != nil {
+		memAlloc, ok := claimAllocs[string(types.Memory)]
+		if !ok {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("claim %s burstable memory: the claim has no memory, hugepages can't be burstable", claim.String()),
+			}
+		}
+		envs = append(envs, env.CreateMemoryHigh(lh, claim.UID, memAlloc.Amount))
+	}
+	var mounts []*cdiSpec.Mount
+	if cfgs.VirtualMachine != nil {
+		vmEnvs, vmMounts, err := mdrv.prepareVirtualMachine(lh, claim.UID, cfgs.VirtualMachine, claimAllocs)
+		if err != nil {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("claim %s virtual machine: %w", claim.String(), err),
+			}
+		}
+		envs = append(envs, vmEnvs...)
+		mounts = append(mounts, vmMounts...)
+	}
+	if cfgs.HugepagesFile != nil {
+		fileEnvs, fileMounts, err := mdrv.prepareHugepagesFile(lh, claim.UID, cfgs.HugepagesFile, claimAllocs)
+		if err != nil {
+			return kubeletplugin.PrepareResult{
+				Err: fmt.Errorf("claim %s hugepages file: %w", claim.String(), err),
+			}
+		}
+		envs = append(envs, fileEnvs...)
+		mounts = append(mounts, fileMounts...)
+	}
 
-	err := mdrv.cdiMgr.AddDevice(lh, deviceName, envs...)
+	err = mdrv.cdiMgr.AddDeviceWithEdits(lh, deviceName, cdiSpec.ContainerEdits{
+		Env:    envs,
+		Mounts: mounts,
+	})
 	if err != nil {
 		return kubeletplugin.PrepareResult{
 			Err: err,
 		}
 	}
+	mdrv.failpoints.Hit(lh, failpoint.PrepareAfterCDIWrite)
 
 	mdrv.allocMgr.RegisterClaim(claim.UID, claimAllocs)
+	mdrv.allocMgr.ReserveClaim(claim.UID, string(claim.Status.ReservedFor[0].UID))
+	mdrv.lifetimes.Register(lifetimeClaim(claim), lifetimePolicy(cfgs.Lifetime), time.Now())
+	mdrv.updateClaimStatus(ctx, lh, claim, devStatuses)
+	mdrv.annotatePod(ctx, lh, claim, claimAllocs)
+	prepared = true
+
+	return kubeletplugin.PrepareResult{
+		Devices: preparedDevices,
+	}
+}
+
+// admitClaim checks the claim fits the limits of the admission policy of its namespace and of the priority class of its pod.
+func (mdrv *MemoryDriver) admitClaim(ctx context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim, claimAllocs map[string]types.Allocation) error {
+	req := admission.Request{
+		ClaimUID:  claim.UID,
+		Namespace: claim.Namespace,
+		Amounts:   make(map[string]int64, len(claimAllocs)),
+	}
+	for resourceName, alloc := range claimAllocs {
+		req.Amounts[resourceName] = alloc.Amount
+	}
+	if mdrv.admission.NeedsPriorityClass() {
+		owner := claim.Status.ReservedFor[0]
+		pod, err := mdrv.kubeClient.CoreV1().Pods(claim.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("getting the pod %s/%s: %w", claim.Namespace, owner.Name, err)
+		}
+		req.PriorityClass = pod.Spec.PriorityClassName
+	}
+	return mdrv.admission.Admit(lh, req)
+}
 
+// prepareAdminAccessClaim prepares a claim whose devices are all requested with admin access, typically
+// by monitoring agents. The container gets the information about the devices, but the claim is not
+// tracked: it doesn't consume the free capacity, and its containers are neither pinned nor limited.
+func (mdrv *MemoryDriver) prepareAdminAccessClaim(lh logr.Logger, claim *resourceapi.ResourceClaim, deviceName string, adminDevices []env.AdminDevice, preparedDevices []kubeletplugin.Device) kubeletplugin.PrepareResult {
+	err := mdrv.cdiMgr.AddDeviceWithEdits(lh, deviceName, cdiSpec.ContainerEdits{
+		Env: []string{env.CreateClaim(lh, claim.UID), env.CreateAdminAccess(lh, claim.UID, adminDevices)},
+	})
+	if err != nil {
+		return kubeletplugin.PrepareResult{
+			Err: err,
+		}
+	}
 	return kubeletplugin.PrepareResult{
 		Devices: preparedDevices,
 	}
 }
 
-func (mdrv *MemoryDriver) unprepareResourceClaim(lh logr.Logger, claim kubeletplugin.NamespacedObject) error {
+// failDevice reports in the claim status why the device cannot be prepared, and fails the preparation.
+func (mdrv *MemoryDriver) failDevice(ctx context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim, devRes resourceapi.DeviceRequestAllocationResult, err error) kubeletplugin.PrepareResult {
+	devStatus := makeBaseDeviceStatus(devRes)
+	devStatus.Conditions = []metav1.Condition{
+		{
+			Type:               DeviceConditionPrepared,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: claim.Generation,
+			LastTransitionTime: metav1.Now(),
+			Reason:             failureReason(err),
+			Message:            err.Error(),
+		},
+	}
+	mdrv.updateClaimStatus(ctx, lh, claim, []resourceapi.AllocatedDeviceStatus{devStatus})
+	return kubeletplugin.PrepareResult{
+		Err: err,
+	}
+}
+
+// failZoneGone fails the preparation of a device whose NUMA zone is gone, and asks to publish the resources
+// without the devices of the zone, or with the devices tainted, according to the mode. The kubelet retries
+// the preparation, which succeeds only if the zone comes back.
+func (mdrv *MemoryDriver) failZoneGone(ctx context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim, devRes resourceapi.DeviceRequestAllocationResult, zoneID int64) kubeletplugin.PrepareResult {
+	if mdrv.zonesGone.MarkGone(zoneID, time.Now()) {
+		lh.Info("NUMA zone gone, withdrawing its devices", "numaNode", zoneID, "mode", mdrv.zonesGone.Mode())
+		mdrv.requestPublish(lh, publishTriggerZoneGone)
+	}
+	zoneGonePreparationsTotal.Inc()
+	return mdrv.failDevice(ctx, lh, claim, devRes, fmt.Errorf("device %q on NUMA zone %d: %w", devRes.Device, zoneID, zonegone.ErrZoneGone))
+}
+
+// DeviceConditionPrepared is the condition of the devices in the claim status telling if the device is prepared.
+// The reasons of the failures map the validation errors of the amounts, and the zones gone.
+const (
+	DeviceConditionPrepared = "Prepared"
+
+	ReasonPrepared                = "Prepared"
+	ReasonInvalidAmount           = "InvalidAmount"
+	ReasonNotPageAligned          = "NotPageAligned"
+	ReasonBelowMinimumAllocatable = "BelowMinimumAllocatable"
+	ReasonExceedsCapacity         = "ExceedsCapacity"
+	ReasonZoneGone                = "ZoneGone"
+)
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, zonegone.ErrZoneGone):
+		return ReasonZoneGone
+	case errors.Is(err, types.ErrNotPageAligned):
+		return ReasonNotPageAligned
+	case errors.Is(err, types.ErrBelowMinimumAllocatable):
+		return ReasonBelowMinimumAllocatable
+	case errors.Is(err, types.ErrExceedsCapacity):
+		return ReasonExceedsCapacity
+	default:
+		return ReasonInvalidAmount
+	}
+}
+
+// DeviceStatusData is reported in the status of the prepared devices of the claims.
+type DeviceStatusData struct {
+	RequestedBytes int64                `json:"requestedBytes"`
+	AllocatedBytes int64                `json:"allocatedBytes"`
+	RoundingPolicy types.RoundingPolicy `json:"roundingPolicy"`
+}
+
+func makeDeviceStatus(devRes resourceapi.DeviceRequestAllocationResult, requested, allocated int64, rp types.RoundingPolicy) resourceapi.AllocatedDeviceStatus {
+	data, _ := json.Marshal(DeviceStatusData{ // can't fail
+		RequestedBytes: requested,
+		AllocatedBytes: allocated,
+		RoundingPolicy: rp,
+	})
+	devStatus := makeBaseDeviceStatus(devRes)
+	devStatus.Data = &runtime.RawExtension{Raw: data}
+	devStatus.Conditions = []metav1.Condition{
+		{
+			Type:               DeviceConditionPrepared,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonPrepared,
+		},
+	}
+	return devStatus
+}
+
+func makeBaseDeviceStatus(devRes resourceapi.DeviceRequestAllocationResult) resourceapi.AllocatedDeviceStatus {
+	devStatus := resourceapi.AllocatedDeviceStatus{
+		Driver: devRes.Driver,
+		Pool:   devRes.Pool,
+		Device: devRes.Device,
+	}
+	if devRes.ShareID != nil {
+		devStatus.ShareID = ptr.To(string(*devRes.ShareID))
+	}
+	return devStatus
+}
+
+// claimStatusQueueSize bounds the claim status updates waiting to be applied.
+const claimStatusQueueSize = 256
+
+// claimStatusUpdate is a status of the devices of a claim, to apply off the critical path of the preparation.
+type claimStatusUpdate struct {
+	lh    logr.Logger
+	claim *resourcev1ac.ResourceClaimApplyConfiguration
+}
+
+// updateClaimStatus reports the devices in the claim status. This is informational, so it is applied
+// in the background, and failures are logged but don't fail the preparation. The updates are applied
+// in order, so a later status of a device always wins over an earlier one.
+func (mdrv *MemoryDriver) updateClaimStatus(_ context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim, devStatuses []resourceapi.AllocatedDeviceStatus) {
+	if mdrv.kubeClient == nil || len(devStatuses) == 0 {
+		return
+	}
+	upd := claimStatusUpdate{
+		lh:    lh.WithValues("claim", claim.Namespace+"/"+claim.Name),
+		claim: claimStatusApplyConfig(mdrv.driverName, claim, devStatuses),
+	}
+	select {
+	case mdrv.claimStatuses <- upd:
+	default:
+		lh.Info("claim status updates queue full, status dropped")
+	}
+}
+
+// runClaimStatusUpdates applies the claim status updates, until the context is done.
+func (mdrv *MemoryDriver) runClaimStatusUpdates(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case upd := <-mdrv.claimStatuses:
+			mdrv.applyClaimStatus(ctx, upd)
+		}
+	}
+}
+
+// applyClaimStatus applies the status of the devices of the driver with a server-side apply, so it doesn't
+// depend on the version of the claim the kubelet passed, and doesn't touch the devices of the other drivers.
+func (mdrv *MemoryDriver) applyClaimStatus(ctx context.Context, upd claimStatusUpdate) {
+	claims := mdrv.kubeClient.ResourceV1().ResourceClaims(*upd.claim.Namespace)
+	err := retry.OnError(retry.DefaultBackoff, isRetriableStatusError, func() error {
+		_, err := claims.ApplyStatus(ctx, upd.claim, metav1.ApplyOptions{FieldManager: mdrv.driverName, Force: true})
+		return err
+	})
+	if err != nil {
+		upd.lh.Error(err, "updating claim status")
+	}
+}
+
+func isRetriableStatusError(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err)
+}
+
+// claimStatusApplyConfig returns the status of all the devices of the driver in the claim, updated with `devStatuses`.
+// The apply must include all of them: the devices the driver set before and doesn't include would be removed.
+func claimStatusApplyConfig(driverName string, claim *resourceapi.ResourceClaim, devStatuses []resourceapi.AllocatedDeviceStatus) *resourcev1ac.ResourceClaimApplyConfiguration {
+	var merged []resourceapi.AllocatedDeviceStatus
+	for _, cur := range claim.Status.Devices {
+		if cur.Driver == driverName {
+			merged = append(merged, cur)
+		}
+	}
+	for _, devStatus := range devStatuses {
+		idx := slices.IndexFunc(merged, func(cur resourceapi.AllocatedDeviceStatus) bool {
+			return sameDevice(cur, devStatus)
+		})
+		if idx == -1 {
+			merged = append(merged, devStatus)
+			continue
+		}
+		merged[idx].Data = devStatus.Data
+		merged[idx].Conditions = devStatus.Conditions
+	}
+	status := resourcev1ac.ResourceClaimStatus()
+	for _, devStatus := range merged {
+		status.WithDevices(deviceStatusApplyConfig(devStatus))
+	}
+	return resourcev1ac.ResourceClaim(claim.Name, claim.Namespace).WithStatus(status)
+}
+
+func deviceStatusApplyConfig(devStatus resourceapi.AllocatedDeviceStatus) *resourcev1ac.AllocatedDeviceStatusApplyConfiguration {
+	ac := resourcev1ac.AllocatedDeviceStatus().
+		WithDriver(devStatus.Driver).
+		WithPool(devStatus.Pool).
+		WithDevice(devStatus.Device)
+	if devStatus.ShareID != nil {
+		ac.WithShareID(*devStatus.ShareID)
+	}
+	if devStatus.Data != nil {
+		ac.WithData(*devStatus.Data)
+	}
+	for _, cond := range devStatus.Conditions {
+		ac.WithConditions(metav1ac.Condition().
+			WithType(cond.Type).
+			WithStatus(cond.Status).
+			WithObservedGeneration(cond.ObservedGeneration).
+			WithLastTransitionTime(cond.LastTransitionTime).
+			WithReason(cond.Reason).
+			WithMessage(cond.Message))
+	}
+	return ac
+}
+
+// annotatePod summarizes the allocations of the claim in an annotation of the pod consuming it.
+// This is informational, so failures are logged but don't fail the preparation.
+func (mdrv *MemoryDriver) annotatePod(ctx context.Context, lh logr.Logger, claim *resourceapi.ResourceClaim, claimAllocs map[string]types.Allocation) {
+	if mdrv.kubeClient == nil || !mdrv.annotatePods {
+		return
+	}
+	owner := claim.Status.ReservedFor[0]
+	if owner.APIGroup != "" || owner.Resource != "pods" {
+		lh.V(4).Info("claim not reserved for a pod, skipped annotation", "APIGroup", owner.APIGroup, "resource", owner.Resource)
+		return
+	}
+	pod := podannotation.Pod{
+		Namespace: claim.Namespace,
+		Name:      owner.Name,
+		UID:       owner.UID,
+	}
+	summary := podannotation.Summary(claim.Name, slices.Collect(maps.Values(claimAllocs)))
+	err := podannotation.Annotate(ctx, lh, mdrv.kubeClient, pod, claim.UID, summary)
+	if err != nil {
+		lh.Error(err, "annotating pod", "pod", owner.Name)
+	}
+}
+
+func sameDevice(a, b resourceapi.AllocatedDeviceStatus) bool {
+	return a.Driver == b.Driver && a.Pool == b.Pool && a.Device == b.Device && ptr.Equal(a.ShareID, b.ShareID)
+}
+
+// requestedCapacities returns the capacities originally requested by the claim for the request `requestName`,
+// which can refer to a subrequest (`request/subrequest`). Values not representable as int64 are skipped.
+func requestedCapacities(claim *resourceapi.ResourceClaim, requestName string) map[resourceapi.QualifiedName]int64 {
+	reqName, subReqName, _ := strings.Cut(requestName, "/")
+	var capReqs *resourceapi.CapacityRequirements
+	for _, req := range claim.Spec.Devices.Requests {
+		if req.Name != reqName {
+			continue
+		}
+		if req.Exactly != nil {
+			capReqs = req.Exactly.Capacity
+		}
+		for _, subReq := range req.FirstAvailable {
+			if subReq.Name == subReqName {
+				capReqs = subReq.Capacity
+			}
+		}
+	}
+	if capReqs == nil {
+		return nil
+	}
+	requests := make(map[resourceapi.QualifiedName]int64, len(capReqs.Requests))
+	for capName, qty := range capReqs.Requests {
+		if value, ok := qty.AsInt64(); ok {
+			requests[capName] = value
+		}
+	}
+	return requests
+}
+
+func (mdrv *MemoryDriver) unprepareResourceClaim(ctx context.Context, lh logr.Logger, claim kubeletplugin.NamespacedObject) error {
 	lh = lh.WithValues("claim", claim.String())
 	mdrv.allocMgr.UnregisterClaim(claim.UID)
-	return mdrv.cdiMgr.RemoveDevice(lh, cdi.MakeDeviceName(claim.UID))
+	mdrv.lifetimes.Unregister(claim.UID)
+	mdrv.admission.Release(lh, claim.UID)
+	resized, rsvErr := mdrv.hpReserver.Release(lh, claim.UID)
+	if resized {
+		mdrv.publishResizedPools(ctx, lh)
+	}
+	return errors.Join(
+		mdrv.cdiMgr.RemoveDevice(lh, cdi.MakeDeviceName(claim.UID)),
+		mdrv.hpMounter.UnmountAll(lh, claim.UID),
+		rsvErr,
+	)
+}
+
+// publishResizedPools discovers and publishes the resources again in the background after the
+// reservation resized the hugepages pools, so the devices report the pools the claims can consume.
+func (mdrv *MemoryDriver) publishResizedPools(ctx context.Context, lh logr.Logger) {
+	go mdrv.PublishResources(logr.NewContext(context.WithoutCancel(ctx), lh))
+}
+
+// prepareHugepagesFile reserves the hugepages of the claim with a hugetlbfs instance of the claim, creates a file
+// on it sized as the claim, and computes the container edits to expose it. The pages are charged to the pods
+// mapping the file, like any other hugepages of the claim.
+func (mdrv *MemoryDriver) prepareHugepagesFile(lh logr.Logger, claimUID k8stypes.UID, hfCfg *claimconfig.HugepagesFileConfig, claimAllocs map[string]types.Allocation) ([]string, []*cdiSpec.Mount, error) {
+	var hpAllocs []types.Allocation
+	for _, alloc := range claimAllocs {
+		if alloc.NeedsHugeTLB() {
+			hpAllocs = append(hpAllocs, alloc)
+		}
+	}
+	if len(hpAllocs) != 1 {
+		return nil, nil, fmt.Errorf("requires exactly one hugepages resource, found %d", len(hpAllocs))
+	}
+	alloc := hpAllocs[0]
+	hostPath := hugetlbfs.MountPath(claimUID, alloc.Name())
+	err := mdrv.hpMounter.Mount(lh, claimUID, alloc.Name(), alloc.Pagesize, alloc.Amount, alloc.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = mdrv.hpMounter.CreateFile(lh, claimUID, alloc.Name(), hfCfg.Name(), alloc.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	lh.V(2).Info("hugepages file", "resource", alloc.Name(), "size", alloc.Amount, "mountPath", hfCfg.MountPath, "fileName", hfCfg.Name())
+	envs := []string{
+		env.CreateHugepagesFile(lh, claimUID, filepath.Join(hfCfg.MountPath, hfCfg.Name())),
+	}
+	mounts := []*cdiSpec.Mount{
+		{
+			HostPath:      hostPath,
+			ContainerPath: hfCfg.MountPath,
+			Type:          "bind",
+			Options:       []string{"rbind", "rw"},
+		},
+	}
+	return envs, mounts, nil
+}
+
+// prepareVirtualMachine computes the container edits to back the guest memory of a VM with the hugepages of the claim.
+func (mdrv *MemoryDriver) prepareVirtualMachine(lh logr.Logger, claimUID k8stypes.UID, vmCfg *claimconfig.VirtualMachineMemoryConfig, claimAllocs map[string]types.Allocation) ([]string, []*cdiSpec.Mount, error) {
+	var hpAllocs []types.Allocation
+	for _, alloc := range claimAllocs {
+		if alloc.NeedsHugeTLB() {
+			hpAllocs = append(hpAllocs, alloc)
+		}
+	}
+	if len(hpAllocs) != 1 {
+		return nil, nil, fmt.Errorf("requires exactly one hugepages resource, found %d", len(hpAllocs))
+	}
+	alloc := hpAllocs[0]
+	guestMemory, err := vmCfg.GuestMemory(alloc.Amount, alloc.Pagesize)
+	if err != nil {
+		return nil, nil, err
+	}
+	lh.V(2).Info("virtual machine", "resource", alloc.Name(), "guestMemory", guestMemory, "hugetlbfsMountPath", vmCfg.HugeTLBFSMountPath)
+	envs := []string{
+		env.CreateGuestMemory(lh, claimUID, guestMemory),
+	}
+	if vmCfg.HugeTLBFSMountPath == "" {
+		return envs, nil, nil
+	}
+	hostPath := hugetlbfs.MountPath(claimUID, alloc.Name())
+	err = mdrv.hpMounter.Mount(lh, claimUID, alloc.Name(), alloc.Pagesize, alloc.Amount, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	envs = append(envs, env.CreateHugeTLBFS(lh, claimUID, vmCfg.HugeTLBFSMountPath))
+	mounts := []*cdiSpec.Mount{
+		{
+			HostPath:      hostPath,
+			ContainerPath: vmCfg.HugeTLBFSMountPath,
+			Type:          "bind",
+			Options:       []string{"rbind", "rw"},
+		},
+	}
+	return envs, mounts, nil
 }
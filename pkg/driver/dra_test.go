@@ -0,0 +1,213 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ffromani/dra-driver-memory/pkg/debounce"
+	"github.com/ffromani/dra-driver-memory/pkg/failpoint"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unpublish"
+)
+
+func TestClaimStatusApplyConfig(t *testing.T) {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim", Namespace: "ns"},
+		Status: resourceapi.ResourceClaimStatus{
+			Devices: []resourceapi.AllocatedDeviceStatus{
+				{Driver: "other.driver", Pool: "node", Device: "gpu-0"},
+				{Driver: "dra.memory", Pool: "node", Device: "memory-a"},
+				{
+					Driver: "dra.memory", Pool: "node", Device: "hugepages-2m-b",
+					Data: &runtime.RawExtension{Raw: []byte(`{"allocated":"2097152"}`)},
+				},
+			},
+		},
+	}
+	devStatuses := []resourceapi.AllocatedDeviceStatus{
+		{
+			Driver: "dra.memory", Pool: "node", Device: "hugepages-2m-b",
+			Data: &runtime.RawExtension{Raw: []byte(`{"allocated":"4194304"}`)},
+		},
+	}
+
+	ac := claimStatusApplyConfig("dra.memory", claim, devStatuses)
+	require.Equal(t, "claim", *ac.Name)
+	require.Equal(t, "ns", *ac.Namespace)
+	// the devices of the other drivers are left alone, the ones of the driver set before are kept
+	require.Len(t, ac.Status.Devices, 2)
+	require.Equal(t, "memory-a", *ac.Status.Devices[0].Device)
+	require.Equal(t, "hugepages-2m-b", *ac.Status.Devices[1].Device)
+	require.JSONEq(t, `{"allocated":"4194304"}`, string(ac.Status.Devices[1].Data.Raw))
+}
+
+func TestOnNodeUpdate(t *testing.T) {
+	plain := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}}
+	inMaintenance := plain.DeepCopy()
+	inMaintenance.Annotations = map[string]string{unpublish.AnnotationMaintenance: "2026-01-01T00:00:00Z"}
+	cordoned := plain.DeepCopy()
+	cordoned.Spec.Unschedulable = true
+
+	testcases := []struct {
+		name     string
+		mode     unpublish.Mode
+		oldNode  *corev1.Node
+		newNode  *corev1.Node
+		expected bool
+	}{
+		{name: "unchanged", mode: unpublish.ModeNone, oldNode: plain, newNode: plain},
+		{name: "maintenance started", mode: unpublish.ModeNone, oldNode: plain, newNode: inMaintenance, expected: true},
+		{name: "maintenance ended", mode: unpublish.ModeNone, oldNode: inMaintenance, newNode: plain, expected: true},
+		{name: "cordoned without unpublish", mode: unpublish.ModeNone, oldNode: plain, newNode: cordoned},
+		{name: "cordoned with unpublish", mode: unpublish.ModeTaint, oldNode: plain, newNode: cordoned, expected: true},
+	}
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			mdrv := &MemoryDriver{
+				publisher:     debounce.New(0),
+				unpublishMode: tcase.mode,
+			}
+			mdrv.onNodeUpdate(testr.New(t), tcase.oldNode, tcase.newNode)
+			// a pending request makes the next one coalesce
+			require.Equal(t, tcase.expected, !mdrv.publisher.Request("probe"))
+		})
+	}
+}
+
+func TestNodeInformerRequestsPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cli := fake.NewClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}})
+	mdrv := &MemoryDriver{publisher: debounce.New(0)}
+	triggers := make(chan string, 16)
+	go mdrv.publisher.Run(ctx, func(_ context.Context, trgs []string) {
+		for _, trg := range trgs {
+			triggers <- trg
+		}
+	})
+	mdrv.startNodeInformer(ctx, Environment{Logger: testr.New(t), Clientset: cli, NodeName: "node-0"})
+
+	// the changes made before the informer synced are not updates, so toggle until one is seen
+	inMaintenance := false
+	require.Eventually(t, func() bool {
+		select {
+		case trg := <-triggers:
+			return trg == publishTriggerMaintenance
+		default:
+		}
+		inMaintenance = !inMaintenance
+		require.NoError(t, unpublish.SetMaintenance(ctx, testr.New(t), cli, "node-0", inMaintenance, time.Now()))
+		return false
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestPrepareFailureUnmountsHugeTLBFS(t *testing.T) {
+	mdrv, _ := newBenchDriver(t)
+	lh := testr.New(t)
+	mdrv.discoverer.GetMachineData = func(_ logr.Logger, _ string) (sysinfo.MachineData, error) {
+		return sysinfo.MachineData{
+			Pagesize:      4096,
+			Hugepagesizes: []uint64{2 << 20},
+			Zones: []sysinfo.Zone{
+				{
+					ID:        0,
+					Distances: []int{10},
+					Memory: &ghwmemory.Area{
+						TotalUsableBytes: 1 << 40,
+						HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+							2 << 20: {Total: 512},
+						},
+					},
+				},
+			},
+		}, nil
+	}
+	require.NoError(t, mdrv.discoverer.Refresh(lh))
+	var hpDevName string
+	for _, slice := range mdrv.discoverer.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			span, err := mdrv.discoverer.GetSpanForDevice(lh, dev.Name)
+			require.NoError(t, err)
+			if span.Kind == types.Hugepages {
+				hpDevName = dev.Name
+			}
+		}
+	}
+	require.NotEmpty(t, hpDevName, "no hugepages device discovered")
+
+	mounter := &recordingMounter{}
+	mdrv.hpMounter = mounter
+	// the driver crashes right after writing the CDI spec, but the cleanups run unlike with a real kill
+	mdrv.failpoints = failpoint.NewSet([]failpoint.Name{failpoint.PrepareAfterCDIWrite}, t.TempDir(), func() {
+		panic("failpoint")
+	})
+
+	claim := makeBenchClaim(0, hpDevName)
+	claim.Status.Allocation.Devices.Results[0].ConsumedCapacity["size"] = *resource.NewQuantity(32<<20, resource.BinarySI)
+	claim.Status.Allocation.Devices.Config = []resourceapi.DeviceAllocationConfiguration{
+		{
+			Source: resourceapi.AllocationConfigSourceClaim,
+			DeviceConfiguration: resourceapi.DeviceConfiguration{
+				Opaque: &resourceapi.OpaqueDeviceConfiguration{
+					Driver:     Name,
+					Parameters: runtime.RawExtension{Raw: []byte(`{"apiVersion": "dra.memory/v1alpha1", "kind": "HugepagesFileConfig", "mountPath": "/dev/hugepages-app"}`)},
+				},
+			},
+		},
+	}
+	require.Panics(t, func() {
+		_, _ = mdrv.PrepareResourceClaims(context.Background(), []*resourceapi.ResourceClaim{claim})
+	})
+	require.Equal(t, []string{"mount " + string(claim.UID), "create " + string(claim.UID), "unmount " + string(claim.UID)}, mounter.calls)
+}
+
+// recordingMounter records the calls, mounting needs privileges
+type recordingMounter struct {
+	calls []string
+}
+
+func (rm *recordingMounter) Mount(_ logr.Logger, claimUID k8stypes.UID, _ string, _ uint64, _, _ int64) error {
+	rm.calls = append(rm.calls, "mount "+string(claimUID))
+	return nil
+}
+
+func (rm *recordingMounter) CreateFile(_ logr.Logger, claimUID k8stypes.UID, _, _ string, _ int64) error {
+	rm.calls = append(rm.calls, "create "+string(claimUID))
+	return nil
+}
+
+func (rm *recordingMounter) UnmountAll(_ logr.Logger, claimUID k8stypes.UID) error {
+	rm.calls = append(rm.calls, "unmount "+string(claimUID))
+	return nil
+}
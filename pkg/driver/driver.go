@@ -19,22 +19,60 @@ package driver
 import (
 	"context"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"time"
+	"unicode"
 
+	"github.com/containerd/nri/pkg/api"
 	"github.com/containerd/nri/pkg/stub"
 	"github.com/go-logr/logr"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/dynamic-resource-allocation/kubeletplugin"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 
+	"github.com/ffromani/dra-driver-memory/pkg/admission"
 	"github.com/ffromani/dra-driver-memory/pkg/alloc"
 	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/debounce"
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+	"github.com/ffromani/dra-driver-memory/pkg/enforcement"
+	"github.com/ffromani/dra-driver-memory/pkg/failpoint"
+	"github.com/ffromani/dra-driver-memory/pkg/guardband"
 	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/exclude"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/subpools"
+	"github.com/ffromani/dra-driver-memory/pkg/hugetlbfs"
+	"github.com/ffromani/dra-driver-memory/pkg/lifetime"
+	"github.com/ffromani/dra-driver-memory/pkg/limitretry"
+	"github.com/ffromani/dra-driver-memory/pkg/limitwatch"
+	"github.com/ffromani/dra-driver-memory/pkg/logthrottle"
+	"github.com/ffromani/dra-driver-memory/pkg/nodelabels"
+	"github.com/ffromani/dra-driver-memory/pkg/nriconflict"
+	"github.com/ffromani/dra-driver-memory/pkg/oomwatch"
+	"github.com/ffromani/dra-driver-memory/pkg/policy"
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unpublish"
+	"github.com/ffromani/dra-driver-memory/pkg/zonegone"
 )
 
 // This is the orchestration layer. All the sub-components (DRA layer, NRI layer, CDI manager...)
@@ -47,8 +85,27 @@ const (
 
 const (
 	kubeletPluginPath = "/var/lib/kubelet/plugins"
-	// maxAttempts indicates the number of times the driver will try to recover itself before failing
-	maxAttempts = 5
+	// hpReservationsFile checkpoints the hugepages pools adjusted for the claims, in the plugin path
+	hpReservationsFile = "hugepages-reservations.json"
+	// admittedClaimsFile checkpoints the amounts held by the claims admitted by the admission policy, in the plugin path
+	admittedClaimsFile = "admitted-claims.json"
+	// nriBackoffInitial is the delay before the first attempt to restart the NRI plugin
+	nriBackoffInitial = 1 * time.Second
+	// nriBackoffCap is the maximum delay between attempts to restart the NRI plugin
+	nriBackoffCap = 2 * time.Minute
+	// enforcementCloseTimeout bounds the publication of the enforcement status on stop
+	enforcementCloseTimeout = 5 * time.Second
+	// unpublishTimeout bounds the withdrawal of the resources on stop
+	unpublishTimeout = 10 * time.Second
+	// lifetimeCheckInterval is the interval to check the claims with a lifetime policy
+	lifetimeCheckInterval = 1 * time.Minute
+)
+
+// The reasons of the degraded enforcement status.
+const (
+	reasonNRIConnecting   = "NRI plugin not connected to the container runtime yet"
+	reasonNRIDisconnected = "NRI plugin disconnected from the container runtime"
+	reasonDriverStopped   = "driver stopped"
 )
 
 // KubeletPlugin is an interface that describes the methods used from kubeletplugin.Helper.
@@ -61,6 +118,8 @@ type MemoryDriver struct {
 	driverName     string
 	nodeName       string
 	cgMount        string
+	cgWriter       cgroups.Writer
+	hpMounter      hugetlbfs.Mounter
 	logger         logr.Logger
 	kubeClient     kubernetes.Interface
 	draPlugin      KubeletPlugin
@@ -71,6 +130,41 @@ type MemoryDriver struct {
 	discoverer     *sysinfo.Discoverer
 	hpRootLimits   []hugepages.Limit
 	cgPathByPodUID map[string]string // podUID -> cgroupParent
+	podBaselines   *hugepages.Baselines
+	ctrPolicy      policy.Containers
+	podLister      corelisters.PodLister
+	oomWatcher     *oomwatch.Watcher
+	eventRecorder  record.EventRecorder
+	nriConnected   atomic.Bool
+	swapPolicy     policy.SwapPolicy
+	swapInfo       sysinfo.SwapInfo
+	roundingPolicy types.RoundingPolicy
+	sysRoot        string
+	nodeLabels     nodelabels.Config
+	hpReserver     *reserve.Reserver
+	publisher      *debounce.Debouncer
+	failpoints     *failpoint.Set
+	nriEvents      *debugapi.EventLog
+	nriLogs        *logthrottle.Limiter
+	enforcement    *enforcement.Reporter
+	unpublishMode  unpublish.Mode
+	lifetimes      *lifetime.Tracker
+	admission      *admission.Controller
+	claimStatuses  chan claimStatusUpdate
+	annotatePods   bool
+	watchdog       *limitwatch.Watchdog
+	limitsRetry    *limitretry.Queue
+	conflictMode   nriconflict.Mode
+	zonesGone      *zonegone.Tracker
+	rsvdMode       hugepages.ReservationMode
+	strictEnvs     bool
+	capsNamespace  string
+	// features tells which optional features are enabled, for the capabilities
+	features map[string]bool
+	// nriPluginName is how the runtime names the plugin, e.g. in the owners of the adjustments
+	nriPluginName string
+	// drainingSince is the time the node was seen draining, nil if not. Accessed only by the publisher.
+	drainingSince *time.Time
 }
 
 type SysinfoVerifier interface {
@@ -88,7 +182,165 @@ type Environment struct {
 	Clientset   kubernetes.Interface
 	SysVerifier SysinfoVerifier
 	SysRoot     string
+	ProcRoot    string
 	CgroupMount string
+	// ContainerPolicy controls the containers which don't consume memory claims
+	ContainerPolicy policy.Containers
+	// OOMWatchInterval is the polling interval of the memory events. Zero disables the watch.
+	OOMWatchInterval time.Duration
+	NRI              NRIConfig
+	// SwapPolicy controls the swap of the containers holding memory claims. Ignored if swap is disabled.
+	SwapPolicy policy.SwapPolicy
+	// PublishInterval is the interval to refresh the published resources. Zero means publish only once.
+	PublishInterval time.Duration
+	// PublishWindow is the window to coalesce the requests to publish the resources. Zero means no delay.
+	PublishWindow time.Duration
+	// PublishStaleAfter is the age after which the published resources are reported stale.
+	// Zero means three times the PublishInterval.
+	PublishStaleAfter time.Duration
+	// RoundingPolicy controls the requests which are not multiple of the page size.
+	RoundingPolicy types.RoundingPolicy
+	// NodeLabels controls the publishing of the discovery facts as node labels
+	NodeLabels nodelabels.Config
+	// AlignAttributes enables the attributes to align the memory with the devices of other drivers.
+	AlignAttributes bool
+	// PagesCapacity enables the capacity in pages of the hugepages devices.
+	PagesCapacity bool
+	// DeviceNaming controls the names of the devices: random, or stable across the restarts.
+	DeviceNaming sysinfo.DeviceNaming
+	// HPReservation controls the check of the free hugepages when preparing the claims.
+	HPReservation reserve.Policy
+	// HugepagesPools splits the hugepages pools of the zones into named sub-pools. Nil publishes the whole pools.
+	HugepagesPools *subpools.Config
+	// HPExclusions are the hugepages left out of the devices, like the ones the kubelet reserves to the extended resources.
+	HPExclusions exclude.Exclusions
+	// MemoryGuardBands are the memory of each zone left out of the devices, for the kernel and the device drivers.
+	MemoryGuardBands guardband.Bands
+	// Failpoints are the fault injection points enabled for the chaos tests. Never set in production.
+	Failpoints []failpoint.Name
+	// EnforcementStatus enables the node annotations telling if the driver enforces the claims.
+	EnforcementStatus bool
+	// Unpublish controls the withdrawal of the resources when the driver stops or the node is drained.
+	Unpublish unpublish.Mode
+	// AdmissionPolicy caps the amounts the claims of a namespace or priority class can hold. Nil admits all the claims.
+	AdmissionPolicy *admission.Policy
+	// AnnotatePods enables the annotations summarizing the allocations of the claims on the pods consuming them.
+	AnnotatePods bool
+	// LimitsWatchdog controls the checks of the hugetlb limits of the pods holding claims and of their ancestors.
+	LimitsWatchdog limitwatch.Mode
+	// WatchdogInterval is the interval of the checks of the limits.
+	WatchdogInterval time.Duration
+	// AdjustConflicts controls the checks of the adjustments of the containers made by the other NRI plugins.
+	AdjustConflicts nriconflict.Mode
+	// RsvdLimits controls the hugetlb reservation limits set along the usage limits.
+	RsvdLimits hugepages.ReservationMode
+	// ZoneGone controls the claims allocated on a NUMA zone gone, and the devices of the zone.
+	ZoneGone zonegone.Mode
+	// StrictEnvs fails the creation of the containers with malformed driver variables, rather than
+	// leaving out their claims. Meant for the CI, to catch the encoding bugs.
+	StrictEnvs bool
+	// CapsNamespace is the namespace of the ConfigMap documenting the resources and the features
+	// of the driver on the node. Empty disables the ConfigMap.
+	CapsNamespace string
+	// CgroupWriter, PoolWriter and HugeTLBFSMounter do the writes needing privileges, like the privileged helper
+	// does for the driver running unprivileged. Nil means the driver writes by itself.
+	CgroupWriter     cgroups.Writer
+	PoolWriter       reserve.PoolWriter
+	HugeTLBFSMounter hugetlbfs.Mounter
+}
+
+// features tells which optional features of the driver the environment enables, by name.
+func (env Environment) features() map[string]bool {
+	return map[string]bool{
+		"cgroupEnforcement":     env.CgroupMount != "",
+		"alignmentAttributes":   env.AlignAttributes,
+		"pagesCapacity":         env.PagesCapacity,
+		"stableDeviceNames":     env.DeviceNaming == sysinfo.DeviceNamingStable,
+		"hugepagesPools":        env.HugepagesPools != nil,
+		"hugepagesReservation":  env.HPReservation != "" && env.HPReservation != reserve.PolicyNone,
+		"admissionPolicy":       env.AdmissionPolicy != nil,
+		"swapManagement":        env.SwapPolicy != "" && env.SwapPolicy != policy.SwapPolicyUnmanaged,
+		"podAnnotations":        env.AnnotatePods,
+		"limitsWatchdog":        env.LimitsWatchdog.IsEnabled(),
+		"adjustmentConflicts":   env.AdjustConflicts.IsEnabled(),
+		"ephemeralContainerPin": env.ContainerPolicy.Ephemeral.InheritMems(),
+		"zoneGoneTaint":         env.ZoneGone == zonegone.ModeTaint,
+		"unlimitedReservations": env.RsvdLimits == hugepages.ReservationUnlimited,
+	}
+}
+
+// NRIConfig controls how the NRI plugin registers with the runtime.
+type NRIConfig struct {
+	// PluginIndex orders the plugin relative to the other NRI plugins. Plugins are invoked
+	// in increasing index order, so higher indexes win when touching the same resources.
+	PluginIndex string
+	// SocketPath is the NRI socket of the runtime. Empty means the NRI default, DefaultNRISocketPath.
+	SocketPath string
+	// ConnectTimeout bounds the time to connect to the runtime. Zero means no timeout.
+	ConnectTimeout time.Duration
+	// LogInterval and LogBurst bound the messages logged about each pod by the hooks: at most LogBurst
+	// every LogInterval, below the detail verbosity. Zero in either disables the throttling.
+	LogInterval time.Duration
+	LogBurst    int
+}
+
+// DefaultNRIPluginIndex is the index used if none is given.
+const DefaultNRIPluginIndex = "00"
+
+// DefaultNRISocketPath is the NRI default socket path, used if none is given.
+const DefaultNRISocketPath = "/var/run/nri/nri.sock"
+
+// DefaultDebugSocketPath is where the daemon serves the debug API, shared with the host like the plugin socket.
+const DefaultDebugSocketPath = kubeletPluginPath + "/" + Name + "/debug.sock"
+
+// Validate checks the configuration is usable. The NRI plugin index must be two digits.
+func (cfg NRIConfig) Validate() error {
+	if cfg.PluginIndex != "" && (len(cfg.PluginIndex) != 2 || !unicode.IsDigit(rune(cfg.PluginIndex[0])) || !unicode.IsDigit(rune(cfg.PluginIndex[1]))) {
+		return fmt.Errorf("invalid NRI plugin index %q: must be two digits", cfg.PluginIndex)
+	}
+	if cfg.ConnectTimeout < 0 {
+		return fmt.Errorf("invalid NRI connect timeout %v: must be not negative", cfg.ConnectTimeout)
+	}
+	if cfg.LogInterval < 0 || cfg.LogBurst < 0 {
+		return fmt.Errorf("invalid NRI log throttling %d every %v: must be not negative", cfg.LogBurst, cfg.LogInterval)
+	}
+	return nil
+}
+
+// EffectiveSocketPath returns the NRI socket the plugin connects to.
+func (cfg NRIConfig) EffectiveSocketPath() string {
+	if cfg.SocketPath == "" {
+		return DefaultNRISocketPath
+	}
+	return cfg.SocketPath
+}
+
+func (cfg NRIConfig) effectivePluginIndex() string {
+	if cfg.PluginIndex == "" {
+		return DefaultNRIPluginIndex
+	}
+	return cfg.PluginIndex
+}
+
+// pluginName returns the name the runtime knows the plugin by: the index, then the base name.
+func (cfg NRIConfig) pluginName(base string) string {
+	return cfg.effectivePluginIndex() + "-" + base
+}
+
+func (cfg NRIConfig) stubOptions() []stub.Option {
+	opts := []stub.Option{
+		stub.WithPluginIdx(cfg.effectivePluginIndex()),
+	}
+	if cfg.SocketPath != "" {
+		opts = append(opts, stub.WithSocketPath(cfg.SocketPath))
+	}
+	if cfg.ConnectTimeout > 0 {
+		timeout := cfg.ConnectTimeout
+		opts = append(opts, stub.WithDialer(func(path string) (net.Conn, error) {
+			return net.DialTimeout("unix", path, timeout)
+		}))
+	}
+	return opts
 }
 
 // Start creates and starts a new MemoryDriver.
@@ -97,29 +349,97 @@ func Start(ctx context.Context, env Environment) (*MemoryDriver, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = env.NRI.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	cgWriter := env.CgroupWriter
+	if cgWriter == nil {
+		cgWriter = cgroups.LocalWriter{}
+	}
+	poolWriter := env.PoolWriter
+	if poolWriter == nil {
+		poolWriter = reserve.LocalPoolWriter{SysRoot: env.SysRoot}
+	}
+	hpMounter := env.HugeTLBFSMounter
+	if hpMounter == nil {
+		hpMounter = hugetlbfs.LocalMounter{}
+	}
+	allocMgr := alloc.NewTracker()
 
 	mdrv := &MemoryDriver{
 		driverName:     env.DriverName,
 		nodeName:       env.NodeName,
 		cgMount:        env.CgroupMount,
+		cgWriter:       cgWriter,
+		hpMounter:      hpMounter,
 		kubeClient:     env.Clientset,
 		logger:         env.Logger.WithName(env.DriverName),
-		allocMgr:       alloc.NewTracker(),
+		allocMgr:       allocMgr,
 		bindMgr:        alloc.NewBinder(),
 		discoverer:     sysinfo.NewDiscoverer(env.SysRoot),
 		cgPathByPodUID: make(map[string]string),
+		podBaselines:   hugepages.NewBaselines(),
+		ctrPolicy:      env.ContainerPolicy,
+		roundingPolicy: env.RoundingPolicy,
+		sysRoot:        env.SysRoot,
+		nodeLabels:     env.NodeLabels,
+		hpReserver:     reserve.NewReserver(env.HPReservation, env.SysRoot, poolWriter, allocMgr.AllocatedBytes),
+		publisher:      debounce.New(env.PublishWindow),
+		nriEvents:      debugapi.NewEventLog(),
+		nriLogs:        logthrottle.New(env.NRI.LogInterval, env.NRI.LogBurst, countDroppedLog),
+		unpublishMode:  env.Unpublish,
+		lifetimes:      lifetime.NewTracker(),
+		admission:      admission.NewController(env.AdmissionPolicy),
+		claimStatuses:  make(chan claimStatusUpdate, claimStatusQueueSize),
+		annotatePods:   env.AnnotatePods,
+		conflictMode:   env.AdjustConflicts,
+		zonesGone:      zonegone.NewTracker(env.ZoneGone),
+		rsvdMode:       env.RsvdLimits,
+		strictEnvs:     env.StrictEnvs,
+		capsNamespace:  env.CapsNamespace,
+		features:       env.features(),
+		nriPluginName:  env.NRI.pluginName(env.DriverName),
+	}
+
+	mdrv.discoverer.AlignmentAttributes = env.AlignAttributes
+	mdrv.discoverer.PagesCapacity = env.PagesCapacity
+	if env.DeviceNaming != "" {
+		mdrv.discoverer.DeviceNaming = env.DeviceNaming
 	}
+	mdrv.discoverer.NodeName = env.NodeName
+	mdrv.discoverer.SubPools = env.HugepagesPools
+	mdrv.discoverer.ExcludedHugepages = env.HPExclusions
+	mdrv.discoverer.MemoryGuardBands = env.MemoryGuardBands
 
 	err = mdrv.gatherHugepages(env.Logger)
 	if err != nil {
 		return nil, err
 	}
 
+	err = mdrv.gatherSwap(env.Logger, env.ProcRoot, env.SwapPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	driverPluginPath := filepath.Join(kubeletPluginPath, env.DriverName)
 	err = os.MkdirAll(driverPluginPath, 0750)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plugin path %s: %w", driverPluginPath, err)
 	}
+	// the plugin path survives the restarts of the driver, so the failpoints fire only once
+	mdrv.failpoints = failpoint.NewSet(env.Failpoints, driverPluginPath, failpoint.KillSelf)
+	// and the pools grown for the claims prepared before a restart are restored on release
+	err = mdrv.hpReserver.LoadState(env.Logger, filepath.Join(driverPluginPath, hpReservationsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore the hugepages reservations: %w", err)
+	}
+	// the claims prepared before a restart keep counting against the admission limits
+	err = mdrv.admission.LoadState(env.Logger, filepath.Join(driverPluginPath, admittedClaimsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore the admitted claims: %w", err)
+	}
 
 	kubeletOpts := []kubeletplugin.Option{
 		kubeletplugin.DriverName(env.DriverName),
@@ -151,45 +471,83 @@ func Start(ctx context.Context, env Environment) (*MemoryDriver, error) {
 	// register the NRI plugin
 	nriOpts := []stub.Option{
 		stub.WithPluginName(env.DriverName),
-		stub.WithPluginIdx("00"),
 		// https://github.com/containerd/nri/pull/173
 		// Otherwise it silently exits the program
 		stub.WithOnClose(func() {
 			env.Logger.Info("NRI plugin closed", "driverName", env.DriverName)
+			mdrv.setNRIConnected(false)
 		}),
 	}
+	nriOpts = append(nriOpts, env.NRI.stubOptions()...)
+	env.Logger.V(2).Info("NRI plugin", "index", env.NRI.PluginIndex, "socketPath", env.NRI.SocketPath, "connectTimeout", env.NRI.ConnectTimeout)
 	stub, err := stub.New(mdrv, nriOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create plugin stub: %w", err)
 	}
 	mdrv.nriPlugin = stub
 
+	if env.EnforcementStatus {
+		// not enforcing until the runtime synchronizes the NRI plugin
+		mdrv.enforcement = enforcement.NewReporter(env.Clientset, env.NodeName, enforcement.Degraded(reasonNRIConnecting))
+		go mdrv.enforcement.Run(ctx, mdrv.logger.WithName("enforcement"))
+	}
+
+	mdrv.startPodInformer(ctx, env)
+	go mdrv.runNRIPlugin(ctx, env.Logger)
+
+	mdrv.startEventRecorder(ctx, env)
+	mdrv.startOOMWatch(ctx, env)
+	mdrv.startLimitsWatchdog(ctx, env)
+	mdrv.startLimitsRetry(ctx)
+	go mdrv.runClaimStatusUpdates(ctx)
+	go mdrv.runLifetimeCheck(ctx)
+
+	staleThreshold := env.PublishStaleAfter
+	if staleThreshold == 0 {
+		staleThreshold = 3 * env.PublishInterval
+	}
+	publicationHealth.Reset(time.Now(), staleThreshold)
+
+	// publish available resources
+	go mdrv.runPublisher(ctx)
+	mdrv.startNodeInformer(ctx, env)
 	go func() {
-		for i := 0; i < maxAttempts; i++ {
-			err = mdrv.nriPlugin.Run(ctx)
-			if err != nil {
-				env.Logger.Error(err, "NRI plugin failed")
-			}
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				env.Logger.Info("Restarting NRI plugin", "attempt", i, "maxAttempts", maxAttempts)
-			}
+		mdrv.PublishResources(ctx)
+		if env.PublishInterval > 0 {
+			mdrv.RefreshResources(ctx, env.PublishInterval)
 		}
-		env.Logger.Info("NRI plugin failed for %d times to be restarted", "maxAttempts", maxAttempts)
-		os.Exit(1)
 	}()
 
-	// publish available resources
-	go mdrv.PublishResources(ctx)
-
 	return mdrv, nil
 }
 
 func (mdrv *MemoryDriver) Stop() {
 	lh := mdrv.logger // alias
 	lh.V(3).Info("Driver stopping...")
+	ctx, cancel := context.WithTimeout(context.Background(), enforcementCloseTimeout)
+	defer cancel()
+	err := mdrv.enforcement.Close(ctx, lh, enforcement.Degraded(reasonDriverStopped))
+	if err != nil {
+		lh.Error(err, "publishing enforcement status")
+	}
+	mdrv.withdrawResources(lh)
+}
+
+// withdrawResources removes or taints the published resources, if enabled.
+func (mdrv *MemoryDriver) withdrawResources(lh logr.Logger) {
+	if !mdrv.unpublishMode.IsEnabled() {
+		return
+	}
+	// the kubelet plugin would restore the slices, so it must stop first
+	mdrv.draPlugin.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), unpublishTimeout)
+	defer cancel()
+	err := unpublish.Withdraw(ctx, lh, mdrv.kubeClient, mdrv.unpublishMode, mdrv.driverName, mdrv.nodeName)
+	if err != nil {
+		lh.Error(err, "withdrawing resources", "mode", mdrv.unpublishMode)
+		return
+	}
+	lh.Info("withdrawn resources", "mode", mdrv.unpublishMode)
 }
 
 // Shutdown is called when the runtime is shutting down.
@@ -198,6 +556,62 @@ func (mdrv *MemoryDriver) Shutdown(ctx context.Context) {
 	lh.V(3).Info("Driver shutting down...")
 }
 
+// IsNRIConnected returns true if the NRI plugin is connected to the container runtime.
+// The driver is degraded while disconnected: claims can be prepared, but no container is pinned.
+func (mdrv *MemoryDriver) IsNRIConnected() bool {
+	return mdrv.nriConnected.Load()
+}
+
+func (mdrv *MemoryDriver) setNRIConnected(val bool) {
+	mdrv.nriConnected.Store(val)
+	if val {
+		nriConnectedGauge.Set(1)
+		mdrv.enforcement.Set(enforcement.Active())
+	} else {
+		nriConnectedGauge.Set(0)
+		mdrv.enforcement.Set(enforcement.Degraded(reasonNRIDisconnected))
+	}
+}
+
+// runNRIPlugin keeps the NRI plugin running until the context is done,
+// restarting it with exponential backoff if the connection with the runtime is lost.
+func (mdrv *MemoryDriver) runNRIPlugin(ctx context.Context, lh logr.Logger) {
+	backoff := newNRIBackoff()
+	for {
+		started := time.Now()
+		err := mdrv.nriPlugin.Run(ctx)
+		mdrv.setNRIConnected(false)
+		if err != nil {
+			lh.Error(err, "NRI plugin failed")
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > nriBackoffCap {
+			// the plugin was running fine for a while, so this is a new failure
+			backoff = newNRIBackoff()
+		}
+		delay := backoff.Step()
+		nriRestartsTotal.Inc()
+		lh.Info("Restarting NRI plugin", "delay", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func newNRIBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: nriBackoffInitial,
+		Factor:   2.0,
+		Jitter:   0.2,
+		Steps:    math.MaxInt32,
+		Cap:      nriBackoffCap,
+	}
+}
+
 func (mdrv *MemoryDriver) logrFromContext(ctx context.Context) logr.Logger {
 	lh, err := logr.FromContext(ctx)
 	if err != nil {
@@ -206,6 +620,135 @@ func (mdrv *MemoryDriver) logrFromContext(ctx context.Context) logr.Logger {
 	return lh
 }
 
+// podLogrFromContext is like logrFromContext, for the hooks about `podUID`, throttled per pod.
+func (mdrv *MemoryDriver) podLogrFromContext(ctx context.Context, podUID string) logr.Logger {
+	return mdrv.nriLogs.Logger(mdrv.logrFromContext(ctx), podUID)
+}
+
+// startEventRecorder sets up the recorder of the events about the pods and the claims.
+func (mdrv *MemoryDriver) startEventRecorder(ctx context.Context, env Environment) {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: env.Clientset.CoreV1().Events("")})
+	mdrv.eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: env.DriverName, Host: env.NodeName})
+	go func() {
+		<-ctx.Done()
+		broadcaster.Shutdown()
+	}()
+}
+
+// startPodInformer caches the pods of the node, for the NRI hooks to tell the kind of the containers
+// without reading their pod from the API server. Only the container policies need it.
+func (mdrv *MemoryDriver) startPodInformer(ctx context.Context, env Environment) {
+	if !mdrv.ctrPolicy.IsEnabled() {
+		return
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(env.Clientset, 0, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", env.NodeName).String()
+	}))
+	mdrv.podLister = factory.Core().V1().Pods().Lister()
+	factory.Start(ctx.Done())
+}
+
+// startNodeInformer watches the node, to publish the resources as soon as the node enters or leaves the maintenance.
+// The watch is restricted to the node itself, so it costs nothing until the node changes.
+func (mdrv *MemoryDriver) startNodeInformer(ctx context.Context, env Environment) {
+	lh := env.Logger.WithName("NodeInformer")
+	factory := informers.NewSharedInformerFactoryWithOptions(env.Clientset, 0, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", env.NodeName).String()
+	}))
+	_, err := factory.Core().V1().Nodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj any) {
+			oldNode, okOld := oldObj.(*corev1.Node)
+			newNode, okNew := newObj.(*corev1.Node)
+			if !okOld || !okNew {
+				return
+			}
+			mdrv.onNodeUpdate(lh, oldNode, newNode)
+		},
+	})
+	if err != nil {
+		// the periodic publication still catches the changes, later
+		lh.Error(err, "watching the node")
+		return
+	}
+	factory.Start(ctx.Done())
+}
+
+func (mdrv *MemoryDriver) startOOMWatch(ctx context.Context, env Environment) {
+	if mdrv.cgMount == "" || env.OOMWatchInterval <= 0 {
+		env.Logger.V(2).Info("memory events watch disabled")
+		return
+	}
+	mdrv.oomWatcher = oomwatch.NewWatcher(env.OOMWatchInterval, mdrv.notifyMemoryEvent)
+	go mdrv.oomWatcher.Run(ctx, mdrv.logger.WithName("oomwatch"))
+}
+
+func (mdrv *MemoryDriver) notifyMemoryEvent(tgt oomwatch.Target, ev oomwatch.Event) {
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  tgt.Namespace,
+		Name:       tgt.Name,
+		UID:        k8stypes.UID(tgt.PodUID),
+	}
+	eventType := corev1.EventTypeWarning
+	if !ev.IsWarning() {
+		eventType = corev1.EventTypeNormal
+	}
+	mdrv.eventRecorder.Event(ref, eventType, ev.Reason, ev.Message(tgt))
+}
+
+func (mdrv *MemoryDriver) startLimitsWatchdog(ctx context.Context, env Environment) {
+	if mdrv.cgMount == "" || !env.LimitsWatchdog.IsEnabled() || env.WatchdogInterval <= 0 {
+		env.Logger.V(2).Info("limits watchdog disabled")
+		return
+	}
+	mdrv.watchdog = limitwatch.NewWatchdog(env.LimitsWatchdog, env.WatchdogInterval, mdrv.cgMount, mdrv.cgWriter, mdrv.notifyLimitViolation)
+	mdrv.watchdog.UnlimitedReservations = mdrv.rsvdMode == hugepages.ReservationUnlimited
+	go mdrv.watchdog.Run(ctx, mdrv.logger.WithName("watchdog"))
+}
+
+func (mdrv *MemoryDriver) startLimitsRetry(ctx context.Context) {
+	if mdrv.cgMount == "" {
+		return // the driver doesn't manage the cgroups, nothing to retry
+	}
+	mdrv.limitsRetry = limitretry.NewQueue(limitsRetryBackoff, isRetriablePodLimitsError, func(pending int) {
+		podLimitsPending.Set(float64(pending))
+	})
+	go mdrv.limitsRetry.Run(ctx, mdrv.logger.WithName("limitretry"), limitsRetryBackoff.Duration)
+}
+
+// notifyAdjustmentConflicts reports the conflicts of the container with an event on its pod.
+func (mdrv *MemoryDriver) notifyAdjustmentConflicts(lh logr.Logger, stage string, pod *api.PodSandbox, ctr *api.Container, conflicts []nriconflict.Conflict) {
+	msg := nriconflict.Message(ctr.Name, conflicts)
+	lh.Info("conflicting adjustments", "stage", stage, "conflicts", msg)
+	for _, cf := range conflicts {
+		adjustmentConflictsTotal.WithLabelValues(cf.Field, stage).Inc()
+	}
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		UID:        k8stypes.UID(pod.Uid),
+	}
+	mdrv.eventRecorder.Event(ref, corev1.EventTypeWarning, nriconflict.ReasonAdjustmentConflict, msg)
+}
+
+func (mdrv *MemoryDriver) notifyLimitViolation(vi limitwatch.Violation) {
+	limitViolationsTotal.WithLabelValues(strconv.FormatBool(vi.Repaired)).Inc()
+	for _, tgt := range vi.Targets {
+		ref := &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Namespace:  tgt.Namespace,
+			Name:       tgt.Name,
+			UID:        k8stypes.UID(tgt.PodUID),
+		}
+		mdrv.eventRecorder.Event(ref, corev1.EventTypeWarning, limitwatch.ReasonHugeTLBLimitTooLow, vi.Message())
+	}
+}
+
 func (mdrv *MemoryDriver) gatherHugepages(lh logr.Logger) error {
 	lh.V(2).Info("cgroups", "mountPath", mdrv.cgMount)
 	if mdrv.cgMount == "" {
@@ -225,3 +768,22 @@ func (mdrv *MemoryDriver) gatherHugepages(lh logr.Logger) error {
 	mdrv.hpRootLimits = limits
 	return nil
 }
+
+func (mdrv *MemoryDriver) gatherSwap(lh logr.Logger, procRoot string, swapPolicy policy.SwapPolicy) error {
+	if !swapPolicy.IsManaged() || mdrv.cgMount == "" {
+		lh.V(2).Info("swap settings left to the kubelet", "policy", swapPolicy)
+		return nil
+	}
+	swapInfo, err := sysinfo.GetSwapInfo(lh, procRoot)
+	if err != nil {
+		return err
+	}
+	if !swapInfo.Enabled() {
+		lh.Info("swap disabled on the node, ignoring the swap policy", "policy", swapPolicy)
+		return nil
+	}
+	mdrv.swapPolicy = swapPolicy
+	mdrv.swapInfo = swapInfo
+	lh.V(2).Info("swap managed", "policy", swapPolicy, "swapTotalBytes", swapInfo.SwapTotalBytes)
+	return nil
+}
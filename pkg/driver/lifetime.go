@@ -0,0 +1,151 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/claimconfig"
+	"github.com/ffromani/dra-driver-memory/pkg/lifetime"
+)
+
+// DeviceConditionExpired is the condition of the devices in the claim status telling if the claim expired.
+// The reasons are the ones of the lifetime package.
+const DeviceConditionExpired = "Expired"
+
+func lifetimePolicy(cfg *claimconfig.ClaimLifetimeConfig) lifetime.Policy {
+	if cfg == nil {
+		return lifetime.Policy{}
+	}
+	pol := lifetime.Policy{
+		Taint: cfg.TaintForCleanup,
+	}
+	if cfg.MaxLifetime != nil {
+		pol.MaxLifetime = cfg.MaxLifetime.Duration
+	}
+	if cfg.IdleTimeout != nil {
+		pol.IdleTimeout = cfg.IdleTimeout.Duration
+	}
+	return pol
+}
+
+func lifetimeClaim(claim *resourceapi.ResourceClaim) lifetime.Claim {
+	return lifetime.Claim{
+		UID:       claim.UID,
+		Namespace: claim.Namespace,
+		Name:      claim.Name,
+		CreatedAt: claim.CreationTimestamp.Time,
+	}
+}
+
+// runLifetimeCheck periodically reports the claims which outlived their lifetime policy.
+func (mdrv *MemoryDriver) runLifetimeCheck(ctx context.Context) {
+	lh := mdrv.logger.WithName("lifetime")
+	ticker := time.NewTicker(lifetimeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, exp := range mdrv.lifetimes.Expire(time.Now()) {
+				mdrv.expireClaim(ctx, lh, exp)
+			}
+		}
+	}
+}
+
+// expireClaim marks the devices of the expired claim, emits an event and optionally labels the claim.
+// The claim stays prepared: the workloads using it, if any, are not disrupted.
+func (mdrv *MemoryDriver) expireClaim(ctx context.Context, lh logr.Logger, exp lifetime.Expiration) {
+	lh = lh.WithValues("claim", exp.Claim.String(), "claimUID", exp.Claim.UID, "reason", exp.Reason)
+	lh.Info("claim expired", "message", exp.Message)
+	if mdrv.kubeClient == nil {
+		return
+	}
+	claim, err := mdrv.kubeClient.ResourceV1().ResourceClaims(exp.Claim.Namespace).Get(ctx, exp.Claim.Name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			lh.Error(err, "getting expired claim")
+		}
+		return
+	}
+	if claim.UID != exp.Claim.UID {
+		lh.V(2).Info("claim recreated meanwhile, skipped")
+		return
+	}
+
+	if mdrv.eventRecorder != nil {
+		mdrv.eventRecorder.Event(claim, corev1.EventTypeWarning, exp.Reason, exp.Message)
+	}
+	mdrv.updateClaimStatus(ctx, lh, claim, expiredDeviceStatuses(mdrv.driverName, claim, exp))
+
+	if !exp.Policy.Taint {
+		return
+	}
+	patch, _ := json.Marshal(map[string]any{ // can't fail
+		"metadata": map[string]any{
+			"labels": map[string]string{
+				lifetime.ExpiredLabel: exp.Reason,
+			},
+		},
+	})
+	_, err = mdrv.kubeClient.ResourceV1().ResourceClaims(claim.Namespace).Patch(ctx, claim.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		lh.Error(err, "labeling expired claim")
+	}
+}
+
+// expiredDeviceStatuses adds the expired condition to the current status of the devices of the claim allocated by the driver.
+func expiredDeviceStatuses(driverName string, claim *resourceapi.ResourceClaim, exp lifetime.Expiration) []resourceapi.AllocatedDeviceStatus {
+	if claim.Status.Allocation == nil {
+		return nil
+	}
+	var devStatuses []resourceapi.AllocatedDeviceStatus
+	for _, devRes := range claim.Status.Allocation.Devices.Results {
+		if devRes.Driver != driverName {
+			continue
+		}
+		devStatus := makeBaseDeviceStatus(devRes)
+		for _, cur := range claim.Status.Devices {
+			if sameDevice(cur, devStatus) {
+				devStatus.Data = cur.Data
+				devStatus.Conditions = append(devStatus.Conditions, cur.Conditions...)
+			}
+		}
+		meta.SetStatusCondition(&devStatus.Conditions, metav1.Condition{
+			Type:               DeviceConditionExpired,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: claim.Generation,
+			Reason:             exp.Reason,
+			Message:            exp.Message,
+		})
+		devStatuses = append(devStatuses, devStatus)
+	}
+	return devStatuses
+}
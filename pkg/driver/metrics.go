@@ -0,0 +1,180 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "dramemory"
+)
+
+var (
+	nriConnectedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "connected",
+		Help:      "Whether the NRI plugin is connected to the container runtime (1) or not (0).",
+	})
+	nriRestartsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "restarts_total",
+		Help:      "Number of times the NRI plugin was restarted after losing the connection with the container runtime.",
+	})
+	publicationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "publications_total",
+		Help:      "Number of times the ResourceSlices were published.",
+	})
+	publicationsSuppressedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "publications_suppressed_total",
+		Help:      "Number of requests to publish the ResourceSlices coalesced with other requests, by trigger.",
+	}, []string{"trigger"})
+	publicationFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "publication_failures_total",
+		Help:      "Number of failed publications of the ResourceSlices, by stage (discovery, publish).",
+	}, []string{"stage"})
+	lastPublicationTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "last_publication_timestamp_seconds",
+		Help:      "Unix time of the last successful publication of the ResourceSlices.",
+	})
+	publishedDevices = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "published_devices",
+		Help:      "Number of devices in the last published ResourceSlices, by resource (memory, hugepages-2Mi...).",
+	}, []string{"resource"})
+	limitViolationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "hugetlb",
+		Name:      "limit_violations_total",
+		Help:      "Number of hugetlb limits found lower than the claims need by the limits watchdog, by repaired (true, false).",
+	}, []string{"repaired"})
+	adjustmentConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "adjustment_conflicts_total",
+		Help:      "Number of settings of the containers consuming the claims changed by other NRI plugins, by field (cpuset.mems, hugepage-limit) and stage (validate, synchronize).",
+	}, []string{"field", "stage"})
+	enforcementOptOutsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "enforcement_opt_outs_total",
+		Help:      "Number of containers consuming the claims created without enforcement, because their pod opted out with the annotation.",
+	})
+	malformedEnvsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "malformed_envs_total",
+		Help:      "Number of malformed driver variables found in the containers consuming the claims, whose claims were not enforced.",
+	})
+	nriLogsDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "nri",
+		Name:      "logs_dropped_total",
+		Help:      "Number of log messages about the pods dropped by the throttling of the NRI hooks, by reason (ratelimit, duplicate).",
+	}, []string{"reason"})
+	zoneGonePreparationsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "claims",
+		Name:      "zone_gone_preparations_total",
+		Help:      "Number of devices of the claims failed to prepare because their NUMA zone is gone.",
+	})
+	zonesGoneGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "zones_gone",
+		Help:      "Number of NUMA zones found gone, whose devices are withdrawn or tainted.",
+	})
+	podLimitsPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "hugetlb",
+		Name:      "pod_limits_retry_pending",
+		Help:      "Number of pods whose cgroup hugetlb limits failed to apply and are retried.",
+	})
+	publicationStaleGauge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "resourceslices",
+		Name:      "stale",
+		Help:      "Whether the last successful publication of the ResourceSlices is older than the staleness threshold (1) or not (0).",
+	}, func() float64 {
+		if publicationHealth.IsStale(time.Now()) {
+			return 1
+		}
+		return 0
+	})
+)
+
+// The stages of the publication which can fail.
+const (
+	publishStageDiscovery = "discovery"
+	publishStagePublish   = "publish"
+)
+
+func init() {
+	prometheus.MustRegister(nriConnectedGauge, nriRestartsTotal, publicationsTotal, publicationsSuppressedTotal,
+		publicationFailuresTotal, lastPublicationTimestamp, publishedDevices, publicationStaleGauge, limitViolationsTotal,
+		podLimitsPending, adjustmentConflictsTotal, enforcementOptOutsTotal, malformedEnvsTotal,
+		nriLogsDroppedTotal, zoneGonePreparationsTotal, zonesGoneGauge)
+}
+
+// countDroppedLog is notified by the throttling of the NRI logs, the summary of what it dropped.
+func countDroppedLog(reason string) {
+	nriLogsDroppedTotal.WithLabelValues(reason).Inc()
+}
+
+// publicationHealth is global like the metrics it feeds, which are evaluated at scrape time.
+var publicationHealth publicationTracker
+
+// publicationTracker tells if the published resources are stale, i.e. not published successfully for too long.
+type publicationTracker struct {
+	mu          sync.Mutex
+	threshold   time.Duration
+	lastSuccess time.Time
+}
+
+// Reset starts tracking from `now`. A zero `threshold` disables the staleness check.
+func (pt *publicationTracker) Reset(now time.Time, threshold time.Duration) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.threshold = threshold
+	pt.lastSuccess = now
+}
+
+func (pt *publicationTracker) Succeeded(now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.lastSuccess = now
+}
+
+func (pt *publicationTracker) IsStale(now time.Time) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.threshold > 0 && now.Sub(pt.lastSuccess) > pt.threshold
+}
@@ -19,20 +19,38 @@ package driver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/go-logr/logr"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/cpuset"
 
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
 	"github.com/ffromani/dra-driver-memory/pkg/env"
 	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/limitretry"
+	"github.com/ffromani/dra-driver-memory/pkg/limitwatch"
+	"github.com/ffromani/dra-driver-memory/pkg/nriconflict"
+	"github.com/ffromani/dra-driver-memory/pkg/oomwatch"
+	"github.com/ffromani/dra-driver-memory/pkg/policy"
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
 
 // NRI is the actuation layer. Once we reach this point, all the allocation decisions
@@ -44,6 +62,9 @@ func (mdrv *MemoryDriver) Synchronize(ctx context.Context, pods []*api.PodSandbo
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
 
+	// the runtime always synchronizes the plugins once connected
+	mdrv.setNRIConnected(true)
+
 	// we start from empty state, so we can just be additive
 	// we recover in reverse (container, then sandbox) because we have a easy way
 	// to detect the containers which we processed, so from these we can find the
@@ -52,6 +73,7 @@ func (mdrv *MemoryDriver) Synchronize(ctx context.Context, pods []*api.PodSandbo
 	for _, pod := range pods {
 		lh.V(4).Info("reverse map", "podSandboxID", pod.Id, "podUID", pod.Uid)
 		podsBySandboxID[pod.Id] = pod
+		mdrv.recordNRIEvent("Synchronize", pod, nil)
 	}
 	knownPods := sets.New[string]()
 
@@ -61,7 +83,7 @@ func (mdrv *MemoryDriver) Synchronize(ctx context.Context, pods []*api.PodSandbo
 		if !ok {
 			return nil, fmt.Errorf("unknown sandbox: %q for container %q (%q)", ctr.PodSandboxId, ctr.Name, ctr.Id)
 		}
-		_, _, ok, err := mdrv.handleContainer(lh_, pod, ctr)
+		_, allocs, ok, err := mdrv.handleContainer(lh_, pod, ctr)
 		if err != nil {
 			return nil, err
 		}
@@ -69,6 +91,7 @@ func (mdrv *MemoryDriver) Synchronize(ctx context.Context, pods []*api.PodSandbo
 			continue
 		}
 		lh_.V(4).Info("backreferencing")
+		mdrv.checkRunningContainer(lh_, pod, ctr, allocs)
 		knownPods.Insert(ctr.PodSandboxId)
 	}
 
@@ -82,35 +105,73 @@ func (mdrv *MemoryDriver) Synchronize(ctx context.Context, pods []*api.PodSandbo
 		if err != nil {
 			return nil, err
 		}
+		mdrv.recoverPodBaseline(lh_, pod)
 	}
 
 	return []*api.ContainerUpdate{}, nil
 }
 
 func (mdrv *MemoryDriver) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("CreateContainer").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "container", ctr.Name, "containerID", ctr.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("CreateContainer", pod, ctr)
 
 	lh.V(4).Info("container backref", "sandboxID", ctr.PodSandboxId)
+	// a container is recreated by the runtime on restart, e.g. after a crash.
+	// The pod limits were already updated on the first creation and must not grow.
+	restarted := mdrv.allocMgr.HasContainer(ctr.PodSandboxId, ctr.Name)
 	numaNodes, allocs, ok, err := mdrv.handleContainer(lh, pod, ctr)
 	if err != nil {
 		lh.Error(err, "cannot create container")
 		return nil, nil, err
 	}
 	var updates []*api.ContainerUpdate
+	isCompanion := false
+	var companionPolicy policy.ContainerPolicy
+	if !ok {
+		numaNodes, allocs, companionPolicy, ok = mdrv.handleCompanionContainer(ctx, lh, pod, ctr)
+		isCompanion = ok
+	}
 	if !ok {
 		lh.V(4).Info("No memory pinning for container")
 		return &api.ContainerAdjustment{}, updates, nil
 	}
+	mdrv.lifetimes.SetInUse(true, time.Now(), mdrv.allocMgr.GetClaimsForPod(pod.Uid)...)
+	if mdrv.enforcementDisabled(lh, pod) {
+		// the claims are accounted and bound to the container all the same
+		lh.Info("enforcement disabled by the pod annotation, container not adjusted", "annotation", policy.AnnotationEnforce)
+		enforcementOptOutsTotal.Inc()
+		return &api.ContainerAdjustment{}, updates, nil
+	}
 
-	machineData := mdrv.discoverer.GetCachedMachineData()
-	hpLimits := hugepages.LimitsFromAllocations(lh, machineData, allocs)
 	cgroupParent := mdrv.cgPathByPodUID[pod.Uid]
-	if cgroupParent != "" {
+	err = mdrv.validatePodMems(lh, cgroupParent, numaNodes)
+	if err != nil {
+		lh.Error(err, "cannot pin container memory")
+		return nil, nil, err
+	}
+
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	var hpLimits []hugepages.Limit
+	// without allocations all the limits are zero
+	if !isCompanion || len(allocs) > 0 || companionPolicy.ZeroHugeTLB() {
+		hpLimits = hugepages.LimitsFromAllocations(lh, machineData, allocs)
+	}
+	// companion containers share the budget of the claims, which is accounted at pod level. The init containers
+	// inheriting the hugetlb limits run before the containers consuming the claims, so the pod limits are raised now.
+	if cgroupParent != "" && isCompanion && companionPolicy.InheritHugeTLB() {
+		lh.V(2).Info("setting pod cgroup limit for the companion container", "cgroupParent", cgroupParent)
+		mdrv.applyPodLimits(lh, machineData, pod, cgroupParent)
+		mdrv.guardPodLimits(lh, machineData, pod, cgroupParent)
+	}
+	if cgroupParent != "" && !isCompanion && !restarted {
 		lh.V(2).Info("setting deferred pod cgroup limit", "cgroupParent", cgroupParent)
-		_ = mdrv.updatePodLimits(lh, machineData, cgroupParent, hpLimits)
+		mdrv.applyPodLimits(lh, machineData, pod, cgroupParent)
+		mdrv.updatePodSwap(lh, pod, cgroupParent)
+		mdrv.watchPodEvents(lh, machineData, pod, cgroupParent)
+		mdrv.guardPodLimits(lh, machineData, pod, cgroupParent)
 	}
 
 	adjust := &api.ContainerAdjustment{}
@@ -118,83 +179,331 @@ func (mdrv *MemoryDriver) CreateContainer(ctx context.Context, pod *api.PodSandb
 	for _, hpLimit := range hpLimits {
 		adjust.AddLinuxHugepageLimit(hpLimit.PageSize, hpLimit.Limit.Value) // MUST be set
 	}
+	// the runtime sets the reservation limits like the hugepage limits: lift them, but for the containers barred from the hugepages
+	if mdrv.rsvdMode == hugepages.ReservationUnlimited && (!isCompanion || !companionPolicy.ZeroHugeTLB()) {
+		for _, hpLimit := range hpLimits {
+			adjust.AddLinuxUnified(hugepages.ReservationLimitFile(hpLimit.PageSize), cgroups.MaxValue)
+		}
+	}
+	if swapLimit, ok := mdrv.swapLimit(allocs); ok && !isCompanion {
+		lh.V(2).Info("setting container swap limit", "policy", mdrv.swapPolicy, "limit", swapLimit)
+		adjust.AddLinuxUnified(policy.SwapMaxFile, strconv.FormatInt(swapLimit, 10))
+	}
+	// the soft limit belongs to the containers consuming the burstable claims, the companions only share the pinning
+	if !isCompanion {
+		memHigh, err := memoryHigh(ctr)
+		if err != nil {
+			lh.Error(err, "cannot set the container soft limit")
+			return nil, nil, err
+		}
+		if memHigh > 0 {
+			lh.V(2).Info("setting container soft limit", "limit", memHigh)
+			adjust.AddLinuxUnified(memoryHighFile, strconv.FormatInt(memHigh, 10))
+		}
+	}
 
 	logAdjust(lh, adjust)
 
 	return adjust, updates, nil
 }
 
+// ValidateContainerAdjustment checks the adjustments of all the plugins, merged by the runtime, don't change
+// the settings the claims of the container need. The runtime calls it only if it supports the validation.
+func (mdrv *MemoryDriver) ValidateContainerAdjustment(ctx context.Context, req *api.ValidateContainerAdjustmentRequest) error {
+	pod, ctr := req.GetPod(), req.GetContainer()
+	lh := mdrv.podLogrFromContext(ctx, pod.GetUid())
+	lh = lh.WithName("ValidateContainerAdjustment").WithValues("pod", pod.GetNamespace()+"/"+pod.GetName(), "podUID", pod.GetUid(), "container", ctr.GetName(), "containerID", ctr.GetId())
+	lh.V(4).Info("start")
+	defer lh.V(4).Info("done")
+
+	if !mdrv.conflictMode.IsEnabled() || mdrv.enforcementDisabled(lh, pod) {
+		return nil
+	}
+	exp, ok := mdrv.expectedAdjustment(lh, ctr)
+	if !ok {
+		return nil // not consuming our claims
+	}
+	resources := req.GetAdjust().GetLinux().GetResources()
+	act := nriconflict.Actual{
+		Mems:           ctr.GetLinux().GetResources().GetCpu().GetMems(),
+		HugepageLimits: hugepageLimitsOf(ctr.GetLinux().GetResources().GetHugepageLimits()),
+		HugepageOwners: make(map[string]string),
+	}
+	// the adjustments override the settings of the container
+	if mems := resources.GetCpu().GetMems(); mems != "" {
+		act.Mems = mems
+	}
+	maps.Copy(act.HugepageLimits, hugepageLimitsOf(resources.GetHugepageLimits()))
+	owners := req.GetOwners()
+	if owner, ok := owners.CPUSetMemsOwner(ctr.GetId()); ok {
+		act.MemsOwner = owner
+	}
+	for pageSize := range exp.HugepageLimits {
+		if owner, ok := owners.HugepageLimitOwner(ctr.GetId(), pageSize); ok {
+			act.HugepageOwners[pageSize] = owner
+		}
+	}
+	conflicts := nriconflict.Detect(mdrv.nriPluginName, exp, act)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	mdrv.notifyAdjustmentConflicts(lh, conflictStageValidate, pod, ctr, conflicts)
+	if mdrv.conflictMode != nriconflict.ModeReject {
+		return nil
+	}
+	return fmt.Errorf("adjustments conflicting with the memory claims: %s", nriconflict.Message(ctr.GetName(), conflicts))
+}
+
+// The stages detecting the conflicts, for the metrics.
+const (
+	conflictStageValidate    = "validate"
+	conflictStageSynchronize = "synchronize"
+)
+
+// checkRunningContainer reports the settings of a container found running when synchronizing with the runtime
+// which differ from what its claims need, e.g. adjusted by another plugin while the driver was down.
+func (mdrv *MemoryDriver) checkRunningContainer(lh logr.Logger, pod *api.PodSandbox, ctr *api.Container, allocs []types.Allocation) {
+	if !mdrv.conflictMode.IsEnabled() || mdrv.enforcementDisabled(lh, pod) {
+		return
+	}
+	exp := mdrv.expectedFromAllocations(lh, allocs)
+	act := nriconflict.Actual{
+		Mems:           ctr.GetLinux().GetResources().GetCpu().GetMems(),
+		HugepageLimits: hugepageLimitsOf(ctr.GetLinux().GetResources().GetHugepageLimits()),
+	}
+	// the owners of the settings of the running containers are unknown
+	conflicts := nriconflict.Detect("", exp, act)
+	if len(conflicts) == 0 {
+		return
+	}
+	mdrv.notifyAdjustmentConflicts(lh, conflictStageSynchronize, pod, ctr, conflicts)
+}
+
+// expectedAdjustment returns the settings the claims consumed by the container need, if any.
+func (mdrv *MemoryDriver) expectedAdjustment(lh logr.Logger, ctr *api.Container) (nriconflict.Expected, bool) {
+	allocsByClaim, ok := mdrv.allocMgr.GetAllocationsForContainer(ctr.GetPodSandboxId(), ctr.GetName())
+	if !ok {
+		return nriconflict.Expected{}, false
+	}
+	var allocs []types.Allocation
+	for _, claimAllocs := range allocsByClaim {
+		for _, alloc := range claimAllocs {
+			allocs = append(allocs, alloc)
+		}
+	}
+	return mdrv.expectedFromAllocations(lh, allocs), true
+}
+
+func (mdrv *MemoryDriver) expectedFromAllocations(lh logr.Logger, allocs []types.Allocation) nriconflict.Expected {
+	exp := nriconflict.Expected{
+		HugepageLimits: make(map[string]uint64),
+	}
+	for _, alloc := range allocs {
+		exp.Mems = exp.Mems.Union(numaNodesOf(alloc))
+	}
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	for _, hpLimit := range hugepages.LimitsFromAllocations(lh, machineData, allocs) {
+		exp.HugepageLimits[hpLimit.PageSize] = hpLimit.Limit.Value
+	}
+	return exp
+}
+
+func hugepageLimitsOf(hpLimits []*api.HugepageLimit) map[string]uint64 {
+	ret := make(map[string]uint64, len(hpLimits))
+	for _, hpLimit := range hpLimits {
+		ret[hpLimit.GetPageSize()] = hpLimit.GetLimit()
+	}
+	return ret
+}
+
 func (mdrv *MemoryDriver) UpdatePodSandbox(ctx context.Context, pod *api.PodSandbox, over *api.LinuxResources, res *api.LinuxResources) error {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("UpdatePodSandbox").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "podSandboxID", pod.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("UpdatePodSandbox", pod, nil)
 
 	lh.V(2).Info("updates", "overhead", toJSON(over), "resources", toJSON(res))
+
+	// with pod-level resources (KEP-2837) the kubelet rewrites the pod cgroup limits on resize,
+	// dropping the limits of the claims it doesn't know about. We need to add them back, but the
+	// NRI can't adjust the pod cgroup, and the update is not applied yet: we do once it is, on PostUpdatePodSandbox.
+	// The driver never programs memory.max, so there's nothing to merge for regular memory.
+	if mdrv.cgMount == "" || mdrv.cgPathByPodUID[pod.Uid] == "" || mdrv.enforcementDisabled(lh, pod) {
+		return nil
+	}
+	// the limits the kubelet sets are the new baseline, also for the containers created later
+	podLimits := limitsFromResources(res)
+	mdrv.podBaselines.Store(pod.Uid, podLimits)
+	lh.V(2).Info("pod-level limits updated", "podLevel", hugepages.LimitsToString(podLimits))
+	return nil
+}
+
+func (mdrv *MemoryDriver) PostUpdatePodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
+	lh = lh.WithName("PostUpdatePodSandbox").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "podSandboxID", pod.Id)
+	lh.V(4).Info("start")
+	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("PostUpdatePodSandbox", pod, nil)
+
+	cgroupParent := mdrv.cgPathByPodUID[pod.Uid]
+	if mdrv.cgMount == "" || cgroupParent == "" || mdrv.enforcementDisabled(lh, pod) {
+		return nil
+	}
+	if len(mdrv.allocMgr.GetAllocationsForSandbox(pod.Id)) == 0 {
+		return nil
+	}
+	// merge the limits of the claims on top of the pod-level ones, now the update can't overwrite them
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	mdrv.applyPodLimits(lh, machineData, pod, cgroupParent)
+	mdrv.guardPodLimits(lh, machineData, pod, cgroupParent)
 	return nil
 }
 
 func (mdrv *MemoryDriver) UpdateContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container, res *api.LinuxResources) ([]*api.ContainerUpdate, error) {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("UpdateContainer").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "container", ctr.Name, "containerID", ctr.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("UpdateContainer", pod, ctr)
 
 	lh.V(2).Info("updates", "resources", toJSON(res))
-	return nil, nil
+
+	// the kubelet recomputes the swap limit on resize, so we need to enforce ours again
+	if mdrv.enforcementDisabled(lh, pod) {
+		return nil, nil
+	}
+	allocsByClaim, ok := mdrv.allocMgr.GetAllocationsForContainer(ctr.PodSandboxId, ctr.Name)
+	if !ok {
+		return nil, nil
+	}
+	var allocs []types.Allocation
+	for _, claimAllocs := range allocsByClaim {
+		for _, alloc := range claimAllocs {
+			allocs = append(allocs, alloc)
+		}
+	}
+	swapLimit, ok := mdrv.swapLimit(allocs)
+	if !ok {
+		return nil, nil
+	}
+	lh.V(2).Info("enforcing container swap limit", "policy", mdrv.swapPolicy, "limit", swapLimit)
+	update := &api.ContainerUpdate{}
+	update.SetContainerId(ctr.Id)
+	update.AddLinuxUnified(policy.SwapMaxFile, strconv.FormatInt(swapLimit, 10))
+	return []*api.ContainerUpdate{update}, nil
 }
 
 func (mdrv *MemoryDriver) StopContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) ([]*api.ContainerUpdate, error) {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("StopContainer").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "container", ctr.Name, "containerID", ctr.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("StopContainer", pod, ctr)
 
 	// TODO: downsize the pod limits?
 	return nil, nil
 }
 
 func (mdrv *MemoryDriver) RemoveContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) error {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("RemoveContainer").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "container", ctr.Name, "containerID", ctr.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("RemoveContainer", pod, ctr)
 
 	return nil
 }
 
 func (mdrv *MemoryDriver) RunPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("RunPodSandbox").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "podSandboxID", pod.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("RunPodSandbox", pod, nil)
 
 	return mdrv.handlePodSandbox(lh, pod)
 }
 
 func (mdrv *MemoryDriver) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("StopPodSandbox").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "podSandboxID", pod.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
+	mdrv.recordNRIEvent("StopPodSandbox", pod, nil)
 
+	if mdrv.oomWatcher != nil {
+		// the pod cgroup is still there, last chance to read its counters
+		mdrv.oomWatcher.Finish(lh, pod.Uid)
+	}
+	if mdrv.watchdog != nil {
+		mdrv.watchdog.Untrack(lh, pod.Uid)
+	}
+	if mdrv.limitsRetry != nil {
+		mdrv.limitsRetry.Remove(lh, pod.Uid)
+	}
+	// the claims are idle from now on, until unprepared
+	mdrv.lifetimes.SetInUse(false, time.Now(), mdrv.allocMgr.GetClaimsForPod(pod.Uid)...)
 	delete(mdrv.cgPathByPodUID, pod.Uid)
 	return nil
 }
 
 func (mdrv *MemoryDriver) RemovePodSandbox(ctx context.Context, pod *api.PodSandbox) error {
-	lh := mdrv.logrFromContext(ctx)
+	lh := mdrv.podLogrFromContext(ctx, pod.Uid)
 	lh = lh.WithName("RemovePodSandbox").WithValues("pod", pod.Namespace+"/"+pod.Name, "podUID", pod.Uid, "podSandboxID", pod.Id)
 	lh.V(4).Info("start")
 	defer lh.V(4).Info("done")
 
+	mdrv.nriEvents.Forget(pod.Id)
+	mdrv.nriLogs.Forget(pod.Uid)
+	mdrv.podBaselines.Forget(pod.Uid)
 	claimUIDs := mdrv.allocMgr.CleanupPod(lh, pod.Id)
 	mdrv.bindMgr.Cleanup(lh, claimUIDs...)
+	if mdrv.oomWatcher != nil {
+		mdrv.oomWatcher.Untrack(lh, pod.Uid)
+	}
+	if mdrv.watchdog != nil {
+		mdrv.watchdog.Untrack(lh, pod.Uid)
+	}
+	if mdrv.limitsRetry != nil {
+		mdrv.limitsRetry.Remove(lh, pod.Uid)
+	}
 	return nil
 }
 
+// recordNRIEvent keeps the last event of the pod for the debug API. `ctr` is nil for the pod sandbox events.
+func (mdrv *MemoryDriver) recordNRIEvent(name string, pod *api.PodSandbox, ctr *api.Container) {
+	podEv := debugapi.PodEvent{
+		SandboxID:    pod.Id,
+		PodUID:       pod.Uid,
+		Namespace:    pod.Namespace,
+		Name:         pod.Name,
+		CgroupParent: pod.GetLinux().GetCgroupParent(),
+		Event: debugapi.Event{
+			Name: name,
+		},
+	}
+	if ctr != nil {
+		podEv.Event.ContainerName = ctr.Name
+	}
+	mdrv.nriEvents.Record(podEv)
+}
+
 func (mdrv *MemoryDriver) handleContainer(lh logr.Logger, pod *api.PodSandbox, ctr *api.Container) (cpuset.CPUSet, []types.Allocation, bool, error) {
 	nodesByClaim, allocsByClaim, err := env.ExtractAll(lh, ctr.Env, mdrv.discoverer.AllResourceNames())
+	if err != nil || len(nodesByClaim) == 0 {
+		numaNodes, allocs, ok := mdrv.recoverContainer(ctr)
+		if ok {
+			lh.V(2).Info("recovered container allocations from tracked state", "parseError", err)
+			return numaNodes, allocs, true, nil
+		}
+	}
+	var malformed *env.MalformedEnvsError
+	if errors.As(err, &malformed) && !mdrv.strictEnvs {
+		// the container still gets the claims whose variables are well formed
+		mdrv.notifyMalformedEnvs(lh, pod, ctr, malformed)
+		err = nil
+	}
 	if err != nil {
 		return cpuset.CPUSet{}, nil, false, err
 	}
@@ -213,8 +522,8 @@ func (mdrv *MemoryDriver) handleContainer(lh logr.Logger, pod *api.PodSandbox, c
 		numaNodes = numaNodes.Union(claimNUMANodes)
 		claimUIDs.Insert(claimUID)
 	}
-	for claimUID, alloc := range allocsByClaim {
-		allocs = append(allocs, alloc)
+	for claimUID, claimAllocs := range allocsByClaim {
+		allocs = append(allocs, claimAllocs...)
 		claimUIDs.Insert(claimUID)
 	}
 
@@ -225,41 +534,367 @@ func (mdrv *MemoryDriver) handleContainer(lh logr.Logger, pod *api.PodSandbox, c
 			return cpuset.CPUSet{}, nil, false, err
 		}
 	}
+	mdrv.allocMgr.BindContainer(lh, ctr.PodSandboxId, ctr.Name, claimUIDs.UnsortedList()...)
 
 	return numaNodes, allocs, true, nil
 }
 
+// notifyMalformedEnvs reports the driver variables of the container skipped because malformed with an event on its pod.
+func (mdrv *MemoryDriver) notifyMalformedEnvs(lh logr.Logger, pod *api.PodSandbox, ctr *api.Container, malformed *env.MalformedEnvsError) {
+	lh.Error(malformed, "malformed DRA envs skipped, the claims affected are not enforced")
+	malformedEnvsTotal.Add(float64(len(malformed.Errs)))
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		UID:        k8stypes.UID(pod.Uid),
+	}
+	mdrv.eventRecorder.Eventf(ref, corev1.EventTypeWarning, env.ReasonMalformedEnv, "container %s: %v", ctr.Name, malformed)
+}
+
+// recoverContainer rebuilds the container allocations from the tracked state, if any.
+func (mdrv *MemoryDriver) recoverContainer(ctr *api.Container) (cpuset.CPUSet, []types.Allocation, bool) {
+	allocsByClaim, ok := mdrv.allocMgr.GetAllocationsForContainer(ctr.PodSandboxId, ctr.Name)
+	if !ok {
+		return cpuset.CPUSet{}, nil, false
+	}
+	var numaNodes cpuset.CPUSet
+	var allocs []types.Allocation
+	for _, claimAllocs := range allocsByClaim {
+		for _, alloc := range claimAllocs {
+			numaNodes = numaNodes.Union(numaNodesOf(alloc))
+			allocs = append(allocs, alloc)
+		}
+	}
+	return numaNodes, allocs, true
+}
+
+// handleCompanionContainer handles the containers which don't consume any memory claim,
+// but belong to a pod which does, according to the configured container policy.
+// Returns the policy applied, the allocations are returned only if the container inherits the hugetlb limits.
+func (mdrv *MemoryDriver) handleCompanionContainer(ctx context.Context, lh logr.Logger, pod *api.PodSandbox, ctr *api.Container) (cpuset.CPUSet, []types.Allocation, policy.ContainerPolicy, bool) {
+	if !mdrv.ctrPolicy.IsEnabled() {
+		return cpuset.CPUSet{}, nil, policy.ContainerPolicyNone, false
+	}
+	podAllocs := mdrv.allocMgr.GetAllocationsForPod(pod.Uid)
+	if len(podAllocs) == 0 {
+		return cpuset.CPUSet{}, nil, policy.ContainerPolicyNone, false
+	}
+	kind, err := mdrv.containerKind(ctx, lh, pod, ctr)
+	if err != nil {
+		lh.Error(err, "cannot detect the container kind, assuming regular")
+	}
+	ctrPolicy := mdrv.ctrPolicy.ForKind(kind)
+	lh.V(2).Info("companion container", "kind", kind, "policy", ctrPolicy)
+	if !ctrPolicy.InheritMems() {
+		return cpuset.CPUSet{}, nil, ctrPolicy, false
+	}
+	var numaNodes cpuset.CPUSet
+	for _, alloc := range podAllocs {
+		numaNodes = numaNodes.Union(numaNodesOf(alloc))
+	}
+	if !ctrPolicy.InheritHugeTLB() {
+		return numaNodes, nil, ctrPolicy, true
+	}
+	// the pod limits must cover the claims the container shares, including the ones
+	// of the containers not created yet, as the init containers run before them
+	for _, claimUID := range mdrv.allocMgr.GetClaimsForPod(pod.Uid) {
+		mdrv.allocMgr.BindClaim(lh, claimUID, ctr.PodSandboxId)
+	}
+	return numaNodes, podAllocs, ctrPolicy, true
+}
+
+func (mdrv *MemoryDriver) containerKind(ctx context.Context, lh logr.Logger, pod *api.PodSandbox, ctr *api.Container) (policy.ContainerKind, error) {
+	if mdrv.podLister != nil {
+		k8sPod, err := mdrv.podLister.Pods(pod.Namespace).Get(pod.Name)
+		if err == nil {
+			return policy.KindOfContainer(k8sPod, ctr.Name), nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return policy.ContainerKindRegular, err
+		}
+		// the cache is still syncing, or lagging behind the runtime
+		lh.V(4).Info("pod not cached, reading from the API server")
+	}
+	k8sPod, err := mdrv.kubeClient.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return policy.ContainerKindRegular, err
+	}
+	return policy.KindOfContainer(k8sPod, ctr.Name), nil
+}
+
+// enforcementDisabled tells if the pod opted out of the enforcement of its claims with the pod annotation.
+func (mdrv *MemoryDriver) enforcementDisabled(lh logr.Logger, pod *api.PodSandbox) bool {
+	disabled, err := policy.EnforcementDisabled(pod.GetAnnotations())
+	if err != nil {
+		lh.Error(err, "enforcing the claims of the pod")
+	}
+	return disabled
+}
+
 func (mdrv *MemoryDriver) handlePodSandbox(lh logr.Logger, pod *api.PodSandbox) error {
 	mdrv.cgPathByPodUID[pod.Uid] = pod.Linux.CgroupParent
 	lh.V(2).Info("registered pod cgroup path", "cgroupParent", pod.Linux.CgroupParent)
 	return nil
 }
 
-func (mdrv *MemoryDriver) updatePodLimits(lh logr.Logger, machineData sysinfo.MachineData, cgroupParent string, limits []hugepages.Limit) error {
-	if mdrv.cgMount == "" {
+// validatePodMems makes sure the kernel will not silently intersect the requested memory nodes
+// with a more restrictive cpuset set higher in the hierarchy.
+func (mdrv *MemoryDriver) validatePodMems(lh logr.Logger, cgroupParent string, numaNodes cpuset.CPUSet) error {
+	if mdrv.cgMount == "" || cgroupParent == "" {
 		return nil // nothing to do
 	}
+	return cgroups.ValidateMemsHierarchy(lh, mdrv.cgMount, cgroupParent, numaNodes)
+}
+
+// applyPodLimits sets the hugetlb limits of the pod cgroup to its baseline plus the limits of the claims
+// of all its containers created so far. The failures which can go away by themselves, like the pod cgroup
+// not created yet, are retried in the background.
+func (mdrv *MemoryDriver) applyPodLimits(lh logr.Logger, machineData sysinfo.MachineData, pod *api.PodSandbox, cgroupParent string) {
+	if mdrv.cgMount == "" {
+		return // nothing to do
+	}
+	op := mdrv.podLimitsOp(machineData, pod, cgroupParent)
+	err := op(lh)
+	if err == nil || mdrv.limitsRetry == nil || !isRetriablePodLimitsError(err) {
+		return
+	}
+	lh.Info("pod cgroup limits failed, will retry", "cgroupParent", cgroupParent, "reason", err.Error())
+	mdrv.limitsRetry.Add(lh, limitretry.Item{
+		PodUID: pod.Uid,
+		Op: func(lh logr.Logger) error {
+			if err := op(lh); err != nil {
+				return err
+			}
+			// the watchdog got the limits before they were set
+			mdrv.guardPodLimits(lh, machineData, pod, cgroupParent)
+			return nil
+		},
+	}, time.Now())
+}
+
+// errPodCgroupMissing: the runtime did not create the pod cgroup yet.
+var errPodCgroupMissing = errors.New("pod cgroup missing")
+
+// podLimitsOp returns the operation setting the limits of the pod cgroup to its baseline plus the limits
+// of the claims bound to its containers. The baseline is read once, before the first write, and the claims
+// are counted once each, so the operation can run again, on retries or for the next containers, without
+// adding the limits of the claims twice.
+func (mdrv *MemoryDriver) podLimitsOp(machineData sysinfo.MachineData, pod *api.PodSandbox, cgroupParent string) limitretry.Op {
+	cgPath := filepath.Join(mdrv.cgMount, cgroupParent)
+	podUID, podSandboxID := pod.Uid, pod.Id
+	return func(lh logr.Logger) error {
+		// the limits of a missing cgroup read as unset, which would make us drop the ones of the kubelet
+		if _, err := os.Stat(cgPath); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %q", errPodCgroupMissing, cgPath)
+		}
+		baseLimits, err := mdrv.podBaseLimits(lh, machineData, podUID, cgPath)
+		if err != nil {
+			lh.V(2).Error(err, "failed to get the current pod cgroup limits", "root", mdrv.cgMount, "path", cgroupParent)
+			return err
+		}
+		claimLimits := hugepages.LimitsFromAllocations(lh, machineData, mdrv.allocMgr.GetAllocationsForSandbox(podSandboxID))
+		newLimits := hugepages.SumLimits(baseLimits, claimLimits)
+		lh.V(4).Info("pod limits",
+			"baseline", hugepages.LimitsToString(baseLimits),
+			"claims", hugepages.LimitsToString(claimLimits),
+			"enforcing", hugepages.LimitsToString(newLimits),
+		)
+		err = mdrv.setSystemLimits(lh, cgPath, newLimits)
+		if err != nil {
+			lh.V(2).Error(err, "failed to set pod cgroup limits", "root", mdrv.cgMount, "path", cgroupParent, "permanent", cgroups.IsPermanent(err))
+			return err
+		}
+		return nil
+	}
+}
+
+// podBaseLimits returns the limits of the pod cgroup before the claims, reading them the first time.
+func (mdrv *MemoryDriver) podBaseLimits(lh logr.Logger, machineData sysinfo.MachineData, podUID, cgPath string) ([]hugepages.Limit, error) {
+	if baseLimits, ok := mdrv.podBaselines.Load(podUID); ok {
+		return baseLimits, nil
+	}
+	curLimits, err := hugepages.LimitsFromSystemPath(lh, machineData, cgPath)
+	if err != nil {
+		return nil, err
+	}
+	return mdrv.podBaselines.LoadOrStore(podUID, curLimits), nil
+}
+
+// recoverPodBaseline rebuilds the baseline of a pod found running, whose limits include the claims of its
+// containers already created, for the containers created after a restart of the driver.
+func (mdrv *MemoryDriver) recoverPodBaseline(lh logr.Logger, pod *api.PodSandbox) {
+	cgroupParent := mdrv.cgPathByPodUID[pod.Uid]
+	if mdrv.cgMount == "" || cgroupParent == "" {
+		return // nothing to do
+	}
+	machineData := mdrv.discoverer.GetCachedMachineData()
+	curLimits, err := hugepages.LimitsFromSystemPath(lh, machineData, filepath.Join(mdrv.cgMount, cgroupParent))
+	if err != nil {
+		lh.Error(err, "cannot recover the pod cgroup limits before the claims", "cgroupParent", cgroupParent)
+		return
+	}
+	claimLimits := hugepages.LimitsFromAllocations(lh, machineData, mdrv.allocMgr.GetAllocationsForSandbox(pod.Id))
+	baseLimits := hugepages.SubtractLimits(curLimits, claimLimits)
+	lh.V(2).Info("recovered pod baseline limits", "current", hugepages.LimitsToString(curLimits), "baseline", hugepages.LimitsToString(baseLimits))
+	mdrv.podBaselines.Store(pod.Uid, baseLimits)
+}
+
+// isRetriablePodLimitsError tells the failures setting the pod limits worth a retry later.
+func isRetriablePodLimitsError(err error) bool {
+	return errors.Is(err, errPodCgroupMissing) || !cgroups.IsPermanent(err)
+}
+
+// setSystemLimits retries the transient failures setting the limits. The permanent failures,
+// like a missing controller or a rejected value, would fail again in the same way.
+func (mdrv *MemoryDriver) setSystemLimits(lh logr.Logger, cgPath string, limits []hugepages.Limit) error {
+	return retry.OnError(limitsBackoff, func(err error) bool {
+		return !cgroups.IsPermanent(err)
+	}, func() error {
+		return hugepages.SetSystemLimits(lh, mdrv.cgWriter, cgPath, limits, mdrv.rsvdMode)
+	})
+}
+
+var limitsBackoff = wait.Backoff{
+	Steps:    3,
+	Duration: 10 * time.Millisecond,
+	Factor:   2.0,
+}
+
+// limitsRetryBackoff paces the background retries of the pod limits, once the immediate ones failed.
+var limitsRetryBackoff = wait.Backoff{
+	Steps:    10,
+	Duration: 1 * time.Second,
+	Factor:   2.0,
+	Cap:      1 * time.Minute,
+}
+
+// watchPodEvents makes sure the memory events of the pod are reported referencing its claims.
+func (mdrv *MemoryDriver) watchPodEvents(lh logr.Logger, machineData sysinfo.MachineData, pod *api.PodSandbox, cgroupParent string) {
+	if mdrv.oomWatcher == nil {
+		return
+	}
+	cgPath := filepath.Join(mdrv.cgMount, cgroupParent)
+	tgt := oomwatch.Target{
+		PodUID:     pod.Uid,
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		CgroupPath: cgPath,
+	}
+	for _, claimUID := range mdrv.allocMgr.GetClaimsForPod(pod.Uid) {
+		tgt.ClaimUIDs = append(tgt.ClaimUIDs, string(claimUID))
+	}
+	for _, hpSize := range machineData.Hugepagesizes {
+		tgt.PageSizes = append(tgt.PageSizes, unitconv.SizeInBytesToCGroupString(hpSize))
+	}
+	limits, err := hugepages.LimitsFromSystemPath(lh, machineData, cgPath)
+	if err == nil {
+		tgt.Limits = hugepages.LimitsToString(limits)
+	}
+	mdrv.oomWatcher.Track(lh, tgt)
+}
+
+// guardPodLimits makes sure the hugetlb limits of the pod, as programmed, are guarded by the watchdog.
+func (mdrv *MemoryDriver) guardPodLimits(lh logr.Logger, machineData sysinfo.MachineData, pod *api.PodSandbox, cgroupParent string) {
+	if mdrv.watchdog == nil {
+		return
+	}
 	cgPath := filepath.Join(mdrv.cgMount, cgroupParent)
+	limits, err := hugepages.LimitsFromSystemPath(lh, machineData, cgPath)
+	if err != nil {
+		lh.V(2).Error(err, "failed to get the pod cgroup limits to guard", "path", cgroupParent)
+		return
+	}
+	tgt := limitwatch.Target{
+		PodUID:     pod.Uid,
+		Namespace:  pod.Namespace,
+		Name:       pod.Name,
+		CgroupPath: cgPath,
+		Limits:     make(map[string]int64, len(limits)),
+	}
+	for _, limit := range limits {
+		if !limit.Limit.Unset {
+			tgt.Limits[limit.PageSize] = int64(limit.Limit.Value)
+		}
+	}
+	mdrv.watchdog.Track(lh, tgt)
+}
+
+// memoryHighFile is the cgroup v2 soft limit of the memory: past it, the cgroup is throttled and its memory reclaimed.
+const memoryHighFile = "memory.high"
 
-	curLimits, err := hugepages.LimitsFromSystemPath(lh, machineData, cgroupParent)
+// memoryHigh returns the soft limit of the container: the memory of the burstable claims it consumes. Zero means none.
+func memoryHigh(ctr *api.Container) (int64, error) {
+	highByClaim, err := env.ExtractMemoryHigh(ctr.Env)
 	if err != nil {
-		lh.V(2).Error(err, "failed to get the current pod cgroup limits", "root", mdrv.cgMount, "path", cgroupParent)
-		return err
+		return 0, err
 	}
+	var total int64
+	for _, amount := range highByClaim {
+		total += amount
+	}
+	return total, nil
+}
 
-	newLimits := hugepages.SumLimits(curLimits, limits)
-	lh.V(4).Info("pod limits",
-		"previous", hugepages.LimitsToString(curLimits),
-		"current", hugepages.LimitsToString(limits),
-		"enforcing", hugepages.LimitsToString(newLimits),
-	)
+// swapLimit returns the swap limit for the given allocations, according to the swap policy.
+func (mdrv *MemoryDriver) swapLimit(allocs []types.Allocation) (int64, bool) {
+	if !mdrv.swapPolicy.IsManaged() {
+		return 0, false
+	}
+	var claimedBytes uint64
+	for _, alloc := range allocs {
+		if alloc.Kind != types.Memory {
+			continue
+		}
+		claimedBytes += uint64(alloc.Amount)
+	}
+	return mdrv.swapPolicy.SwapLimit(claimedBytes, mdrv.swapInfo.MemTotalBytes, mdrv.swapInfo.SwapTotalBytes)
+}
 
-	err = hugepages.SetSystemLimits(lh, cgPath, newLimits)
+// updatePodSwap sets the pod swap limit consistently with the limits of its containers.
+func (mdrv *MemoryDriver) updatePodSwap(lh logr.Logger, pod *api.PodSandbox, cgroupParent string) {
+	swapLimit, ok := mdrv.swapLimit(mdrv.allocMgr.GetAllocationsForPod(pod.Uid))
+	if !ok {
+		return
+	}
+	cgPath := filepath.Join(mdrv.cgMount, cgroupParent)
+	lh.V(2).Info("setting pod swap limit", "policy", mdrv.swapPolicy, "limit", swapLimit, "cgroupParent", cgroupParent)
+	err := mdrv.cgWriter.WriteValue(lh, cgPath, policy.SwapMaxFile, swapLimit)
+	if errors.Is(err, cgroups.ErrLimitFileMissing) {
+		// swap accounting disabled, nothing to enforce
+		lh.V(2).Info("swap limit not available, skipped", "cgroupParent", cgroupParent)
+		return
+	}
 	if err != nil {
-		lh.V(2).Error(err, "failed to set pod cgroup limits", "root", mdrv.cgMount, "path", cgroupParent)
-		return err
+		lh.Error(err, "failed to set pod swap limit", "cgroupParent", cgroupParent)
 	}
-	return nil
+}
+
+// limitsFromResources extracts the hugetlb limits set by the runtime from the pod-level resources.
+func limitsFromResources(res *api.LinuxResources) []hugepages.Limit {
+	if res == nil {
+		return nil
+	}
+	limits := make([]hugepages.Limit, 0, len(res.HugepageLimits))
+	for _, hpLimit := range res.HugepageLimits {
+		limits = append(limits, hugepages.Limit{
+			PageSize: hpLimit.PageSize,
+			Limit: hugepages.LimitValue{
+				Value: hpLimit.Limit,
+			},
+		})
+	}
+	return limits
+}
+
+// numaNodesOf returns the NUMA nodes an allocation spans as memory nodes set.
+func numaNodesOf(alloc types.Allocation) cpuset.CPUSet {
+	nodes := make([]int, 0, len(alloc.AmountByZone))
+	for _, numaZone := range alloc.NUMAZones() {
+		nodes = append(nodes, int(numaZone))
+	}
+	return cpuset.New(nodes...)
 }
 
 func toJSON(v any) string {
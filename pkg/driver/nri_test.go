@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/alloc"
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func TestPodLimitsOpReadsUnderCgroupMount(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	cgMount := t.TempDir()
+	cgroupParent := "kubepods.slice/kubepods-pod1234.slice"
+	cgPath := filepath.Join(cgMount, cgroupParent)
+	require.NoError(t, os.MkdirAll(cgPath, 0o755))
+	// the limits the kubelet set on the pod cgroup, which the claims must be added to
+	podLimit := int64(2 * (1 << 21))
+	for _, fileName := range []string{"hugetlb.2MB.max", "hugetlb.2MB.rsvd.max"} {
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, fileName), []byte(strconv.FormatInt(podLimit, 10)), 0o600))
+	}
+
+	allocMgr := alloc.NewTracker()
+	allocMgr.RegisterClaim("claim-UID", map[string]types.Allocation{
+		"hugepages-2m": {
+			ResourceIdent: types.ResourceIdent{
+				Kind:     types.Hugepages,
+				Pagesize: 2 * (1 << 20),
+			},
+			Amount:       4 * (1 << 21),
+			AmountByZone: map[int64]int64{0: 4 * (1 << 21)},
+		},
+	})
+	allocMgr.ReserveClaim("claim-UID", "pod-UID")
+	allocMgr.BindContainer(lh, "pod-SandboxID", "app", "claim-UID")
+
+	mdrv := &MemoryDriver{
+		cgMount:      cgMount,
+		cgWriter:     cgroups.LocalWriter{},
+		allocMgr:     allocMgr,
+		podBaselines: hugepages.NewBaselines(),
+	}
+	machineData := sysinfo.MachineData{
+		Hugepagesizes: []uint64{2 * (1 << 20)},
+	}
+	pod := &api.PodSandbox{
+		Id:  "pod-SandboxID",
+		Uid: "pod-UID",
+	}
+	require.NoError(t, mdrv.podLimitsOp(machineData, pod, cgroupParent)(lh))
+
+	got, err := os.ReadFile(filepath.Join(cgPath, "hugetlb.2MB.max"))
+	require.NoError(t, err)
+	require.Equal(t, strconv.FormatInt(6*(1<<21), 10), string(got))
+}
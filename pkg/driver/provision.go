@@ -0,0 +1,60 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/provision"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+)
+
+// ProvisionHugepages provisions the hugepages at runtime like the setup-hugepages tool, with the configuration
+// `config`, then discovers and publishes the resources again at once, so the claims can consume the new pools
+// without restarting the driver. The pools are never shrunk below the pages of the claims the driver tracks.
+// The pools are written like the reservations, through the privileged helper if the driver runs unprivileged,
+// and become the baseline of the reservations: releasing the claims afterwards leaves them like provisioned.
+// The resources are discovered again also if the provisioning failed, which can fail on some NUMA nodes only.
+func (mdrv *MemoryDriver) ProvisionHugepages(ctx context.Context, config []byte) (any, error) {
+	lh := mdrv.logger.WithName("ProvisionHugepages")
+	hpp, err := provision.ReadConfigurationFrom(bytes.NewReader(config))
+	if err != nil {
+		return nil, fmt.Errorf("reading the configuration: %w", err)
+	}
+	numaZones := len(mdrv.discoverer.GetCachedMachineData().Zones)
+	if numaZones == 0 {
+		return nil, errors.New("no NUMA zones discovered yet")
+	}
+	claimed := provision.ClaimedPagesFromDebug(mdrv.DebugClaims())
+	lh.Info("provisioning hugepages", "name", hpp.Name, "numaZones", numaZones)
+	var res provision.Result
+	err = mdrv.hpReserver.Provision(lh, func(writer reserve.PoolWriter) error {
+		var provErr error
+		res, provErr = provision.RuntimeHugepagesThrough(lh, hpp, mdrv.sysRoot, numaZones, claimed, writer)
+		return provErr
+	})
+	if err != nil {
+		lh.Error(err, "provisioning hugepages", "name", hpp.Name)
+	}
+	mdrv.PublishResources(logr.NewContext(ctx, lh))
+	return res, err
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package enforcement
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The enforcement status tells the cluster-level tooling if the driver can actually enforce the claims
+// on the node. A node can publish memory devices, but without NRI the containers are neither pinned nor limited.
+
+const (
+	AnnotationStatus = "dra.memory/enforcement"
+	// AnnotationReason explains the degraded status, and is removed once active
+	AnnotationReason = "dra.memory/enforcement-reason"
+
+	// maxReasonLength bounds the reason, which may embed error messages
+	maxReasonLength = 1024
+	// defaultRetryInterval is the delay between the attempts to publish the status
+	defaultRetryInterval = 10 * time.Second
+)
+
+type Status string
+
+const (
+	// StatusActive: the driver enforces the claims.
+	StatusActive Status = "active"
+	// StatusDegraded: the claims can be allocated, but not enforced.
+	StatusDegraded Status = "degraded"
+)
+
+type State struct {
+	Status Status
+	Reason string
+}
+
+func Active() State {
+	return State{Status: StatusActive}
+}
+
+func Degraded(reason string) State {
+	return State{Status: StatusDegraded, Reason: reason}
+}
+
+// Annotate sets the enforcement status annotations on the node.
+func Annotate(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, nodeName string, state State) error {
+	var reason any // nil removes the annotation
+	if state.Reason != "" {
+		reason = truncate(state.Reason, maxReasonLength)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				AnnotationStatus: string(state.Status),
+				AnnotationReason: reason,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cli.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("enforcement status updated", "node", nodeName, "status", state.Status, "reason", state.Reason)
+	return nil
+}
+
+// Reporter publishes the latest enforcement state on the node in the background,
+// so the callers, like the NRI hooks, never wait for the API server.
+type Reporter struct {
+	cli      kubernetes.Interface
+	nodeName string
+	notify   chan struct{}
+	// retryInterval is the delay before publishing again a failed publication
+	retryInterval time.Duration
+
+	mu      sync.Mutex
+	desired State
+
+	// publishMu serializes the publications, and protects closed
+	publishMu sync.Mutex
+	closed    bool
+}
+
+func NewReporter(cli kubernetes.Interface, nodeName string, initial State) *Reporter {
+	return &Reporter{
+		cli:           cli,
+		nodeName:      nodeName,
+		notify:        make(chan struct{}, 1),
+		retryInterval: defaultRetryInterval,
+		desired:       initial,
+	}
+}
+
+// Set requests to publish the state. Never blocks. A nil Reporter does nothing.
+func (rep *Reporter) Set(state State) {
+	if rep == nil {
+		return
+	}
+	rep.mu.Lock()
+	changed := rep.desired != state
+	rep.desired = state
+	rep.mu.Unlock()
+	if !changed {
+		return
+	}
+	select {
+	case rep.notify <- struct{}{}:
+	default: // a publication is pending already, and will pick the latest state
+	}
+}
+
+// Run publishes the state on changes until the context is done, retrying the failed publications.
+func (rep *Reporter) Run(ctx context.Context, lh logr.Logger) {
+	var published *State
+	ticker := time.NewTicker(rep.retryInterval)
+	defer ticker.Stop()
+	for {
+		desired := rep.get()
+		if published == nil || *published != desired {
+			err := rep.publish(ctx, lh, desired)
+			if err != nil {
+				lh.Error(err, "publishing enforcement status, will retry", "status", desired.Status)
+			} else {
+				published = &desired
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-rep.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close publishes the final state synchronously. Run publishes nothing afterwards. A nil Reporter does nothing.
+func (rep *Reporter) Close(ctx context.Context, lh logr.Logger, state State) error {
+	if rep == nil {
+		return nil
+	}
+	rep.publishMu.Lock()
+	defer rep.publishMu.Unlock()
+	rep.closed = true
+	return Annotate(ctx, lh, rep.cli, rep.nodeName, state)
+}
+
+func (rep *Reporter) get() State {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return rep.desired
+}
+
+func (rep *Reporter) publish(ctx context.Context, lh logr.Logger, state State) error {
+	rep.publishMu.Lock()
+	defer rep.publishMu.Unlock()
+	if rep.closed {
+		return nil
+	}
+	return Annotate(ctx, lh, rep.cli, rep.nodeName, state)
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
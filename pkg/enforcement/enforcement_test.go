@@ -0,0 +1,130 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package enforcement
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func makeNode() *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-0",
+			Annotations: map[string]string{
+				"example.com/unrelated": "keep",
+			},
+		},
+	}
+}
+
+func getAnnotations(t *testing.T, cli *fake.Clientset) map[string]string {
+	t.Helper()
+	node, err := cli.CoreV1().Nodes().Get(context.Background(), "node-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	return node.Annotations
+}
+
+func TestAnnotate(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+	cli := fake.NewClientset(makeNode())
+
+	require.NoError(t, Annotate(ctx, lh, cli, "node-0", Degraded("NRI plugin disconnected")))
+	require.Equal(t, map[string]string{
+		"example.com/unrelated": "keep",
+		AnnotationStatus:        "degraded",
+		AnnotationReason:        "NRI plugin disconnected",
+	}, getAnnotations(t, cli))
+
+	require.NoError(t, Annotate(ctx, lh, cli, "node-0", Active()))
+	require.Equal(t, map[string]string{
+		"example.com/unrelated": "keep",
+		AnnotationStatus:        "active",
+	}, getAnnotations(t, cli))
+
+	require.NoError(t, Annotate(ctx, lh, cli, "node-0", Degraded(strings.Repeat("x", 2*maxReasonLength))))
+	require.Len(t, getAnnotations(t, cli)[AnnotationReason], maxReasonLength)
+}
+
+func TestReporter(t *testing.T) {
+	lh := testr.New(t)
+	cli := fake.NewClientset(makeNode())
+	rep := NewReporter(cli, "node-0", Degraded("starting"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rep.Run(ctx, lh)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return getAnnotations(t, cli)[AnnotationReason] == "starting"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rep.Set(Active())
+	require.Eventually(t, func() bool {
+		return getAnnotations(t, cli)[AnnotationStatus] == "active"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, rep.Close(context.Background(), lh, Degraded("driver stopped")))
+	rep.Set(Active())
+	cancel()
+	<-done
+	require.Equal(t, "degraded", getAnnotations(t, cli)[AnnotationStatus], "published after close")
+}
+
+func TestReporterNil(t *testing.T) {
+	var rep *Reporter
+	require.NotPanics(t, func() { rep.Set(Active()) })
+	require.NoError(t, rep.Close(context.Background(), testr.New(t), Active()))
+}
+
+func TestReporterRetries(t *testing.T) {
+	lh := testr.New(t)
+	cli := fake.NewClientset(makeNode())
+	patches := 0
+	cli.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patches++
+		if patches == 1 {
+			return true, nil, errors.New("injected failure")
+		}
+		return false, nil, nil
+	})
+	rep := NewReporter(cli, "node-0", Active())
+	rep.retryInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rep.Run(ctx, lh)
+	require.Eventually(t, func() bool {
+		return getAnnotations(t, cli)[AnnotationStatus] == "active"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, patches, 2)
+}
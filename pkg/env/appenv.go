@@ -0,0 +1,97 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// The application-facing variables expose the allocation facts of a claim in plain formats, so the
+// applications can configure themselves from the claim without parsing the driver variables,
+// e.g. the JVM heap size or the DPDK EAL `--socket-mem`. Unlike the driver variables they don't
+// carry the claim UID: the containers consuming more claims get the values of one of them.
+
+const (
+	// AppNUMANodes is the comma-separated list of the NUMA nodes of the claim, e.g. "0,1".
+	AppNUMANodes = "DRA_MEMORY_NUMA_NODES"
+	// AppMemoryBytes is the amount of memory of the claim, in bytes.
+	AppMemoryBytes = "DRA_MEMORY_BYTES"
+	// the hugepages variables are named after the page size, e.g. DRA_HUGEPAGES_2M_BYTES
+	appHugepagesPrefix     = "DRA_HUGEPAGES_"
+	appHugepagesBytes      = "_BYTES"
+	appHugepagesSocketMem  = "_SOCKET_MEM"
+	appSocketMemMultiplier = 1 << 20
+)
+
+// AppHugepagesBytes returns the name of the variable holding the bytes of hugepages of size `pagesize` of the claim.
+func AppHugepagesBytes(pagesize uint64) string {
+	return appHugepagesPrefix + appPagesizeName(pagesize) + appHugepagesBytes
+}
+
+// AppHugepagesSocketMem returns the name of the variable holding the hugepages of size `pagesize` of the claim,
+// as the comma-separated MiB on each NUMA node from the node 0 up to the last node of the claim, like the DPDK
+// EAL `--socket-mem` option expects, e.g. "1024,0,1024".
+func AppHugepagesSocketMem(pagesize uint64) string {
+	return appHugepagesPrefix + appPagesizeName(pagesize) + appHugepagesSocketMem
+}
+
+// CreateApp returns the application-facing variables of the allocations of a claim, sorted by name.
+func CreateApp(_ logr.Logger, allocs []types.Allocation, claimNodes sets.Set[int64]) []string {
+	envs := []string{
+		AppNUMANodes + "=" + numaNodesToString(claimNodes),
+	}
+	for _, alloc := range allocs {
+		if !alloc.NeedsHugeTLB() {
+			envs = append(envs, AppMemoryBytes+"="+strconv.FormatInt(alloc.Amount, 10))
+			continue
+		}
+		envs = append(envs,
+			AppHugepagesBytes(alloc.Pagesize)+"="+strconv.FormatInt(alloc.Amount, 10),
+			AppHugepagesSocketMem(alloc.Pagesize)+"="+socketMem(alloc),
+		)
+	}
+	slices.Sort(envs)
+	return envs
+}
+
+// appPagesizeName returns the page size in the short form used by the applications, e.g. 2M or 1G.
+func appPagesizeName(pagesize uint64) string {
+	value, unit := unitconv.NarrowSize(pagesize)
+	return strconv.FormatUint(value, 10) + unit[:1]
+}
+
+func socketMem(alloc types.Allocation) string {
+	numaZones := alloc.NUMAZones()
+	if len(numaZones) == 0 {
+		return ""
+	}
+	chunks := make([]string, 0, numaZones[len(numaZones)-1]+1)
+	for numaZone := int64(0); numaZone <= numaZones[len(numaZones)-1]; numaZone++ {
+		// rounded down, not to exceed the claim with the pages smaller than 1MiB
+		chunks = append(chunks, strconv.FormatInt(alloc.AmountByZone[numaZone]/appSocketMemMultiplier, 10))
+	}
+	return strings.Join(chunks, ",")
+}
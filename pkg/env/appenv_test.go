@@ -0,0 +1,63 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func TestCreateApp(t *testing.T) {
+	logger := testr.New(t)
+
+	memory := types.NewAllocation(types.ResourceIdent{Kind: types.Memory, Pagesize: 4096}, 8*(1<<30), 2)
+	hugepages := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+		Amount:        3 * (1 << 30),
+		AmountByZone: map[int64]int64{
+			0: 1 << 30,
+			2: 2 * (1 << 30),
+		},
+	}
+	got := CreateApp(logger, []types.Allocation{memory, hugepages}, sets.New[int64](0, 2))
+	require.Equal(t, []string{
+		"DRA_HUGEPAGES_2M_BYTES=3221225472",
+		"DRA_HUGEPAGES_2M_SOCKET_MEM=1024,0,2048",
+		"DRA_MEMORY_BYTES=8589934592",
+		"DRA_MEMORY_NUMA_NODES=0,2",
+	}, got)
+
+	gigantic := types.NewAllocation(types.ResourceIdent{Kind: types.Hugepages, Pagesize: 1 << 30}, 4*(1<<30), 1)
+	got = CreateApp(logger, []types.Allocation{gigantic}, sets.New[int64](1))
+	require.Equal(t, []string{
+		"DRA_HUGEPAGES_1G_BYTES=4294967296",
+		"DRA_HUGEPAGES_1G_SOCKET_MEM=0,4096",
+		"DRA_MEMORY_NUMA_NODES=1",
+	}, got)
+
+	// the application variables must not confuse the driver parsers
+	gotNodes, gotAllocs, err := ExtractAll(logger, got, sets.New("hugepages-1Gi"))
+	require.NoError(t, err)
+	require.Empty(t, gotNodes)
+	require.Empty(t, gotAllocs)
+}
@@ -17,7 +17,11 @@
 package env
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -27,102 +31,271 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/cpuset"
 
-	"github.com/ffromani/dra-driver-memory/pkg/cdi"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
 
-const (
-	partNUMANodes = "NUMANodes"
-)
+// allocations spanning multiple NUMA zones are encoded as multiple single-zone chunks
+const allocChunkSeparator = ";"
 
 // This is the internal "communication" layer helpers. DRA and NRI layers communicate
-// through CDI specs and other channels whose code sits here.
+// through CDI specs and other channels whose code sits here. See key.go for the names of the variables.
+
+// CreateClaim reports the UID of the claim, which the other variables of the claim refer to by hash.
+// Must be part of the variables of each claim.
+func CreateClaim(_ logr.Logger, claimUID k8stypes.UID) string {
+	return makeEnv(claimUID, partClaim, string(claimUID))
+}
 
 func CreateNUMANodes(_ logr.Logger, claimUID k8stypes.UID, claimNodes sets.Set[int64]) string {
-	return fmt.Sprintf("%s_%s_%s=%s", cdi.EnvVarPrefix, claimUID, partNUMANodes, numaNodesToString(claimNodes))
+	return makeEnv(claimUID, partNUMANodes, numaNodesToString(claimNodes))
 }
 
 func CreateAlloc(_ logr.Logger, claimUID k8stypes.UID, alloc types.Allocation) string {
-	return fmt.Sprintf("%s_%s_%s=numanode:%d,size:%s", cdi.EnvVarPrefix, claimUID, resourceNameToEnv(alloc.Name()), alloc.NUMAZone, alloc.ToQuantityString())
+	chunks := make([]string, 0, len(alloc.AmountByZone))
+	for _, numaZone := range alloc.NUMAZones() {
+		chunks = append(chunks, fmt.Sprintf("numanode:%d,size:%s", numaZone, unitconv.SizeInBytesToQuantityString(alloc.AmountByZone[numaZone])))
+	}
+	return makeEnv(claimUID, allocPart(alloc.Name()), strings.Join(chunks, allocChunkSeparator))
 }
 
-func ExtractNUMANodesInto(lh logr.Logger, env string, numaNodesByClaim map[k8stypes.UID]cpuset.CPUSet) (bool, error) {
-	parts := strings.SplitN(env, "=", 2)
-	if len(parts) != 2 {
-		return false, fmt.Errorf("malformed DRA env entry %q", env)
-	}
-	key, value := parts[0], parts[1]
+// CreateMemoryHigh reports the soft limit of the memory of a burstable claim.
+func CreateMemoryHigh(_ logr.Logger, claimUID k8stypes.UID, amount int64) string {
+	return makeEnv(claimUID, partMemoryHigh, unitconv.SizeInBytesToQuantityString(amount))
+}
 
-	keyParts := strings.SplitN(key, "_", 3)
-	if len(keyParts) != 3 {
-		return false, fmt.Errorf("malformed DRA env key %q", key)
-	}
-	if keyParts[2] != partNUMANodes {
-		return false, nil // it's another env. Move on.
+// ExtractMemoryHigh returns the soft limits of the memory of the burstable claims, by claim, from the environment of a container.
+func ExtractMemoryHigh(envs []string) (map[k8stypes.UID]int64, error) {
+	entries, errs := parseEnvs(envs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
-	claimUID := k8stypes.UID(keyParts[1])
-	numaNodes, err := cpuset.Parse(value)
-	if err != nil {
-		return true, fmt.Errorf("failed to parse cpuset (for memory nodes) value %q from env %q: %w", value, env, err)
+	highByClaim := make(map[k8stypes.UID]int64)
+	for _, ent := range entries {
+		if ent.part != partMemoryHigh {
+			continue
+		}
+		if ent.claimUID == "" {
+			return nil, fmt.Errorf("malformed DRA env %q: missing claim UID", ent.env)
+		}
+		qty, err := resource.ParseQuantity(ent.value)
+		if err != nil {
+			return nil, fmt.Errorf("malformed DRA env memory high %q: %w", ent.env, err)
+		}
+		amount, ok := qty.AsInt64()
+		if !ok || amount <= 0 {
+			return nil, fmt.Errorf("malformed DRA env memory high %q", ent.env)
+		}
+		highByClaim[ent.claimUID] = amount
 	}
-	numaNodesByClaim[claimUID] = numaNodes
-	lh.V(4).Info("parsed NUMA Nodes", "claimUID", claimUID, "numaNodes", numaNodes.String())
-	return true, nil
+	return highByClaim, nil
+}
+
+// CreateGuestMemory reports the bytes of the claim available to the guest memory of a VM.
+func CreateGuestMemory(_ logr.Logger, claimUID k8stypes.UID, amount int64) string {
+	return makeEnv(claimUID, partGuestMemory, unitconv.SizeInBytesToQuantityString(amount))
 }
 
-func ExtractAllocsInto(lh logr.Logger, env string, resourceNames sets.Set[string], allocsByClaim map[k8stypes.UID]types.Allocation) (bool, error) {
-	parts := strings.SplitN(env, "=", 2)
-	if len(parts) != 2 {
-		return false, fmt.Errorf("malformed DRA env entry %q", env)
+// CreateHugeTLBFS reports the path in the container of the hugetlbfs mount of the claim.
+func CreateHugeTLBFS(_ logr.Logger, claimUID k8stypes.UID, path string) string {
+	return makeEnv(claimUID, partHugeTLBFS, path)
+}
+
+// CreateHugepagesFile reports the path in the container of the file holding the hugepages of the claim.
+func CreateHugepagesFile(_ logr.Logger, claimUID k8stypes.UID, path string) string {
+	return makeEnv(claimUID, partHugepagesFile, path)
+}
+
+// AdminDevice is a device observed by a claim with admin access.
+type AdminDevice struct {
+	Name string
+	Span types.Span
+}
+
+// CreateAdminAccess reports the devices observed by a claim with admin access. The NRI layer
+// ignores this entry on purpose: admin access consumes no capacity, so there is nothing to enforce.
+func CreateAdminAccess(_ logr.Logger, claimUID k8stypes.UID, devices []AdminDevice) string {
+	chunks := make([]string, 0, len(devices))
+	for _, dev := range devices {
+		chunks = append(chunks, fmt.Sprintf("device:%s,resource:%s,numanode:%d,size:%s", dev.Name, dev.Span.FullName(), dev.Span.NUMAZone, unitconv.SizeInBytesToQuantityString(dev.Span.Amount)))
 	}
-	key, value := parts[0], parts[1]
+	return makeEnv(claimUID, partAdminAccess, strings.Join(chunks, allocChunkSeparator))
+}
 
-	keyParts := strings.SplitN(key, "_", 3)
-	if len(keyParts) != 3 {
-		return false, fmt.Errorf("malformed DRA env key %q", key)
+// LookupAdminAccess returns the devices observed with admin access, if any, from the environment of a container.
+// The consumers don't know the claim UIDs, so the devices of all the claims are returned.
+func LookupAdminAccess(envs []string) ([]AdminDevice, error) {
+	entries, errs := parseEnvs(envs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
-	resourceName := envToResourceName(keyParts[2])
-	if !resourceNames.Has(resourceName) {
-		return false, nil // it's another env. Move on.
+	var devices []AdminDevice
+	for _, ent := range entries {
+		if ent.part != partAdminAccess {
+			continue
+		}
+		for chunk := range strings.SplitSeq(ent.value, allocChunkSeparator) {
+			dev, err := parseAdminDevice(chunk)
+			if err != nil {
+				return nil, err
+			}
+			devices = append(devices, dev)
+		}
 	}
-	claimUID := k8stypes.UID(keyParts[1])
+	return devices, nil
+}
 
-	ident, err := types.ResourceIdentFromName(resourceName)
+func parseAdminDevice(chunk string) (AdminDevice, error) {
+	fields := make(map[string]string)
+	for field := range strings.SplitSeq(chunk, ",") {
+		name, value, ok := strings.Cut(field, ":")
+		if !ok {
+			return AdminDevice{}, fmt.Errorf("malformed DRA env admin device %q", chunk)
+		}
+		fields[name] = value
+	}
+	ident, err := types.ResourceIdentFromName(fields["resource"])
 	if err != nil {
-		return false, err
+		return AdminDevice{}, fmt.Errorf("malformed DRA env admin device %q: %w", chunk, err)
 	}
-	alloc := types.Allocation{
-		ResourceIdent: ident,
+	numaZone, err := strconv.ParseInt(fields["numanode"], 10, 64)
+	if err != nil {
+		return AdminDevice{}, fmt.Errorf("malformed DRA env admin device %q: %w", chunk, err)
 	}
-	err = extractAllocValueInto(value, &alloc)
+	qty, err := resource.ParseQuantity(fields["size"])
 	if err != nil {
-		return false, err
+		return AdminDevice{}, fmt.Errorf("malformed DRA env admin device %q: %w", chunk, err)
+	}
+	amount, ok := qty.AsInt64()
+	if !ok || fields["device"] == "" {
+		return AdminDevice{}, fmt.Errorf("malformed DRA env admin device %q", chunk)
 	}
-	allocsByClaim[claimUID] = alloc
-	lh.V(4).Info("parsed allocation", "claimUID", claimUID, "resourceName", alloc.Name(), "amount", alloc.Amount, "NUMANode", alloc.NUMAZone)
-	return true, nil
+	return AdminDevice{
+		Name: fields["device"],
+		Span: types.Span{
+			ResourceIdent: ident,
+			Amount:        amount,
+			NUMAZone:      numaZone,
+		},
+	}, nil
 }
 
-func ExtractAll(lh logr.Logger, envs []string, resourceNames sets.Set[string]) (map[k8stypes.UID]cpuset.CPUSet, map[k8stypes.UID]types.Allocation, error) {
-	numaNodesByClaim := make(map[k8stypes.UID]cpuset.CPUSet)
-	allocsByClaim := make(map[k8stypes.UID]types.Allocation)
+// LookupGuestMemory returns the guest memory bytes, if any, from the environment of a container.
+// The consumers don't know the claim UIDs, so the first value found is returned.
+func LookupGuestMemory(envs []string) (int64, bool) {
+	value, ok := lookupPart(envs, partGuestMemory)
+	if !ok {
+		return 0, false
+	}
+	qty, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, false
+	}
+	return qty.AsInt64()
+}
 
-	for _, env := range envs {
-		if !strings.HasPrefix(env, cdi.EnvVarPrefix) {
+// LookupHugeTLBFS returns the hugetlbfs mount path, if any, from the environment of a container.
+// The consumers don't know the claim UIDs, so the first value found is returned.
+func LookupHugeTLBFS(envs []string) (string, bool) {
+	return lookupPart(envs, partHugeTLBFS)
+}
+
+// LookupHugepagesFile returns the path of the file holding the hugepages, if any, from the environment of a container.
+// The consumers don't know the claim UIDs, so the first value found is returned.
+func LookupHugepagesFile(envs []string) (string, bool) {
+	return lookupPart(envs, partHugepagesFile)
+}
+
+func lookupPart(envs []string, part string) (string, bool) {
+	entries, _ := parseEnvs(envs) // the consumers make do with the well-formed variables
+	for _, ent := range entries {
+		if ent.part == part {
+			return ent.value, true
+		}
+	}
+	return "", false
+}
+
+// ReasonMalformedEnv is the reason of the events about the claims left out because of malformed variables.
+const ReasonMalformedEnv = "MemoryClaimMalformedEnv"
+
+// MalformedEnvsError reports the driver variables skipped because malformed.
+type MalformedEnvsError struct {
+	Errs []error
+}
+
+func (e *MalformedEnvsError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d malformed DRA envs: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+func (e *MalformedEnvsError) Unwrap() []error {
+	return e.Errs
+}
+
+// ExtractAll returns the NUMA nodes and the allocations, sorted by resource, of the claims consumed by
+// a container, from its environment. The variables of the other sources are ignored.
+// A malformed variable leaves out its claim, whose other variables can't be trusted either, but not the
+// other claims: these are returned along with a *MalformedEnvsError listing the variables skipped.
+func ExtractAll(lh logr.Logger, envs []string, resourceNames sets.Set[string]) (map[k8stypes.UID]cpuset.CPUSet, map[k8stypes.UID][]types.Allocation, error) {
+	entries, errs := parseEnvs(envs)
+	resourceNameByPart := make(map[string]string, resourceNames.Len())
+	for resourceName := range resourceNames {
+		resourceNameByPart[allocPart(resourceName)] = resourceName
+	}
+
+	numaNodesByClaim := make(map[k8stypes.UID]cpuset.CPUSet)
+	allocsByClaim := make(map[k8stypes.UID][]types.Allocation)
+	malformedClaims := sets.New[k8stypes.UID]()
+	for _, ent := range entries {
+		resourceName, isAlloc := resourceNameByPart[ent.part]
+		if ent.part != partNUMANodes && !isAlloc {
+			continue // not needed to enforce the claims
+		}
+		lh.V(4).Info("Parsing DRA env", "entry", ent.env)
+		if ent.claimUID == "" {
+			errs = append(errs, fmt.Errorf("malformed DRA env %q: missing claim UID", ent.env))
 			continue
 		}
-		lh.V(4).Info("Parsing DRA env", "entry", env)
-		// we will ignore errors related to envs we didn't set: these are not significant
-		found, err := ExtractNUMANodesInto(lh, env, numaNodesByClaim)
-		if found && err != nil {
-			return nil, nil, err
+		if !isAlloc {
+			numaNodes, err := cpuset.Parse(ent.value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to parse cpuset (for memory nodes) value %q from env %q: %w", ent.value, ent.env, err))
+				malformedClaims.Insert(ent.claimUID)
+				continue
+			}
+			numaNodesByClaim[ent.claimUID] = numaNodes
+			lh.V(4).Info("parsed NUMA Nodes", "claimUID", ent.claimUID, "numaNodes", numaNodes.String())
+			continue
 		}
-		found, err = ExtractAllocsInto(lh, env, resourceNames, allocsByClaim)
-		if found && err != nil {
-			return nil, nil, err
+		alloc, err := extractAlloc(resourceName, ent.value)
+		if err != nil {
+			errs = append(errs, err)
+			malformedClaims.Insert(ent.claimUID)
+			continue
 		}
+		if slices.ContainsFunc(allocsByClaim[ent.claimUID], func(other types.Allocation) bool { return other.Name() == resourceName }) {
+			errs = append(errs, fmt.Errorf("malformed DRA env %q: duplicate allocation of %s", ent.env, resourceName))
+			malformedClaims.Insert(ent.claimUID)
+			continue
+		}
+		allocsByClaim[ent.claimUID] = append(allocsByClaim[ent.claimUID], alloc)
+		lh.V(4).Info("parsed allocation", "claimUID", ent.claimUID, "resourceName", alloc.Name(), "amount", alloc.Amount, "NUMANodes", alloc.NUMAZones())
+	}
+	for claimUID := range malformedClaims {
+		lh.V(2).Info("claim left out because of malformed DRA envs", "claimUID", claimUID)
+		delete(numaNodesByClaim, claimUID)
+		delete(allocsByClaim, claimUID)
+	}
+	for _, allocs := range allocsByClaim {
+		slices.SortFunc(allocs, func(a, b types.Allocation) int { return strings.Compare(a.Name(), b.Name()) })
+	}
+	if len(errs) > 0 {
+		return numaNodesByClaim, allocsByClaim, &MalformedEnvsError{Errs: errs}
 	}
-
 	return numaNodesByClaim, allocsByClaim, nil
 }
 
@@ -143,22 +316,44 @@ func envToResourceName(ev string) string {
 	return strings.ReplaceAll(ev, "_", "-")
 }
 
-func extractAllocValueInto(value string, alloc *types.Allocation) error {
-	var allocStr string
-	var numaNode int64
-	n, err := fmt.Sscanf(value, "numanode:%d,size:%s", &numaNode, &allocStr)
-	if n != 2 || err != nil {
-		return fmt.Errorf("malformed DRA env value %q: %w", value, err)
+// identFromName is like types.ResourceIdentFromName, but accepts also the canonical name of the memory,
+// which the allocations are reported by. Its page size is the one of the system, like in the discovery.
+func identFromName(resourceName string) (types.ResourceIdent, error) {
+	if resourceName == string(types.Memory) {
+		return types.ResourceIdent{Kind: types.Memory, Pagesize: uint64(os.Getpagesize())}, nil
 	}
-	qty, err := resource.ParseQuantity(allocStr)
+	return types.ResourceIdentFromName(resourceName)
+}
+
+func extractAlloc(resourceName, value string) (types.Allocation, error) {
+	ident, err := identFromName(resourceName)
 	if err != nil {
-		return fmt.Errorf("malformed DRA env size %q: %w", value, err)
+		return types.Allocation{}, err
 	}
-	amount, ok := qty.AsInt64()
-	if !ok {
-		return fmt.Errorf("cannot convert DRA env amount %v: %w", qty.String(), err)
+	alloc := types.Allocation{
+		ResourceIdent: ident,
+		AmountByZone:  make(map[int64]int64),
+	}
+	for chunk := range strings.SplitSeq(value, allocChunkSeparator) {
+		var allocStr string
+		var numaNode int64
+		n, err := fmt.Sscanf(chunk, "numanode:%d,size:%s", &numaNode, &allocStr)
+		if n != 2 || err != nil {
+			return types.Allocation{}, fmt.Errorf("malformed DRA env value %q: %w", value, err)
+		}
+		qty, err := resource.ParseQuantity(allocStr)
+		if err != nil {
+			return types.Allocation{}, fmt.Errorf("malformed DRA env size %q: %w", value, err)
+		}
+		amount, ok := qty.AsInt64()
+		if !ok {
+			return types.Allocation{}, fmt.Errorf("cannot convert DRA env amount %v: %w", qty.String(), err)
+		}
+		if _, ok := alloc.AmountByZone[numaNode]; ok {
+			return types.Allocation{}, fmt.Errorf("malformed DRA env value %q: duplicate NUMA node %d", value, numaNode)
+		}
+		alloc.AmountByZone[numaNode] = amount
+		alloc.Amount += amount
 	}
-	alloc.Amount = amount
-	alloc.NUMAZone = numaNode
-	return nil
+	return alloc, nil
 }
@@ -28,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/cpuset"
 
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
 )
 
@@ -53,11 +54,12 @@ func TestCreateNUMANodesRoundTrip(t *testing.T) {
 	for _, tcase := range testcases {
 		t.Run(tcase.name, func(t *testing.T) {
 			logger := testr.New(t)
-			env := CreateNUMANodes(logger, tcase.uid, tcase.nodes)
-			got := make(map[k8stypes.UID]cpuset.CPUSet)
-			ok, err := ExtractNUMANodesInto(logger, env, got)
+			envs := []string{
+				CreateClaim(logger, tcase.uid),
+				CreateNUMANodes(logger, tcase.uid, tcase.nodes),
+			}
+			got, _, err := ExtractAll(logger, envs, sets.New[string]())
 			require.NoError(t, err)
-			require.True(t, ok, "cannot extract from env var %q", env)
 			if diff := cmp.Diff(got, tcase.expected, cmpopts.IgnoreUnexported(cpuset.CPUSet{})); diff != "" {
 				t.Errorf("unexpected value: %v", diff)
 			}
@@ -70,7 +72,7 @@ func TestCreateAllocRoundTrip(t *testing.T) {
 		name     string
 		uid      k8stypes.UID
 		alloc    types.Allocation
-		expected map[k8stypes.UID]types.Allocation
+		expected map[k8stypes.UID][]types.Allocation
 	}
 
 	testcases := []testcase{
@@ -82,18 +84,40 @@ func TestCreateAllocRoundTrip(t *testing.T) {
 					Kind:     types.Hugepages,
 					Pagesize: 2 * 1024 * 1024,
 				},
-				Amount:   8 * 2 * 1024 * 1024,
-				NUMAZone: 2,
+				Amount:       8 * 2 * 1024 * 1024,
+				AmountByZone: map[int64]int64{2: 8 * 2 * 1024 * 1024},
 			},
-			expected: map[k8stypes.UID]types.Allocation{
-				k8stypes.UID("FOOBAR"): {
+			expected: map[k8stypes.UID][]types.Allocation{
+				k8stypes.UID("FOOBAR"): {{
 					ResourceIdent: types.ResourceIdent{
 						Kind:     types.Hugepages,
 						Pagesize: 2 * 1024 * 1024,
 					},
-					Amount:   8 * 2 * 1024 * 1024,
-					NUMAZone: 2,
+					Amount:       8 * 2 * 1024 * 1024,
+					AmountByZone: map[int64]int64{2: 8 * 2 * 1024 * 1024},
+				}},
+			},
+		},
+		{
+			name: "multiple nodes",
+			uid:  k8stypes.UID("FOOBAR"),
+			alloc: types.Allocation{
+				ResourceIdent: types.ResourceIdent{
+					Kind:     types.Hugepages,
+					Pagesize: 2 * 1024 * 1024,
 				},
+				Amount:       24 * 2 * 1024 * 1024,
+				AmountByZone: map[int64]int64{0: 16 * 2 * 1024 * 1024, 3: 8 * 2 * 1024 * 1024},
+			},
+			expected: map[k8stypes.UID][]types.Allocation{
+				k8stypes.UID("FOOBAR"): {{
+					ResourceIdent: types.ResourceIdent{
+						Kind:     types.Hugepages,
+						Pagesize: 2 * 1024 * 1024,
+					},
+					Amount:       24 * 2 * 1024 * 1024,
+					AmountByZone: map[int64]int64{0: 16 * 2 * 1024 * 1024, 3: 8 * 2 * 1024 * 1024},
+				}},
 			},
 		},
 	}
@@ -103,10 +127,8 @@ func TestCreateAllocRoundTrip(t *testing.T) {
 			logger := testr.New(t)
 			env := CreateAlloc(logger, tcase.uid, tcase.alloc)
 			logger.Info("CreateAlloc", "env", env)
-			got := make(map[k8stypes.UID]types.Allocation)
-			ok, err := ExtractAllocsInto(logger, env, sets.New(tcase.alloc.Name()), got)
+			_, got, err := ExtractAll(logger, []string{CreateClaim(logger, tcase.uid), env}, sets.New(tcase.alloc.Name()))
 			require.NoError(t, err)
-			require.True(t, ok, "cannot extract from env var %q", env)
 			if diff := cmp.Diff(got, tcase.expected); diff != "" {
 				t.Errorf("unexpected value: %v", diff)
 			}
@@ -114,6 +136,26 @@ func TestCreateAllocRoundTrip(t *testing.T) {
 	}
 }
 
+func TestExtractAllocsMalformed(t *testing.T) {
+	logger := testr.New(t)
+	resourceNames := sets.New("hugepages-2Mi")
+	for _, value := range []string{
+		"numanode:0,size:2Mi;",
+		"numanode:0,size:2Mi;numanode:0,size:4Mi",
+		"numanode:0;numanode:1,size:4Mi",
+	} {
+		envs := []string{
+			CreateClaim(logger, "FOOBAR"),
+			makeEnv("FOOBAR", allocPart("hugepages-2Mi"), value),
+		}
+		_, _, err := ExtractAll(logger, envs, resourceNames)
+		require.Error(t, err, "value %q", value)
+		// the variables of the older versions are validated the same way
+		_, _, err = ExtractAll(logger, []string{cdi.EnvVarPrefix + "_FOOBAR_hugepages_2Mi=" + value}, resourceNames)
+		require.Error(t, err, "legacy value %q", value)
+	}
+}
+
 func TestExtractAll(t *testing.T) {
 	type testcase struct {
 		name          string
@@ -121,7 +163,7 @@ func TestExtractAll(t *testing.T) {
 		alloc         types.Allocation
 		nodes         sets.Set[int64]
 		expectedNodes map[k8stypes.UID]cpuset.CPUSet
-		expectedSpans map[k8stypes.UID]types.Allocation
+		expectedSpans map[k8stypes.UID][]types.Allocation
 	}
 
 	testcases := []testcase{
@@ -133,22 +175,22 @@ func TestExtractAll(t *testing.T) {
 					Kind:     types.Hugepages,
 					Pagesize: 1024 * 1024 * 1024,
 				},
-				Amount:   8 * 1024 * 1024 * 1024,
-				NUMAZone: 0,
+				Amount:       8 * 1024 * 1024 * 1024,
+				AmountByZone: map[int64]int64{0: 8 * 1024 * 1024 * 1024},
 			},
 			nodes: sets.New[int64](0),
 			expectedNodes: map[k8stypes.UID]cpuset.CPUSet{
 				k8stypes.UID("FOOBAR"): cpuset.New(0),
 			},
-			expectedSpans: map[k8stypes.UID]types.Allocation{
-				k8stypes.UID("FOOBAR"): {
+			expectedSpans: map[k8stypes.UID][]types.Allocation{
+				k8stypes.UID("FOOBAR"): {{
 					ResourceIdent: types.ResourceIdent{
 						Kind:     types.Hugepages,
 						Pagesize: 1024 * 1024 * 1024,
 					},
-					Amount:   8 * 1024 * 1024 * 1024,
-					NUMAZone: 0,
-				},
+					Amount:       8 * 1024 * 1024 * 1024,
+					AmountByZone: map[int64]int64{0: 8 * 1024 * 1024 * 1024},
+				}},
 			},
 		},
 	}
@@ -159,6 +201,7 @@ func TestExtractAll(t *testing.T) {
 			envs := []string{
 				CreateAlloc(logger, tcase.uid, tcase.alloc),
 				CreateNUMANodes(logger, tcase.uid, tcase.nodes),
+				CreateClaim(logger, tcase.uid),
 			}
 			gotNodes, gotSpans, err := ExtractAll(logger, envs, sets.New(tcase.alloc.Name()))
 			require.NoError(t, err)
@@ -180,8 +223,8 @@ func TestExtractAllWithMixedEnvs(t *testing.T) {
 			Kind:     types.Hugepages,
 			Pagesize: 2 * (1 << 20),
 		},
-		Amount:   16 * (1 << 20),
-		NUMAZone: 1,
+		Amount:       16 * (1 << 20),
+		AmountByZone: map[int64]int64{1: 16 * (1 << 20)},
 	}
 	nodes := sets.New[int64](1)
 
@@ -189,6 +232,7 @@ func TestExtractAllWithMixedEnvs(t *testing.T) {
 	envs := []string{
 		"PATH=/usr/bin:/bin",
 		"HOME=/home/user",
+		CreateClaim(logger, uid),
 		CreateAlloc(logger, uid, alloc),
 		"LD_LIBRARY_PATH=/usr/lib",
 		CreateNUMANodes(logger, uid, nodes),
@@ -198,15 +242,15 @@ func TestExtractAllWithMixedEnvs(t *testing.T) {
 	expNodes := map[k8stypes.UID]cpuset.CPUSet{
 		uid: cpuset.New(0),
 	}
-	expSpans := map[k8stypes.UID]types.Allocation{
-		uid: {
+	expSpans := map[k8stypes.UID][]types.Allocation{
+		uid: {{
 			ResourceIdent: types.ResourceIdent{
 				Kind:     types.Hugepages,
 				Pagesize: 2 * (1 << 20),
 			},
-			Amount:   16 * (1 << 20),
-			NUMAZone: 1,
-		},
+			Amount:       16 * (1 << 20),
+			AmountByZone: map[int64]int64{1: 16 * (1 << 20)},
+		}},
 	}
 
 	gotNodes, gotSpans, err := ExtractAll(logger, envs, sets.New(alloc.Name()))
@@ -229,3 +273,161 @@ func TestExtractAllEmptyEnvs(t *testing.T) {
 	require.Empty(t, gotNodes)
 	require.Empty(t, gotSpans)
 }
+
+func TestLookupVirtualMachine(t *testing.T) {
+	logger := testr.New(t)
+	uid := k8stypes.UID("TESTUID")
+
+	_, ok := LookupGuestMemory(nil)
+	require.False(t, ok)
+	_, ok = LookupHugeTLBFS(nil)
+	require.False(t, ok)
+
+	envs := []string{
+		"PATH=/usr/bin:/bin",
+		CreateClaim(logger, uid),
+		CreateNUMANodes(logger, uid, sets.New[int64](0)),
+		CreateGuestMemory(logger, uid, 3*(1<<30)),
+		CreateHugeTLBFS(logger, uid, "/dev/hugepages-vm"),
+	}
+	guestMemory, ok := LookupGuestMemory(envs)
+	require.True(t, ok)
+	require.Equal(t, int64(3*(1<<30)), guestMemory)
+	path, ok := LookupHugeTLBFS(envs)
+	require.True(t, ok)
+	require.Equal(t, "/dev/hugepages-vm", path)
+
+	// must not confuse the allocation parser
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("hugepages-1Gi"))
+	require.NoError(t, err)
+	require.Len(t, gotNodes, 1)
+	require.Empty(t, gotAllocs)
+}
+
+func TestLookupHugepagesFile(t *testing.T) {
+	logger := testr.New(t)
+	uid := k8stypes.UID("TESTUID")
+
+	_, ok := LookupHugepagesFile(nil)
+	require.False(t, ok)
+
+	envs := []string{
+		"PATH=/usr/bin:/bin",
+		CreateClaim(logger, uid),
+		CreateNUMANodes(logger, uid, sets.New[int64](0)),
+		CreateHugepagesFile(logger, uid, "/dev/hugepages-app/pages"),
+	}
+	path, ok := LookupHugepagesFile(envs)
+	require.True(t, ok)
+	require.Equal(t, "/dev/hugepages-app/pages", path)
+	_, ok = LookupHugeTLBFS(envs)
+	require.False(t, ok)
+
+	// must not confuse the allocation parser
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("hugepages-1Gi"))
+	require.NoError(t, err)
+	require.Len(t, gotNodes, 1)
+	require.Empty(t, gotAllocs)
+}
+
+func TestLookupAdminAccess(t *testing.T) {
+	logger := testr.New(t)
+	devices := []AdminDevice{
+		{
+			Name: "memory-0",
+			Span: types.Span{
+				ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4 * (1 << 10)},
+				Amount:        64 * (1 << 30),
+				NUMAZone:      0,
+			},
+		},
+		{
+			Name: "hugepages-2m-1",
+			Span: types.Span{
+				ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+				Amount:        512 * (1 << 20),
+				NUMAZone:      1,
+			},
+		},
+	}
+
+	got, err := LookupAdminAccess(nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	envs := []string{
+		"PATH=/usr/bin:/bin",
+		CreateAdminAccess(logger, k8stypes.UID("TESTUID"), devices),
+	}
+	got, err = LookupAdminAccess(envs)
+	require.NoError(t, err)
+	require.Equal(t, devices, got)
+
+	// must not confuse the enforcement
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("memory", "hugepages-2Mi"))
+	require.NoError(t, err)
+	require.Empty(t, gotNodes)
+	require.Empty(t, gotAllocs)
+
+	_, err = LookupAdminAccess([]string{cdi.EnvVarPrefix + "_TESTUID_AdminAccess=device:memory-0,resource:memory-4Ki"})
+	require.Error(t, err)
+}
+
+func TestExtractMemoryHigh(t *testing.T) {
+	logger := testr.New(t)
+
+	got, err := ExtractMemoryHigh(nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	hpAlloc := types.NewAllocation(types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)}, 1<<30, 0)
+	envs := []string{
+		"PATH=/usr/bin:/bin",
+		CreateClaim(logger, k8stypes.UID("claim-a")),
+		CreateClaim(logger, k8stypes.UID("claim-b")),
+		CreateNUMANodes(logger, k8stypes.UID("claim-a"), sets.New[int64](0)),
+		CreateAlloc(logger, k8stypes.UID("claim-a"), hpAlloc),
+		CreateMemoryHigh(logger, k8stypes.UID("claim-a"), 2*(1<<30)),
+		CreateMemoryHigh(logger, k8stypes.UID("claim-b"), 512*(1<<20)),
+	}
+	got, err = ExtractMemoryHigh(envs)
+	require.NoError(t, err)
+	require.Equal(t, map[k8stypes.UID]int64{"claim-a": 2 * (1 << 30), "claim-b": 512 * (1 << 20)}, got)
+
+	// must not confuse the allocation parser
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("hugepages-2Mi"))
+	require.NoError(t, err)
+	require.Len(t, gotNodes, 1)
+	require.Equal(t, map[k8stypes.UID][]types.Allocation{"claim-a": {hpAlloc}}, gotAllocs)
+
+	_, err = ExtractMemoryHigh([]string{CreateClaim(logger, "claim-a"), makeEnv("claim-a", partMemoryHigh, "lots")})
+	require.Error(t, err)
+	_, err = ExtractMemoryHigh([]string{cdi.EnvVarPrefix + "_claim-a_MemoryHigh=lots"})
+	require.Error(t, err)
+}
+
+func TestExtractAllMalformedClaimLeftOut(t *testing.T) {
+	logger := testr.New(t)
+	hpAlloc := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+		Amount:        4 * (1 << 20),
+		AmountByZone:  map[int64]int64{0: 4 * (1 << 20)},
+	}
+	envs := []string{
+		CreateClaim(logger, "claim-good"),
+		CreateNUMANodes(logger, "claim-good", sets.New[int64](0)),
+		CreateAlloc(logger, "claim-good", hpAlloc),
+		CreateClaim(logger, "claim-bad"),
+		CreateNUMANodes(logger, "claim-bad", sets.New[int64](1)),
+		makeEnv("claim-bad", allocPart(hpAlloc.Name()), "numanode:1,size:lots"),
+		CreateNUMANodes(logger, "claim-orphan", sets.New[int64](1)),
+	}
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New(hpAlloc.Name()))
+	var malformed *MalformedEnvsError
+	require.ErrorAs(t, err, &malformed)
+	require.Len(t, malformed.Errs, 2, "malformed allocation and missing claim UID")
+
+	require.Len(t, gotNodes, 1)
+	require.True(t, gotNodes["claim-good"].Equals(cpuset.New(0)))
+	require.Equal(t, map[k8stypes.UID][]types.Allocation{"claim-good": {hpAlloc}}, gotAllocs)
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+)
+
+// The driver variables are named DRAMEMORY_C<claim hash>_<part>, e.g. DRAMEMORY_C5D41402ABC4B2A76_NUMA_NODES.
+// The claim hash is the first 8 bytes of the SHA-256 of the claim UID, in uppercase hex: the names made only
+// of uppercase letters, digits and underscores are portable to all the shells, unlike the raw UIDs.
+// The claim UID is carried by the CLAIM part, and checked against the hash when parsing.
+//
+// The parts are registered here, and nowhere else:
+//   - CLAIM: the claim UID
+//   - NUMA_NODES: the NUMA nodes of the claim, e.g. "0,1"
+//   - ALLOC_<resource>: the allocation of the resource, e.g. ALLOC_HUGEPAGES_2MI
+//   - MEMORY_HIGH: the soft limit of the memory of a burstable claim
+//   - GUEST_MEMORY, HUGETLBFS: the facts of the claims backing virtual machines
+//   - HUGEPAGES_FILE: the file holding the hugepages of the claim
+//   - ADMIN_ACCESS: the devices observed by a claim with admin access
+//
+// The older versions of the driver named the variables DRAMEMORY_<claim UID>_<part>, with the parts in
+// camel case and the resource names as they are. They are still understood, for the claims prepared before
+// the upgrade, and for the containers created before the upgrade.
+
+const (
+	partClaim         = "CLAIM"
+	partNUMANodes     = "NUMA_NODES"
+	partAllocPrefix   = "ALLOC_"
+	partMemoryHigh    = "MEMORY_HIGH"
+	partGuestMemory   = "GUEST_MEMORY"
+	partHugeTLBFS     = "HUGETLBFS"
+	partHugepagesFile = "HUGEPAGES_FILE"
+	partAdminAccess   = "ADMIN_ACCESS"
+
+	// claimHashBytes is long enough to make the collisions between the claims of a container negligible
+	claimHashBytes = 8
+)
+
+var (
+	keyRE = regexp.MustCompile(`^C([0-9A-F]{16})_([A-Z][A-Z0-9_]*)$`)
+
+	// legacyParts maps the parts of the older versions, but the allocations
+	legacyParts = map[string]string{
+		"NUMANodes":     partNUMANodes,
+		"MemoryHigh":    partMemoryHigh,
+		"GuestMemory":   partGuestMemory,
+		"HugeTLBFS":     partHugeTLBFS,
+		"HugepagesFile": partHugepagesFile,
+		"AdminAccess":   partAdminAccess,
+	}
+)
+
+// key is the parsed name of a driver variable. Claim is the claim hash, or the claim UID for the legacy names.
+type key struct {
+	claim  string
+	part   string
+	legacy bool
+}
+
+// entry is a driver variable, with its claim UID resolved. The UID is empty if the variables lack the claim part.
+type entry struct {
+	claimUID k8stypes.UID
+	part     string
+	value    string
+	env      string
+}
+
+func claimHash(claimUID k8stypes.UID) string {
+	sum := sha256.Sum256([]byte(claimUID))
+	return strings.ToUpper(hex.EncodeToString(sum[:claimHashBytes]))
+}
+
+func makeEnv(claimUID k8stypes.UID, part, value string) string {
+	return cdi.EnvVarPrefix + "_C" + claimHash(claimUID) + "_" + part + "=" + value
+}
+
+func allocPart(resourceName string) string {
+	return partAllocPrefix + strings.ToUpper(resourceNameToEnv(resourceName))
+}
+
+func parseKey(name string) (key, bool) {
+	rest, ok := strings.CutPrefix(name, cdi.EnvVarPrefix+"_")
+	if !ok {
+		return key{}, false
+	}
+	if match := keyRE.FindStringSubmatch(rest); match != nil {
+		return key{claim: match[1], part: match[2]}, true
+	}
+	claimUID, part, ok := strings.Cut(rest, "_")
+	if !ok || claimUID == "" || part == "" {
+		return key{}, false
+	}
+	if canon, ok := legacyParts[part]; ok {
+		part = canon
+	} else {
+		part = allocPart(envToResourceName(part))
+	}
+	return key{claim: claimUID, part: part, legacy: true}, true
+}
+
+// parseEnvs returns the driver variables found in the environment of a container, skipping the others.
+// A claim part not matching its hash is skipped and reported: the variables were not written by the driver.
+func parseEnvs(envs []string) ([]entry, []error) {
+	var entries []entry
+	var errs []error
+	var hashes []string // of the entries, empty for the legacy ones
+	uidByHash := make(map[string]k8stypes.UID)
+	for _, env := range envs {
+		name, value, ok := strings.Cut(env, "=")
+		if !ok {
+			continue
+		}
+		k, ok := parseKey(name)
+		if !ok {
+			continue
+		}
+		if k.legacy {
+			entries = append(entries, entry{claimUID: k8stypes.UID(k.claim), part: k.part, value: value, env: env})
+			hashes = append(hashes, "")
+			continue
+		}
+		if k.part == partClaim {
+			if claimHash(k8stypes.UID(value)) != k.claim {
+				errs = append(errs, fmt.Errorf("malformed DRA env %q: claim UID not matching the hash", env))
+				continue
+			}
+			uidByHash[k.claim] = k8stypes.UID(value)
+			continue
+		}
+		entries = append(entries, entry{part: k.part, value: value, env: env})
+		hashes = append(hashes, k.claim)
+	}
+	// the claim part can come after the other parts of the claim
+	for idx, hash := range hashes {
+		if hash != "" {
+			entries[idx].claimUID = uidByHash[hash]
+		}
+	}
+	return entries, errs
+}
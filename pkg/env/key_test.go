@@ -0,0 +1,155 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package env
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/cpuset"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func TestCreateEnvsArePortable(t *testing.T) {
+	logger := testr.New(t)
+	uid := k8stypes.UID("3f2b8e2a-6c1d-4e5f-9a7b-0c1d2e3f4a5b")
+	envs := []string{
+		CreateClaim(logger, uid),
+		CreateNUMANodes(logger, uid, sets.New[int64](0, 1)),
+		CreateAlloc(logger, uid, types.Allocation{
+			ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+			Amount:        4 * (1 << 20),
+			AmountByZone:  map[int64]int64{0: 4 * (1 << 20)},
+		}),
+		CreateMemoryHigh(logger, uid, 1<<30),
+		CreateGuestMemory(logger, uid, 1<<30),
+		CreateHugeTLBFS(logger, uid, "/dev/hugepages"),
+		CreateHugepagesFile(logger, uid, "/dev/hugepages/guest"),
+		CreateAdminAccess(logger, uid, []AdminDevice{{
+			Name: "memory-0",
+			Span: types.Span{
+				ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4 * (1 << 10)},
+				Amount:        64 * (1 << 30),
+			},
+		}}),
+	}
+	nameRE := regexp.MustCompile(`^` + cdi.EnvVarPrefix + `_C[0-9A-F]{16}_[A-Z][A-Z0-9_]*$`)
+	for _, env := range envs {
+		name, _, ok := strings.Cut(env, "=")
+		require.True(t, ok, "env %q", env)
+		require.Regexp(t, nameRE, name)
+	}
+}
+
+func TestExtractAllMultipleResources(t *testing.T) {
+	logger := testr.New(t)
+	uid := k8stypes.UID("claim-multi")
+	gbAlloc := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 1 << 30},
+		Amount:        2 * (1 << 30),
+		AmountByZone:  map[int64]int64{0: 2 * (1 << 30)},
+	}
+	hpAlloc := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+		Amount:        8 * (1 << 20),
+		AmountByZone:  map[int64]int64{0: 8 * (1 << 20)},
+	}
+	memAlloc := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: uint64(os.Getpagesize())},
+		Amount:        1 << 30,
+		AmountByZone:  map[int64]int64{0: 1 << 30},
+	}
+	envs := []string{
+		CreateAlloc(logger, uid, gbAlloc),
+		CreateAlloc(logger, uid, hpAlloc),
+		CreateAlloc(logger, uid, memAlloc),
+		CreateClaim(logger, uid),
+	}
+	resourceNames := sets.New(gbAlloc.Name(), hpAlloc.Name(), memAlloc.Name())
+	_, gotAllocs, err := ExtractAll(logger, envs, resourceNames)
+	require.NoError(t, err)
+	require.Equal(t, map[k8stypes.UID][]types.Allocation{uid: {gbAlloc, hpAlloc, memAlloc}}, gotAllocs)
+
+	envs = append(envs, CreateAlloc(logger, uid, hpAlloc))
+	_, _, err = ExtractAll(logger, envs, resourceNames)
+	require.Error(t, err, "duplicate allocation")
+}
+
+func TestExtractAllLegacyEnvs(t *testing.T) {
+	logger := testr.New(t)
+	legacyUID := k8stypes.UID("3f2b8e2a-6c1d-4e5f-9a7b-0c1d2e3f4a5b")
+	uid := k8stypes.UID("5d8c1f3e-2a4b-4c6d-8e9f-a0b1c2d3e4f5")
+	envs := []string{
+		cdi.EnvVarPrefix + "_" + string(legacyUID) + "_NUMANodes=1",
+		cdi.EnvVarPrefix + "_" + string(legacyUID) + "_hugepages_2Mi=numanode:1,size:4Mi",
+		CreateClaim(logger, uid),
+		CreateNUMANodes(logger, uid, sets.New[int64](0)),
+	}
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("hugepages-2Mi"))
+	require.NoError(t, err)
+	require.Len(t, gotNodes, 2)
+	require.True(t, gotNodes[legacyUID].Equals(cpuset.New(1)))
+	require.True(t, gotNodes[uid].Equals(cpuset.New(0)))
+	require.Equal(t, map[k8stypes.UID][]types.Allocation{
+		legacyUID: {{
+			ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+			Amount:        4 * (1 << 20),
+			AmountByZone:  map[int64]int64{1: 4 * (1 << 20)},
+		}},
+	}, gotAllocs)
+
+	got, ok := LookupGuestMemory([]string{cdi.EnvVarPrefix + "_" + string(legacyUID) + "_GuestMemory=1Gi"})
+	require.True(t, ok)
+	require.Equal(t, int64(1<<30), got)
+}
+
+func TestExtractAllClaimUID(t *testing.T) {
+	logger := testr.New(t)
+	uid := k8stypes.UID("claim-a")
+	nodes := CreateNUMANodes(logger, uid, sets.New[int64](0))
+
+	_, _, err := ExtractAll(logger, []string{nodes}, sets.New[string]())
+	require.Error(t, err, "missing claim UID")
+
+	forged := strings.Replace(CreateClaim(logger, uid), "=claim-a", "=claim-b", 1)
+	_, _, err = ExtractAll(logger, []string{forged, nodes}, sets.New[string]())
+	require.Error(t, err, "claim UID not matching the hash")
+}
+
+func TestExtractAllSkipsForeignEnvs(t *testing.T) {
+	logger := testr.New(t)
+	envs := []string{
+		cdi.EnvVarPrefix + "=1",
+		cdi.EnvVarPrefix + "_DEBUG=1",
+		cdi.EnvVarPrefix + "_LOG_LEVEL=4",
+		cdi.EnvVarPrefix + "X_FOO_NUMANodes=0",
+		cdi.EnvVarPrefix + "_CNOTAHASH_NUMA_NODES",
+	}
+	gotNodes, gotAllocs, err := ExtractAll(logger, envs, sets.New("hugepages-2Mi"))
+	require.NoError(t, err)
+	require.Empty(t, gotNodes)
+	require.Empty(t, gotAllocs)
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package failpoint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-logr/logr"
+)
+
+// Failpoints are the named points of the driver where the chaos tests inject faults.
+// An enabled failpoint kills the driver the first time it is hit, and records it fired
+// on a marker file, so the restarted driver goes past it and the tests can check the
+// system converges. Meant for testing only: never enable them in production.
+
+type Name string
+
+const (
+	// PrepareAfterCDIWrite fires after the CDI spec of a claim is written, before the claim is registered,
+	// so the NRI CreateContainer of the containers consuming the claim runs against a restarted driver.
+	PrepareAfterCDIWrite Name = "prepare-after-cdi-write"
+)
+
+var known = []Name{
+	PrepareAfterCDIWrite,
+}
+
+// Parse decodes a comma-separated list of failpoint names. The empty string enables none.
+func Parse(s string) ([]Name, error) {
+	var names []Name
+	if s == "" {
+		return names, nil
+	}
+	for item := range strings.SplitSeq(s, ",") {
+		name := Name(strings.TrimSpace(item))
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown failpoint: %q", item)
+		}
+		if slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// String returns the comma-separated list of the failpoint names, which Parse decodes.
+func String(names []Name) string {
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		items = append(items, string(name))
+	}
+	return strings.Join(items, ",")
+}
+
+// KillFunc terminates the driver abruptly, like a crash would.
+type KillFunc func()
+
+// KillSelf sends SIGKILL to the driver process, so no cleanup runs.
+func KillSelf() {
+	_ = syscall.Kill(os.Getpid(), syscall.SIGKILL)
+}
+
+type Set struct {
+	enabled   []Name
+	markerDir string
+	kill      KillFunc
+}
+
+// NewSet creates the Set of the enabled failpoints, which record they fired in `markerDir`.
+// The markers must survive the restarts of the driver. Returns nil if no failpoint is enabled.
+func NewSet(enabled []Name, markerDir string, kill KillFunc) *Set {
+	if len(enabled) == 0 {
+		return nil
+	}
+	return &Set{
+		enabled:   enabled,
+		markerDir: markerDir,
+		kill:      kill,
+	}
+}
+
+// Hit kills the driver if the failpoint `name` is enabled and didn't fire yet.
+// A nil Set has no failpoint enabled.
+func (fs *Set) Hit(lh logr.Logger, name Name) {
+	if fs == nil || !slices.Contains(fs.enabled, name) {
+		return
+	}
+	markerPath := fs.MarkerPath(name)
+	_, err := os.Stat(markerPath)
+	if err == nil {
+		lh.V(4).Info("failpoint already fired", "failpoint", name)
+		return
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		lh.Error(err, "checking failpoint marker, skipped", "failpoint", name, "path", markerPath)
+		return
+	}
+	// if we can't record the failpoint fired, we would kill the driver on every restart
+	err = os.WriteFile(markerPath, []byte(name), 0600)
+	if err != nil {
+		lh.Error(err, "writing failpoint marker, skipped", "failpoint", name, "path", markerPath)
+		return
+	}
+	lh.Info("failpoint fired, killing the driver", "failpoint", name)
+	fs.kill()
+}
+
+func (fs *Set) MarkerPath(name Name) string {
+	return MarkerPath(fs.markerDir, name)
+}
+
+// MarkerPath returns the path of the file in `markerDir` recording the failpoint `name` fired.
+// Remove it to rearm the failpoint.
+func MarkerPath(markerDir string, name Name) string {
+	return filepath.Join(markerDir, "failpoint-"+string(name)+".fired")
+}
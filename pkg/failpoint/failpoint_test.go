@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package failpoint
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testcases := []struct {
+		name        string
+		input       string
+		expected    []Name
+		expectedErr bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:     "single",
+			input:    "prepare-after-cdi-write",
+			expected: []Name{PrepareAfterCDIWrite},
+		},
+		{
+			name:     "duplicates and spaces",
+			input:    "prepare-after-cdi-write, prepare-after-cdi-write",
+			expected: []Name{PrepareAfterCDIWrite},
+		},
+		{
+			name:        "unknown",
+			input:       "prepare-after-cdi-write,before-everything",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Parse(tcase.input)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+			require.Equal(t, tcase.input != "", String(got) == string(PrepareAfterCDIWrite))
+		})
+	}
+}
+
+func TestHitFiresOnce(t *testing.T) {
+	lh := testr.New(t)
+	markerDir := t.TempDir()
+	kills := 0
+	fs := NewSet([]Name{PrepareAfterCDIWrite}, markerDir, func() { kills++ })
+	require.NotNil(t, fs)
+
+	fs.Hit(lh, PrepareAfterCDIWrite)
+	require.Equal(t, 1, kills)
+	require.FileExists(t, fs.MarkerPath(PrepareAfterCDIWrite))
+
+	// the driver restarted: the marker survived
+	fs = NewSet([]Name{PrepareAfterCDIWrite}, markerDir, func() { kills++ })
+	fs.Hit(lh, PrepareAfterCDIWrite)
+	require.Equal(t, 1, kills)
+}
+
+func TestHitDisabled(t *testing.T) {
+	lh := testr.New(t)
+	var fs *Set
+	require.NotPanics(t, func() { fs.Hit(lh, PrepareAfterCDIWrite) })
+
+	fs = NewSet(nil, t.TempDir(), func() { t.Fatal("disabled failpoint fired") })
+	require.Nil(t, fs)
+	fs.Hit(lh, PrepareAfterCDIWrite)
+}
+
+func TestHitUnwritableMarker(t *testing.T) {
+	lh := testr.New(t)
+	kills := 0
+	fs := NewSet([]Name{PrepareAfterCDIWrite}, filepath.Join(t.TempDir(), "missing"), func() { kills++ })
+	fs.Hit(lh, PrepareAfterCDIWrite)
+	require.Zero(t, kills, "fired without a marker")
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package guardband
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// The usable memory of a NUMA zone is not all available to the workloads: the kernel, the DMA zones and the
+// device drivers allocate from it, and more so on the zone 0. Advertising all of it makes the claims fail
+// with ENOMEM on healthy nodes. The guard bands are the memory of each zone left out of the published devices.
+
+// AnyZone marks the guard band of each zone without its own.
+const AnyZone int64 = -1
+
+// Band is the memory left out of the memory device of a zone.
+type Band struct {
+	// NUMAZone is the zone of the band, or AnyZone
+	NUMAZone int64
+	Amount   int64 // bytes
+}
+
+func (bd Band) String() string {
+	qty := resource.NewQuantity(bd.Amount, resource.BinarySI)
+	if bd.NUMAZone == AnyZone {
+		return qty.String()
+	}
+	return strconv.FormatInt(bd.NUMAZone, 10) + "=" + qty.String()
+}
+
+type Bands []Band
+
+func (bds Bands) String() string {
+	items := make([]string, 0, len(bds))
+	for _, bd := range bds {
+		items = append(items, bd.String())
+	}
+	return strings.Join(items, ",")
+}
+
+// Parse reads the guard bands from a comma-separated list of `[<zone>=]<size>`, like `256Mi,0=2Gi`.
+// Without a zone, the size applies to each zone without its own band.
+func Parse(s string) (Bands, error) {
+	var bds Bands
+	if s == "" {
+		return bds, nil
+	}
+	for item := range strings.SplitSeq(s, ",") {
+		numaZone := AnyZone
+		size := item
+		if zone, val, ok := strings.Cut(item, "="); ok {
+			num, err := strconv.ParseInt(zone, 10, 64)
+			if err != nil || num < 0 {
+				return nil, fmt.Errorf("malformed memory guard band %q: invalid NUMA zone %q", item, zone)
+			}
+			numaZone, size = num, val
+		}
+		qty, err := resource.ParseQuantity(size)
+		if err != nil {
+			return nil, fmt.Errorf("malformed memory guard band %q: %w", item, err)
+		}
+		if qty.Value() <= 0 {
+			return nil, fmt.Errorf("memory guard band %q must be positive", item)
+		}
+		for _, bd := range bds {
+			if bd.NUMAZone == numaZone {
+				return nil, fmt.Errorf("duplicate memory guard band %q", item)
+			}
+		}
+		bds = append(bds, Band{NUMAZone: numaZone, Amount: qty.Value()})
+	}
+	return bds, nil
+}
+
+// For returns the bytes to leave out of the memory device of the zone: its own band if any,
+// otherwise the band of any zone, otherwise zero.
+func (bds Bands) For(numaZone int64) int64 {
+	var amount int64
+	for _, bd := range bds {
+		if bd.NUMAZone == numaZone {
+			return bd.Amount
+		}
+		if bd.NUMAZone == AnyZone {
+			amount = bd.Amount
+		}
+	}
+	return amount
+}
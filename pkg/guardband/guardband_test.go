@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package guardband
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		name        string
+		value       string
+		expected    Bands
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:  "empty",
+			value: "",
+		},
+		{
+			name:  "any zone",
+			value: "256Mi",
+			expected: Bands{
+				{NUMAZone: AnyZone, Amount: 256 << 20},
+			},
+		},
+		{
+			name:  "any zone and zone",
+			value: "256Mi,0=2Gi",
+			expected: Bands{
+				{NUMAZone: AnyZone, Amount: 256 << 20},
+				{NUMAZone: 0, Amount: 2 << 30},
+			},
+		},
+		{
+			name:        "duplicate zone",
+			value:       "1=1Gi,1=2Gi",
+			expectedErr: true,
+		},
+		{
+			name:        "negative zone",
+			value:       "-1=1Gi",
+			expectedErr: true,
+		},
+		{
+			name:        "zero size",
+			value:       "0=0",
+			expectedErr: true,
+		},
+		{
+			name:        "malformed size",
+			value:       "0=lots",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Parse(tcase.value)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+			require.Equal(t, tcase.value, got.String())
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	bds := Bands{
+		{NUMAZone: 0, Amount: 2 << 30},
+		{NUMAZone: AnyZone, Amount: 256 << 20},
+	}
+	require.Equal(t, int64(2<<30), bds.For(0))
+	require.Equal(t, int64(256<<20), bds.For(1))
+	require.Equal(t, int64(2<<30), Bands{{NUMAZone: 0, Amount: 2 << 30}}.For(0))
+	require.Zero(t, Bands{{NUMAZone: 0, Amount: 2 << 30}}.For(1))
+	require.Zero(t, Bands(nil).For(0))
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The hugepages reserved on the kernel command line are allocated at boot, before the memory fragments,
+// so the pools are stable. The pages allocated at runtime may not be available when the node needs them:
+// the gigantic pages (1Gi on x86) need physically contiguous memory, which a running node seldom has,
+// unless it was set aside at boot as contiguous memory area (CMA) with `hugetlb_cma`.
+
+// Backing is how the pages of a pool are obtained by the kernel.
+type Backing string
+
+const (
+	// Boot is for the pages reserved on the kernel command line.
+	Boot Backing = "boot"
+	// CMA is for the gigantic pages allocated at runtime from the contiguous memory area reserved at boot.
+	CMA Backing = "cma"
+	// Runtime is for the pages allocated at runtime only, which may fail.
+	Runtime Backing = "runtime"
+)
+
+// AnyZone marks the settings of the whole node.
+const AnyZone int64 = -1
+
+// giganticThreshold is the largest page size the buddy allocator can provide, with 4Ki base pages.
+// The larger pages are gigantic, and can come from the CMA.
+const giganticThreshold = 4 << 20
+
+// Cmdline is the hugepages setup of the kernel command line.
+type Cmdline struct {
+	// BootPages are the pages reserved at boot, by page size and NUMA zone, or AnyZone
+	BootPages map[uint64]map[int64]int64 `json:"bootPages,omitempty"`
+	// CMAZones are the NUMA zones having a contiguous memory area for the gigantic pages, or AnyZone
+	CMAZones []int64 `json:"cmaZones,omitempty"`
+}
+
+// Read parses the hugepages setup from the kernel command line of the node.
+func Read(procRoot string, defaultSize uint64) (Cmdline, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "proc", "cmdline"))
+	if err != nil {
+		return Cmdline{}, err
+	}
+	return Parse(string(data), defaultSize)
+}
+
+// Parse reads the hugepages setup from the kernel command line, like
+// `default_hugepagesz=1G hugepagesz=1G hugepages=0:4,1:4 hugepagesz=2M hugepages=512 hugetlb_cma=2G`.
+// `hugepages` reserves the pages of the last `hugepagesz`, or of the default size if it comes first;
+// `defaultSize` is the default size if the command line does not set it.
+func Parse(cmdline string, defaultSize uint64) (Cmdline, error) {
+	cl := Cmdline{
+		BootPages: make(map[uint64]map[int64]int64),
+	}
+	params := strings.Fields(cmdline)
+	for _, param := range params {
+		if val, ok := strings.CutPrefix(param, "default_hugepagesz="); ok {
+			size, err := parseSize(val)
+			if err != nil {
+				return Cmdline{}, fmt.Errorf("malformed %q: %w", param, err)
+			}
+			defaultSize = size
+		}
+	}
+	var pageSize uint64
+	for _, param := range params {
+		name, val, _ := strings.Cut(param, "=")
+		switch name {
+		case "hugepagesz":
+			size, err := parseSize(val)
+			if err != nil {
+				return Cmdline{}, fmt.Errorf("malformed %q: %w", param, err)
+			}
+			pageSize = size
+		case "hugepages":
+			size := pageSize
+			if size == 0 {
+				size = defaultSize
+			}
+			pagesByZone, err := parseZoned(val, parseCount)
+			if err != nil {
+				return Cmdline{}, fmt.Errorf("malformed %q: %w", param, err)
+			}
+			if size == 0 {
+				return Cmdline{}, fmt.Errorf("malformed %q: unknown page size", param)
+			}
+			cl.BootPages[size] = pagesByZone
+		case "hugetlb_cma":
+			areaByZone, err := parseZoned(val, parseArea)
+			if err != nil {
+				return Cmdline{}, fmt.Errorf("malformed %q: %w", param, err)
+			}
+			for numaZone, area := range areaByZone {
+				if area > 0 {
+					cl.CMAZones = append(cl.CMAZones, numaZone)
+				}
+			}
+		}
+	}
+	return cl, nil
+}
+
+// BackingOf returns how the pages of the given size of the NUMA zone are obtained.
+func (cl Cmdline) BackingOf(pageSize uint64, numaZone int64) Backing {
+	pagesByZone := cl.BootPages[pageSize]
+	if pagesByZone[numaZone] > 0 || pagesByZone[AnyZone] > 0 {
+		return Boot
+	}
+	if pageSize > giganticThreshold {
+		for _, cmaZone := range cl.CMAZones {
+			if cmaZone == numaZone || cmaZone == AnyZone {
+				return CMA
+			}
+		}
+	}
+	return Runtime
+}
+
+// parseZoned parses the values of the whole node, like `4`, or of the NUMA zones, like `0:4,1:2`.
+func parseZoned(val string, parse func(string) (int64, error)) (map[int64]int64, error) {
+	ret := make(map[int64]int64)
+	if !strings.Contains(val, ":") {
+		amount, err := parse(val)
+		if err != nil {
+			return nil, err
+		}
+		ret[AnyZone] = amount
+		return ret, nil
+	}
+	for item := range strings.SplitSeq(val, ",") {
+		zone, amount, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected <zone>:<value>, found %q", item)
+		}
+		numaZone, err := strconv.ParseInt(zone, 10, 64)
+		if err != nil || numaZone < 0 {
+			return nil, fmt.Errorf("invalid NUMA zone %q", zone)
+		}
+		ret[numaZone], err = parse(amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func parseCount(val string) (int64, error) {
+	return strconv.ParseInt(val, 10, 64)
+}
+
+func parseArea(val string) (int64, error) {
+	size, err := parseSize(val)
+	return int64(size), err
+}
+
+// parseSize parses the sizes like the kernel does (memparse), with an optional K, M, G or T binary suffix.
+func parseSize(val string) (uint64, error) {
+	shifts := map[byte]uint{'k': 10, 'K': 10, 'm': 20, 'M': 20, 'g': 30, 'G': 30, 't': 40, 'T': 40}
+	var shift uint
+	if len(val) > 0 {
+		if sh, ok := shifts[val[len(val)-1]]; ok {
+			shift = sh
+			val = val[:len(val)-1]
+		}
+	}
+	num, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return num << shift, nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	hp2m = 2 << 20
+	hp1g = 1 << 30
+)
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		name        string
+		cmdline     string
+		expected    Cmdline
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "no hugepages",
+			cmdline:  "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet",
+			expected: Cmdline{BootPages: map[uint64]map[int64]int64{}},
+		},
+		{
+			name:    "default size",
+			cmdline: "ro hugepages=512",
+			expected: Cmdline{BootPages: map[uint64]map[int64]int64{
+				hp2m: {AnyZone: 512},
+			}},
+		},
+		{
+			name:    "default size set later",
+			cmdline: "hugepages=4 default_hugepagesz=1G",
+			expected: Cmdline{BootPages: map[uint64]map[int64]int64{
+				hp1g: {AnyZone: 4},
+			}},
+		},
+		{
+			name:    "sizes and zones",
+			cmdline: "hugepagesz=1G hugepages=0:4,1:2 hugepagesz=2048K hugepages=1024 hugetlb_cma=0:2G,1:0",
+			expected: Cmdline{
+				BootPages: map[uint64]map[int64]int64{
+					hp1g: {0: 4, 1: 2},
+					hp2m: {AnyZone: 1024},
+				},
+				CMAZones: []int64{0},
+			},
+		},
+		{
+			name:        "malformed size",
+			cmdline:     "hugepagesz=1X hugepages=4",
+			expectedErr: true,
+		},
+		{
+			name:        "malformed zone",
+			cmdline:     "hugepages=a:4",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Parse(tcase.cmdline, hp2m)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
+func TestBackingOf(t *testing.T) {
+	cl, err := Parse("hugepagesz=1G hugepages=0:4 hugetlb_cma=2G hugepagesz=2M hugepages=0", hp2m)
+	require.NoError(t, err)
+	require.Equal(t, Boot, cl.BackingOf(hp1g, 0))
+	require.Equal(t, CMA, cl.BackingOf(hp1g, 1))
+	// the CMA is only for the gigantic pages
+	require.Equal(t, Runtime, cl.BackingOf(hp2m, 0))
+
+	require.Equal(t, Runtime, Cmdline{}.BackingOf(hp1g, 0))
+}
+
+func TestRead(t *testing.T) {
+	procRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "proc"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "proc", "cmdline"), []byte("ro hugepagesz=1G hugepages=8\n"), 0o644))
+
+	cl, err := Read(procRoot, hp2m)
+	require.NoError(t, err)
+	require.Equal(t, Boot, cl.BackingOf(hp1g, 3))
+
+	_, err = Read(t.TempDir(), hp2m)
+	require.Error(t, err)
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hugepages
+
+import (
+	"slices"
+	"sync"
+)
+
+// Baselines remembers the limits of the pod cgroups before the claims, like the ones the kubelet
+// sets from the hugepages resources of the containers. The limits of a pod cgroup are its baseline
+// plus the limits of all the claims consumed by its containers, each claim counted once, so they
+// can be recomputed from scratch whenever a container is created, without adding up twice.
+type Baselines struct {
+	mu    sync.Mutex
+	byPod map[string][]Limit
+}
+
+func NewBaselines() *Baselines {
+	return &Baselines{
+		byPod: make(map[string][]Limit),
+	}
+}
+
+// Load returns the baseline of the pod, if any.
+func (bl *Baselines) Load(podUID string) ([]Limit, bool) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	cur, ok := bl.byPod[podUID]
+	return slices.Clone(cur), ok
+}
+
+// LoadOrStore returns the baseline of the pod, storing `limits` if it has none yet.
+func (bl *Baselines) LoadOrStore(podUID string, limits []Limit) []Limit {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	if cur, ok := bl.byPod[podUID]; ok {
+		return slices.Clone(cur)
+	}
+	bl.byPod[podUID] = slices.Clone(limits)
+	return slices.Clone(limits)
+}
+
+// Store replaces the baseline of the pod, like when the kubelet rewrites the limits of the pod cgroup.
+func (bl *Baselines) Store(podUID string, limits []Limit) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.byPod[podUID] = slices.Clone(limits)
+}
+
+func (bl *Baselines) Forget(podUID string) {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	delete(bl.byPod, podUID)
+}
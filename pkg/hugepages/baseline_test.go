@@ -0,0 +1,49 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hugepages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselines(t *testing.T) {
+	kubeletLimits := []Limit{{PageSize: "2MB", Limit: LimitValue{Value: 4 * (1 << 21)}}}
+	readLater := []Limit{{PageSize: "2MB", Limit: LimitValue{Value: 6 * (1 << 21)}}}
+
+	bl := NewBaselines()
+	_, ok := bl.Load("pod-a")
+	require.False(t, ok)
+	require.Equal(t, kubeletLimits, bl.LoadOrStore("pod-a", kubeletLimits))
+	// the limits read once the claims are added must not replace the baseline
+	require.Equal(t, kubeletLimits, bl.LoadOrStore("pod-a", readLater))
+
+	got := bl.LoadOrStore("pod-a", nil)
+	got[0].Limit.Value = 0
+	require.Equal(t, kubeletLimits, bl.LoadOrStore("pod-a", nil), "baseline modified through a returned copy")
+
+	bl.Store("pod-a", readLater)
+	require.Equal(t, readLater, bl.LoadOrStore("pod-a", kubeletLimits))
+
+	got, ok = bl.Load("pod-a")
+	require.True(t, ok)
+	require.Equal(t, readLater, got)
+
+	bl.Forget("pod-a")
+	require.Equal(t, kubeletLimits, bl.LoadOrStore("pod-a", kubeletLimits))
+}
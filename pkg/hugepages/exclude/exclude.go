@@ -0,0 +1,199 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exclude
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// While a cluster migrates to DRA, the pods can still consume hugepages through the extended resources
+// (e.g. `hugepages-2Mi`) the kubelet advertises, and the kubelet can reserve hugepages for the system.
+// Both come from the same pools the driver publishes. The exclusions are the hugepages not managed
+// through DRA, which the driver subtracts from the capacity of its devices.
+
+// AnyZone marks the exclusions of the whole node, spread across the zones.
+const AnyZone int64 = -1
+
+// Exclusion is an amount of hugepages not managed through DRA.
+type Exclusion struct {
+	types.ResourceIdent
+	// NUMAZone is the zone of the hugepages, or AnyZone
+	NUMAZone int64
+	Amount   int64 // bytes
+}
+
+func (ex Exclusion) String() string {
+	qty := resource.NewQuantity(ex.Amount, resource.BinarySI)
+	if ex.NUMAZone == AnyZone {
+		return ex.Name() + "=" + qty.String()
+	}
+	return ex.Name() + "@" + strconv.FormatInt(ex.NUMAZone, 10) + "=" + qty.String()
+}
+
+type Exclusions []Exclusion
+
+func (exs Exclusions) String() string {
+	items := make([]string, 0, len(exs))
+	for _, ex := range exs {
+		items = append(items, ex.String())
+	}
+	return strings.Join(items, ",")
+}
+
+// Parse reads the exclusions from a comma-separated list of `<resource>[@<zone>]=<size>`,
+// like `hugepages-1Gi=4Gi,hugepages-2Mi@0=512Mi`. Without a zone, the size is of the whole node.
+func Parse(s string) (Exclusions, error) {
+	var exs Exclusions
+	if s == "" {
+		return exs, nil
+	}
+	for item := range strings.SplitSeq(s, ",") {
+		name, size, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed hugepages exclusion %q, expected <resource>[@<zone>]=<size>", item)
+		}
+		numaZone := AnyZone
+		name, zone, ok := strings.Cut(name, "@")
+		if ok {
+			val, err := strconv.ParseInt(zone, 10, 64)
+			if err != nil || val < 0 {
+				return nil, fmt.Errorf("malformed hugepages exclusion %q: invalid NUMA zone %q", item, zone)
+			}
+			numaZone = val
+		}
+		qty, err := resource.ParseQuantity(size)
+		if err != nil {
+			return nil, fmt.Errorf("malformed hugepages exclusion %q: %w", item, err)
+		}
+		ex, err := makeExclusion(name, numaZone, qty)
+		if err != nil {
+			return nil, err
+		}
+		exs = append(exs, ex)
+	}
+	return exs, nil
+}
+
+func makeExclusion(name string, numaZone int64, qty resource.Quantity) (Exclusion, error) {
+	ri, err := types.ResourceIdentFromName(name)
+	if err != nil || ri.Kind != types.Hugepages || ri.Name() != name {
+		return Exclusion{}, fmt.Errorf("unsupported resource %q, expected hugepages-<size> like hugepages-2Mi", name)
+	}
+	amount := qty.Value()
+	if amount <= 0 || amount%int64(ri.Pagesize) != 0 {
+		return Exclusion{}, fmt.Errorf("%s exclusion %s must be positive and multiple of the page size", name, qty.String())
+	}
+	return Exclusion{ResourceIdent: ri, NUMAZone: numaZone, Amount: amount}, nil
+}
+
+// kubeletConfig is the part of the KubeletConfiguration holding the memory reserved by NUMA zone,
+// including the hugepages.
+type kubeletConfig struct {
+	Kind           string                `json:"kind"`
+	ReservedMemory []kubeletReservedZone `json:"reservedMemory,omitempty"`
+}
+
+type kubeletReservedZone struct {
+	NUMANode int32                        `json:"numaNode"`
+	Limits   map[string]resource.Quantity `json:"limits"`
+}
+
+// FromKubeletConfig reads the hugepages the kubelet reserves for the system from its configuration file.
+func FromKubeletConfig(path string) (Exclusions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg kubeletConfig
+	err = yaml.Unmarshal(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("malformed kubelet configuration %q: %w", path, err)
+	}
+	if cfg.Kind != "KubeletConfiguration" {
+		return nil, fmt.Errorf("unsupported kubelet configuration %q: kind %q", path, cfg.Kind)
+	}
+	var exs Exclusions
+	for _, zone := range cfg.ReservedMemory {
+		for _, name := range slices.Sorted(maps.Keys(zone.Limits)) {
+			if !strings.HasPrefix(name, string(types.Hugepages)+"-") {
+				continue // the regular memory is not published as hugepages
+			}
+			qty := zone.Limits[name]
+			if qty.IsZero() {
+				continue
+			}
+			ex, err := makeExclusion(name, int64(zone.NUMANode), qty)
+			if err != nil {
+				return nil, fmt.Errorf("kubelet configuration %q: NUMA zone %d: %w", path, zone.NUMANode, err)
+			}
+			exs = append(exs, ex)
+		}
+	}
+	return exs, nil
+}
+
+// Resolve returns the bytes of hugepages of size `pagesize` to exclude, by NUMA zone, given the pages of each zone.
+// The exclusions of the whole node are spread evenly across the zones having pages of that size, in whole pages,
+// the first zones taking the pages left over. The exclusions never exceed the pages of a zone.
+func (exs Exclusions) Resolve(pagesize uint64, pagesByZone map[int64]int64) map[int64]int64 {
+	ret := make(map[int64]int64)
+	var nodeWidePages int64
+	for _, ex := range exs {
+		if ex.Kind != types.Hugepages || ex.Pagesize != pagesize {
+			continue
+		}
+		if ex.NUMAZone == AnyZone {
+			nodeWidePages += ex.Amount / int64(pagesize)
+			continue
+		}
+		ret[ex.NUMAZone] += ex.Amount
+	}
+	var zones []int64
+	for _, numaZone := range slices.Sorted(maps.Keys(pagesByZone)) {
+		if pagesByZone[numaZone] > 0 {
+			zones = append(zones, numaZone)
+		}
+	}
+	if nodeWidePages > 0 && len(zones) > 0 {
+		share, extra := nodeWidePages/int64(len(zones)), nodeWidePages%int64(len(zones))
+		for idx, numaZone := range zones {
+			pages := share
+			if int64(idx) < extra {
+				pages++
+			}
+			ret[numaZone] += pages * int64(pagesize)
+		}
+	}
+	for numaZone, amount := range ret {
+		ret[numaZone] = min(amount, pagesByZone[numaZone]*int64(pagesize))
+		if ret[numaZone] == 0 {
+			delete(ret, numaZone)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package exclude
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+var (
+	hp2m = types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 << 20}
+	hp1g = types.ResourceIdent{Kind: types.Hugepages, Pagesize: 1 << 30}
+)
+
+func TestParse(t *testing.T) {
+	type testcase struct {
+		name        string
+		value       string
+		expected    Exclusions
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:  "empty",
+			value: "",
+		},
+		{
+			name:  "node and zone",
+			value: "hugepages-1Gi=4Gi,hugepages-2Mi@1=512Mi",
+			expected: Exclusions{
+				{ResourceIdent: hp1g, NUMAZone: AnyZone, Amount: 4 << 30},
+				{ResourceIdent: hp2m, NUMAZone: 1, Amount: 512 << 20},
+			},
+		},
+		{
+			name:        "memory",
+			value:       "memory=1Gi",
+			expectedErr: true,
+		},
+		{
+			name:        "not multiple of the page size",
+			value:       "hugepages-1Gi=1536Mi",
+			expectedErr: true,
+		},
+		{
+			name:        "negative zone",
+			value:       "hugepages-2Mi@-1=2Mi",
+			expectedErr: true,
+		},
+		{
+			name:        "missing size",
+			value:       "hugepages-2Mi",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := Parse(tcase.value)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+			require.Equal(t, tcase.value, got.String())
+		})
+	}
+}
+
+func TestFromKubeletConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+memoryManagerPolicy: Static
+reservedMemory:
+- numaNode: 0
+  limits:
+    memory: 1Gi
+    hugepages-2Mi: 64Mi
+- numaNode: 1
+  limits:
+    memory: 1Gi
+    hugepages-1Gi: "0"
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	got, err := FromKubeletConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, Exclusions{{ResourceIdent: hp2m, NUMAZone: 0, Amount: 64 << 20}}, got)
+
+	require.NoError(t, os.WriteFile(path, []byte("kind: KubeProxyConfiguration\n"), 0o644))
+	_, err = FromKubeletConfig(path)
+	require.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	exs := Exclusions{
+		{ResourceIdent: hp2m, NUMAZone: AnyZone, Amount: 5 * (2 << 20)},
+		{ResourceIdent: hp2m, NUMAZone: 1, Amount: 8 << 20},
+		{ResourceIdent: hp1g, NUMAZone: 0, Amount: 4 << 30},
+	}
+	// the node-wide pages go to the zones having pages, the first zones taking the pages left over
+	require.Equal(t, map[int64]int64{
+		0: 3 * (2 << 20),
+		1: 2*(2<<20) + 8<<20,
+	}, exs.Resolve(2<<20, map[int64]int64{0: 512, 1: 512, 2: 0}))
+	// never more than the pages of the zone
+	require.Equal(t, map[int64]int64{0: 2 << 30}, exs.Resolve(1<<30, map[int64]int64{0: 2, 1: 4}))
+	require.Empty(t, exs.Resolve(1<<30, map[int64]int64{1: 4}))
+	require.Empty(t, Exclusions(nil).Resolve(2<<20, map[int64]int64{0: 512}))
+}
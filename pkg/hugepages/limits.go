@@ -18,7 +18,7 @@ package hugepages
 
 import (
 	"errors"
-	"io/fs"
+	"fmt"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -129,6 +129,24 @@ func SumLimits(lla, llb []Limit) []Limit {
 	return ret
 }
 
+// SubtractLimits removes limits "llb" from the existing "lla", never going below zero.
+// The unset limits of "lla" stay unset, the sizes only in "llb" are ignored.
+func SubtractLimits(lla, llb []Limit) []Limit {
+	var ret []Limit
+	for idxa := range lla {
+		lim := lla[idxa].Clone()
+		for idxb := range llb {
+			if lim.PageSize != llb[idxb].PageSize || lim.Limit.Unset || llb[idxb].Limit.Unset {
+				continue
+			}
+			lim.Limit.Value -= min(lim.Limit.Value, llb[idxb].Limit.Value)
+			break
+		}
+		ret = append(ret, lim)
+	}
+	return ret
+}
+
 func LimitsToString(lls []Limit) string {
 	if len(lls) == 0 {
 		return ""
@@ -141,6 +159,10 @@ func LimitsToString(lls []Limit) string {
 	return strings.TrimPrefix(sb.String(), sep)
 }
 
+// LimitsFromAllocations computes the hugetlb limits of the allocations. All the hugepage sizes of the machine
+// get a limit, zero unless allocated, so the containers can't use the sizes they didn't claim. The allocations
+// count only on the NUMA zones which have pages of their size: on heterogeneous machines some zones may lack
+// the pools of some sizes, and the pages can't come from there anyway.
 func LimitsFromAllocations(lh logr.Logger, machineData sysinfo.MachineData, allocs []types.Allocation) []Limit {
 	var hpLimits []Limit
 
@@ -154,10 +176,20 @@ func LimitsFromAllocations(lh logr.Logger, machineData sysinfo.MachineData, allo
 	}
 	lh.V(2).Info("default hugepage limits", "limits", hpLimits)
 
+	sizesByZone := hugepageSizesByZone(machineData)
 	allocationLimits := map[string]uint64{}
 	for _, alloc := range allocs {
+		if !alloc.NeedsHugeTLB() {
+			continue
+		}
 		pageSize := unitconv.SizeInBytesToCGroupString(alloc.Pagesize)
-		allocationLimits[pageSize] = uint64(alloc.Amount)
+		for numaZone, amount := range alloc.AmountByZone {
+			if sizesByZone != nil && !sizesByZone[numaZone][alloc.Pagesize] {
+				lh.Info("ignoring allocation on NUMA zone lacking the hugepages", "resource", alloc.Name(), "numaZone", numaZone, "amount", amount)
+				continue
+			}
+			allocationLimits[pageSize] += uint64(amount)
+		}
 	}
 	lh.V(2).Info("allocation hugepage limits", "limits", allocationLimits)
 
@@ -173,6 +205,27 @@ func LimitsFromAllocations(lh logr.Logger, machineData sysinfo.MachineData, allo
 	return hpLimits
 }
 
+// hugepageSizesByZone returns the hugepage sizes with pages provisioned on each NUMA zone,
+// or nil if the machine data lacks the zones, in which case every size is assumed valid everywhere.
+func hugepageSizesByZone(machineData sysinfo.MachineData) map[int64]map[uint64]bool {
+	if len(machineData.Zones) == 0 {
+		return nil
+	}
+	sizesByZone := make(map[int64]map[uint64]bool, len(machineData.Zones))
+	for _, zone := range machineData.Zones {
+		sizes := make(map[uint64]bool)
+		if zone.Memory != nil {
+			for hpSize, amounts := range zone.Memory.HugePageAmountsBySize {
+				if amounts != nil && amounts.Total > 0 {
+					sizes[hpSize] = true
+				}
+			}
+		}
+		sizesByZone[int64(zone.ID)] = sizes
+	}
+	return sizesByZone
+}
+
 func LimitsFromSystemPID(lh logr.Logger, machineData sysinfo.MachineData, procRoot string, pid int) ([]Limit, error) {
 	cgPath, err := cgroups.FullPathByPID(procRoot, pid)
 	if err != nil {
@@ -191,7 +244,7 @@ func LimitsFromSystemPath(lh logr.Logger, machineData sysinfo.MachineData, cgPat
 		fileName := "hugetlb." + pageSize + ".max"
 		val, err := cgroups.ParseValue(lh, cgPath, fileName)
 		if err != nil {
-			if errors.Is(err, fs.ErrNotExist) {
+			if errors.Is(err, cgroups.ErrLimitFileMissing) {
 				val = -1
 			} else {
 				lh.V(2).Error(err, "parsing limit", "path", cgPath, "file", fileName)
@@ -212,7 +265,43 @@ func LimitsFromSystemPath(lh logr.Logger, machineData sysinfo.MachineData, cgPat
 	return limits, nil
 }
 
-func SetSystemLimits(lh logr.Logger, cgPath string, limits []Limit) error {
+// ReservationMode controls the reservation limits (hugetlb.<size>.rsvd.max) set along the usage limits.
+type ReservationMode string
+
+const (
+	// ReservationSync sets the reservation limits like the usage limits, so the mappings exceeding
+	// the claims fail at mmap time with ENOMEM. This is the default.
+	ReservationSync ReservationMode = "sync"
+	// ReservationUnlimited leaves the reservation limits unlimited, and enforces only the usage limits.
+	// The applications can reserve more than their claims, e.g. mapping large areas they touch only
+	// in part, and get SIGBUS when faulting the pages exceeding the claims.
+	ReservationUnlimited ReservationMode = "unlimited"
+)
+
+func ParseReservationMode(s string) (ReservationMode, error) {
+	rm := ReservationMode(strings.ToLower(s))
+	switch rm {
+	case ReservationSync, ReservationUnlimited:
+		return rm, nil
+	default:
+		return ReservationSync, fmt.Errorf("unsupported hugetlb reservation mode: %q", s)
+	}
+}
+
+// ReservationValue returns the reservation limit to set along the usage limit `value`; -1 is unlimited.
+func (rm ReservationMode) ReservationValue(value int64) int64 {
+	if rm == ReservationUnlimited {
+		return -1
+	}
+	return value
+}
+
+// ReservationLimitFile is the cgroup file of the reservation limit of the page size, in the cgroup naming (2MB, 1GB).
+func ReservationLimitFile(pageSize string) string {
+	return "hugetlb." + pageSize + ".rsvd.max"
+}
+
+func SetSystemLimits(lh logr.Logger, cgw cgroups.Writer, cgPath string, limits []Limit, rsvd ReservationMode) error {
 	/* doortrap: HugeTLB Cgroup v2 Limits
 	 * When setting hugepage limits in Cgroup v2, we MUST set two distinct values.
 	 * Failing to set the reservation limit is will cause amibguous ENOMEM failures.
@@ -229,17 +318,22 @@ func SetSystemLimits(lh logr.Logger, cgPath string, limits []Limit) error {
 	 * a guarantee (Reservation). If 'rsvd.max' is 0 (default) but 'max' is > 0, the kernel
 	 * allows 0 bytes of reservation. The mmap() call fails immediately with ENOMEM, despite
 	 * the visible usage limit looking correct.
-	 * So: always sync 'rsvd.max' to at least the value of 'max'.
+	 * So: sync 'rsvd.max' to the value of 'max', unless the reservations are explicitly left unlimited.
 	 */
-	attrs := []string{".rsvd.max", ".max"}
 	for _, limit := range limits {
 		value := convertLimitValue(limit.Limit)
-		for _, attr := range attrs {
-			fileName := "hugetlb." + limit.PageSize + attr
-			lh.V(2).Info("setting limit", "cgPath", cgPath, "file", fileName, "value", value)
-			err := cgroups.WriteValue(lh, cgPath, fileName, value)
+		values := []struct {
+			fileName string
+			value    int64
+		}{
+			{fileName: ReservationLimitFile(limit.PageSize), value: rsvd.ReservationValue(value)},
+			{fileName: "hugetlb." + limit.PageSize + ".max", value: value},
+		}
+		for _, val := range values {
+			lh.V(2).Info("setting limit", "cgPath", cgPath, "file", val.fileName, "value", val.value)
+			err := cgw.WriteValue(lh, cgPath, val.fileName, val.value)
 			if err != nil {
-				return err
+				return fmt.Errorf("setting the %s hugetlb limit: %w", limit.PageSize, err)
 			}
 		}
 	}
@@ -21,6 +21,7 @@ package hugepages
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/go-logr/logr/testr"
@@ -120,7 +121,7 @@ func TestSetSystemLimits(t *testing.T) {
 			lh := testr.New(t)
 			tmpDir := t.TempDir()
 
-			err := SetSystemLimits(lh, tmpDir, tcase.limits)
+			err := SetSystemLimits(lh, cgroups.LocalWriter{}, tmpDir, tcase.limits, ReservationSync)
 			require.NoError(t, err)
 
 			// Verify files were created with correct content
@@ -150,3 +151,33 @@ func TestSetSystemLimits(t *testing.T) {
 		})
 	}
 }
+
+func TestSetSystemLimitsReservationMode(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	limits := []Limit{
+		{PageSize: "2MB", Limit: LimitValue{Value: 4 * (1 << 21)}},
+		{PageSize: "1GB", Limit: LimitValue{Value: 0}},
+	}
+	for _, tcase := range []struct {
+		mode         ReservationMode
+		expectedRsvd map[string]string
+	}{
+		{mode: ReservationSync, expectedRsvd: map[string]string{"2MB": "8388608", "1GB": "0"}},
+		{mode: ReservationUnlimited, expectedRsvd: map[string]string{"2MB": "max", "1GB": "max"}},
+	} {
+		t.Run(string(tcase.mode), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			require.NoError(t, SetSystemLimits(testr.New(t), cgroups.LocalWriter{}, tmpDir, limits, tcase.mode))
+			for _, limit := range limits {
+				maxContent, err := os.ReadFile(filepath.Join(tmpDir, "hugetlb."+limit.PageSize+".max"))
+				require.NoError(t, err)
+				require.Equal(t, strconv.FormatUint(limit.Limit.Value, 10), string(maxContent), "the usage limits are always enforced")
+				rsvdContent, err := os.ReadFile(filepath.Join(tmpDir, ReservationLimitFile(limit.PageSize)))
+				require.NoError(t, err)
+				require.Equal(t, tcase.expectedRsvd[limit.PageSize], string(rsvdContent))
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/go-logr/logr/testr"
 	"github.com/google/go-cmp/cmp"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
 
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
@@ -100,6 +101,35 @@ func TestAddLimitValue(t *testing.T) {
 	}
 }
 
+func TestParseReservationMode(t *testing.T) {
+	for val, expected := range map[string]ReservationMode{
+		"sync":      ReservationSync,
+		"Unlimited": ReservationUnlimited,
+	} {
+		got, err := ParseReservationMode(val)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", val, err)
+		}
+		if got != expected {
+			t.Errorf("parsing %q: got %q expected %q", val, got, expected)
+		}
+	}
+	got, err := ParseReservationMode("noreserve")
+	if err == nil {
+		t.Errorf("parsing an unsupported mode must fail")
+	}
+	if got != ReservationSync {
+		t.Errorf("unsupported mode: got %q expected %q", got, ReservationSync)
+	}
+
+	if got := ReservationSync.ReservationValue(1 << 21); got != 1<<21 {
+		t.Errorf("sync reservation value: got %d", got)
+	}
+	if got := ReservationUnlimited.ReservationValue(1 << 21); got != -1 {
+		t.Errorf("unlimited reservation value: got %d", got)
+	}
+}
+
 func TestSumLimits(t *testing.T) {
 	type testcase struct {
 		name     string
@@ -281,6 +311,109 @@ func TestSumLimits(t *testing.T) {
 	}
 }
 
+func TestSubtractLimits(t *testing.T) {
+	type testcase struct {
+		name     string
+		lla      []Limit
+		llb      []Limit
+		expected []Limit
+	}
+
+	testcases := []testcase{
+		{
+			name:     "all empty",
+			expected: nil,
+		},
+		{
+			name: "partial overlap",
+			lla: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 4 * (1 << 21),
+					},
+				},
+				{
+					PageSize: "1GB",
+					Limit: LimitValue{
+						Unset: true,
+					},
+				},
+			},
+			llb: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 1 * (1 << 21),
+					},
+				},
+				{
+					PageSize: "1GB",
+					Limit: LimitValue{
+						Value: 2 * (1 << 30),
+					},
+				},
+				{
+					PageSize: "16GB",
+					Limit: LimitValue{
+						Value: 16 * (1 << 30),
+					},
+				},
+			},
+			expected: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 3 * (1 << 21),
+					},
+				},
+				{
+					PageSize: "1GB",
+					Limit: LimitValue{
+						Unset: true,
+					},
+				},
+			},
+		},
+		{
+			name: "below zero",
+			lla: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 1 * (1 << 21),
+					},
+				},
+			},
+			llb: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 4 * (1 << 21),
+					},
+				},
+			},
+			expected: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 0,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := SubtractLimits(tcase.lla, tcase.llb)
+			if diff := cmp.Diff(got, tcase.expected); diff != "" {
+				t.Errorf("difference is different: %s", diff)
+			}
+		})
+	}
+}
+
 func TestLimitString(t *testing.T) {
 	type testcase struct {
 		name     string
@@ -440,8 +573,8 @@ func TestLimitsFromAllocation(t *testing.T) {
 						Kind:     types.Hugepages,
 						Pagesize: 2 * (1 << 20),
 					},
-					Amount:   128 * 2 * (1 << 20),
-					NUMAZone: 1,
+					Amount:       128 * 2 * (1 << 20),
+					AmountByZone: map[int64]int64{1: 128 * 2 * (1 << 20)},
 				},
 			},
 			expected: []Limit{
@@ -468,8 +601,8 @@ func TestLimitsFromAllocation(t *testing.T) {
 						Kind:     types.Hugepages,
 						Pagesize: (1 << 30),
 					},
-					Amount:   4 * (1 << 30),
-					NUMAZone: 1,
+					Amount:       4 * (1 << 30),
+					AmountByZone: map[int64]int64{1: 4 * (1 << 30)},
 				},
 			},
 			expected: []Limit{
@@ -487,6 +620,42 @@ func TestLimitsFromAllocation(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "multiple hugepages-2m",
+			machineData: machineDataX86,
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{
+						Kind:     types.Hugepages,
+						Pagesize: 2 * (1 << 20),
+					},
+					Amount:       128 * 2 * (1 << 20),
+					AmountByZone: map[int64]int64{0: 128 * 2 * (1 << 20)},
+				},
+				{
+					ResourceIdent: types.ResourceIdent{
+						Kind:     types.Hugepages,
+						Pagesize: 2 * (1 << 20),
+					},
+					Amount:       64 * 2 * (1 << 20),
+					AmountByZone: map[int64]int64{1: 64 * 2 * (1 << 20)},
+				},
+			},
+			expected: []Limit{
+				{
+					PageSize: "2MB",
+					Limit: LimitValue{
+						Value: 192 * 2 * (1 << 20),
+					},
+				},
+				{
+					PageSize: "1GB",
+					Limit: LimitValue{
+						Value: 0,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tcase := range testcases {
@@ -499,3 +668,127 @@ func TestLimitsFromAllocation(t *testing.T) {
 		})
 	}
 }
+
+func TestLimitsFromAllocationHeterogeneousZones(t *testing.T) {
+	// zone 0 has pools of both sizes, zone 1 lacks the 1Gi pool
+	machineData := sysinfo.MachineData{
+		Hugepagesizes: []uint64{
+			(1 << 21),
+			(1 << 30),
+		},
+		Zones: []sysinfo.Zone{
+			{
+				ID: 0,
+				Memory: &ghwmemory.Area{
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						(1 << 21): {Total: 1024},
+						(1 << 30): {Total: 4},
+					},
+				},
+			},
+			{
+				ID: 1,
+				Memory: &ghwmemory.Area{
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						(1 << 21): {Total: 1024},
+						(1 << 30): {Total: 0},
+					},
+				},
+			},
+		},
+	}
+
+	type testcase struct {
+		description string
+		allocs      []types.Allocation
+		expected    []Limit
+	}
+
+	testcases := []testcase{
+		{
+			description: "sizes provisioned on the zone",
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+					Amount:        64 * 2 * (1 << 20),
+					AmountByZone:  map[int64]int64{1: 64 * 2 * (1 << 20)},
+				},
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: (1 << 30)},
+					Amount:        2 * (1 << 30),
+					AmountByZone:  map[int64]int64{0: 2 * (1 << 30)},
+				},
+			},
+			expected: []Limit{
+				{PageSize: "2MB", Limit: LimitValue{Value: 64 * 2 * (1 << 20)}},
+				{PageSize: "1GB", Limit: LimitValue{Value: 2 * (1 << 30)}},
+			},
+		},
+		{
+			description: "size lacking on the zone",
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: (1 << 30)},
+					Amount:        2 * (1 << 30),
+					AmountByZone:  map[int64]int64{1: 2 * (1 << 30)},
+				},
+			},
+			expected: []Limit{
+				{PageSize: "2MB", Limit: LimitValue{Value: 0}},
+				{PageSize: "1GB", Limit: LimitValue{Value: 0}},
+			},
+		},
+		{
+			description: "allocation spanning zones, one lacking the size",
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: (1 << 30)},
+					Amount:        3 * (1 << 30),
+					AmountByZone:  map[int64]int64{0: 2 * (1 << 30), 1: 1 * (1 << 30)},
+				},
+			},
+			expected: []Limit{
+				{PageSize: "2MB", Limit: LimitValue{Value: 0}},
+				{PageSize: "1GB", Limit: LimitValue{Value: 2 * (1 << 30)}},
+			},
+		},
+		{
+			description: "unknown zone",
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)},
+					Amount:        8 * 2 * (1 << 20),
+					AmountByZone:  map[int64]int64{3: 8 * 2 * (1 << 20)},
+				},
+			},
+			expected: []Limit{
+				{PageSize: "2MB", Limit: LimitValue{Value: 0}},
+				{PageSize: "1GB", Limit: LimitValue{Value: 0}},
+			},
+		},
+		{
+			description: "memory allocations are ignored",
+			allocs: []types.Allocation{
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4 * (1 << 10)},
+					Amount:        1 << 30,
+					AmountByZone:  map[int64]int64{0: 1 << 30},
+				},
+			},
+			expected: []Limit{
+				{PageSize: "2MB", Limit: LimitValue{Value: 0}},
+				{PageSize: "1GB", Limit: LimitValue{Value: 0}},
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.description, func(t *testing.T) {
+			logger := testr.New(t)
+			got := LimitsFromAllocations(logger, machineData, tcase.allocs)
+			if diff := cmp.Diff(got, tcase.expected); diff != "" {
+				t.Errorf("limits are different: %s", diff)
+			}
+		})
+	}
+}
@@ -17,17 +17,24 @@
  */
 package v0
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
 
 // ValidateHugePageSize returns the internal (sysfs) hugepage size to use
 // and nil error if is a supported size; otherwise returns empty string
 // and an error detailing the reason
 func ValidateHugePageSize(hps HugePageSize) (string, error) {
-	hpSize := string(hps) // shortcut
-	if hpSize == "1G" || hpSize == "1Gi" || hpSize == "1g" {
+	hpSize, err := unitconv.ParsePageSizeInBytes(string(hps))
+	if err != nil {
+		return "", err
+	}
+	if hpSize == unitconv.GiB {
 		return "1048576kB", nil
 	}
-	if hpSize == "2M" || hpSize == "2Mi" || hpSize == "2m" {
+	if hpSize == 2*unitconv.MiB {
 		return "2048kB", nil
 	}
 	return "", errors.New("unsupported size")
@@ -63,6 +63,21 @@ func TestValidateHugePageSize(t *testing.T) {
 			expectedValue: "2048kB",
 			expectedError: false,
 		},
+		{
+			hps:           "2MiB",
+			expectedValue: "2048kB",
+			expectedError: false,
+		},
+		{
+			hps:           "2048kB",
+			expectedValue: "2048kB",
+			expectedError: false,
+		},
+		{
+			hps:           "1GiB",
+			expectedValue: "1048576kB",
+			expectedError: false,
+		},
 		// negative cases
 		{
 			hps:           "4k",
@@ -72,6 +87,10 @@ func TestValidateHugePageSize(t *testing.T) {
 			hps:           "64k",
 			expectedError: true,
 		},
+		{
+			hps:           "2X",
+			expectedError: true,
+		},
 	}
 
 	for _, tcase := range testcases {
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provision
+
+import (
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// PoolKey identifies the pool of the hugepages of a size on a NUMA node.
+type PoolKey struct {
+	Node     int
+	PageSize uint64 // bytes
+}
+
+// ClaimedPages are the pages of each pool allocated to the claims.
+type ClaimedPages map[PoolKey]int
+
+// ClaimedPagesFromDebug sums the hugepages allocated to the claims reported by the debug API of the driver.
+func ClaimedPagesFromDebug(data debugapi.Claims) ClaimedPages {
+	claimed := make(ClaimedPages)
+	for _, claim := range data.Claims {
+		for _, alloc := range claim.Allocations {
+			ri, err := types.ResourceIdentFromName(alloc.Resource)
+			if err != nil || !ri.NeedsHugeTLB() || ri.Pagesize == 0 {
+				continue // memory, which has no pool
+			}
+			for numaNode, amount := range alloc.BytesByNUMAZone {
+				key := PoolKey{Node: int(numaNode), PageSize: ri.Pagesize}
+				claimed[key] += int((uint64(amount) + ri.Pagesize - 1) / ri.Pagesize)
+			}
+		}
+	}
+	return claimed
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
+)
+
+func TestClaimedPagesFromDebug(t *testing.T) {
+	data := debugapi.Claims{
+		Claims: []debugapi.Claim{
+			{
+				UID: "claim-1",
+				Allocations: []debugapi.Allocation{
+					{Resource: "memory", Bytes: 1 << 30, BytesByNUMAZone: map[int64]int64{0: 1 << 30}},
+					{Resource: "hugepages-2Mi", Bytes: 8 << 20, BytesByNUMAZone: map[int64]int64{0: 4 << 20, 1: 4 << 20}},
+				},
+			},
+			{
+				UID: "claim-2",
+				Allocations: []debugapi.Allocation{
+					{Resource: "hugepages-2Mi", Bytes: 2 << 20, BytesByNUMAZone: map[int64]int64{1: 2 << 20}},
+					{Resource: "hugepages-1Gi", Bytes: 1 << 30, BytesByNUMAZone: map[int64]int64{1: 1 << 30}},
+				},
+			},
+		},
+	}
+	require.Equal(t, ClaimedPages{
+		{Node: 0, PageSize: 2 << 20}: 2,
+		{Node: 1, PageSize: 2 << 20}: 3,
+		{Node: 1, PageSize: 1 << 30}: 1,
+	}, ClaimedPagesFromDebug(data))
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provision
+
+import (
+	"github.com/go-logr/logr"
+
+	apiv0 "github.com/ffromani/dra-driver-memory/pkg/hugepages/provision/api/v0"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// DeprovisionName is the name of the configuration returning the hugepages to the kernel.
+const DeprovisionName = "deprovision"
+
+// Deprovision releases all the hugepages of the given sizes on all the NUMA nodes, like
+// provisioning zero pages, for the maintenance of the node. Like the provisioning, the pools
+// are never shrunk below the pages in use or in `claimed`, reported as ErrConflict.
+func Deprovision(logger logr.Logger, sysRoot string, numaZones int, pageSizes []uint64, claimed ClaimedPages) (Result, error) {
+	hpp := apiv0.HugePageProvision{
+		ObjectMeta: apiv0.ObjectMeta{
+			Name: DeprovisionName,
+		},
+	}
+	for _, pageSize := range pageSizes {
+		hpp.Spec.Pages = append(hpp.Spec.Pages, apiv0.HugePage{
+			Size:  apiv0.HugePageSize(unitconv.SizeInBytesToMinimizedString(pageSize)),
+			Count: 0,
+		})
+	}
+	return RuntimeHugepagesWithResult(logger, hpp, sysRoot, numaZones, claimed)
+}
@@ -0,0 +1,67 @@
+//go:build amd64
+
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package provision
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+func TestDeprovision(t *testing.T) {
+	lh := testr.New(t)
+
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 1024, Free: 1024}, {SizeKB: 1048576, Total: 4, Free: 4}}},
+		{ID: 1, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 1024, Free: 1024}, {SizeKB: 1048576, Total: 4, Free: 4}}},
+	}})
+
+	res, err := Deprovision(lh, tmpDir, 2, []uint64{2 * unitconv.MiB, unitconv.GiB}, nil)
+	require.NoError(t, err)
+	require.Equal(t, DeprovisionName, res.Name)
+	require.Equal(t, []NodeResult{
+		{Node: 0, Size: "2Mi"},
+		{Node: 1, Size: "2Mi"},
+		{Node: 0, Size: "1Gi"},
+		{Node: 1, Size: "1Gi"},
+	}, res.Nodes)
+	for nn := range 2 {
+		require.Equal(t, 0, readPages(t, tmpDir, nn, "hugepages-2048kB"))
+		require.Equal(t, 0, readPages(t, tmpDir, nn, "hugepages-1048576kB"))
+	}
+}
+
+func TestDeprovisionKeepsClaimed(t *testing.T) {
+	lh := testr.New(t)
+
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 1024, Free: 1024}}},
+	}})
+
+	claimed := ClaimedPages{{Node: 0, PageSize: 2 << 20}: 16}
+	res, err := Deprovision(lh, tmpDir, 1, []uint64{2 * unitconv.MiB}, claimed)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Len(t, res.Nodes, 1)
+	require.True(t, res.Nodes[0].Conflict)
+	require.Equal(t, 1024, readPages(t, tmpDir, 0, "hugepages-2048kB"))
+}
@@ -1,17 +1,21 @@
 package provision
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
 
 	"sigs.k8s.io/yaml"
 
 	apiv0 "github.com/ffromani/dra-driver-memory/pkg/hugepages/provision/api/v0"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
 
 func ReadConfiguration(source string) (apiv0.HugePageProvision, error) {
@@ -27,66 +31,196 @@ func ReadConfiguration(source string) (apiv0.HugePageProvision, error) {
 	return ReadConfigurationFrom(src)
 }
 
+// ErrConflict is returned if the provisioning would take away pages in use or claimed.
+var ErrConflict = errors.New("conflict with the pages in use")
+
+// NodeResult is the outcome of the provisioning of the hugepages of a size on a NUMA node.
+type NodeResult struct {
+	Node int                `json:"node"`
+	Size apiv0.HugePageSize `json:"size"`
+	// Requested is the count of pages written on sysfs
+	Requested int `json:"requested"`
+	// Achieved is the count of pages the kernel could allocate, read back from sysfs, which can be lower than requested
+	Achieved int `json:"achieved"`
+	// InUse is the count of pages of the pool not free, as the kernel reports them
+	InUse int `json:"inUse"`
+	// Claimed is the count of pages of the pool allocated to the claims prepared by the driver, faulted or not
+	Claimed int `json:"claimed"`
+	// AlreadySatisfied is set if the pool had already the requested pages, and was left untouched
+	AlreadySatisfied bool `json:"alreadySatisfied,omitempty"`
+	// Conflict is set if the requested pages are fewer than the pages in use or claimed
+	Conflict bool   `json:"conflict,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result is the outcome of the provisioning of a configuration.
+type Result struct {
+	Name  string       `json:"name"`
+	Nodes []NodeResult `json:"nodes"`
+}
+
 func RuntimeHugepages(logger logr.Logger, hpp apiv0.HugePageProvision, sysRoot string, numaZones int) error {
+	_, err := RuntimeHugepagesWithResult(logger, hpp, sysRoot, numaZones, nil)
+	return err
+}
+
+// RuntimeHugepagesWithResult provisions the hugepages like RuntimeHugepages, and reports the outcome on each NUMA node.
+// The provisioning goes on past the failing nodes, to report them all. The pools are never shrunk below the pages
+// in use or in `claimed`: a group of pages conflicting on any NUMA node is refused as whole, not to rebalance it halfway.
+func RuntimeHugepagesWithResult(logger logr.Logger, hpp apiv0.HugePageProvision, sysRoot string, numaZones int, claimed ClaimedPages) (Result, error) {
+	return RuntimeHugepagesThrough(logger, hpp, sysRoot, numaZones, claimed, reserve.LocalPoolWriter{SysRoot: sysRoot})
+}
+
+// RuntimeHugepagesThrough provisions the hugepages like RuntimeHugepagesWithResult, reading the pools on the sysfs tree
+// at `sysRoot` and writing them through `writer`, like the privileged helper of the driver running unprivileged.
+func RuntimeHugepagesThrough(logger logr.Logger, hpp apiv0.HugePageProvision, sysRoot string, numaZones int, claimed ClaimedPages, writer reserve.PoolWriter) (Result, error) {
 	logger.V(2).Info("start provisioning hugepages", "groups", len(hpp.Spec.Pages))
 	defer logger.V(2).Info("done provisioning hugepages", "groups", len(hpp.Spec.Pages))
 
+	res := Result{Name: hpp.Name}
+	var errs []error
 	for _, conf := range hpp.Spec.Pages {
-		var err error
+		var nodeResults []NodeResult
 		// can't be lower on machines >= 2025
 		if numaZones == 1 {
 			numaNode := pickNode(conf)
 			logger.V(0).Info("provisioning pages", "numaNode", numaNode, "count", conf.Count, "size", conf.Size)
-			err = provisionOnNode(logger, numaNode, int(conf.Count), conf.Size, sysRoot)
+			nodeResults = provisionGroup(logger, []NodeResult{{Node: numaNode, Size: conf.Size, Requested: int(conf.Count)}}, sysRoot, claimed, writer)
 		} else {
 			logger.V(0).Info("splitting pages", "count", conf.Count, "NUMACount", numaZones)
-			err = provisionOnMultiNode(logger, numaZones, int(conf.Count), conf.Size, sysRoot)
+			nodeResults = provisionGroup(logger, splitOnNodes(numaZones, int(conf.Count), conf.Size), sysRoot, claimed, writer)
 		}
-		if err != nil {
-			return err
+		for _, nodeRes := range nodeResults {
+			if nodeRes.Conflict {
+				errs = append(errs, fmt.Errorf("node %d size %s: %w: %s", nodeRes.Node, nodeRes.Size, ErrConflict, nodeRes.Error))
+			} else if nodeRes.Error != "" {
+				errs = append(errs, fmt.Errorf("node %d size %s: %s", nodeRes.Node, nodeRes.Size, nodeRes.Error))
+			}
 		}
+		res.Nodes = append(res.Nodes, nodeResults...)
 	}
-	return nil
+	return res, errors.Join(errs...)
 }
 
-func provisionOnMultiNode(logger logr.Logger, numaNodeCount, hpCount int, hpSize apiv0.HugePageSize, sysRoot string) error {
+func splitOnNodes(numaNodeCount, hpCount int, hpSize apiv0.HugePageSize) []NodeResult {
 	extra := hpCount % numaNodeCount
 	perNode := hpCount / numaNodeCount
 
 	// we choose to move excess pages on numa node 0 because this is the most common observed practice
-	err := provisionOnNode(logger, 0, perNode+extra, hpSize, sysRoot)
+	results := []NodeResult{{Node: 0, Size: hpSize, Requested: perNode + extra}}
+	for numaNode := 1; numaNode < numaNodeCount; numaNode++ {
+		results = append(results, NodeResult{Node: numaNode, Size: hpSize, Requested: perNode})
+	}
+	return results
+}
+
+// provisionGroup provisions the pages of the same size planned in `results`, first checking them all against
+// the pages in use, and fills the outcome.
+func provisionGroup(logger logr.Logger, results []NodeResult, sysRoot string, claimed ClaimedPages, writer reserve.PoolWriter) []NodeResult {
+	apiHpSize := results[0].Size
+	// this is done too late, we should have proper validation and API translation but good enough for starters.
+	hpSize, err := apiv0.ValidateHugePageSize(apiHpSize)
+	var pageSize uint64
+	if err == nil {
+		pageSize, err = unitconv.ParsePageSizeInBytes(string(apiHpSize))
+	}
 	if err != nil {
-		return err
+		for idx := range results {
+			results[idx].Error = err.Error()
+		}
+		return results
 	}
-	for numaNode := 1; numaNode < numaNodeCount; numaNode++ {
-		err = provisionOnNode(logger, numaNode, perNode, hpSize, sysRoot)
+
+	conflicts := 0
+	for idx := range results {
+		res := &results[idx]
+		res.Claimed = claimed[PoolKey{Node: res.Node, PageSize: pageSize}]
+		current, free, err := readPool(poolPath(sysRoot, res.Node, hpSize))
 		if err != nil {
-			return err
+			res.Error = err.Error()
+			continue
+		}
+		res.Achieved = current
+		res.InUse = current - free
+		// the claimed pages are free for the kernel until the containers fault them
+		if res.Requested < max(res.InUse, res.Claimed) {
+			logger.V(0).Info("refusing to shrink the pool below the pages in use", "numaNode", res.Node, "size", apiHpSize, "requested", res.Requested, "inUse", res.InUse, "claimed", res.Claimed)
+			res.Conflict = true
+			res.Error = fmt.Sprintf("requested %d pages, but %d are in use and %d are claimed", res.Requested, res.InUse, res.Claimed)
+			conflicts++
 		}
 	}
-	return nil
+	if conflicts > 0 {
+		for idx := range results {
+			if results[idx].Error == "" {
+				results[idx].Error = "not provisioned: the pages conflict on other NUMA nodes"
+			}
+		}
+		return results
+	}
+
+	for idx := range results {
+		res := &results[idx]
+		if res.Error != "" {
+			continue
+		}
+		provisionOnNode(logger, res, poolPath(sysRoot, res.Node, hpSize), pageSize, writer)
+	}
+	return results
 }
 
-func provisionOnNode(logger logr.Logger, numaNode, hpCount int, apiHpSize apiv0.HugePageSize, sysRoot string) error {
-	// this is done too late, we should have proper validation and API translation but good enough for starters.
-	hpSize, err := apiv0.ValidateHugePageSize(apiHpSize)
+func provisionOnNode(logger logr.Logger, res *NodeResult, hpDir string, pageSize uint64, writer reserve.PoolWriter) {
+	hpPath := filepath.Join(hpDir, "nr_hugepages")
+	// rewriting the pool is not free: the kernel may free and reallocate pages, e.g. when the init container restarts
+	if res.Achieved == res.Requested {
+		logger.V(0).Info("pages already satisfied", "path", hpPath, "pages", res.Achieved)
+		res.AlreadySatisfied = true
+		return
+	}
+	logger.V(2).Info("adjusting pages", "path", hpPath, "current", res.Achieved, "delta", res.Requested-res.Achieved)
+	err := writer.SetPoolPages(logger, int64(res.Node), pageSize, int64(res.Requested))
 	if err != nil {
-		return err
+		res.Error = err.Error()
+	} else {
+		logger.V(0).Info("wrote on sysfs", "path", hpPath, "pages", res.Requested)
+	}
+	// the kernel allocates what it can, which may be less than requested if the memory is fragmented
+	achieved, rerr := readNrPages(hpPath)
+	if rerr != nil {
+		if err == nil {
+			res.Error = rerr.Error()
+		}
+		return
+	}
+	res.Achieved = achieved
+	if err == nil && achieved < res.Requested {
+		logger.V(0).Info("allocated fewer pages than requested", "path", hpPath, "requested", res.Requested, "achieved", achieved)
 	}
-	hpPath := filepath.Join(sysRoot, "sys", "devices", "system", "node", fmt.Sprintf("node%d", numaNode), "hugepages", "hugepages-"+hpSize, "nr_hugepages")
-	logger.V(0).Info("writing on sysfs", "path", hpPath)
-	dst, err := os.OpenFile(hpPath, os.O_WRONLY, 0)
+}
+
+func poolPath(sysRoot string, numaNode int, hpSize string) string {
+	return filepath.Join(sysRoot, "sys", "devices", "system", "node", fmt.Sprintf("node%d", numaNode), "hugepages", "hugepages-"+hpSize)
+}
+
+// readPool returns the configured and the free pages of the pool in `hpDir`.
+func readPool(hpDir string) (int, int, error) {
+	current, err := readNrPages(filepath.Join(hpDir, "nr_hugepages"))
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
-	//nolint:errcheck
-	defer dst.Close()
-	_, err = dst.WriteString(strconv.Itoa(hpCount))
+	free, err := readNrPages(filepath.Join(hpDir, "free_hugepages"))
 	if err != nil {
-		return fmt.Errorf("failed to write on %q: %w", hpPath, err)
+		return 0, 0, err
 	}
-	logger.V(0).Info("wrote on sysfs", "path", hpPath, "pages", hpCount)
-	return err
+	return current, free, nil
+}
+
+func readNrPages(hpPath string) (int, error) {
+	data, err := os.ReadFile(hpPath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
 }
 
 func ReadConfigurationFrom(r io.Reader) (apiv0.HugePageProvision, error) {
@@ -19,6 +19,7 @@
 package provision
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -26,10 +27,13 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/require"
 
 	apiv0 "github.com/ffromani/dra-driver-memory/pkg/hugepages/provision/api/v0"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/reserve"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
 )
 
 func TestReadConfiguration(t *testing.T) {
@@ -64,76 +68,190 @@ func TestReadConfigurationFileNotFound(t *testing.T) {
 func TestProvisionBaseSingleNode(t *testing.T) {
 	lh := testr.New(t)
 
-	tmpDir := t.TempDir()
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: makeZones(1)})
 	confPath := filepath.Join(tmpDir, "test-provision-2m.yaml")
 	require.NoError(t, os.WriteFile(confPath, []byte(provision2M), 0600))
 
-	hpPath := filepath.Join(tmpDir, "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-1048576kB")
-	lh.Info("creating test path", "hpPath", hpPath)
-	require.NoError(t, os.MkdirAll(hpPath, 0755))
+	hpConf, err := ReadConfiguration(confPath)
+	require.NoError(t, err)
 
-	hpPath = filepath.Join(tmpDir, "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-2048kB")
-	lh.Info("creating test path", "hpPath", hpPath)
-	require.NoError(t, os.MkdirAll(hpPath, 0755))
+	require.NoError(t, RuntimeHugepages(lh, hpConf, tmpDir, 1))
 
-	require.NoError(t, os.WriteFile(filepath.Join(hpPath, "nr_hugepages"), []byte(""), 0600))
+	require.Equal(t, 0, readPages(t, tmpDir, 0, "hugepages-1048576kB"))
+	require.Equal(t, 4096, readPages(t, tmpDir, 0, "hugepages-2048kB"))
+}
+
+func TestProvisionBaseMultiNode(t *testing.T) {
+	lh := testr.New(t)
+
+	numaZones := 4
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: makeZones(numaZones)})
+	confPath := filepath.Join(tmpDir, "test-provision-2m.yaml")
+	require.NoError(t, os.WriteFile(confPath, []byte(provision2M), 0600))
 
 	hpConf, err := ReadConfiguration(confPath)
 	require.NoError(t, err)
 
-	require.NoError(t, RuntimeHugepages(lh, hpConf, tmpDir, 1))
+	require.NoError(t, RuntimeHugepages(lh, hpConf, tmpDir, numaZones))
+
+	for nn := 0; nn < numaZones; nn++ {
+		require.Equal(t, 0, readPages(t, tmpDir, nn, "hugepages-1048576kB"))
+		require.Equal(t, 1024, readPages(t, tmpDir, nn, "hugepages-2048kB")) // 4096 HPs evenly split on 4 NUMA Zones
+	}
+}
+
+func TestProvisionResultMultiNode(t *testing.T) {
+	lh := testr.New(t)
 
-	hpPath = filepath.Join(tmpDir, "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-1048576kB")
-	dents, err := os.ReadDir(hpPath)
+	numaZones := 2
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: makeZones(numaZones)})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2M))
 	require.NoError(t, err)
-	require.Empty(t, dents)
 
-	data, err := os.ReadFile(filepath.Join(tmpDir, "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-2048kB", "nr_hugepages"))
+	res, err := RuntimeHugepagesWithResult(lh, hpConf, tmpDir, numaZones, nil)
 	require.NoError(t, err)
-	numPages, err := strconv.Atoi(string(data))
+	require.Equal(t, "balanced-runtime", res.Name)
+	require.Equal(t, []NodeResult{
+		{Node: 0, Size: "2M", Requested: 2048, Achieved: 2048},
+		{Node: 1, Size: "2M", Requested: 2048, Achieved: 2048},
+	}, res.Nodes)
+}
+
+func TestProvisionThroughWriter(t *testing.T) {
+	lh := testr.New(t)
+
+	numaZones := 2
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: makeZones(numaZones)})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2M))
+	require.NoError(t, err)
+
+	writer := &recordingPoolWriter{local: reserve.LocalPoolWriter{SysRoot: tmpDir}}
+	res, err := RuntimeHugepagesThrough(lh, hpConf, tmpDir, numaZones, nil, writer)
 	require.NoError(t, err)
+	require.Equal(t, []string{"0/2097152/2048", "1/2097152/2048"}, writer.writes)
+	require.Equal(t, []NodeResult{
+		{Node: 0, Size: "2M", Requested: 2048, Achieved: 2048},
+		{Node: 1, Size: "2M", Requested: 2048, Achieved: 2048},
+	}, res.Nodes)
 
-	require.Equal(t, numPages, 4096)
+	writer.fail = true
+	hpConf.Spec.Pages[0].Count = 8192
+	res, err = RuntimeHugepagesThrough(lh, hpConf, tmpDir, numaZones, nil, writer)
+	require.ErrorContains(t, err, "refused")
+	require.Equal(t, 2048, res.Nodes[0].Achieved, "the pools must be read back after a failed write")
+	require.Equal(t, 2048, readPages(t, tmpDir, 0, "hugepages-2048kB"))
 }
 
-func TestProvisionBaseMultiNode(t *testing.T) {
+func TestProvisionResultReportsFailures(t *testing.T) {
 	lh := testr.New(t)
 
-	tmpDir := t.TempDir()
-	confPath := filepath.Join(tmpDir, "test-provision-2m.yaml")
-	require.NoError(t, os.WriteFile(confPath, []byte(provision2M), 0600))
+	// the second zone lacks the pool, like on machines not supporting the size
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048}}},
+		{ID: 1},
+	}})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2M))
+	require.NoError(t, err)
 
-	numaZones := 4
-	for nn := 0; nn < numaZones; nn++ {
-		hpPath := filepath.Join(tmpDir, "sys", "devices", "system", "node", fmt.Sprintf("node%d", nn), "hugepages", "hugepages-1048576kB")
-		lh.Info("creating test path", "hpPath", hpPath)
-		require.NoError(t, os.MkdirAll(hpPath, 0755))
+	res, err := RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 2, nil)
+	require.Error(t, err)
+	require.Len(t, res.Nodes, 2)
+	require.Equal(t, NodeResult{Node: 0, Size: "2M", Requested: 2048, Achieved: 2048}, res.Nodes[0])
+	require.Equal(t, 1, res.Nodes[1].Node)
+	require.Equal(t, 2048, res.Nodes[1].Requested)
+	require.Zero(t, res.Nodes[1].Achieved)
+	require.NotEmpty(t, res.Nodes[1].Error)
+}
 
-		hpPath = filepath.Join(tmpDir, "sys", "devices", "system", "node", fmt.Sprintf("node%d", nn), "hugepages", "hugepages-2048kB")
-		lh.Info("creating test path", "hpPath", hpPath)
-		require.NoError(t, os.MkdirAll(hpPath, 0755))
+func TestProvisionAlreadySatisfied(t *testing.T) {
+	lh := testr.New(t)
 
-		require.NoError(t, os.WriteFile(filepath.Join(hpPath, "nr_hugepages"), []byte(""), 0600))
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: makeZones(2)})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2M))
+	require.NoError(t, err)
+
+	res, err := RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 2, nil)
+	require.NoError(t, err)
+	for _, nodeRes := range res.Nodes {
+		require.False(t, nodeRes.AlreadySatisfied)
 	}
 
-	hpConf, err := ReadConfiguration(confPath)
+	// like an init container restarting
+	res, err = RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 2, nil)
 	require.NoError(t, err)
+	require.Equal(t, []NodeResult{
+		{Node: 0, Size: "2M", Requested: 2048, Achieved: 2048, InUse: 2048, AlreadySatisfied: true},
+		{Node: 1, Size: "2M", Requested: 2048, Achieved: 2048, InUse: 2048, AlreadySatisfied: true},
+	}, res.Nodes)
+}
 
-	require.NoError(t, RuntimeHugepages(lh, hpConf, tmpDir, numaZones))
+func TestProvisionRefusesShrinkingBelowInUse(t *testing.T) {
+	lh := testr.New(t)
 
-	for nn := 0; nn < numaZones; nn++ {
-		hpPath := filepath.Join(tmpDir, "sys", "devices", "system", "node", fmt.Sprintf("node%d", nn), "hugepages", "hugepages-1048576kB")
-		dents, err := os.ReadDir(hpPath)
-		require.NoError(t, err)
-		require.Empty(t, dents)
+	// 1000 pages in use on node 1, the new split leaves it only 512
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 2048, Free: 2048}}},
+		{ID: 1, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 2048, Free: 1048}}},
+	}})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2MSmall))
+	require.NoError(t, err)
+
+	res, err := RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 2, nil)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Len(t, res.Nodes, 2)
+	require.False(t, res.Nodes[0].Conflict)
+	require.NotEmpty(t, res.Nodes[0].Error)
+	require.True(t, res.Nodes[1].Conflict)
+	require.Equal(t, 1000, res.Nodes[1].InUse)
+	// the whole group is refused, the node without conflicts too
+	require.Equal(t, 2048, readPages(t, tmpDir, 0, "hugepages-2048kB"))
+	require.Equal(t, 2048, readPages(t, tmpDir, 1, "hugepages-2048kB"))
+}
+
+func TestProvisionRefusesShrinkingBelowClaimed(t *testing.T) {
+	lh := testr.New(t)
+
+	tmpDir := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{
+		{ID: 0, Hugepages: []fakesys.Pool{{SizeKB: 2048, Total: 2048, Free: 2048}}},
+	}})
+	hpConf, err := ReadConfigurationFrom(strings.NewReader(provision2MSmall))
+	require.NoError(t, err)
 
-		data, err := os.ReadFile(filepath.Join(tmpDir, "sys", "devices", "system", "node", fmt.Sprintf("node%d", nn), "hugepages", "hugepages-2048kB", "nr_hugepages"))
-		require.NoError(t, err)
-		numPages, err := strconv.Atoi(string(data))
-		require.NoError(t, err)
+	// the claimed pages are still free for the kernel, the container did not fault them yet
+	claimed := ClaimedPages{{Node: 0, PageSize: 2 << 20}: 1536}
+	res, err := RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 1, claimed)
+	require.ErrorIs(t, err, ErrConflict)
+	require.Equal(t, []NodeResult{
+		{Node: 0, Size: "2M", Requested: 1024, Achieved: 2048, Claimed: 1536, Conflict: true, Error: "requested 1024 pages, but 0 are in use and 1536 are claimed"},
+	}, res.Nodes)
+	require.Equal(t, 2048, readPages(t, tmpDir, 0, "hugepages-2048kB"))
 
-		require.Equal(t, numPages, 1024) // 4096 HPs evenly split on 4 NUMA Zones
+	claimed = ClaimedPages{{Node: 0, PageSize: 2 << 20}: 512}
+	res, err = RuntimeHugepagesWithResult(lh, hpConf, tmpDir, 1, claimed)
+	require.NoError(t, err)
+	require.Equal(t, 1024, res.Nodes[0].Achieved)
+	require.Equal(t, 1024, readPages(t, tmpDir, 0, "hugepages-2048kB"))
+}
+
+// makeZones returns `count` zones with empty pools of 2Mi and 1Gi hugepages.
+func makeZones(count int) []fakesys.Zone {
+	zones := make([]fakesys.Zone, 0, count)
+	for nn := range count {
+		zones = append(zones, fakesys.Zone{
+			ID:        nn,
+			Hugepages: []fakesys.Pool{{SizeKB: 2048}, {SizeKB: 1048576}},
+		})
 	}
+	return zones
+}
+
+func readPages(t *testing.T, sysRoot string, numaZone int, hpDir string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(sysRoot, "sys", "devices", "system", "node", fmt.Sprintf("node%d", numaZone), "hugepages", hpDir, "nr_hugepages"))
+	require.NoError(t, err)
+	numPages, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+	return numPages
 }
 
 const provision2M = `kind: HugePageProvision
@@ -143,3 +261,26 @@ spec:
   pages:
   - size: "2M"
     count: 4096`
+
+const provision2MSmall = `kind: HugePageProvision
+metadata:
+  name: small-runtime
+spec:
+  pages:
+  - size: "2M"
+    count: 1024`
+
+// recordingPoolWriter records the writes, and delegates them to the local writer unless failing.
+type recordingPoolWriter struct {
+	local  reserve.LocalPoolWriter
+	fail   bool
+	writes []string
+}
+
+func (rpw *recordingPoolWriter) SetPoolPages(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) error {
+	if rpw.fail {
+		return errors.New("refused")
+	}
+	rpw.writes = append(rpw.writes, fmt.Sprintf("%d/%d/%d", numaZone, pagesize, pages))
+	return rpw.local.SetPoolPages(lh, numaZone, pagesize, pages)
+}
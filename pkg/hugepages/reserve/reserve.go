@@ -0,0 +1,434 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reserve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The published hugepages capacity can't tell if the free pages of a NUMA zone were consumed
+// outside of the claims, or were never there. Without reservation, the pods find out late, when
+// mmap fails with ENOMEM. The Reserver checks the free pages of the zones when claims are prepared,
+// and tries to grow the pools if they fall short. The check is best effort: pages of claims prepared
+// but not yet faulted in by their pods are still reported as free by the kernel. For the same reason,
+// the pools of the other zones are shrunk only by the free pages exceeding the claims allocated there.
+// The adjustments are checkpointed, so the pools are restored when the claims are released also
+// across the restarts of the driver.
+
+type Policy string
+
+const (
+	// PolicyNone doesn't check the free pages.
+	PolicyNone Policy = "none"
+	// PolicyGrow grows the pool of the zone lacking free pages.
+	PolicyGrow Policy = "grow"
+	// PolicyMove grows the pool of the zone lacking free pages, and shrinks by the same amount
+	// the pools of the other zones, keeping the size of the pool of the machine constant.
+	PolicyMove Policy = "move"
+)
+
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case PolicyNone, PolicyGrow, PolicyMove:
+		return Policy(s), nil
+	default:
+		return "", fmt.Errorf("unsupported hugepages reservation policy %q", s)
+	}
+}
+
+// Adjustment is a change of the pool of the hugepages of size `Pagesize` on the NUMA zone.
+type Adjustment struct {
+	NUMAZone int64  `json:"numaZone"`
+	Pagesize uint64 `json:"pagesize"`
+	Pages    int64  `json:"pages"`
+}
+
+// AllocatedBytesFunc returns the bytes allocated to the claims, by resource name (e.g. `hugepages-2Mi`) and by NUMA zone.
+type AllocatedBytesFunc func() map[string]map[int64]int64
+
+// PoolWriter sets the size of the hugepages pools. The driver running unprivileged delegates the writes
+// to the privileged helper.
+type PoolWriter interface {
+	SetPoolPages(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) error
+}
+
+// LocalPoolWriter writes the pools on the sysfs tree rooted at SysRoot from the calling process.
+type LocalPoolWriter struct {
+	SysRoot string
+}
+
+func (lpw LocalPoolWriter) SetPoolPages(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) error {
+	if pages < 0 {
+		return fmt.Errorf("invalid pages count %d", pages)
+	}
+	return writePoolValue(lpw.SysRoot, numaZone, pagesize, "nr_hugepages", pages)
+}
+
+type Reserver struct {
+	policy    Policy
+	sysRoot   string
+	writer    PoolWriter
+	allocated AllocatedBytesFunc
+	// mu serializes the changes to the pools, so claims prepared concurrently see consistent pools.
+	mu                 sync.Mutex
+	adjustmentsByClaim map[k8stypes.UID][]Adjustment
+	statePath          string
+}
+
+// NewReserver creates a new Reserver. The empty policy means PolicyNone. The pools are read from `sysRoot`,
+// and written through `writer`. The free pages of the zones still allocated to claims are read from `allocated`.
+func NewReserver(policy Policy, sysRoot string, writer PoolWriter, allocated AllocatedBytesFunc) *Reserver {
+	if policy == "" {
+		policy = PolicyNone
+	}
+	return &Reserver{
+		policy:             policy,
+		sysRoot:            sysRoot,
+		writer:             writer,
+		allocated:          allocated,
+		adjustmentsByClaim: make(map[k8stypes.UID][]Adjustment),
+	}
+}
+
+// LoadState restores the adjustments checkpointed in `statePath` by a previous run, if any,
+// and checkpoints there the adjustments from now on. The claims released while the driver
+// was down are released again by the kubelet, which restores their pools.
+func (rs *Reserver) LoadState(lh logr.Logger, statePath string) error {
+	if rs == nil || rs.policy == PolicyNone {
+		return nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.statePath = statePath
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	adjustmentsByClaim := make(map[k8stypes.UID][]Adjustment)
+	err = json.Unmarshal(data, &adjustmentsByClaim)
+	if err != nil {
+		return fmt.Errorf("decoding %q: %w", statePath, err)
+	}
+	rs.adjustmentsByClaim = adjustmentsByClaim
+	lh.V(2).Info("restored hugepages reservations", "path", statePath, "claims", len(adjustmentsByClaim))
+	return nil
+}
+
+// Reserve makes sure the zones have enough free hugepages for the allocations of the claim `claimUID`,
+// growing the pools if needed according to the policy. On failure, the pools are restored.
+// Returns true if the pools changed. Reserving again the same claim is a no-op.
+func (rs *Reserver) Reserve(lh logr.Logger, claimUID k8stypes.UID, allocs []types.Allocation) (bool, error) {
+	if rs == nil || rs.policy == PolicyNone {
+		return false, nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if _, ok := rs.adjustmentsByClaim[claimUID]; ok {
+		return false, nil
+	}
+
+	var adjs []Adjustment
+	for _, alloc := range allocs {
+		if !alloc.NeedsHugeTLB() {
+			continue
+		}
+		for _, numaZone := range alloc.NUMAZones() {
+			pages := alloc.AmountByZone[numaZone] / int64(alloc.Pagesize)
+			zoneAdjs, err := rs.reserveOnZone(lh, numaZone, alloc.Pagesize, pages)
+			adjs = append(adjs, zoneAdjs...)
+			if err != nil {
+				return false, errors.Join(err, rs.restore(lh, adjs))
+			}
+		}
+	}
+	rs.adjustmentsByClaim[claimUID] = adjs
+	if len(adjs) > 0 {
+		rs.checkpoint(lh)
+	}
+	return len(adjs) > 0, nil
+}
+
+// Release restores the pools changed to reserve the hugepages of the claim `claimUID`.
+// Pages still in use are returned to the kernel when freed, as surplus pages.
+// Returns true if the pools changed.
+func (rs *Reserver) Release(lh logr.Logger, claimUID k8stypes.UID) (bool, error) {
+	if rs == nil {
+		return false, nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	adjs, ok := rs.adjustmentsByClaim[claimUID]
+	if !ok {
+		return false, nil
+	}
+	delete(rs.adjustmentsByClaim, claimUID)
+	err := rs.restore(lh, adjs)
+	if len(adjs) > 0 {
+		rs.checkpoint(lh)
+	}
+	return len(adjs) > 0, err
+}
+
+// Provision runs `provision`, which sets the pools through the writer it gets, while no claim changes the pools.
+// The pools set become the new baseline: the adjustments of the claims on them are dropped, so the claims
+// released later leave the pools like the admin set them.
+func (rs *Reserver) Provision(lh logr.Logger, provision func(writer PoolWriter) error) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	pw := &provisionWriter{
+		writer: rs.writer,
+		pools:  make(map[poolKey]bool),
+	}
+	err := provision(pw)
+	if rs.rebase(lh, pw.pools) {
+		rs.checkpoint(lh)
+	}
+	return err
+}
+
+// rebase drops the adjustments of the claims on the pools `pools`. Returns true if any was dropped.
+func (rs *Reserver) rebase(lh logr.Logger, pools map[poolKey]bool) bool {
+	var rebased bool
+	for claimUID, adjs := range rs.adjustmentsByClaim {
+		kept := slices.DeleteFunc(slices.Clone(adjs), func(adj Adjustment) bool {
+			return pools[poolKey{numaZone: adj.NUMAZone, pagesize: adj.Pagesize}]
+		})
+		if len(kept) == len(adjs) {
+			continue
+		}
+		lh.V(2).Info("hugepages reservations rebased on the provisioned pools", "claimUID", claimUID, "dropped", len(adjs)-len(kept))
+		rs.adjustmentsByClaim[claimUID] = kept
+		rebased = true
+	}
+	return rebased
+}
+
+type poolKey struct {
+	numaZone int64
+	pagesize uint64
+}
+
+// provisionWriter writes the pools like the writer of the Reserver, tracking the pools written.
+type provisionWriter struct {
+	writer PoolWriter
+	pools  map[poolKey]bool
+}
+
+func (pw *provisionWriter) SetPoolPages(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) error {
+	// a failed write may have changed the pool anyway, e.g. if the kernel allocated only some pages
+	pw.pools[poolKey{numaZone: numaZone, pagesize: pagesize}] = true
+	return pw.writer.SetPoolPages(lh, numaZone, pagesize, pages)
+}
+
+// checkpoint writes the adjustments on the state file, replacing it atomically. A failed checkpoint
+// is not fatal: the pools are still restored on release, unless the driver restarts in the meantime.
+func (rs *Reserver) checkpoint(lh logr.Logger) {
+	if rs.statePath == "" {
+		return
+	}
+	err := writeState(rs.statePath, rs.adjustmentsByClaim)
+	if err != nil {
+		lh.Error(err, "checkpointing hugepages reservations", "path", rs.statePath)
+	}
+}
+
+func (rs *Reserver) reserveOnZone(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) ([]Adjustment, error) {
+	free, err := readPoolValue(rs.sysRoot, numaZone, pagesize, "free_hugepages")
+	if err != nil {
+		return nil, err
+	}
+	if free >= pages {
+		return nil, nil
+	}
+	missing := pages - free
+	lh.V(2).Info("missing free hugepages", "numaZone", numaZone, "pagesize", pagesize, "requested", pages, "free", free)
+
+	grown, err := rs.resizePool(lh, numaZone, pagesize, missing)
+	var adjs []Adjustment
+	if grown != 0 {
+		adjs = append(adjs, Adjustment{NUMAZone: numaZone, Pagesize: pagesize, Pages: grown})
+	}
+	if err != nil {
+		return adjs, err
+	}
+	if grown < missing {
+		return adjs, fmt.Errorf("cannot reserve %d hugepages of size %d on NUMA zone %d: free %d, could grow only by %d", pages, pagesize, numaZone, free, grown)
+	}
+	if rs.policy != PolicyMove {
+		return adjs, nil
+	}
+	shrunk, err := rs.shrinkOtherZones(lh, numaZone, pagesize, grown)
+	adjs = append(adjs, shrunk...)
+	return adjs, err
+}
+
+// shrinkOtherZones releases up to `pages` free hugepages from the zones other than `numaZone`. The free pages
+// allocated to claims are kept, because they are reported free until the pods fault them in.
+// Running short of free pages on the other zones is not an error: the pool of the machine just grows.
+func (rs *Reserver) shrinkOtherZones(lh logr.Logger, numaZone int64, pagesize uint64, pages int64) ([]Adjustment, error) {
+	zones, err := listZones(rs.sysRoot)
+	if err != nil {
+		return nil, err
+	}
+	var allocatedBytes map[int64]int64
+	if rs.allocated != nil {
+		allocatedBytes = rs.allocated()[types.ResourceIdent{Kind: types.Hugepages, Pagesize: pagesize}.Name()]
+	}
+	var adjs []Adjustment
+	for _, otherZone := range zones {
+		if pages == 0 {
+			break
+		}
+		if otherZone == numaZone {
+			continue
+		}
+		free, err := readPoolValue(rs.sysRoot, otherZone, pagesize, "free_hugepages")
+		if err != nil {
+			continue // the zone may lack pages of this size
+		}
+		free -= allocatedBytes[otherZone] / int64(pagesize)
+		if free <= 0 {
+			lh.V(4).Info("no unallocated free hugepages to move", "numaZone", otherZone, "pagesize", pagesize)
+			continue
+		}
+		shrunk, err := rs.resizePool(lh, otherZone, pagesize, -min(free, pages))
+		if shrunk != 0 {
+			adjs = append(adjs, Adjustment{NUMAZone: otherZone, Pagesize: pagesize, Pages: shrunk})
+			pages += shrunk
+		}
+		if err != nil {
+			return adjs, err
+		}
+	}
+	if pages > 0 {
+		lh.V(2).Info("cannot move all the hugepages, the pool grows", "numaZone", numaZone, "pagesize", pagesize, "pages", pages)
+	}
+	return adjs, nil
+}
+
+// resizePool changes the pool by `delta` pages, and returns the actual change. The kernel
+// may fail to allocate all the pages, e.g. if the memory of the zone is fragmented.
+func (rs *Reserver) resizePool(lh logr.Logger, numaZone int64, pagesize uint64, delta int64) (int64, error) {
+	before, err := readPoolValue(rs.sysRoot, numaZone, pagesize, "nr_hugepages")
+	if err != nil {
+		return 0, err
+	}
+	err = rs.writer.SetPoolPages(lh, numaZone, pagesize, max(before+delta, 0))
+	if err != nil {
+		return 0, err
+	}
+	after, err := readPoolValue(rs.sysRoot, numaZone, pagesize, "nr_hugepages")
+	if err != nil {
+		return 0, err
+	}
+	lh.V(2).Info("resized hugepages pool", "numaZone", numaZone, "pagesize", pagesize, "requested", delta, "before", before, "after", after)
+	return after - before, nil
+}
+
+func (rs *Reserver) restore(lh logr.Logger, adjs []Adjustment) error {
+	var errs []error
+	for _, adj := range slices.Backward(adjs) {
+		_, err := rs.resizePool(lh, adj.NUMAZone, adj.Pagesize, -adj.Pages)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func zonePath(sysRoot string, numaZone int64) string {
+	return filepath.Join(sysRoot, "sys", "devices", "system", "node", "node"+strconv.FormatInt(numaZone, 10))
+}
+
+func poolPath(sysRoot string, numaZone int64, pagesize uint64, fileName string) string {
+	return filepath.Join(zonePath(sysRoot, numaZone), "hugepages", "hugepages-"+strconv.FormatUint(pagesize/1024, 10)+"kB", fileName)
+}
+
+func readPoolValue(sysRoot string, numaZone int64, pagesize uint64, fileName string) (int64, error) {
+	data, err := os.ReadFile(poolPath(sysRoot, numaZone, pagesize, fileName))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func writePoolValue(sysRoot string, numaZone int64, pagesize uint64, fileName string, value int64) error {
+	hpPath := poolPath(sysRoot, numaZone, pagesize, fileName)
+	err := os.WriteFile(hpPath, []byte(strconv.FormatInt(value, 10)), 0)
+	if err != nil {
+		return fmt.Errorf("failed to write on %q: %w", hpPath, err)
+	}
+	return nil
+}
+
+func writeState(statePath string, adjustmentsByClaim map[k8stypes.UID][]Adjustment) error {
+	data, err := json.Marshal(adjustmentsByClaim)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(statePath), "."+filepath.Base(statePath)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after the rename
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), statePath)
+}
+
+func listZones(sysRoot string) ([]int64, error) {
+	entries, err := os.ReadDir(filepath.Join(sysRoot, "sys", "devices", "system", "node"))
+	if err != nil {
+		return nil, err
+	}
+	var zones []int64
+	for _, entry := range entries {
+		zoneID, ok := strings.CutPrefix(entry.Name(), "node")
+		if !ok {
+			continue
+		}
+		numaZone, err := strconv.ParseInt(zoneID, 10, 64)
+		if err != nil {
+			continue
+		}
+		zones = append(zones, numaZone)
+	}
+	slices.Sort(zones)
+	return zones, nil
+}
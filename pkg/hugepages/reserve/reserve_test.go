@@ -0,0 +1,235 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reserve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+const pagesize2M = 2 * (1 << 20)
+
+func TestParsePolicy(t *testing.T) {
+	for _, val := range []string{"none", "grow", "move"} {
+		pol, err := ParsePolicy(val)
+		require.NoError(t, err)
+		require.Equal(t, Policy(val), pol)
+	}
+	_, err := ParsePolicy("steal")
+	require.Error(t, err)
+}
+
+func TestReserve(t *testing.T) {
+	type testcase struct {
+		name           string
+		policy         Policy
+		pages          int64
+		allocatedPages map[int64]int64
+		expectedPools  map[int64]int64
+	}
+
+	testcases := []testcase{
+		{
+			name:          "none",
+			policy:        PolicyNone,
+			pages:         8,
+			expectedPools: map[int64]int64{0: 10, 1: 10},
+		},
+		{
+			name:          "enough free pages",
+			policy:        PolicyGrow,
+			pages:         4,
+			expectedPools: map[int64]int64{0: 10, 1: 10},
+		},
+		{
+			name:          "grow",
+			policy:        PolicyGrow,
+			pages:         8,
+			expectedPools: map[int64]int64{0: 14, 1: 10},
+		},
+		{
+			name:          "move",
+			policy:        PolicyMove,
+			pages:         8,
+			expectedPools: map[int64]int64{0: 14, 1: 6},
+		},
+		{
+			name:          "move more than free",
+			policy:        PolicyMove,
+			pages:         20,
+			expectedPools: map[int64]int64{0: 26, 1: 4},
+		},
+		{
+			name:           "move keeps the free pages allocated to claims",
+			policy:         PolicyMove,
+			pages:          8,
+			allocatedPages: map[int64]int64{1: 4},
+			expectedPools:  map[int64]int64{0: 14, 1: 8},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			lh := testr.New(t)
+			sysRoot := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{makeZone(0, 10, 4), makeZone(1, 10, 6)}})
+
+			claimUID := k8stypes.UID("claim-UID")
+			allocated := func() map[string]map[int64]int64 {
+				byZone := make(map[int64]int64)
+				for numaZone, pages := range tcase.allocatedPages {
+					byZone[numaZone] = pages * pagesize2M
+				}
+				return map[string]map[int64]int64{"hugepages-2Mi": byZone}
+			}
+			rs := NewReserver(tcase.policy, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, allocated)
+			allocs := []types.Allocation{
+				makeAllocation(0, tcase.pages),
+				{
+					ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4096},
+					Amount:        1 << 30,
+					AmountByZone:  map[int64]int64{0: 1 << 30},
+				},
+			}
+			_, err := rs.Reserve(lh, claimUID, allocs)
+			require.NoError(t, err)
+			requirePools(t, sysRoot, tcase.expectedPools)
+
+			// reserving again must not change the pools
+			resized, err := rs.Reserve(lh, claimUID, allocs)
+			require.NoError(t, err)
+			require.False(t, resized)
+			requirePools(t, sysRoot, tcase.expectedPools)
+
+			_, err = rs.Release(lh, claimUID)
+			require.NoError(t, err)
+			requirePools(t, sysRoot, map[int64]int64{0: 10, 1: 10})
+			resized, err = rs.Release(lh, claimUID)
+			require.NoError(t, err, "release must be idempotent")
+			require.False(t, resized)
+		})
+	}
+}
+
+func TestReserveFailureRestores(t *testing.T) {
+	lh := testr.New(t)
+	sysRoot := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{makeZone(0, 10, 0), makeZone(1, 10, 0)}})
+	// break the pool of the zone 1
+	nrPath := poolPath(sysRoot, 1, pagesize2M, "nr_hugepages")
+	require.NoError(t, os.Remove(nrPath))
+	require.NoError(t, os.Mkdir(nrPath, 0755))
+
+	alloc := makeAllocation(0, 4)
+	alloc.AmountByZone[1] = 4 * pagesize2M
+	alloc.Amount += 4 * pagesize2M
+
+	rs := NewReserver(PolicyGrow, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	_, err := rs.Reserve(lh, "claim-UID", []types.Allocation{alloc})
+	require.Error(t, err)
+	val, err := readPoolValue(sysRoot, 0, pagesize2M, "nr_hugepages")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), val, "the pool of the zone 0 must be restored")
+}
+
+func TestReleaseAfterRestart(t *testing.T) {
+	lh := testr.New(t)
+	sysRoot := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{makeZone(0, 10, 4), makeZone(1, 10, 6)}})
+	statePath := filepath.Join(t.TempDir(), "reservations.json")
+
+	rs := NewReserver(PolicyMove, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	require.NoError(t, rs.LoadState(lh, statePath), "missing state must be fine")
+	resized, err := rs.Reserve(lh, "claim-UID", []types.Allocation{makeAllocation(0, 8)})
+	require.NoError(t, err)
+	require.True(t, resized)
+	requirePools(t, sysRoot, map[int64]int64{0: 14, 1: 6})
+
+	// the driver restarts, and the kubelet releases the claim afterwards
+	rs = NewReserver(PolicyMove, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	require.NoError(t, rs.LoadState(lh, statePath))
+	resized, err = rs.Release(lh, "claim-UID")
+	require.NoError(t, err)
+	require.True(t, resized)
+	requirePools(t, sysRoot, map[int64]int64{0: 10, 1: 10})
+
+	// the release is checkpointed too
+	rs = NewReserver(PolicyMove, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	require.NoError(t, rs.LoadState(lh, statePath))
+	resized, err = rs.Release(lh, "claim-UID")
+	require.NoError(t, err)
+	require.False(t, resized)
+	requirePools(t, sysRoot, map[int64]int64{0: 10, 1: 10})
+}
+
+func TestReleaseAfterProvision(t *testing.T) {
+	lh := testr.New(t)
+	sysRoot := fakesys.Make(t, fakesys.Spec{Zones: []fakesys.Zone{makeZone(0, 10, 4), makeZone(1, 10, 6)}})
+	statePath := filepath.Join(t.TempDir(), "reservations.json")
+
+	rs := NewReserver(PolicyMove, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	require.NoError(t, rs.LoadState(lh, statePath))
+	_, err := rs.Reserve(lh, "claim-UID", []types.Allocation{makeAllocation(0, 8)})
+	require.NoError(t, err)
+	requirePools(t, sysRoot, map[int64]int64{0: 14, 1: 6})
+
+	// the admin sets the pool of the zone 0 only
+	err = rs.Provision(lh, func(writer PoolWriter) error {
+		return writer.SetPoolPages(lh, 0, pagesize2M, 32)
+	})
+	require.NoError(t, err)
+	requirePools(t, sysRoot, map[int64]int64{0: 32, 1: 6})
+
+	// the release leaves alone the provisioned pool, and restores the others, also after a restart
+	rs = NewReserver(PolicyMove, sysRoot, LocalPoolWriter{SysRoot: sysRoot}, nil)
+	require.NoError(t, rs.LoadState(lh, statePath))
+	resized, err := rs.Release(lh, "claim-UID")
+	require.NoError(t, err)
+	require.True(t, resized)
+	requirePools(t, sysRoot, map[int64]int64{0: 32, 1: 10})
+}
+
+func makeAllocation(numaZone, pages int64) types.Allocation {
+	return types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: pagesize2M},
+		Amount:        pages * pagesize2M,
+		AmountByZone:  map[int64]int64{numaZone: pages * pagesize2M},
+	}
+}
+
+// makeZone returns a zone with only a pool of 2Mi hugepages.
+func makeZone(numaZone int, total, free int64) fakesys.Zone {
+	return fakesys.Zone{
+		ID:        numaZone,
+		Hugepages: []fakesys.Pool{{SizeKB: pagesize2M / 1024, Total: total, Free: free}},
+	}
+}
+
+func requirePools(t *testing.T, sysRoot string, expected map[int64]int64) {
+	t.Helper()
+	for numaZone, pages := range expected {
+		val, err := readPoolValue(sysRoot, numaZone, pagesize2M, "nr_hugepages")
+		require.NoError(t, err)
+		require.Equal(t, pages, val, "numaZone %d", numaZone)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subpools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The hugepages pool of a NUMA zone is published as a single device, so any claim can consume all of it.
+// The operators can split the pool of the zones into named sub-pools, each published as its own device
+// carrying the pool name as attribute, to let critical workloads select a reserved share of the pages.
+// The split is an accounting of the driver: the kernel still has one pool per page size and zone.
+
+const (
+	APIVersion = "dra.memory/v1alpha1"
+	Kind       = "HugepagesPools"
+
+	// DefaultRest is the name of the sub-pool holding the pages not reserved, if the configuration doesn't set it.
+	DefaultRest = "general"
+)
+
+// Config is the content of the sub-pools configuration file.
+type Config struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Pools are the reserved sub-pools, carved in order from the pool of each zone.
+	Pools []Pool `json:"pools"`
+
+	// Rest is the name of the sub-pool holding the pages left. Defaults to DefaultRest.
+	// +optional
+	Rest string `json:"rest,omitempty"`
+}
+
+// Pool is a reserved sub-pool.
+type Pool struct {
+	Name string `json:"name"`
+	// Resource is the hugepages resource to split, like hugepages-2Mi or hugepages-1Gi
+	Resource string `json:"resource"`
+	// Size is reserved on each zone, and must be multiple of the page size
+	Size resource.Quantity `json:"size"`
+	// NUMAZones restricts the pool to the given zones. Empty means all the zones.
+	// +optional
+	NUMAZones []int64 `json:"numaZones,omitempty"`
+}
+
+// Part is a share of the pool of a zone.
+type Part struct {
+	Name   string
+	Amount int64 // bytes
+}
+
+// Load reads the sub-pools configuration from `path`.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	err = yaml.UnmarshalStrict(data, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("malformed hugepages pools %q: %w", path, err)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid hugepages pools %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (cfg *Config) Validate() error {
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		return fmt.Errorf("unsupported %s %s, expected %s %s", cfg.APIVersion, cfg.Kind, APIVersion, Kind)
+	}
+	var errs []error
+	if err := validateName(cfg.RestName()); err != nil {
+		errs = append(errs, fmt.Errorf("rest pool: %w", err))
+	}
+	var names []string
+	for _, pool := range cfg.Pools {
+		if err := validateName(pool.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pool.Name == cfg.RestName() || slices.Contains(names, pool.Name) {
+			errs = append(errs, fmt.Errorf("duplicate pool %q", pool.Name))
+			continue
+		}
+		names = append(names, pool.Name)
+		ri, err := types.ResourceIdentFromName(pool.Resource)
+		if err != nil || ri.Kind != types.Hugepages || ri.Name() != pool.Resource {
+			errs = append(errs, fmt.Errorf("pool %q: unsupported resource %q, expected hugepages-<size> like hugepages-2Mi", pool.Name, pool.Resource))
+			continue
+		}
+		size := pool.Size.Value()
+		if size <= 0 || size%int64(ri.Pagesize) != 0 {
+			errs = append(errs, fmt.Errorf("pool %q: size %s must be positive and multiple of the page size", pool.Name, pool.Size.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateName(name string) error {
+	if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid pool name %q: %s", name, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// RestName returns the name of the sub-pool holding the pages left.
+func (cfg *Config) RestName() string {
+	if cfg.Rest == "" {
+		return DefaultRest
+	}
+	return cfg.Rest
+}
+
+// Split divides the `amount` bytes of the pool of `ri` on the NUMA zone into the sub-pools, in order,
+// and the rest. The sub-pools which don't fit in what is left are skipped, rather than reserving less
+// than configured. Returns nil if the pool of the zone is not split.
+func (cfg *Config) Split(lh logr.Logger, ri types.ResourceIdent, numaZone, amount int64) []Part {
+	if cfg == nil {
+		return nil
+	}
+	var parts []Part
+	left := amount
+	for _, pool := range cfg.Pools {
+		if pool.Resource != ri.Name() {
+			continue
+		}
+		if len(pool.NUMAZones) > 0 && !slices.Contains(pool.NUMAZones, numaZone) {
+			continue
+		}
+		size := pool.Size.Value()
+		if size > left {
+			lh.Info("hugepages pool too small for the sub-pool, skipped", "resource", ri.Name(), "numaZone", numaZone, "pool", pool.Name, "size", size, "available", left)
+			continue
+		}
+		parts = append(parts, Part{Name: pool.Name, Amount: size})
+		left -= size
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	if left > 0 {
+		parts = append(parts, Part{Name: cfg.RestName(), Amount: left})
+	}
+	return parts
+}
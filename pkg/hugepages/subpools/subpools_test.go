@@ -0,0 +1,144 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package subpools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func TestLoad(t *testing.T) {
+	type testcase struct {
+		name        string
+		content     string
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name: "valid",
+			content: `apiVersion: dra.memory/v1alpha1
+kind: HugepagesPools
+pools:
+- name: dpdk
+  resource: hugepages-1Gi
+  size: 8Gi
+  numaZones: [0]
+- name: critical
+  resource: hugepages-2Mi
+  size: 512Mi
+rest: shared
+`,
+		},
+		{
+			name:        "wrong kind",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: AdmissionPolicy\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\nzones: []\n",
+			expectedErr: true,
+		},
+		{
+			name:        "memory resource",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\npools:\n- name: dpdk\n  resource: memory\n  size: 1Gi\n",
+			expectedErr: true,
+		},
+		{
+			name:        "size not multiple of the page size",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\npools:\n- name: dpdk\n  resource: hugepages-1Gi\n  size: 1536Mi\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid name",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\npools:\n- name: DPDK_pool\n  resource: hugepages-2Mi\n  size: 1Gi\n",
+			expectedErr: true,
+		},
+		{
+			name:        "duplicate pool",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\npools:\n- name: dpdk\n  resource: hugepages-2Mi\n  size: 1Gi\n- name: dpdk\n  resource: hugepages-1Gi\n  size: 1Gi\n",
+			expectedErr: true,
+		},
+		{
+			name:        "pool named as the rest",
+			content:     "apiVersion: dra.memory/v1alpha1\nkind: HugepagesPools\npools:\n- name: general\n  resource: hugepages-2Mi\n  size: 1Gi\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pools.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tcase.content), 0o644))
+			_, err := Load(path)
+			if tcase.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	hp2m := types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 << 20}
+	hp1g := types.ResourceIdent{Kind: types.Hugepages, Pagesize: 1 << 30}
+	path := filepath.Join(t.TempDir(), "pools.yaml")
+	content := `apiVersion: dra.memory/v1alpha1
+kind: HugepagesPools
+pools:
+- name: dpdk
+  resource: hugepages-1Gi
+  size: 8Gi
+  numaZones: [0]
+- name: critical
+  resource: hugepages-2Mi
+  size: 512Mi
+- name: large
+  resource: hugepages-2Mi
+  size: 4Gi
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	lh := testr.New(t)
+	require.Equal(t, []Part{
+		{Name: "dpdk", Amount: 8 << 30},
+		{Name: "general", Amount: 8 << 30},
+	}, cfg.Split(lh, hp1g, 0, 16<<30))
+	// all the pages reserved, nothing left
+	require.Equal(t, []Part{{Name: "dpdk", Amount: 8 << 30}}, cfg.Split(lh, hp1g, 0, 8<<30))
+	// not configured on the zone
+	require.Nil(t, cfg.Split(lh, hp1g, 1, 16<<30))
+	// the sub-pools which don't fit are skipped
+	require.Equal(t, []Part{
+		{Name: "critical", Amount: 512 << 20},
+		{Name: "general", Amount: 512 << 20},
+	}, cfg.Split(lh, hp2m, 1, 1<<30))
+	require.Nil(t, cfg.Split(lh, hp2m, 1, 256<<20))
+
+	var none *Config
+	require.Nil(t, none.Split(lh, hp2m, 0, 1<<30))
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hugetlbfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// The driver mounts a hugetlbfs instance for each claim which asks for it, on the host.
+// The mount is sized as the claim, so the kernel enforces the claim size on top of the
+// hugetlb cgroup limits. The mount can also reserve the pages of the claim in the pool,
+// without charging them to any cgroup: the pages are charged to the pods faulting them in.
+// The container runtime bind-mounts the instance into the containers.
+// The base directory must be shared with the host with bidirectional mount propagation.
+
+var (
+	BaseDir = "/var/run/dramemory/hugetlbfs"
+)
+
+// Mounter mounts the hugetlbfs instances of the claims and creates their files, like Mount, CreateFile
+// and UnmountAll. The driver running unprivileged delegates them to the privileged helper.
+type Mounter interface {
+	Mount(lh logr.Logger, claimUID k8stypes.UID, resourceName string, pagesize uint64, sizeInBytes, reservedBytes int64) error
+	CreateFile(lh logr.Logger, claimUID k8stypes.UID, resourceName, fileName string, sizeInBytes int64) error
+	UnmountAll(lh logr.Logger, claimUID k8stypes.UID) error
+}
+
+// LocalMounter mounts the instances below BaseDir from the calling process.
+type LocalMounter struct{}
+
+func (LocalMounter) Mount(lh logr.Logger, claimUID k8stypes.UID, resourceName string, pagesize uint64, sizeInBytes, reservedBytes int64) error {
+	return Mount(lh, MountPath(claimUID, resourceName), pagesize, sizeInBytes, reservedBytes)
+}
+
+func (LocalMounter) CreateFile(lh logr.Logger, claimUID k8stypes.UID, resourceName, fileName string, sizeInBytes int64) error {
+	return CreateFile(lh, filepath.Join(MountPath(claimUID, resourceName), fileName), sizeInBytes)
+}
+
+func (LocalMounter) UnmountAll(lh logr.Logger, claimUID k8stypes.UID) error {
+	return UnmountAll(lh, claimUID)
+}
+
+// MountPath returns the host path of the hugetlbfs instance of the claim `claimUID` for the resource `resourceName`.
+func MountPath(claimUID k8stypes.UID, resourceName string) string {
+	return filepath.Join(BaseDir, string(claimUID), resourceName)
+}
+
+// MountOptions returns the hugetlbfs mount options for pages of size `pagesize` capped at `sizeInBytes`,
+// reserving `reservedBytes` in the pool, if any.
+func MountOptions(pagesize uint64, sizeInBytes, reservedBytes int64) string {
+	opts := "pagesize=" + strconv.FormatUint(pagesize, 10) + ",size=" + strconv.FormatInt(sizeInBytes, 10)
+	if reservedBytes > 0 {
+		opts += ",min_size=" + strconv.FormatInt(reservedBytes, 10)
+	}
+	return opts
+}
+
+// Mount mounts a new hugetlbfs instance on `path`, creating it if needed. Mounting again
+// the same path is not an error: the existing mount is left untouched.
+// The kernel reserves `reservedBytes` of hugepages in the pool for the instance, failing the mount if they are
+// missing, and keeps them reserved until the instance is unmounted. The reservation is charged to no cgroup.
+func Mount(lh logr.Logger, path string, pagesize uint64, sizeInBytes, reservedBytes int64) error {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return fmt.Errorf("creating hugetlbfs mount point %q: %w", path, err)
+	}
+	mounted, err := isHugeTLBFS(path)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		lh.V(2).Info("hugetlbfs already mounted", "path", path)
+		return nil
+	}
+	opts := MountOptions(pagesize, sizeInBytes, reservedBytes)
+	err = unix.Mount("hugetlbfs", path, "hugetlbfs", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, opts)
+	if err != nil {
+		return fmt.Errorf("mounting hugetlbfs on %q with %q: %w", path, opts, err)
+	}
+	lh.V(2).Info("hugetlbfs mounted", "path", path, "options", opts)
+	return nil
+}
+
+// CreateFile creates the file `path` of `sizeInBytes` on a hugetlbfs instance, if missing. Setting the size
+// allocates no page: the pages are allocated, and charged, to the processes faulting them in, so the
+// pages must be reserved by the instance for the mappings not to fail. Creating again the same file is not an error.
+func CreateFile(lh logr.Logger, path string, sizeInBytes int64) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return fmt.Errorf("creating hugepages file %q: %w", path, err)
+	}
+	defer f.Close()
+	err = f.Truncate(sizeInBytes)
+	if err != nil {
+		return fmt.Errorf("sizing hugepages file %q to %d bytes: %w", path, sizeInBytes, err)
+	}
+	lh.V(2).Info("hugepages file created", "path", path, "size", sizeInBytes)
+	return nil
+}
+
+// UnmountAll unmounts all the hugetlbfs instances of the claim `claimUID` and removes the mount points.
+func UnmountAll(lh logr.Logger, claimUID k8stypes.UID) error {
+	claimDir := filepath.Join(BaseDir, string(claimUID))
+	entries, err := os.ReadDir(claimDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // nothing to do
+	}
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(claimDir, entry.Name())
+		err := unix.Unmount(path, unix.MNT_DETACH)
+		if err != nil && !errors.Is(err, unix.EINVAL) { // EINVAL: not mounted
+			errs = append(errs, fmt.Errorf("unmounting %q: %w", path, err))
+			continue
+		}
+		lh.V(2).Info("hugetlbfs unmounted", "path", path)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return os.RemoveAll(claimDir)
+}
+
+func isHugeTLBFS(path string) (bool, error) {
+	var st unix.Statfs_t
+	err := unix.Statfs(path, &st)
+	if err != nil {
+		return false, fmt.Errorf("checking %q: %w", path, err)
+	}
+	return st.Type == unix.HUGETLBFS_MAGIC, nil
+}
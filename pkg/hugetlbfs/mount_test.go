@@ -0,0 +1,63 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hugetlbfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+func TestMountOptions(t *testing.T) {
+	require.Equal(t, "pagesize=2097152,size=67108864", MountOptions(2*1024*1024, 64*1024*1024, 0))
+	require.Equal(t, "pagesize=2097152,size=67108864,min_size=67108864", MountOptions(2*1024*1024, 64*1024*1024, 64*1024*1024))
+}
+
+func TestUnmountAllNotMounted(t *testing.T) {
+	saved := BaseDir
+	BaseDir = t.TempDir()
+	t.Cleanup(func() { BaseDir = saved })
+
+	lh := testr.New(t)
+	claimUID := k8stypes.UID("claim-UID")
+	require.NoError(t, UnmountAll(lh, claimUID), "missing claim")
+
+	path := MountPath(claimUID, "hugepages-2Mi")
+	require.Equal(t, filepath.Join(BaseDir, "claim-UID", "hugepages-2Mi"), path)
+	require.NoError(t, os.MkdirAll(path, 0755))
+	require.NoError(t, UnmountAll(lh, claimUID))
+	_, err := os.Stat(filepath.Join(BaseDir, "claim-UID"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCreateFile(t *testing.T) {
+	// truncate behaves the same on the regular filesystems, bar the alignment to the hugepages
+	lh := testr.New(t)
+	path := filepath.Join(t.TempDir(), "pages")
+	require.NoError(t, CreateFile(lh, path, 4*1024*1024))
+	st, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, int64(4*1024*1024), st.Size())
+	require.NoError(t, CreateFile(lh, path, 4*1024*1024), "creating again")
+
+	require.Error(t, CreateFile(lh, filepath.Join(t.TempDir(), "missing", "pages"), 4*1024*1024))
+}
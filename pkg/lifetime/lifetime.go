@@ -0,0 +1,182 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lifetime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// The claims can set a maximum lifetime or an idle timeout, after which the driver considers them
+// expired. This is meant for batch clusters, where the claims leaked by the workloads strand
+// hugepages for long. The driver never unprepares the expired claims by itself: it reports them,
+// and optionally labels them, so the cleanup is left to the cluster tooling.
+
+const (
+	ReasonMaxLifetimeExceeded = "MaxLifetimeExceeded"
+	ReasonIdleTimeoutExceeded = "IdleTimeoutExceeded"
+)
+
+// ExpiredLabel is set on the expired claims whose policy asks to taint them for cleanup. The value is the reason.
+const ExpiredLabel = "dra.memory/expired"
+
+// Policy tells when a claim expires. Zero values disable the corresponding check.
+type Policy struct {
+	// MaxLifetime is counted from the creation of the claim
+	MaxLifetime time.Duration
+	// IdleTimeout is counted from the last time a container used the claim, or from the preparation if none did
+	IdleTimeout time.Duration
+	// Taint asks to label the claim once expired
+	Taint bool
+}
+
+func (pol Policy) IsEnabled() bool {
+	return pol.MaxLifetime > 0 || pol.IdleTimeout > 0
+}
+
+// Claim identifies a claim.
+type Claim struct {
+	UID       k8stypes.UID
+	Namespace string
+	Name      string
+	CreatedAt time.Time
+}
+
+func (cl Claim) String() string {
+	return cl.Namespace + "/" + cl.Name
+}
+
+// Expiration describes a claim found expired.
+type Expiration struct {
+	Claim   Claim
+	Policy  Policy
+	Reason  string
+	Message string
+}
+
+type item struct {
+	claim    Claim
+	policy   Policy
+	inUse    bool
+	lastUsed time.Time
+	expired  bool
+}
+
+// Tracker keeps the claims with a lifetime policy and their usage.
+type Tracker struct {
+	mu    sync.Mutex
+	items map[k8stypes.UID]*item
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		items: make(map[k8stypes.UID]*item),
+	}
+}
+
+// Register starts tracking a prepared claim. Registering again a claim, e.g. preparing it again, updates its
+// policy but keeps its usage. Claims with a disabled policy are not tracked.
+func (trk *Tracker) Register(claim Claim, policy Policy, now time.Time) {
+	if !policy.IsEnabled() {
+		return
+	}
+	trk.mu.Lock()
+	defer trk.mu.Unlock()
+	if it, ok := trk.items[claim.UID]; ok {
+		it.policy = policy
+		return
+	}
+	trk.items[claim.UID] = &item{
+		claim:    claim,
+		policy:   policy,
+		lastUsed: now,
+	}
+}
+
+func (trk *Tracker) Unregister(claimUID k8stypes.UID) {
+	trk.mu.Lock()
+	defer trk.mu.Unlock()
+	delete(trk.items, claimUID)
+}
+
+// SetInUse records if containers are using the claims. The idle time starts when they stop using them.
+func (trk *Tracker) SetInUse(inUse bool, now time.Time, claimUIDs ...k8stypes.UID) {
+	trk.mu.Lock()
+	defer trk.mu.Unlock()
+	for _, claimUID := range claimUIDs {
+		it, ok := trk.items[claimUID]
+		if !ok {
+			continue
+		}
+		if it.inUse && !inUse {
+			it.lastUsed = now
+		}
+		it.inUse = inUse
+	}
+}
+
+func (trk *Tracker) Len() int {
+	trk.mu.Lock()
+	defer trk.mu.Unlock()
+	return len(trk.items)
+}
+
+// Expire returns the claims expired at `now`. Each claim is returned only once.
+func (trk *Tracker) Expire(now time.Time) []Expiration {
+	trk.mu.Lock()
+	defer trk.mu.Unlock()
+	var exps []Expiration
+	for _, it := range trk.items {
+		if it.expired {
+			continue
+		}
+		exp, ok := it.check(now)
+		if !ok {
+			continue
+		}
+		it.expired = true
+		exps = append(exps, exp)
+	}
+	return exps
+}
+
+func (it *item) check(now time.Time) (Expiration, bool) {
+	if pol := it.policy; pol.MaxLifetime > 0 && !it.claim.CreatedAt.IsZero() {
+		if age := now.Sub(it.claim.CreatedAt); age > pol.MaxLifetime {
+			return Expiration{
+				Claim:   it.claim,
+				Policy:  pol,
+				Reason:  ReasonMaxLifetimeExceeded,
+				Message: fmt.Sprintf("claim age %v exceeds the maximum lifetime %v", age.Round(time.Second), pol.MaxLifetime),
+			}, true
+		}
+	}
+	if pol := it.policy; pol.IdleTimeout > 0 && !it.inUse {
+		if idle := now.Sub(it.lastUsed); idle > pol.IdleTimeout {
+			return Expiration{
+				Claim:   it.claim,
+				Policy:  pol,
+				Reason:  ReasonIdleTimeoutExceeded,
+				Message: fmt.Sprintf("claim unused for %v, exceeds the idle timeout %v", idle.Round(time.Second), pol.IdleTimeout),
+			}, true
+		}
+	}
+	return Expiration{}, false
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lifetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireMaxLifetime(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	trk := NewTracker()
+	claim := Claim{UID: "claim-UID", Namespace: "ns", Name: "claim", CreatedAt: created}
+	trk.Register(claim, Policy{MaxLifetime: time.Hour, Taint: true}, created.Add(time.Minute))
+	trk.SetInUse(true, created.Add(2*time.Minute), "claim-UID")
+
+	require.Empty(t, trk.Expire(created.Add(59*time.Minute)))
+
+	exps := trk.Expire(created.Add(61 * time.Minute))
+	require.Len(t, exps, 1)
+	require.Equal(t, claim, exps[0].Claim)
+	require.Equal(t, ReasonMaxLifetimeExceeded, exps[0].Reason)
+	require.True(t, exps[0].Policy.Taint)
+
+	// reported only once
+	require.Empty(t, trk.Expire(created.Add(2*time.Hour)))
+	require.Equal(t, 1, trk.Len())
+	trk.Unregister("claim-UID")
+	require.Equal(t, 0, trk.Len())
+}
+
+func TestExpireIdleTimeout(t *testing.T) {
+	prepared := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	trk := NewTracker()
+	trk.Register(Claim{UID: "claim-UID"}, Policy{IdleTimeout: 10 * time.Minute}, prepared)
+
+	trk.SetInUse(true, prepared.Add(5*time.Minute), "claim-UID")
+	// busy claims never idle out
+	require.Empty(t, trk.Expire(prepared.Add(time.Hour)))
+
+	trk.SetInUse(false, prepared.Add(time.Hour), "claim-UID")
+	require.Empty(t, trk.Expire(prepared.Add(65*time.Minute)))
+
+	exps := trk.Expire(prepared.Add(71 * time.Minute))
+	require.Len(t, exps, 1)
+	require.Equal(t, ReasonIdleTimeoutExceeded, exps[0].Reason)
+}
+
+func TestExpireNeverUsed(t *testing.T) {
+	prepared := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	trk := NewTracker()
+	trk.Register(Claim{UID: "claim-UID"}, Policy{IdleTimeout: 10 * time.Minute}, prepared)
+	// registering again must not reset the idle time
+	trk.Register(Claim{UID: "claim-UID"}, Policy{IdleTimeout: 10 * time.Minute}, prepared.Add(5*time.Minute))
+
+	exps := trk.Expire(prepared.Add(11 * time.Minute))
+	require.Len(t, exps, 1)
+	require.Equal(t, ReasonIdleTimeoutExceeded, exps[0].Reason)
+}
+
+func TestRegisterDisabled(t *testing.T) {
+	trk := NewTracker()
+	trk.Register(Claim{UID: "claim-UID"}, Policy{Taint: true}, time.Now())
+	require.Equal(t, 0, trk.Len())
+	// unknown claims are ignored
+	trk.SetInUse(true, time.Now(), "claim-UID")
+	require.Empty(t, trk.Expire(time.Now()))
+}
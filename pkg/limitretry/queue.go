@@ -0,0 +1,177 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limitretry
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The driver sets the hugetlb limits of the pod cgroups when it creates the first container consuming the claims.
+// The write can fail for reasons which go away by themselves, like the pod cgroup not created yet, or a busy
+// filesystem. The queue retries the failed writes with exponential backoff, until they succeed, fail permanently,
+// or the pod goes away.
+
+// Op applies the limits of a pod. It must be idempotent: it is called again until it succeeds.
+type Op func(lh logr.Logger) error
+
+// Item is a pod whose limits are pending.
+type Item struct {
+	PodUID string
+	Op     Op
+}
+
+// RetriableFunc tells if the error of an Op is worth a retry.
+type RetriableFunc func(err error) bool
+
+// NotifyFunc is called with the count of the pods pending, each time it changes.
+type NotifyFunc func(pending int)
+
+type entry struct {
+	item     Item
+	backoff  wait.Backoff
+	next     time.Time
+	attempts int
+}
+
+// Queue holds the pods whose limits are pending, and retries them.
+type Queue struct {
+	backoff   wait.Backoff
+	retriable RetriableFunc
+	notify    NotifyFunc
+
+	mu      sync.Mutex
+	entries map[string]*entry // podUID -> entry
+}
+
+// NewQueue creates a queue retrying with `backoff`: the first retry is after its Duration, growing up to its Cap.
+func NewQueue(backoff wait.Backoff, retriable RetriableFunc, notify NotifyFunc) *Queue {
+	return &Queue{
+		backoff:   backoff,
+		retriable: retriable,
+		notify:    notify,
+		entries:   make(map[string]*entry),
+	}
+}
+
+// Add queues a pod whose limits failed to apply. Adding a pod already queued replaces its Op and resets its backoff.
+func (qu *Queue) Add(lh logr.Logger, item Item, now time.Time) {
+	qu.mu.Lock()
+	ent := &entry{
+		item:    item,
+		backoff: qu.backoff,
+	}
+	ent.next = now.Add(ent.backoff.Step())
+	qu.entries[item.PodUID] = ent
+	pending := len(qu.entries)
+	qu.mu.Unlock()
+	lh.V(2).Info("queued pod limits", "podUID", item.PodUID, "retryAt", ent.next)
+	qu.notifyPending(pending)
+}
+
+// Remove forgets a pod, e.g. because it was stopped. Removing a pod not queued is fine.
+func (qu *Queue) Remove(lh logr.Logger, podUID string) {
+	qu.mu.Lock()
+	_, ok := qu.entries[podUID]
+	delete(qu.entries, podUID)
+	pending := len(qu.entries)
+	qu.mu.Unlock()
+	if !ok {
+		return
+	}
+	lh.V(2).Info("dropped pending pod limits", "podUID", podUID)
+	qu.notifyPending(pending)
+}
+
+// Len returns the count of the pods pending.
+func (qu *Queue) Len() int {
+	qu.mu.Lock()
+	defer qu.mu.Unlock()
+	return len(qu.entries)
+}
+
+// Run retries the pods due every `interval`, until the context is done.
+func (qu *Queue) Run(ctx context.Context, lh logr.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			qu.Process(lh, now)
+		}
+	}
+}
+
+// Process retries once the pods due at `now`, and returns the count of the pods still pending.
+func (qu *Queue) Process(lh logr.Logger, now time.Time) int {
+	qu.mu.Lock()
+	var due []*entry
+	for _, podUID := range slices.Sorted(maps.Keys(qu.entries)) {
+		if ent := qu.entries[podUID]; !now.Before(ent.next) {
+			due = append(due, ent)
+		}
+	}
+	qu.mu.Unlock()
+
+	// the Ops write the cgroups, don't block Add and Remove meanwhile
+	done := make(map[*entry]bool, len(due))
+	for _, ent := range due {
+		ent.attempts++
+		podLh := lh.WithValues("podUID", ent.item.PodUID, "attempt", ent.attempts)
+		err := ent.item.Op(podLh)
+		switch {
+		case err == nil:
+			podLh.Info("pod limits applied on retry")
+			done[ent] = true
+		case !qu.retriable(err):
+			podLh.Error(err, "pod limits failed permanently, giving up")
+			done[ent] = true
+		default:
+			ent.next = now.Add(ent.backoff.Step())
+			podLh.V(2).Info("pod limits still failing", "reason", err.Error(), "retryAt", ent.next)
+		}
+	}
+
+	qu.mu.Lock()
+	for ent := range done {
+		// the pod may have been removed, or queued again, while we retried
+		if qu.entries[ent.item.PodUID] == ent {
+			delete(qu.entries, ent.item.PodUID)
+		}
+	}
+	pending := len(qu.entries)
+	qu.mu.Unlock()
+	if len(done) > 0 {
+		qu.notifyPending(pending)
+	}
+	return pending
+}
+
+func (qu *Queue) notifyPending(pending int) {
+	if qu.notify != nil {
+		qu.notify(pending)
+	}
+}
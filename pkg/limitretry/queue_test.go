@@ -0,0 +1,129 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limitretry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+var testBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2.0,
+	Cap:      4 * time.Second,
+	Steps:    10,
+}
+
+func isRetriable(err error) bool {
+	return !errors.Is(err, errPermanent)
+}
+
+// failingOp fails with `errs` in order, then succeeds, and counts the calls.
+func failingOp(calls *int, errs ...error) Op {
+	return func(lh logr.Logger) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestQueueBackoff(t *testing.T) {
+	lh := testr.New(t)
+	var notified []int
+	qu := NewQueue(testBackoff, isRetriable, func(pending int) {
+		notified = append(notified, pending)
+	})
+	start := time.Now()
+	calls := 0
+	qu.Add(lh, Item{PodUID: "pod-A", Op: failingOp(&calls, errTransient, errTransient, errTransient)}, start)
+	require.Equal(t, 1, qu.Len())
+
+	require.Equal(t, 1, qu.Process(lh, start.Add(500*time.Millisecond)), "not due yet")
+	require.Equal(t, 0, calls)
+
+	// retries at +1s, then +2s later, then +4s (the cap) later
+	require.Equal(t, 1, qu.Process(lh, start.Add(1*time.Second)))
+	require.Equal(t, 1, calls)
+	require.Equal(t, 1, qu.Process(lh, start.Add(2*time.Second)), "not due yet")
+	require.Equal(t, 1, calls)
+	require.Equal(t, 1, qu.Process(lh, start.Add(3*time.Second)))
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, qu.Process(lh, start.Add(6*time.Second)), "not due yet")
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, qu.Process(lh, start.Add(7*time.Second)))
+	require.Equal(t, 3, calls)
+	require.Equal(t, 0, qu.Process(lh, start.Add(11*time.Second)))
+	require.Equal(t, 4, calls)
+	require.Equal(t, 0, qu.Len())
+
+	require.Equal(t, []int{1, 0}, notified)
+}
+
+func TestQueuePermanentFailure(t *testing.T) {
+	lh := testr.New(t)
+	qu := NewQueue(testBackoff, isRetriable, nil)
+	start := time.Now()
+	calls := 0
+	qu.Add(lh, Item{PodUID: "pod-A", Op: failingOp(&calls, errTransient, errPermanent, errTransient)}, start)
+	require.Equal(t, 1, qu.Process(lh, start.Add(time.Second)))
+	require.Equal(t, 0, qu.Process(lh, start.Add(3*time.Second)), "gave up")
+	require.Equal(t, 2, calls)
+	require.Equal(t, 0, qu.Process(lh, start.Add(time.Minute)))
+	require.Equal(t, 2, calls)
+}
+
+func TestQueueRemoveAndReplace(t *testing.T) {
+	lh := testr.New(t)
+	var notified []int
+	qu := NewQueue(testBackoff, isRetriable, func(pending int) {
+		notified = append(notified, pending)
+	})
+	start := time.Now()
+	callsA, callsB := 0, 0
+	qu.Add(lh, Item{PodUID: "pod-A", Op: failingOp(&callsA, errTransient)}, start)
+	qu.Add(lh, Item{PodUID: "pod-B", Op: failingOp(&callsB, errTransient)}, start)
+	require.Equal(t, 2, qu.Len())
+
+	qu.Remove(lh, "pod-A")
+	qu.Remove(lh, "pod-missing")
+	require.Equal(t, 1, qu.Process(lh, start.Add(time.Second)))
+	require.Equal(t, 0, callsA, "removed")
+	require.Equal(t, 1, callsB)
+
+	// queued again: the backoff starts over
+	callsB2 := 0
+	qu.Add(lh, Item{PodUID: "pod-B", Op: failingOp(&callsB2)}, start.Add(time.Second))
+	require.Equal(t, 0, qu.Process(lh, start.Add(2*time.Second)))
+	require.Equal(t, 1, callsB, "replaced")
+	require.Equal(t, 1, callsB2)
+
+	require.Equal(t, []int{1, 2, 1, 1, 0}, notified)
+}
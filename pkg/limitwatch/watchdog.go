@@ -0,0 +1,294 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limitwatch
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// The watchdog guards the hugetlb limits of the cgroups of the pods holding claims, and of their ancestors.
+// The kubelet and the runtime can program these limits without knowing the claims, e.g. on pod resize or on
+// restart, down to zero: the containers then fail with ENOMEM at mmap or fault time, even if correctly claimed.
+// The watchdog checks periodically the pod cgroups against the limits the driver programmed, and the ancestors
+// against the sum of the limits of their children. The violations are reported, and optionally repaired.
+
+// Mode controls what the watchdog does.
+type Mode string
+
+const (
+	// ModeNone: don't watch. This is the default.
+	ModeNone Mode = "none"
+	// ModeAlert: report the violations.
+	ModeAlert Mode = "alert"
+	// ModeRepair: raise the limits found too low, and report the repairs.
+	ModeRepair Mode = "repair"
+)
+
+func ParseMode(s string) (Mode, error) {
+	md := Mode(strings.ToLower(s))
+	switch md {
+	case ModeNone, ModeAlert, ModeRepair:
+		return md, nil
+	default:
+		return ModeNone, fmt.Errorf("unsupported limits watchdog mode: %q", s)
+	}
+}
+
+func (md Mode) IsEnabled() bool {
+	return md == ModeAlert || md == ModeRepair
+}
+
+const ReasonHugeTLBLimitTooLow = "HugeTLBClaimLimitTooLow"
+
+// Target is a pod cgroup to guard.
+type Target struct {
+	PodUID     string
+	Namespace  string
+	Name       string
+	CgroupPath string // full path
+	// Limits are the hugetlb limits in bytes the pod cgroup must have, by page size in the cgroup format (e.g. "2MB").
+	// The pod cgroup is checked against them, not against its containers: the limits of the containers can overlap,
+	// because the companion containers share the budget of the claims.
+	Limits map[string]int64
+}
+
+// Violation is a cgroup whose hugetlb limit is lower than the one its pods need.
+type Violation struct {
+	CgroupPath string
+	PageSize   string
+	Limit      int64 // bytes, the value found
+	Expected   int64 // bytes
+	Repaired   bool
+	// Targets are the pods affected, whose cgroups are the cgroup itself or its descendants
+	Targets []Target
+}
+
+func (vi Violation) Message() string {
+	action := "not repaired"
+	if vi.Repaired {
+		action = "repaired"
+	}
+	return fmt.Sprintf("hugetlb %s limit of %s is %s, lower than the %s needed by the claims; %s",
+		vi.PageSize, vi.CgroupPath, unitconv.SizeInBytesToQuantityString(vi.Limit), unitconv.SizeInBytesToQuantityString(vi.Expected), action)
+}
+
+type NotifyFunc func(Violation)
+
+type Watchdog struct {
+	// UnlimitedReservations leaves the reservation limits unlimited when repairing, like the driver does
+	// when programming the limits with the unlimited reservations.
+	UnlimitedReservations bool
+
+	mu       sync.Mutex
+	mode     Mode
+	interval time.Duration
+	// root is the cgroup mount point: the watchdog never checks it, nor anything above
+	root    string
+	cgw     cgroups.Writer
+	notify  NotifyFunc
+	targets map[string]Target // podUID -> target
+	// reported holds the limits of the violations already reported, so they are reported once while they last
+	reported map[string]int64 // cgroupPath:pageSize -> limit
+}
+
+func NewWatchdog(mode Mode, interval time.Duration, root string, cgw cgroups.Writer, notify NotifyFunc) *Watchdog {
+	return &Watchdog{
+		mode:     mode,
+		interval: interval,
+		root:     root,
+		cgw:      cgw,
+		notify:   notify,
+		targets:  make(map[string]Target),
+		reported: make(map[string]int64),
+	}
+}
+
+// Track starts guarding a target, or updates an existing one.
+func (wd *Watchdog) Track(lh logr.Logger, tgt Target) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	wd.targets[tgt.PodUID] = tgt
+	lh.V(4).Info("guarding", "podUID", tgt.PodUID, "path", tgt.CgroupPath, "limits", tgt.Limits)
+}
+
+func (wd *Watchdog) Untrack(lh logr.Logger, podUID string) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	delete(wd.targets, podUID)
+	lh.V(4).Info("unguarding", "podUID", podUID)
+}
+
+func (wd *Watchdog) Len() int {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return len(wd.targets)
+}
+
+// Run scans the targets until the context is done.
+func (wd *Watchdog) Run(ctx context.Context, lh logr.Logger) {
+	ticker := time.NewTicker(wd.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wd.Scan(lh)
+		}
+	}
+}
+
+// Scan checks the targets and their ancestors once, notifies the new violations and returns them.
+// The pod cgroups are checked first, then the ancestors from the deepest, so the repairs propagate
+// up to the root in a single scan.
+func (wd *Watchdog) Scan(lh logr.Logger) []Violation {
+	wd.mu.Lock()
+	targets := slices.SortedFunc(maps.Values(wd.targets), func(a, b Target) int {
+		return strings.Compare(a.PodUID, b.PodUID)
+	})
+	var violations []Violation
+	pageSizes := make(map[string]bool)
+	targetsByAncestor := make(map[string][]Target)
+	for _, tgt := range targets {
+		for _, pageSize := range slices.Sorted(maps.Keys(tgt.Limits)) {
+			pageSizes[pageSize] = true
+			if vi, ok := wd.check(lh, tgt.CgroupPath, pageSize, tgt.Limits[pageSize]); ok {
+				vi.Targets = []Target{tgt}
+				violations = append(violations, vi)
+			}
+		}
+		for dir := filepath.Dir(tgt.CgroupPath); wd.isBelowRoot(dir); dir = filepath.Dir(dir) {
+			targetsByAncestor[dir] = append(targetsByAncestor[dir], tgt)
+		}
+	}
+	ancestors := slices.SortedFunc(maps.Keys(targetsByAncestor), func(a, b string) int {
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return db - da
+		}
+		return strings.Compare(a, b)
+	})
+	for _, dir := range ancestors {
+		for _, pageSize := range slices.Sorted(maps.Keys(pageSizes)) {
+			expected := childrenLimit(lh, dir, pageSize)
+			if vi, ok := wd.check(lh, dir, pageSize, expected); ok {
+				vi.Targets = targetsByAncestor[dir]
+				violations = append(violations, vi)
+			}
+		}
+	}
+	wd.mu.Unlock()
+
+	for _, vi := range violations {
+		lh.Info("hugetlb limit too low", "path", vi.CgroupPath, "pageSize", vi.PageSize, "limit", vi.Limit, "expected", vi.Expected, "repaired", vi.Repaired)
+		if wd.notify != nil {
+			wd.notify(vi)
+		}
+	}
+	return violations
+}
+
+// check returns the violation of the hugetlb limit of the cgroup `cgPath`, if any and not reported yet, repairing it if required.
+// Must be called with the lock held.
+func (wd *Watchdog) check(lh logr.Logger, cgPath, pageSize string, expected int64) (Violation, bool) {
+	key := cgPath + ":" + pageSize
+	limit, err := cgroups.ParseValue(lh, cgPath, limitFile(pageSize))
+	if err != nil {
+		// the cgroups come and go, we will catch up at the next scan
+		lh.V(4).Info("reading hugetlb limit", "path", cgPath, "pageSize", pageSize, "err", err.Error())
+		return Violation{}, false
+	}
+	if limit == -1 || limit >= expected { // -1: max
+		delete(wd.reported, key)
+		return Violation{}, false
+	}
+	vi := Violation{
+		CgroupPath: cgPath,
+		PageSize:   pageSize,
+		Limit:      limit,
+		Expected:   expected,
+	}
+	if wd.mode == ModeRepair {
+		err = wd.setLimit(lh, cgPath, pageSize, expected)
+		if err == nil {
+			delete(wd.reported, key)
+			vi.Repaired = true
+			return vi, true
+		}
+		lh.Error(err, "repairing hugetlb limit", "path", cgPath, "pageSize", pageSize)
+	}
+	if prev, ok := wd.reported[key]; ok && prev == limit {
+		return Violation{}, false
+	}
+	wd.reported[key] = limit
+	return vi, true
+}
+
+func (wd *Watchdog) isBelowRoot(dir string) bool {
+	rel, err := filepath.Rel(wd.root, dir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// childrenLimit returns the sum of the hugetlb limits of the children of the cgroup `cgPath`. The unlimited children are skipped.
+func childrenLimit(lh logr.Logger, cgPath, pageSize string) int64 {
+	entries, err := os.ReadDir(cgPath)
+	if err != nil {
+		lh.V(4).Info("reading cgroup children", "path", cgPath, "err", err.Error())
+		return 0
+	}
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		limit, err := cgroups.ParseValue(lh, filepath.Join(cgPath, entry.Name()), limitFile(pageSize))
+		if err != nil || limit == -1 {
+			continue
+		}
+		total += limit
+	}
+	return total
+}
+
+func limitFile(pageSize string) string {
+	return "hugetlb." + pageSize + ".max"
+}
+
+// setLimit sets both the usage and the reservation limit, like the driver does when programming the limits.
+func (wd *Watchdog) setLimit(lh logr.Logger, cgPath, pageSize string, value int64) error {
+	rsvdValue := value
+	if wd.UnlimitedReservations {
+		rsvdValue = -1
+	}
+	err := wd.cgw.WriteValue(lh, cgPath, "hugetlb."+pageSize+".rsvd.max", rsvdValue)
+	if err != nil {
+		return err
+	}
+	return wd.cgw.WriteValue(lh, cgPath, limitFile(pageSize), value)
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package limitwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, val := range []string{"none", "alert", "Repair"} {
+		_, err := ParseMode(val)
+		require.NoError(t, err, "mode %q", val)
+	}
+	_, err := ParseMode("fix")
+	require.Error(t, err)
+	require.False(t, ModeNone.IsEnabled())
+	require.True(t, ModeAlert.IsEnabled())
+}
+
+// makeTree creates a fake cgroup hierarchy: kubepods, burstable and the pod, with the given 2MB limits.
+func makeTree(t *testing.T, limits map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for dir, limit := range limits {
+		path := filepath.Join(root, dir)
+		require.NoError(t, os.MkdirAll(path, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(path, "hugetlb.2MB.max"), []byte(limit+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(path, "hugetlb.2MB.rsvd.max"), []byte(limit+"\n"), 0644))
+	}
+	return root
+}
+
+func readLimit(t *testing.T, path, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(path, file))
+	require.NoError(t, err)
+	return strings.TrimSpace(string(data))
+}
+
+func TestScanAlert(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	root := makeTree(t, map[string]string{
+		"kubepods":                    "max",
+		"kubepods/burstable":          "max",
+		"kubepods/burstable/pod-a":    "0", // reset by the kubelet
+		"kubepods/burstable/pod-a/c0": "4194304",
+		"kubepods/burstable/pod-a/c1": "4194304", // a sidecar sharing the claim
+	})
+	var notified []Violation
+	wd := NewWatchdog(ModeAlert, time.Second, root, cgroups.LocalWriter{}, func(vi Violation) {
+		notified = append(notified, vi)
+	})
+	tgt := Target{
+		PodUID:     "pod-a",
+		CgroupPath: filepath.Join(root, "kubepods/burstable/pod-a"),
+		Limits:     map[string]int64{"2MB": 4194304},
+	}
+	wd.Track(lh, tgt)
+	require.Equal(t, 1, wd.Len())
+
+	got := wd.Scan(lh)
+	require.Equal(t, []Violation{{
+		CgroupPath: tgt.CgroupPath,
+		PageSize:   "2MB",
+		Limit:      0,
+		Expected:   4194304,
+		Targets:    []Target{tgt},
+	}}, got)
+	require.Equal(t, got, notified)
+	require.Equal(t, "0", readLimit(t, tgt.CgroupPath, "hugetlb.2MB.max"), "alert must not repair")
+
+	require.Empty(t, wd.Scan(lh), "reported once")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tgt.CgroupPath, "hugetlb.2MB.max"), []byte("4194304\n"), 0644))
+	require.Empty(t, wd.Scan(lh), "fixed meanwhile")
+
+	wd.Untrack(lh, "pod-a")
+	require.Equal(t, 0, wd.Len())
+}
+
+func TestScanRepair(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	root := makeTree(t, map[string]string{
+		"kubepods":                 "max",
+		"kubepods/burstable":       "2097152",
+		"kubepods/burstable/pod-a": "0",
+		"kubepods/burstable/pod-b": "4194304",
+		"kubepods/burstable/pod-c": "max",
+	})
+	wd := NewWatchdog(ModeRepair, time.Second, root, cgroups.LocalWriter{}, nil)
+	podA := Target{
+		PodUID:     "pod-a",
+		CgroupPath: filepath.Join(root, "kubepods/burstable/pod-a"),
+		Limits:     map[string]int64{"2MB": 8388608},
+	}
+	wd.Track(lh, podA)
+
+	got := wd.Scan(lh)
+	require.Len(t, got, 2)
+	require.Equal(t, podA.CgroupPath, got[0].CgroupPath)
+	require.True(t, got[0].Repaired)
+	// the parent must fit the repaired pod and its siblings, but the unlimited ones
+	burstable := filepath.Join(root, "kubepods/burstable")
+	require.Equal(t, Violation{
+		CgroupPath: burstable,
+		PageSize:   "2MB",
+		Limit:      2097152,
+		Expected:   12582912,
+		Repaired:   true,
+		Targets:    []Target{podA},
+	}, got[1])
+	require.Equal(t, "8388608", readLimit(t, podA.CgroupPath, "hugetlb.2MB.max"))
+	require.Equal(t, "8388608", readLimit(t, podA.CgroupPath, "hugetlb.2MB.rsvd.max"))
+	require.Equal(t, "12582912", readLimit(t, burstable, "hugetlb.2MB.max"))
+	require.Equal(t, "max", readLimit(t, filepath.Join(root, "kubepods"), "hugetlb.2MB.max"))
+
+	require.Empty(t, wd.Scan(lh))
+}
+
+func TestScanRepairUnlimitedReservations(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	root := makeTree(t, map[string]string{
+		"kubepods":                 "max",
+		"kubepods/burstable":       "max",
+		"kubepods/burstable/pod-a": "0",
+	})
+	wd := NewWatchdog(ModeRepair, time.Second, root, cgroups.LocalWriter{}, nil)
+	wd.UnlimitedReservations = true
+	podA := Target{
+		PodUID:     "pod-a",
+		CgroupPath: filepath.Join(root, "kubepods/burstable/pod-a"),
+		Limits:     map[string]int64{"2MB": 8388608},
+	}
+	wd.Track(lh, podA)
+
+	got := wd.Scan(lh)
+	require.Len(t, got, 1)
+	require.True(t, got[0].Repaired)
+	require.Equal(t, "8388608", readLimit(t, podA.CgroupPath, "hugetlb.2MB.max"))
+	require.Equal(t, "max", readLimit(t, podA.CgroupPath, "hugetlb.2MB.rsvd.max"))
+}
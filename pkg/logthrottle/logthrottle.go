@@ -0,0 +1,282 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logthrottle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// The NRI hooks log the start and the end of every event, and more, for every pod: on busy nodes
+// this floods the journal. The Limiter bounds the messages logged about each pod: at most `burst`
+// messages every `interval`. The messages beyond the budget are dropped, and their count is reported
+// with the first message logged in the next interval. Identical errors about the same pod are logged
+// once per interval, with the count of the repetitions. At DetailLevel or higher verbosity everything
+// is logged, like without the Limiter.
+
+// DetailLevel is the verbosity which disables the throttling.
+const DetailLevel = 6
+
+// The reasons to drop a message.
+const (
+	ReasonRateLimit = "ratelimit"
+	ReasonDuplicate = "duplicate"
+)
+
+// DropFunc is notified of every message dropped, with the reason.
+type DropFunc func(reason string)
+
+// Stats summarizes what the Limiter did.
+type Stats struct {
+	Logged      uint64
+	RateLimited uint64
+	Duplicates  uint64
+}
+
+type Limiter struct {
+	interval time.Duration
+	burst    int
+	onDrop   DropFunc
+	// now is replaceable for the tests
+	now func() time.Time
+
+	mu        sync.Mutex
+	budgets   map[string]*budget
+	lastSweep time.Time
+	stats     Stats
+}
+
+type budget struct {
+	windowStart time.Time
+	lastUsed    time.Time
+	used        int
+	dropped     int
+	errs        map[string]*seenError
+}
+
+type seenError struct {
+	last    time.Time
+	repeats int
+}
+
+// New creates a Limiter allowing `burst` messages every `interval` for each key.
+// Returns nil, which logs everything, if either is not positive.
+func New(interval time.Duration, burst int, onDrop DropFunc) *Limiter {
+	if interval <= 0 || burst <= 0 {
+		return nil
+	}
+	return &Limiter{
+		interval: interval,
+		burst:    burst,
+		onDrop:   onDrop,
+		now:      time.Now,
+		budgets:  make(map[string]*budget),
+	}
+}
+
+// Logger returns a logger throttled within the budget of `key`, usually the pod UID.
+// A nil Limiter, or an empty key, returns `lh` unchanged.
+func (lim *Limiter) Logger(lh logr.Logger, key string) logr.Logger {
+	if lim == nil || key == "" {
+		return lh
+	}
+	sink := lh.GetSink()
+	if sink == nil {
+		return lh
+	}
+	if withDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = withDepth.WithCallDepth(1) // skip our own frame
+	}
+	return lh.WithSink(&throttledSink{lim: lim, key: key, sink: sink})
+}
+
+// Forget releases the budget of `key`, once there is nothing more to log about it.
+func (lim *Limiter) Forget(key string) {
+	if lim == nil {
+		return
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	delete(lim.budgets, key)
+}
+
+// Stats returns the counters since the Limiter was created.
+func (lim *Limiter) Stats() Stats {
+	if lim == nil {
+		return Stats{}
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.stats
+}
+
+// allowInfo consumes the budget of `key`. Returns the count of the messages dropped
+// since the last one logged, to be reported, and false if the message must be dropped.
+func (lim *Limiter) allowInfo(key string) (int, bool) {
+	lim.mu.Lock()
+	now := lim.now()
+	bdg := lim.budgetFor(key, now)
+	if now.Sub(bdg.windowStart) >= lim.interval {
+		bdg.windowStart = now
+		bdg.used = 0
+	}
+	if bdg.used >= lim.burst {
+		bdg.dropped++
+		lim.stats.RateLimited++
+		lim.mu.Unlock()
+		lim.notifyDrop(ReasonRateLimit)
+		return 0, false
+	}
+	bdg.used++
+	dropped := bdg.dropped
+	bdg.dropped = 0
+	lim.stats.Logged++
+	lim.mu.Unlock()
+	return dropped, true
+}
+
+// allowError tells if the error was not logged for `key` in the last interval. Returns the count
+// of the repetitions dropped since it was last logged, and false if the error must be dropped.
+func (lim *Limiter) allowError(key, fingerprint string) (int, bool) {
+	lim.mu.Lock()
+	now := lim.now()
+	bdg := lim.budgetFor(key, now)
+	if bdg.errs == nil {
+		bdg.errs = make(map[string]*seenError)
+	}
+	seen, ok := bdg.errs[fingerprint]
+	if ok && now.Sub(seen.last) < lim.interval {
+		seen.repeats++
+		lim.stats.Duplicates++
+		lim.mu.Unlock()
+		lim.notifyDrop(ReasonDuplicate)
+		return 0, false
+	}
+	repeats := 0
+	if ok {
+		repeats = seen.repeats
+	}
+	bdg.errs[fingerprint] = &seenError{last: now}
+	lim.stats.Logged++
+	lim.mu.Unlock()
+	return repeats, true
+}
+
+// budgetFor must be called with the lock held.
+func (lim *Limiter) budgetFor(key string, now time.Time) *budget {
+	lim.sweep(now)
+	bdg, ok := lim.budgets[key]
+	if !ok {
+		bdg = &budget{windowStart: now}
+		lim.budgets[key] = bdg
+	}
+	bdg.lastUsed = now
+	return bdg
+}
+
+// sweep drops the budgets unused for a while, whose keys were not forgotten, like the pods
+// missed while the plugin was disconnected. Must be called with the lock held.
+func (lim *Limiter) sweep(now time.Time) {
+	if now.Sub(lim.lastSweep) < lim.interval {
+		return
+	}
+	lim.lastSweep = now
+	for key, bdg := range lim.budgets {
+		if now.Sub(bdg.lastUsed) >= 2*lim.interval {
+			delete(lim.budgets, key)
+		}
+	}
+}
+
+func (lim *Limiter) notifyDrop(reason string) {
+	if lim.onDrop == nil {
+		return
+	}
+	lim.onDrop(reason)
+}
+
+type throttledSink struct {
+	lim  *Limiter
+	key  string
+	sink logr.LogSink
+}
+
+var _ logr.CallDepthLogSink = &throttledSink{}
+
+func (ts *throttledSink) Init(info logr.RuntimeInfo) {
+	// the wrapped sink was initialized by its own logger
+}
+
+func (ts *throttledSink) Enabled(level int) bool {
+	return ts.sink.Enabled(level)
+}
+
+func (ts *throttledSink) Info(level int, msg string, keysAndValues ...any) {
+	if ts.detailed() {
+		ts.sink.Info(level, msg, keysAndValues...)
+		return
+	}
+	dropped, ok := ts.lim.allowInfo(ts.key)
+	if !ok {
+		return
+	}
+	if dropped > 0 {
+		keysAndValues = append(keysAndValues, "suppressed", dropped)
+	}
+	ts.sink.Info(level, msg, keysAndValues...)
+}
+
+func (ts *throttledSink) Error(err error, msg string, keysAndValues ...any) {
+	if ts.detailed() {
+		ts.sink.Error(err, msg, keysAndValues...)
+		return
+	}
+	fingerprint := msg
+	if err != nil {
+		fingerprint += "\x00" + err.Error()
+	}
+	repeats, ok := ts.lim.allowError(ts.key, fingerprint)
+	if !ok {
+		return
+	}
+	if repeats > 0 {
+		keysAndValues = append(keysAndValues, "repeated", repeats)
+	}
+	ts.sink.Error(err, msg, keysAndValues...)
+}
+
+func (ts *throttledSink) WithValues(keysAndValues ...any) logr.LogSink {
+	return &throttledSink{lim: ts.lim, key: ts.key, sink: ts.sink.WithValues(keysAndValues...)}
+}
+
+func (ts *throttledSink) WithName(name string) logr.LogSink {
+	return &throttledSink{lim: ts.lim, key: ts.key, sink: ts.sink.WithName(name)}
+}
+
+func (ts *throttledSink) WithCallDepth(depth int) logr.LogSink {
+	withDepth, ok := ts.sink.(logr.CallDepthLogSink)
+	if !ok {
+		return ts
+	}
+	return &throttledSink{lim: ts.lim, key: ts.key, sink: withDepth.WithCallDepth(depth)}
+}
+
+func (ts *throttledSink) detailed() bool {
+	return ts.sink.Enabled(DetailLevel)
+}
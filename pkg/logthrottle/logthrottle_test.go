@@ -0,0 +1,165 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logthrottle
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.now = fc.now.Add(d)
+}
+
+func newRecorder(verbosity int) (logr.Logger, *[]string) {
+	var lines []string
+	lh := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: verbosity})
+	return lh, &lines
+}
+
+func newTestLimiter(interval time.Duration, burst int) (*Limiter, *fakeClock, map[string]int) {
+	drops := make(map[string]int)
+	lim := New(interval, burst, func(reason string) {
+		drops[reason]++
+	})
+	clk := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	lim.now = clk.Now
+	return lim, clk, drops
+}
+
+func TestNewDisabled(t *testing.T) {
+	require.Nil(t, New(0, 10, nil))
+	require.Nil(t, New(time.Minute, 0, nil))
+
+	var lim *Limiter
+	lh, lines := newRecorder(0)
+	tlh := lim.Logger(lh, "pod-a")
+	for range 10 {
+		tlh.Info("start")
+	}
+	require.Len(t, *lines, 10)
+	require.Equal(t, Stats{}, lim.Stats())
+}
+
+func TestRateLimitPerKey(t *testing.T) {
+	lim, clk, drops := newTestLimiter(time.Minute, 3)
+	lh, lines := newRecorder(4)
+
+	podA := lim.Logger(lh, "pod-a").WithName("CreateContainer")
+	podB := lim.Logger(lh, "pod-b").WithName("CreateContainer")
+	for range 5 {
+		podA.V(4).Info("start")
+	}
+	podB.V(4).Info("start")
+	require.Len(t, *lines, 4, "lines=%v", *lines)
+	require.Equal(t, 2, drops[ReasonRateLimit])
+
+	clk.Advance(time.Minute)
+	podA.V(4).Info("done")
+	require.Len(t, *lines, 5)
+	require.Contains(t, (*lines)[4], `"suppressed"=2`)
+
+	require.Equal(t, Stats{Logged: 5, RateLimited: 2}, lim.Stats())
+}
+
+func TestRateLimitSkipsDisabledLevels(t *testing.T) {
+	lim, _, drops := newTestLimiter(time.Minute, 2)
+	lh, lines := newRecorder(2)
+
+	podA := lim.Logger(lh, "pod-a")
+	for range 10 {
+		podA.V(4).Info("start")
+	}
+	podA.Info("pinned")
+	podA.Info("limits set")
+	require.Len(t, *lines, 2)
+	require.Empty(t, drops)
+}
+
+func TestDuplicateErrors(t *testing.T) {
+	lim, clk, drops := newTestLimiter(time.Minute, 1)
+	lh, lines := newRecorder(0)
+
+	podA := lim.Logger(lh, "pod-a")
+	errA := errors.New("cannot write the limit")
+	for range 4 {
+		podA.Error(errA, "cannot set the container soft limit")
+	}
+	podA.Error(errors.New("another failure"), "cannot set the container soft limit")
+	lim.Logger(lh, "pod-b").Error(errA, "cannot set the container soft limit")
+	require.Len(t, *lines, 3, "lines=%v", *lines)
+	require.Equal(t, 3, drops[ReasonDuplicate])
+
+	clk.Advance(time.Minute)
+	podA.Error(errA, "cannot set the container soft limit")
+	require.Len(t, *lines, 4)
+	require.Contains(t, (*lines)[3], `"repeated"=3`)
+
+	require.Equal(t, Stats{Logged: 4, Duplicates: 3}, lim.Stats())
+}
+
+func TestDetailLevelNotThrottled(t *testing.T) {
+	lim, _, drops := newTestLimiter(time.Minute, 1)
+	lh, lines := newRecorder(DetailLevel)
+
+	podA := lim.Logger(lh, "pod-a")
+	errA := errors.New("cannot write the limit")
+	for range 5 {
+		podA.V(4).Info("start")
+		podA.Error(errA, "cannot set the container soft limit")
+	}
+	require.Len(t, *lines, 10)
+	require.Empty(t, drops)
+}
+
+func TestForgetAndSweep(t *testing.T) {
+	lim, clk, _ := newTestLimiter(time.Minute, 1)
+	lh, lines := newRecorder(0)
+
+	lim.Logger(lh, "pod-a").Info("start")
+	lim.Logger(lh, "pod-a").Info("done")
+	require.Len(t, *lines, 1)
+
+	lim.Forget("pod-a")
+	lim.Logger(lh, "pod-a").Info("start")
+	require.Len(t, *lines, 2)
+	require.NotContains(t, (*lines)[1], "suppressed")
+
+	lim.Logger(lh, "pod-b").Info("start")
+	clk.Advance(3 * time.Minute)
+	lim.Logger(lh, "pod-c").Info("start")
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	require.NotContains(t, lim.budgets, "pod-a")
+	require.NotContains(t, lim.budgets, "pod-b")
+	require.Contains(t, lim.budgets, "pod-c")
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nodelabels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// The node labels mirror a few discovery facts, so the admins can use plain nodeSelectors
+// alongside DRA while the ecosystem transitions. The labels are coarse by design: the
+// ResourceSlices remain the source of truth.
+
+// Mode controls how the facts are published.
+type Mode string
+
+const (
+	// ModeNone: don't publish the facts. This is the default.
+	ModeNone Mode = "none"
+	// ModeLabels: label the node object directly.
+	ModeLabels Mode = "labels"
+	// ModeNFD: write a local feature file for node-feature-discovery, which will label the node.
+	ModeNFD Mode = "nfd"
+)
+
+func ParseMode(s string) (Mode, error) {
+	md := Mode(strings.ToLower(s))
+	switch md {
+	case ModeNone, ModeLabels, ModeNFD:
+		return md, nil
+	default:
+		return ModeNone, fmt.Errorf("unsupported node labels mode: %q", s)
+	}
+}
+
+const (
+	Prefix = "dra.memory/"
+	// LabelNUMANodes is the count of the NUMA nodes with memory
+	LabelNUMANodes = Prefix + "numa-nodes"
+	// LabelCXL is set to "true" if any CXL device is present
+	LabelCXL = Prefix + "cxl"
+	// LabelDefaultHugepagesSize is the default hugepage size of the node (e.g. `2Mi`)
+	LabelDefaultHugepagesSize = Prefix + "default-hugepages-size"
+	// labelHugepagesPrefix is followed by the page size (e.g. `hugepages-2Mi`) and set to "true" if pages of that size are provisioned
+	labelHugepagesPrefix = Prefix + "hugepages-"
+	// labelMaxPoolSuffix is appended to the hugepages label and set to the largest count of pages provisioned on a single NUMA node
+	labelMaxPoolSuffix = ".max-pool-pages"
+)
+
+// DefaultNFDFeaturesDir is the default directory node-feature-discovery reads the local features from.
+const DefaultNFDFeaturesDir = "/etc/kubernetes/node-feature-discovery/features.d"
+
+// nfdFeatureFile is the name of the feature file the driver owns
+const nfdFeatureFile = "dra-memory"
+
+type Config struct {
+	Mode Mode
+	// NFDFeaturesDir is the directory to write the feature file into. Used only in the NFD mode.
+	NFDFeaturesDir string
+}
+
+func (cfg Config) IsEnabled() bool {
+	return cfg.Mode == ModeLabels || cfg.Mode == ModeNFD
+}
+
+type Facts struct {
+	NUMANodes int
+	CXL       bool
+	// HugepagesMaxPoolPages holds, for each provisioned hugepage size in bytes,
+	// the largest count of pages provisioned on a single NUMA node.
+	HugepagesMaxPoolPages map[uint64]int64
+	// DefaultHugepagesSize is the default hugepage size in bytes, zero if unknown
+	DefaultHugepagesSize uint64
+}
+
+func FactsFromMachine(machine sysinfo.MachineData, cxl bool) Facts {
+	facts := Facts{
+		CXL:                   cxl,
+		HugepagesMaxPoolPages: make(map[uint64]int64),
+	}
+	for _, zone := range machine.Zones {
+		if zone.Memory == nil {
+			continue
+		}
+		facts.NUMANodes++
+		if facts.DefaultHugepagesSize == 0 {
+			// system-wide, all the zones report the same
+			facts.DefaultHugepagesSize = zone.Memory.DefaultHugePageSize
+		}
+		for hpSize, amounts := range zone.Memory.HugePageAmountsBySize {
+			if amounts == nil || amounts.Total == 0 {
+				continue
+			}
+			facts.HugepagesMaxPoolPages[hpSize] = max(facts.HugepagesMaxPoolPages[hpSize], amounts.Total)
+		}
+	}
+	return facts
+}
+
+func (facts Facts) Labels() map[string]string {
+	labels := map[string]string{
+		LabelNUMANodes: strconv.Itoa(facts.NUMANodes),
+	}
+	if facts.CXL {
+		labels[LabelCXL] = "true"
+	}
+	if facts.DefaultHugepagesSize > 0 {
+		labels[LabelDefaultHugepagesSize] = unitconv.SizeInBytesToMinimizedString(facts.DefaultHugepagesSize)
+	}
+	for hpSize, pages := range facts.HugepagesMaxPoolPages {
+		key := labelHugepagesPrefix + unitconv.SizeInBytesToMinimizedString(hpSize)
+		labels[key] = "true"
+		labels[key+labelMaxPoolSuffix] = strconv.FormatInt(pages, 10)
+	}
+	return labels
+}
+
+// Publish publishes the labels according to the configuration.
+func Publish(ctx context.Context, lh logr.Logger, cfg Config, cli kubernetes.Interface, nodeName string, labels map[string]string) error {
+	switch cfg.Mode {
+	case ModeLabels:
+		return LabelNode(ctx, lh, cli, nodeName, labels)
+	case ModeNFD:
+		return WriteFeatureFile(lh, cfg.NFDFeaturesDir, labels)
+	default:
+		return nil
+	}
+}
+
+// LabelNode sets the labels on the node, removing the stale labels previously set by the driver.
+func LabelNode(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, nodeName string, labels map[string]string) error {
+	node, err := cli.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	patchLabels := make(map[string]any)
+	for key := range node.Labels {
+		if _, ok := labels[key]; !ok && strings.HasPrefix(key, Prefix) {
+			patchLabels[key] = nil // stale, remove
+		}
+	}
+	for key, val := range labels {
+		if node.Labels[key] != val {
+			patchLabels[key] = val
+		}
+	}
+	if len(patchLabels) == 0 {
+		lh.V(4).Info("node labels up to date", "node", nodeName)
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels": patchLabels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cli.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("node labels updated", "node", nodeName, "labels", patchLabels)
+	return nil
+}
+
+// WriteFeatureFile writes the labels in the node-feature-discovery local feature file format.
+// The file is replaced atomically, so NFD never reads a partial file.
+func WriteFeatureFile(lh logr.Logger, featuresDir string, labels map[string]string) error {
+	var sb strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		fmt.Fprintf(&sb, "%s=%s\n", key, labels[key])
+	}
+	tmp, err := os.CreateTemp(featuresDir, "."+nfdFeatureFile+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after the rename
+	_, err = tmp.WriteString(sb.String())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	featurePath := filepath.Join(featuresDir, nfdFeatureFile)
+	err = os.Rename(tmp.Name(), featurePath)
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("feature file updated", "path", featurePath, "labels", len(labels))
+	return nil
+}
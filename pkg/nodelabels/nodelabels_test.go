@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nodelabels
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+)
+
+func TestFactsLabels(t *testing.T) {
+	machine := sysinfo.MachineData{
+		Pagesize: 4096,
+		Zones: []sysinfo.Zone{
+			{
+				ID: 0,
+				Memory: &ghwmemory.Area{
+					DefaultHugePageSize: 2 * 1024 * 1024,
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * 1024 * 1024:    {Total: 512},
+						1024 * 1024 * 1024: {Total: 0},
+					},
+				},
+			},
+			{
+				ID: 1,
+				Memory: &ghwmemory.Area{
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * 1024 * 1024:    {Total: 128},
+						1024 * 1024 * 1024: {Total: 4},
+					},
+				},
+			},
+		},
+	}
+
+	got := FactsFromMachine(machine, true).Labels()
+	require.Equal(t, map[string]string{
+		"dra.memory/numa-nodes":                   "2",
+		"dra.memory/cxl":                          "true",
+		"dra.memory/default-hugepages-size":       "2Mi",
+		"dra.memory/hugepages-2Mi":                "true",
+		"dra.memory/hugepages-2Mi.max-pool-pages": "512",
+		"dra.memory/hugepages-1Gi":                "true",
+		"dra.memory/hugepages-1Gi.max-pool-pages": "4",
+	}, got)
+}
+
+func TestParseMode(t *testing.T) {
+	md, err := ParseMode("NFD")
+	require.NoError(t, err)
+	require.Equal(t, ModeNFD, md)
+	_, err = ParseMode("annotations")
+	require.Error(t, err)
+}
+
+func TestWriteFeatureFile(t *testing.T) {
+	lh := testr.New(t)
+	dir := t.TempDir()
+	labels := map[string]string{
+		LabelNUMANodes: "2",
+		LabelCXL:       "true",
+	}
+	require.NoError(t, WriteFeatureFile(lh, dir, labels))
+	data, err := os.ReadFile(filepath.Join(dir, nfdFeatureFile))
+	require.NoError(t, err)
+	require.Equal(t, "dra.memory/cxl=true\ndra.memory/numa-nodes=2\n", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "leftover temporary files")
+}
+
+func TestLabelNode(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+	cli := fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-0",
+			Labels: map[string]string{
+				"kubernetes.io/hostname":   "node-0",
+				LabelCXL:                   "true",
+				"dra.memory/hugepages-1Gi": "true",
+			},
+		},
+	})
+
+	require.NoError(t, LabelNode(ctx, lh, cli, "node-0", map[string]string{
+		LabelNUMANodes:             "2",
+		"dra.memory/hugepages-1Gi": "true",
+	}))
+
+	node, err := cli.CoreV1().Nodes().Get(ctx, "node-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"kubernetes.io/hostname":   "node-0",
+		LabelNUMANodes:             "2",
+		"dra.memory/hugepages-1Gi": "true",
+	}, node.Labels)
+}
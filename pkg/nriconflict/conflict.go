@@ -0,0 +1,166 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nriconflict
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/cpuset"
+)
+
+// Other NRI plugins, like CPU or topology managers, can adjust the same settings of the containers the driver pins:
+// the memory nodes (cpuset.mems) and the hugetlb limits. The runtime merges the adjustments of the plugins, so
+// the containers can run with settings different from the claims, and nobody notices until the memory is
+// misplaced or mmap fails. The driver can check the merged adjustments before the containers are created,
+// if the runtime asks the plugins to validate them, and the settings of the running containers when it
+// synchronizes with the runtime.
+
+// Mode controls what the driver does on the conflicts.
+type Mode string
+
+const (
+	// ModeNone: don't check. This is the default.
+	ModeNone Mode = "none"
+	// ModeAlert: report the conflicts, and let the containers run.
+	ModeAlert Mode = "alert"
+	// ModeReject: report the conflicts, and fail the creation of the containers. The containers already running are only reported.
+	ModeReject Mode = "reject"
+)
+
+func ParseMode(s string) (Mode, error) {
+	md := Mode(strings.ToLower(s))
+	switch md {
+	case ModeNone, ModeAlert, ModeReject:
+		return md, nil
+	default:
+		return ModeNone, fmt.Errorf("unsupported adjustment conflicts mode: %q", s)
+	}
+}
+
+func (md Mode) IsEnabled() bool {
+	return md == ModeAlert || md == ModeReject
+}
+
+// ReasonAdjustmentConflict is the reason of the events reporting the conflicts.
+const ReasonAdjustmentConflict = "MemoryAdjustmentConflict"
+
+const (
+	FieldCPUSetMems    = "cpuset.mems"
+	FieldHugepageLimit = "hugepage-limit"
+)
+
+// Expected are the settings the claims of the container need.
+type Expected struct {
+	Mems cpuset.CPUSet
+	// HugepageLimits are the limits in bytes by page size, in the cgroup naming (2MB, 1GB)
+	HugepageLimits map[string]uint64
+}
+
+// Actual are the settings the container gets, and the plugins which set them, if known.
+type Actual struct {
+	// Mems is empty if not set
+	Mems      string
+	MemsOwner string
+	// HugepageLimits are the limits in bytes by page size, in the cgroup naming (2MB, 1GB)
+	HugepageLimits map[string]uint64
+	HugepageOwners map[string]string
+}
+
+// Conflict is a setting of the container which differs from what its claims need.
+type Conflict struct {
+	// Field is FieldCPUSetMems or FieldHugepageLimit
+	Field string
+	// PageSize is set only for FieldHugepageLimit
+	PageSize string
+	Expected string
+	Actual   string
+	// Owner is the plugin which set the value, if known
+	Owner string
+}
+
+func (cf Conflict) String() string {
+	name := cf.Field
+	if cf.PageSize != "" {
+		name += "[" + cf.PageSize + "]"
+	}
+	msg := fmt.Sprintf("%s is %s, the claims need %s", name, orUnset(cf.Actual), cf.Expected)
+	if cf.Owner != "" {
+		msg += " (set by plugin " + cf.Owner + ")"
+	}
+	return msg
+}
+
+// Detect returns the conflicts between the settings the claims need and the actual ones, sorted by field.
+// The values set by the plugin `self` never conflict: the runtime reports the merged value, but the owner is us.
+func Detect(self string, exp Expected, act Actual) []Conflict {
+	var conflicts []Conflict
+	if act.MemsOwner != self || self == "" {
+		mems, err := cpuset.Parse(act.Mems)
+		// an unset cpuset.mems means all the nodes, which is a conflict as well
+		if err != nil || act.Mems == "" || !mems.Equals(exp.Mems) {
+			conflicts = append(conflicts, Conflict{
+				Field:    FieldCPUSetMems,
+				Expected: exp.Mems.String(),
+				Actual:   act.Mems,
+				Owner:    act.MemsOwner,
+			})
+		}
+	}
+	for _, pageSize := range slices.Sorted(maps.Keys(exp.HugepageLimits)) {
+		owner := act.HugepageOwners[pageSize]
+		if owner == self && self != "" {
+			continue
+		}
+		expLimit := exp.HugepageLimits[pageSize]
+		actLimit, ok := act.HugepageLimits[pageSize]
+		if ok && actLimit == expLimit {
+			continue
+		}
+		actual := ""
+		if ok {
+			actual = strconv.FormatUint(actLimit, 10)
+		}
+		conflicts = append(conflicts, Conflict{
+			Field:    FieldHugepageLimit,
+			PageSize: pageSize,
+			Expected: strconv.FormatUint(expLimit, 10),
+			Actual:   actual,
+			Owner:    owner,
+		})
+	}
+	return conflicts
+}
+
+// Message summarizes the conflicts of the container `containerName` for the events.
+func Message(containerName string, conflicts []Conflict) string {
+	items := make([]string, 0, len(conflicts))
+	for _, cf := range conflicts {
+		items = append(items, cf.String())
+	}
+	return fmt.Sprintf("container %q: %s", containerName, strings.Join(items, "; "))
+}
+
+func orUnset(val string) string {
+	if val == "" {
+		return "unset"
+	}
+	return val
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nriconflict
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/utils/cpuset"
+)
+
+const testSelf = "00-dra.memory"
+
+func TestParseMode(t *testing.T) {
+	for _, val := range []string{"none", "alert", "Reject"} {
+		_, err := ParseMode(val)
+		require.NoError(t, err, val)
+	}
+	_, err := ParseMode("ignore")
+	require.Error(t, err)
+	require.False(t, ModeNone.IsEnabled())
+	require.True(t, ModeAlert.IsEnabled())
+	require.True(t, ModeReject.IsEnabled())
+}
+
+func TestDetect(t *testing.T) {
+	exp := Expected{
+		Mems: cpuset.New(1),
+		HugepageLimits: map[string]uint64{
+			"2MB": 32 << 20,
+			"1GB": 0,
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		self     string
+		actual   Actual
+		expected []Conflict
+	}{
+		{
+			name: "all set by us",
+			self: testSelf,
+			actual: Actual{
+				Mems:           "1",
+				MemsOwner:      testSelf,
+				HugepageLimits: map[string]uint64{"2MB": 32 << 20, "1GB": 0},
+				HugepageOwners: map[string]string{"2MB": testSelf, "1GB": testSelf},
+			},
+		},
+		{
+			name: "same values set by another plugin",
+			self: testSelf,
+			actual: Actual{
+				Mems:           "1",
+				MemsOwner:      "10-topology",
+				HugepageLimits: map[string]uint64{"2MB": 32 << 20, "1GB": 0},
+			},
+		},
+		{
+			name: "overridden by another plugin",
+			self: testSelf,
+			actual: Actual{
+				Mems:           "0-1",
+				MemsOwner:      "10-topology",
+				HugepageLimits: map[string]uint64{"2MB": 64 << 20, "1GB": 0},
+				HugepageOwners: map[string]string{"2MB": "20-hugepages", "1GB": testSelf},
+			},
+			expected: []Conflict{
+				{Field: FieldCPUSetMems, Expected: "1", Actual: "0-1", Owner: "10-topology"},
+				{Field: FieldHugepageLimit, PageSize: "2MB", Expected: "33554432", Actual: "67108864", Owner: "20-hugepages"},
+			},
+		},
+		{
+			name: "running containers, owners unknown",
+			actual: Actual{
+				HugepageLimits: map[string]uint64{"2MB": 32 << 20},
+			},
+			expected: []Conflict{
+				{Field: FieldCPUSetMems, Expected: "1"},
+				{Field: FieldHugepageLimit, PageSize: "1GB", Expected: "0"},
+			},
+		},
+		{
+			name: "malformed mems",
+			actual: Actual{
+				Mems:           "one",
+				HugepageLimits: map[string]uint64{"2MB": 32 << 20, "1GB": 0},
+			},
+			expected: []Conflict{
+				{Field: FieldCPUSetMems, Expected: "1", Actual: "one"},
+			},
+		},
+	}
+
+	for _, tcase := range testCases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := Detect(tcase.self, exp, tcase.actual)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	msg := Message("app", []Conflict{
+		{Field: FieldCPUSetMems, Expected: "1", Actual: "0-1", Owner: "10-topology"},
+		{Field: FieldHugepageLimit, PageSize: "1GB", Expected: "0"},
+	})
+	require.Equal(t, `container "app": cpuset.mems is 0-1, the claims need 1 (set by plugin 10-topology); hugepage-limit[1GB] is unset, the claims need 0`, msg)
+}
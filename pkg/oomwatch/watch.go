@@ -0,0 +1,340 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oomwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// The watcher polls the event counters of the cgroups of the pods holding memory claims.
+// Both memory.events and hugetlb.<size>.events are hierarchical, so watching the pod cgroup
+// is enough to catch the events of all its containers.
+// The kernel doesn't record the peak hugetlb usage, nor events for the reservations, so the
+// watcher samples hugetlb.<size>.current and hugetlb.<size>.rsvd.current at each poll and
+// reports the highest values observed when the pod terminates.
+
+const (
+	ReasonOOMKilled       = "MemoryClaimOOMKilled"
+	ReasonHugeTLBLimitHit = "HugeTLBClaimLimitHit"
+	ReasonHugeTLBSummary  = "HugeTLBClaimUsage"
+)
+
+const (
+	memoryEventsFile = "memory.events"
+	keyOOMKill       = "oom_kill"
+	keyMax           = "max"
+)
+
+// Target is a pod cgroup to watch.
+type Target struct {
+	PodUID     string
+	Namespace  string
+	Name       string
+	CgroupPath string // full path
+	ClaimUIDs  []string
+	// PageSizes is the list of the hugepage sizes, in the cgroup format (e.g. "2MB"), to watch
+	PageSizes []string
+	// Limits is the human readable representation of the limits programmed by the driver
+	Limits string
+}
+
+// Event is emitted when a counter increases, or summarizes the hugetlb usage when the pod terminates.
+type Event struct {
+	Reason   string
+	PageSize string // only for hugetlb events
+	Count    int64  // new events since last check
+	// Usage is set only in the summary events
+	Usage []HugeTLBUsage
+}
+
+// HugeTLBUsage is the usage of a hugepage size over the lifetime of the pod.
+type HugeTLBUsage struct {
+	PageSize     string
+	LimitHits    int64 // since the pod was tracked
+	PeakUsage    int64 // bytes, highest value observed
+	PeakReserved int64 // bytes, highest value observed
+}
+
+func (hu HugeTLBUsage) String() string {
+	return fmt.Sprintf("%s: peak usage %s, peak reserved %s, limit hit %d time(s)",
+		hu.PageSize, unitconv.SizeInBytesToQuantityString(hu.PeakUsage), unitconv.SizeInBytesToQuantityString(hu.PeakReserved), hu.LimitHits)
+}
+
+// IsWarning returns false only for the summaries of the pods which never hit their limits.
+func (ev Event) IsWarning() bool {
+	if ev.Reason != ReasonHugeTLBSummary {
+		return true
+	}
+	for _, usage := range ev.Usage {
+		if usage.LimitHits > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (ev Event) Message(tgt Target) string {
+	switch ev.Reason {
+	case ReasonHugeTLBLimitHit:
+		return fmt.Sprintf("hugetlb %s limit hit %d time(s); claims %v; programmed limits: %s", ev.PageSize, ev.Count, tgt.ClaimUIDs, tgt.Limits)
+	case ReasonHugeTLBSummary:
+		verdict := "hugetlb limits never hit"
+		if ev.IsWarning() {
+			verdict = "hugetlb limits hit"
+		}
+		usages := make([]string, 0, len(ev.Usage))
+		for _, usage := range ev.Usage {
+			usages = append(usages, usage.String())
+		}
+		return fmt.Sprintf("%s; %s; claims %v; programmed limits: %s", verdict, strings.Join(usages, "; "), tgt.ClaimUIDs, tgt.Limits)
+	}
+	return fmt.Sprintf("OOM killed %d time(s); claims %v; programmed limits: %s", ev.Count, tgt.ClaimUIDs, tgt.Limits)
+}
+
+type NotifyFunc func(Target, Event)
+
+type Counters struct {
+	OOMKill         int64
+	HugeTLBMax      map[string]int64 // pageSize -> events
+	HugeTLBUsage    map[string]int64 // pageSize -> bytes
+	HugeTLBReserved map[string]int64 // pageSize -> bytes
+}
+
+// ReadCounters reads the current counters of the cgroup `cgPath`. Missing files (e.g. disabled controllers) are skipped.
+func ReadCounters(lh logr.Logger, cgPath string, pageSizes []string) (Counters, error) {
+	cnt := Counters{
+		HugeTLBMax:      make(map[string]int64, len(pageSizes)),
+		HugeTLBUsage:    make(map[string]int64, len(pageSizes)),
+		HugeTLBReserved: make(map[string]int64, len(pageSizes)),
+	}
+	vals, err := cgroups.ParseKeyValues(lh, cgPath, memoryEventsFile)
+	if err != nil && !errors.Is(err, cgroups.ErrLimitFileMissing) {
+		return cnt, err
+	}
+	cnt.OOMKill = vals[keyOOMKill]
+	for _, pageSize := range pageSizes {
+		vals, err := cgroups.ParseKeyValues(lh, cgPath, "hugetlb."+pageSize+".events")
+		if err != nil && !errors.Is(err, cgroups.ErrLimitFileMissing) {
+			return cnt, err
+		}
+		cnt.HugeTLBMax[pageSize] = vals[keyMax]
+		cnt.HugeTLBUsage[pageSize], err = readUsage(lh, cgPath, "hugetlb."+pageSize+".current")
+		if err != nil {
+			return cnt, err
+		}
+		cnt.HugeTLBReserved[pageSize], err = readUsage(lh, cgPath, "hugetlb."+pageSize+".rsvd.current")
+		if err != nil {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+func readUsage(lh logr.Logger, cgPath, file string) (int64, error) {
+	val, err := cgroups.ParseValue(lh, cgPath, file)
+	if err != nil {
+		return 0, err
+	}
+	return max(val, 0), nil // missing file
+}
+
+type item struct {
+	target   Target
+	counters Counters
+	// initial are the counters when the tracking started, to tell the events of this pod
+	initial      Counters
+	peakUsage    map[string]int64
+	peakReserved map[string]int64
+}
+
+func newItem(tgt Target, cnt Counters) *item {
+	it := &item{
+		target:       tgt,
+		initial:      cnt,
+		peakUsage:    make(map[string]int64),
+		peakReserved: make(map[string]int64),
+	}
+	it.update(cnt)
+	return it
+}
+
+func (it *item) update(cnt Counters) {
+	for pageSize, val := range cnt.HugeTLBUsage {
+		it.peakUsage[pageSize] = max(it.peakUsage[pageSize], val)
+	}
+	for pageSize, val := range cnt.HugeTLBReserved {
+		it.peakReserved[pageSize] = max(it.peakReserved[pageSize], val)
+	}
+	it.counters = cnt
+}
+
+// usage returns the usage of the page sizes the pod used or tried to use, sorted like the target page sizes.
+func (it *item) usage() []HugeTLBUsage {
+	var usages []HugeTLBUsage
+	for _, pageSize := range it.target.PageSizes {
+		usage := HugeTLBUsage{
+			PageSize:     pageSize,
+			LimitHits:    it.counters.HugeTLBMax[pageSize] - it.initial.HugeTLBMax[pageSize],
+			PeakUsage:    it.peakUsage[pageSize],
+			PeakReserved: it.peakReserved[pageSize],
+		}
+		if usage.LimitHits <= 0 && usage.PeakUsage == 0 && usage.PeakReserved == 0 {
+			continue
+		}
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+type Watcher struct {
+	mu       sync.Mutex
+	interval time.Duration
+	notify   NotifyFunc
+	items    map[string]*item // podUID -> item
+}
+
+func NewWatcher(interval time.Duration, notify NotifyFunc) *Watcher {
+	return &Watcher{
+		interval: interval,
+		notify:   notify,
+		items:    make(map[string]*item),
+	}
+}
+
+// Track starts watching a target, or updates an existing one. The counters are
+// snapshotted on the first call, so only events happened later are notified.
+func (wt *Watcher) Track(lh logr.Logger, tgt Target) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	it, ok := wt.items[tgt.PodUID]
+	if ok {
+		it.target = tgt
+		return
+	}
+	cnt, err := ReadCounters(lh, tgt.CgroupPath, tgt.PageSizes)
+	if err != nil {
+		lh.V(2).Error(err, "reading initial counters", "path", tgt.CgroupPath)
+	}
+	wt.items[tgt.PodUID] = newItem(tgt, cnt)
+	lh.V(4).Info("tracking", "podUID", tgt.PodUID, "path", tgt.CgroupPath)
+}
+
+func (wt *Watcher) Untrack(lh logr.Logger, podUID string) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	delete(wt.items, podUID)
+	lh.V(4).Info("untracking", "podUID", podUID)
+}
+
+// Finish stops watching a target, and notifies the summary of its hugetlb usage, if any.
+// Must be called while the cgroup still exists, to catch the events happened since the last poll.
+func (wt *Watcher) Finish(lh logr.Logger, podUID string) {
+	wt.mu.Lock()
+	it, ok := wt.items[podUID]
+	if !ok {
+		wt.mu.Unlock()
+		return
+	}
+	delete(wt.items, podUID)
+	cnt, err := ReadCounters(lh, it.target.CgroupPath, it.target.PageSizes)
+	if err != nil {
+		// summarize what we observed so far
+		lh.V(2).Error(err, "reading final counters", "podUID", podUID, "path", it.target.CgroupPath)
+	} else {
+		it.update(cnt)
+	}
+	usages := it.usage()
+	wt.mu.Unlock()
+
+	if len(usages) == 0 {
+		lh.V(4).Info("untracking, no hugetlb usage", "podUID", podUID)
+		return
+	}
+	ev := Event{Reason: ReasonHugeTLBSummary, Usage: usages}
+	lh.Info("hugetlb usage", "podUID", podUID, "limitHit", ev.IsWarning(), "usage", ev.Message(it.target))
+	wt.notify(it.target, ev)
+}
+
+func (wt *Watcher) Len() int {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	return len(wt.items)
+}
+
+// Run polls the targets until the context is done.
+func (wt *Watcher) Run(ctx context.Context, lh logr.Logger) {
+	ticker := time.NewTicker(wt.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			wt.Poll(lh)
+		}
+	}
+}
+
+// Poll checks all the targets once, and notifies the new events.
+func (wt *Watcher) Poll(lh logr.Logger) {
+	type notification struct {
+		target Target
+		event  Event
+	}
+	var notifications []notification
+
+	wt.mu.Lock()
+	for podUID, it := range wt.items {
+		cnt, err := ReadCounters(lh, it.target.CgroupPath, it.target.PageSizes)
+		if err != nil {
+			lh.V(2).Error(err, "reading counters", "podUID", podUID, "path", it.target.CgroupPath)
+			continue
+		}
+		if delta := cnt.OOMKill - it.counters.OOMKill; delta > 0 {
+			notifications = append(notifications, notification{
+				target: it.target,
+				event:  Event{Reason: ReasonOOMKilled, Count: delta},
+			})
+		}
+		for pageSize, val := range cnt.HugeTLBMax {
+			if delta := val - it.counters.HugeTLBMax[pageSize]; delta > 0 {
+				notifications = append(notifications, notification{
+					target: it.target,
+					event:  Event{Reason: ReasonHugeTLBLimitHit, PageSize: pageSize, Count: delta},
+				})
+			}
+		}
+		it.update(cnt)
+	}
+	wt.mu.Unlock()
+
+	// notify outside the lock, the callback can be slow
+	for _, nt := range notifications {
+		lh.V(2).Info("event detected", "podUID", nt.target.PodUID, "reason", nt.event.Reason, "count", nt.event.Count)
+		wt.notify(nt.target, nt.event)
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oomwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	cgPath := t.TempDir()
+	writeEvents := func(oomKill, hpMax int) {
+		t.Helper()
+		memEvents := "low 0\nhigh 0\nmax 0\noom 0\noom_kill " + strconv.Itoa(oomKill) + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, "memory.events"), []byte(memEvents), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, "hugetlb.2MB.events"), []byte("max "+strconv.Itoa(hpMax)+"\n"), 0644))
+	}
+
+	var got []Event
+	wt := NewWatcher(time.Second, func(tgt Target, ev Event) {
+		require.Equal(t, "pod-UID", tgt.PodUID)
+		got = append(got, ev)
+	})
+
+	writeEvents(1, 2) // preexisting events must not be reported
+	wt.Track(lh, Target{
+		PodUID:     "pod-UID",
+		CgroupPath: cgPath,
+		ClaimUIDs:  []string{"claim-UID"},
+		PageSizes:  []string{"2MB", "1GB"},
+	})
+	require.Equal(t, 1, wt.Len())
+
+	wt.Poll(lh)
+	require.Empty(t, got)
+
+	writeEvents(2, 2)
+	wt.Poll(lh)
+	require.Equal(t, []Event{{Reason: ReasonOOMKilled, Count: 1}}, got)
+
+	got = nil
+	writeEvents(2, 5)
+	wt.Poll(lh)
+	require.Equal(t, []Event{{Reason: ReasonHugeTLBLimitHit, PageSize: "2MB", Count: 3}}, got)
+
+	got = nil
+	wt.Untrack(lh, "pod-UID")
+	require.Equal(t, 0, wt.Len())
+	writeEvents(5, 8)
+	wt.Poll(lh)
+	require.Empty(t, got)
+}
+
+func TestEventMessage(t *testing.T) {
+	tgt := Target{
+		ClaimUIDs: []string{"claim-UID"},
+		Limits:    "2MB=64MB",
+	}
+	msg := Event{Reason: ReasonHugeTLBLimitHit, PageSize: "2MB", Count: 1}.Message(tgt)
+	require.Contains(t, msg, "claim-UID")
+	require.Contains(t, msg, "2MB=64MB")
+	msg = Event{Reason: ReasonOOMKilled, Count: 1}.Message(tgt)
+	require.Contains(t, msg, "OOM killed")
+}
+
+func TestWatcherFinish(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	cgPath := t.TempDir()
+	writeHugeTLB := func(hpMax, current, reserved int) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, "hugetlb.2MB.events"), []byte("max "+strconv.Itoa(hpMax)+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, "hugetlb.2MB.current"), []byte(strconv.Itoa(current)+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(cgPath, "hugetlb.2MB.rsvd.current"), []byte(strconv.Itoa(reserved)+"\n"), 0644))
+	}
+
+	var got []Event
+	wt := NewWatcher(time.Second, func(tgt Target, ev Event) {
+		require.Equal(t, "pod-UID", tgt.PodUID)
+		got = append(got, ev)
+	})
+
+	writeHugeTLB(1, 0, 0) // preexisting events must not be reported
+	wt.Track(lh, Target{
+		PodUID:     "pod-UID",
+		CgroupPath: cgPath,
+		PageSizes:  []string{"2MB", "1GB"},
+	})
+
+	writeHugeTLB(1, 8<<20, 16<<20)
+	wt.Poll(lh)
+	writeHugeTLB(1, 4<<20, 16<<20) // the peak must be retained
+	wt.Poll(lh)
+	require.Empty(t, got)
+
+	writeHugeTLB(3, 0, 0) // hit after the last poll, and the memory released
+	wt.Finish(lh, "pod-UID")
+	require.Equal(t, 0, wt.Len())
+	require.Equal(t, []Event{
+		{
+			Reason: ReasonHugeTLBSummary,
+			Usage: []HugeTLBUsage{
+				{PageSize: "2MB", LimitHits: 2, PeakUsage: 8 << 20, PeakReserved: 16 << 20},
+			},
+		},
+	}, got)
+	require.True(t, got[0].IsWarning())
+
+	// untracked already
+	got = nil
+	wt.Finish(lh, "pod-UID")
+	require.Empty(t, got)
+}
+
+func TestWatcherFinishNoUsage(t *testing.T) {
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+
+	lh := testr.New(t)
+	var got []Event
+	wt := NewWatcher(time.Second, func(tgt Target, ev Event) {
+		got = append(got, ev)
+	})
+	wt.Track(lh, Target{
+		PodUID:     "pod-UID",
+		CgroupPath: t.TempDir(),
+		PageSizes:  []string{"2MB"},
+	})
+	wt.Finish(lh, "pod-UID")
+	require.Equal(t, 0, wt.Len())
+	require.Empty(t, got)
+}
+
+func TestSummaryMessage(t *testing.T) {
+	tgt := Target{
+		ClaimUIDs: []string{"claim-UID"},
+		Limits:    "2MB=64MB",
+	}
+	ev := Event{
+		Reason: ReasonHugeTLBSummary,
+		Usage:  []HugeTLBUsage{{PageSize: "2MB", PeakUsage: 32 << 20}},
+	}
+	require.False(t, ev.IsWarning())
+	msg := ev.Message(tgt)
+	require.Contains(t, msg, "never hit")
+	require.Contains(t, msg, "peak usage 32Mi")
+	require.Contains(t, msg, "claim-UID")
+	require.Contains(t, msg, "2MB=64MB")
+	require.True(t, Event{Reason: ReasonOOMKilled, Count: 1}.IsWarning())
+}
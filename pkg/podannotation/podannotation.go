@@ -0,0 +1,84 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podannotation
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The allocation summary annotations let the users see what the claims of a pod got, e.g. with
+// `kubectl describe pod`, without access to the node. They are informational: the driver never reads them.
+
+// AnnotationPrefix is the prefix of the annotations, one per claim. The claim names may not fit
+// in the name of an annotation, so the annotations are named after the claim UIDs.
+const AnnotationPrefix = "dra.memory/allocation-"
+
+// Pod identifies the pod consuming a claim.
+type Pod struct {
+	Namespace string
+	Name      string
+	UID       k8stypes.UID
+}
+
+// Key returns the name of the annotation of the claim `claimUID`.
+func Key(claimUID k8stypes.UID) string {
+	return AnnotationPrefix + string(claimUID)
+}
+
+// Summary returns the summary of the allocations of the claim `claimName`, sorted by resource name,
+// e.g. "my-claim: hugepages-2Mi size=1Gi numaZone=0; memory size=8Gi numaZones=0:4Gi,1:4Gi".
+func Summary(claimName string, allocs []types.Allocation) string {
+	items := make([]string, 0, len(allocs))
+	for _, alloc := range allocs {
+		items = append(items, alloc.String())
+	}
+	slices.Sort(items)
+	return claimName + ": " + strings.Join(items, "; ")
+}
+
+// Annotate sets the summary of the allocations of the claim `claimUID` on the pod. The pod UID is
+// a precondition of the patch, so a pod recreated meanwhile with the same name is not annotated.
+func Annotate(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, pod Pod, claimUID k8stypes.UID, summary string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"uid": pod.UID,
+			"annotations": map[string]string{
+				Key(claimUID): summary,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cli.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("pod annotated with the allocation summary", "pod", pod.Namespace+"/"+pod.Name, "claimUID", claimUID)
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package podannotation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+func TestSummary(t *testing.T) {
+	memory := types.Allocation{
+		ResourceIdent: types.ResourceIdent{Kind: types.Memory, Pagesize: 4096},
+		Amount:        8 * (1 << 30),
+		AmountByZone: map[int64]int64{
+			0: 4 * (1 << 30),
+			1: 4 * (1 << 30),
+		},
+	}
+	hugepages := types.NewAllocation(types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)}, 1<<30, 0)
+	require.Equal(t,
+		"my-claim: hugepages-2Mi size=1Gi numaZone=0; memory size=8Gi numaZones=0:4Gi,1:4Gi",
+		Summary("my-claim", []types.Allocation{memory, hugepages}),
+	)
+}
+
+func TestAnnotate(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+	cli := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "pod-0",
+			UID:       "pod-UID",
+			Annotations: map[string]string{
+				"example.com/unrelated": "keep",
+			},
+		},
+	})
+
+	pod := Pod{Namespace: "ns", Name: "pod-0", UID: "pod-UID"}
+	require.NoError(t, Annotate(ctx, lh, cli, pod, "claim-UID-0", "claim-0: memory size=1Gi numaZone=0"))
+	require.NoError(t, Annotate(ctx, lh, cli, pod, "claim-UID-1", "claim-1: memory size=2Gi numaZone=1"))
+	got, err := cli.CoreV1().Pods("ns").Get(ctx, "pod-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"example.com/unrelated":             "keep",
+		"dra.memory/allocation-claim-UID-0": "claim-0: memory size=1Gi numaZone=0",
+		"dra.memory/allocation-claim-UID-1": "claim-1: memory size=2Gi numaZone=1",
+	}, got.Annotations)
+
+	require.Error(t, Annotate(ctx, lh, cli, Pod{Namespace: "ns", Name: "missing", UID: "missing-UID"}, "claim-UID-0", "claim-0"))
+}
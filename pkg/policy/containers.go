@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContainerPolicy describes what the containers which don't consume any memory claim
+// inherit from the claims of their pod. Only the containers consuming claims get
+// adjustments by default, so for example init containers may fault memory on the wrong node.
+type ContainerPolicy string
+
+const (
+	// ContainerPolicyNone: no adjustments at all. This is the default.
+	ContainerPolicyNone ContainerPolicy = "none"
+	// ContainerPolicyMems: inherit the cpuset.mems of the pod claims.
+	ContainerPolicyMems ContainerPolicy = "mems"
+	// ContainerPolicyFull: inherit the cpuset.mems and the hugetlb limits of the pod claims.
+	ContainerPolicyFull ContainerPolicy = "full"
+	// ContainerPolicyMemsZeroHugeTLB: inherit the cpuset.mems of the pod claims, with zero hugetlb limits,
+	// so the container can't consume the hugepages of the claims. The default for the ephemeral containers.
+	ContainerPolicyMemsZeroHugeTLB ContainerPolicy = "mems-zero-hugetlb"
+)
+
+func ParseContainerPolicy(s string) (ContainerPolicy, error) {
+	cp := ContainerPolicy(strings.ToLower(s))
+	switch cp {
+	case ContainerPolicyNone, ContainerPolicyMems, ContainerPolicyFull, ContainerPolicyMemsZeroHugeTLB:
+		return cp, nil
+	default:
+		return ContainerPolicyNone, fmt.Errorf("unsupported container policy: %q", s)
+	}
+}
+
+func (cp ContainerPolicy) InheritMems() bool {
+	return cp == ContainerPolicyMems || cp == ContainerPolicyFull || cp == ContainerPolicyMemsZeroHugeTLB
+}
+
+func (cp ContainerPolicy) InheritHugeTLB() bool {
+	return cp == ContainerPolicyFull
+}
+
+func (cp ContainerPolicy) ZeroHugeTLB() bool {
+	return cp == ContainerPolicyMemsZeroHugeTLB
+}
+
+type ContainerKind string
+
+const (
+	ContainerKindRegular ContainerKind = "regular"
+	ContainerKindInit    ContainerKind = "init"
+	// ContainerKindSidecar is a restartable init container
+	ContainerKindSidecar ContainerKind = "sidecar"
+	// ContainerKindEphemeral is a container added to the running pod, typically by kubectl debug
+	ContainerKindEphemeral ContainerKind = "ephemeral"
+)
+
+// KindOfContainer returns the kind of the container called `name` within `pod`.
+// Unknown containers are reported as regular containers.
+func KindOfContainer(pod *corev1.Pod, name string) ContainerKind {
+	for idx := range pod.Spec.InitContainers {
+		cnt := &pod.Spec.InitContainers[idx]
+		if cnt.Name != name {
+			continue
+		}
+		if cnt.RestartPolicy != nil && *cnt.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			return ContainerKindSidecar
+		}
+		return ContainerKindInit
+	}
+	for idx := range pod.Spec.EphemeralContainers {
+		if pod.Spec.EphemeralContainers[idx].Name == name {
+			return ContainerKindEphemeral
+		}
+	}
+	return ContainerKindRegular
+}
+
+// Containers holds the policies for the containers which don't consume memory claims.
+type Containers struct {
+	Init      ContainerPolicy
+	Sidecar   ContainerPolicy
+	Ephemeral ContainerPolicy
+}
+
+func DefaultContainers() Containers {
+	return Containers{
+		Init:      ContainerPolicyNone,
+		Sidecar:   ContainerPolicyNone,
+		Ephemeral: ContainerPolicyMemsZeroHugeTLB,
+	}
+}
+
+func (cs Containers) IsEnabled() bool {
+	return cs.Init.InheritMems() || cs.Sidecar.InheritMems() || cs.Ephemeral.InheritMems()
+}
+
+func (cs Containers) ForKind(kind ContainerKind) ContainerPolicy {
+	switch kind {
+	case ContainerKindInit:
+		return cs.Init
+	case ContainerKindSidecar:
+		return cs.Sidecar
+	case ContainerKindEphemeral:
+		return cs.Ephemeral
+	default:
+		return ContainerPolicyNone
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseContainerPolicy(t *testing.T) {
+	type testcase struct {
+		value       string
+		expected    ContainerPolicy
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{value: "none", expected: ContainerPolicyNone},
+		{value: "mems", expected: ContainerPolicyMems},
+		{value: "Full", expected: ContainerPolicyFull},
+		{value: "mems-zero-hugetlb", expected: ContainerPolicyMemsZeroHugeTLB},
+		{value: "", expectedErr: true},
+		{value: "foobar", expectedErr: true},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.value, func(t *testing.T) {
+			got, err := ParseContainerPolicy(tcase.value)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
+func TestContainerPolicyInheritance(t *testing.T) {
+	require.False(t, ContainerPolicyNone.InheritMems())
+	require.False(t, ContainerPolicyNone.InheritHugeTLB())
+	require.True(t, ContainerPolicyMems.InheritMems())
+	require.False(t, ContainerPolicyMems.InheritHugeTLB())
+	require.True(t, ContainerPolicyFull.InheritMems())
+	require.True(t, ContainerPolicyFull.InheritHugeTLB())
+	require.False(t, ContainerPolicyFull.ZeroHugeTLB())
+	require.True(t, ContainerPolicyMemsZeroHugeTLB.InheritMems())
+	require.False(t, ContainerPolicyMemsZeroHugeTLB.InheritHugeTLB())
+	require.True(t, ContainerPolicyMemsZeroHugeTLB.ZeroHugeTLB())
+}
+
+func TestKindOfContainer(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{
+					Name: "init",
+				},
+				{
+					Name:          "sidecar",
+					RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+				},
+			},
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+				},
+			},
+			EphemeralContainers: []corev1.EphemeralContainer{
+				{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name: "debugger",
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, ContainerKindInit, KindOfContainer(pod, "init"))
+	require.Equal(t, ContainerKindSidecar, KindOfContainer(pod, "sidecar"))
+	require.Equal(t, ContainerKindRegular, KindOfContainer(pod, "app"))
+	require.Equal(t, ContainerKindEphemeral, KindOfContainer(pod, "debugger"))
+	require.Equal(t, ContainerKindRegular, KindOfContainer(pod, "missing"))
+}
+
+func TestContainersForKind(t *testing.T) {
+	cs := DefaultContainers()
+	require.True(t, cs.IsEnabled(), "ephemeral containers inherit the mems by default")
+	require.Equal(t, ContainerPolicyNone, cs.ForKind(ContainerKindInit))
+	require.Equal(t, ContainerPolicyMemsZeroHugeTLB, cs.ForKind(ContainerKindEphemeral))
+
+	cs.Ephemeral = ContainerPolicyNone
+	require.False(t, cs.IsEnabled())
+
+	cs.Sidecar = ContainerPolicyFull
+	require.True(t, cs.IsEnabled())
+	require.Equal(t, ContainerPolicyNone, cs.ForKind(ContainerKindInit))
+	require.Equal(t, ContainerPolicyFull, cs.ForKind(ContainerKindSidecar))
+	require.Equal(t, ContainerPolicyNone, cs.ForKind(ContainerKindRegular))
+}
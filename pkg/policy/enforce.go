@@ -0,0 +1,41 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AnnotationEnforce set to false on a pod opts its containers out of the enforcement of their claims:
+// the driver still accounts the claims, but sets neither cpuset.mems nor the hugetlb limits. This is meant
+// for debugging and for the system workloads managing their memory by themselves, not for the regular pods.
+const AnnotationEnforce = "dra.memory/enforce"
+
+// EnforcementDisabled tells if the pod annotations opt the pod out of the enforcement.
+// A malformed value keeps the enforcement, and is reported as error.
+func EnforcementDisabled(annotations map[string]string) (bool, error) {
+	val, ok := annotations[AnnotationEnforce]
+	if !ok {
+		return false, nil
+	}
+	enforce, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("malformed annotation %s=%q: %w", AnnotationEnforce, val, err)
+	}
+	return !enforce, nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforcementDisabled(t *testing.T) {
+	type testcase struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name: "no annotations",
+		},
+		{
+			name:        "unrelated annotations",
+			annotations: map[string]string{"example.com/enforce": "false"},
+		},
+		{
+			name:        "enforced",
+			annotations: map[string]string{AnnotationEnforce: "true"},
+		},
+		{
+			name:        "opted out",
+			annotations: map[string]string{AnnotationEnforce: "false"},
+			expected:    true,
+		},
+		{
+			name:        "malformed",
+			annotations: map[string]string{AnnotationEnforce: "nope"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := EnforcementDisabled(tcase.annotations)
+			if tcase.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
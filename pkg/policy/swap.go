@@ -0,0 +1,72 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SwapPolicy describes how the swap of the containers holding memory claims is limited.
+// On nodes with NodeSwap enabled the kubelet computes memory.swap.max from the container
+// memory requests, which don't include the memory claims.
+type SwapPolicy string
+
+const (
+	// SwapPolicyUnmanaged: leave the swap settings to the kubelet. This is the default.
+	SwapPolicyUnmanaged SwapPolicy = "unmanaged"
+	// SwapPolicyNoSwap: the containers holding memory claims can't swap.
+	SwapPolicyNoSwap SwapPolicy = "none"
+	// SwapPolicyProportional: the swap is limited proportionally to the claimed memory,
+	// like the kubelet LimitedSwap behavior does with the memory requests.
+	SwapPolicyProportional SwapPolicy = "proportional"
+)
+
+// SwapMaxFile is the cgroup v2 file controlling the swap limit.
+const SwapMaxFile = "memory.swap.max"
+
+func ParseSwapPolicy(s string) (SwapPolicy, error) {
+	sp := SwapPolicy(strings.ToLower(s))
+	switch sp {
+	case SwapPolicyUnmanaged, SwapPolicyNoSwap, SwapPolicyProportional:
+		return sp, nil
+	default:
+		return SwapPolicyUnmanaged, fmt.Errorf("unsupported swap policy: %q", s)
+	}
+}
+
+func (sp SwapPolicy) IsManaged() bool {
+	return sp == SwapPolicyNoSwap || sp == SwapPolicyProportional
+}
+
+// SwapLimit returns the swap limit in bytes for `claimedBytes` of memory claims on a node with
+// `memTotal` bytes of memory and `swapTotal` bytes of swap. Returns false if the limit should be left alone.
+func (sp SwapPolicy) SwapLimit(claimedBytes, memTotal, swapTotal uint64) (int64, bool) {
+	switch sp {
+	case SwapPolicyNoSwap:
+		return 0, true
+	case SwapPolicyProportional:
+		if claimedBytes == 0 || memTotal == 0 {
+			return 0, false // no memory claims, only hugepages which can't swap anyway
+		}
+		// same formula the kubelet uses for LimitedSwap. Use floats to avoid overflows.
+		ratio := float64(claimedBytes) / float64(memTotal)
+		return int64(ratio * float64(swapTotal)), true
+	default:
+		return 0, false
+	}
+}
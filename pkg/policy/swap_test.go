@@ -0,0 +1,78 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSwapPolicy(t *testing.T) {
+	for _, val := range []string{"unmanaged", "none", "Proportional"} {
+		_, err := ParseSwapPolicy(val)
+		require.NoError(t, err, "value %q", val)
+	}
+	_, err := ParseSwapPolicy("limited")
+	require.Error(t, err)
+}
+
+func TestSwapLimit(t *testing.T) {
+	const gib = uint64(1 << 30)
+
+	type testcase struct {
+		name         string
+		policy       SwapPolicy
+		claimedBytes uint64
+		expected     int64
+		expectedOK   bool
+	}
+
+	testcases := []testcase{
+		{
+			name:         "unmanaged",
+			policy:       SwapPolicyUnmanaged,
+			claimedBytes: 4 * gib,
+		},
+		{
+			name:         "no swap",
+			policy:       SwapPolicyNoSwap,
+			claimedBytes: 4 * gib,
+			expected:     0,
+			expectedOK:   true,
+		},
+		{
+			name:         "proportional",
+			policy:       SwapPolicyProportional,
+			claimedBytes: 4 * gib,
+			expected:     int64(2 * gib),
+			expectedOK:   true,
+		},
+		{
+			name:   "proportional without memory claims",
+			policy: SwapPolicyProportional,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, ok := tcase.policy.SwapLimit(tcase.claimedBytes, 32*gib, 16*gib)
+			require.Equal(t, tcase.expectedOK, ok)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
@@ -0,0 +1,357 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	cdiSpec "tags.cncf.io/container-device-interface/specs-go"
+
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/env"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+// The self-test runs the enforcement pipeline on the node, without the API server nor the container runtime:
+// it makes a synthetic allocation, writes its CDI device like when preparing a claim, reads the variables
+// back like the NRI layer, programs the limits in a scratch cgroup and reads them back. Everything created
+// is removed at the end, even if a step fails. The allocation is not backed: no memory is consumed.
+
+const (
+	StepAllocate = "allocate"
+	StepCDI      = "cdi"
+	StepEnvs     = "envs"
+	StepCgroup   = "cgroup"
+	StepLimits   = "limits"
+	StepVerify   = "verify"
+	StepCleanup  = "cleanup"
+)
+
+const (
+	// scratchPrefix names the scratch cgroups, followed by the PID of the self-test
+	scratchPrefix = "dramemory-selftest-"
+	// memoryHighFile is the soft limit of the memory, set for the burstable claims
+	memoryHighFile = "memory.high"
+)
+
+type Config struct {
+	DriverName string
+	// CgroupMount is the cgroup2 mount point. Empty skips the cgroup steps, like the cgroup enforcement disabled.
+	CgroupMount string
+	// CgroupParent is the cgroup to create the scratch cgroup into, relative to CgroupMount. Empty means the root.
+	CgroupParent string
+}
+
+// Step is the outcome of a step. A skipped step has neither error nor detail of what was done.
+type Step struct {
+	Name    string
+	Detail  string
+	Err     error
+	Skipped bool
+}
+
+type Report struct {
+	Steps []Step
+}
+
+// Err returns the errors of the failed steps, nil if all passed.
+func (rep *Report) Err() error {
+	var errs []error
+	for _, step := range rep.Steps {
+		if step.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, step.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes the outcome of each step, one per line, then the verdict.
+func (rep *Report) Print(w io.Writer) {
+	for _, step := range rep.Steps {
+		switch {
+		case step.Err != nil:
+			fmt.Fprintf(w, "FAIL %s: %v\n", step.Name, step.Err)
+		case step.Skipped:
+			fmt.Fprintf(w, "SKIP %s: %s\n", step.Name, step.Detail)
+		default:
+			fmt.Fprintf(w, "PASS %s: %s\n", step.Name, step.Detail)
+		}
+	}
+	if rep.Err() != nil {
+		fmt.Fprintln(w, "FAIL")
+		return
+	}
+	fmt.Fprintln(w, "PASS")
+}
+
+// Run runs the self-test on the machine, stopping at the first failed step.
+func Run(lh logr.Logger, machine sysinfo.MachineData, cfg Config) *Report {
+	st := &selfTest{
+		lh:       lh.WithName("selftest"),
+		machine:  machine,
+		cfg:      cfg,
+		report:   &Report{},
+		claimUID: uuid.NewUUID(),
+	}
+	defer st.cleanup()
+
+	steps := []struct {
+		name   string
+		fn     func() (string, error)
+		cgroup bool
+	}{
+		{StepAllocate, st.allocate, false},
+		{StepCDI, st.writeDevice, false},
+		{StepEnvs, st.readDevice, false},
+		{StepCgroup, st.makeCgroup, true},
+		{StepLimits, st.setLimits, true},
+		{StepVerify, st.verifyLimits, true},
+	}
+	for _, step := range steps {
+		if step.cgroup && cfg.CgroupMount == "" {
+			st.report.Steps = append(st.report.Steps, Step{Name: step.name, Detail: "cgroup enforcement disabled", Skipped: true})
+			continue
+		}
+		detail, err := step.fn()
+		st.report.Steps = append(st.report.Steps, Step{Name: step.name, Detail: detail, Err: err})
+		if err != nil {
+			break
+		}
+	}
+	return st.report
+}
+
+type selfTest struct {
+	lh       logr.Logger
+	machine  sysinfo.MachineData
+	cfg      Config
+	report   *Report
+	claimUID k8stypes.UID
+	alloc    types.Allocation
+	cdiMgr   *cdi.Manager
+	cgPath   string
+	// undo are the cleanups of what was created, in creation order
+	undo []func() error
+}
+
+// allocate makes the allocation of a claim, preferring the hugepages, whose limits are most of the enforcement.
+func (st *selfTest) allocate() (string, error) {
+	alloc, err := pickAllocation(st.machine)
+	if err != nil {
+		return "", err
+	}
+	st.alloc = alloc
+	return alloc.String(), nil
+}
+
+func pickAllocation(machine sysinfo.MachineData) (types.Allocation, error) {
+	hpSizes := slices.Sorted(slices.Values(machine.Hugepagesizes))
+	for _, zone := range machine.Zones {
+		if zone.Memory == nil {
+			continue
+		}
+		for _, hpSize := range hpSizes {
+			amounts := zone.Memory.HugePageAmountsBySize[hpSize]
+			if amounts == nil || amounts.Free <= 0 {
+				continue
+			}
+			ident := types.ResourceIdent{Kind: types.Hugepages, Pagesize: hpSize}
+			return types.NewAllocation(ident, int64(hpSize), int64(zone.ID)), nil
+		}
+	}
+	for _, zone := range machine.Zones {
+		if zone.Memory == nil || zone.Memory.TotalUsableBytes <= 0 {
+			continue
+		}
+		ident := types.ResourceIdent{Kind: types.Memory, Pagesize: machine.Pagesize}
+		return types.NewAllocation(ident, int64(ident.MinimumAllocatable()), int64(zone.ID)), nil
+	}
+	return types.Allocation{}, errors.New("no NUMA zone with memory to allocate from")
+}
+
+// writeDevice writes the CDI device of the allocation, like preparing the claim does.
+func (st *selfTest) writeDevice() (string, error) {
+	mgr, err := cdi.NewManager(st.cfg.DriverName, st.lh)
+	if err != nil {
+		return "", err
+	}
+	deviceName := cdi.MakeDeviceName(st.claimUID)
+	envs := []string{
+		env.CreateAlloc(st.lh, st.claimUID, st.alloc),
+		env.CreateClaim(st.lh, st.claimUID),
+		env.CreateNUMANodes(st.lh, st.claimUID, sets.New(st.alloc.NUMAZones()...)),
+	}
+	st.cdiMgr = mgr
+	err = mgr.AddDevice(st.lh, deviceName, envs...)
+	st.undo = append(st.undo, func() error {
+		return mgr.RemoveDevice(st.lh, deviceName)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("device %s in %s", deviceName, cdi.SpecDir), nil
+}
+
+// readDevice reads the variables of the device back, like the NRI layer does from the container.
+func (st *selfTest) readDevice() (string, error) {
+	spec, err := st.cdiMgr.GetSpec(st.lh)
+	if err != nil {
+		return "", err
+	}
+	deviceName := cdi.MakeDeviceName(st.claimUID)
+	idx := slices.IndexFunc(spec.Devices, func(dev cdiSpec.Device) bool { return dev.Name == deviceName })
+	if idx == -1 {
+		return "", fmt.Errorf("device %s not found in the specs", deviceName)
+	}
+	envs := spec.Devices[idx].ContainerEdits.Env
+	nodesByClaim, allocsByClaim, err := env.ExtractAll(st.lh, envs, sets.New(st.alloc.Name()))
+	if err != nil {
+		return "", err
+	}
+	expectedNodes := st.alloc.NUMAZones()
+	gotNodes := nodesByClaim[st.claimUID].List()
+	if !slices.EqualFunc(gotNodes, expectedNodes, func(a int, b int64) bool { return int64(a) == b }) {
+		return "", fmt.Errorf("NUMA nodes mismatch expected=%v got=%v", expectedNodes, gotNodes)
+	}
+	allocs := allocsByClaim[st.claimUID]
+	if len(allocs) != 1 || !sameAllocation(allocs[0], st.alloc) {
+		return "", fmt.Errorf("allocations mismatch expected=%v got=%v", st.alloc, allocs)
+	}
+	return fmt.Sprintf("%d variables read back", len(envs)), nil
+}
+
+func sameAllocation(a, b types.Allocation) bool {
+	if a.Name() != b.Name() || a.Amount != b.Amount || len(a.AmountByZone) != len(b.AmountByZone) {
+		return false
+	}
+	for numaZone, amount := range a.AmountByZone {
+		if b.AmountByZone[numaZone] != amount {
+			return false
+		}
+	}
+	return true
+}
+
+// makeCgroup creates the scratch cgroup, empty: no process is ever moved there.
+func (st *selfTest) makeCgroup() (string, error) {
+	cgPath := filepath.Join(st.cfg.CgroupMount, st.cfg.CgroupParent, scratchPrefix+strconv.Itoa(os.Getpid()))
+	err := os.Mkdir(cgPath, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("cannot create the scratch cgroup: %w", err)
+	}
+	st.cgPath = cgPath
+	st.undo = append(st.undo, func() error {
+		return removeCgroup(cgPath)
+	})
+	return cgPath, nil
+}
+
+// setLimits programs the limits of the allocation, like creating the container does.
+func (st *selfTest) setLimits() (string, error) {
+	limits := hugepages.LimitsFromAllocations(st.lh, st.machine, []types.Allocation{st.alloc})
+	err := hugepages.SetSystemLimits(st.lh, cgroups.LocalWriter{}, st.cgPath, limits, hugepages.ReservationSync)
+	if err != nil {
+		return "", err
+	}
+	detail := "hugetlb " + hugepages.LimitsToString(limits)
+	if st.alloc.NeedsHugeTLB() {
+		return detail, nil
+	}
+	// the soft limit of the burstable claims is the one limit about the memory
+	err = cgroups.LocalWriter{}.WriteValue(st.lh, st.cgPath, memoryHighFile, st.alloc.Amount)
+	if err != nil {
+		return "", fmt.Errorf("setting the memory soft limit: %w", err)
+	}
+	return detail + fmt.Sprintf(", %s=%d", memoryHighFile, st.alloc.Amount), nil
+}
+
+// verifyLimits reads the limits back, both the usage and the reservation ones for the hugepages.
+func (st *selfTest) verifyLimits() (string, error) {
+	limits := hugepages.LimitsFromAllocations(st.lh, st.machine, []types.Allocation{st.alloc})
+	checked := 0
+	for _, limit := range limits {
+		for _, attr := range []string{".max", ".rsvd.max"} {
+			err := st.verifyValue("hugetlb."+limit.PageSize+attr, int64(limit.Limit.Value))
+			if err != nil {
+				return "", err
+			}
+			checked++
+		}
+	}
+	if !st.alloc.NeedsHugeTLB() {
+		err := st.verifyValue(memoryHighFile, st.alloc.Amount)
+		if err != nil {
+			return "", err
+		}
+		checked++
+	}
+	return fmt.Sprintf("%d values read back", checked), nil
+}
+
+func (st *selfTest) verifyValue(file string, expected int64) error {
+	got, err := cgroups.ParseValue(st.lh, st.cgPath, file)
+	if err != nil {
+		return err
+	}
+	if got != expected {
+		return fmt.Errorf("%s mismatch expected=%d got=%d", file, expected, got)
+	}
+	return nil
+}
+
+// cleanup undoes everything created, in reverse order, and records the outcome if anything was created.
+func (st *selfTest) cleanup() {
+	if len(st.undo) == 0 {
+		return
+	}
+	var errs []error
+	for _, undo := range slices.Backward(st.undo) {
+		errs = append(errs, undo())
+	}
+	st.report.Steps = append(st.report.Steps, Step{
+		Name:   StepCleanup,
+		Detail: fmt.Sprintf("%d objects removed", len(st.undo)),
+		Err:    errors.Join(errs...),
+	})
+}
+
+// removeCgroup removes the scratch cgroup. On cgroupfs the control files go along with the directory,
+// while the tests, emulating cgroupfs on a plain directory, need to remove them.
+func removeCgroup(cgPath string) error {
+	if cgroups.TestMode {
+		return os.RemoveAll(cgPath)
+	}
+	err := os.Remove(cgPath)
+	if err != nil {
+		return fmt.Errorf("cannot remove the scratch cgroup: %w", err)
+	}
+	return nil
+}
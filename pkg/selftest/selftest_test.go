@@ -0,0 +1,167 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package selftest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cdi"
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
+	"github.com/ffromani/dra-driver-memory/pkg/types"
+)
+
+const (
+	size2Mi = 2 * (1 << 20)
+	size1Gi = 1 << 30
+)
+
+func setupDirs(t *testing.T) string {
+	t.Helper()
+	cgroups.TestMode = true
+	t.Cleanup(func() { cgroups.TestMode = false })
+	specDir := cdi.SpecDir
+	cdi.SpecDir = t.TempDir()
+	t.Cleanup(func() { cdi.SpecDir = specDir })
+	return t.TempDir()
+}
+
+func makeMachine(freeHugepages int64) sysinfo.MachineData {
+	return sysinfo.MachineData{
+		Pagesize:      4096,
+		Hugepagesizes: []uint64{size1Gi, size2Mi},
+		Zones: []sysinfo.Zone{
+			{
+				ID: 0,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 8 * size1Gi,
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						size2Mi: {Total: freeHugepages, Free: freeHugepages},
+					},
+				},
+			},
+			{
+				ID: 1,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 8 * size1Gi,
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						size1Gi: {Total: 2, Free: 1},
+					},
+				},
+			},
+		},
+	}
+}
+
+func stepNames(rep *Report) []string {
+	var names []string
+	for _, step := range rep.Steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+func requireNoDevices(t *testing.T) {
+	t.Helper()
+	entries, err := os.ReadDir(cdi.SpecDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.False(t, strings.HasPrefix(entry.Name(), "dra.memory_"), "leftover device %q", entry.Name())
+	}
+}
+
+func TestRunHugepages(t *testing.T) {
+	cgMount := setupDirs(t)
+	rep := Run(testr.New(t), makeMachine(0), Config{DriverName: "dra.memory", CgroupMount: cgMount})
+	require.NoError(t, rep.Err())
+	require.Equal(t, []string{StepAllocate, StepCDI, StepEnvs, StepCgroup, StepLimits, StepVerify, StepCleanup}, stepNames(rep))
+	require.Equal(t, "hugepages-1Gi size=1Gi numaZone=1", rep.Steps[0].Detail)
+	require.Contains(t, rep.Steps[4].Detail, "1GB=")
+
+	entries, err := os.ReadDir(cgMount)
+	require.NoError(t, err)
+	require.Empty(t, entries, "scratch cgroup left over")
+	requireNoDevices(t)
+
+	var out bytes.Buffer
+	rep.Print(&out)
+	require.True(t, strings.HasSuffix(out.String(), "PASS cleanup: 2 objects removed\nPASS\n"), out.String())
+}
+
+func TestRunMemoryWithoutCgroups(t *testing.T) {
+	setupDirs(t)
+	machine := makeMachine(0)
+	machine.Hugepagesizes = nil
+	for idx := range machine.Zones {
+		machine.Zones[idx].Memory.HugePageAmountsBySize = nil
+	}
+	rep := Run(testr.New(t), machine, Config{DriverName: "dra.memory"})
+	require.NoError(t, rep.Err())
+	require.Equal(t, []string{StepAllocate, StepCDI, StepEnvs, StepCgroup, StepLimits, StepVerify, StepCleanup}, stepNames(rep))
+	require.Equal(t, "memory size=1Mi numaZone=0", rep.Steps[0].Detail)
+	for _, step := range rep.Steps[3:6] {
+		require.True(t, step.Skipped, "step %q", step.Name)
+	}
+	requireNoDevices(t)
+}
+
+func TestRunMemorySoftLimit(t *testing.T) {
+	cgMount := setupDirs(t)
+	machine := makeMachine(0)
+	machine.Zones = machine.Zones[:1]
+	rep := Run(testr.New(t), machine, Config{DriverName: "dra.memory", CgroupMount: cgMount})
+	require.NoError(t, rep.Err())
+	require.Contains(t, rep.Steps[4].Detail, "memory.high=1048576")
+	require.Equal(t, "5 values read back", rep.Steps[5].Detail)
+}
+
+func TestRunFailureCleansUp(t *testing.T) {
+	cgMount := setupDirs(t)
+	rep := Run(testr.New(t), makeMachine(4), Config{DriverName: "dra.memory", CgroupMount: cgMount, CgroupParent: "missing.slice"})
+	require.Error(t, rep.Err())
+	require.Equal(t, []string{StepAllocate, StepCDI, StepEnvs, StepCgroup, StepCleanup}, stepNames(rep))
+	require.Equal(t, "hugepages-2Mi size=2Mi numaZone=0", rep.Steps[0].Detail)
+	require.Error(t, rep.Steps[3].Err)
+	require.NoError(t, rep.Steps[4].Err)
+	requireNoDevices(t)
+
+	var out bytes.Buffer
+	rep.Print(&out)
+	require.True(t, strings.HasSuffix(out.String(), "\nFAIL\n"), out.String())
+}
+
+func TestPickAllocationWithoutMemory(t *testing.T) {
+	_, err := pickAllocation(sysinfo.MachineData{Zones: []sysinfo.Zone{{ID: 0}}})
+	require.Error(t, err)
+
+	alloc, err := pickAllocation(makeMachine(1))
+	require.NoError(t, err)
+	require.Equal(t, types.NewAllocation(types.ResourceIdent{Kind: types.Hugepages, Pagesize: size2Mi}, size2Mi, 0), alloc)
+}
+
+func TestRemoveCgroupMissing(t *testing.T) {
+	err := removeCgroup(filepath.Join(t.TempDir(), scratchPrefix+"0"))
+	require.Error(t, err)
+}
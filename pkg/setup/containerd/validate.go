@@ -0,0 +1,83 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package containerd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	DefaultConfigPath = "/etc/containerd/config.toml"
+
+	pluginNRI = "io.containerd.nri.v1.nri"
+	// the CRI plugin settings moved in the config version 3 (containerd 2.x)
+	pluginCRIv2 = "io.containerd.grpc.v1.cri"
+	pluginCRIv3 = "io.containerd.cri.v1.runtime"
+)
+
+var (
+	ErrNRIDisabled               = errors.New("NRI disabled in the containerd configuration")
+	ErrNRIConnectionsDisabled    = errors.New("NRI external plugins connections disabled in the containerd configuration")
+	ErrNRISocketMismatch         = errors.New("NRI socket path mismatch")
+	ErrHugeTLBControllerDisabled = errors.New("hugetlb controller disabled in the containerd configuration")
+)
+
+// ValidateConfig checks the containerd configuration `data` enables what the driver needs, which is NRI
+// on the socket `nriSocketPath` and the hugetlb controller. The settings left to the containerd defaults are
+// not checked. The errors carry the remediation hints. The imported configuration files are not checked.
+func ValidateConfig(data []byte, nriSocketPath string) error {
+	var conf map[string]any
+	err := toml.Unmarshal(data, &conf)
+	if err != nil {
+		return fmt.Errorf("malformed containerd configuration: %w", err)
+	}
+	plugins, ok := getMap(conf, "plugins")
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	if nri, ok := getMap(plugins, pluginNRI); ok {
+		if isTrue(nri, "disable") {
+			errs = append(errs, fmt.Errorf("%w: set `disable = false` in the [plugins.%q] section and restart containerd", ErrNRIDisabled, pluginNRI))
+		}
+		if isTrue(nri, "disable_connections") {
+			errs = append(errs, fmt.Errorf("%w: set `disable_connections = false` in the [plugins.%q] section and restart containerd", ErrNRIConnectionsDisabled, pluginNRI))
+		}
+		if socketPath, ok := nri["socket_path"].(string); ok && nriSocketPath != "" && socketPath != nriSocketPath {
+			errs = append(errs, fmt.Errorf("%w: containerd listens on %q, the driver connects to %q: align the containerd `socket_path` and the driver `--nri-socket-path`", ErrNRISocketMismatch, socketPath, nriSocketPath))
+		}
+	}
+	for _, pluginName := range []string{pluginCRIv2, pluginCRIv3} {
+		cri, ok := getMap(plugins, pluginName)
+		if !ok {
+			continue
+		}
+		if isTrue(cri, "disable_hugetlb_controller") {
+			errs = append(errs, fmt.Errorf("%w: set `disable_hugetlb_controller = false` in the [plugins.%q] section and restart containerd", ErrHugeTLBControllerDisabled, pluginName))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isTrue(node map[string]any, key string) bool {
+	val, ok := node[key].(bool)
+	return ok && val
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package containerd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	testcases := []struct {
+		name          string
+		config        string
+		expectedErrs  []error
+		expectedHints []string
+	}{
+		{
+			name:   "empty",
+			config: "",
+		},
+		{
+			name: "configured by setup-runtime",
+			config: `
+version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  enable_cdi = true
+  tolerate_missing_hugetlb_controller = false
+  disable_hugetlb_controller = false
+[plugins."io.containerd.nri.v1.nri"]
+  disable = false
+  disable_connections = false
+  socket_path = "/var/run/nri/nri.sock"
+`,
+		},
+		{
+			name: "defaults",
+			config: `
+version = 3
+[plugins."io.containerd.cri.v1.runtime"]
+  enable_cdi = true
+`,
+		},
+		{
+			name: "NRI disabled",
+			config: `
+version = 2
+[plugins."io.containerd.nri.v1.nri"]
+  disable = true
+  disable_connections = true
+`,
+			expectedErrs:  []error{ErrNRIDisabled, ErrNRIConnectionsDisabled},
+			expectedHints: []string{"set `disable = false`", "set `disable_connections = false`"},
+		},
+		{
+			name: "NRI socket mismatch",
+			config: `
+version = 2
+[plugins."io.containerd.nri.v1.nri"]
+  socket_path = "/run/nri/nri.sock"
+`,
+			expectedErrs:  []error{ErrNRISocketMismatch},
+			expectedHints: []string{`containerd listens on "/run/nri/nri.sock"`},
+		},
+		{
+			name: "hugetlb controller disabled, v3",
+			config: `
+version = 3
+[plugins."io.containerd.cri.v1.runtime"]
+  disable_hugetlb_controller = true
+`,
+			expectedErrs:  []error{ErrHugeTLBControllerDisabled},
+			expectedHints: []string{`[plugins."io.containerd.cri.v1.runtime"]`},
+		},
+		{
+			name:         "malformed",
+			config:       `[plugins`,
+			expectedErrs: []error{},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := ValidateConfig([]byte(tcase.config), "/var/run/nri/nri.sock")
+			if tcase.expectedErrs == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, expectedErr := range tcase.expectedErrs {
+				require.ErrorIs(t, err, expectedErr)
+			}
+			for _, expectedHint := range tcase.expectedHints {
+				require.ErrorContains(t, err, expectedHint)
+			}
+		})
+	}
+}
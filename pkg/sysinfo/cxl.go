@@ -0,0 +1,36 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+)
+
+// HasCXL returns true if the kernel enumerated any CXL device. We don't care about the kind
+// of the devices (memory expanders or else): this is meant only as coarse hint for the admins.
+func HasCXL(lh logr.Logger, sysRoot string) bool {
+	cxlPath := filepath.Join(sysRoot, "sys", "bus", "cxl", "devices")
+	entries, err := os.ReadDir(cxlPath)
+	if err != nil {
+		lh.V(4).Info("no CXL devices", "path", cxlPath, "err", err)
+		return false
+	}
+	return len(entries) > 0
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasCXL(t *testing.T) {
+	lh := testr.New(t)
+	sysRoot := t.TempDir()
+	require.False(t, HasCXL(lh, sysRoot))
+
+	cxlPath := filepath.Join(sysRoot, "sys", "bus", "cxl", "devices")
+	require.NoError(t, os.MkdirAll(cxlPath, 0755))
+	require.False(t, HasCXL(lh, sysRoot))
+
+	require.NoError(t, os.Mkdir(filepath.Join(cxlPath, "mem0"), 0755))
+	require.True(t, HasCXL(lh, sysRoot))
+}
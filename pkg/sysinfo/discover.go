@@ -20,37 +20,86 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 
+	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 
+	"github.com/ffromani/dra-driver-memory/pkg/guardband"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/backing"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/exclude"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/subpools"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
 )
 
 type Discoverer struct {
 	// GetMachineData is overridable to enable testing.
 	// We expect the vast majority of cases to be fine with default.
-	GetMachineData     GetMachineDataFunc
-	sysRoot            string
+	GetMachineData GetMachineDataFunc
+	// AlignmentAttributes enables the attributes to align the devices with the devices of other drivers.
+	AlignmentAttributes bool
+	// PagesCapacity enables the capacity in pages of the hugepages devices, alongside the capacity in bytes.
+	PagesCapacity bool
+	// RetiredGracePeriod is how long the devices withdrawn by a refresh are still resolvable, for the claims
+	// allocated before the refresh and prepared after it. Zero forgets the withdrawn devices at once.
+	RetiredGracePeriod time.Duration
+	// DeviceNaming is how the devices are named. The stable naming requires NodeName.
+	DeviceNaming DeviceNaming
+	NodeName     string
+	// SubPools splits the hugepages pools of the zones into devices of the named sub-pools. Nil publishes the whole pools.
+	SubPools *subpools.Config
+	// ExcludedHugepages are the hugepages left out of the devices, because consumed by other means,
+	// like the extended resources of the kubelet.
+	ExcludedHugepages exclude.Exclusions
+	// MemoryGuardBands are the memory of each zone left out of the devices, for the kernel and the device drivers.
+	MemoryGuardBands guardband.Bands
+	sysRoot          string
+	// mu guards the discovered state below, refreshed at runtime e.g. after provisioning the hugepages
+	mu                 sync.RWMutex
 	machineData        MachineData
 	spanByDeviceName   map[string]types.Span
 	deviceTypeToSlices map[string]resourceslice.Slice
+	retiredDevices     map[string]retiredDevice
+	// zoneOfGoneDevice is the zone of the devices withdrawn with all the devices of their zone,
+	// kept past the grace period to tell why the claims allocated on them can't be prepared
+	zoneOfGoneDevice map[string]int64
+	// now is overridable to enable testing
+	now func() time.Time
+}
+
+// DefaultRetiredGracePeriod covers the time the scheduler takes to notice the refreshed slices,
+// plus the time the kubelet takes to prepare the claims allocated just before.
+const DefaultRetiredGracePeriod = 10 * time.Minute
+
+// retiredDevice is a device withdrawn by a refresh, still resolvable until the deadline.
+type retiredDevice struct {
+	span     types.Span
+	deadline time.Time
 }
 
 type GetMachineDataFunc func(logr.Logger, string) (MachineData, error)
 
 func NewDiscoverer(sysRoot string) *Discoverer {
 	ds := &Discoverer{
-		GetMachineData: GetMachineData,
-		sysRoot:        sysRoot,
+		GetMachineData:     GetMachineData,
+		RetiredGracePeriod: DefaultRetiredGracePeriod,
+		DeviceNaming:       DeviceNamingRandom,
+		sysRoot:            sysRoot,
+		retiredDevices:     make(map[string]retiredDevice),
+		zoneOfGoneDevice:   make(map[string]int64),
+		now:                time.Now,
 	}
 	ds.reset()
 	return ds
 }
 
 func (ds *Discoverer) AllResourceNames() sets.Set[string] {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	resourceNames := sets.New[string]()
 	for span := range maps.Values(ds.spanByDeviceName) {
 		resourceNames.Insert(span.Name())
@@ -58,7 +107,16 @@ func (ds *Discoverer) AllResourceNames() sets.Set[string] {
 	return resourceNames
 }
 
+// Spans returns the spans of the devices currently published, by device name.
+func (ds *Discoverer) Spans() map[string]types.Span {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return maps.Clone(ds.spanByDeviceName)
+}
+
 func (ds *Discoverer) GetCachedMachineData() MachineData {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	return ds.machineData
 }
 
@@ -66,13 +124,38 @@ func (ds *Discoverer) GetFreshMachineData(lh logr.Logger) (MachineData, error) {
 	return ds.GetMachineData(lh, ds.sysRoot)
 }
 
+// GetSpanForDevice returns the span of the device, which can also be a device withdrawn by a refresh
+// within the grace period: the device names change at each refresh, but the claims already allocated
+// still reference the old names.
 func (ds *Discoverer) GetSpanForDevice(lh logr.Logger, devName string) (types.Span, error) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	span, ok := ds.spanByDeviceName[devName]
-	if !ok {
+	if ok {
+		lh.V(4).Info("device span", "devName", devName, "span", span.String())
+		return span, nil
+	}
+	retired, ok := ds.retiredDevices[devName]
+	if !ok || ds.now().After(retired.deadline) {
 		return types.Span{}, fmt.Errorf("device %q not matches any registered memory span", devName)
 	}
-	lh.V(4).Info("device span", "devName", devName, "span", span.String())
-	return span, nil
+	lh.V(2).Info("retired device span", "devName", devName, "span", retired.span.String(), "deadline", retired.deadline)
+	return retired.span, nil
+}
+
+// ZoneOfDevice returns the NUMA zone of the device, which can also be a device withdrawn by a refresh,
+// even past the grace period if all the devices of its zone were withdrawn.
+func (ds *Discoverer) ZoneOfDevice(devName string) (int64, bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if span, ok := ds.spanByDeviceName[devName]; ok {
+		return span.NUMAZone, true
+	}
+	if retired, ok := ds.retiredDevices[devName]; ok {
+		return retired.span.NUMAZone, true
+	}
+	zoneID, ok := ds.zoneOfGoneDevice[devName]
+	return zoneID, ok
 }
 
 func (ds *Discoverer) Refresh(lh logr.Logger) error {
@@ -80,17 +163,91 @@ func (ds *Discoverer) Refresh(lh logr.Logger) error {
 	if err != nil {
 		return err
 	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	previous := ds.spanByDeviceName
+	ds.retire(lh)
 	ds.reset()
 	ds.processMachine(lh, machineData)
+	ds.trackGoneZones(previous)
 	ds.machineData = machineData
 	ds.logMachine(lh)
 	return nil
 }
 
 func (ds *Discoverer) ResourceSlices() []resourceslice.Slice {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 	return slices.Collect(maps.Values(ds.deviceTypeToSlices))
 }
 
+// ResourceSlicesWithFreeCapacity returns the resource slices with the free capacity attributes
+// added to all the devices. `remaining` holds the bytes not allocated by device name; the devices
+// missing are entirely free. The hugepages devices whose pool is found in `pools` get the pool attributes as well.
+func (ds *Discoverer) ResourceSlicesWithFreeCapacity(remaining map[string]int64, pools HugepagesPools) []resourceslice.Slice {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	ret := make([]resourceslice.Slice, 0, len(ds.deviceTypeToSlices))
+	for _, slice := range ds.deviceTypeToSlices {
+		devices := make([]resourceapi.Device, 0, len(slice.Devices))
+		for idx := range slice.Devices {
+			dev := slice.Devices[idx].DeepCopy()
+			span, ok := ds.spanByDeviceName[dev.Name]
+			if ok {
+				free, ok := remaining[dev.Name]
+				if !ok {
+					free = span.Amount
+				}
+				maps.Copy(dev.Attributes, MakeFreeCapacityAttributes(span, span.Amount-free))
+				if pool, ok := pools[span.Name()][span.NUMAZone]; ok && span.NeedsHugeTLB() {
+					maps.Copy(dev.Attributes, MakePoolAttributes(pool))
+				}
+			}
+			devices = append(devices, *dev)
+		}
+		slice.Devices = devices
+		ret = append(ret, slice)
+	}
+	return ret
+}
+
+// retire keeps the current devices resolvable for the grace period, and forgets the devices past it.
+func (ds *Discoverer) retire(lh logr.Logger) {
+	now := ds.now()
+	for devName, retired := range ds.retiredDevices {
+		if now.After(retired.deadline) {
+			delete(ds.retiredDevices, devName)
+		}
+	}
+	if ds.RetiredGracePeriod <= 0 {
+		return
+	}
+	deadline := now.Add(ds.RetiredGracePeriod)
+	for devName, span := range ds.spanByDeviceName {
+		ds.retiredDevices[devName] = retiredDevice{span: span, deadline: deadline}
+	}
+	lh.V(4).Info("retired devices", "count", len(ds.spanByDeviceName), "deadline", deadline)
+}
+
+// trackGoneZones remembers the zone of the previous devices whose zone has no devices anymore,
+// and forgets the devices whose zone has devices again.
+func (ds *Discoverer) trackGoneZones(previous map[string]types.Span) {
+	zones := sets.New[int64]()
+	for _, span := range ds.spanByDeviceName {
+		zones.Insert(span.NUMAZone)
+	}
+	for devName, zoneID := range ds.zoneOfGoneDevice {
+		if zones.Has(zoneID) {
+			delete(ds.zoneOfGoneDevice, devName)
+		}
+	}
+	for devName, span := range previous {
+		if !zones.Has(span.NUMAZone) {
+			ds.zoneOfGoneDevice[devName] = span.NUMAZone
+		}
+	}
+}
+
 func (ds *Discoverer) reset() {
 	ds.spanByDeviceName = make(map[string]types.Span)
 	ds.deviceTypeToSlices = make(map[string]resourceslice.Slice)
@@ -99,6 +256,7 @@ func (ds *Discoverer) reset() {
 // processMachine receives MachineData and creates resource slices out of it, plus a device:numaNode mapping.
 // This function cannot really fail and never returns invalid data but it can return empty data.
 func (ds *Discoverer) processMachine(lh logr.Logger, machine MachineData) {
+	excluded := ds.resolveExclusions(machine)
 	for numaNode, nodeInfo := range machine.Zones {
 		if nodeInfo.Memory == nil {
 			lh.V(2).Info("NUMA node %d reports no memory", numaNode)
@@ -106,11 +264,38 @@ func (ds *Discoverer) processMachine(lh logr.Logger, machine MachineData) {
 		}
 		ds.processMemory(lh, machine.Pagesize, int64(numaNode), nodeInfo)
 		for _, hpSize := range sortedHugepageSizes(nodeInfo) {
-			ds.processHugepages(lh, hpSize, int64(numaNode), nodeInfo)
+			ds.processHugepages(lh, hpSize, int64(numaNode), nodeInfo, excluded[hpSize][int64(numaNode)], machine.HugepagesBacking)
 		}
 	}
 }
 
+// resolveExclusions returns the bytes of hugepages to leave out of the devices, by page size and NUMA zone.
+func (ds *Discoverer) resolveExclusions(machine MachineData) map[uint64]map[int64]int64 {
+	if len(ds.ExcludedHugepages) == 0 {
+		return nil
+	}
+	pagesBySize := make(map[uint64]map[int64]int64)
+	for numaNode, nodeInfo := range machine.Zones {
+		if nodeInfo.Memory == nil {
+			continue
+		}
+		for hpSize, amounts := range nodeInfo.Memory.HugePageAmountsBySize {
+			if amounts == nil {
+				continue
+			}
+			if pagesBySize[hpSize] == nil {
+				pagesBySize[hpSize] = make(map[int64]int64)
+			}
+			pagesBySize[hpSize][int64(numaNode)] = amounts.Total
+		}
+	}
+	ret := make(map[uint64]map[int64]int64, len(pagesBySize))
+	for hpSize, pagesByZone := range pagesBySize {
+		ret[hpSize] = ds.ExcludedHugepages.Resolve(hpSize, pagesByZone)
+	}
+	return ret
+}
+
 func sortedHugepageSizes(nodeInfo Zone) []uint64 {
 	var sizeInBytes []uint64
 	for sz := range nodeInfo.Memory.HugePageAmountsBySize {
@@ -121,26 +306,38 @@ func sortedHugepageSizes(nodeInfo Zone) []uint64 {
 }
 
 func (ds *Discoverer) processMemory(lh logr.Logger, pageSize uint64, numaNode int64, nodeInfo Zone) {
-	if nodeInfo.Memory.TotalUsableBytes == 0 {
+	// the usable memory includes the hugepages pools, also if provisioned at runtime after boot,
+	// but the hugepages are advertised by their own devices; don't count twice the same DRAM.
+	amount := nodeInfo.Memory.TotalUsableBytes - hugepagesBytes(nodeInfo)
+	if amount <= 0 {
 		lh.V(4).Info("discovery: no usable memory detected, skipped", "numaNode", numaNode)
 		return
 	}
+	guardBand := min(ds.MemoryGuardBands.For(numaNode), amount)
+	amount -= guardBand
+	if amount <= 0 {
+		lh.V(2).Info("discovery: all the usable memory in the guard band, skipped", "numaNode", numaNode, "guardBand", guardBand)
+		return
+	}
 	span := types.Span{
 		ResourceIdent: types.ResourceIdent{
 			Kind:     types.Memory,
 			Pagesize: pageSize,
 		},
-		Amount:   nodeInfo.Memory.TotalUsableBytes,
+		Amount:   amount,
 		NUMAZone: numaNode,
 	}
-	memDevice := ToDevice(span)
+	memDevice := ds.makeDevice(span, nodeInfo)
+	if guardBand > 0 {
+		memDevice.Attributes[GuardBandSizeAttribute] = MakeGuardBandSizeAttribute(guardBand)
+	}
 	ds.spanByDeviceName[memDevice.Name] = span
 	memorySlice := ds.deviceTypeToSlices[span.Name()]
 	memorySlice.Devices = append(memorySlice.Devices, memDevice)
 	ds.deviceTypeToSlices[span.Name()] = memorySlice
 }
 
-func (ds *Discoverer) processHugepages(lh logr.Logger, hpSize uint64, numaNode int64, nodeInfo Zone) {
+func (ds *Discoverer) processHugepages(lh logr.Logger, hpSize uint64, numaNode int64, nodeInfo Zone, excluded int64, cmdline *backing.Cmdline) {
 	amounts, ok := nodeInfo.Memory.HugePageAmountsBySize[hpSize]
 	if !ok || amounts.Total == 0 {
 		lh.V(4).Info("discovery: no hugepages detected, skipped", "numaNode", numaNode, "hugepageSize", hpSize)
@@ -151,21 +348,87 @@ func (ds *Discoverer) processHugepages(lh logr.Logger, hpSize uint64, numaNode i
 			Kind:     types.Hugepages,
 			Pagesize: hpSize,
 		},
-		Amount:   int64(hpSize) * amounts.Total,
+		Amount:   int64(hpSize)*amounts.Total - excluded,
 		NUMAZone: numaNode,
 	}
-	hpDevice := ToDevice(span)
-	ds.spanByDeviceName[hpDevice.Name] = span
+	if span.Amount <= 0 {
+		lh.V(4).Info("discovery: all hugepages excluded, skipped", "numaNode", numaNode, "hugepageSize", hpSize)
+		return
+	}
+	spans := []types.Span{span}
+	if parts := ds.SubPools.Split(lh, span.ResourceIdent, numaNode, span.Amount); len(parts) > 0 {
+		spans = make([]types.Span, 0, len(parts))
+		for _, part := range parts {
+			spans = append(spans, types.Span{
+				ResourceIdent: span.ResourceIdent,
+				Amount:        part.Amount,
+				NUMAZone:      numaNode,
+				Pool:          part.Name,
+			})
+		}
+	}
 	hugepageSlice := ds.deviceTypeToSlices[span.Name()]
-	hugepageSlice.Devices = append(hugepageSlice.Devices, hpDevice)
+	for _, sp := range spans {
+		hpDevice := ds.makeDevice(sp, nodeInfo)
+		if excluded > 0 {
+			hpDevice.Attributes[ExcludedSizeAttribute] = MakeExcludedSizeAttribute(excluded)
+		}
+		if cmdline != nil {
+			hpDevice.Attributes[PoolBackingAttribute] = MakePoolBackingAttribute(cmdline.BackingOf(hpSize, numaNode))
+		}
+		ds.spanByDeviceName[hpDevice.Name] = sp
+		hugepageSlice.Devices = append(hugepageSlice.Devices, hpDevice)
+	}
 	ds.deviceTypeToSlices[span.Name()] = hugepageSlice
 }
 
+func (ds *Discoverer) makeDevice(span types.Span, nodeInfo Zone) resourceapi.Device {
+	dev := ToDevice(span, ds.localityOf(nodeInfo))
+	if ds.DeviceNaming == DeviceNamingStable {
+		dev.Name = ds.stableDeviceName(span)
+	}
+	if hpSize := nodeInfo.Memory.DefaultHugePageSize; hpSize > 0 {
+		dev.Attributes[DefaultHugePageSizeAttribute] = MakeDefaultHugePageSizeAttribute(hpSize)
+	}
+	if ds.PagesCapacity && span.NeedsHugeTLB() {
+		dev.Capacity[types.CapacityNamePages] = MakePagesCapacity(span)
+	}
+	return dev
+}
+
+// stableDeviceName returns the stable name of the device of the span, unique among the devices discovered so far.
+func (ds *Discoverer) stableDeviceName(span types.Span) string {
+	for attempt := 0; ; attempt++ {
+		name := MakeStableDeviceName(ds.NodeName, span, attempt)
+		if _, ok := ds.spanByDeviceName[name]; !ok {
+			return name
+		}
+	}
+}
+
+func hugepagesBytes(nodeInfo Zone) int64 {
+	var total int64
+	for hpSize, amounts := range nodeInfo.Memory.HugePageAmountsBySize {
+		if amounts == nil {
+			continue
+		}
+		total += int64(hpSize) * amounts.Total
+	}
+	return total
+}
+
+func (ds *Discoverer) localityOf(nodeInfo Zone) *Locality {
+	if !ds.AlignmentAttributes {
+		return nil
+	}
+	return nodeInfo.Locality
+}
+
 func (ds *Discoverer) logMachine(lh logr.Logger) {
 	if !lh.V(4).Enabled() {
 		return
 	}
 	for devName, devSpan := range ds.spanByDeviceName {
-		lh.V(4).Info("Devices mapping", "device", devName, "deviceType", devSpan.Name(), "NUMANode", devSpan.NUMAZone)
+		lh.V(4).Info("Devices mapping", "device", devName, "deviceType", devSpan.Name(), "NUMANode", devSpan.NUMAZone, "pool", devSpan.Pool)
 	}
 }
@@ -19,9 +19,12 @@
 package sysinfo
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
@@ -35,6 +38,10 @@ import (
 	"k8s.io/dynamic-resource-allocation/resourceslice"
 	"k8s.io/utils/ptr"
 
+	"github.com/ffromani/dra-driver-memory/pkg/guardband"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/backing"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/exclude"
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/subpools"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
 )
 
@@ -74,9 +81,10 @@ func TestRefreshWithData(t *testing.T) {
 						{
 							Name: "memory-XXXXXX",
 							Attributes: makeAttributes(attrInfo{
-								numaNode: 0,
-								sizeName: "4Ki",
-								hugeTLB:  false,
+								numaNode:            0,
+								sizeName:            "4Ki",
+								hugeTLB:             false,
+								defaultHugePageSize: "2Mi",
 							}),
 							Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
 								"size": {
@@ -132,9 +140,10 @@ func TestRefreshWithData(t *testing.T) {
 						{
 							Name: "hugepages-1gi-XXXXXX",
 							Attributes: makeAttributes(attrInfo{
-								numaNode: 0,
-								sizeName: "1Gi",
-								hugeTLB:  true,
+								numaNode:            0,
+								sizeName:            "1Gi",
+								hugeTLB:             true,
+								defaultHugePageSize: "2Mi",
 							}),
 							Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
 								"size": {
@@ -158,9 +167,10 @@ func TestRefreshWithData(t *testing.T) {
 						{
 							Name: "hugepages-2mi-XXXXXX",
 							Attributes: makeAttributes(attrInfo{
-								numaNode: 0,
-								sizeName: "2Mi",
-								hugeTLB:  true,
+								numaNode:            0,
+								sizeName:            "2Mi",
+								hugeTLB:             true,
+								defaultHugePageSize: "2Mi",
 							}),
 							Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
 								"size": {
@@ -184,18 +194,19 @@ func TestRefreshWithData(t *testing.T) {
 						{
 							Name: "memory-XXXXXX",
 							Attributes: makeAttributes(attrInfo{
-								numaNode: 0,
-								sizeName: "4Ki",
-								hugeTLB:  false,
+								numaNode:            0,
+								sizeName:            "4Ki",
+								hugeTLB:             false,
+								defaultHugePageSize: "2Mi",
 							}),
 							Capacity: map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
 								"size": {
-									Value: *resource.NewQuantity(33332322304, resource.BinarySI),
+									Value: *resource.NewQuantity(20447420416, resource.BinarySI),
 									RequestPolicy: &resourceapi.CapacityRequestPolicy{
 										Default: resource.NewQuantity(1<<20, resource.BinarySI),
 										ValidRange: &resourceapi.CapacityRequestPolicyRange{
 											Min:  resource.NewQuantity(4*1<<10, resource.BinarySI),
-											Max:  resource.NewQuantity(33332322304, resource.BinarySI),
+											Max:  resource.NewQuantity(20447420416, resource.BinarySI),
 											Step: resource.NewQuantity(4*1<<10, resource.BinarySI),
 										},
 									},
@@ -251,6 +262,56 @@ func TestRefreshWithData(t *testing.T) {
 	}
 }
 
+func TestRefreshAfterHugepagesProvisioning(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+
+	saveMakeDeviceName := MakeDeviceName
+	t.Cleanup(func() {
+		MakeDeviceName = saveMakeDeviceName
+	})
+	MakeDeviceName = makeTestDeviceName
+
+	logger := testr.New(t)
+
+	// the hugepages are provisioned at runtime, so the usable memory doesn't change,
+	// but part of it is now reserved for the hugepages pool.
+	memArea := ghwmemory.Area{
+		TotalUsableBytes: 8 * (1 << 30),
+	}
+	machine := MachineData{
+		Pagesize: 4096,
+		Zones: []Zone{
+			{
+				ID:        0,
+				Distances: []int{10},
+				Memory:    &memArea,
+			},
+		},
+	}
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		return machine, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+	span, err := disc.GetSpanForDevice(logger, "memory-XXXXXX")
+	require.NoError(t, err)
+	require.Equal(t, int64(8*(1<<30)), span.Amount)
+
+	memArea.HugePageAmountsBySize = map[uint64]*ghwmemory.HugePageAmounts{
+		2 * (1 << 20): {
+			Total: 1024,
+		},
+	}
+	require.NoError(t, disc.Refresh(logger))
+	span, err = disc.GetSpanForDevice(logger, "memory-XXXXXX")
+	require.NoError(t, err)
+	require.Equal(t, int64(6*(1<<30)), span.Amount, "memory must not include the hugepages pool")
+	span, err = disc.GetSpanForDevice(logger, "hugepages-2mi-XXXXXX")
+	require.NoError(t, err)
+	require.Equal(t, int64(2*(1<<30)), span.Amount)
+}
+
 func TestGetFreshMachineData(t *testing.T) {
 	fakeSysRoot := t.TempDir()
 	logger := testr.New(t)
@@ -292,6 +353,396 @@ func TestGetSpanForDeviceNotFound(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestGetSpanForDeviceRetired(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	// the device names change at each refresh, like with the random suffix
+	refreshes := 0
+	saveMakeDeviceName := MakeDeviceName
+	t.Cleanup(func() {
+		MakeDeviceName = saveMakeDeviceName
+	})
+	MakeDeviceName = func(devName string) string {
+		return strings.ToLower(devName) + "-" + strconv.Itoa(refreshes)
+	}
+
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.RetiredGracePeriod = 5 * time.Minute
+	disc.now = func() time.Time { return now }
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		return MachineData{
+			Pagesize: 4096,
+			Zones: []Zone{
+				{
+					ID: 0,
+					Memory: &ghwmemory.Area{
+						TotalUsableBytes: 16 * (1 << 30),
+					},
+				},
+			},
+		}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+	oldSpan, err := disc.GetSpanForDevice(logger, "memory-0")
+	require.NoError(t, err)
+
+	refreshes++
+	now = now.Add(time.Minute)
+	require.NoError(t, disc.Refresh(logger))
+	_, err = disc.GetSpanForDevice(logger, "memory-1")
+	require.NoError(t, err)
+	span, err := disc.GetSpanForDevice(logger, "memory-0")
+	require.NoError(t, err, "retired devices must be resolvable within the grace period")
+	require.Equal(t, oldSpan, span)
+
+	now = now.Add(6 * time.Minute)
+	_, err = disc.GetSpanForDevice(logger, "memory-0")
+	require.Error(t, err, "retired devices must not be resolvable past the grace period")
+
+	refreshes++
+	require.NoError(t, disc.Refresh(logger))
+	require.Len(t, disc.retiredDevices, 1, "expired devices must be forgotten")
+}
+
+func TestZoneOfDeviceGoneZone(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	// the device names change at each refresh, like with the random suffix
+	saveMakeDeviceName := MakeDeviceName
+	t.Cleanup(func() {
+		MakeDeviceName = saveMakeDeviceName
+	})
+	MakeDeviceName = makeTestDeviceNamer()
+
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	zone := func(zoneID int) Zone {
+		return Zone{
+			ID: zoneID,
+			Memory: &ghwmemory.Area{
+				TotalUsableBytes: 16 * (1 << 30),
+			},
+		}
+	}
+	zones := []Zone{zone(0), zone(1)}
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.RetiredGracePeriod = 5 * time.Minute
+	disc.now = func() time.Time { return now }
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		return MachineData{Pagesize: 4096, Zones: zones}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+	devNames := map[int64]string{}
+	for devName, span := range disc.Spans() {
+		devNames[span.NUMAZone] = devName
+	}
+	require.Len(t, devNames, 2)
+
+	// the zone 1 is gone, and the grace period expires
+	zones = []Zone{zone(0)}
+	require.NoError(t, disc.Refresh(logger))
+	now = now.Add(6 * time.Minute)
+	require.NoError(t, disc.Refresh(logger))
+
+	_, err := disc.GetSpanForDevice(logger, devNames[1])
+	require.Error(t, err)
+	zoneID, ok := disc.ZoneOfDevice(devNames[1])
+	require.True(t, ok, "the devices of the gone zones must be resolvable past the grace period")
+	require.Equal(t, int64(1), zoneID)
+	_, ok = disc.ZoneOfDevice(devNames[0])
+	require.False(t, ok, "the devices of the zones still present must be forgotten past the grace period")
+
+	// the zone 1 is back
+	zones = []Zone{zone(0), zone(1)}
+	require.NoError(t, disc.Refresh(logger))
+	require.Empty(t, disc.zoneOfGoneDevice)
+}
+
+func TestRefreshWithStableDeviceNames(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.DeviceNaming = DeviceNamingStable
+	disc.NodeName = "worker-0"
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		zone := func(zoneID int) Zone {
+			return Zone{
+				ID: zoneID,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 16 * (1 << 30),
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * (1 << 20): {
+							Total: 512,
+						},
+					},
+				},
+			}
+		}
+		return MachineData{
+			Pagesize: 4096,
+			Zones:    []Zone{zone(0), zone(1)},
+		}, nil
+	}
+
+	deviceNames := func() []string {
+		var names []string
+		for _, slice := range disc.ResourceSlices() {
+			for _, dev := range slice.Devices {
+				names = append(names, dev.Name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.NoError(t, disc.Refresh(logger))
+	names := deviceNames()
+	require.Len(t, names, 4)
+	require.Len(t, sets.New(names...), 4, "device names must be unique")
+
+	// like a restart of the driver
+	require.NoError(t, disc.Refresh(logger))
+	require.Equal(t, names, deviceNames(), "device names must be stable")
+}
+
+func TestRefreshWithSubPools(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.DeviceNaming = DeviceNamingStable
+	disc.NodeName = "worker-0"
+	disc.SubPools = &subpools.Config{
+		Pools: []subpools.Pool{
+			{Name: "dpdk", Resource: "hugepages-2Mi", Size: resource.MustParse("256Mi"), NUMAZones: []int64{0}},
+		},
+	}
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		zone := func(zoneID int) Zone {
+			return Zone{
+				ID: zoneID,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 16 * (1 << 30),
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * (1 << 20): {
+							Total: 512,
+						},
+					},
+				},
+			}
+		}
+		return MachineData{
+			Pagesize: 4096,
+			Zones:    []Zone{zone(0), zone(1)},
+		}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+
+	type poolZone struct {
+		pool     string
+		numaZone int64
+	}
+	got := make(map[poolZone]int64)
+	for _, slice := range disc.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			span, err := disc.GetSpanForDevice(logger, dev.Name)
+			require.NoError(t, err)
+			if !span.NeedsHugeTLB() {
+				continue
+			}
+			got[poolZone{pool: span.Pool, numaZone: span.NUMAZone}] = span.Amount
+			if span.Pool == "" {
+				require.NotContains(t, dev.Attributes, PoolAttribute, "device %q", dev.Name)
+				continue
+			}
+			require.Equal(t, span.Pool, ptr.Deref(dev.Attributes[PoolAttribute].StringValue, ""), "device %q", dev.Name)
+		}
+	}
+	require.Equal(t, map[poolZone]int64{
+		{pool: "dpdk", numaZone: 0}:    256 * (1 << 20),
+		{pool: "general", numaZone: 0}: 768 * (1 << 20),
+		{pool: "", numaZone: 1}:        1 << 30,
+	}, got)
+}
+
+func TestRefreshWithExcludedHugepages(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.ExcludedHugepages = exclude.Exclusions{
+		{ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 2 * (1 << 20)}, NUMAZone: exclude.AnyZone, Amount: 128 * (1 << 20)},
+		{ResourceIdent: types.ResourceIdent{Kind: types.Hugepages, Pagesize: 1 << 30}, NUMAZone: 1, Amount: 2 * (1 << 30)},
+	}
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		zone := func(zoneID int) Zone {
+			return Zone{
+				ID: zoneID,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 16 * (1 << 30),
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * (1 << 20): {
+							Total: 512,
+						},
+						1 << 30: {
+							Total: 2,
+						},
+					},
+				},
+			}
+		}
+		return MachineData{
+			Pagesize: 4096,
+			Zones:    []Zone{zone(0), zone(1)},
+		}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+
+	type sizeZone struct {
+		name     string
+		numaZone int64
+	}
+	got := make(map[sizeZone]int64)
+	for _, slice := range disc.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			span, err := disc.GetSpanForDevice(logger, dev.Name)
+			require.NoError(t, err)
+			if !span.NeedsHugeTLB() {
+				continue
+			}
+			got[sizeZone{name: span.Name(), numaZone: span.NUMAZone}] = span.Amount
+			if span.Pagesize == 2*(1<<20) {
+				require.Equal(t, "64Mi", ptr.Deref(dev.Attributes[ExcludedSizeAttribute].StringValue, ""), "device %q", dev.Name)
+				continue
+			}
+			require.NotContains(t, dev.Attributes, ExcludedSizeAttribute, "device %q", dev.Name)
+		}
+	}
+	// the 128Mi of 2Mi pages of any zone are spread on the two zones, 1Gi-64Mi each.
+	// the 1Gi pages of the zone 1 are all excluded, so there is no device for them
+	require.Equal(t, map[sizeZone]int64{
+		{name: "hugepages-2Mi", numaZone: 0}: 960 * (1 << 20),
+		{name: "hugepages-2Mi", numaZone: 1}: 960 * (1 << 20),
+		{name: "hugepages-1Gi", numaZone: 0}: 2 * (1 << 30),
+	}, got)
+}
+
+func TestRefreshWithMemoryGuardBands(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.MemoryGuardBands = guardband.Bands{
+		{NUMAZone: guardband.AnyZone, Amount: 256 * (1 << 20)},
+		{NUMAZone: 0, Amount: 2 * (1 << 30)},
+		{NUMAZone: 2, Amount: 8 * (1 << 30)},
+	}
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		zone := func(zoneID int) Zone {
+			return Zone{
+				ID: zoneID,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 8 * (1 << 30),
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * (1 << 20): {
+							Total: 512,
+						},
+					},
+				},
+			}
+		}
+		return MachineData{
+			Pagesize: 4096,
+			Zones:    []Zone{zone(0), zone(1), zone(2)},
+		}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+
+	got := make(map[int64]int64)
+	for _, slice := range disc.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			span, err := disc.GetSpanForDevice(logger, dev.Name)
+			require.NoError(t, err)
+			if span.NeedsHugeTLB() {
+				// the guard bands are of the memory only
+				require.Equal(t, int64(1<<30), span.Amount)
+				require.NotContains(t, dev.Attributes, GuardBandSizeAttribute, "device %q", dev.Name)
+				continue
+			}
+			got[span.NUMAZone] = span.Amount
+			expected := map[int64]string{0: "2Gi", 1: "256Mi"}[span.NUMAZone]
+			require.Equal(t, expected, ptr.Deref(dev.Attributes[GuardBandSizeAttribute].StringValue, ""), "device %q", dev.Name)
+		}
+	}
+	// the memory of the zone 2 is all in the guard band, so there is no memory device for it
+	require.Equal(t, map[int64]int64{
+		0: 5 * (1 << 30),
+		1: 7*(1<<30) - 256*(1<<20),
+	}, got)
+}
+
+func TestRefreshWithPoolBacking(t *testing.T) {
+	fakeSysRoot := t.TempDir()
+	logger := testr.New(t)
+
+	cmdline, err := backing.Parse("hugepagesz=1G hugepages=0:4 hugetlb_cma=1:4G", 2*(1<<20))
+	require.NoError(t, err)
+
+	disc := NewDiscoverer(fakeSysRoot)
+	disc.GetMachineData = func(_ logr.Logger, _ string) (MachineData, error) {
+		zone := func(zoneID int) Zone {
+			return Zone{
+				ID: zoneID,
+				Memory: &ghwmemory.Area{
+					TotalUsableBytes: 16 * (1 << 30),
+					HugePageAmountsBySize: map[uint64]*ghwmemory.HugePageAmounts{
+						2 * (1 << 20): {
+							Total: 512,
+						},
+						1 << 30: {
+							Total: 4,
+						},
+					},
+				},
+			}
+		}
+		return MachineData{
+			Pagesize:         4096,
+			Zones:            []Zone{zone(0), zone(1), zone(2)},
+			HugepagesBacking: &cmdline,
+		}, nil
+	}
+	require.NoError(t, disc.Refresh(logger))
+
+	type sizeZone struct {
+		name     string
+		numaZone int64
+	}
+	got := make(map[sizeZone]string)
+	for _, slice := range disc.ResourceSlices() {
+		for _, dev := range slice.Devices {
+			span, err := disc.GetSpanForDevice(logger, dev.Name)
+			require.NoError(t, err)
+			if !span.NeedsHugeTLB() {
+				require.NotContains(t, dev.Attributes, PoolBackingAttribute, "device %q", dev.Name)
+				continue
+			}
+			got[sizeZone{name: span.Name(), numaZone: span.NUMAZone}] = ptr.Deref(dev.Attributes[PoolBackingAttribute].StringValue, "")
+		}
+	}
+	require.Equal(t, map[sizeZone]string{
+		{name: "hugepages-1Gi", numaZone: 0}: "boot",
+		{name: "hugepages-1Gi", numaZone: 1}: "cma",
+		{name: "hugepages-1Gi", numaZone: 2}: "runtime",
+		{name: "hugepages-2Mi", numaZone: 0}: "runtime",
+		{name: "hugepages-2Mi", numaZone: 1}: "runtime",
+		{name: "hugepages-2Mi", numaZone: 2}: "runtime",
+	}, got)
+}
+
 func TestGetSpanForDevice(t *testing.T) {
 	type testcase struct {
 		name     string
@@ -362,22 +813,41 @@ func TestGetSpanForDevice(t *testing.T) {
 }
 
 type attrInfo struct {
-	numaNode int64
-	sizeName string
-	hugeTLB  bool
+	numaNode            int64
+	sizeName            string
+	hugeTLB             bool
+	defaultHugePageSize string
 }
 
 func makeAttributes(info attrInfo) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	pNode := ptr.To(info.numaNode)
-	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+	attrs := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 		"resource.kubernetes.io/numaNode": {IntValue: pNode},
 		"resource.kubernetes.io/pageSize": {StringValue: ptr.To(info.sizeName)},
 		"resource.kubernetes.io/hugeTLB":  {BoolValue: ptr.To(info.hugeTLB)},
 		"dra.cpu/numaNodeID":              {IntValue: pNode},
 		"dra.net/numaNode":                {IntValue: pNode},
 	}
+	if !info.hugeTLB {
+		attrs["softLimit"] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	if info.defaultHugePageSize != "" {
+		attrs["defaultHugePageSize"] = resourceapi.DeviceAttribute{StringValue: ptr.To(info.defaultHugePageSize)}
+	}
+	return attrs
 }
 
 func makeTestDeviceName(devName string) string {
 	return strings.ToLower(devName) + "-XXXXXX"
 }
+
+// makeTestDeviceNamer returns a predictable MakeDeviceName for the machines with many zones,
+// numbering the devices of the same type in creation order, e.g. zone order.
+func makeTestDeviceNamer() func(devName string) string {
+	count := make(map[string]int)
+	return func(devName string) string {
+		name := strings.ToLower(devName)
+		count[name]++
+		return fmt.Sprintf("%s-%06d", name, count[name]-1)
+	}
+}
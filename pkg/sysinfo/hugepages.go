@@ -15,6 +15,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"golang.org/x/sys/unix"
+
+	"github.com/ffromani/dra-driver-memory/pkg/types"
 )
 
 func HugepageSizes(lh logr.Logger, sysRoot string) []string {
@@ -88,3 +90,42 @@ func getHugepageSizeFromFilenames(fileNames []string) ([]string, error) {
 
 	return pageSizes, warn
 }
+
+// HugepagesPool holds the kernel counters of the hugepages pool of a NUMA zone, in pages.
+type HugepagesPool struct {
+	Total int64
+	Free  int64
+}
+
+// HugepagesPools maps the resource name (e.g. "hugepages-2Mi") and the NUMA zone to the pool.
+type HugepagesPools map[string]map[int64]HugepagesPool
+
+// ReadHugepagesPools reads the current counters of the hugepages pools of the zones of the machine.
+// The pools which can't be read are omitted.
+func ReadHugepagesPools(lh logr.Logger, sysRoot string, machine MachineData) HugepagesPools {
+	pools := make(HugepagesPools)
+	for _, zone := range machine.Zones {
+		for _, hpSize := range machine.Hugepagesizes {
+			hpPath := filepath.Join(sysRoot, "sys", "devices", "system", "node", "node"+strconv.Itoa(zone.ID), "hugepages", "hugepages-"+strconv.FormatUint(hpSize/1024, 10)+"kB")
+			total, err := readIntFile(filepath.Join(hpPath, "nr_hugepages"))
+			if err != nil {
+				lh.V(4).Info("cannot read the hugepages pool", "path", hpPath, "err", err)
+				continue
+			}
+			free, err := readIntFile(filepath.Join(hpPath, "free_hugepages"))
+			if err != nil {
+				lh.V(4).Info("cannot read the hugepages pool", "path", hpPath, "err", err)
+				continue
+			}
+			resName := types.ResourceIdent{Kind: types.Hugepages, Pagesize: hpSize}.Name()
+			if pools[resName] == nil {
+				pools[resName] = make(map[int64]HugepagesPool)
+			}
+			pools[resName][int64(zone.ID)] = HugepagesPool{
+				Total: int64(total),
+				Free:  int64(free),
+			}
+		}
+	}
+	return pools
+}
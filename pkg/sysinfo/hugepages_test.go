@@ -0,0 +1,59 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHugepagesPools(t *testing.T) {
+	lh := testr.New(t)
+	sysRoot := t.TempDir()
+
+	writePool := func(zoneID, hpSize, total, free string) {
+		hpPath := filepath.Join(sysRoot, "sys", "devices", "system", "node", "node"+zoneID, "hugepages", "hugepages-"+hpSize)
+		require.NoError(t, os.MkdirAll(hpPath, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(hpPath, "nr_hugepages"), []byte(total+"\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(hpPath, "free_hugepages"), []byte(free+"\n"), 0644))
+	}
+	writePool("0", "2048kB", "512", "128")
+	writePool("1", "2048kB", "512", "512")
+	writePool("0", "1048576kB", "4", "0")
+	// 1G pool on zone 1 is missing
+
+	machine := MachineData{
+		Pagesize:      4096,
+		Hugepagesizes: []uint64{2 * (1 << 20), 1 << 30},
+		Zones:         []Zone{{ID: 0}, {ID: 1}},
+	}
+	got := ReadHugepagesPools(lh, sysRoot, machine)
+	expected := HugepagesPools{
+		"hugepages-2Mi": {
+			0: {Total: 512, Free: 128},
+			1: {Total: 512, Free: 512},
+		},
+		"hugepages-1Gi": {
+			0: {Total: 4, Free: 0},
+		},
+	}
+	require.Equal(t, expected, got)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
+	"k8s.io/utils/ptr"
+)
+
+// Locality holds the facts other DRA drivers (GPUs, NICs) can use to align their devices with a NUMA zone.
+type Locality struct {
+	// CPUSocketID is the physical package of the CPUs of the zone. -1 if unknown, e.g. on CPU-less zones.
+	CPUSocketID int `json:"cpu_socket_id"`
+	// PCIeRoots are the PCIe root complexes attached to the zone, sorted by name (e.g. "pci0000:00").
+	PCIeRoots []string `json:"pcie_roots,omitempty"`
+}
+
+// GetLocality returns the locality facts of all the NUMA zones of the machine, by zone ID.
+// Missing or malformed facts are not fatal: the affected zones just get less facts.
+func GetLocality(lh logr.Logger, sysRoot string, zoneIDs []int) map[int]Locality {
+	localities := make(map[int]Locality, len(zoneIDs))
+	for _, zoneID := range zoneIDs {
+		localities[zoneID] = Locality{
+			CPUSocketID: cpuSocketOfZone(lh, sysRoot, zoneID),
+		}
+	}
+	for zoneID, roots := range pcieRootsByZone(lh, sysRoot) {
+		loc, ok := localities[zoneID]
+		if !ok {
+			continue
+		}
+		loc.PCIeRoots = roots
+		localities[zoneID] = loc
+	}
+	return localities
+}
+
+// cpuSocketOfZone returns the physical package of the first CPU of the zone. The CPUs of a NUMA
+// zone can't span multiple physical packages, so any CPU is as good as the others.
+func cpuSocketOfZone(lh logr.Logger, sysRoot string, zoneID int) int {
+	nodePath := filepath.Join(sysRoot, "sys", "devices", "system", "node", "node"+strconv.Itoa(zoneID))
+	entries, err := os.ReadDir(nodePath)
+	if err != nil {
+		lh.V(4).Info("cannot read NUMA zone", "path", nodePath, "err", err)
+		return -1
+	}
+	for _, entry := range entries {
+		cpuID, ok := strings.CutPrefix(entry.Name(), "cpu")
+		if !ok {
+			continue
+		}
+		if _, err := strconv.Atoi(cpuID); err != nil {
+			continue // cpulist, cpumap
+		}
+		socketID, err := readIntFile(filepath.Join(sysRoot, "sys", "devices", "system", "cpu", entry.Name(), "topology", "physical_package_id"))
+		if err != nil {
+			lh.V(4).Info("cannot read the CPU physical package", "cpu", entry.Name(), "err", err)
+			return -1
+		}
+		return socketID
+	}
+	return -1
+}
+
+// pcieRootsByZone maps the PCI devices to their NUMA zone, and returns the root complexes
+// of the devices by zone, resolved like the other DRA drivers do, so the values match theirs.
+func pcieRootsByZone(lh logr.Logger, sysRoot string) map[int][]string {
+	devsPath := filepath.Join(sysRoot, "sys", "bus", "pci", "devices")
+	entries, err := os.ReadDir(devsPath)
+	if err != nil {
+		lh.V(4).Info("cannot read PCI devices", "path", devsPath, "err", err)
+		return nil
+	}
+	rootsByZone := make(map[int][]string)
+	for _, entry := range entries {
+		zoneID, err := readIntFile(filepath.Join(devsPath, entry.Name(), "numa_node"))
+		if err != nil || zoneID < 0 { // -1: the firmware reports no affinity
+			continue
+		}
+		root, err := pcieRootOf(entry.Name())
+		if err != nil {
+			lh.V(4).Info("cannot resolve the PCIe root", "device", entry.Name(), "err", err)
+			continue
+		}
+		if slices.Contains(rootsByZone[zoneID], root) {
+			continue
+		}
+		rootsByZone[zoneID] = append(rootsByZone[zoneID], root)
+	}
+	for zoneID := range rootsByZone {
+		slices.Sort(rootsByZone[zoneID])
+	}
+	return rootsByZone
+}
+
+// pcieRootOf returns the PCIe root complex of the PCI device with the given bus ID (e.g. "0000:00:1f.0").
+// The resolution always reads /sys, which shows the devices of the host also within the container.
+// Can be changed by unit tests.
+var pcieRootOf = func(pciBusID string) (string, error) {
+	attr, err := deviceattribute.GetPCIeRootAttributeByPCIBusID(pciBusID)
+	if err != nil {
+		return "", err
+	}
+	return ptr.Deref(attr.Value.StringValue, ""), nil
+}
+
+func readIntFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
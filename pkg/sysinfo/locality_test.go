@@ -0,0 +1,88 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+func TestGetLocality(t *testing.T) {
+	lh := testr.New(t)
+	// two sockets, one NUMA zone each, plus a CPU-less zone (e.g. CXL memory expander)
+	sysRoot := fakesys.Make(t, fakesys.Spec{
+		Zones: []fakesys.Zone{
+			{ID: 0, CPUs: []int{0}, Socket: 0},
+			{ID: 1, CPUs: []int{1}, Socket: 1},
+			{ID: 2},
+		},
+	})
+
+	setFakePCIeRoots(t, sysRoot)
+	makePCIDevice(t, sysRoot, "pci0000:00", "0000:00:1f.0", "0")
+	makePCIDevice(t, sysRoot, "pci0000:00", "0000:00:1f.3", "0")
+	makePCIDevice(t, sysRoot, "pci0000:3a", "0000:3a:00.0", "0")
+	makePCIDevice(t, sysRoot, "pci0000:80", "0000:80:00.0", "1")
+	makePCIDevice(t, sysRoot, "pci0000:c0", "0000:c0:00.0", "-1")
+
+	got := GetLocality(lh, sysRoot, []int{0, 1, 2})
+	expected := map[int]Locality{
+		0: {CPUSocketID: 0, PCIeRoots: []string{"pci0000:00", "pci0000:3a"}},
+		1: {CPUSocketID: 1, PCIeRoots: []string{"pci0000:80"}},
+		2: {CPUSocketID: -1},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("unexpected diff: %v", diff)
+	}
+}
+
+func TestGetLocalityMissingSysfs(t *testing.T) {
+	got := GetLocality(testr.New(t), t.TempDir(), []int{0})
+	require.Equal(t, map[int]Locality{0: {CPUSocketID: -1}}, got)
+}
+
+// setFakePCIeRoots makes the PCIe roots resolve within the fake sysfs, like the real resolution does from /sys.
+func setFakePCIeRoots(t *testing.T, sysRoot string) {
+	t.Helper()
+	saved := pcieRootOf
+	t.Cleanup(func() { pcieRootOf = saved })
+	pcieRootOf = func(pciBusID string) (string, error) {
+		target, err := os.Readlink(filepath.Join(sysRoot, "sys", "bus", "pci", "devices", pciBusID))
+		if err != nil {
+			return "", err
+		}
+		// "../../../devices/pci0000:00/0000:00:1f.0"
+		return filepath.Base(filepath.Dir(target)), nil
+	}
+}
+
+func makePCIDevice(t *testing.T, sysRoot, root, addr, numaNode string) {
+	t.Helper()
+	devPath := filepath.Join(sysRoot, "sys", "devices", root, addr)
+	require.NoError(t, os.MkdirAll(devPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(devPath, "numa_node"), []byte(numaNode+"\n"), 0644))
+	busPath := filepath.Join(sysRoot, "sys", "bus", "pci", "devices")
+	require.NoError(t, os.MkdirAll(busPath, 0755))
+	require.NoError(t, os.Symlink(filepath.Join("..", "..", "..", "devices", root, addr), filepath.Join(busPath, addr)))
+}
@@ -21,9 +21,8 @@ import (
 
 	"github.com/go-logr/logr"
 	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
-	ghwopt "github.com/jaypipes/ghw/pkg/option"
-	ghwtopology "github.com/jaypipes/ghw/pkg/topology"
 
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/backing"
 	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
 
@@ -31,28 +30,19 @@ type Zone struct {
 	ID        int             `json:"id"`
 	Distances []int           `json:"distances"`
 	Memory    *ghwmemory.Area `json:"memory"`
-}
-
-func FromNodes(nodes []*ghwtopology.Node) []Zone {
-	zones := make([]Zone, 0, len(nodes))
-	for _, node := range nodes {
-		zones = append(zones, Zone{
-			ID:        node.ID,
-			Distances: node.Distances,
-			Memory:    node.Memory,
-		})
-	}
-	return zones
+	Locality  *Locality       `json:"locality,omitempty"`
 }
 
 type MachineData struct {
 	Pagesize      uint64   `json:"page_size"`
 	Hugepagesizes []uint64 `json:"huge_page_sizes"`
 	Zones         []Zone   `json:"zones"`
+	// HugepagesBacking is the hugepages setup of the kernel command line, nil if unknown
+	HugepagesBacking *backing.Cmdline `json:"hugepages_backing,omitempty"`
 }
 
 func GetMachineData(lh logr.Logger, sysRoot string) (MachineData, error) {
-	topo, err := ghwtopology.New(ghwopt.WithChroot(sysRoot))
+	zones, err := GetZones(lh, sysRoot)
 	if err != nil {
 		return MachineData{}, err
 	}
@@ -65,9 +55,37 @@ func GetMachineData(lh logr.Logger, sysRoot string) (MachineData, error) {
 		}
 		Hugepagesizes = append(Hugepagesizes, sz)
 	}
+	zoneIDs := make([]int, 0, len(zones))
+	for _, zone := range zones {
+		zoneIDs = append(zoneIDs, zone.ID)
+	}
+	localities := GetLocality(lh, sysRoot, zoneIDs)
+	for idx := range zones {
+		loc := localities[zones[idx].ID]
+		zones[idx].Locality = &loc
+	}
 	return MachineData{
-		Pagesize:      uint64(os.Getpagesize()),
-		Hugepagesizes: Hugepagesizes,
-		Zones:         FromNodes(topo.Nodes),
+		Pagesize:         uint64(os.Getpagesize()),
+		Hugepagesizes:    Hugepagesizes,
+		Zones:            zones,
+		HugepagesBacking: readHugepagesBacking(lh, sysRoot, zones),
 	}, nil
 }
+
+// readHugepagesBacking reads how the hugepages pools are obtained. This is informative,
+// so failures are logged and reported as unknown.
+func readHugepagesBacking(lh logr.Logger, sysRoot string, zones []Zone) *backing.Cmdline {
+	var defaultSize uint64
+	for _, zone := range zones {
+		if zone.Memory != nil && zone.Memory.DefaultHugePageSize > 0 {
+			defaultSize = zone.Memory.DefaultHugePageSize
+			break
+		}
+	}
+	cl, err := backing.Read(sysRoot, defaultSize)
+	if err != nil {
+		lh.V(2).Info("cannot read the hugepages setup of the kernel command line", "err", err)
+		return nil
+	}
+	return &cl
+}
@@ -21,10 +21,13 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/moby/sys/mountinfo"
+
+	"github.com/ffromani/dra-driver-memory/pkg/setup/containerd"
 )
 
 const (
@@ -32,24 +35,20 @@ const (
 )
 
 var (
-	ErrCGroupV2Missing         = errors.New("cgroup v2 not configured")
-	ErrCGroupV2Repeated        = errors.New("cgroup v2 configured multiple times")
-	ErrMemoryHugeTLBAccounting = errors.New("memory hugetlb accounting not supported")
+	ErrCGroupV2Missing           = errors.New("cgroup v2 not configured")
+	ErrCGroupV2Repeated          = errors.New("cgroup v2 configured multiple times")
+	ErrMemoryHugeTLBAccounting   = errors.New("memory hugetlb accounting not supported")
+	ErrHugeTLBControllerMissing  = errors.New("hugetlb cgroup controller not available")
+	ErrHugeTLBControllerDisabled = errors.New("hugetlb cgroup controller not enabled for the child cgroups")
+	ErrNRISocketMissing          = errors.New("NRI socket not found")
 )
 
 func Validate(lh logr.Logger, procRoot string) error {
-	mounts, err := getThreadSelfMounts(procRoot, mountinfo.FSTypeFilter(cgroup2FSType))
+	mount, err := findCgroup2Mount(procRoot)
 	if err != nil {
-		return fmt.Errorf("discovering mount infos: %w", err)
-	}
-	if len(mounts) == 0 {
-		return ErrCGroupV2Missing
-	}
-	if len(mounts) > 1 {
-		return ErrCGroupV2Repeated
+		return err
 	}
 	lh.V(2).Info("system check", "cgroupV2", "pass")
-	mount := mounts[0] // shortcut
 	lh.Info("cgroup2 mount", "options", mount.Options)
 	if strings.Contains(mount.Options, "memory_hugetlb_accounting") {
 		return ErrMemoryHugeTLBAccounting
@@ -58,6 +57,115 @@ func Validate(lh logr.Logger, procRoot string) error {
 	return nil
 }
 
+// DetectCgroupMount returns the mount point of the cgroup2 hierarchy, found in the mountinfo of the process.
+func DetectCgroupMount(lh logr.Logger, procRoot string) (string, error) {
+	mount, err := findCgroup2Mount(procRoot)
+	if err != nil {
+		return "", err
+	}
+	lh.V(2).Info("cgroup2 mount found", "mountPoint", mount.Mountpoint, "options", mount.Options)
+	return mount.Mountpoint, nil
+}
+
+func findCgroup2Mount(procRoot string) (*mountinfo.Info, error) {
+	mounts, err := getThreadSelfMounts(procRoot, mountinfo.FSTypeFilter(cgroup2FSType))
+	if err != nil {
+		return nil, fmt.Errorf("discovering mount infos: %w", err)
+	}
+	if len(mounts) == 0 {
+		return nil, ErrCGroupV2Missing
+	}
+	if len(mounts) > 1 {
+		return nil, ErrCGroupV2Repeated
+	}
+	return mounts[0], nil
+}
+
+// RuntimeConfig locates the settings of the container runtime to validate. The empty fields skip their checks.
+type RuntimeConfig struct {
+	// CgroupMount is the cgroup2 mount point of the host
+	CgroupMount string
+	// NRISocketPath is the socket the driver connects to
+	NRISocketPath string
+	// ContainerdConfigPath is checked only if the file exists, the driver may not see the host configuration
+	ContainerdConfigPath string
+}
+
+// ValidateRuntime checks the container runtime will enforce the allocations, which would otherwise silently
+// not happen. Reports all the problems found, each with a remediation hint.
+func ValidateRuntime(lh logr.Logger, cfg RuntimeConfig) error {
+	var errs []error
+	if cfg.CgroupMount != "" {
+		errs = append(errs, validateHugeTLBController(lh, cfg.CgroupMount))
+	}
+	if cfg.NRISocketPath != "" {
+		errs = append(errs, validateNRISocket(lh, cfg.NRISocketPath))
+	}
+	if cfg.ContainerdConfigPath != "" {
+		errs = append(errs, validateContainerdConfig(lh, cfg.ContainerdConfigPath, cfg.NRISocketPath))
+	}
+	return errors.Join(errs...)
+}
+
+func validateHugeTLBController(lh logr.Logger, cgroupMount string) error {
+	controllers, err := readControllers(filepath.Join(cgroupMount, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(controllers, "hugetlb") {
+		return fmt.Errorf("%w: the kernel must be built with CONFIG_CGROUP_HUGETLB, and the controller must not be disabled on the kernel command line (cgroup_disable=hugetlb)", ErrHugeTLBControllerMissing)
+	}
+	controllers, err = readControllers(filepath.Join(cgroupMount, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(controllers, "hugetlb") {
+		return fmt.Errorf("%w: enable it with `echo +hugetlb > %s`, or let the init system delegate it", ErrHugeTLBControllerDisabled, filepath.Join(cgroupMount, "cgroup.subtree_control"))
+	}
+	lh.V(2).Info("system check", "hugetlbController", "pass")
+	return nil
+}
+
+func readControllers(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cgroup controllers: %w", err)
+	}
+	return strings.Fields(string(data)), nil
+}
+
+func validateNRISocket(lh logr.Logger, socketPath string) error {
+	finfo, err := os.Stat(socketPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %q: enable NRI in the container runtime, and share the socket directory with the driver", ErrNRISocketMissing, socketPath)
+	}
+	if err != nil {
+		return fmt.Errorf("checking NRI socket: %w", err)
+	}
+	if finfo.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%w: %q is not a socket: check the NRI socket path of the container runtime", ErrNRISocketMissing, socketPath)
+	}
+	lh.V(2).Info("system check", "nriSocket", "pass")
+	return nil
+}
+
+func validateContainerdConfig(lh logr.Logger, configPath, nriSocketPath string) error {
+	data, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		lh.V(2).Info("system check", "containerdConfig", "skip", "path", configPath)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading containerd configuration: %w", err)
+	}
+	err = containerd.ValidateConfig(data, nriSocketPath)
+	if err != nil {
+		return fmt.Errorf("containerd configuration %q: %w", configPath, err)
+	}
+	lh.V(2).Info("system check", "containerdConfig", "pass")
+	return nil
+}
+
 // os thread locking inspired by moby/sys code
 func getThreadSelfMounts(procRoot string, filter mountinfo.FilterFunc) ([]*mountinfo.Info, error) {
 	// We need to lock ourselves to the current OS thread in order to make sure
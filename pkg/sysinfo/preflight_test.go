@@ -16,51 +16,51 @@
 package sysinfo
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/pkg/setup/containerd"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
 )
 
 func TestValidate(t *testing.T) {
 	type testcase struct {
 		name          string
-		mountInfo     string
+		mounts        []fakesys.Mount
 		expectedError bool
 	}
 
 	testcases := []testcase{
 		{
 			name:          "empty mountinfo",
-			mountInfo:     "",
+			mounts:        nil,
 			expectedError: true,
 		},
 		{
 			name:          "basic with cgroup v2",
-			mountInfo:     mountinfoLaptopCGroupV2,
+			mounts:        fakesys.NodeMounts(true),
 			expectedError: false,
 		},
 		{
 			name:          "basic without cgroup v2",
-			mountInfo:     mountinfoLaptopNoCGroupV2,
+			mounts:        fakesys.NodeMounts(false),
 			expectedError: true,
 		},
 		{
 			name:          "basic without cgroup v2 and hugetlb acct",
-			mountInfo:     mountinfoLaptopCGroupV2Acct,
+			mounts:        fakesys.NodeMounts(true, "memory_hugetlb_accounting"),
 			expectedError: true,
 		},
 	}
 
 	for _, tcase := range testcases {
 		t.Run(tcase.name, func(t *testing.T) {
-			tmpDir := t.TempDir()
-			require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "proc", "thread-self"), 0755))
-			if len(tcase.mountInfo) > 0 {
-				require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "proc", "thread-self", "mountinfo"), []byte(tcase.mountInfo), 0600))
-			}
+			tmpDir := fakesys.Make(t, fakesys.Spec{Mounts: tcase.mounts})
 
 			logger := testr.New(t)
 			err := Validate(logger, tmpDir)
@@ -72,100 +72,143 @@ func TestValidate(t *testing.T) {
 	}
 }
 
-const mountinfoLaptopCGroupV2 = `74 2 MAJOR:1 / / rw,relatime shared:1 - ext4 /dev/mapper/DISK-MAIN rw,seclabel
-38 74 0:6 / /dev rw,nosuid shared:2 - devtmpfs devtmpfs rw,seclabel,size=16248436k,nr_inodes=4062109,mode=755,inode64
-39 38 0:26 / /dev/shm rw,nosuid,nodev shared:3 - tmpfs tmpfs rw,seclabel,inode64
-40 38 0:27 / /dev/pts rw,nosuid,noexec,relatime shared:4 - devpts devpts rw,seclabel,gid=5,mode=620,ptmxmode=000
-41 74 0:25 / /sys rw,nosuid,nodev,noexec,relatime shared:5 - sysfs sysfs rw,seclabel
-42 41 0:7 / /sys/kernel/security rw,nosuid,nodev,noexec,relatime shared:6 - securityfs securityfs rw
-43 41 0:29 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:7 - cgroup2 cgroup2 rw,seclabel,nsdelegate,memory_recursiveprot
-44 41 0:30 / /sys/fs/pstore rw,nosuid,nodev,noexec,relatime shared:8 - pstore none rw,seclabel
-45 41 0:31 / /sys/firmware/efi/efivars rw,nosuid,nodev,noexec,relatime shared:9 - efivarfs efivarfs rw
-46 41 0:32 / /sys/fs/bpf rw,nosuid,nodev,noexec,relatime shared:10 - bpf bpf rw,mode=700
-47 41 0:19 / /sys/kernel/config rw,nosuid,nodev,noexec,relatime shared:11 - configfs configfs rw
-48 74 0:24 / /proc rw,nosuid,nodev,noexec,relatime shared:13 - proc proc rw
-49 74 0:28 / /run rw,nosuid,nodev shared:14 - tmpfs tmpfs rw,seclabel,size=6510268k,nr_inodes=819200,mode=755,inode64
-27 41 0:22 / /sys/fs/selinux rw,nosuid,noexec,relatime shared:12 - selinuxfs selinuxfs rw
-26 48 0:33 / /proc/sys/fs/binfmt_misc rw,relatime shared:15 - autofs systemd-1 rw,fd=39,pgrp=1,timeout=0,minproto=5,maxproto=5,direct,pipe_ino=8651
-29 41 0:8 / /sys/kernel/debug rw,nosuid,nodev,noexec,relatime shared:18 - debugfs debugfs rw,seclabel
-30 41 0:13 / /sys/kernel/tracing rw,nosuid,nodev,noexec,relatime shared:17 - tracefs tracefs rw,seclabel
-33 38 0:21 / /dev/mqueue rw,nosuid,nodev,noexec,relatime shared:19 - mqueue mqueue rw,seclabel
-34 38 0:36 / /dev/hugepages rw,nosuid,nodev,relatime shared:20 - hugetlbfs hugetlbfs rw,seclabel,pagesize=2M
-95 49 0:37 / /run/credentials/systemd-journald.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:21 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-50 41 0:39 / /sys/fs/fuse/connections rw,nosuid,nodev,noexec,relatime shared:72 - fusectl fusectl rw
-54 74 MAJOR:2 / /boot rw,relatime shared:75 - ext4 /dev/nvme0n1p2 rw,seclabel
-57 74 MAJOR:4 / /var rw,relatime shared:78 - ext4 /dev/mapper/DISK-VAR rw,seclabel
-60 54 MAJOR:1 / /boot/efi rw,relatime shared:81 - vfat /dev/BOOT rw,fmask=0077,dmask=0077,codepage=437,iocharset=ascii,shortname=winnt,errors=remount-ro
-63 74 MAJOR:3 / /tmp rw,relatime shared:84 - ext4 /dev/mapper/DISK-TEMP rw,seclabel
-64 74 MAJOR:5 / /home rw,relatime shared:87 - ext4 /dev/mapper/DISK-HOME rw,seclabel
-209 49 0:43 / /run/credentials/systemd-resolved.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:90 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-217 26 0:46 / /proc/sys/fs/binfmt_misc rw,nosuid,nodev,noexec,relatime shared:93 - binfmt_misc binfmt_misc rw
-71 57 0:48 / /var/lib/nfs/rpc_pipefs rw,relatime shared:157 - rpc_pipefs sunrpc rw
-28 49 0:83 / /run/user/1000 rw,nosuid,nodev,relatime shared:1181 - tmpfs tmpfs rw,seclabel,size=3255132k,nr_inodes=813783,mode=700,uid=1000,gid=1000,inode64
-1226 28 0:84 / /run/user/1000/gvfs rw,nosuid,nodev,relatime shared:1213 - fuse.gvfsd-fuse gvfsd-fuse rw,user_id=1000,group_id=1000
-1326 28 0:85 / /run/user/1000/doc rw,nosuid,nodev,relatime shared:1245 - fuse.portal portal rw,user_id=1000,group_id=1000`
-
-const mountinfoLaptopCGroupV2Acct = `74 2 MAJOR:1 / / rw,relatime shared:1 - ext4 /dev/mapper/DISK-MAIN rw,seclabel
-38 74 0:6 / /dev rw,nosuid shared:2 - devtmpfs devtmpfs rw,seclabel,size=16248436k,nr_inodes=4062109,mode=755,inode64
-39 38 0:26 / /dev/shm rw,nosuid,nodev shared:3 - tmpfs tmpfs rw,seclabel,inode64
-40 38 0:27 / /dev/pts rw,nosuid,noexec,relatime shared:4 - devpts devpts rw,seclabel,gid=5,mode=620,ptmxmode=000
-41 74 0:25 / /sys rw,nosuid,nodev,noexec,relatime shared:5 - sysfs sysfs rw,seclabel
-42 41 0:7 / /sys/kernel/security rw,nosuid,nodev,noexec,relatime shared:6 - securityfs securityfs rw
-43 41 0:29 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime,memory_hugetlb_accounting shared:7 - cgroup2 cgroup2 rw,seclabel,nsdelegate,memory_recursiveprot
-44 41 0:30 / /sys/fs/pstore rw,nosuid,nodev,noexec,relatime shared:8 - pstore none rw,seclabel
-45 41 0:31 / /sys/firmware/efi/efivars rw,nosuid,nodev,noexec,relatime shared:9 - efivarfs efivarfs rw
-46 41 0:32 / /sys/fs/bpf rw,nosuid,nodev,noexec,relatime shared:10 - bpf bpf rw,mode=700
-47 41 0:19 / /sys/kernel/config rw,nosuid,nodev,noexec,relatime shared:11 - configfs configfs rw
-48 74 0:24 / /proc rw,nosuid,nodev,noexec,relatime shared:13 - proc proc rw
-49 74 0:28 / /run rw,nosuid,nodev shared:14 - tmpfs tmpfs rw,seclabel,size=6510268k,nr_inodes=819200,mode=755,inode64
-27 41 0:22 / /sys/fs/selinux rw,nosuid,noexec,relatime shared:12 - selinuxfs selinuxfs rw
-26 48 0:33 / /proc/sys/fs/binfmt_misc rw,relatime shared:15 - autofs systemd-1 rw,fd=39,pgrp=1,timeout=0,minproto=5,maxproto=5,direct,pipe_ino=8651
-29 41 0:8 / /sys/kernel/debug rw,nosuid,nodev,noexec,relatime shared:18 - debugfs debugfs rw,seclabel
-30 41 0:13 / /sys/kernel/tracing rw,nosuid,nodev,noexec,relatime shared:17 - tracefs tracefs rw,seclabel
-33 38 0:21 / /dev/mqueue rw,nosuid,nodev,noexec,relatime shared:19 - mqueue mqueue rw,seclabel
-34 38 0:36 / /dev/hugepages rw,nosuid,nodev,relatime shared:20 - hugetlbfs hugetlbfs rw,seclabel,pagesize=2M
-95 49 0:37 / /run/credentials/systemd-journald.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:21 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-50 41 0:39 / /sys/fs/fuse/connections rw,nosuid,nodev,noexec,relatime shared:72 - fusectl fusectl rw
-54 74 MAJOR:2 / /boot rw,relatime shared:75 - ext4 /dev/nvme0n1p2 rw,seclabel
-57 74 MAJOR:4 / /var rw,relatime shared:78 - ext4 /dev/mapper/DISK-VAR rw,seclabel
-60 54 MAJOR:1 / /boot/efi rw,relatime shared:81 - vfat /dev/BOOT rw,fmask=0077,dmask=0077,codepage=437,iocharset=ascii,shortname=winnt,errors=remount-ro
-63 74 MAJOR:3 / /tmp rw,relatime shared:84 - ext4 /dev/mapper/DISK-TEMP rw,seclabel
-64 74 MAJOR:5 / /home rw,relatime shared:87 - ext4 /dev/mapper/DISK-HOME rw,seclabel
-209 49 0:43 / /run/credentials/systemd-resolved.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:90 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-217 26 0:46 / /proc/sys/fs/binfmt_misc rw,nosuid,nodev,noexec,relatime shared:93 - binfmt_misc binfmt_misc rw
-71 57 0:48 / /var/lib/nfs/rpc_pipefs rw,relatime shared:157 - rpc_pipefs sunrpc rw
-28 49 0:83 / /run/user/1000 rw,nosuid,nodev,relatime shared:1181 - tmpfs tmpfs rw,seclabel,size=3255132k,nr_inodes=813783,mode=700,uid=1000,gid=1000,inode64
-1226 28 0:84 / /run/user/1000/gvfs rw,nosuid,nodev,relatime shared:1213 - fuse.gvfsd-fuse gvfsd-fuse rw,user_id=1000,group_id=1000
-1326 28 0:85 / /run/user/1000/doc rw,nosuid,nodev,relatime shared:1245 - fuse.portal portal rw,user_id=1000,group_id=1000`
-
-const mountinfoLaptopNoCGroupV2 = `74 2 MAJOR:1 / / rw,relatime shared:1 - ext4 /dev/mapper/DISK-MAIN rw,seclabel
-38 74 0:6 / /dev rw,nosuid shared:2 - devtmpfs devtmpfs rw,seclabel,size=16248436k,nr_inodes=4062109,mode=755,inode64
-39 38 0:26 / /dev/shm rw,nosuid,nodev shared:3 - tmpfs tmpfs rw,seclabel,inode64
-40 38 0:27 / /dev/pts rw,nosuid,noexec,relatime shared:4 - devpts devpts rw,seclabel,gid=5,mode=620,ptmxmode=000
-41 74 0:25 / /sys rw,nosuid,nodev,noexec,relatime shared:5 - sysfs sysfs rw,seclabel
-42 41 0:7 / /sys/kernel/security rw,nosuid,nodev,noexec,relatime shared:6 - securityfs securityfs rw
-44 41 0:30 / /sys/fs/pstore rw,nosuid,nodev,noexec,relatime shared:8 - pstore none rw,seclabel
-45 41 0:31 / /sys/firmware/efi/efivars rw,nosuid,nodev,noexec,relatime shared:9 - efivarfs efivarfs rw
-46 41 0:32 / /sys/fs/bpf rw,nosuid,nodev,noexec,relatime shared:10 - bpf bpf rw,mode=700
-47 41 0:19 / /sys/kernel/config rw,nosuid,nodev,noexec,relatime shared:11 - configfs configfs rw
-48 74 0:24 / /proc rw,nosuid,nodev,noexec,relatime shared:13 - proc proc rw
-49 74 0:28 / /run rw,nosuid,nodev shared:14 - tmpfs tmpfs rw,seclabel,size=6510268k,nr_inodes=819200,mode=755,inode64
-27 41 0:22 / /sys/fs/selinux rw,nosuid,noexec,relatime shared:12 - selinuxfs selinuxfs rw
-26 48 0:33 / /proc/sys/fs/binfmt_misc rw,relatime shared:15 - autofs systemd-1 rw,fd=39,pgrp=1,timeout=0,minproto=5,maxproto=5,direct,pipe_ino=8651
-29 41 0:8 / /sys/kernel/debug rw,nosuid,nodev,noexec,relatime shared:18 - debugfs debugfs rw,seclabel
-30 41 0:13 / /sys/kernel/tracing rw,nosuid,nodev,noexec,relatime shared:17 - tracefs tracefs rw,seclabel
-33 38 0:21 / /dev/mqueue rw,nosuid,nodev,noexec,relatime shared:19 - mqueue mqueue rw,seclabel
-34 38 0:36 / /dev/hugepages rw,nosuid,nodev,relatime shared:20 - hugetlbfs hugetlbfs rw,seclabel,pagesize=2M
-95 49 0:37 / /run/credentials/systemd-journald.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:21 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-50 41 0:39 / /sys/fs/fuse/connections rw,nosuid,nodev,noexec,relatime shared:72 - fusectl fusectl rw
-54 74 MAJOR:2 / /boot rw,relatime shared:75 - ext4 /dev/nvme0n1p2 rw,seclabel
-57 74 MAJOR:4 / /var rw,relatime shared:78 - ext4 /dev/mapper/DISK-VAR rw,seclabel
-60 54 MAJOR:1 / /boot/efi rw,relatime shared:81 - vfat /dev/BOOT rw,fmask=0077,dmask=0077,codepage=437,iocharset=ascii,shortname=winnt,errors=remount-ro
-63 74 MAJOR:3 / /tmp rw,relatime shared:84 - ext4 /dev/mapper/DISK-TEMP rw,seclabel
-64 74 MAJOR:5 / /home rw,relatime shared:87 - ext4 /dev/mapper/DISK-HOME rw,seclabel
-209 49 0:43 / /run/credentials/systemd-resolved.service ro,nosuid,nodev,noexec,relatime,nosymfollow shared:90 - tmpfs tmpfs rw,seclabel,size=1024k,nr_inodes=1024,mode=700,inode64,noswap
-217 26 0:46 / /proc/sys/fs/binfmt_misc rw,nosuid,nodev,noexec,relatime shared:93 - binfmt_misc binfmt_misc rw
-71 57 0:48 / /var/lib/nfs/rpc_pipefs rw,relatime shared:157 - rpc_pipefs sunrpc rw
-28 49 0:83 / /run/user/1000 rw,nosuid,nodev,relatime shared:1181 - tmpfs tmpfs rw,seclabel,size=3255132k,nr_inodes=813783,mode=700,uid=1000,gid=1000,inode64
-1226 28 0:84 / /run/user/1000/gvfs rw,nosuid,nodev,relatime shared:1213 - fuse.gvfsd-fuse gvfsd-fuse rw,user_id=1000,group_id=1000
-1326 28 0:85 / /run/user/1000/doc rw,nosuid,nodev,relatime shared:1245 - fuse.portal portal rw,user_id=1000,group_id=1000`
+func TestDetectCgroupMount(t *testing.T) {
+	testcases := []struct {
+		name          string
+		mounts        []fakesys.Mount
+		expectedMount string
+		expectedError error
+	}{
+		{
+			name:          "basic with cgroup v2",
+			mounts:        fakesys.NodeMounts(true),
+			expectedMount: "/sys/fs/cgroup",
+		},
+		{
+			name:          "basic without cgroup v2",
+			mounts:        fakesys.NodeMounts(false),
+			expectedError: ErrCGroupV2Missing,
+		},
+		{
+			name: "cgroup v2 mounted twice",
+			mounts: append(fakesys.NodeMounts(true), fakesys.Mount{
+				ID: 79, ParentID: 74, Root: "/", MountPoint: "/run/cgroup", Options: "rw,relatime", FSType: "cgroup2", Source: "cgroup2", SuperOpts: "rw",
+			}),
+			expectedError: ErrCGroupV2Repeated,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			tmpDir := fakesys.Make(t, fakesys.Spec{Mounts: tcase.mounts})
+
+			mountPoint, err := DetectCgroupMount(testr.New(t), tmpDir)
+			if tcase.expectedError != nil {
+				require.ErrorIs(t, err, tcase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expectedMount, mountPoint)
+		})
+	}
+}
+
+func TestValidateRuntime(t *testing.T) {
+	// keep the path short, unix socket paths are limited to ~100 chars
+	socketDir, err := os.MkdirTemp("", "nri")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(socketDir) })
+	nriSocketPath := filepath.Join(socketDir, "nri.sock")
+	listener, err := net.Listen("unix", nriSocketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	rootCgroup := func(controllers, subtreeControl string) []fakesys.Cgroup {
+		return []fakesys.Cgroup{
+			{
+				Path: "/",
+				Files: map[string]string{
+					"cgroup.controllers":     controllers,
+					"cgroup.subtree_control": subtreeControl,
+				},
+			},
+		}
+	}
+
+	type testcase struct {
+		name             string
+		cgroups          []fakesys.Cgroup
+		nriSocketPath    string
+		containerdConfig string
+		expectedErrs     []error
+	}
+
+	testcases := []testcase{
+		{
+			name:          "all good",
+			cgroups:       rootCgroup("cpuset cpu memory hugetlb pids\n", "cpuset cpu memory hugetlb pids\n"),
+			nriSocketPath: nriSocketPath,
+			containerdConfig: `
+[plugins."io.containerd.nri.v1.nri"]
+  disable = false
+`,
+		},
+		{
+			name:          "missing containerd configuration is skipped",
+			cgroups:       rootCgroup("memory hugetlb\n", "memory hugetlb\n"),
+			nriSocketPath: nriSocketPath,
+		},
+		{
+			name:          "missing hugetlb controller",
+			cgroups:       rootCgroup("cpuset cpu memory pids\n", "cpuset cpu memory pids\n"),
+			nriSocketPath: nriSocketPath,
+			expectedErrs:  []error{ErrHugeTLBControllerMissing},
+		},
+		{
+			name:          "hugetlb controller not enabled",
+			cgroups:       rootCgroup("memory hugetlb\n", "memory\n"),
+			nriSocketPath: nriSocketPath,
+			expectedErrs:  []error{ErrHugeTLBControllerDisabled},
+		},
+		{
+			name:          "missing NRI socket and NRI disabled",
+			cgroups:       rootCgroup("memory hugetlb\n", "memory hugetlb\n"),
+			nriSocketPath: filepath.Join(socketDir, "missing.sock"),
+			containerdConfig: `
+[plugins."io.containerd.nri.v1.nri"]
+  disable = true
+`,
+			expectedErrs: []error{ErrNRISocketMissing, containerd.ErrNRIDisabled},
+		},
+		{
+			name:          "NRI socket is not a socket",
+			cgroups:       rootCgroup("memory hugetlb\n", "memory hugetlb\n"),
+			nriSocketPath: socketDir,
+			expectedErrs:  []error{ErrNRISocketMissing},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			tmpDir := fakesys.Make(t, fakesys.Spec{Cgroups: tcase.cgroups})
+			cfg := RuntimeConfig{
+				CgroupMount:          filepath.Join(tmpDir, "sys", "fs", "cgroup"),
+				NRISocketPath:        tcase.nriSocketPath,
+				ContainerdConfigPath: filepath.Join(tmpDir, "etc", "containerd", "config.toml"),
+			}
+			if tcase.containerdConfig != "" {
+				require.NoError(t, os.MkdirAll(filepath.Dir(cfg.ContainerdConfigPath), 0755))
+				require.NoError(t, os.WriteFile(cfg.ContainerdConfigPath, []byte(tcase.containerdConfig), 0644))
+			}
+
+			err := ValidateRuntime(testr.New(t), cfg)
+			if len(tcase.expectedErrs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			for _, expectedErr := range tcase.expectedErrs {
+				require.ErrorIs(t, err, expectedErr)
+			}
+		})
+	}
+}
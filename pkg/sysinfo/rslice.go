@@ -17,6 +17,8 @@
 package sysinfo
 
 import (
+	"fmt"
+	"hash/fnv"
 	"strings"
 
 	resourceapi "k8s.io/api/resource/v1"
@@ -25,21 +27,26 @@ import (
 	"k8s.io/dynamic-resource-allocation/deviceattribute"
 	"k8s.io/utils/ptr"
 
+	"github.com/ffromani/dra-driver-memory/pkg/hugepages/backing"
 	"github.com/ffromani/dra-driver-memory/pkg/types"
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
 
 const (
 	StandardDeviceAttributePrefix = deviceattribute.StandardDeviceAttributePrefix
 )
 
-func MakeAttributes(sp types.Span) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+// MakeAttributes creates the attributes of the device of the span. If `loc` is not nil, the device
+// also gets the alignment attributes, to let claims ask for memory on the same socket or
+// PCIe root of devices of other drivers, like GPUs and NICs.
+func MakeAttributes(sp types.Span, loc *Locality) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
 	pNode := ptr.To(sp.NUMAZone)
 	// some attributes are stabler than others, we have more confidence that
 	// their naming and meaning is solid; others are incubating: less stable
 	// in the sense we may need to change them; some others, listed last,
 	// are added for compatibility with other DRA drivers until the ecosystem
 	// matures and we get standards for attributes.
-	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+	attrs := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
 		// stable attributes
 		StandardDeviceAttributePrefix + "numaNode": {IntValue: pNode},
 		// incubating attributes
@@ -49,17 +56,123 @@ func MakeAttributes(sp types.Span) map[resourceapi.QualifiedName]resourceapi.Dev
 		"dra.cpu/numaNodeID": {IntValue: pNode}, // dra-driver-cpu
 		"dra.net/numaNode":   {IntValue: pNode}, // dranet
 	}
+	if !sp.NeedsHugeTLB() {
+		attrs[SoftLimitAttribute] = resourceapi.DeviceAttribute{BoolValue: ptr.To(true)}
+	}
+	if sp.Pool != "" {
+		attrs[PoolAttribute] = resourceapi.DeviceAttribute{StringValue: ptr.To(sp.Pool)}
+	}
+	if loc == nil {
+		return attrs
+	}
+	// the PCIe root attribute is single-valued, so we can publish it only if the zone has
+	// exactly one root complex. Otherwise claims can still align using the socket.
+	if len(loc.PCIeRoots) == 1 {
+		attrs[deviceattribute.StandardDeviceAttributePCIeRoot] = resourceapi.DeviceAttribute{StringValue: ptr.To(loc.PCIeRoots[0])}
+	}
+	if loc.CPUSocketID >= 0 {
+		pSocket := ptr.To(int64(loc.CPUSocketID))
+		// incubating attributes
+		attrs[StandardDeviceAttributePrefix+"cpuSocketID"] = resourceapi.DeviceAttribute{IntValue: pSocket}
+		// compatibility attributes
+		attrs["dra.cpu/socketID"] = resourceapi.DeviceAttribute{IntValue: pSocket} // dra-driver-cpu
+	}
+	return attrs
+}
+
+// SoftLimitAttribute tells the device supports the burstable claims, whose size is enforced as a soft limit
+// (memory.high: throttling and reclaim) rather than left to the hard limit of the container (memory.max: OOM kill).
+// Only the memory devices support it: the hugepages can't be reclaimed.
+const SoftLimitAttribute resourceapi.QualifiedName = "softLimit"
+
+// PoolAttribute is the name of the sub-pool of the hugepages device, if the pool of its zone is split,
+// to let the claims select a reserved share of the pages.
+const PoolAttribute resourceapi.QualifiedName = "pool"
+
+// The free capacity attributes are hints to let the claims prefer the least loaded NUMA zones.
+// They are refreshed periodically, so they can lag behind the actual allocations.
+// Unqualified names belong to the domain of the driver.
+const (
+	FreeBytesAttribute   resourceapi.QualifiedName = "freeBytes"
+	FreePercentAttribute resourceapi.QualifiedName = "freePercent"
+)
+
+// MakeFreeCapacityAttributes computes the free capacity attributes of the span, given the bytes allocated from it.
+func MakeFreeCapacityAttributes(sp types.Span, allocated int64) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	free := max(sp.Amount-allocated, 0)
+	var freePercent int64
+	if sp.Amount > 0 {
+		freePercent = (free * 100) / sp.Amount
+	}
+	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		FreeBytesAttribute:   {IntValue: ptr.To(free)},
+		FreePercentAttribute: {IntValue: ptr.To(freePercent)},
+	}
+}
+
+// DefaultHugePageSizeAttribute is the default hugepage size of the node, which the applications get with
+// mmap(MAP_HUGETLB) and no size flag. All the devices of the node publish it, to let the claims select
+// the hugepages of the default size, whatever it is, comparing it with the page size of the device.
+const DefaultHugePageSizeAttribute resourceapi.QualifiedName = "defaultHugePageSize"
+
+// MakeDefaultHugePageSizeAttribute creates the attribute of the default hugepage size, given in bytes.
+func MakeDefaultHugePageSizeAttribute(hpSize uint64) resourceapi.DeviceAttribute {
+	return resourceapi.DeviceAttribute{StringValue: ptr.To(unitconv.SizeInBytesToMinimizedString(hpSize))}
+}
+
+// ExcludedSizeAttribute is the amount of hugepages of the zone left out of the devices, because consumed by other means,
+// like the extended resources of the kubelet. Set only if some hugepages are excluded.
+const ExcludedSizeAttribute resourceapi.QualifiedName = "excludedSize"
+
+// MakeExcludedSizeAttribute creates the attribute of the excluded hugepages, given in bytes.
+func MakeExcludedSizeAttribute(amount int64) resourceapi.DeviceAttribute {
+	return resourceapi.DeviceAttribute{StringValue: ptr.To(unitconv.SizeInBytesToMinimizedString(uint64(amount)))}
+}
+
+// PoolBackingAttribute is how the kernel obtains the pages of the hugepages pool of the zone: reserved at boot
+// (`boot`), allocated at runtime from the contiguous memory area reserved at boot (`cma`), or allocated at
+// runtime only (`runtime`), which may fail for the gigantic pages. Set only if the kernel command line is readable.
+const PoolBackingAttribute resourceapi.QualifiedName = "poolBacking"
+
+// MakePoolBackingAttribute creates the attribute of the backing of the hugepages pool.
+func MakePoolBackingAttribute(bk backing.Backing) resourceapi.DeviceAttribute {
+	return resourceapi.DeviceAttribute{StringValue: ptr.To(string(bk))}
+}
+
+// GuardBandSizeAttribute is the memory of the zone left out of the memory device, for the kernel and the device drivers.
+// Set only if the zone has a guard band.
+const GuardBandSizeAttribute resourceapi.QualifiedName = "guardBandSize"
+
+// MakeGuardBandSizeAttribute creates the attribute of the guard band, given in bytes.
+func MakeGuardBandSizeAttribute(amount int64) resourceapi.DeviceAttribute {
+	return resourceapi.DeviceAttribute{StringValue: ptr.To(unitconv.SizeInBytesToMinimizedString(uint64(amount)))}
+}
+
+// The pool attributes expose the kernel view of the hugepages pools, to let the admins tell apart
+// the pages allocated to claims from the pages consumed outside of the claims. Refreshed like the
+// free capacity attributes.
+const (
+	PoolPagesAttribute     resourceapi.QualifiedName = "poolPages"
+	PoolFreePagesAttribute resourceapi.QualifiedName = "poolFreePages"
+)
+
+// MakePoolAttributes computes the attributes of the hugepages pool backing a device.
+func MakePoolAttributes(pool HugepagesPool) map[resourceapi.QualifiedName]resourceapi.DeviceAttribute {
+	return map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		PoolPagesAttribute:     {IntValue: ptr.To(pool.Total)},
+		PoolFreePagesAttribute: {IntValue: ptr.To(pool.Free)},
+	}
 }
 
 func MakeCapacity(sp types.Span) map[resourceapi.QualifiedName]resourceapi.DeviceCapacity {
 	name := sp.CapacityName()
-	capQty := resource.NewQuantity(sp.Amount, resource.BinarySI)
-	stepQty := resource.NewQuantity(int64(sp.Pagesize), resource.BinarySI)
+	capQty := unitconv.SizeInBytesToQuantity(sp.Amount)
+	stepQty := unitconv.SizeInBytesToQuantity(int64(sp.Pagesize))
 	return map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
 		name: {
 			Value: *capQty,
 			RequestPolicy: &resourceapi.CapacityRequestPolicy{
-				Default: resource.NewQuantity(int64(sp.MinimumAllocatable()), resource.BinarySI),
+				Default: unitconv.SizeInBytesToQuantity(int64(sp.MinimumAllocatable())),
 				ValidRange: &resourceapi.CapacityRequestPolicyRange{
 					Min:  stepQty,
 					Step: stepQty,
@@ -70,10 +183,27 @@ func MakeCapacity(sp types.Span) map[resourceapi.QualifiedName]resourceapi.Devic
 	}
 }
 
-func ToDevice(sp types.Span) resourceapi.Device {
+// MakePagesCapacity creates the alternate capacity of the hugepages device of the span, in pages.
+// The scheduler accounts each capacity on its own: the requests in bytes consume the default of one page,
+// and the requests in pages consume the default of one page worth of bytes.
+func MakePagesCapacity(sp types.Span) resourceapi.DeviceCapacity {
+	return resourceapi.DeviceCapacity{
+		Value: *resource.NewQuantity(sp.Pages(), resource.DecimalSI),
+		RequestPolicy: &resourceapi.CapacityRequestPolicy{
+			Default: resource.NewQuantity(1, resource.DecimalSI),
+			ValidRange: &resourceapi.CapacityRequestPolicyRange{
+				Min:  resource.NewQuantity(1, resource.DecimalSI),
+				Step: resource.NewQuantity(1, resource.DecimalSI),
+				Max:  resource.NewQuantity(sp.Pages(), resource.DecimalSI),
+			},
+		},
+	}
+}
+
+func ToDevice(sp types.Span, loc *Locality) resourceapi.Device {
 	return resourceapi.Device{
 		Name:                     MakeDeviceName(sp.Name()),
-		Attributes:               MakeAttributes(sp),
+		Attributes:               MakeAttributes(sp, loc),
 		Capacity:                 MakeCapacity(sp),
 		AllowMultipleAllocations: ptr.To(true),
 	}
@@ -89,3 +219,37 @@ func ToDevice(sp types.Span) resourceapi.Device {
 var MakeDeviceName = func(devName string) string {
 	return strings.ToLower(devName) + "-" + k8srand.String(6)
 }
+
+// DeviceNaming is how the devices are named.
+type DeviceNaming string
+
+const (
+	// DeviceNamingRandom: a random suffix, changing at each discovery. This is the default.
+	DeviceNamingRandom DeviceNaming = "random"
+	// DeviceNamingStable: a suffix derived from the node, the NUMA zone and the resource, stable across the restarts.
+	DeviceNamingStable DeviceNaming = "stable"
+)
+
+func ParseDeviceNaming(s string) (DeviceNaming, error) {
+	dn := DeviceNaming(strings.ToLower(s))
+	switch dn {
+	case DeviceNamingRandom, DeviceNamingStable:
+		return dn, nil
+	default:
+		return DeviceNamingRandom, fmt.Errorf("unsupported device naming: %q", s)
+	}
+}
+
+// MakeStableDeviceName creates a device name which is the same for the same node, NUMA zone and resource,
+// to let the external automation key on the device names. The suffix is a hash, so the names stay short
+// and valid whatever the node name is. `attempt` is mixed in the hash to resolve the collisions.
+func MakeStableDeviceName(nodeName string, sp types.Span, attempt int) string {
+	hasher := fnv.New32a()
+	// writing on a hash never fails
+	if sp.Pool != "" {
+		_, _ = fmt.Fprintf(hasher, "%s/%d/%s/%s/%d", nodeName, sp.NUMAZone, sp.Name(), sp.Pool, attempt)
+	} else {
+		_, _ = fmt.Fprintf(hasher, "%s/%d/%s/%d", nodeName, sp.NUMAZone, sp.Name(), attempt)
+	}
+	return fmt.Sprintf("%s-%08x", strings.ToLower(sp.Name()), hasher.Sum32())
+}
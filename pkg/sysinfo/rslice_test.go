@@ -17,11 +17,14 @@
 package sysinfo
 
 import (
+	"maps"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
 
 	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/dynamic-resource-allocation/deviceattribute"
 	"k8s.io/utils/ptr"
 
 	"github.com/ffromani/dra-driver-memory/pkg/types"
@@ -54,6 +57,25 @@ func TestMakeAttributes(t *testing.T) {
 				"dra.net/numaNode":                         {IntValue: ptr.To(int64(0))},
 			},
 		},
+		{
+			span: types.Span{
+				ResourceIdent: types.ResourceIdent{
+					Kind:     types.Hugepages,
+					Pagesize: uint64(2 * 1 << 20),
+				},
+				Amount:   1, // not really relevant
+				NUMAZone: 0,
+				Pool:     "dpdk",
+			},
+			expected: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				StandardDeviceAttributePrefix + "numaNode": {IntValue: ptr.To(int64(0))},
+				StandardDeviceAttributePrefix + "pageSize": {StringValue: ptr.To("2Mi")},
+				StandardDeviceAttributePrefix + "hugeTLB":  {BoolValue: ptr.To(true)},
+				"dra.cpu/numaNodeID":                       {IntValue: ptr.To(int64(0))},
+				"dra.net/numaNode":                         {IntValue: ptr.To(int64(0))},
+				PoolAttribute:                              {StringValue: ptr.To("dpdk")},
+			},
+		},
 		{
 			span: types.Span{
 				ResourceIdent: types.ResourceIdent{
@@ -120,6 +142,7 @@ func TestMakeAttributes(t *testing.T) {
 				StandardDeviceAttributePrefix + "hugeTLB":  {BoolValue: ptr.To(false)},
 				"dra.cpu/numaNodeID":                       {IntValue: ptr.To(int64(0))},
 				"dra.net/numaNode":                         {IntValue: ptr.To(int64(0))},
+				SoftLimitAttribute:                         {BoolValue: ptr.To(true)},
 			},
 		},
 		{
@@ -137,16 +160,188 @@ func TestMakeAttributes(t *testing.T) {
 				StandardDeviceAttributePrefix + "hugeTLB":  {BoolValue: ptr.To(false)},
 				"dra.cpu/numaNodeID":                       {IntValue: ptr.To(int64(2))},
 				"dra.net/numaNode":                         {IntValue: ptr.To(int64(2))},
+				SoftLimitAttribute:                         {BoolValue: ptr.To(true)},
 			},
 		},
 	}
 
 	for _, tcase := range testcases {
 		t.Run(tcase.span.String(), func(t *testing.T) {
-			got := MakeAttributes(tcase.span)
+			got := MakeAttributes(tcase.span, nil)
 			if diff := cmp.Diff(tcase.expected, got); diff != "" {
 				t.Fatalf("unexpected diff: %v", diff)
 			}
 		})
 	}
 }
+
+func TestMakeAttributesAlignment(t *testing.T) {
+	type testcase struct {
+		name     string
+		loc      Locality
+		expected map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
+	}
+
+	span := types.Span{
+		ResourceIdent: types.ResourceIdent{
+			Kind:     types.Hugepages,
+			Pagesize: uint64(1 << 30),
+		},
+		Amount:   4 * (1 << 30),
+		NUMAZone: 1,
+	}
+	baseAttrs := MakeAttributes(span, nil)
+
+	testcases := []testcase{
+		{
+			name: "unknown socket, no PCIe roots",
+			loc:  Locality{CPUSocketID: -1},
+		},
+		{
+			name: "single PCIe root",
+			loc:  Locality{CPUSocketID: 1, PCIeRoots: []string{"pci0000:80"}},
+			expected: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				deviceattribute.StandardDeviceAttributePCIeRoot: {StringValue: ptr.To("pci0000:80")},
+				StandardDeviceAttributePrefix + "cpuSocketID":   {IntValue: ptr.To(int64(1))},
+				"dra.cpu/socketID": {IntValue: ptr.To(int64(1))},
+			},
+		},
+		{
+			name: "multiple PCIe roots",
+			loc:  Locality{CPUSocketID: 0, PCIeRoots: []string{"pci0000:00", "pci0000:3a"}},
+			expected: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				StandardDeviceAttributePrefix + "cpuSocketID": {IntValue: ptr.To(int64(0))},
+				"dra.cpu/socketID": {IntValue: ptr.To(int64(0))},
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			expected := maps.Clone(baseAttrs)
+			maps.Copy(expected, tcase.expected)
+			got := MakeAttributes(span, &tcase.loc)
+			if diff := cmp.Diff(expected, got); diff != "" {
+				t.Fatalf("unexpected diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestMakeFreeCapacityAttributes(t *testing.T) {
+	type testcase struct {
+		name        string
+		allocated   int64
+		expectedB   int64
+		expectedPct int64
+	}
+
+	span := types.Span{
+		ResourceIdent: types.ResourceIdent{
+			Kind:     types.Hugepages,
+			Pagesize: uint64(2 * 1 << 20),
+		},
+		Amount:   1024 * (2 * 1 << 20),
+		NUMAZone: 1,
+	}
+
+	testcases := []testcase{
+		{
+			name:        "all free",
+			allocated:   0,
+			expectedB:   1024 * (2 * 1 << 20),
+			expectedPct: 100,
+		},
+		{
+			name:        "quarter allocated",
+			allocated:   256 * (2 * 1 << 20),
+			expectedB:   768 * (2 * 1 << 20),
+			expectedPct: 75,
+		},
+		{
+			name:        "overcommitted",
+			allocated:   2048 * (2 * 1 << 20),
+			expectedB:   0,
+			expectedPct: 0,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got := MakeFreeCapacityAttributes(span, tcase.allocated)
+			expected := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+				FreeBytesAttribute:   {IntValue: ptr.To(tcase.expectedB)},
+				FreePercentAttribute: {IntValue: ptr.To(tcase.expectedPct)},
+			}
+			if diff := cmp.Diff(expected, got); diff != "" {
+				t.Fatalf("unexpected diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestMakePoolAttributes(t *testing.T) {
+	got := MakePoolAttributes(HugepagesPool{Total: 1024, Free: 256})
+	expected := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+		PoolPagesAttribute:     {IntValue: ptr.To(int64(1024))},
+		PoolFreePagesAttribute: {IntValue: ptr.To(int64(256))},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatalf("unexpected diff: %v", diff)
+	}
+}
+
+func TestMakeDefaultHugePageSizeAttribute(t *testing.T) {
+	require.Equal(t, "2Mi", *MakeDefaultHugePageSizeAttribute(2 * 1024 * 1024).StringValue)
+	require.Equal(t, "1Gi", *MakeDefaultHugePageSizeAttribute(1024 * 1024 * 1024).StringValue)
+}
+
+func TestMakePagesCapacity(t *testing.T) {
+	span := types.Span{
+		ResourceIdent: types.ResourceIdent{
+			Kind:     types.Hugepages,
+			Pagesize: 2 * 1024 * 1024,
+		},
+		Amount:   512 * 1024 * 1024,
+		NUMAZone: 0,
+	}
+	got := MakePagesCapacity(span)
+	require.Equal(t, int64(256), got.Value.Value())
+	require.NotNil(t, got.RequestPolicy)
+	require.Equal(t, int64(1), got.RequestPolicy.Default.Value())
+	require.Equal(t, int64(1), got.RequestPolicy.ValidRange.Min.Value())
+	require.Equal(t, int64(1), got.RequestPolicy.ValidRange.Step.Value())
+	require.Equal(t, int64(256), got.RequestPolicy.ValidRange.Max.Value())
+}
+
+func TestParseDeviceNaming(t *testing.T) {
+	dn, err := ParseDeviceNaming("Stable")
+	require.NoError(t, err)
+	require.Equal(t, DeviceNamingStable, dn)
+	dn, err = ParseDeviceNaming("random")
+	require.NoError(t, err)
+	require.Equal(t, DeviceNamingRandom, dn)
+	_, err = ParseDeviceNaming("sequential")
+	require.Error(t, err)
+}
+
+func TestMakeStableDeviceName(t *testing.T) {
+	span := types.Span{
+		ResourceIdent: types.ResourceIdent{
+			Kind:     types.Hugepages,
+			Pagesize: 2 * 1024 * 1024,
+		},
+		NUMAZone: 1,
+	}
+	name := MakeStableDeviceName("worker-0", span, 0)
+	require.Regexp(t, `^hugepages-2mi-[0-9a-f]{8}$`, name)
+	require.Equal(t, name, MakeStableDeviceName("worker-0", span, 0), "names must be deterministic")
+	require.NotEqual(t, name, MakeStableDeviceName("worker-1", span, 0))
+	require.NotEqual(t, name, MakeStableDeviceName("worker-0", span, 1))
+	otherZone := span
+	otherZone.NUMAZone = 0
+	require.NotEqual(t, name, MakeStableDeviceName("worker-0", otherZone, 0))
+	subPool := span
+	subPool.Pool = "dpdk"
+	require.NotEqual(t, name, MakeStableDeviceName("worker-0", subPool, 0))
+}
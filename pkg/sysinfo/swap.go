@@ -0,0 +1,77 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// SwapInfo describes the swap configuration of the node.
+type SwapInfo struct {
+	MemTotalBytes  uint64 `json:"memTotalBytes"`
+	SwapTotalBytes uint64 `json:"swapTotalBytes"`
+}
+
+func (si SwapInfo) Enabled() bool {
+	return si.SwapTotalBytes > 0
+}
+
+// GetSwapInfo reads the swap configuration from `procRoot`/proc/meminfo.
+func GetSwapInfo(lh logr.Logger, procRoot string) (SwapInfo, error) {
+	var si SwapInfo
+	src, err := os.Open(filepath.Join(procRoot, "proc", "meminfo"))
+	if err != nil {
+		return si, err
+	}
+	//nolint:errcheck
+	defer src.Close()
+
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		// MemTotal:       32562256 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[2] != "kB" {
+			continue
+		}
+		var dst *uint64
+		switch fields[0] {
+		case "MemTotal:":
+			dst = &si.MemTotalBytes
+		case "SwapTotal:":
+			dst = &si.SwapTotalBytes
+		default:
+			continue
+		}
+		val, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return si, fmt.Errorf("parsing %q: %w", fields[0], err)
+		}
+		*dst = val * 1024
+	}
+	if err := scanner.Err(); err != nil {
+		return si, err
+	}
+	lh.V(2).Info("swap info", "memTotalBytes", si.MemTotalBytes, "swapTotalBytes", si.SwapTotalBytes)
+	return si, nil
+}
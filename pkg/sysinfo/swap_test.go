@@ -0,0 +1,87 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+)
+
+const meminfoSwap = `MemTotal:       32562256 kB
+MemFree:         1843412 kB
+MemAvailable:   17428840 kB
+SwapCached:        10496 kB
+SwapTotal:       8388604 kB
+SwapFree:        8120316 kB
+HugePages_Total:       0
+Hugepagesize:       2048 kB
+`
+
+const meminfoNoSwap = `MemTotal:       32562256 kB
+MemFree:         1843412 kB
+SwapTotal:             0 kB
+SwapFree:              0 kB
+`
+
+func TestGetSwapInfo(t *testing.T) {
+	type testcase struct {
+		name     string
+		meminfo  string
+		expected SwapInfo
+		enabled  bool
+	}
+
+	testcases := []testcase{
+		{
+			name:    "swap enabled",
+			meminfo: meminfoSwap,
+			expected: SwapInfo{
+				MemTotalBytes:  32562256 * 1024,
+				SwapTotalBytes: 8388604 * 1024,
+			},
+			enabled: true,
+		},
+		{
+			name:    "swap disabled",
+			meminfo: meminfoNoSwap,
+			expected: SwapInfo{
+				MemTotalBytes: 32562256 * 1024,
+			},
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "proc"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "proc", "meminfo"), []byte(tcase.meminfo), 0644))
+			got, err := GetSwapInfo(testr.New(t), tmpDir)
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+			require.Equal(t, tcase.enabled, got.Enabled())
+		})
+	}
+}
+
+func TestGetSwapInfoMissing(t *testing.T) {
+	_, err := GetSwapInfo(testr.New(t), t.TempDir())
+	require.Error(t, err)
+}
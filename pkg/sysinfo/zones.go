@@ -0,0 +1,217 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	ghwcontext "github.com/jaypipes/ghw/pkg/context"
+	ghwmemory "github.com/jaypipes/ghw/pkg/memory"
+	ghwopt "github.com/jaypipes/ghw/pkg/option"
+	"golang.org/x/sync/errgroup"
+
+	"k8s.io/utils/cpuset"
+)
+
+// We read the zones ourselves instead of using the ghw topology, which also walks
+// the cores and the caches of all the CPUs of the machine, which we don't need.
+// On big machines, the zones have many memory blocks and hugepage sizes to read,
+// so we read the zones concurrently.
+
+// maxZoneReaders bounds the zones read concurrently.
+const maxZoneReaders = 8
+
+// localDistance is the distance of a zone from itself, see the ACPI SLIT.
+const localDistance = 10
+
+// GetZones returns the NUMA zones of the machine, sorted by ID.
+func GetZones(lh logr.Logger, sysRoot string) ([]Zone, error) {
+	return getZones(lh, sysRoot, maxZoneReaders)
+}
+
+func getZones(lh logr.Logger, sysRoot string, readers int) ([]Zone, error) {
+	zoneIDs, err := listZoneIDs(sysRoot)
+	if err != nil {
+		return nil, err
+	}
+	lh.V(4).Info("reading NUMA zones", "zones", len(zoneIDs), "readers", readers)
+
+	zones := make([]Zone, len(zoneIDs))
+	ghwCtx := ghwcontext.New(ghwopt.WithChroot(sysRoot))
+	err = ghwCtx.Do(func() error {
+		var eg errgroup.Group
+		eg.SetLimit(readers)
+		for idx, zoneID := range zoneIDs {
+			eg.Go(func() error {
+				zone, err := readZone(ghwCtx, sysRoot, zoneID)
+				if err != nil {
+					return fmt.Errorf("reading NUMA zone %d: %w", zoneID, err)
+				}
+				zones[idx] = zone
+				return nil
+			})
+		}
+		return eg.Wait()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func readZone(ghwCtx *ghwcontext.Context, sysRoot string, zoneID int) (Zone, error) {
+	if !hasZones(sysRoot) {
+		return readMachineZone(sysRoot)
+	}
+	distances, err := readDistances(sysRoot, zoneID)
+	if err != nil {
+		return Zone{}, err
+	}
+	area, err := ghwmemory.AreaForNode(ghwCtx, zoneID)
+	if err != nil {
+		return Zone{}, err
+	}
+	return Zone{
+		ID:        zoneID,
+		Distances: distances,
+		Memory:    area,
+	}, nil
+}
+
+// IsZoneGone returns true if the NUMA zone is not on the machine anymore, e.g. after a memory hot-unplug.
+// The offlined zones can stay listed with no memory, so a zone missing in has_memory, or with
+// no MemTotal, is gone as well. If the zones cannot be read, the zone is assumed present:
+// only the zones surely gone are reported.
+func IsZoneGone(sysRoot string, zoneID int64) bool {
+	zoneIDs, err := listZoneIDs(sysRoot)
+	if err != nil {
+		return false
+	}
+	if !slices.Contains(zoneIDs, int(zoneID)) {
+		return true
+	}
+	if !hasZones(sysRoot) {
+		return false // the machine is the zone 0
+	}
+	if withMemory, err := readZonesWithMemory(sysRoot); err == nil && !withMemory.Contains(int(zoneID)) {
+		return true
+	}
+	memTotal, err := readZoneMemTotal(sysRoot, int(zoneID))
+	return err == nil && memTotal == 0
+}
+
+// readZonesWithMemory reads the zones with memory, as a list like "0-1,3".
+func readZonesWithMemory(sysRoot string) (cpuset.CPUSet, error) {
+	data, err := os.ReadFile(filepath.Join(zonesPath(sysRoot), "has_memory"))
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	// the zones lists have the same format as the CPU lists
+	return cpuset.Parse(strings.TrimSpace(string(data)))
+}
+
+// readZoneMemTotal reads the MemTotal of the zone in bytes, from lines like "Node 0 MemTotal:       262144 kB".
+func readZoneMemTotal(sysRoot string, zoneID int) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(zonesPath(sysRoot), "node"+strconv.Itoa(zoneID), "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "MemTotal:" {
+			continue
+		}
+		val, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return val * 1024, nil
+	}
+	return 0, fmt.Errorf("MemTotal not found for NUMA zone %d", zoneID)
+}
+
+// readMachineZone reads the memory of the whole machine as the zone 0, for the kernels without NUMA support.
+func readMachineZone(sysRoot string) (Zone, error) {
+	info, err := ghwmemory.New(ghwopt.WithChroot(sysRoot))
+	if err != nil {
+		return Zone{}, err
+	}
+	return Zone{
+		ID:        0,
+		Distances: []int{localDistance},
+		Memory:    &info.Area,
+	}, nil
+}
+
+// listZoneIDs returns the IDs of the NUMA zones, sorted. The kernels built without NUMA support
+// don't report the zones, and the machine is a single zone 0.
+func listZoneIDs(sysRoot string) ([]int, error) {
+	if !hasZones(sysRoot) {
+		return []int{0}, nil
+	}
+	entries, err := os.ReadDir(zonesPath(sysRoot))
+	if err != nil {
+		return nil, err
+	}
+	var zoneIDs []int
+	for _, entry := range entries {
+		zoneID, ok := strings.CutPrefix(entry.Name(), "node")
+		if !ok {
+			continue
+		}
+		val, err := strconv.Atoi(zoneID)
+		if err != nil {
+			continue // nodeN only
+		}
+		zoneIDs = append(zoneIDs, val)
+	}
+	slices.Sort(zoneIDs)
+	return zoneIDs, nil
+}
+
+func zonesPath(sysRoot string) string {
+	return filepath.Join(sysRoot, "sys", "devices", "system", "node")
+}
+
+func hasZones(sysRoot string) bool {
+	_, err := os.Stat(zonesPath(sysRoot))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+func readDistances(sysRoot string, zoneID int) ([]int, error) {
+	data, err := os.ReadFile(filepath.Join(zonesPath(sysRoot), "node"+strconv.Itoa(zoneID), "distance"))
+	if err != nil {
+		return nil, err
+	}
+	items := strings.Fields(string(data))
+	distances := make([]int, 0, len(items))
+	for _, item := range items {
+		dist, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		distances = append(distances, dist)
+	}
+	return distances, nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+const (
+	fakeBlockSize     = 128 * (1 << 20)
+	fakeHugepages     = 16
+	fakeFreeHugepages = 4
+)
+
+var fakePagesizesKB = []int{2048, 1048576}
+
+func TestGetZones(t *testing.T) {
+	sysRoot := makeFakeZones(t, 12, 8, fakePagesizesKB)
+
+	zones, err := GetZones(testr.New(t), sysRoot)
+	require.NoError(t, err)
+	require.Len(t, zones, 12)
+	for idx, zone := range zones {
+		require.Equal(t, idx, zone.ID, "zones must be sorted by ID") // node10 sorts before node2
+		require.Len(t, zone.Distances, 12)
+		require.Equal(t, 10, zone.Distances[idx])
+		require.Equal(t, int64(8*fakeBlockSize), zone.Memory.TotalPhysicalBytes)
+		require.Equal(t, int64(8*fakeBlockSize-(1<<20)), zone.Memory.TotalUsableBytes)
+		require.Len(t, zone.Memory.HugePageAmountsBySize, len(fakePagesizesKB))
+		for _, sizeKB := range fakePagesizesKB {
+			hpAmounts, ok := zone.Memory.HugePageAmountsBySize[uint64(sizeKB)*1024]
+			require.True(t, ok, "missing pagesize %dkB", sizeKB)
+			require.Equal(t, int64(fakeHugepages), hpAmounts.Total)
+			require.Equal(t, int64(fakeFreeHugepages), hpAmounts.Free)
+		}
+	}
+}
+
+func TestGetZonesBrokenZone(t *testing.T) {
+	sysRoot := makeFakeZones(t, 4, 2, fakePagesizesKB)
+	require.NoError(t, os.Remove(filepath.Join(sysRoot, "sys", "devices", "system", "node", "node3", "meminfo")))
+
+	_, err := GetZones(testr.New(t), sysRoot)
+	require.ErrorContains(t, err, "NUMA zone 3")
+}
+
+func TestGetZonesWithoutNUMA(t *testing.T) {
+	sysRoot := makeFakeZones(t, 1, 8, []int{2048})
+	// the kernels without NUMA support report only the hugepages of the machine
+	require.NoError(t, os.RemoveAll(filepath.Join(sysRoot, "sys", "devices", "system", "node")))
+	hpPath := filepath.Join(sysRoot, "sys", "kernel", "mm", "hugepages", "hugepages-2048kB")
+	require.NoError(t, os.WriteFile(filepath.Join(hpPath, "free_hugepages"), []byte("4\n"), 0644))
+
+	zoneIDs, err := listZoneIDs(sysRoot)
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, zoneIDs)
+
+	zones, err := GetZones(testr.New(t), sysRoot)
+	require.NoError(t, err)
+	require.Len(t, zones, 1)
+	require.Equal(t, 0, zones[0].ID)
+	require.Equal(t, []int{10}, zones[0].Distances)
+	require.Equal(t, int64(8*fakeBlockSize), zones[0].Memory.TotalUsableBytes)
+	hpAmounts, ok := zones[0].Memory.HugePageAmountsBySize[2*(1<<20)]
+	require.True(t, ok, "missing the hugepages of the machine")
+	require.Equal(t, int64(fakeHugepages), hpAmounts.Total)
+	require.Equal(t, int64(4), hpAmounts.Free)
+
+	require.False(t, IsZoneGone(sysRoot, 0))
+	require.True(t, IsZoneGone(sysRoot, 1))
+}
+
+func TestIsZoneGone(t *testing.T) {
+	sysRoot := makeFakeZones(t, 2, 2, fakePagesizesKB)
+	require.False(t, IsZoneGone(sysRoot, 0))
+	require.False(t, IsZoneGone(sysRoot, 1))
+	require.True(t, IsZoneGone(sysRoot, 2))
+
+	require.NoError(t, os.RemoveAll(filepath.Join(sysRoot, "sys", "devices", "system", "node", "node1")))
+	require.True(t, IsZoneGone(sysRoot, 1))
+	require.False(t, IsZoneGone(sysRoot, 0))
+
+	// can't tell without the zones
+	require.False(t, IsZoneGone(t.TempDir(), 0))
+}
+
+func TestIsZoneGoneWithoutMemory(t *testing.T) {
+	sysRoot := makeFakeZones(t, 3, 2, fakePagesizesKB)
+	nodePath := filepath.Join(sysRoot, "sys", "devices", "system", "node")
+
+	require.NoError(t, os.WriteFile(filepath.Join(nodePath, "has_memory"), []byte("0,2\n"), 0644))
+	require.False(t, IsZoneGone(sysRoot, 0))
+	require.True(t, IsZoneGone(sysRoot, 1))
+	require.False(t, IsZoneGone(sysRoot, 2))
+
+	require.NoError(t, os.WriteFile(filepath.Join(nodePath, "has_memory"), []byte("0-2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(nodePath, "node2", "meminfo"), []byte("Node 2 MemTotal:       0 kB\nNode 2 MemFree:        0 kB\n"), 0644))
+	require.False(t, IsZoneGone(sysRoot, 1))
+	require.True(t, IsZoneGone(sysRoot, 2))
+
+	// can't tell without the memory data
+	require.NoError(t, os.WriteFile(filepath.Join(nodePath, "has_memory"), []byte("garbage\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(nodePath, "node2", "meminfo")))
+	require.False(t, IsZoneGone(sysRoot, 2))
+}
+
+// BenchmarkGetZones reads a synthetic machine with 16 zones, like an 8-socket machine
+// with sub-NUMA clustering, with many memory blocks and hugepage sizes.
+func BenchmarkGetZones(b *testing.B) {
+	sysRoot := makeFakeZones(b, 16, 256, []int{64, 2048, 32768, 1048576})
+
+	for _, readers := range []int{1, maxZoneReaders} {
+		b.Run(fmt.Sprintf("readers=%d", readers), func(b *testing.B) {
+			for b.Loop() {
+				zones, err := getZones(logr.Discard(), sysRoot, readers)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(zones) != 16 {
+					b.Fatalf("unexpected zones: %d", len(zones))
+				}
+			}
+		})
+	}
+}
+
+// makeFakeZones creates the sysfs and procfs content read by GetZones, for zones with `blocks` memory blocks each.
+func makeFakeZones(tb testing.TB, zoneCount, blocks int, pagesizesKB []int) string {
+	tb.Helper()
+	pools := make([]fakesys.Pool, 0, len(pagesizesKB))
+	for _, sizeKB := range pagesizesKB {
+		pools = append(pools, fakesys.Pool{SizeKB: sizeKB, Total: fakeHugepages, Free: fakeFreeHugepages})
+	}
+	return fakesys.Make(tb, fakesys.Spec{
+		BlockSize: fakeBlockSize,
+		Zones:     fakesys.UniformZones(zoneCount, blocks, fakeBlockSize, pools...),
+	})
+}
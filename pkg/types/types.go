@@ -17,11 +17,13 @@
 package types
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	resourceapi "k8s.io/api/resource/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 )
@@ -78,12 +80,47 @@ func (ri ResourceIdent) NeedsHugeTLB() bool {
 	return ri.Kind != Memory
 }
 
+// The capacities of the devices. The hugepages devices can also publish their capacity in pages,
+// for the users who think in pages rather than in bytes.
+const (
+	CapacityNameSize  resourceapi.QualifiedName = "size"
+	CapacityNamePages resourceapi.QualifiedName = "pages"
+)
+
 func (ri ResourceIdent) CapacityName() resourceapi.QualifiedName {
 	// hugepages are represented as memory intentionally,
 	// to be closer to what kubelet did.
 	// We may revisit this in the future, but we don't want
 	// to diverge until and unless we have very strong reason to
-	return resourceapi.QualifiedName("size")
+	return CapacityNameSize
+}
+
+// ResolveRequest returns the bytes requested through the capacities in `requests`, and the capacity
+// expressing them, whose consumption is authoritative. Requests in pages are honored only by hugepages.
+// If both the size and the pages are requested, they must agree once the size is rounded up to the page.
+// Returns false if no capacity is requested.
+func (ri ResourceIdent) ResolveRequest(requests map[resourceapi.QualifiedName]int64) (resourceapi.QualifiedName, int64, bool, error) {
+	size, hasSize := requests[CapacityNameSize]
+	pages, hasPages := requests[CapacityNamePages]
+	if !hasPages || !ri.NeedsHugeTLB() {
+		return ri.CapacityName(), size, hasSize, nil
+	}
+	pagesize := int64(ri.Pagesize)
+	if !hasSize {
+		return CapacityNamePages, pages * pagesize, true, nil
+	}
+	if sizePages := (size + pagesize - 1) / pagesize; sizePages != pages {
+		return "", 0, false, fmt.Errorf("inconsistent request for %s: size %d is %d pages, but %d pages requested", ri.Name(), size, sizePages, pages)
+	}
+	return ri.CapacityName(), size, true, nil
+}
+
+// CapacityBytes returns the bytes of a value of the capacity `capName`.
+func (ri ResourceIdent) CapacityBytes(capName resourceapi.QualifiedName, value int64) int64 {
+	if capName == CapacityNamePages {
+		return value * int64(ri.Pagesize)
+	}
+	return value
 }
 
 func (ri ResourceIdent) MinimumAllocatable() uint64 {
@@ -98,9 +135,14 @@ type Span struct {
 	ResourceIdent
 	Amount   int64 // bytes
 	NUMAZone int64
+	// Pool is the name of the sub-pool the span is carved from, if the pool of the zone is split
+	Pool string
 }
 
 func (sp Span) String() string {
+	if sp.Pool != "" {
+		return fmt.Sprintf("%s size=%s numaZone=%d pool=%s", sp.Name(), unitconv.SizeInBytesToMinimizedString(uint64(sp.Amount)), sp.NUMAZone, sp.Pool)
+	}
 	return fmt.Sprintf("%s size=%s numaZone=%d", sp.Name(), unitconv.SizeInBytesToMinimizedString(uint64(sp.Amount)), sp.NUMAZone)
 }
 
@@ -109,26 +151,179 @@ func (sp Span) Pages() int64 {
 }
 
 func (sp Span) MakeAllocation(amount int64) Allocation {
-	return Allocation{
-		ResourceIdent: sp.ResourceIdent,
-		Amount:        amount,
-		NUMAZone:      sp.NUMAZone,
+	alloc := NewAllocation(sp.ResourceIdent, amount, sp.NUMAZone)
+	if sp.Pool != "" {
+		alloc.AmountByPool = map[string]map[int64]int64{
+			sp.Pool: {sp.NUMAZone: amount},
+		}
+	}
+	return alloc
+}
+
+// RoundingPolicy controls how the requested amounts which are not multiple of the page size are handled.
+type RoundingPolicy string
+
+const (
+	// RoundingPolicyRoundUp: round the amount up to the next page. This is the default.
+	RoundingPolicyRoundUp RoundingPolicy = "round-up"
+	// RoundingPolicyExact: the amount must be multiple of the page size, or the allocation fails.
+	RoundingPolicyExact RoundingPolicy = "exact"
+)
+
+func ParseRoundingPolicy(s string) (RoundingPolicy, error) {
+	rp := RoundingPolicy(strings.ToLower(s))
+	switch rp {
+	case RoundingPolicyRoundUp, RoundingPolicyExact:
+		return rp, nil
+	default:
+		return RoundingPolicyRoundUp, fmt.Errorf("unsupported rounding policy: %q", s)
+	}
+}
+
+// The reasons an amount cannot be allocated from a Span.
+var (
+	ErrInvalidAmount           = errors.New("invalid amount")
+	ErrNotPageAligned          = errors.New("amount not multiple of the page size")
+	ErrBelowMinimumAllocatable = errors.New("amount below the minimum allocatable")
+	ErrExceedsCapacity         = errors.New("amount exceeds the capacity")
+)
+
+// ValidateAmount checks that `amount` bytes can be allocated as they are from the Span:
+// the hugepages must be whole pages, and the memory must be at least the minimum allocatable.
+// The errors wrap one of the Err* errors of this package.
+func (sp Span) ValidateAmount(amount int64) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w %d for %s: must be positive", ErrInvalidAmount, amount, sp.Name())
+	}
+	if sp.NeedsHugeTLB() && amount%int64(sp.Pagesize) != 0 {
+		return fmt.Errorf("%w: %d for %s, page size %d", ErrNotPageAligned, amount, sp.Name(), sp.Pagesize)
+	}
+	if minimum := int64(sp.MinimumAllocatable()); amount < minimum {
+		return fmt.Errorf("%w: %d for %s, minimum %d", ErrBelowMinimumAllocatable, amount, sp.Name(), minimum)
 	}
+	if amount > sp.Amount {
+		return fmt.Errorf("%w: %d for %s", ErrExceedsCapacity, amount, sp.String())
+	}
+	return nil
 }
 
-// Currently, an Allocation currently can only be a proper subset of a Span.
+// MakeRoundedAllocation creates an Allocation of `amount` bytes, handling the amounts which are
+// not multiple of the page size according to the policy `rp`. The allocation must fit in the Span.
+func (sp Span) MakeRoundedAllocation(amount int64, rp RoundingPolicy) (Allocation, error) {
+	if amount <= 0 {
+		return Allocation{}, fmt.Errorf("%w %d for %s: must be positive", ErrInvalidAmount, amount, sp.Name())
+	}
+	pagesize := int64(sp.Pagesize)
+	if rem := amount % pagesize; rem != 0 {
+		if rp == RoundingPolicyExact {
+			return Allocation{}, fmt.Errorf("%w: %d for %s, page size %d", ErrNotPageAligned, amount, sp.Name(), pagesize)
+		}
+		amount += pagesize - rem
+	}
+	if amount > sp.Amount {
+		return Allocation{}, fmt.Errorf("%w: %d for %s", ErrExceedsCapacity, amount, sp.String())
+	}
+	return sp.MakeAllocation(amount), nil
+}
+
+// An Allocation is made by one or more subsets of Spans of the same resource, each on a different NUMA zone.
 type Allocation struct {
 	ResourceIdent
-	Amount   int64 // bytes
-	NUMAZone int64
+	Amount int64 // bytes, across all the NUMA zones
+	// AmountByZone holds the bytes allocated on each NUMA zone. The values add up to Amount.
+	AmountByZone map[int64]int64
+	// AmountByPool holds the bytes allocated from the sub-pools, by pool name and NUMA zone.
+	// The bytes allocated from the zones whose pool is not split are not included.
+	AmountByPool map[string]map[int64]int64
+}
+
+// NewAllocation creates a single-zone allocation.
+func NewAllocation(ident ResourceIdent, amount, numaZone int64) Allocation {
+	return Allocation{
+		ResourceIdent: ident,
+		Amount:        amount,
+		AmountByZone: map[int64]int64{
+			numaZone: amount,
+		},
+	}
 }
 
 func (ac Allocation) String() string {
-	return fmt.Sprintf("%s size=%s numaZone=%d", ac.Name(), unitconv.SizeInBytesToMinimizedString(uint64(ac.Amount)), ac.NUMAZone)
+	if numaZone, ok := ac.SingleZone(); ok {
+		return fmt.Sprintf("%s size=%s numaZone=%d", ac.Name(), unitconv.SizeInBytesToMinimizedString(uint64(ac.Amount)), numaZone)
+	}
+	var sb strings.Builder
+	for _, numaZone := range ac.NUMAZones() {
+		fmt.Fprintf(&sb, ",%d:%s", numaZone, unitconv.SizeInBytesToMinimizedString(uint64(ac.AmountByZone[numaZone])))
+	}
+	return fmt.Sprintf("%s size=%s numaZones=%s", ac.Name(), unitconv.SizeInBytesToMinimizedString(uint64(ac.Amount)), strings.TrimPrefix(sb.String(), ","))
+}
+
+// NUMAZones returns the sorted list of the NUMA zones the allocation spans.
+func (ac Allocation) NUMAZones() []int64 {
+	return slices.Sorted(maps.Keys(ac.AmountByZone))
+}
+
+// SingleZone returns the NUMA zone of the allocation, and true, if the allocation spans exactly one zone.
+func (ac Allocation) SingleZone() (int64, bool) {
+	if len(ac.AmountByZone) != 1 {
+		return 0, false
+	}
+	for numaZone := range ac.AmountByZone {
+		return numaZone, true
+	}
+	return 0, false // can't happen
+}
+
+func (ac Allocation) Clone() Allocation {
+	return Allocation{
+		ResourceIdent: ac.ResourceIdent,
+		Amount:        ac.Amount,
+		AmountByZone:  maps.Clone(ac.AmountByZone),
+		AmountByPool:  clonePoolAmounts(ac.AmountByPool),
+	}
+}
+
+func clonePoolAmounts(amounts map[string]map[int64]int64) map[string]map[int64]int64 {
+	if amounts == nil {
+		return nil
+	}
+	ret := make(map[string]map[int64]int64, len(amounts))
+	for pool, byZone := range amounts {
+		ret[pool] = maps.Clone(byZone)
+	}
+	return ret
+}
+
+// Merge returns a new Allocation adding the amounts of `other`, which must be an allocation of the same resource.
+func (ac Allocation) Merge(other Allocation) (Allocation, error) {
+	if ac.ResourceIdent != other.ResourceIdent {
+		return Allocation{}, fmt.Errorf("cannot merge allocations of different resources: %q and %q", ac.Name(), other.Name())
+	}
+	ret := ac.Clone()
+	if ret.AmountByZone == nil {
+		ret.AmountByZone = make(map[int64]int64, len(other.AmountByZone))
+	}
+	for numaZone, amount := range other.AmountByZone {
+		ret.AmountByZone[numaZone] += amount
+	}
+	for pool, byZone := range other.AmountByPool {
+		if ret.AmountByPool == nil {
+			ret.AmountByPool = make(map[string]map[int64]int64, len(other.AmountByPool))
+		}
+		if ret.AmountByPool[pool] == nil {
+			ret.AmountByPool[pool] = make(map[int64]int64, len(byZone))
+		}
+		for numaZone, amount := range byZone {
+			ret.AmountByPool[pool][numaZone] += amount
+		}
+	}
+	ret.Amount += other.Amount
+	return ret, nil
 }
 
 func (ac Allocation) ToQuantityString() string {
-	return resource.NewQuantity(ac.Amount, resource.BinarySI).String()
+	return unitconv.SizeInBytesToQuantityString(ac.Amount)
 }
 
 func (ac Allocation) Pages() int64 {
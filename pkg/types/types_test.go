@@ -21,6 +21,8 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
+
+	resourceapi "k8s.io/api/resource/v1"
 )
 
 func TestResourceIdentNameRoundTrip(t *testing.T) {
@@ -194,8 +196,8 @@ func TestResourceQuantityStringRepr(t *testing.T) {
 					Kind:     Memory,
 					Pagesize: 4 * 1 << 10,
 				},
-				Amount:   32 * 1 << 10,
-				NUMAZone: 1, // not really significant
+				Amount:       32 * 1 << 10,
+				AmountByZone: map[int64]int64{1: 32 * 1 << 10}, // not really significant
 			},
 			expected: "32Ki",
 		},
@@ -232,8 +234,29 @@ func TestSpanMakeAllocation(t *testing.T) {
 					Kind:     Memory,
 					Pagesize: 4 * 1 << 10,
 				},
-				Amount:   256 * 1 << 20,
-				NUMAZone: 1,
+				Amount:       256 * 1 << 20,
+				AmountByZone: map[int64]int64{1: 256 * 1 << 20},
+			},
+		},
+		{
+			name: "hugepages-pool-0",
+			span: Span{
+				ResourceIdent: ResourceIdent{
+					Kind:     Hugepages,
+					Pagesize: 2 * 1 << 20,
+				},
+				Amount:   1 * 1 << 30,
+				NUMAZone: 0,
+				Pool:     "dpdk",
+			},
+			expected: Allocation{
+				ResourceIdent: ResourceIdent{
+					Kind:     Hugepages,
+					Pagesize: 2 * 1 << 20,
+				},
+				Amount:       256 * 1 << 20,
+				AmountByZone: map[int64]int64{0: 256 * 1 << 20},
+				AmountByPool: map[string]map[int64]int64{"dpdk": {0: 256 * 1 << 20}},
 			},
 		},
 	}
@@ -293,8 +316,8 @@ func TestAllocationPages(t *testing.T) {
 					Kind:     Memory,
 					Pagesize: 4 * 1 << 10,
 				},
-				Amount:   32 * 1 << 10,
-				NUMAZone: 1, // not really significant
+				Amount:       32 * 1 << 10,
+				AmountByZone: map[int64]int64{1: 32 * 1 << 10}, // not really significant
 			},
 			expected: 8,
 		},
@@ -352,8 +375,8 @@ func TestAllocationString(t *testing.T) {
 					Kind:     Memory,
 					Pagesize: 4 * 1 << 10,
 				},
-				Amount:   1 * 1 << 30,
-				NUMAZone: 1, // not really significant
+				Amount:       1 * 1 << 30,
+				AmountByZone: map[int64]int64{1: 1 * 1 << 30}, // not really significant
 			},
 		},
 	}
@@ -367,3 +390,232 @@ func TestAllocationString(t *testing.T) {
 		})
 	}
 }
+
+func TestAllocationZones(t *testing.T) {
+	ident := ResourceIdent{
+		Kind:     Hugepages,
+		Pagesize: 2 * 1 << 20,
+	}
+
+	alloc := NewAllocation(ident, 64*1<<20, 1)
+	numaZone, ok := alloc.SingleZone()
+	require.True(t, ok)
+	require.Equal(t, int64(1), numaZone)
+	require.Equal(t, []int64{1}, alloc.NUMAZones())
+	require.Contains(t, alloc.String(), "numaZone=1")
+
+	merged, err := alloc.Merge(NewAllocation(ident, 32*1<<20, 0))
+	require.NoError(t, err)
+	require.Equal(t, int64(96*1<<20), merged.Amount)
+	require.Equal(t, map[int64]int64{0: 32 * 1 << 20, 1: 64 * 1 << 20}, merged.AmountByZone)
+	require.Equal(t, []int64{0, 1}, merged.NUMAZones())
+	_, ok = merged.SingleZone()
+	require.False(t, ok)
+	require.Contains(t, merged.String(), "numaZones=0:32Mi,1:64Mi")
+	// the source allocation must not be modified
+	require.Equal(t, map[int64]int64{1: 64 * 1 << 20}, alloc.AmountByZone)
+
+	merged, err = merged.Merge(NewAllocation(ident, 2*1<<20, 1))
+	require.NoError(t, err)
+	require.Equal(t, map[int64]int64{0: 32 * 1 << 20, 1: 66 * 1 << 20}, merged.AmountByZone)
+
+	_, err = alloc.Merge(NewAllocation(ResourceIdent{Kind: Memory, Pagesize: 4 * 1 << 10}, 1<<20, 1))
+	require.Error(t, err)
+}
+
+func TestAllocationMergePools(t *testing.T) {
+	ident := ResourceIdent{
+		Kind:     Hugepages,
+		Pagesize: 2 * 1 << 20,
+	}
+	dpdk := Span{ResourceIdent: ident, Amount: 1 << 30, NUMAZone: 0, Pool: "dpdk"}
+	general := Span{ResourceIdent: ident, Amount: 1 << 30, NUMAZone: 0, Pool: "general"}
+
+	alloc := dpdk.MakeAllocation(32 * 1 << 20)
+	merged, err := alloc.Merge(general.MakeAllocation(16 * 1 << 20))
+	require.NoError(t, err)
+	merged, err = merged.Merge(dpdk.MakeAllocation(8 * 1 << 20))
+	require.NoError(t, err)
+	require.Equal(t, map[int64]int64{0: 56 * 1 << 20}, merged.AmountByZone)
+	require.Equal(t, map[string]map[int64]int64{
+		"dpdk":    {0: 40 * 1 << 20},
+		"general": {0: 16 * 1 << 20},
+	}, merged.AmountByPool)
+	// the source allocation must not be modified
+	require.Equal(t, map[string]map[int64]int64{"dpdk": {0: 32 * 1 << 20}}, alloc.AmountByPool)
+
+	// the allocations from the zones not split have no pools
+	merged, err = NewAllocation(ident, 2*1<<20, 1).Merge(alloc)
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[int64]int64{"dpdk": {0: 32 * 1 << 20}}, merged.AmountByPool)
+	require.Nil(t, NewAllocation(ident, 2*1<<20, 1).Clone().AmountByPool)
+}
+
+func TestParseRoundingPolicy(t *testing.T) {
+	rp, err := ParseRoundingPolicy("Round-Up")
+	require.NoError(t, err)
+	require.Equal(t, RoundingPolicyRoundUp, rp)
+	rp, err = ParseRoundingPolicy("exact")
+	require.NoError(t, err)
+	require.Equal(t, RoundingPolicyExact, rp)
+	_, err = ParseRoundingPolicy("nearest")
+	require.Error(t, err)
+}
+
+func TestSpanMakeRoundedAllocation(t *testing.T) {
+	type testcase struct {
+		name        string
+		amount      int64
+		policy      RoundingPolicy
+		expected    int64
+		expectedErr error
+	}
+
+	span := Span{
+		ResourceIdent: ResourceIdent{
+			Kind:     Hugepages,
+			Pagesize: 2 * 1 << 20,
+		},
+		Amount:   64 * 1 << 20,
+		NUMAZone: 1,
+	}
+
+	testcases := []testcase{
+		{name: "aligned, round-up", amount: 32 * 1 << 20, policy: RoundingPolicyRoundUp, expected: 32 * 1 << 20},
+		{name: "aligned, exact", amount: 32 * 1 << 20, policy: RoundingPolicyExact, expected: 32 * 1 << 20},
+		{name: "unaligned, round-up", amount: 33 * 1 << 20, policy: RoundingPolicyRoundUp, expected: 34 * 1 << 20},
+		{name: "unaligned, exact", amount: 33 * 1 << 20, policy: RoundingPolicyExact, expectedErr: ErrNotPageAligned},
+		{name: "rounded up to capacity", amount: 63*1<<20 + 1, policy: RoundingPolicyRoundUp, expected: 64 * 1 << 20},
+		{name: "exceeds capacity", amount: 65 * 1 << 20, policy: RoundingPolicyRoundUp, expectedErr: ErrExceedsCapacity},
+		{name: "zero", amount: 0, policy: RoundingPolicyRoundUp, expectedErr: ErrInvalidAmount},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := span.MakeRoundedAllocation(tcase.amount, tcase.policy)
+			if tcase.expectedErr != nil {
+				require.ErrorIs(t, err, tcase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, span.MakeAllocation(tcase.expected), got)
+		})
+	}
+}
+
+func TestSpanValidateAmount(t *testing.T) {
+	hpSpan := Span{
+		ResourceIdent: ResourceIdent{Kind: Hugepages, Pagesize: 2 * 1 << 20},
+		Amount:        64 * 1 << 20,
+	}
+	memSpan := Span{
+		ResourceIdent: ResourceIdent{Kind: Memory, Pagesize: 4 * 1 << 10},
+		Amount:        1 << 30,
+	}
+
+	type testcase struct {
+		name        string
+		span        Span
+		amount      int64
+		expectedErr error
+	}
+
+	testcases := []testcase{
+		{name: "hugepages aligned", span: hpSpan, amount: 32 * 1 << 20},
+		{name: "hugepages whole capacity", span: hpSpan, amount: 64 * 1 << 20},
+		{name: "hugepages unaligned", span: hpSpan, amount: 33 * 1 << 20, expectedErr: ErrNotPageAligned},
+		{name: "hugepages exceeds capacity", span: hpSpan, amount: 66 * 1 << 20, expectedErr: ErrExceedsCapacity},
+		{name: "memory", span: memSpan, amount: 256 * 1 << 20},
+		{name: "memory unaligned", span: memSpan, amount: 256*1<<20 + 1},
+		{name: "memory below minimum", span: memSpan, amount: 512 * 1 << 10, expectedErr: ErrBelowMinimumAllocatable},
+		{name: "zero", span: memSpan, amount: 0, expectedErr: ErrInvalidAmount},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := tcase.span.ValidateAmount(tcase.amount)
+			if tcase.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tcase.expectedErr)
+		})
+	}
+}
+
+func TestResourceIdentResolveRequest(t *testing.T) {
+	hp2M := ResourceIdent{Kind: Hugepages, Pagesize: 2 * 1024 * 1024}
+	mem := ResourceIdent{Kind: Memory, Pagesize: 4 * 1024}
+
+	type testcase struct {
+		name        string
+		ident       ResourceIdent
+		requests    map[resourceapi.QualifiedName]int64
+		expName     resourceapi.QualifiedName
+		expBytes    int64
+		expFound    bool
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "nothing requested",
+			ident:    hp2M,
+			expName:  CapacityNameSize,
+			expFound: false,
+		},
+		{
+			name:     "size",
+			ident:    hp2M,
+			requests: map[resourceapi.QualifiedName]int64{CapacityNameSize: 16 * 1024 * 1024},
+			expName:  CapacityNameSize,
+			expBytes: 16 * 1024 * 1024,
+			expFound: true,
+		},
+		{
+			name:     "pages",
+			ident:    hp2M,
+			requests: map[resourceapi.QualifiedName]int64{CapacityNamePages: 8},
+			expName:  CapacityNamePages,
+			expBytes: 16 * 1024 * 1024,
+			expFound: true,
+		},
+		{
+			name:     "size and pages consistent",
+			ident:    hp2M,
+			requests: map[resourceapi.QualifiedName]int64{CapacityNameSize: 15 * 1024 * 1024, CapacityNamePages: 8},
+			expName:  CapacityNameSize,
+			expBytes: 15 * 1024 * 1024,
+			expFound: true,
+		},
+		{
+			name:        "size and pages inconsistent",
+			ident:       hp2M,
+			requests:    map[resourceapi.QualifiedName]int64{CapacityNameSize: 16 * 1024 * 1024, CapacityNamePages: 4},
+			expectedErr: true,
+		},
+		{
+			name:     "pages ignored by memory",
+			ident:    mem,
+			requests: map[resourceapi.QualifiedName]int64{CapacityNameSize: 1024 * 1024 * 1024, CapacityNamePages: 4},
+			expName:  CapacityNameSize,
+			expBytes: 1024 * 1024 * 1024,
+			expFound: true,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			capName, amount, found, err := tcase.ident.ResolveRequest(tcase.requests)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expName, capName)
+			require.Equal(t, tcase.expBytes, amount)
+			require.Equal(t, tcase.expFound, found)
+			require.Equal(t, amount, tcase.ident.CapacityBytes(capName, tcase.requests[capName]))
+		})
+	}
+}
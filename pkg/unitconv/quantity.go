@@ -0,0 +1,42 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unitconv
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// All the quantities we publish or report must be created through these helpers, so
+// users always see binary units (e.g. "32Gi", "31828224Ki") and never a mix of decimal
+// units and plain byte counts (e.g. "33332322304") depending on the code path.
+
+// SizeInBytesToQuantity returns the canonical quantity for `sizeInBytes`.
+// The quantity is rendered using the largest binary suffix which represents the value exactly.
+func SizeInBytesToQuantity(sizeInBytes int64) *resource.Quantity {
+	return resource.NewQuantity(sizeInBytes, resource.BinarySI)
+}
+
+// CanonicalQuantity returns a copy of `qty` in the canonical format. The value is preserved,
+// so for example "1G" becomes "1000000000" while "1024Mi" becomes "1Gi".
+func CanonicalQuantity(qty resource.Quantity) resource.Quantity {
+	return *SizeInBytesToQuantity(qty.Value())
+}
+
+// SizeInBytesToQuantityString is a shortcut for the string representation of the canonical quantity.
+func SizeInBytesToQuantityString(sizeInBytes int64) string {
+	return SizeInBytesToQuantity(sizeInBytes).String()
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unitconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestSizeInBytesToQuantityRoundTrip(t *testing.T) {
+	type testcase struct {
+		size     int64
+		expected string
+	}
+
+	testcases := []testcase{
+		{size: 0, expected: "0"},
+		{size: 1023, expected: "1023"},
+		{size: 4 * int64(KiB), expected: "4Ki"},
+		{size: 2 * int64(MiB), expected: "2Mi"},
+		{size: int64(GiB), expected: "1Gi"},
+		{size: 128 * 2 * int64(MiB), expected: "256Mi"},
+		{size: 33332322304, expected: "32551096Ki"}, // typical NUMA zone memory
+		{size: 16 * int64(TiB), expected: "16Ti"},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.expected, func(t *testing.T) {
+			qty := SizeInBytesToQuantity(tcase.size)
+			require.Equal(t, tcase.expected, qty.String())
+			require.Equal(t, tcase.expected, SizeInBytesToQuantityString(tcase.size))
+
+			parsed, err := resource.ParseQuantity(qty.String())
+			require.NoError(t, err)
+			require.Equal(t, tcase.size, parsed.Value())
+			canonical := CanonicalQuantity(parsed)
+			require.Equal(t, tcase.expected, canonical.String())
+		})
+	}
+}
+
+func TestCanonicalQuantity(t *testing.T) {
+	type testcase struct {
+		value    string
+		expected string
+	}
+
+	testcases := []testcase{
+		{value: "1024Mi", expected: "1Gi"},
+		{value: "2048Ki", expected: "2Mi"},
+		{value: "1G", expected: "1000000000"},
+		{value: "4096", expected: "4Ki"},
+		{value: "2097152", expected: "2Mi"},
+		{value: "0.5Gi", expected: "512Mi"},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.value, func(t *testing.T) {
+			qty := resource.MustParse(tcase.value)
+			got := CanonicalQuantity(qty)
+			require.Equal(t, tcase.expected, got.String())
+			require.Equal(t, qty.Value(), got.Value())
+		})
+	}
+}
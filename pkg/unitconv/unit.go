@@ -19,8 +19,10 @@ package unitconv
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -107,6 +109,81 @@ func MinimizedStringToSizeInBytes(sz string) (uint64, error) {
 	return 0, fmt.Errorf("unsupported unit: %q", sz)
 }
 
+var (
+	binaryMults = map[string]uint64{
+		"":   1,
+		"ki": KiB,
+		"mi": MiB,
+		"gi": GiB,
+		"ti": TiB,
+		"pi": PiB,
+		"ei": EiB,
+	}
+	decimalMults = map[string]uint64{
+		"k": KB,
+		"m": MB,
+		"g": GB,
+		"t": TB,
+		"p": PB,
+		"e": EB,
+	}
+)
+
+// ParseSizeInBytes is a tolerant parser meant for user input. Unlike MinimizedStringToSizeInBytes,
+// it accepts the long form of the units ("512MiB"), decimal units ("1GB", "1G" = 10^9 bytes),
+// plain byte counts ("4096") and it is case insensitive. Use SizeInBytesToMinimizedString
+// to get the canonical representation back.
+func ParseSizeInBytes(sz string) (uint64, error) {
+	return parseSize(sz, false)
+}
+
+// ParsePageSizeInBytes is like ParseSizeInBytes, but follows the kernel convention for page sizes,
+// where decimal units are actually binary: "2M", "2MB" and "2048kB" are all 2 MiB.
+func ParsePageSizeInBytes(sz string) (uint64, error) {
+	return parseSize(sz, true)
+}
+
+func parseSize(sz string, decimalAsBinary bool) (uint64, error) {
+	rval, unit, err := splitSize(sz)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(rval, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	mulp, ok := binaryMults[unit]
+	if !ok {
+		mulp, ok = decimalMults[unit]
+		if ok && decimalAsBinary {
+			mulp = binaryMults[unit+"i"]
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("unsupported unit: %q", sz)
+	}
+	if value > math.MaxUint64/mulp {
+		return 0, fmt.Errorf("size too large: %q", sz)
+	}
+	return value * mulp, nil
+}
+
+// splitSize splits the numeric part from the unit, which is normalized in lowercase without the trailing "B".
+func splitSize(sz string) (string, string, error) {
+	sz = strings.TrimSpace(sz)
+	idx := strings.IndexFunc(sz, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	if idx == -1 {
+		return sz, "", nil // plain bytes
+	}
+	if idx == 0 {
+		return "", "", fmt.Errorf("malformed string: %q", sz)
+	}
+	unit := strings.ToLower(strings.TrimSpace(sz[idx:]))
+	return sz[:idx], strings.TrimSuffix(unit, "b"), nil
+}
+
 func SizeInBytesToCGroupString(sizeInBytes uint64) string {
 	// translated from https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/mm/hugetlb_cgroup.c?id=eff48ddeab782e35e58ccc8853f7386bbae9dec4#n574
 	if sizeInBytes >= (1 << 30) {
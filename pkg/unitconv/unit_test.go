@@ -279,3 +279,60 @@ func TestCGroupStringToSizeRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSizeInBytes(t *testing.T) {
+	type testcase struct {
+		sval         string
+		uval         uint64
+		pageSizeUval uint64
+		fail         bool
+	}
+
+	testcases := []testcase{
+		// good cases, add them at the bottom of the section
+		{sval: "4096", uval: 4096, pageSizeUval: 4096},
+		{sval: "7B", uval: 7, pageSizeUval: 7},
+		{sval: "4Ki", uval: 4 * KiB, pageSizeUval: 4 * KiB},
+		{sval: "2048KiB", uval: 2 * MiB, pageSizeUval: 2 * MiB},
+		{sval: "512MiB", uval: 512 * MiB, pageSizeUval: 512 * MiB},
+		{sval: "512 MiB", uval: 512 * MiB, pageSizeUval: 512 * MiB},
+		{sval: "1gib", uval: GiB, pageSizeUval: GiB},
+		{sval: "1GB", uval: GB, pageSizeUval: GiB},
+		{sval: "1G", uval: GB, pageSizeUval: GiB},
+		{sval: "2M", uval: 2 * MB, pageSizeUval: 2 * MiB},
+		{sval: "2048kB", uval: 2048 * KB, pageSizeUval: 2 * MiB},
+		{sval: "3Ti", uval: 3 * TiB, pageSizeUval: 3 * TiB},
+		// bad cases, add them at the bottom of the section
+		{sval: "", fail: true},
+		{sval: "-1", fail: true},
+		{sval: "Gi", fail: true},
+		{sval: "1.5Gi", fail: true},
+		{sval: "42XB", fail: true},
+		{sval: "42iB", fail: true},
+		{sval: "16384Ei", fail: true},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(fmt.Sprintf("%s=%d", tcase.sval, tcase.uval), func(t *testing.T) {
+			got, err := ParseSizeInBytes(tcase.sval)
+			if tcase.fail {
+				require.Error(t, err)
+				_, err = ParsePageSizeInBytes(tcase.sval)
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.uval, got)
+
+			got, err = ParsePageSizeInBytes(tcase.sval)
+			require.NoError(t, err)
+			require.Equal(t, tcase.pageSizeUval, got)
+
+			// the canonical form must be accepted by the strict parser too
+			canonical := SizeInBytesToMinimizedString(got)
+			back, err := MinimizedStringToSizeInBytes(canonical)
+			require.NoError(t, err)
+			require.Equal(t, got, back)
+		})
+	}
+}
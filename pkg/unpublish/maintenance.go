@@ -0,0 +1,99 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unpublish
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The maintenance mode withdraws the resources on request, regardless of the unpublish mode and of the node
+// being cordoned, to let the claims on the node drain before servicing it, e.g. to repartition the hugepages.
+
+// AnnotationMaintenance marks the node in maintenance. The value is the time the maintenance started.
+const AnnotationMaintenance = "dra.memory/maintenance"
+
+// NodeState is what the driver needs to know about the node to decide whether to withdraw the resources.
+type NodeState struct {
+	// Cordoned is true if the node is unschedulable, which is the first step of draining it
+	Cordoned bool
+	// Maintenance is true if the node is marked in maintenance
+	Maintenance bool
+}
+
+// Withdrawn tells if the resources must be withdrawn: always in maintenance, if cordoned only if the mode is enabled.
+func (st NodeState) Withdrawn(md Mode) bool {
+	return st.Maintenance || (md.IsEnabled() && st.Cordoned)
+}
+
+// WithdrawMode is how the resources are withdrawn in `md`. Maintenance without an enabled mode taints the devices,
+// so the claims already allocated keep their devices, but no new claim is allocated on the node.
+func WithdrawMode(md Mode) Mode {
+	if md.IsEnabled() {
+		return md
+	}
+	return ModeTaint
+}
+
+func GetNodeState(ctx context.Context, cli kubernetes.Interface, nodeName string) (NodeState, error) {
+	node, err := cli.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return NodeState{}, err
+	}
+	return NodeStateOf(node), nil
+}
+
+// NodeStateOf returns the state of `node`, like read from a watch.
+func NodeStateOf(node *corev1.Node) NodeState {
+	_, inMaintenance := node.Annotations[AnnotationMaintenance]
+	return NodeState{
+		Cordoned:    node.Spec.Unschedulable,
+		Maintenance: inMaintenance,
+	}
+}
+
+// SetMaintenance marks the node in maintenance, or clears the mark.
+func SetMaintenance(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, nodeName string, enabled bool, now time.Time) error {
+	var value any // nil removes the annotation
+	if enabled {
+		value = now.UTC().Format(time.RFC3339)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				AnnotationMaintenance: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cli.CoreV1().Nodes().Patch(ctx, nodeName, k8stypes.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	lh.V(2).Info("maintenance mode updated", "node", nodeName, "enabled", enabled)
+	return nil
+}
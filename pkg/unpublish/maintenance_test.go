@@ -0,0 +1,94 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unpublish
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeStateWithdrawn(t *testing.T) {
+	type testcase struct {
+		name     string
+		state    NodeState
+		mode     Mode
+		expected bool
+	}
+
+	testcases := []testcase{
+		{name: "schedulable", mode: ModeTaint, expected: false},
+		{name: "cordoned, mode disabled", state: NodeState{Cordoned: true}, mode: ModeNone, expected: false},
+		{name: "cordoned, mode enabled", state: NodeState{Cordoned: true}, mode: ModeDelete, expected: true},
+		{name: "maintenance, mode disabled", state: NodeState{Maintenance: true}, mode: ModeNone, expected: true},
+		{name: "maintenance, mode enabled", state: NodeState{Maintenance: true}, mode: ModeTaint, expected: true},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			require.Equal(t, tcase.expected, tcase.state.Withdrawn(tcase.mode))
+		})
+	}
+
+	require.Equal(t, ModeTaint, WithdrawMode(ModeNone))
+	require.Equal(t, ModeTaint, WithdrawMode(ModeTaint))
+	require.Equal(t, ModeDelete, WithdrawMode(ModeDelete))
+}
+
+func TestSetMaintenance(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+	cli := fake.NewClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-0",
+			Annotations: map[string]string{
+				"example.com/unrelated": "keep",
+			},
+		},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+	})
+
+	st, err := GetNodeState(ctx, cli, "node-0")
+	require.NoError(t, err)
+	require.Equal(t, NodeState{Cordoned: true}, st)
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, SetMaintenance(ctx, lh, cli, "node-0", true, now))
+	node, err := cli.CoreV1().Nodes().Get(ctx, "node-0", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"example.com/unrelated": "keep",
+		AnnotationMaintenance:   "2026-01-02T03:04:05Z",
+	}, node.Annotations)
+	st, err = GetNodeState(ctx, cli, "node-0")
+	require.NoError(t, err)
+	require.Equal(t, NodeState{Cordoned: true, Maintenance: true}, st)
+
+	require.NoError(t, SetMaintenance(ctx, lh, cli, "node-0", false, now))
+	st, err = GetNodeState(ctx, cli, "node-0")
+	require.NoError(t, err)
+	require.False(t, st.Maintenance)
+
+	require.Error(t, SetMaintenance(ctx, lh, cli, "node-1", true, now))
+}
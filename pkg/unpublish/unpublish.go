@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unpublish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The driver can withdraw its ResourceSlices when it stops cleanly or while the node is drained,
+// so the scheduler stops placing memory claims on a node whose enforcer is going away.
+// The slices are published again as usual when the driver starts, or when the node is uncordoned.
+
+// Mode controls how the resources are withdrawn.
+type Mode string
+
+const (
+	// ModeNone: keep the resources published. This is the default.
+	ModeNone Mode = "none"
+	// ModeDelete: delete the ResourceSlices.
+	ModeDelete Mode = "delete"
+	// ModeTaint: keep the ResourceSlices, but taint all their devices. Requires the DRADeviceTaints feature gate.
+	ModeTaint Mode = "taint"
+)
+
+func ParseMode(s string) (Mode, error) {
+	md := Mode(strings.ToLower(s))
+	switch md {
+	case ModeNone, ModeDelete, ModeTaint:
+		return md, nil
+	default:
+		return ModeNone, fmt.Errorf("unsupported unpublish mode: %q", s)
+	}
+}
+
+func (md Mode) IsEnabled() bool {
+	return md == ModeDelete || md == ModeTaint
+}
+
+// TaintKey is the key of the taint added to the devices of the withdrawn resources.
+const TaintKey = "dra.memory/unpublished"
+
+// TaintDevices returns a copy of the devices with the TaintKey taint added, unless already present.
+// `since` is the time the resources were withdrawn; it must be stable across publications, to not update the slices in vain.
+func TaintDevices(devices []resourceapi.Device, since time.Time) []resourceapi.Device {
+	taint := resourceapi.DeviceTaint{
+		Key:       TaintKey,
+		Effect:    resourceapi.DeviceTaintEffectNoSchedule,
+		TimeAdded: &metav1.Time{Time: since},
+	}
+	tainted := make([]resourceapi.Device, 0, len(devices))
+	for _, dev := range devices {
+		hasTaint := slices.ContainsFunc(dev.Taints, func(tnt resourceapi.DeviceTaint) bool {
+			return tnt.Key == TaintKey
+		})
+		if !hasTaint {
+			dev.Taints = append(slices.Clone(dev.Taints), taint)
+		}
+		tainted = append(tainted, dev)
+	}
+	return tainted
+}
+
+// IsDraining returns true if the node is cordoned, which is the first step of draining it.
+func IsDraining(ctx context.Context, cli kubernetes.Interface, nodeName string) (bool, error) {
+	st, err := GetNodeState(ctx, cli, nodeName)
+	if err != nil {
+		return false, err
+	}
+	return st.Cordoned, nil
+}
+
+// Withdraw deletes or taints, according to the mode, the ResourceSlices of the driver on the node.
+// The driver must have stopped publishing the slices, or it would restore them.
+func Withdraw(ctx context.Context, lh logr.Logger, cli kubernetes.Interface, md Mode, driverName, nodeName string) error {
+	if !md.IsEnabled() {
+		return nil
+	}
+	sliceList, err := cli.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			resourceapi.ResourceSliceSelectorDriver:   driverName,
+			resourceapi.ResourceSliceSelectorNodeName: nodeName,
+		}).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("listing the resource slices: %w", err)
+	}
+	now := time.Now()
+	var errs []error
+	for idx := range sliceList.Items {
+		slice := &sliceList.Items[idx]
+		// double check: the field selectors are cheap to get wrong
+		if slice.Spec.Driver != driverName || slice.Spec.NodeName == nil || *slice.Spec.NodeName != nodeName {
+			continue
+		}
+		switch md {
+		case ModeDelete:
+			err = cli.ResourceV1().ResourceSlices().Delete(ctx, slice.Name, metav1.DeleteOptions{})
+		case ModeTaint:
+			slice.Spec.Devices = TaintDevices(slice.Spec.Devices, now)
+			_, err = cli.ResourceV1().ResourceSlices().Update(ctx, slice, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("withdrawing the resource slice %q: %w", slice.Name, err))
+			continue
+		}
+		lh.V(2).Info("withdrawn resource slice", "resourceSlice", slice.Name, "mode", md)
+	}
+	return errors.Join(errs...)
+}
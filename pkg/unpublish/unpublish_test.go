@@ -0,0 +1,144 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unpublish
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, val := range []string{"none", "delete", "Taint"} {
+		_, err := ParseMode(val)
+		require.NoError(t, err, "mode %q", val)
+	}
+	md, err := ParseMode("drain")
+	require.Error(t, err)
+	require.Equal(t, ModeNone, md)
+	require.False(t, ModeNone.IsEnabled())
+	require.True(t, ModeDelete.IsEnabled())
+	require.True(t, ModeTaint.IsEnabled())
+}
+
+func TestTaintDevices(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	devices := []resourceapi.Device{
+		{Name: "memory-0"},
+		{
+			Name: "hugepages-2m-0",
+			Taints: []resourceapi.DeviceTaint{
+				{Key: "example.com/broken", Effect: resourceapi.DeviceTaintEffectNoExecute},
+			},
+		},
+	}
+
+	tainted := TaintDevices(devices, since)
+	require.Len(t, tainted, 2)
+	require.Equal(t, []resourceapi.DeviceTaint{
+		{Key: TaintKey, Effect: resourceapi.DeviceTaintEffectNoSchedule, TimeAdded: &metav1.Time{Time: since}},
+	}, tainted[0].Taints)
+	require.Len(t, tainted[1].Taints, 2)
+	require.Equal(t, TaintKey, tainted[1].Taints[1].Key)
+	// the input is left untouched
+	require.Empty(t, devices[0].Taints)
+	require.Len(t, devices[1].Taints, 1)
+
+	again := TaintDevices(tainted, since.Add(time.Hour))
+	require.Equal(t, tainted, again, "the taint added twice")
+}
+
+func TestIsDraining(t *testing.T) {
+	ctx := context.Background()
+	cli := fake.NewClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	)
+
+	draining, err := IsDraining(ctx, cli, "node-0")
+	require.NoError(t, err)
+	require.False(t, draining)
+	draining, err = IsDraining(ctx, cli, "node-1")
+	require.NoError(t, err)
+	require.True(t, draining)
+	_, err = IsDraining(ctx, cli, "node-2")
+	require.Error(t, err)
+}
+
+func makeSlice(name, driverName, nodeName string) *resourceapi.ResourceSlice {
+	return &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:   driverName,
+			NodeName: ptr.To(nodeName),
+			Pool:     resourceapi.ResourcePool{Name: nodeName, ResourceSliceCount: 1},
+			Devices:  []resourceapi.Device{{Name: "memory-0"}},
+		},
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	lh := testr.New(t)
+	ctx := context.Background()
+
+	t.Run("none", func(t *testing.T) {
+		cli := fake.NewClientset(makeSlice("node-0-dra.memory-a", "dra.memory", "node-0"))
+		require.NoError(t, Withdraw(ctx, lh, cli, ModeNone, "dra.memory", "node-0"))
+		_, err := cli.ResourceV1().ResourceSlices().Get(ctx, "node-0-dra.memory-a", metav1.GetOptions{})
+		require.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cli := fake.NewClientset(
+			makeSlice("node-0-dra.memory-a", "dra.memory", "node-0"),
+			makeSlice("node-1-dra.memory-a", "dra.memory", "node-1"),
+			makeSlice("node-0-gpu.example.com-a", "gpu.example.com", "node-0"),
+		)
+		require.NoError(t, Withdraw(ctx, lh, cli, ModeDelete, "dra.memory", "node-0"))
+		_, err := cli.ResourceV1().ResourceSlices().Get(ctx, "node-0-dra.memory-a", metav1.GetOptions{})
+		require.True(t, apierrors.IsNotFound(err), "slice not deleted: %v", err)
+		for _, name := range []string{"node-1-dra.memory-a", "node-0-gpu.example.com-a"} {
+			_, err := cli.ResourceV1().ResourceSlices().Get(ctx, name, metav1.GetOptions{})
+			require.NoError(t, err, "slice %q", name)
+		}
+	})
+
+	t.Run("taint", func(t *testing.T) {
+		cli := fake.NewClientset(
+			makeSlice("node-0-dra.memory-a", "dra.memory", "node-0"),
+			makeSlice("node-0-gpu.example.com-a", "gpu.example.com", "node-0"),
+		)
+		require.NoError(t, Withdraw(ctx, lh, cli, ModeTaint, "dra.memory", "node-0"))
+		slice, err := cli.ResourceV1().ResourceSlices().Get(ctx, "node-0-dra.memory-a", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Len(t, slice.Spec.Devices[0].Taints, 1)
+		require.Equal(t, TaintKey, slice.Spec.Devices[0].Taints[0].Key)
+		other, err := cli.ResourceV1().ResourceSlices().Get(ctx, "node-0-gpu.example.com-a", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Empty(t, other.Spec.Devices[0].Taints)
+	})
+}
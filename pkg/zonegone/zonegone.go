@@ -0,0 +1,161 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zonegone
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A NUMA zone can go away, e.g. on memory hot-unplug, after the scheduler allocated the claims on its devices,
+// and before the kubelet prepares them. The driver discovers the zones only when it starts, so it keeps publishing
+// the devices of the zone, and the claims would fail later and opaquely, when the containers get pinned.
+// The driver checks the zone of each device when preparing the claims, and when the zone is gone it fails
+// the preparation telling why, and stops publishing the devices of the zone, until the zone comes back.
+
+// Mode controls what the driver does with the devices of the zones gone.
+type Mode string
+
+const (
+	// ModeFail: fail the preparation of the claims, and withdraw the devices of the zone. This is the default.
+	// The kubelet keeps retrying the preparation, until the zone comes back or the pod is deleted.
+	ModeFail Mode = "fail"
+	// ModeTaint: like ModeFail, but keep publishing the devices of the zone tainted with the NoExecute effect,
+	// so the pods consuming them are evicted, and their claims deallocated and allocated again elsewhere.
+	// Requires the DRADeviceTaints feature gate.
+	ModeTaint Mode = "taint"
+)
+
+func ParseMode(s string) (Mode, error) {
+	md := Mode(strings.ToLower(s))
+	switch md {
+	case ModeFail, ModeTaint:
+		return md, nil
+	default:
+		return ModeFail, fmt.Errorf("unsupported zone gone mode: %q", s)
+	}
+}
+
+// ErrZoneGone is the error of the devices whose NUMA zone is gone.
+var ErrZoneGone = errors.New("NUMA zone gone")
+
+// TaintKey is the key of the taint added to the devices of the zones gone.
+const TaintKey = "dra.memory/zone-gone"
+
+// Tracker remembers the zones found gone, and the time they were found gone.
+// It is safe to use concurrently, e.g. preparing the claims and publishing the resources.
+type Tracker struct {
+	mode  Mode
+	mu    sync.Mutex
+	since map[int64]time.Time
+}
+
+func NewTracker(mode Mode) *Tracker {
+	return &Tracker{
+		mode:  mode,
+		since: make(map[int64]time.Time),
+	}
+}
+
+func (tr *Tracker) Mode() Mode {
+	return tr.mode
+}
+
+// MarkGone records the zone gone at `now`. Returns true if the zone was not known gone already.
+func (tr *Tracker) MarkGone(zoneID int64, now time.Time) bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if _, ok := tr.since[zoneID]; ok {
+		return false
+	}
+	tr.since[zoneID] = now
+	return true
+}
+
+// Zones returns the zones known gone, sorted.
+func (tr *Tracker) Zones() []int64 {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return slices.Sorted(maps.Keys(tr.since))
+}
+
+// Recheck forgets the zones back online, and returns them.
+func (tr *Tracker) Recheck(isGone func(zoneID int64) bool) []int64 {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var back []int64
+	for zoneID := range tr.since {
+		if isGone(zoneID) {
+			continue
+		}
+		delete(tr.since, zoneID)
+		back = append(back, zoneID)
+	}
+	slices.Sort(back)
+	return back
+}
+
+// Devices returns the devices to publish: the devices of the zones gone are left out or, in ModeTaint,
+// tainted copies. `zoneOf` tells the zone of the devices; the devices of unknown zone are kept as they are.
+func (tr *Tracker) Devices(devices []resourceapi.Device, zoneOf func(devName string) (int64, bool)) []resourceapi.Device {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.since) == 0 {
+		return devices
+	}
+	ret := make([]resourceapi.Device, 0, len(devices))
+	for _, dev := range devices {
+		zoneID, ok := zoneOf(dev.Name)
+		if !ok {
+			ret = append(ret, dev)
+			continue
+		}
+		since, gone := tr.since[zoneID]
+		switch {
+		case !gone:
+			ret = append(ret, dev)
+		case tr.mode == ModeTaint:
+			ret = append(ret, taintDevice(dev, since))
+		}
+	}
+	return ret
+}
+
+// taintDevice returns a copy of the device with the TaintKey taint added, unless already present.
+// `since` must be stable across publications, to not update the slices in vain.
+func taintDevice(dev resourceapi.Device, since time.Time) resourceapi.Device {
+	hasTaint := slices.ContainsFunc(dev.Taints, func(tnt resourceapi.DeviceTaint) bool {
+		return tnt.Key == TaintKey
+	})
+	if hasTaint {
+		return dev
+	}
+	dev.Taints = append(slices.Clone(dev.Taints), resourceapi.DeviceTaint{
+		Key:       TaintKey,
+		Effect:    resourceapi.DeviceTaintEffectNoExecute,
+		TimeAdded: &metav1.Time{Time: since},
+	})
+	return dev
+}
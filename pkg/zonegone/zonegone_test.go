@@ -0,0 +1,106 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zonegone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, val := range []string{"fail", "Taint"} {
+		_, err := ParseMode(val)
+		require.NoError(t, err, "mode %q", val)
+	}
+	md, err := ParseMode("ignore")
+	require.Error(t, err)
+	require.Equal(t, ModeFail, md)
+}
+
+func TestTrackerMarkGone(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tr := NewTracker(ModeFail)
+	require.Empty(t, tr.Zones())
+	require.True(t, tr.MarkGone(1, now))
+	require.False(t, tr.MarkGone(1, now.Add(time.Minute)), "zone marked gone twice")
+	require.True(t, tr.MarkGone(0, now))
+	require.Equal(t, []int64{0, 1}, tr.Zones())
+
+	back := tr.Recheck(func(zoneID int64) bool { return zoneID == 1 })
+	require.Equal(t, []int64{0}, back)
+	require.Equal(t, []int64{1}, tr.Zones())
+}
+
+func TestTrackerDevices(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	zones := map[string]int64{
+		"memory-0":       0,
+		"memory-1":       1,
+		"hugepages-2m-1": 1,
+	}
+	zoneOf := func(devName string) (int64, bool) {
+		zoneID, ok := zones[devName]
+		return zoneID, ok
+	}
+	devices := []resourceapi.Device{
+		{Name: "memory-0"},
+		{Name: "memory-1"},
+		{
+			Name: "hugepages-2m-1",
+			Taints: []resourceapi.DeviceTaint{
+				{Key: "example.com/broken", Effect: resourceapi.DeviceTaintEffectNoSchedule},
+			},
+		},
+		{Name: "unknown"},
+	}
+
+	t.Run("no zones gone", func(t *testing.T) {
+		tr := NewTracker(ModeTaint)
+		require.Equal(t, devices, tr.Devices(devices, zoneOf))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		tr := NewTracker(ModeFail)
+		tr.MarkGone(1, since)
+		got := tr.Devices(devices, zoneOf)
+		require.Equal(t, []resourceapi.Device{{Name: "memory-0"}, {Name: "unknown"}}, got)
+	})
+
+	t.Run("taint", func(t *testing.T) {
+		tr := NewTracker(ModeTaint)
+		tr.MarkGone(1, since)
+		got := tr.Devices(devices, zoneOf)
+		require.Len(t, got, 4)
+		require.Empty(t, got[0].Taints)
+		taint := resourceapi.DeviceTaint{Key: TaintKey, Effect: resourceapi.DeviceTaintEffectNoExecute, TimeAdded: &metav1.Time{Time: since}}
+		require.Equal(t, []resourceapi.DeviceTaint{taint}, got[1].Taints)
+		require.Len(t, got[2].Taints, 2)
+		require.Equal(t, taint, got[2].Taints[1])
+		require.Empty(t, got[3].Taints)
+		// the input is left untouched
+		require.Empty(t, devices[1].Taints)
+		require.Len(t, devices[2].Taints, 1)
+
+		again := tr.Devices(got, zoneOf)
+		require.Equal(t, got, again, "the taint added twice")
+	})
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ffromani/dra-driver-memory/pkg/failpoint"
+	"github.com/ffromani/dra-driver-memory/test/pkg/cgrouptree"
+	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
+	"github.com/ffromani/dra-driver-memory/test/pkg/node"
+	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+const (
+	// must match the plugin path of the driver, which holds the failpoint markers
+	driverPluginDir = "/var/lib/kubelet/plugins/dra.memory"
+	cdiSpecDir      = "/var/run/cdi"
+)
+
+// The chaos tests need the driver deployed with the failpoints enabled, so they are gated by their own label.
+var _ = ginkgo.Describe("Driver chaos", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier2", "chaos", "disruptive", "platform:kind"), func() {
+	var rootFxt *fixture.Fixture
+	var targetNode *corev1.Node
+	var dramemoryTesterImage string
+
+	ginkgo.BeforeAll(func(ctx context.Context) {
+		// early cheap check before to create the Fixture, so we use GinkgoLogr directly
+		dramemoryTesterImage = os.Getenv("DRAMEM_E2E_TEST_IMAGE")
+		gomega.Expect(dramemoryTesterImage).ToNot(gomega.BeEmpty(), "missing environment variable DRAMEM_E2E_TEST_IMAGE")
+		ginkgo.GinkgoLogr.Info("discovery image", "pullSpec", dramemoryTesterImage)
+
+		var err error
+
+		rootFxt, err = fixture.ForGinkgo()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot create root fixture: %v", err)
+		infraFxt := rootFxt.WithPrefix("infra")
+		gomega.Expect(infraFxt.Setup(ctx)).To(gomega.Succeed())
+		ginkgo.DeferCleanup(infraFxt.Teardown)
+
+		if targetNodeName := os.Getenv("DRAMEM_E2E_TARGET_NODE"); len(targetNodeName) > 0 {
+			targetNode, err = rootFxt.K8SClientset.CoreV1().Nodes().Get(ctx, targetNodeName, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot get worker node %q: %v", targetNodeName, err)
+		} else {
+			workerNodes, err := node.FindWorkers(ctx, infraFxt.K8SClientset)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot find worker nodes: %v", err)
+			gomega.Expect(workerNodes).ToNot(gomega.BeEmpty(), "no worker nodes detected")
+			targetNode = workerNodes[0] // pick random one, this is the simplest random pick
+		}
+		rootFxt.Log.Info("using worker node", "nodeName", targetNode.Name)
+
+		driverPod := getDriverPod(ctx, rootFxt, targetNode.Name)
+		if !hasFailpoint(driverPod, failpoint.PrepareAfterCDIWrite) {
+			fixture.Skip("driver pod %s/%s lacks the failpoint %q", driverPod.Namespace, driverPod.Name, failpoint.PrepareAfterCDIWrite)
+		}
+	})
+
+	ginkgo.When("the driver is killed while preparing a claim", ginkgo.Label("hugepages:2M"), func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			fxt = rootFxt.WithPrefix("chaos")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+
+			rsName, devName, ok := fxt.NodeHasMemoryResource(ctx, targetNode.Name, "2m", 32*(1<<20))
+			if !ok {
+				ginkgo.Skip("missing hugepages in resource slices")
+			}
+			fxt.Log.Info("found 2M hugepages device", "resourceSlice", rsName, "device", devName)
+
+			// rearm the failpoint, which fired in the previous runs
+			_, err := cgrouptree.DockerExec(ctx, targetNode.Name, "rm -f "+failpoint.MarkerPath(driverPluginDir, failpoint.PrepareAfterCDIWrite))
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should converge after the driver is killed between the CDI write and the container creation", func(ctx context.Context) {
+			driverRestarts := containerRestarts(getDriverPod(ctx, fxt, targetNode.Name))
+
+			fixture.By("creating a hugepages ResourceClaim on %q", fxt.Namespace.Name)
+			claim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, makeHugepages2MClaim(fxt.Namespace.Name, "hugepages-32m-chaos"), metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			// allocating over the claim proves the limits are correct after the recovery
+			fixture.By("creating a pod consuming the ResourceClaim on %q", fxt.Namespace.Name)
+			testPod := makeHugepages2MPod(fxt.Namespace.Name, targetNode.Name, "pod-over-hugepages-chaos", dramemoryTesterImage, claim.Name, corev1.RestartPolicyNever, "-use-hugetlb=true", "-alloc-size=48Mi", "-should-fail")
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "pod stuck after the driver was killed")
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.FailedAsExpected))
+
+			fixture.By("checking the failpoint killed the driver on %q", targetNode.Name)
+			gomega.Expect(containerRestarts(getDriverPod(ctx, fxt, targetNode.Name))).To(gomega.BeNumerically(">", driverRestarts), "the driver was not killed")
+
+			fixture.By("deleting the pod")
+			err = fxt.K8SClientset.CoreV1().Pods(createdPod.Namespace).Delete(ctx, createdPod.Name, metav1.DeleteOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(pod.WaitToBeDeleted(ctx, fxt.K8SClientset, createdPod.Namespace, createdPod.Name)).To(gomega.Succeed())
+
+			fixture.By("checking the CDI device of the claim is removed from %q", targetNode.Name)
+			gomega.Eventually(func(g gomega.Gomega) {
+				out, err := cgrouptree.DockerExec(ctx, targetNode.Name, "ls "+cdiSpecDir)
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				g.Expect(string(out)).ToNot(gomega.ContainSubstring(string(claim.UID)), "orphan CDI device")
+			}).WithContext(ctx).WithTimeout(pod.PollTimeout).WithPolling(pod.PollInterval).Should(gomega.Succeed())
+		})
+	})
+})
+
+func getDriverPod(ctx context.Context, fxt *fixture.Fixture, nodeName string) *corev1.Pod {
+	ginkgo.GinkgoHelper()
+	driverPods, err := pod.FindOnNode(ctx, fxt.K8SClientset, pod.DriverNamespace, pod.DriverPodSelector, nodeName)
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(driverPods).To(gomega.HaveLen(1), "expected exactly one driver pod on %q", nodeName)
+	return &driverPods[0]
+}
+
+func hasFailpoint(driverPod *corev1.Pod, name failpoint.Name) bool {
+	for _, cnt := range driverPod.Spec.Containers {
+		for _, arg := range slices.Concat(cnt.Command, cnt.Args) {
+			val, ok := strings.CutPrefix(strings.TrimLeft(arg, "-"), "failpoints=")
+			if !ok {
+				continue
+			}
+			names, err := failpoint.Parse(val)
+			if err == nil && slices.Contains(names, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containerRestarts(driverPod *corev1.Pod) int32 {
+	var restarts int32
+	for _, cntSt := range driverPod.Status.ContainerStatuses {
+		restarts += cntSt.RestartCount
+	}
+	return restarts
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"os"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
+	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+const (
+	cpuDeviceClassName = "dra.cpu"
+	// cpuNUMANodeAttribute is published by dra-driver-cpu, and by us for compatibility
+	cpuNUMANodeAttribute = "dra.cpu/numaNodeID"
+)
+
+// The suite needs dra-driver-cpu deployed alongside the memory driver, so it is gated by its own label.
+var _ = ginkgo.Describe("NUMA alignment with dra-driver-cpu", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier1", "alignment", "dra-driver-cpu"), func() {
+	var rootFxt *fixture.Fixture
+	var dramemoryTesterImage string
+
+	ginkgo.BeforeAll(func(ctx context.Context) {
+		dramemoryTesterImage = os.Getenv("DRAMEM_E2E_TEST_IMAGE")
+		gomega.Expect(dramemoryTesterImage).ToNot(gomega.BeEmpty(), "missing environment variable DRAMEM_E2E_TEST_IMAGE")
+		ginkgo.GinkgoLogr.Info("discovery image", "pullSpec", dramemoryTesterImage)
+
+		var err error
+		rootFxt, err = fixture.ForGinkgo()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot create root fixture: %v", err)
+
+		_, err = rootFxt.K8SClientset.ResourceV1().DeviceClasses().Get(ctx, cpuDeviceClassName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			fixture.Skip("missing DeviceClass %q: dra-driver-cpu not deployed", cpuDeviceClassName)
+		}
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot get DeviceClass %q: %v", cpuDeviceClassName, err)
+	})
+
+	ginkgo.When("requesting CPUs and memory in the same claim", func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			fxt = rootFxt.WithPrefix("cpualign")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should allocate the memory from the NUMA node of the CPUs", ginkgo.Label("positive"), func(ctx context.Context) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "cpus-memory-aligned",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "cpus",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: cpuDeviceClassName,
+										AllocationMode:  resourcev1.DeviceAllocationModeExactCount,
+										Count:           2,
+									},
+								},
+								{
+									Name: "mem",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.memory",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(256*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+							Constraints: []resourcev1.DeviceConstraint{
+								{
+									Requests:       []string{"cpus", "mem"},
+									MatchAttribute: ptr.To(resourcev1.FullyQualifiedName(cpuNUMANodeAttribute)),
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:    fxt.Namespace.Name,
+					GenerateName: "pod-with-cpus-memory-aligned-",
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-with-cpus-memory-aligned",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							Args:    []string{"-use-hugetlb=false", "-alloc-size=128Mi", "-numa-align=single", "-numa-align-cpus"},
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceMemory: *resource.NewQuantity(256*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "cpumem",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "cpumem",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+	})
+})
@@ -78,20 +78,55 @@ func ReportReason(fxt *fixture.Fixture, reason result.Reason) types.GomegaMatche
 			return false, errors.New("nil Pod")
 		}
 		lh := fxt.Log.WithValues("podUID", actual.UID, "namespace", actual.Namespace, "name", actual.Name)
-		ctx := context.TODO()
-		logs, err := pod.GetLogs(fxt.K8SClientset, ctx, actual.Namespace, actual.Name, actual.Spec.Containers[0].Name)
+		res, err := getResult(fxt, actual)
 		if err != nil {
 			return false, err
 		}
-		res, err := result.FromLogs(logs)
-		if err != nil {
-			return false, err
+		lh.Info("result", "schemaVersion", res.SchemaVersion, "reason", res.Status.Reason, "reasonCode", res.Status.ReasonCode, "message", res.Status.Message)
+		// the reason codes are stable across the versions of the tester, the reason strings may be not
+		if code := reason.Code(); code != result.ReasonCodeUnknown && res.Status.ReasonCode != result.ReasonCodeUnknown {
+			return res.Status.ReasonCode == code, nil
 		}
-		lh.Info("result", "reason", res.Status.Reason, "message", res.Status.Message)
 		return res.Status.Reason == reason, nil
 	}).WithTemplate("Pod {{.Actual.Namespace}}/{{.Actual.Name}} UID {{.Actual.UID}} did not fail with expected reason {{.Data}}").WithTemplateData(reason)
 }
 
+// ReportGrownSize matches pods whose dramemtester grew the allocation exactly to `size` bytes.
+func ReportGrownSize(fxt *fixture.Fixture, size uint64) types.GomegaMatcher {
+	return gcustom.MakeMatcher(func(actual *corev1.Pod) (bool, error) {
+		if actual == nil {
+			return false, errors.New("nil Pod")
+		}
+		lh := fxt.Log.WithValues("podUID", actual.UID, "namespace", actual.Namespace, "name", actual.Name)
+		res, err := getResult(fxt, actual)
+		if err != nil {
+			return false, err
+		}
+		if res.Allocation == nil {
+			return false, errors.New("missing allocation in the result")
+		}
+		lh.Info("result", "reason", res.Status.Reason, "grownSize", res.Allocation.GrownSize)
+		return res.Allocation.GrownSizeInBytes == size, nil
+	}).WithTemplate("Pod {{.Actual.Namespace}}/{{.Actual.Name}} UID {{.Actual.UID}} did not grow the allocation to {{.Data}} bytes").WithTemplateData(size)
+}
+
+// getResult returns the result reported by the dramemtester running as the first container of the pod.
+// The termination message is reliable once the container exited; the logs are the fallback for the running containers.
+func getResult(fxt *fixture.Fixture, actual *corev1.Pod) (*result.Result, error) {
+	cntName := actual.Spec.Containers[0].Name
+	for _, cntSt := range actual.Status.ContainerStatuses {
+		if cntSt.Name != cntName || cntSt.State.Terminated == nil || cntSt.State.Terminated.Message == "" {
+			continue
+		}
+		return result.FromString(cntSt.State.Terminated.Message)
+	}
+	logs, err := pod.GetLogs(fxt.K8SClientset, context.TODO(), actual.Namespace, actual.Name, cntName)
+	if err != nil {
+		return nil, err
+	}
+	return result.FromLogs(logs)
+}
+
 const (
 	reasonOOMKilled            = "OOMKilled"
 	reasonCreateContainerError = "CreateContainerError"
@@ -18,6 +18,7 @@ package e2e
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/onsi/ginkgo/v2"
@@ -29,6 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 
+	"github.com/ffromani/dra-driver-memory/test/pkg/cgrouptree"
 	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
 	"github.com/ffromani/dra-driver-memory/test/pkg/node"
 	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
@@ -129,7 +131,7 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 							Name:    "container-with-hugepages-2m",
 							Image:   dramemoryTesterImage,
 							Command: []string{"/bin/dramemtester"},
-							Args:    []string{"-use-hugetlb=true", "-alloc-size=32Mi", "-numa-align=single", "-run-forever"},
+							Args:    []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-numa-align=single", "-run-forever"},
 							Resources: corev1.ResourceRequirements{
 								Limits: corev1.ResourceList{
 									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
@@ -155,6 +157,10 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 			createdPod, err := pod.CreateSync(ctx, fxt.K8SClientset, &testPod)
 			gomega.Expect(err).ToNot(gomega.HaveOccurred())
 			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+
+			fixture.By("checking the hugetlb limits of the container and of the pod")
+			gomega.Expect(createdPod).To(cgrouptree.HaveContainerHugeTLBLimit(ctx, cgrouptree.DockerExec, "container-with-hugepages-2m", 2*(1<<20), 32*(1<<20)))
+			gomega.Expect(createdPod).To(cgrouptree.HavePodHugeTLBLimit(ctx, cgrouptree.DockerExec, 2*(1<<20), 32*(1<<20)))
 		})
 
 		ginkgo.It("should run and fail a pod which allocates exceeding the limits", ginkgo.Label("negative"), func(ctx context.Context) {
@@ -230,6 +236,400 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 			gomega.Expect(createdPod).To(ReportReason(fxt, result.FailedAsExpected))
 		})
 
+		// the shared backings reserve the hugepages at mmap/shmget time, so they exercise the hugetlb rsvd limits
+		ginkgo.DescribeTable("should enforce the limits on shared hugepages", ginkgo.Label("backing"), func(ctx context.Context, backing string, allocSize string, expectedReason result.Reason) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-32m",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp2m",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages-2m",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			args := []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-backing=" + backing, "-alloc-size=" + allocSize}
+			if expectedReason == result.FailedAsExpected {
+				args = append(args, "-should-fail")
+			}
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q with backing %q", fxt.Namespace.Name, backing)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "pod-with-hugepages-2m-" + backing,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-with-hugepages-2m",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							Args:    args,
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp2m",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp2m",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, expectedReason))
+		},
+			ginkgo.Entry("memfd within the limits", ginkgo.Label("positive"), "memfd", "32Mi", result.Succeeded),
+			ginkgo.Entry("memfd exceeding the limits", ginkgo.Label("negative"), "memfd", "48Mi", result.FailedAsExpected),
+			ginkgo.Entry("shm within the limits", ginkgo.Label("positive"), "shm", "32Mi", result.Succeeded),
+			ginkgo.Entry("shm exceeding the limits", ginkgo.Label("negative"), "shm", "48Mi", result.FailedAsExpected),
+		)
+
+		ginkgo.It("should grow the allocation of a pod up to the limits", ginkgo.Label("positive", "grow"), func(ctx context.Context) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-32m",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp2m",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages-2m",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "pod-growing-hugepages-2m",
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-growing-hugepages-2m",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							// the limit guards against a missing hugetlb limit, which would let the allocation grow over the claim
+							Args: []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=2Mi", "-grow", "-grow-limit=64Mi"},
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp2m",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp2m",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+			gomega.Expect(createdPod).To(ReportGrownSize(fxt, 32*(1<<20)))
+		})
+
+		ginkgo.It("should run successfully a pod which churns allocations within the limits", ginkgo.Label("positive", "churn"), func(ctx context.Context) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-32m",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp2m",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages-2m",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "pod-churning-hugepages-2m",
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-churning-hugepages-2m",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							// the workers together stay within the claim, so no allocation should be refused
+							Args: []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=4Mi", "-churn-workers=4", "-churn-duration=20s"},
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp2m",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp2m",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+
+		// the children share the cgroup of the container, so their allocations add up against the same limits
+		ginkgo.DescribeTable("should enforce the limits on the children of the container process", ginkgo.Label("fork"), func(ctx context.Context, children int, expectedReason result.Reason) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-32m",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp2m",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages-2m",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			args := []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=8Mi", fmt.Sprintf("-fork-children=%d", children)}
+			if expectedReason == result.FailedAsExpected {
+				args = append(args, "-should-fail")
+			}
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q with %d children", fxt.Namespace.Name, children)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      fmt.Sprintf("pod-forking-hugepages-2m-%d", children),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-forking-hugepages-2m",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							Args:    args,
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp2m",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp2m",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, expectedReason))
+		},
+			ginkgo.Entry("children within the limits", ginkgo.Label("positive"), 4, result.Succeeded),
+			ginkgo.Entry("children exceeding the limits", ginkgo.Label("negative"), 6, result.FailedAsExpected),
+		)
+
+		ginkgo.It("should run successfully a pod which locks its allocation within the limits", ginkgo.Label("positive", "mlock"), func(ctx context.Context) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-32m",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp2m",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages-2m",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "pod-locking-hugepages-2m",
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-locking-hugepages-2m",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							Args:    []string{"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-mlock"},
+							// like DPDK, lift RLIMIT_MEMLOCK, which is set by the runtime regardless of the claims
+							SecurityContext: &corev1.SecurityContext{
+								Capabilities: &corev1.Capabilities{
+									Add: []corev1.Capability{"IPC_LOCK"},
+								},
+							},
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp2m",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp2m",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+
 		ginkgo.It("should run successfully a pod which allocates within the limits including memory", ginkgo.Label("positive", "memory"), func(ctx context.Context) {
 			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
 			claimTmpl := resourcev1.ResourceClaimTemplate{
@@ -380,7 +780,7 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 							Name:    "container-with-hugepages-1g",
 							Image:   dramemoryTesterImage,
 							Command: []string{"/bin/dramemtester"},
-							Args:    []string{"-use-hugetlb=true", "-alloc-size=1Gi", "-numa-align=single", "-run-forever"},
+							Args:    []string{"-use-hugetlb=true", "-hugepage-size=1Gi", "-alloc-size=1Gi", "-numa-align=single", "-run-forever"},
 							Resources: corev1.ResourceRequirements{
 								Limits: corev1.ResourceList{
 									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
@@ -453,7 +853,7 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 							Name:    "container-over-hugepages-1g",
 							Image:   dramemoryTesterImage,
 							Command: []string{"/bin/dramemtester"},
-							Args:    []string{"-use-hugetlb=true", "-alloc-size=2Gi", "-should-fail"},
+							Args:    []string{"-use-hugetlb=true", "-hugepage-size=1Gi", "-alloc-size=2Gi", "-should-fail"},
 							Resources: corev1.ResourceRequirements{
 								Limits: corev1.ResourceList{
 									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
@@ -481,4 +881,98 @@ var _ = ginkgo.Describe("Hugepages Allocation", ginkgo.Serial, ginkgo.Ordered, g
 			gomega.Expect(createdPod).To(ReportReason(fxt, result.FailedAsExpected))
 		})
 	})
+
+	ginkgo.When("requesting hugepages of the default size", ginkgo.Label("hugepages:default"), func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			fxt = rootFxt.WithPrefix("allochpdef")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+
+			// the default size is 2M on x86_64
+			rsName, devName, ok := fxt.NodeHasMemoryResource(ctx, targetNode.Name, "2m", 32*(1<<20))
+			if !ok {
+				ginkgo.Skip("missing hugepages in resource slices")
+			}
+			fxt.Log.Info("found 2M hugepages device", "resourceSlice", rsName, "device", devName)
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should run successfully a pod which does not know the hugepage size", ginkgo.Label("positive"), func(ctx context.Context) {
+			fixture.By("creating a ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			claimTmpl := resourcev1.ResourceClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "hugepages-default",
+				},
+				Spec: resourcev1.ResourceClaimTemplateSpec{
+					Spec: resourcev1.ResourceClaimSpec{
+						Devices: resourcev1.DeviceClaim{
+							Requests: []resourcev1.DeviceRequest{
+								{
+									Name: "hp",
+									Exactly: &resourcev1.ExactDeviceRequest{
+										DeviceClassName: "dra.hugepages",
+										Capacity: &resourcev1.CapacityRequirements{
+											Requests: map[resourcev1.QualifiedName]resource.Quantity{
+												resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+
+			fixture.By("creating a pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: fxt.Namespace.Name,
+					Name:      "pod-with-hugepages-default",
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "container-with-hugepages-default",
+							Image:   dramemoryTesterImage,
+							Command: []string{"/bin/dramemtester"},
+							// no hugepage-size: the allocation gets the default size, like the portable applications
+							Args: []string{"-use-hugetlb=true", "-alloc-size=32Mi", "-numa-align=single", "-run-forever"},
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+									corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+								},
+								Claims: []corev1.ResourceClaim{
+									{
+										Name: "hp",
+									},
+								},
+							},
+						},
+					},
+					ResourceClaims: []corev1.PodResourceClaim{
+						{
+							Name:                      "hp",
+							ResourceClaimTemplateName: ptr.To(createdTmpl.Name),
+						},
+					},
+				},
+			}
+
+			createdPod, err := pod.CreateSync(ctx, fxt.K8SClientset, &testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+	})
 })
@@ -0,0 +1,200 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"os"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
+	"github.com/ffromani/dra-driver-memory/test/pkg/node"
+	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+// The tester emulates a VMM like QEMU: it reads the guest memory size from the environment
+// and backs it with a file on the per-claim hugetlbfs mount (memory-backend-file,share=on).
+
+var _ = ginkgo.Describe("Virtual machine guest memory", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier1", "allocation", "kubevirt", "platform:kind"), func() {
+	var rootFxt *fixture.Fixture
+	var targetNode *corev1.Node
+	var dramemoryTesterImage string
+
+	ginkgo.BeforeAll(func(ctx context.Context) {
+		// early cheap check before to create the Fixture, so we use GinkgoLogr directly
+		dramemoryTesterImage = os.Getenv("DRAMEM_E2E_TEST_IMAGE")
+		gomega.Expect(dramemoryTesterImage).ToNot(gomega.BeEmpty(), "missing environment variable DRAMEM_E2E_TEST_IMAGE")
+		ginkgo.GinkgoLogr.Info("discovery image", "pullSpec", dramemoryTesterImage)
+
+		var err error
+
+		rootFxt, err = fixture.ForGinkgo()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot create root fixture: %v", err)
+		infraFxt := rootFxt.WithPrefix("infra")
+		gomega.Expect(infraFxt.Setup(ctx)).To(gomega.Succeed())
+		ginkgo.DeferCleanup(infraFxt.Teardown)
+
+		if targetNodeName := os.Getenv("DRAMEM_E2E_TARGET_NODE"); len(targetNodeName) > 0 {
+			targetNode, err = rootFxt.K8SClientset.CoreV1().Nodes().Get(ctx, targetNodeName, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot get worker node %q: %v", targetNodeName, err)
+		} else {
+			workerNodes, err := node.FindWorkers(ctx, infraFxt.K8SClientset)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot find worker nodes: %v", err)
+			gomega.Expect(workerNodes).ToNot(gomega.BeEmpty(), "no worker nodes detected")
+			targetNode = workerNodes[0] // pick random one, this is the simplest random pick
+		}
+		rootFxt.Log.Info("using worker node", "nodeName", targetNode.Name)
+	})
+
+	ginkgo.When("backing the guest memory with 1G hugepages", ginkgo.Label("hugepages:1G"), func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			SkipIfGithubActions()
+
+			fxt = rootFxt.WithPrefix("vmhp")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+
+			rsName, devName, ok := fxt.NodeHasMemoryResource(ctx, targetNode.Name, "1g", 2*(1<<30))
+			if !ok {
+				ginkgo.Skip("missing hugepages in resource slices")
+			}
+			fxt.Log.Info("found 1G hugepages device", "resourceSlice", rsName, "device", devName)
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should run successfully a VMM-like pod which backs the guest memory on hugetlbfs", ginkgo.Label("positive"), func(ctx context.Context) {
+			createdTmpl := createVMClaimTemplate(ctx, fxt)
+
+			fixture.By("creating a VMM-like pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := makeVMMPod(fxt, dramemoryTesterImage, createdTmpl.Name, "pod-vmm-hugepages-1g", []string{"-guest-memory-from-env", "-hugetlbfs-path=env", "-numa-align=single", "-run-forever"})
+			createdPod, err := pod.CreateSync(ctx, fxt.K8SClientset, testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+
+		ginkgo.It("should fail a VMM-like pod which backs more memory than the claim on hugetlbfs", ginkgo.Label("negative"), func(ctx context.Context) {
+			createdTmpl := createVMClaimTemplate(ctx, fxt)
+
+			fixture.By("creating a VMM-like pod consuming the ResourceClaimTemplate on %q", fxt.Namespace.Name)
+			testPod := makeVMMPod(fxt, dramemoryTesterImage, createdTmpl.Name, "pod-vmm-over-hugepages-1g", []string{"-alloc-size=3Gi", "-hugetlbfs-path=env", "-should-fail"})
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.FailedAsExpected))
+		})
+	})
+})
+
+// createVMClaimTemplate creates a claim of 2Gi of 1G hugepages, 1Gi of which is reserved for the VMM overhead.
+func createVMClaimTemplate(ctx context.Context, fxt *fixture.Fixture) *resourcev1.ResourceClaimTemplate {
+	ginkgo.GinkgoHelper()
+
+	fixture.By("creating a ResourceClaimTemplate with virtual machine configuration on %q", fxt.Namespace.Name)
+	claimTmpl := resourcev1.ResourceClaimTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fxt.Namespace.Name,
+			Name:      "vm-hugepages-1g",
+		},
+		Spec: resourcev1.ResourceClaimTemplateSpec{
+			Spec: resourcev1.ResourceClaimSpec{
+				Devices: resourcev1.DeviceClaim{
+					Requests: []resourcev1.DeviceRequest{
+						{
+							Name: "hp1g",
+							Exactly: &resourcev1.ExactDeviceRequest{
+								DeviceClassName: "dra.hugepages-1g",
+								Capacity: &resourcev1.CapacityRequirements{
+									Requests: map[resourcev1.QualifiedName]resource.Quantity{
+										resourcev1.QualifiedName("size"): *resource.NewQuantity(2*(1<<30), resource.BinarySI),
+									},
+								},
+							},
+						},
+					},
+					Config: []resourcev1.DeviceClaimConfiguration{
+						{
+							Requests: []string{"hp1g"},
+							DeviceConfiguration: resourcev1.DeviceConfiguration{
+								Opaque: &resourcev1.OpaqueDeviceConfiguration{
+									Driver: "dra.memory",
+									Parameters: runtime.RawExtension{
+										Raw: []byte(`{"apiVersion": "dra.memory/v1alpha1", "kind": "VirtualMachineMemoryConfig", "hugetlbfsMountPath": "/dev/hugepages-vm", "guestMemoryOverhead": "1Gi", "guestMemoryAlignment": "1Gi"}`),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	createdTmpl, err := fxt.K8SClientset.ResourceV1().ResourceClaimTemplates(fxt.Namespace.Name).Create(ctx, &claimTmpl, metav1.CreateOptions{})
+	gomega.Expect(err).ToNot(gomega.HaveOccurred())
+	gomega.Expect(createdTmpl).ToNot(gomega.BeNil())
+	return createdTmpl
+}
+
+func makeVMMPod(fxt *fixture.Fixture, image, claimTmplName, podName string, args []string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: fxt.Namespace.Name,
+			Name:      podName,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "vmm",
+					Image:   image,
+					Command: []string{"/bin/dramemtester"},
+					Args:    args,
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+							corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+						},
+						Claims: []corev1.ResourceClaim{
+							{
+								Name: "hp1g",
+							},
+						},
+					},
+				},
+			},
+			ResourceClaims: []corev1.PodResourceClaim{
+				{
+					Name:                      "hp1g",
+					ResourceClaimTemplateName: ptr.To(claimTmplName),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
+	"github.com/ffromani/dra-driver-memory/test/pkg/node"
+	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+const (
+	numaNodeAttribute = resourcev1.QualifiedName("resource.kubernetes.io/numaNode")
+	// reported by the DynamicResources scheduler plugin
+	reasonCannotAllocateClaims = "cannot allocate all claims"
+)
+
+var _ = ginkgo.Describe("Claim placement", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier1", "placement", "platform:kind"), func() {
+	var rootFxt *fixture.Fixture
+	var targetNode *corev1.Node
+	var workerNodes []*corev1.Node
+	var dramemoryTesterImage string
+
+	ginkgo.BeforeAll(func(ctx context.Context) {
+		// early cheap check before to create the Fixture, so we use GinkgoLogr directly
+		dramemoryTesterImage = os.Getenv("DRAMEM_E2E_TEST_IMAGE")
+		gomega.Expect(dramemoryTesterImage).ToNot(gomega.BeEmpty(), "missing environment variable DRAMEM_E2E_TEST_IMAGE")
+		ginkgo.GinkgoLogr.Info("discovery image", "pullSpec", dramemoryTesterImage)
+
+		var err error
+
+		rootFxt, err = fixture.ForGinkgo()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot create root fixture: %v", err)
+		infraFxt := rootFxt.WithPrefix("infra")
+		gomega.Expect(infraFxt.Setup(ctx)).To(gomega.Succeed())
+		ginkgo.DeferCleanup(infraFxt.Teardown)
+
+		workerNodes, err = node.FindWorkers(ctx, infraFxt.K8SClientset)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot find worker nodes: %v", err)
+		gomega.Expect(workerNodes).ToNot(gomega.BeEmpty(), "no worker nodes detected")
+
+		if targetNodeName := os.Getenv("DRAMEM_E2E_TARGET_NODE"); len(targetNodeName) > 0 {
+			targetNode, err = rootFxt.K8SClientset.CoreV1().Nodes().Get(ctx, targetNodeName, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot get worker node %q: %v", targetNodeName, err)
+		} else {
+			targetNode = workerNodes[0] // pick random one, this is the simplest random pick
+		}
+		rootFxt.Log.Info("using worker node", "nodeName", targetNode.Name, "workerCount", len(workerNodes))
+	})
+
+	ginkgo.When("requesting 2M hugepages", ginkgo.Label("hugepages:2M"), func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			fxt = rootFxt.WithPrefix("placement")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+
+			rsName, devName, ok := fxt.NodeHasMemoryResource(ctx, targetNode.Name, "2m", 32*(1<<20))
+			if !ok {
+				ginkgo.Skip("missing hugepages in resource slices")
+			}
+			fxt.Log.Info("found 2M hugepages device", "resourceSlice", rsName, "device", devName)
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should allocate from the NUMA zone selected by the claim", ginkgo.Label("positive", "numa"), func(ctx context.Context) {
+			devs, err := fxt.NodeMemoryDevices(ctx, targetNode.Name, "2m")
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(devs).ToNot(gomega.BeEmpty())
+			// the last zone is the least likely to be picked by chance on multi-NUMA nodes
+			numaNode := deviceNUMANode(devs[len(devs)-1])
+			fxt.Log.Info("selecting NUMA zone", "numaNode", numaNode, "deviceName", devs[len(devs)-1].Name)
+
+			fixture.By("creating a ResourceClaim selecting the NUMA zone %d on %q", numaNode, fxt.Namespace.Name)
+			claim := makeHugepages2MClaimOnNUMANode(fxt.Namespace.Name, "hugepages-32m-numa", 32*(1<<20), numaNode)
+			claim, err = fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, claim, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			testPod := makePlacementPod(fxt.Namespace.Name, "pod-with-hugepages-numa", dramemoryTesterImage, targetNode.Name, []string{claim.Name},
+				"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-numa-align="+strconv.FormatInt(numaNode, 10))
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+
+		ginkgo.It("should keep pending a pod pinned on a node with exhausted hugepages", ginkgo.Label("negative"), func(ctx context.Context) {
+			fixture.By("exhausting the 2M hugepages on %q", targetNode.Name)
+			gomega.Expect(exhaustHugepages2M(ctx, fxt, targetNode.Name, dramemoryTesterImage)).To(gomega.Succeed())
+
+			fixture.By("creating a pod pinned on %q", targetNode.Name)
+			claim := makeHugepages2MClaimOnNUMANode(fxt.Namespace.Name, "hugepages-32m-overcommit", 32*(1<<20), -1)
+			claim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, claim, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			testPod := makePlacementPod(fxt.Namespace.Name, "pod-over-node-capacity", dramemoryTesterImage, targetNode.Name, []string{claim.Name},
+				"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi")
+			createdPod, err := fxt.K8SClientset.CoreV1().Pods(testPod.Namespace).Create(ctx, testPod, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			gomega.Eventually(func(g gomega.Gomega) {
+				updatedPod, err := fxt.K8SClientset.CoreV1().Pods(createdPod.Namespace).Get(ctx, createdPod.Name, metav1.GetOptions{})
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				g.Expect(updatedPod.Status.Phase).To(gomega.Equal(corev1.PodPending))
+				cond := findPodCondition(updatedPod, corev1.PodScheduled)
+				g.Expect(cond).ToNot(gomega.BeNil(), "missing PodScheduled condition")
+				g.Expect(cond.Status).To(gomega.Equal(corev1.ConditionFalse))
+				g.Expect(cond.Reason).To(gomega.Equal(corev1.PodReasonUnschedulable))
+				g.Expect(cond.Message).To(gomega.ContainSubstring(reasonCannotAllocateClaims))
+			}).WithContext(ctx).WithTimeout(pod.PollTimeout).WithPolling(pod.PollInterval).Should(gomega.Succeed())
+		})
+
+		ginkgo.It("should schedule a pod on the only node with free hugepages", ginkgo.Label("positive", "multinode"), func(ctx context.Context) {
+			if len(workerNodes) < 2 {
+				fixture.Skip("needs at least 2 worker nodes, found %d", len(workerNodes))
+			}
+			for _, workerNode := range workerNodes {
+				if workerNode.Name == targetNode.Name {
+					continue
+				}
+				fixture.By("exhausting the 2M hugepages on %q", workerNode.Name)
+				gomega.Expect(exhaustHugepages2M(ctx, fxt, workerNode.Name, dramemoryTesterImage)).To(gomega.Succeed())
+			}
+
+			fixture.By("creating a pod not bound to any node on %q", fxt.Namespace.Name)
+			claim := makeHugepages2MClaimOnNUMANode(fxt.Namespace.Name, "hugepages-32m-anywhere", 32*(1<<20), -1)
+			claim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, claim, metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			testPod := makePlacementPod(fxt.Namespace.Name, "pod-with-hugepages-anywhere", dramemoryTesterImage, "", []string{claim.Name},
+				"-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-numa-align=single")
+			createdPod, err := pod.RunToCompletion(ctx, fxt.K8SClientset, testPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(createdPod.Spec.NodeName).To(gomega.Equal(targetNode.Name), "pod scheduled on a node without free hugepages")
+			gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+		})
+	})
+})
+
+// exhaustHugepages2M claims all the 2M hugepages of the node `nodeName` with a pod which holds them until
+// the namespace of the fixture is deleted.
+func exhaustHugepages2M(ctx context.Context, fxt *fixture.Fixture, nodeName, image string) error {
+	devs, err := fxt.NodeMemoryDevices(ctx, nodeName, "2m")
+	if err != nil {
+		return err
+	}
+	var claimNames []string
+	for _, dev := range devs {
+		capacity, ok := dev.Capacity[resourcev1.QualifiedName("size")]
+		if !ok || capacity.Value.IsZero() {
+			continue
+		}
+		numaNode := deviceNUMANode(dev)
+		claim := makeHugepages2MClaimOnNUMANode(fxt.Namespace.Name, fmt.Sprintf("hugepages-all-%s-%d", nodeName, numaNode), capacity.Value.Value(), numaNode)
+		claim, err = fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, claim, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		fxt.Log.Info("claiming all the hugepages", "nodeName", nodeName, "deviceName", dev.Name, "numaNode", numaNode, "size", capacity.Value.String())
+		claimNames = append(claimNames, claim.Name)
+	}
+	if len(claimNames) == 0 {
+		fxt.Log.Info("no hugepages to claim", "nodeName", nodeName)
+		return nil // nothing to exhaust
+	}
+	blockerPod := makePlacementPod(fxt.Namespace.Name, "pod-holding-hugepages-"+nodeName, image, nodeName, claimNames, "-use-hugetlb=false", "-alloc-size=1Mi", "-run-forever")
+	_, err = pod.CreateSync(ctx, fxt.K8SClientset, blockerPod)
+	return err
+}
+
+func deviceNUMANode(dev resourcev1.Device) int64 {
+	attr, ok := dev.Attributes[numaNodeAttribute]
+	if !ok || attr.IntValue == nil {
+		return 0
+	}
+	return *attr.IntValue
+}
+
+// makeHugepages2MClaimOnNUMANode makes a claim for `size` bytes of 2M hugepages. If `numaNode` is not negative,
+// the claim selects the device of that NUMA zone.
+func makeHugepages2MClaimOnNUMANode(namespace, name string, size, numaNode int64) *resourcev1.ResourceClaim {
+	claim := makeHugepages2MClaim(namespace, name)
+	req := claim.Spec.Devices.Requests[0].Exactly
+	req.Capacity.Requests[resourcev1.QualifiedName("size")] = *resource.NewQuantity(size, resource.BinarySI)
+	if numaNode >= 0 {
+		req.Selectors = []resourcev1.DeviceSelector{
+			{
+				CEL: &resourcev1.CELDeviceSelector{
+					Expression: fmt.Sprintf(`device.attributes["resource.kubernetes.io"].numaNode == %d`, numaNode),
+				},
+			},
+		}
+	}
+	return claim
+}
+
+// makePlacementPod makes a pod consuming all the claims `claimNames`, pinned on `nodeName` unless empty.
+func makePlacementPod(namespace, name, image, nodeName string, claimNames []string, args ...string) *corev1.Pod {
+	testPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "container-with-hugepages-2m",
+					Image:   image,
+					Command: []string{"/bin/dramemtester"},
+					Args:    args,
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+							corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+						},
+					},
+				},
+			},
+		},
+	}
+	if nodeName != "" {
+		testPod.Spec.NodeSelector = map[string]string{
+			"kubernetes.io/hostname": nodeName,
+		}
+	}
+	for idx, claimName := range claimNames {
+		claimRef := fmt.Sprintf("hp2m-%d", idx)
+		testPod.Spec.Containers[0].Resources.Claims = append(testPod.Spec.Containers[0].Resources.Claims, corev1.ResourceClaim{
+			Name: claimRef,
+		})
+		testPod.Spec.ResourceClaims = append(testPod.Spec.ResourceClaims, corev1.PodResourceClaim{
+			Name:              claimRef,
+			ResourceClaimName: ptr.To(claimName),
+		})
+	}
+	return testPod
+}
+
+func findPodCondition(testPod *corev1.Pod, condType corev1.PodConditionType) *corev1.PodCondition {
+	for idx := range testPod.Status.Conditions {
+		if testPod.Status.Conditions[idx].Type == condType {
+			return &testPod.Status.Conditions[idx]
+		}
+	}
+	return nil
+}
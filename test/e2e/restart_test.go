@@ -0,0 +1,281 @@
+/*
+Copyright 2025 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fixture"
+	"github.com/ffromani/dra-driver-memory/test/pkg/node"
+	"github.com/ffromani/dra-driver-memory/test/pkg/pod"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+const (
+	// containers exiting successfully are restarted with exponential backoff up to 5 minutes
+	containerRestartTimeout = 6 * time.Minute
+)
+
+var _ = ginkgo.Describe("Driver restart", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier1", "restart", "disruptive", "platform:kind"), func() {
+	var rootFxt *fixture.Fixture
+	var targetNode *corev1.Node
+	var dramemoryTesterImage string
+
+	ginkgo.BeforeAll(func(ctx context.Context) {
+		// early cheap check before to create the Fixture, so we use GinkgoLogr directly
+		dramemoryTesterImage = os.Getenv("DRAMEM_E2E_TEST_IMAGE")
+		gomega.Expect(dramemoryTesterImage).ToNot(gomega.BeEmpty(), "missing environment variable DRAMEM_E2E_TEST_IMAGE")
+		ginkgo.GinkgoLogr.Info("discovery image", "pullSpec", dramemoryTesterImage)
+
+		var err error
+
+		rootFxt, err = fixture.ForGinkgo()
+		gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot create root fixture: %v", err)
+		infraFxt := rootFxt.WithPrefix("infra")
+		gomega.Expect(infraFxt.Setup(ctx)).To(gomega.Succeed())
+		ginkgo.DeferCleanup(infraFxt.Teardown)
+
+		if targetNodeName := os.Getenv("DRAMEM_E2E_TARGET_NODE"); len(targetNodeName) > 0 {
+			targetNode, err = rootFxt.K8SClientset.CoreV1().Nodes().Get(ctx, targetNodeName, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot get worker node %q: %v", targetNodeName, err)
+		} else {
+			workerNodes, err := node.FindWorkers(ctx, infraFxt.K8SClientset)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred(), "cannot find worker nodes: %v", err)
+			gomega.Expect(workerNodes).ToNot(gomega.BeEmpty(), "no worker nodes detected")
+			targetNode = workerNodes[0] // pick random one, this is the simplest random pick
+		}
+		rootFxt.Log.Info("using worker node", "nodeName", targetNode.Name)
+	})
+
+	ginkgo.When("the driver restarts while pods hold claims", ginkgo.Label("hugepages:2M"), func() {
+		var fxt *fixture.Fixture
+
+		ginkgo.BeforeEach(func(ctx context.Context) {
+			fxt = rootFxt.WithPrefix("restart")
+			gomega.Expect(fxt.Setup(ctx)).To(gomega.Succeed())
+
+			// one claim prepared before the restart, one after
+			rsName, devName, ok := fxt.NodeHasMemoryResource(ctx, targetNode.Name, "2m", 64*(1<<20))
+			if !ok {
+				ginkgo.Skip("missing hugepages in resource slices")
+			}
+			fxt.Log.Info("found 2M hugepages device", "resourceSlice", rsName, "device", devName)
+		})
+
+		ginkgo.AfterEach(func(ctx context.Context) {
+			gomega.Expect(fxt.Teardown(ctx)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should keep serving the claims prepared before the restart", func(ctx context.Context) {
+			fixture.By("creating a hugepages ResourceClaim on %q", fxt.Namespace.Name)
+			claim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, makeHugepages2MClaim(fxt.Namespace.Name, "hugepages-32m-before-restart"), metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			// the container exits after each allocation attempt and is restarted by the kubelet,
+			// so its containers created after the driver restart prove the limits are still enforced.
+			fixture.By("creating a pod consuming the ResourceClaim on %q", fxt.Namespace.Name)
+			beforePod := makeHugepages2MPod(fxt.Namespace.Name, targetNode.Name, "pod-before-restart", dramemoryTesterImage, claim.Name, corev1.RestartPolicyAlways, "-use-hugetlb=true", "-alloc-size=48Mi", "-should-fail")
+			beforePod, err = pod.CreateSync(ctx, fxt.K8SClientset, beforePod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+
+			fixture.By("restarting the driver pod on %q", targetNode.Name)
+			gomega.Expect(restartDriverPod(ctx, fxt, targetNode.Name)).To(gomega.Succeed())
+
+			fixture.By("checking the ResourceClaim is still reserved for pod %s/%s", beforePod.Namespace, beforePod.Name)
+			claim, err = fxt.K8SClientset.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(claim.Status.Allocation).ToNot(gomega.BeNil(), "claim %s/%s lost the allocation", claim.Namespace, claim.Name)
+			gomega.Expect(isReservedFor(claim, beforePod.UID)).To(gomega.BeTrue(), "claim %s/%s not reserved for pod %s", claim.Namespace, claim.Name, beforePod.UID)
+
+			fixture.By("checking the limits are enforced on the containers created after the restart")
+			beforePod, err = fxt.K8SClientset.CoreV1().Pods(beforePod.Namespace).Get(ctx, beforePod.Name, metav1.GetOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			restartCount := beforePod.Status.ContainerStatuses[0].RestartCount
+			gomega.Eventually(func(g gomega.Gomega) {
+				updatedPod, err := fxt.K8SClientset.CoreV1().Pods(beforePod.Namespace).Get(ctx, beforePod.Name, metav1.GetOptions{})
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				cntSt := updatedPod.Status.ContainerStatuses[0]
+				// the restart counter is bumped when the new container is created, so it needs to terminate once more
+				g.Expect(cntSt.RestartCount).To(gomega.BeNumerically(">", restartCount+1))
+				g.Expect(cntSt.LastTerminationState.Terminated).ToNot(gomega.BeNil())
+				res, err := result.FromString(cntSt.LastTerminationState.Terminated.Message)
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				g.Expect(res.Status.Reason).To(gomega.Equal(result.FailedAsExpected), "unexpected result: %s", res.Status.Message)
+			}).WithContext(ctx).WithTimeout(containerRestartTimeout).WithPolling(pod.PollInterval).Should(gomega.Succeed())
+
+			fixture.By("creating a pod consuming a new ResourceClaim after the restart on %q", fxt.Namespace.Name)
+			afterClaim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(fxt.Namespace.Name).Create(ctx, makeHugepages2MClaim(fxt.Namespace.Name, "hugepages-32m-after-restart"), metav1.CreateOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			afterPod := makeHugepages2MPod(fxt.Namespace.Name, targetNode.Name, "pod-after-restart", dramemoryTesterImage, afterClaim.Name, corev1.RestartPolicyNever, "-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-numa-align=single")
+			afterPod, err = pod.RunToCompletion(ctx, fxt.K8SClientset, afterPod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(afterPod).To(ReportReason(fxt, result.Succeeded))
+
+			fixture.By("deleting the pod created before the restart")
+			err = fxt.K8SClientset.CoreV1().Pods(beforePod.Namespace).Delete(ctx, beforePod.Name, metav1.DeleteOptions{})
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(pod.WaitToBeDeleted(ctx, fxt.K8SClientset, beforePod.Namespace, beforePod.Name)).To(gomega.Succeed())
+
+			// the kubelet releases the claim only once the driver unprepared it
+			fixture.By("checking the ResourceClaim prepared before the restart is released")
+			gomega.Eventually(func(g gomega.Gomega) {
+				updatedClaim, err := fxt.K8SClientset.ResourceV1().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+				g.Expect(err).ToNot(gomega.HaveOccurred())
+				g.Expect(updatedClaim.Status.ReservedFor).To(gomega.BeEmpty())
+			}).WithContext(ctx).WithTimeout(pod.PollTimeout).WithPolling(pod.PollInterval).Should(gomega.Succeed())
+
+			fixture.By("reusing the ResourceClaim prepared before the restart")
+			reusePod := makeHugepages2MPod(fxt.Namespace.Name, targetNode.Name, "pod-reusing-claim", dramemoryTesterImage, claim.Name, corev1.RestartPolicyNever, "-use-hugetlb=true", "-hugepage-size=2Mi", "-alloc-size=32Mi", "-numa-align=single")
+			reusePod, err = pod.RunToCompletion(ctx, fxt.K8SClientset, reusePod)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			gomega.Expect(reusePod).To(ReportReason(fxt, result.Succeeded))
+		})
+	})
+})
+
+func makeHugepages2MClaim(namespace, name string) *resourcev1.ResourceClaim {
+	return &resourcev1.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: resourcev1.ResourceClaimSpec{
+			Devices: resourcev1.DeviceClaim{
+				Requests: []resourcev1.DeviceRequest{
+					{
+						Name: "hp2m",
+						Exactly: &resourcev1.ExactDeviceRequest{
+							DeviceClassName: "dra.hugepages-2m",
+							Capacity: &resourcev1.CapacityRequirements{
+								Requests: map[resourcev1.QualifiedName]resource.Quantity{
+									resourcev1.QualifiedName("size"): *resource.NewQuantity(32*(1<<20), resource.BinarySI),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// makeHugepages2MPod pins the pod on `nodeName`, so the claims are prepared by the driver pod we restart.
+func makeHugepages2MPod(namespace, nodeName, name, image, claimName string, restartPolicy corev1.RestartPolicy, args ...string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: restartPolicy,
+			NodeSelector: map[string]string{
+				"kubernetes.io/hostname": nodeName,
+			},
+			Containers: []corev1.Container{
+				{
+					Name:    "container-with-hugepages-2m",
+					Image:   image,
+					Command: []string{"/bin/dramemtester"},
+					Args:    args,
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    *resource.NewQuantity(1, resource.DecimalSI),
+							corev1.ResourceMemory: *resource.NewQuantity(512*(1<<20), resource.BinarySI),
+						},
+						Claims: []corev1.ResourceClaim{
+							{
+								Name: "hp2m",
+							},
+						},
+					},
+				},
+			},
+			ResourceClaims: []corev1.PodResourceClaim{
+				{
+					Name:              "hp2m",
+					ResourceClaimName: ptr.To(claimName),
+				},
+			},
+		},
+	}
+}
+
+// restartDriverPod deletes the driver pod running on `nodeName` and waits for the DaemonSet to replace it with a ready one.
+func restartDriverPod(ctx context.Context, fxt *fixture.Fixture, nodeName string) error {
+	driverPods, err := pod.FindOnNode(ctx, fxt.K8SClientset, pod.DriverNamespace, pod.DriverPodSelector, nodeName)
+	if err != nil {
+		return err
+	}
+	if len(driverPods) != 1 {
+		return fmt.Errorf("expected exactly one driver pod on %q, found %d", nodeName, len(driverPods))
+	}
+	oldPod := driverPods[0]
+	fxt.Log.Info("restarting driver pod", "namespace", oldPod.Namespace, "name", oldPod.Name, "podUID", oldPod.UID)
+
+	err = fxt.K8SClientset.CoreV1().Pods(oldPod.Namespace).Delete(ctx, oldPod.Name, metav1.DeleteOptions{})
+	if err != nil {
+		return err
+	}
+	err = pod.WaitToBeDeleted(ctx, fxt.K8SClientset, oldPod.Namespace, oldPod.Name)
+	if err != nil {
+		return err
+	}
+
+	immediate := true
+	return wait.PollUntilContextTimeout(ctx, pod.PollInterval, pod.PollTimeout, immediate, func(ctx2 context.Context) (done bool, err error) {
+		driverPods, err := pod.FindOnNode(ctx2, fxt.K8SClientset, pod.DriverNamespace, pod.DriverPodSelector, nodeName)
+		if err != nil {
+			return false, err
+		}
+		for idx := range driverPods {
+			newPod := &driverPods[idx]
+			if newPod.UID == oldPod.UID {
+				return false, errors.New("driver pod replaced with the same UID")
+			}
+			if !pod.IsReady(newPod) {
+				continue
+			}
+			fxt.Log.Info("driver pod restarted", "namespace", newPod.Namespace, "name", newPod.Name, "podUID", newPod.UID)
+			return true, nil
+		}
+		return false, nil
+	})
+}
+
+func isReservedFor(claim *resourcev1.ResourceClaim, podUID types.UID) bool {
+	for _, ref := range claim.Status.ReservedFor {
+		if ref.Resource == "pods" && ref.UID == podUID {
+			return true
+		}
+	}
+	return false
+}
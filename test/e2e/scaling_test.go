@@ -18,9 +18,13 @@ package e2e
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/onsi/ginkgo/v2"
@@ -40,6 +44,9 @@ import (
 
 const (
 	maxPodsPerNode = 200 // plenty for our needs
+	// defaultMaxPodStartP99 is generous enough for kind clusters, but still catches the driver
+	// serializing the pod startup, which makes the latency grow linearly with the pod count.
+	defaultMaxPodStartP99 = 60 * time.Second
 )
 
 var _ = ginkgo.Describe("Claim scalability", ginkgo.Serial, ginkgo.Ordered, ginkgo.ContinueOnFailure, ginkgo.Label("tier1", "memory", "allocation", "scaling", "platform:kind"), func() {
@@ -166,26 +173,185 @@ var _ = ginkgo.Describe("Claim scalability", ginkgo.Serial, ginkgo.Ordered, gink
 				},
 			}
 
-			var wg sync.WaitGroup
-			for idx := 0; idx < podCount; idx++ {
-				wg.Add(1)
-				go func(testPod *corev1.Pod, idx int, fxt *fixture.Fixture) {
-					defer ginkgo.GinkgoRecover()
-					defer wg.Done()
-					fixture.By("creating a test pod: %02d/%02d", idx+1, podCount)
-					testPod.Name = fmt.Sprintf("%s-%02d", testPod.Name, idx)
-
-					createdPod, err := pod.CreateSync(ctx, fxt.K8SClientset, testPod)
-					gomega.Expect(err).ToNot(gomega.HaveOccurred())
-					gomega.Expect(createdPod).ToNot(gomega.BeNil())
-					gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
-				}(podTmpl.DeepCopy(), idx, fxt)
+			config, err := scalingConfigFromEnv()
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			rootFxt.Log.Info("scaling configuration", "createQPS", config.CreateQPS, "batchSize", config.BatchSize, "maxPodStartP99", config.MaxPodStartP99)
+
+			// unlimited by default: the batches are the only bound
+			throttle := func() {}
+			if config.CreateQPS > 0 {
+				ticker := time.NewTicker(time.Duration(float64(time.Second) / config.CreateQPS))
+				defer ticker.Stop()
+				throttle = func() { <-ticker.C }
+			}
+			batchSize := config.BatchSize
+			if batchSize <= 0 {
+				batchSize = podCount
+			}
+
+			var latenciesMu sync.Mutex
+			var startLatencies, readyLatencies []time.Duration
+			began := time.Now()
+			for batchStart := 0; batchStart < podCount; batchStart += batchSize {
+				var wg sync.WaitGroup
+				for idx := batchStart; idx < min(batchStart+batchSize, podCount); idx++ {
+					throttle()
+					wg.Add(1)
+					go func(testPod *corev1.Pod, idx int, fxt *fixture.Fixture) {
+						defer ginkgo.GinkgoRecover()
+						defer wg.Done()
+						fixture.By("creating a test pod: %02d/%02d", idx+1, podCount)
+						testPod.Name = fmt.Sprintf("%s-%02d", testPod.Name, idx)
+
+						createdPod, err := pod.CreateSync(ctx, fxt.K8SClientset, testPod)
+						gomega.Expect(err).ToNot(gomega.HaveOccurred())
+						gomega.Expect(createdPod).ToNot(gomega.BeNil())
+						gomega.Expect(createdPod).To(ReportReason(fxt, result.Succeeded))
+
+						startLatency, ok := podStartLatency(createdPod)
+						gomega.Expect(ok).To(gomega.BeTrue(), "cannot compute the start latency of pod %s/%s", createdPod.Namespace, createdPod.Name)
+						readyLatency, ok := podReadyLatency(createdPod)
+						gomega.Expect(ok).To(gomega.BeTrue(), "cannot compute the ready latency of pod %s/%s", createdPod.Namespace, createdPod.Name)
+						latenciesMu.Lock()
+						startLatencies = append(startLatencies, startLatency)
+						readyLatencies = append(readyLatencies, readyLatency)
+						latenciesMu.Unlock()
+					}(podTmpl.DeepCopy(), idx, fxt)
+				}
+				wg.Wait()
+			}
+
+			summary := scalingSummary{
+				Node:      targetNode.Name,
+				PodCount:  len(startLatencies),
+				CreateQPS: config.CreateQPS,
+				BatchSize: config.BatchSize,
+				Elapsed:   time.Since(began),
+				Start:     summarizeLatencies(startLatencies),
+				Ready:     summarizeLatencies(readyLatencies),
+			}
+			rootFxt.Log.Info("pod latency", "podCount", summary.PodCount, "elapsed", summary.Elapsed, "start", summary.Start.String(), "ready", summary.Ready.String())
+			ginkgo.AddReportEntry("scaling summary", summary)
+			if config.SummaryPath != "" {
+				gomega.Expect(summary.WriteFile(config.SummaryPath)).To(gomega.Succeed())
 			}
-			wg.Wait()
+			gomega.Expect(summary.Start.P99).To(gomega.BeNumerically("<=", config.MaxPodStartP99), "p99 pod start latency %v exceeds %v", summary.Start.P99, config.MaxPodStartP99)
 		})
 	})
 })
 
+// scalingConfig controls the pace of the pod creation, to make the scaling test a repeatable performance test.
+type scalingConfig struct {
+	// CreateQPS is the maximum rate of the pod creation. Zero means unlimited.
+	CreateQPS float64
+	// BatchSize is the number of pods created concurrently; the next batch starts when all the pods of
+	// the previous batch are running. Zero means all the pods in a single batch.
+	BatchSize      int
+	MaxPodStartP99 time.Duration
+	// SummaryPath is the file to write the summary on as JSON, if any, to track the performance over time
+	SummaryPath string
+}
+
+func scalingConfigFromEnv() (scalingConfig, error) {
+	config := scalingConfig{
+		MaxPodStartP99: defaultMaxPodStartP99,
+		SummaryPath:    os.Getenv("DRAMEM_E2E_SCALING_SUMMARY"),
+	}
+	var err error
+	if val := os.Getenv("DRAMEM_E2E_MAX_POD_START_P99"); len(val) > 0 {
+		config.MaxPodStartP99, err = time.ParseDuration(val)
+		if err != nil {
+			return config, fmt.Errorf("cannot parse DRAMEM_E2E_MAX_POD_START_P99=%q: %w", val, err)
+		}
+	}
+	if val := os.Getenv("DRAMEM_E2E_SCALING_CREATE_QPS"); len(val) > 0 {
+		config.CreateQPS, err = strconv.ParseFloat(val, 64)
+		if err != nil || config.CreateQPS < 0 {
+			return config, fmt.Errorf("cannot parse DRAMEM_E2E_SCALING_CREATE_QPS=%q: must be a non-negative number", val)
+		}
+	}
+	if val := os.Getenv("DRAMEM_E2E_SCALING_BATCH_SIZE"); len(val) > 0 {
+		config.BatchSize, err = strconv.Atoi(val)
+		if err != nil || config.BatchSize < 0 {
+			return config, fmt.Errorf("cannot parse DRAMEM_E2E_SCALING_BATCH_SIZE=%q: must be a non-negative integer", val)
+		}
+	}
+	return config, nil
+}
+
+// latencySummary is the distribution of a latency. Serialized as JSON, the durations are in nanoseconds.
+type latencySummary struct {
+	P50 time.Duration `json:"p50"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+func summarizeLatencies(latencies []time.Duration) latencySummary {
+	return latencySummary{
+		P50: percentile(latencies, 50),
+		P99: percentile(latencies, 99),
+		Max: percentile(latencies, 100),
+	}
+}
+
+func (ls latencySummary) String() string {
+	return fmt.Sprintf("p50=%v p99=%v max=%v", ls.P50, ls.P99, ls.Max)
+}
+
+type scalingSummary struct {
+	Node      string        `json:"node"`
+	PodCount  int           `json:"podCount"`
+	CreateQPS float64       `json:"createQPS"`
+	BatchSize int           `json:"batchSize"`
+	Elapsed   time.Duration `json:"elapsed"`
+	// Start is the latency from the creation of the pods to the start of their container
+	Start latencySummary `json:"start"`
+	// Ready is the latency from the creation of the pods to their readiness
+	Ready latencySummary `json:"ready"`
+}
+
+func (ss scalingSummary) WriteFile(path string) error {
+	data, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// podStartLatency returns the time from the creation of the pod to the start of its first container.
+// The timestamps have second granularity, which is good enough for our needs.
+func podStartLatency(pod *corev1.Pod) (time.Duration, bool) {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return 0, false
+	}
+	running := pod.Status.ContainerStatuses[0].State.Running
+	if running == nil {
+		return 0, false
+	}
+	return running.StartedAt.Sub(pod.CreationTimestamp.Time), true
+}
+
+// podReadyLatency returns the time from the creation of the pod to its readiness, with the same granularity of podStartLatency.
+func podReadyLatency(pod *corev1.Pod) (time.Duration, bool) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return cond.LastTransitionTime.Sub(pod.CreationTimestamp.Time), true
+		}
+	}
+	return 0, false
+}
+
+// percentile returns the nearest-rank percentile `perc` (1-100) of the given durations.
+func percentile(durations []time.Duration, perc int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := (perc*len(sorted) + 99) / 100 // ceil
+	return sorted[max(rank, 1)-1]
+}
+
 func filterNodesWithEnoughResources(lh logr.Logger, nodes []*corev1.Node, targetResources corev1.ResourceList) ([]*corev1.Node, map[string]int) {
 	maxPodsPerNode := make(map[string]int)
 	allowedNodes := []*corev1.Node{}
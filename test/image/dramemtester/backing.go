@@ -0,0 +1,180 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// The backings mirror how the applications consume hugepages. The shared backings reserve
+// the hugepages when the memory is mapped, not when it is faulted in, so the cgroup
+// hugetlb rsvd limits make the allocation fail early, with ENOMEM.
+
+type Backing string
+
+const (
+	// BackingAnonymous is a private anonymous mapping, like most applications.
+	BackingAnonymous Backing = "anonymous"
+	// BackingHugeTLBFS is a shared mapping of a file on a hugetlbfs mount, like DPDK and QEMU.
+	BackingHugeTLBFS Backing = "hugetlbfs"
+	// BackingMemfd is a shared mapping of an anonymous file, like QEMU memory-backend-memfd.
+	BackingMemfd Backing = "memfd"
+	// BackingSHM is a SysV shared memory segment, like the databases (e.g. Oracle, PostgreSQL).
+	BackingSHM Backing = "shm"
+)
+
+// shmHugeTLB is SHM_HUGETLB, missing in x/sys/unix
+const shmHugeTLB = 0o4000
+
+type Allocation struct {
+	Backing Backing
+	Size    uint64
+	HugeTLB bool
+	// HugepageSizeBits selects the hugepages size, see hugepageSizeBits. Ignored by the hugetlbfs backing.
+	HugepageSizeBits int
+	// HugeTLBFSPath is the hugetlbfs mount of the hugetlbfs backing.
+	HugeTLBFSPath string
+	// HugepagesFile is the existing file of the hugetlbfs backing. Overrides HugeTLBFSPath.
+	HugepagesFile string
+}
+
+// Map maps the memory, without faulting it in.
+func (alloc Allocation) Map() ([]byte, error) {
+	switch alloc.Backing {
+	case BackingHugeTLBFS:
+		return alloc.mapHugeTLBFS()
+	case BackingMemfd:
+		return alloc.mapMemfd()
+	case BackingSHM:
+		return alloc.mapSHM()
+	default:
+		return alloc.mapAnonymous()
+	}
+}
+
+// Unmap releases the memory obtained with Map.
+func (alloc Allocation) Unmap(data []byte) error {
+	if alloc.Backing == BackingSHM {
+		return unix.SysvShmDetach(data)
+	}
+	return unix.Munmap(data)
+}
+
+func (alloc Allocation) mapAnonymous() ([]byte, error) {
+	flags := unix.MAP_ANONYMOUS | unix.MAP_PRIVATE
+	if alloc.HugeTLB {
+		flags |= unix.MAP_HUGETLB | alloc.HugepageSizeBits
+	}
+	return unix.Mmap(-1, 0, int(alloc.Size), unix.PROT_READ|unix.PROT_WRITE, flags)
+}
+
+// mapHugeTLBFS works like QEMU memory-backend-file,share=on. The size of the hugetlbfs mount bounds the allocation.
+// With an existing file, it works like the databases mapping the files of their shared memory.
+func (alloc Allocation) mapHugeTLBFS() ([]byte, error) {
+	var fh *os.File
+	var err error
+	switch {
+	case alloc.HugepagesFile != "":
+		fh, err = os.OpenFile(alloc.HugepagesFile, os.O_RDWR, 0)
+	case alloc.HugeTLBFSPath != "":
+		fh, err = openBackingFile(alloc.HugeTLBFSPath, alloc.Size)
+	default:
+		return nil, fmt.Errorf("backing %q requires the hugetlbfs path or the hugepages file", alloc.Backing)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("backing file error: %w", err)
+	}
+	defer fh.Close() //nolint:errcheck // the mapping keeps the file alive
+	return unix.Mmap(int(fh.Fd()), 0, int(alloc.Size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+}
+
+func (alloc Allocation) mapMemfd() ([]byte, error) {
+	flags := unix.MFD_CLOEXEC
+	if alloc.HugeTLB {
+		flags |= unix.MFD_HUGETLB | alloc.HugepageSizeBits
+	}
+	fd, err := unix.MemfdCreate("dramemtester", flags)
+	if err != nil {
+		return nil, fmt.Errorf("memfd_create error: %w", err)
+	}
+	defer unix.Close(fd) //nolint:errcheck // the mapping keeps the file alive
+	err = unix.Ftruncate(fd, int64(alloc.Size))
+	if err != nil {
+		return nil, fmt.Errorf("ftruncate error: %w", err)
+	}
+	return unix.Mmap(fd, 0, int(alloc.Size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+}
+
+func (alloc Allocation) mapSHM() ([]byte, error) {
+	flags := unix.IPC_CREAT | 0o600
+	if alloc.HugeTLB {
+		flags |= shmHugeTLB | alloc.HugepageSizeBits
+	}
+	id, err := unix.SysvShmGet(unix.IPC_PRIVATE, int(alloc.Size), flags)
+	if err != nil {
+		return nil, fmt.Errorf("shmget error: %w", err)
+	}
+	data, err := unix.SysvShmAttach(id, 0, 0)
+	// the segment is destroyed after the last detach, including the implicit one at exit
+	_, rmErr := unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("shmat error: %w", err)
+	}
+	if rmErr != nil {
+		return nil, fmt.Errorf("shmctl error: %w", rmErr)
+	}
+	return data, nil
+}
+
+// openBackingFile creates an unlinked file of `size` bytes on the hugetlbfs mount `dir`, like QEMU does.
+func openBackingFile(dir string, size uint64) (*os.File, error) {
+	fh, err := os.CreateTemp(dir, "guest-memory-")
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Remove(fh.Name()) // the mapping keeps the file alive
+	err = fh.Truncate(int64(size))
+	if err != nil {
+		_ = fh.Close()
+		return nil, err
+	}
+	return fh, nil
+}
+
+type BackingValue struct {
+	Backing *Backing
+}
+
+func (v BackingValue) String() string {
+	if v.Backing == nil {
+		return ""
+	}
+	return string(*v.Backing)
+}
+
+func (v BackingValue) Set(s string) error {
+	switch bk := Backing(s); bk {
+	case BackingAnonymous, BackingHugeTLBFS, BackingMemfd, BackingSHM:
+		*v.Backing = bk
+		return nil
+	default:
+		return fmt.Errorf("unsupported backing %q", s)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+	"unsafe"
+)
+
+// The benchmark is a coarse probe, not a replacement for STREAM or lmbench: it is meant
+// to tell apart memory tiers whose bandwidth and latency differ by large factors, like
+// local DRAM, remote DRAM and CXL-attached memory.
+
+const (
+	cacheLineSize = 64
+	// maxChaseSlots bounds the memory used to build the pointer chase, regardless of the region size
+	maxChaseSlots = 1 << 22
+	chaseAccesses = 1 << 22
+)
+
+type BenchStats struct {
+	BandwidthMBps float64
+	LatencyNs     float64
+}
+
+// benchSink prevents the compiler from optimizing the reads away
+var benchSink uint64
+
+// benchmark measures the bandwidth with sequential reads and writes of the whole region,
+// repeated `passes` times, and the latency with a chase of dependent loads scattered
+// on the region. Overwrites the region.
+func benchmark(data []byte, passes int) BenchStats {
+	return BenchStats{
+		BandwidthMBps: measureBandwidth(data, passes),
+		LatencyNs:     measureLatency(data),
+	}
+}
+
+// measureBandwidth reads and writes each word, so each pass moves twice the region.
+func measureBandwidth(data []byte, passes int) float64 {
+	words := unsafe.Slice((*uint64)(unsafe.Pointer(&data[0])), len(data)/8)
+	var sum uint64
+	ts := time.Now()
+	for range passes {
+		for i := range words {
+			sum += words[i]
+			words[i] = sum
+		}
+	}
+	elapsed := time.Since(ts)
+	benchSink += sum
+	moved := float64(2 * len(words) * 8 * passes)
+	return moved / elapsed.Seconds() / 1e6
+}
+
+// measureLatency links cache lines spread on the region in a random cycle, so each load
+// depends on the previous one and the prefetchers can't guess the next address.
+func measureLatency(data []byte) float64 {
+	slots := min(len(data)/cacheLineSize, maxChaseSlots)
+	if slots < 2 {
+		return 0
+	}
+	stride := (len(data) / slots) &^ (cacheLineSize - 1)
+	perm := rand.Perm(slots)
+	for i, slot := range perm {
+		next := perm[(i+1)%slots]
+		*(*uint64)(unsafe.Pointer(&data[slot*stride])) = uint64(next * stride)
+	}
+
+	offset := uint64(perm[0] * stride)
+	ts := time.Now()
+	for range chaseAccesses {
+		offset = *(*uint64)(unsafe.Pointer(&data[offset]))
+	}
+	elapsed := time.Since(ts)
+	benchSink += offset
+	return float64(elapsed.Nanoseconds()) / chaseAccesses
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	"k8s.io/utils/cpuset"
+)
+
+// The churn workload keeps allocating, touching and releasing memory from many threads,
+// to exercise the limits while they are enforced and the cgroups of the container change.
+
+type ChurnStats struct {
+	Cycles   uint64
+	Failures uint64 // allocations refused with ENOMEM
+}
+
+// churn runs `workers` threads for `duration`. Each thread allocates `alloc`, binds it round-robin
+// to one of the NUMA nodes `nodes`, if any, touches and releases it, over and over.
+// Allocations refused with ENOMEM are counted but not fatal; any other error stops the workload.
+func churn(lh logr.Logger, alloc Allocation, workers int, duration time.Duration, nodes cpuset.CPUSet) (ChurnStats, error) {
+	var cycles, failures atomic.Uint64
+	deadline := time.Now().Add(duration)
+	nodeIDs := nodes.List()
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// distinct threads, to exercise the per-thread paths of the kernel, like the memory policies
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			for iter := worker; time.Now().Before(deadline); iter++ {
+				data, err := alloc.Map()
+				if errors.Is(err, unix.ENOMEM) {
+					failures.Add(1)
+					continue
+				}
+				if err != nil {
+					errs[worker] = fmt.Errorf("worker %d: map: %w", worker, err)
+					return
+				}
+				if len(nodeIDs) > 0 {
+					err = applyMemPolicy(data, MemPolicyBind, cpuset.New(nodeIDs[iter%len(nodeIDs)]))
+					if err != nil {
+						_ = alloc.Unmap(data)
+						errs[worker] = fmt.Errorf("worker %d: mbind: %w", worker, err)
+						return
+					}
+				}
+				touchMemory(data)
+				err = alloc.Unmap(data)
+				if err != nil {
+					errs[worker] = fmt.Errorf("worker %d: unmap: %w", worker, err)
+					return
+				}
+				cycles.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	stats := ChurnStats{
+		Cycles:   cycles.Load(),
+		Failures: failures.Load(),
+	}
+	lh.Info("churn done", "workers", workers, "duration", duration, "cycles", stats.Cycles, "failures", stats.Failures)
+	return stats, errors.Join(errs...)
+}
+
+// touchMemory writes a byte on each base page, enough to fault in all the region.
+func touchMemory(data []byte) {
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(data); i += pageSize {
+		data[i] = 42
+	}
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/cpuset"
+)
+
+const (
+	memsAllowedList = "Mems_allowed_list"
+	cpusAllowedList = "Cpus_allowed_list"
+)
+
+// readAllowedList returns the list `key` of the status of the process, like the NUMA nodes
+// it is allowed to allocate memory from, or the CPUs it is allowed to run on.
+func readAllowedList(procRoot, key string) (cpuset.CPUSet, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "proc", "self", "status"))
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		val, ok := strings.CutPrefix(line, key+":")
+		if !ok {
+			continue
+		}
+		return cpuset.Parse(strings.TrimSpace(val))
+	}
+	return cpuset.CPUSet{}, fmt.Errorf("missing %s", key)
+}
+
+// numaNodesOfAllowedCPUs returns the NUMA nodes of the CPUs the process is allowed to run on.
+// The CPU drivers, like dra-driver-cpu, set the cpuset of the container like we set the memory nodes.
+func numaNodesOfAllowedCPUs(procRoot, sysRoot string) (cpuset.CPUSet, error) {
+	cpus, err := readAllowedList(procRoot, cpusAllowedList)
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	nodesPath := filepath.Join(sysRoot, "sys", "devices", "system", "node")
+	entries, err := os.ReadDir(nodesPath)
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	var nodes []int
+	for _, entry := range entries {
+		nodeID, ok := strings.CutPrefix(entry.Name(), "node")
+		if !ok {
+			continue
+		}
+		numaNode, err := strconv.Atoi(nodeID)
+		if err != nil {
+			continue // not a node, e.g. "online"
+		}
+		data, err := os.ReadFile(filepath.Join(nodesPath, entry.Name(), "cpulist"))
+		if err != nil {
+			return cpuset.CPUSet{}, err
+		}
+		nodeCPUs, err := cpuset.Parse(strings.TrimSpace(string(data)))
+		if err != nil {
+			return cpuset.CPUSet{}, fmt.Errorf("parsing the CPUs of NUMA node %d: %w", numaNode, err)
+		}
+		if !nodeCPUs.Intersection(cpus).IsEmpty() {
+			nodes = append(nodes, numaNode)
+		}
+	}
+	return cpuset.New(nodes...), nil
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"unsafe"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	"k8s.io/utils/cpuset"
+
+	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/test/pkg/memalign"
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+// The fork workload mimics the servers, like Postgres or nginx, whose workers are child processes
+// consuming the memory claimed by the container. The children must be constrained like the parent:
+// same cgroup, thus same limits, and same cpuset.mems and cpuset.cpus.
+
+// forkChildEnv marks the processes started by the tester as children, and carries their index.
+const forkChildEnv = "DRAMEMTESTER_FORK_CHILD"
+
+// forkChildIndex returns the index of the child, if the process is a child.
+func forkChildIndex() (int, bool) {
+	val, ok := os.LookupEnv(forkChildEnv)
+	if !ok {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
+
+// Parent is what the parent observed about itself, to compare with the children.
+type Parent struct {
+	Cgroup      string
+	MemsAllowed cpuset.CPUSet
+	CPUsAllowed cpuset.CPUSet
+}
+
+func readParent(procRoot string) (Parent, error) {
+	var par Parent
+	var err error
+	par.Cgroup, err = cgroups.PathByPID(procRoot, cgroups.PIDSelf)
+	if err != nil {
+		return par, err
+	}
+	par.MemsAllowed, err = readAllowedList(procRoot, memsAllowedList)
+	if err != nil {
+		return par, err
+	}
+	par.CPUsAllowed, err = readAllowedList(procRoot, cpusAllowedList)
+	return par, err
+}
+
+// runForkChild allocates, reports what it observed to the parent on stdout, and holds the allocation
+// until the parent closes stdin, so the allocations of all the children are charged at the same time.
+// Returns the exit code of the child; a refused allocation is reported, not a failure of the child.
+func runForkChild(lh logr.Logger, alloc Allocation, procRoot string) int {
+	report := result.Child{
+		PID: os.Getpid(),
+	}
+	data, err := forkChildAllocate(alloc, procRoot, &report)
+	if errors.Is(err, unix.ENOMEM) {
+		report.Refused = true
+	} else if err != nil {
+		report.Error = err.Error()
+	}
+	err = json.NewEncoder(os.Stdout).Encode(report)
+	if err != nil {
+		lh.Error(err, "cannot report to the parent")
+		return 1
+	}
+	_, _ = io.Copy(io.Discard, os.Stdin)
+	if data != nil {
+		_ = alloc.Unmap(data)
+	}
+	return 0
+}
+
+func forkChildAllocate(alloc Allocation, procRoot string, report *result.Child) ([]byte, error) {
+	par, err := readParent(procRoot)
+	if err != nil {
+		return nil, err
+	}
+	report.Cgroup = par.Cgroup
+	report.MemsAllowed = par.MemsAllowed.String()
+	report.CPUsAllowed = par.CPUsAllowed.String()
+	data, err := alloc.Map()
+	if err != nil {
+		return nil, err
+	}
+	touchMemory(data)
+	nodes, err := memalign.NUMANodesByAddress(memalign.PIDSelf, procRoot, uintptr(unsafe.Pointer(&data[0])))
+	if err != nil {
+		return data, err
+	}
+	report.NUMANodes = nodes.String()
+	return data, nil
+}
+
+// forkChildren starts `count` children running the tester again with the same arguments, which allocate
+// like the parent would, and collects their reports once all of them hold their allocation.
+func forkChildren(lh logr.Logger, count int) ([]result.Child, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	// the last occurrence of a flag wins: the children must not report in place of the parent
+	args := append(slices.Clone(os.Args[1:]), "-fork-children=0", "-run-forever=false", "-termination-log=", "-result-file=", "-status-addr=")
+
+	type forked struct {
+		cmd    *exec.Cmd
+		stdin  io.WriteCloser
+		stdout *bufio.Reader
+	}
+	var children []forked
+	defer func() {
+		// releasing the allocations of all the children at once
+		for _, child := range children {
+			_ = child.stdin.Close()
+		}
+		for _, child := range children {
+			_ = child.cmd.Wait()
+		}
+	}()
+
+	for idx := range count {
+		cmd := exec.Command(exe, args...)
+		cmd.Env = append(os.Environ(), forkChildEnv+"="+strconv.Itoa(idx))
+		cmd.Stderr = os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		err = cmd.Start()
+		if err != nil {
+			return nil, fmt.Errorf("child %d: %w", idx, err)
+		}
+		children = append(children, forked{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)})
+		lh.V(2).Info("child forked", "index", idx, "pid", cmd.Process.Pid)
+	}
+
+	reports := make([]result.Child, 0, count)
+	for idx, child := range children {
+		line, err := child.stdout.ReadBytes('\n')
+		if err != nil {
+			return reports, fmt.Errorf("child %d: no report: %w", idx, err)
+		}
+		var report result.Child
+		err = json.Unmarshal(line, &report)
+		if err != nil {
+			return reports, fmt.Errorf("child %d: malformed report: %w", idx, err)
+		}
+		reports = append(reports, report)
+	}
+	lh.Info("children allocated", "count", count)
+	return reports, nil
+}
+
+// checkForkChildren returns an error if a child is not constrained like the parent, or if its
+// allocation comes from NUMA nodes the parent is not allowed to allocate from.
+func checkForkChildren(par Parent, reports []result.Child) error {
+	for idx, report := range reports {
+		if report.Cgroup != par.Cgroup {
+			return fmt.Errorf("child %d escaped the cgroup expected=%q actual=%q", idx, par.Cgroup, report.Cgroup)
+		}
+		if report.MemsAllowed != par.MemsAllowed.String() {
+			return fmt.Errorf("child %d allowed NUMA nodes mismatch expected=%q actual=%q", idx, par.MemsAllowed.String(), report.MemsAllowed)
+		}
+		if report.CPUsAllowed != par.CPUsAllowed.String() {
+			return fmt.Errorf("child %d allowed CPUs mismatch expected=%q actual=%q", idx, par.CPUsAllowed.String(), report.CPUsAllowed)
+		}
+		if report.NUMANodes == "" {
+			continue
+		}
+		nodes, err := cpuset.Parse(report.NUMANodes)
+		if err != nil {
+			return fmt.Errorf("child %d: %w", idx, err)
+		}
+		if !nodes.IsSubsetOf(par.MemsAllowed) {
+			return fmt.Errorf("child %d allocated off the allowed NUMA nodes expected=%q actual=%q", idx, par.MemsAllowed.String(), report.NUMANodes)
+		}
+	}
+	return nil
+}
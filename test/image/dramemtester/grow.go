@@ -0,0 +1,49 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/sys/unix"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// grow maps and faults in memory in increments of alloc.Size, until the kernel refuses with ENOMEM
+// or the total reaches `limit`, if not zero. Returns the total allocated. The memory is never released.
+// Only the hugepages reservations fail with ENOMEM: exceeding the memory limit of the container
+// triggers the OOM killer instead, so the total is meaningful with HugeTLB.
+func grow(lh logr.Logger, alloc Allocation, limit uint64) (uint64, error) {
+	var total uint64
+	for limit == 0 || total+alloc.Size <= limit {
+		data, err := alloc.Map()
+		if errors.Is(err, unix.ENOMEM) {
+			lh.Info("allocation refused", "total", unitconv.SizeInBytesToMinimizedString(total))
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		checkAllocatedMemory(lh, data)
+		total += alloc.Size
+		lh.V(2).Info("allocation grown", "total", unitconv.SizeInBytesToMinimizedString(total))
+	}
+	lh.Info("allocation limit reached", "total", unitconv.SizeInBytesToMinimizedString(total))
+	return total, nil
+}
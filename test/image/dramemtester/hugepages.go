@@ -0,0 +1,38 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/bits"
+
+	"golang.org/x/sys/unix"
+)
+
+// minHugepageSize is the smallest hugepage size, found on arm64 with 4Ki base pages (64Ki contiguous PTEs).
+const minHugepageSize = 64 * (1 << 10)
+
+// hugepageSizeBits returns the flags selecting the hugepages of the given size, instead of
+// the default one. The kernel encodes the size as log2 in the high bits of the flags of mmap,
+// memfd_create and shmget alike, so any size supported by the architecture works: 2Mi and 1Gi
+// on x86_64, 64Ki, 2Mi, 32Mi and 1Gi on arm64 with 4Ki base pages, 512Mi with 64Ki base pages.
+func hugepageSizeBits(size uint64) (int, error) {
+	if size < minHugepageSize || bits.OnesCount64(size) != 1 {
+		return 0, fmt.Errorf("invalid hugepage size %d: must be a power of two not smaller than %d", size, minHugepageSize)
+	}
+	return bits.TrailingZeros64(size) << unix.MAP_HUGE_SHIFT, nil
+}
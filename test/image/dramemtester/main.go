@@ -17,12 +17,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
 	"time"
+	"unsafe"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
@@ -31,6 +34,7 @@ import (
 	"k8s.io/utils/cpuset"
 
 	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
+	"github.com/ffromani/dra-driver-memory/pkg/env"
 	"github.com/ffromani/dra-driver-memory/pkg/hugepages"
 	"github.com/ffromani/dra-driver-memory/pkg/sysinfo"
 	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
@@ -40,27 +44,120 @@ import (
 
 func main() {
 	var useHugeTLB bool = true
+	var useTHP bool
+	var growMode bool
+	var churnWorkers int
+	var forkChildrenCount int
+	var benchMode bool
+	var lockMem bool
+	var benchPasses int = 4
+	var churnDuration time.Duration = 30 * time.Second
 	var runForever bool
 	var shouldFail bool
 	var singleNUMA bool
 	var anyNUMA bool
+	var alignCPUs bool
+	var guestMemoryFromEnv bool
+	var hugetlbfsPath string
+	var hugepagesFile string
+	var terminationLogPath string = "/dev/termination-log"
+	var resultPath string
+	var statusAddr string
+	var backing Backing = BackingAnonymous
 	var procRoot string = "/"
 	var sysRoot string = "/"
 	var numaNodes cpuset.CPUSet
+	var memPolicy MemPolicy = MemPolicyNone
+	var memPolicyNodes cpuset.CPUSet
 	var allocSize uint64 = uint64(8 * (1 << 20)) // bytes
+	var hugepageSize uint64                      // bytes, zero means the default size
+	var growLimit uint64                         // bytes, zero means no limit
 
 	flag.BoolVar(&runForever, "run-forever", runForever, "Run forever after the operation is completed.")
 	flag.BoolVar(&useHugeTLB, "use-hugetlb", useHugeTLB, "Use HugeTLB for allocation.")
+	flag.BoolVar(&useTHP, "use-thp", useTHP, "Use transparent hugepages for allocation, with madvise(MADV_HUGEPAGE), and report how much memory they back. Implies use-hugetlb=false and backing=anonymous.")
 	flag.BoolVar(&shouldFail, "should-fail", shouldFail, "Expect failure, not success.")
+	flag.StringVar(&terminationLogPath, "termination-log", terminationLogPath, "Write the result as JSON on this file, if it exists, to be reported as the termination message of the container. Empty disables.")
+	flag.StringVar(&resultPath, "result-file", resultPath, "Write the result as JSON also on this file, created if missing.")
+	flag.StringVar(&statusAddr, "status-addr", statusAddr, "With run-forever, serve /healthz, /readyz and /result on this address, like ':8080' or 'unix:/run/dramemtester.sock'. Empty disables.")
 	flag.StringVar(&procRoot, "proc-root", procRoot, "procfs root path.")
 	flag.StringVar(&sysRoot, "sys-root", sysRoot, "sysfs root path.")
 	flag.Var(&UnitValue{SizeInBytes: &allocSize}, "alloc-size", "Amount of memory to allocate.")
+	flag.BoolVar(&growMode, "grow", growMode, "Allocate in increments of alloc-size until the allocation fails, and report the total. Meaningful with use-hugetlb.")
+	flag.Var(&UnitValue{SizeInBytes: &growLimit}, "grow-limit", "Stop growing the allocation at this total. Default is no limit.")
+	flag.IntVar(&churnWorkers, "churn-workers", churnWorkers, "Run this many threads which allocate alloc-size, bind it to the allowed NUMA nodes round-robin, touch and release it, over and over. Zero disables the churn.")
+	flag.IntVar(&forkChildrenCount, "fork-children", forkChildrenCount, "Fork this many child processes which allocate alloc-size each and hold it until all of them allocated, and verify they share the cgroup, the allowed CPUs and the allowed NUMA nodes of the parent. Zero disables the children.")
+	flag.DurationVar(&churnDuration, "churn-duration", churnDuration, "Duration of the churn workload.")
+	flag.BoolVar(&lockMem, "mlock", lockMem, "Lock the allocation in memory with mlock, like RDMA and DPDK do, and report the memory locked. The failures due to RLIMIT_MEMLOCK are reported distinctly.")
+	flag.BoolVar(&benchMode, "benchmark", benchMode, "Measure the bandwidth and the latency of the allocation, and report them.")
+	flag.IntVar(&benchPasses, "benchmark-passes", benchPasses, "Passes on the allocation of the bandwidth measurement.")
+	flag.Var(&UnitValue{SizeInBytes: &hugepageSize}, "hugepage-size", "Size of the hugepages backing the allocation (e.g. 1Gi), verified after the allocation. Requires use-hugetlb. Default is the system default size.")
 	flag.Var(&NUMAValue{Nodes: &numaNodes, Single: &singleNUMA, Any: &anyNUMA}, "numa-align", "NUMA alignment required.")
+	flag.BoolVar(&alignCPUs, "numa-align-cpus", alignCPUs, "Require the memory to be allocated from the NUMA nodes of the CPUs the process is allowed to run on.")
+	flag.Var(&MemPolicyValue{Policy: &memPolicy}, "policy", "NUMA memory policy of the allocation, set with mbind and verified after the allocation: none, bind, preferred, interleave.")
+	flag.Var(&NUMAValue{Nodes: &memPolicyNodes}, "policy-nodes", "NUMA nodes of the memory policy.")
+	flag.BoolVar(&guestMemoryFromEnv, "guest-memory-from-env", guestMemoryFromEnv, "Allocate the guest memory reported by the driver, like a VMM would. Overrides alloc-size.")
+	flag.StringVar(&hugetlbfsPath, "hugetlbfs-path", hugetlbfsPath, "Back the allocation with a file on this hugetlbfs mount, like a VMM would. Use 'env' for the path reported by the driver. Implies backing=hugetlbfs.")
+	flag.StringVar(&hugepagesFile, "hugepages-file", hugepagesFile, "Back the allocation with this existing file on hugetlbfs, like a database would, mapping all of it. Use 'env' for the file prepared by the driver. Overrides alloc-size, implies backing=hugetlbfs.")
+	flag.Var(&BackingValue{Backing: &backing}, "backing", "Backing of the allocation: anonymous (private mapping), hugetlbfs (file on hugetlbfs-path), memfd (memfd_create), shm (SysV shared memory). Hugepages if use-hugetlb.")
 	flag.Parse()
 
 	var lh logr.Logger = stdr.New(log.New(os.Stderr, "", log.LstdFlags|log.Lshortfile))
 
+	if guestMemoryFromEnv {
+		guestMemory, ok := env.LookupGuestMemory(os.Environ())
+		if !ok {
+			lh.Info("missing guest memory from the environment")
+			os.Exit(3)
+		}
+		allocSize = uint64(guestMemory)
+	}
+	if hugetlbfsPath == "env" {
+		path, ok := env.LookupHugeTLBFS(os.Environ())
+		if !ok {
+			lh.Info("missing hugetlbfs path from the environment")
+			os.Exit(3)
+		}
+		hugetlbfsPath = path
+	}
+	if hugepagesFile == "env" {
+		path, ok := env.LookupHugepagesFile(os.Environ())
+		if !ok {
+			lh.Info("missing hugepages file from the environment")
+			os.Exit(3)
+		}
+		hugepagesFile = path
+	}
+	if hugepagesFile != "" {
+		st, err := os.Stat(hugepagesFile)
+		if err != nil {
+			lh.Info("cannot check the hugepages file", "path", hugepagesFile, "err", err)
+			os.Exit(3)
+		}
+		allocSize = uint64(st.Size())
+	}
+
+	if backing == BackingAnonymous && (hugetlbfsPath != "" || hugepagesFile != "") {
+		backing = BackingHugeTLBFS
+	}
+	if useTHP {
+		useHugeTLB = false
+	}
+
 	res := result.New(allocSize, useHugeTLB, numaNodes.String())
+	res.Request.Backing = string(backing)
+	res.Request.THP = useTHP
+	res.Request.Grow = growMode
+	res.Request.ChurnWorkers = churnWorkers
+	res.Request.Benchmark = benchMode
+	res.Request.ForkChildren = forkChildrenCount
+	res.Request.MLock = lockMem
+	if hugepageSize != 0 {
+		res.Request.HugepageSize = unitconv.SizeInBytesToMinimizedString(hugepageSize)
+	}
+	if memPolicy != MemPolicyNone {
+		res.Request.MemPolicy = string(memPolicy) + ":" + memPolicyNodes.String()
+	}
 
 	var mgr *Manager
 	if runForever {
@@ -68,36 +165,248 @@ func main() {
 	} else {
 		mgr = NewManager(res)
 	}
+	// the kubelet creates the termination log, don't litter outside of kubernetes
+	if _, err := os.Stat(terminationLogPath); terminationLogPath != "" && err == nil {
+		mgr.resultPaths = append(mgr.resultPaths, terminationLogPath)
+	}
+	if resultPath != "" {
+		mgr.resultPaths = append(mgr.resultPaths, resultPath)
+	}
+	if runForever && statusAddr != "" {
+		mgr.status = NewStatusServer()
+		err := mgr.status.Serve(lh, statusAddr)
+		if err != nil {
+			mgr.Complete(3, result.FailureGeneric, "cannot serve the status: %v", err)
+		}
+	}
 
 	disc := sysinfo.NewDiscoverer(sysRoot)
 
-	prot := unix.PROT_READ | unix.PROT_WRITE
-	flags := unix.MAP_ANONYMOUS | unix.MAP_PRIVATE
-	if useHugeTLB {
-		flags |= unix.MAP_HUGETLB
+	alloc := Allocation{
+		Backing:       backing,
+		Size:          allocSize,
+		HugeTLB:       useHugeTLB,
+		HugeTLBFSPath: hugetlbfsPath,
+		HugepagesFile: hugepagesFile,
+	}
+	if useTHP && backing != BackingAnonymous {
+		mgr.Complete(3, result.FailureGeneric, "use-thp requires backing %q", BackingAnonymous)
+	}
+	if lockMem && (growMode || churnWorkers > 0 || forkChildrenCount > 0) {
+		mgr.Complete(3, result.FailureGeneric, "mlock is incompatible with grow, churn and fork-children")
+	}
+	if hugepageSize != 0 {
+		if !useHugeTLB {
+			mgr.Complete(3, result.FailureGeneric, "hugepage-size requires use-hugetlb")
+		}
+		hpBits, err := hugepageSizeBits(hugepageSize)
+		if err != nil {
+			mgr.Complete(3, result.FailureGeneric, "%v", err)
+		}
+		alloc.HugepageSizeBits = hpBits
+	}
+
+	if _, ok := forkChildIndex(); ok {
+		os.Exit(runForkChild(lh, alloc, procRoot))
+	}
+
+	if growMode {
+		if useTHP || memPolicy != MemPolicyNone {
+			mgr.Complete(3, result.FailureGeneric, "grow is incompatible with use-thp and policy")
+		}
+		total, err := grow(lh, alloc, growLimit)
+		if err != nil {
+			mgr.Complete(1, result.UnexpectedMMapError, "mmap error: %v", err)
+		}
+		res.Allocation = &result.Allocation{
+			GrownSize:        unitconv.SizeInBytesToMinimizedString(total),
+			GrownSizeInBytes: total,
+		}
+		mgr.Complete(0, result.Succeeded, "grown to %s", unitconv.SizeInBytesToMinimizedString(total))
 	}
 
-	lh.Info("mmap", "size", unitconv.SizeInBytesToMinimizedString(allocSize), "prot", prot, "flags", flags)
+	if churnWorkers > 0 {
+		if growMode || useTHP || memPolicy != MemPolicyNone {
+			mgr.Complete(3, result.FailureGeneric, "churn is incompatible with grow, use-thp and policy")
+		}
+		memsAllowed, err := readAllowedList(procRoot, memsAllowedList)
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot read the allowed NUMA nodes: %v", err)
+		}
+		stats, err := churn(lh, alloc, churnWorkers, churnDuration, memsAllowed)
+		res.Allocation = &result.Allocation{
+			ChurnCycles:   stats.Cycles,
+			ChurnFailures: stats.Failures,
+		}
+		if err != nil {
+			mgr.Complete(1, result.UnexpectedChurnError, "churn error: %v", err)
+		}
+		mgr.Complete(0, result.Succeeded, "churned %d allocations, %d refused", stats.Cycles, stats.Failures)
+	}
+
+	if forkChildrenCount > 0 {
+		if growMode || useTHP || memPolicy != MemPolicyNone || churnWorkers > 0 {
+			mgr.Complete(3, result.FailureGeneric, "fork-children is incompatible with grow, use-thp, policy and churn")
+		}
+		par, err := readParent(procRoot)
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot read the constraints of the parent: %v", err)
+		}
+		children, err := forkChildren(lh, forkChildrenCount)
+		res.Allocation = &result.Allocation{
+			Children: children,
+		}
+		if err != nil {
+			mgr.Complete(1, result.UnexpectedForkError, "fork error: %v", err)
+		}
+		err = checkForkChildren(par, children)
+		if err != nil {
+			mgr.Complete(4, result.ForkInheritanceViolated, "%v", err)
+		}
+		refused := 0
+		for idx, child := range children {
+			if child.Error != "" {
+				mgr.Complete(1, result.UnexpectedForkError, "child %d error: %s", idx, child.Error)
+			}
+			if child.Refused {
+				refused++
+			}
+		}
+		if shouldFail {
+			if refused > 0 {
+				mgr.Complete(0, result.FailedAsExpected, "Allocation of %d children out of %d failed as expected with 'ENOMEM' (Out of memory)", refused, len(children))
+			}
+			mgr.Complete(1, result.UnexpectedMMapSuccess, "all the %d children allocated", len(children))
+		}
+		if refused > 0 {
+			mgr.Complete(1, result.UnexpectedMMapError, "allocation of %d children out of %d refused", refused, len(children))
+		}
+		mgr.Complete(0, result.Succeeded, "%d children allocated", len(children))
+	}
+
+	lh.Info("mmap", "size", unitconv.SizeInBytesToMinimizedString(allocSize), "backing", backing, "hugeTLB", useHugeTLB, "hugetlbfsPath", hugetlbfsPath)
 
 	logCurrentLimits(lh.WithValues("trace", "pre"), disc, procRoot)
-	data, err := unix.Mmap(-1, 0, int(allocSize), prot, flags)
+	data, err := alloc.Map()
 	logCurrentLimits(lh.WithValues("trace", "pos"), disc, procRoot)
 
 	if err != nil {
-		if shouldFail && err == unix.ENOMEM { // TODO: is equality check the best option here?
+		if shouldFail && errors.Is(err, unix.ENOMEM) {
 			mgr.Complete(0, result.FailedAsExpected, "Allocation failed as expected with 'ENOMEM' (Out of memory)")
 		}
 		// Any other error is a different problem
 		mgr.Complete(1, result.UnexpectedMMapError, "mmap error: %v", err)
 	}
 
+	if useTHP {
+		// must be done before the memory is faulted in
+		err = unix.Madvise(data, unix.MADV_HUGEPAGE)
+		if err != nil {
+			mgr.Complete(1, result.UnexpectedMAdviseError, "madvise error: %v", err)
+		}
+	}
+
+	if memPolicy != MemPolicyNone {
+		err = applyMemPolicy(data, memPolicy, memPolicyNodes)
+		if err != nil {
+			if shouldFail {
+				mgr.Complete(0, result.FailedAsExpected, "Memory policy failed as expected: %v", err)
+			}
+			mgr.Complete(1, result.UnexpectedMemPolicyError, "mbind error: %v", err)
+		}
+	}
+
+	if lockMem {
+		limit, err := readMemlockLimit()
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot read RLIMIT_MEMLOCK: %v", err)
+		}
+		res.Allocation = &result.Allocation{
+			MemlockLimit: limit.String(),
+		}
+		// faults in the memory, so must be done after the memory policy is set
+		err = lockMemory(data)
+		if err != nil {
+			if isMemlockLimitError(err, allocSize, limit) {
+				mgr.Complete(1, result.MemlockLimitExceeded, "mlock exceeds RLIMIT_MEMLOCK size=%q limit=%q: %v", unitconv.SizeInBytesToMinimizedString(allocSize), limit.String(), err)
+			}
+			mgr.Complete(1, result.UnexpectedMLockError, "mlock error: %v", err)
+		}
+	}
+
 	checkAllocatedMemory(lh, data)
 
+	if lockMem {
+		lockedSize, err := memalign.LockedByAddress(memalign.PIDSelf, procRoot, uintptr(unsafe.Pointer(&data[0])))
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot check the locked memory: %v", err)
+		}
+		lh.Info("locked memory", "size", unitconv.SizeInBytesToMinimizedString(allocSize), "lockedSize", unitconv.SizeInBytesToMinimizedString(lockedSize), "limit", res.Allocation.MemlockLimit)
+		res.Allocation.LockedSize = unitconv.SizeInBytesToMinimizedString(lockedSize)
+		res.Allocation.LockedSizeInBytes = lockedSize
+	}
+
+	if hugepageSize != 0 {
+		// must be done after the memory is faulted in
+		pageSize, err := memalign.PageSizeByAddress(memalign.PIDSelf, procRoot, uintptr(unsafe.Pointer(&data[0])))
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot check page size: %v", err)
+		}
+		if pageSize != hugepageSize {
+			mgr.Complete(4, result.PageSizeMismatch, "page size mismatch expected=%q actual=%q", unitconv.SizeInBytesToMinimizedString(hugepageSize), unitconv.SizeInBytesToMinimizedString(pageSize))
+		}
+	}
+
+	if useTHP {
+		// THP backing is best effort: depends on the system settings and on the fragmentation of the memory
+		thpSize, err := memalign.AnonHugePagesByAddress(memalign.PIDSelf, procRoot, uintptr(unsafe.Pointer(&data[0])))
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot check THP backing: %v", err)
+		}
+		lh.Info("THP backing", "size", unitconv.SizeInBytesToMinimizedString(allocSize), "thpSize", unitconv.SizeInBytesToMinimizedString(thpSize))
+		if res.Allocation == nil {
+			res.Allocation = &result.Allocation{}
+		}
+		res.Allocation.THPSize = unitconv.SizeInBytesToMinimizedString(thpSize)
+		res.Allocation.THPSizeInBytes = thpSize
+	}
+
+	if memPolicy != MemPolicyNone {
+		regionNodes, err := memalign.NUMANodesByAddress(memalign.PIDSelf, procRoot, uintptr(unsafe.Pointer(&data[0])))
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot check allocation: %v", err)
+		}
+		err = checkMemPolicy(memPolicy, memPolicyNodes, regionNodes)
+		if err != nil {
+			mgr.Complete(4, result.MemPolicyViolated, "%v", err)
+		}
+	}
+
+	if benchMode {
+		stats := benchmark(data, benchPasses)
+		lh.Info("benchmark", "bandwidthMBps", stats.BandwidthMBps, "latencyNs", stats.LatencyNs)
+		if res.Allocation == nil {
+			res.Allocation = &result.Allocation{}
+		}
+		res.Allocation.BandwidthMBps = stats.BandwidthMBps
+		res.Allocation.LatencyNs = stats.LatencyNs
+	}
+
 	memNodes, err := memalign.NUMANodesByPID(lh, memalign.PIDSelf, procRoot)
 	if err != nil {
 		mgr.Complete(2, result.CannotCheckAllocation, "cannot check allocation: %v", err)
 	}
 
+	if alignCPUs {
+		cpuNodes, err := numaNodesOfAllowedCPUs(procRoot, sysRoot)
+		if err != nil {
+			mgr.Complete(2, result.CannotCheckAllocation, "cannot check the NUMA nodes of the CPUs: %v", err)
+		}
+		if !memNodes.IsSubsetOf(cpuNodes) {
+			mgr.Complete(4, result.NUMACPUMismatch, "NUMA nodes allocation not aligned with the CPUs expected=%q actual=%q", cpuNodes.String(), memNodes.String())
+		}
+	}
+
 	if singleNUMA {
 		if memNodes.Size() != 1 {
 			mgr.Complete(4, result.NUMAOverflown, "NUMA nodes allocation don't come from a single NUMA node actual=%q", memNodes.String())
@@ -117,6 +426,9 @@ func main() {
 type Manager struct {
 	res      *result.Result
 	signalCh chan os.Signal
+	status   *StatusServer
+	// resultPaths are the files to write the result on, besides stdout
+	resultPaths []string
 }
 
 func NewManager(res *result.Result) *Manager {
@@ -139,6 +451,13 @@ func (pl *Manager) Complete(code int, reason result.Reason, fmt_ string, args ..
 		fmt_ = "waiting for a signal to quit; " + fmt_
 	}
 	pl.res.Finalize(code, reason, fmt_, args...)
+	pl.status.Update(pl.res)
+	for _, path := range pl.resultPaths {
+		err := pl.res.WriteFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cannot write the result on %q: %v\n", path, err)
+		}
+	}
 	if pl.signalCh != nil {
 		<-pl.signalCh
 	}
@@ -178,11 +497,11 @@ func (v NUMAValue) String() string {
 
 func (v NUMAValue) Set(s string) error {
 	s = strings.ToLower(s)
-	if s == "single" {
+	if s == "single" && v.Single != nil {
 		*v.Single = true
 		return nil
 	}
-	if s == "any" {
+	if s == "any" && v.Any != nil {
 		*v.Any = true
 		return nil
 	}
@@ -206,7 +525,7 @@ func (v UnitValue) String() string {
 }
 
 func (v UnitValue) Set(s string) error {
-	val, err := unitconv.MinimizedStringToSizeInBytes(s)
+	val, err := unitconv.ParseSizeInBytes(s)
 	if err != nil {
 		return err
 	}
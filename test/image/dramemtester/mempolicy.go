@@ -0,0 +1,111 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"k8s.io/utils/cpuset"
+)
+
+// The cpuset.mems set by the driver is the outer bound of the memory placement. Within it,
+// applications can use the NUMA memory policies to place their memory more precisely.
+
+type MemPolicy string
+
+const (
+	MemPolicyNone       MemPolicy = "none"
+	MemPolicyBind       MemPolicy = "bind"
+	MemPolicyPreferred  MemPolicy = "preferred"
+	MemPolicyInterleave MemPolicy = "interleave"
+)
+
+func (mp MemPolicy) mode() int {
+	switch mp {
+	case MemPolicyBind:
+		return unix.MPOL_BIND
+	case MemPolicyPreferred:
+		return unix.MPOL_PREFERRED
+	case MemPolicyInterleave:
+		return unix.MPOL_INTERLEAVE
+	default:
+		return unix.MPOL_DEFAULT
+	}
+}
+
+// applyMemPolicy sets the NUMA memory policy of the region with mbind(2).
+// Must be done before the memory is faulted in, to affect the placement.
+func applyMemPolicy(data []byte, policy MemPolicy, nodes cpuset.CPUSet) error {
+	if nodes.IsEmpty() {
+		return fmt.Errorf("memory policy %q requires NUMA nodes", policy)
+	}
+	if policy == MemPolicyPreferred && nodes.Size() != 1 {
+		return fmt.Errorf("memory policy %q requires exactly one NUMA node, got %q", policy, nodes.String())
+	}
+	nodeIDs := nodes.List()
+	mask := make([]uint64, nodeIDs[len(nodeIDs)-1]/64+1)
+	for _, nodeID := range nodeIDs {
+		mask[nodeID/64] |= 1 << (nodeID % 64)
+	}
+	// the kernel reads maxnode-1 bits, see the NOTES of mbind(2)
+	maxNode := len(mask)*64 + 1
+	_, _, errno := unix.Syscall6(unix.SYS_MBIND, uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)), uintptr(policy.mode()), uintptr(unsafe.Pointer(&mask[0])), uintptr(maxNode), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// checkMemPolicy returns an error if the actual placement of the region doesn't obey the policy.
+// The interleave check assumes the region spans at least a page per node.
+func checkMemPolicy(policy MemPolicy, nodes, actual cpuset.CPUSet) error {
+	switch policy {
+	case MemPolicyBind:
+		if actual.IsEmpty() || !actual.IsSubsetOf(nodes) {
+			return fmt.Errorf("memory not bound to the NUMA nodes expected=%q actual=%q", nodes.String(), actual.String())
+		}
+	case MemPolicyPreferred, MemPolicyInterleave:
+		if !actual.Equals(nodes) {
+			return fmt.Errorf("memory not placed on the NUMA nodes expected=%q actual=%q", nodes.String(), actual.String())
+		}
+	}
+	return nil
+}
+
+type MemPolicyValue struct {
+	Policy *MemPolicy
+}
+
+func (v MemPolicyValue) String() string {
+	if v.Policy == nil {
+		return ""
+	}
+	return string(*v.Policy)
+}
+
+func (v MemPolicyValue) Set(s string) error {
+	switch mp := MemPolicy(s); mp {
+	case MemPolicyNone, MemPolicyBind, MemPolicyPreferred, MemPolicyInterleave:
+		*v.Policy = mp
+		return nil
+	default:
+		return fmt.Errorf("unsupported memory policy %q", s)
+	}
+}
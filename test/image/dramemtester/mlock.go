@@ -0,0 +1,69 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// The applications which pin their memory, like RDMA, DPDK or io_uring with registered buffers,
+// lock it with mlock(2) or alike, which is bound by RLIMIT_MEMLOCK unless the process has CAP_IPC_LOCK.
+// The limit is set by the container runtime, independently from the claims.
+
+// MemlockLimit is the RLIMIT_MEMLOCK of the process; Unlimited is true for RLIM_INFINITY.
+type MemlockLimit struct {
+	Bytes     uint64
+	Unlimited bool
+}
+
+func (ml MemlockLimit) String() string {
+	if ml.Unlimited {
+		return "unlimited"
+	}
+	return unitconv.SizeInBytesToMinimizedString(ml.Bytes)
+}
+
+func readMemlockLimit() (MemlockLimit, error) {
+	var rlim unix.Rlimit
+	err := unix.Getrlimit(unix.RLIMIT_MEMLOCK, &rlim)
+	if err != nil {
+		return MemlockLimit{}, err
+	}
+	if rlim.Cur == unix.RLIM_INFINITY {
+		return MemlockLimit{Unlimited: true}, nil
+	}
+	return MemlockLimit{Bytes: rlim.Cur}, nil
+}
+
+// lockMemory locks the region with mlock(2), which also faults it in. Must be done after
+// the memory policy is set, like touching the memory.
+func lockMemory(data []byte) error {
+	return unix.Mlock(data)
+}
+
+// isMemlockLimitError tells if mlock(2) failed because the region exceeds RLIMIT_MEMLOCK, as opposed
+// to the lack of memory. The kernel reports both with ENOMEM, and EPERM if the limit is zero.
+func isMemlockLimitError(err error, size uint64, limit MemlockLimit) bool {
+	if !errors.Is(err, unix.ENOMEM) && !errors.Is(err, unix.EPERM) {
+		return false
+	}
+	return !limit.Unlimited && size > limit.Bytes
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/result"
+)
+
+// StatusServer exposes the result of a tester running forever:
+// /healthz always succeeds, /readyz succeeds once the tester completed successfully,
+// /result returns the result JSON once the tester completed.
+type StatusServer struct {
+	result atomic.Pointer[[]byte]
+	ready  atomic.Bool
+}
+
+func NewStatusServer() *StatusServer {
+	return &StatusServer{}
+}
+
+// Update publishes the result. Safe to call on a nil StatusServer.
+func (ss *StatusServer) Update(res *result.Result) {
+	if ss == nil {
+		return
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	ss.result.Store(&data)
+	ss.ready.Store(res.Status.Code == 0)
+}
+
+func (ss *StatusServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !ss.ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/result", func(w http.ResponseWriter, _ *http.Request) {
+		data := ss.result.Load()
+		if data == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(*data)
+	})
+	return mux
+}
+
+// Serve serves the endpoints on `addr` in the background. The address is either
+// a TCP address, like ":8080", or a unix socket path prefixed by "unix:".
+func (ss *StatusServer) Serve(lh logr.Logger, addr string) error {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", path
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           ss.Handler(),
+		IdleTimeout:       120 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	go func() {
+		err := server.Serve(ln)
+		lh.Error(err, "status server stopped")
+	}()
+	lh.Info("status server started", "network", network, "addr", addr)
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cgrouptree reads the cgroup v2 ancestry of the containers of a pod on its node,
+// so the suites can assert on the values set at each level of the hierarchy.
+package cgrouptree
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	CgroupRoot = "/sys/fs/cgroup"
+)
+
+// Files are the interface files read at each level, if present. Shell globs are allowed.
+var Files = []string{
+	"memory.min",
+	"memory.high",
+	"memory.max",
+	"cpuset.cpus",
+	"cpuset.cpus.effective",
+	"cpuset.mems",
+	"cpuset.mems.effective",
+	"hugetlb.*.max",
+}
+
+// Runner runs the shell script on the node `nodeName` and returns its standard output.
+type Runner func(ctx context.Context, nodeName, script string) ([]byte, error)
+
+// DockerExec runs the script on kind nodes, which are docker containers named after the node.
+func DockerExec(ctx context.Context, nodeName, script string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker", "exec", nodeName, "/bin/sh", "-c", script)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return out, fmt.Errorf("%w: %s", err, string(exitErr.Stderr))
+	}
+	return out, err
+}
+
+// Level is a cgroup in the ancestry. Values maps the interface file names to their content.
+type Level struct {
+	Path   string
+	Values map[string]string
+}
+
+// Hierarchy is the cgroup ancestry of a container, from the outermost level below the
+// root cgroup down to the container cgroup.
+type Hierarchy []Level
+
+// Container returns the innermost level, the cgroup of the container.
+func (h Hierarchy) Container() Level {
+	return h[len(h)-1]
+}
+
+// Pod returns the parent level of the container, the cgroup of the pod.
+func (h Hierarchy) Pod() Level {
+	return h[len(h)-2]
+}
+
+// Find returns the deepest level, the closest to the container, whose path ends with `name`.
+func (h Hierarchy) Find(name string) (Level, bool) {
+	for idx := len(h) - 1; idx >= 0; idx-- {
+		if strings.HasSuffix(h[idx].Path, name) {
+			return h[idx], true
+		}
+	}
+	return Level{}, false
+}
+
+// ForContainer returns the cgroup hierarchy of the container `containerName` of the running `pod`.
+func ForContainer(ctx context.Context, run Runner, pod *v1.Pod, containerName string) (Hierarchy, error) {
+	containerID, err := findContainerID(pod, containerName)
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, pod.Spec.NodeName, MakeScript(CgroupRoot, containerID))
+	if err != nil {
+		return nil, fmt.Errorf("reading the cgroups of container %s/%s/%s on %q: %w", pod.Namespace, pod.Name, containerName, pod.Spec.NodeName, err)
+	}
+	return Parse(out)
+}
+
+// MakeScript returns the shell script which finds below `root` the cgroup of the container `containerID`,
+// which embeds the ID in its name with both the cgroupfs and the systemd cgroup drivers,
+// and walks up the ancestry. For each file found, it emits a line `<cgroup path>\t<file name>\t<value>`,
+// starting from the container cgroup.
+func MakeScript(root, containerID string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "d=$(find %s -type d -name '*%s*' | head -n 1); ", root, containerID)
+	sb.WriteString(`[ -n "$d" ] || { echo "cgroup not found" >&2; exit 2; }; `)
+	fmt.Fprintf(&sb, `while [ "$d" != "%s" ] && [ "$d" != "/" ]; do `, root)
+	sb.WriteString("for p in")
+	for _, name := range Files {
+		fmt.Fprintf(&sb, ` "$d"/%s`, name)
+	}
+	sb.WriteString(`; do [ -f "$p" ] || continue; printf '%s\t%s\t%s\n' "$d" "${p##*/}" "$(cat "$p")"; done; `)
+	sb.WriteString(`d=$(dirname "$d"); done`)
+	return sb.String()
+}
+
+// Parse decodes the output of the script made by MakeScript.
+func Parse(data []byte) (Hierarchy, error) {
+	var levels []Level
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		items := strings.SplitN(line, "\t", 3)
+		if len(items) != 3 {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		// the script emits the lines level by level
+		if len(levels) == 0 || levels[len(levels)-1].Path != items[0] {
+			levels = append(levels, Level{
+				Path:   items[0],
+				Values: make(map[string]string),
+			})
+		}
+		levels[len(levels)-1].Values[items[1]] = items[2]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(levels) < 2 {
+		return nil, fmt.Errorf("incomplete cgroup hierarchy: %d levels", len(levels))
+	}
+	// the script walks up from the container, we want to go down from the root
+	slices.Reverse(levels)
+	return levels, nil
+}
+
+func findContainerID(pod *v1.Pod, containerName string) (string, error) {
+	for _, cntSt := range pod.Status.ContainerStatuses {
+		if cntSt.Name != containerName {
+			continue
+		}
+		// e.g. containerd://<id>
+		_, containerID, ok := strings.Cut(cntSt.ContainerID, "://")
+		if !ok || containerID == "" {
+			return "", fmt.Errorf("container %s/%s/%s has no ID yet", pod.Namespace, pod.Name, containerName)
+		}
+		return containerID, nil
+	}
+	return "", fmt.Errorf("container %s not found in pod %s/%s", containerName, pod.Namespace, pod.Name)
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgrouptree
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+const (
+	testContainerID = "0123456789abcdef"
+	testPodSlice    = "kubepods.slice/kubepods-pod0a1b2c3d.slice"
+)
+
+func TestForContainer(t *testing.T) {
+	root := fakesys.Make(t, fakesys.Spec{
+		Cgroups: []fakesys.Cgroup{
+			{
+				Path: "kubepods.slice",
+				Files: map[string]string{
+					"memory.max":            "max",
+					"cpuset.mems.effective": "0-1",
+				},
+			},
+			{
+				Path: testPodSlice,
+				Files: map[string]string{
+					"memory.max":         "536870912",
+					"hugetlb.2MB.max":    "33554432",
+					"hugetlb.1GB.max":    "0",
+					"cpuset.mems":        "",
+					"cgroup.controllers": "cpuset memory hugetlb",
+				},
+			},
+			{
+				Path: testPodSlice + "/cri-containerd-" + testContainerID + ".scope",
+				Files: map[string]string{
+					"memory.max":            "536870912",
+					"hugetlb.2MB.max":       "33554432",
+					"hugetlb.2MB.rsvd.max":  "33554432",
+					"cpuset.mems":           "1",
+					"cpuset.mems.effective": "1",
+				},
+			},
+		},
+	})
+	cgroupRoot := filepath.Join(root, "sys", "fs", "cgroup")
+
+	// run the script locally on the fake tree, rooted where the real one would be
+	localRun := func(ctx context.Context, nodeName, script string) ([]byte, error) {
+		require.Equal(t, "worker", nodeName)
+		return exec.CommandContext(ctx, "/bin/sh", "-c", script).Output()
+	}
+	script := MakeScript(cgroupRoot, testContainerID)
+	out, err := localRun(context.Background(), "worker", script)
+	require.NoError(t, err)
+
+	hier, err := Parse(out)
+	require.NoError(t, err)
+	require.Len(t, hier, 3)
+	require.Equal(t, filepath.Join(cgroupRoot, "kubepods.slice"), hier[0].Path)
+
+	cnt := hier.Container()
+	require.Equal(t, map[string]string{
+		"memory.max":            "536870912",
+		"hugetlb.2MB.max":       "33554432",
+		"hugetlb.2MB.rsvd.max":  "33554432",
+		"cpuset.mems":           "1",
+		"cpuset.mems.effective": "1",
+	}, cnt.Values)
+
+	pod := hier.Pod()
+	require.Equal(t, filepath.Join(cgroupRoot, testPodSlice), pod.Path)
+	require.Equal(t, map[string]string{
+		"memory.max":      "536870912",
+		"hugetlb.2MB.max": "33554432",
+		"hugetlb.1GB.max": "0",
+		"cpuset.mems":     "",
+	}, pod.Values)
+
+	lvl, ok := hier.Find("kubepods.slice")
+	require.True(t, ok)
+	require.Equal(t, "0-1", lvl.Values["cpuset.mems.effective"])
+	_, ok = hier.Find("besteffort.slice")
+	require.False(t, ok)
+
+	_, err = localRun(context.Background(), "worker", MakeScript(cgroupRoot, "fedcba9876543210"))
+	require.Error(t, err, "missing container cgroup")
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte("/sys/fs/cgroup/kubepods.slice\tmemory.max\n"))
+	require.Error(t, err, "malformed line")
+	_, err = Parse([]byte("/sys/fs/cgroup/kubepods.slice\tmemory.max\tmax\n"))
+	require.Error(t, err, "too few levels")
+}
+
+func TestFindContainerID(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "pod",
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:        "cnt",
+					ContainerID: "containerd://" + testContainerID,
+				},
+				{
+					Name: "pending",
+				},
+			},
+		},
+	}
+	containerID, err := findContainerID(pod, "cnt")
+	require.NoError(t, err)
+	require.Equal(t, testContainerID, containerID)
+	_, err = findContainerID(pod, "pending")
+	require.Error(t, err)
+	_, err = findContainerID(pod, "missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgrouptree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/onsi/gomega/gcustom"
+	"github.com/onsi/gomega/types"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
+)
+
+// Unlimited is the value of a hugetlb limit set to "max".
+const Unlimited int64 = -1
+
+// HugeTLBLimit is the pair of hugetlb limits of a page size, which the driver keeps in sync.
+type HugeTLBLimit struct {
+	// Max is the usage limit (hugetlb.<size>.max), enforced when the pages are faulted
+	Max int64
+	// RsvdMax is the reservation limit (hugetlb.<size>.rsvd.max), enforced at mmap time
+	RsvdMax int64
+}
+
+func (hl HugeTLBLimit) String() string {
+	return fmt.Sprintf("max=%s rsvd.max=%s", formatLimit(hl.Max), formatLimit(hl.RsvdMax))
+}
+
+// HugeTLB returns the hugetlb limits of the level for the hugepages of `pageSize` bytes.
+// Both the files must be present: the hierarchies without the hugetlb controller enabled have none.
+func (lvl Level) HugeTLB(pageSize uint64) (HugeTLBLimit, error) {
+	prefix := "hugetlb." + unitconv.SizeInBytesToCGroupString(pageSize)
+	var hl HugeTLBLimit
+	var err error
+	hl.Max, err = lvl.parseLimit(prefix + ".max")
+	if err != nil {
+		return hl, err
+	}
+	hl.RsvdMax, err = lvl.parseLimit(prefix + ".rsvd.max")
+	return hl, err
+}
+
+func (lvl Level) parseLimit(fileName string) (int64, error) {
+	val, ok := lvl.Values[fileName]
+	if !ok {
+		return 0, fmt.Errorf("missing %s in %s", fileName, lvl.Path)
+	}
+	val = strings.TrimSpace(val)
+	if val == "max" {
+		return Unlimited, nil
+	}
+	limit, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s in %s: %w", fileName, lvl.Path, err)
+	}
+	return limit, nil
+}
+
+func formatLimit(limit int64) string {
+	if limit == Unlimited {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+// HaveHugeTLBLimit matches the levels whose usage and reservation hugetlb limits for the hugepages
+// of `pageSize` bytes are both `limit` bytes, or Unlimited.
+func HaveHugeTLBLimit(pageSize uint64, limit int64) types.GomegaMatcher {
+	expected := HugeTLBLimit{Max: limit, RsvdMax: limit}
+	return gcustom.MakeMatcher(func(actual Level) (bool, error) {
+		hl, err := actual.HugeTLB(pageSize)
+		if err != nil {
+			return false, err
+		}
+		return hl == expected, nil
+	}).WithTemplate("Cgroup {{.Actual.Path}} does not have hugetlb limits {{.Data}}").WithTemplateData(expectedLimit{pageSize, expected})
+}
+
+// HaveContainerHugeTLBLimit matches the running pods whose container `containerName` has both the hugetlb
+// limits for the hugepages of `pageSize` bytes set to `limit` bytes, reading its cgroup on the node with `run`.
+func HaveContainerHugeTLBLimit(ctx context.Context, run Runner, containerName string, pageSize uint64, limit int64) types.GomegaMatcher {
+	return havePodLevelHugeTLBLimit(ctx, run, containerName, Hierarchy.Container, pageSize, limit)
+}
+
+// HavePodHugeTLBLimit matches the running pods whose pod cgroup has both the hugetlb limits for the hugepages
+// of `pageSize` bytes set to `limit` bytes, reading it on the node with `run` through the first container.
+func HavePodHugeTLBLimit(ctx context.Context, run Runner, pageSize uint64, limit int64) types.GomegaMatcher {
+	return havePodLevelHugeTLBLimit(ctx, run, "", Hierarchy.Pod, pageSize, limit)
+}
+
+func havePodLevelHugeTLBLimit(ctx context.Context, run Runner, containerName string, pick func(Hierarchy) Level, pageSize uint64, limit int64) types.GomegaMatcher {
+	expected := HugeTLBLimit{Max: limit, RsvdMax: limit}
+	var got HugeTLBLimit
+	var path string
+	return gcustom.MakeMatcher(func(actual *v1.Pod) (bool, error) {
+		if actual == nil {
+			return false, errors.New("nil Pod")
+		}
+		cntName := containerName
+		if cntName == "" {
+			if len(actual.Spec.Containers) == 0 {
+				return false, fmt.Errorf("pod %s/%s has no containers", actual.Namespace, actual.Name)
+			}
+			cntName = actual.Spec.Containers[0].Name
+		}
+		hier, err := ForContainer(ctx, run, actual, cntName)
+		if err != nil {
+			return false, err
+		}
+		lvl := pick(hier)
+		path = lvl.Path
+		got, err = lvl.HugeTLB(pageSize)
+		if err != nil {
+			return false, err
+		}
+		return got == expected, nil
+	}).WithTemplate("Pod {{.Actual.Namespace}}/{{.Actual.Name}} UID {{.Actual.UID}} has hugetlb limits {{.Data}}").WithTemplateData(&mismatch{
+		expected: expectedLimit{pageSize, expected},
+		got:      &got,
+		path:     &path,
+	})
+}
+
+type expectedLimit struct {
+	PageSize uint64
+	Limit    HugeTLBLimit
+}
+
+func (el expectedLimit) String() string {
+	return fmt.Sprintf("%s %v", unitconv.SizeInBytesToCGroupString(el.PageSize), el.Limit)
+}
+
+// mismatch reports the values read by the last match, which are known only when the failure message is rendered.
+type mismatch struct {
+	expected expectedLimit
+	got      *HugeTLBLimit
+	path     *string
+}
+
+func (mm *mismatch) String() string {
+	return fmt.Sprintf("%v in %s, expected %v", *mm.got, *mm.path, mm.expected)
+}
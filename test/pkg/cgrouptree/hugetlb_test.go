@@ -0,0 +1,161 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cgrouptree
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ffromani/dra-driver-memory/test/pkg/fakesys"
+)
+
+const (
+	testPageSize2M = 2 << 20
+	testPageSize1G = 1 << 30
+)
+
+func TestHugeTLB(t *testing.T) {
+	lvl := Level{
+		Path: "/sys/fs/cgroup/kubepods.slice",
+		Values: map[string]string{
+			"hugetlb.2MB.max":      "33554432",
+			"hugetlb.2MB.rsvd.max": "33554432",
+			"hugetlb.1GB.max":      "max",
+			"hugetlb.1GB.rsvd.max": "max",
+			"hugetlb.64KB.max":     "0",
+		},
+	}
+	hl, err := lvl.HugeTLB(testPageSize2M)
+	require.NoError(t, err)
+	require.Equal(t, HugeTLBLimit{Max: 32 << 20, RsvdMax: 32 << 20}, hl)
+
+	hl, err = lvl.HugeTLB(testPageSize1G)
+	require.NoError(t, err)
+	require.Equal(t, HugeTLBLimit{Max: Unlimited, RsvdMax: Unlimited}, hl)
+
+	_, err = lvl.HugeTLB(64 << 10)
+	require.Error(t, err, "missing rsvd.max")
+
+	lvl.Values["hugetlb.2MB.max"] = "lots"
+	_, err = lvl.HugeTLB(testPageSize2M)
+	require.Error(t, err, "malformed limit")
+}
+
+func TestHaveHugeTLBLimit(t *testing.T) {
+	lvl := Level{
+		Path: "/sys/fs/cgroup/kubepods.slice",
+		Values: map[string]string{
+			"hugetlb.2MB.max":      "33554432",
+			"hugetlb.2MB.rsvd.max": "0",
+			"hugetlb.1GB.max":      "max",
+			"hugetlb.1GB.rsvd.max": "max",
+		},
+	}
+	ok, err := HaveHugeTLBLimit(testPageSize1G, Unlimited).Match(lvl)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the limits out of sync are the failure mode the matcher is meant to catch
+	matcher := HaveHugeTLBLimit(testPageSize2M, 32<<20)
+	ok, err = matcher.Match(lvl)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Contains(t, matcher.FailureMessage(lvl), "2MB max=33554432 rsvd.max=33554432")
+}
+
+func TestHaveContainerHugeTLBLimit(t *testing.T) {
+	root := fakesys.Make(t, fakesys.Spec{
+		Cgroups: []fakesys.Cgroup{
+			{
+				Path: "kubepods.slice",
+				Files: map[string]string{
+					"hugetlb.2MB.max":      "max",
+					"hugetlb.2MB.rsvd.max": "max",
+				},
+			},
+			{
+				Path: testPodSlice,
+				Files: map[string]string{
+					"hugetlb.2MB.max":      "67108864",
+					"hugetlb.2MB.rsvd.max": "67108864",
+				},
+			},
+			{
+				Path: testPodSlice + "/cri-containerd-" + testContainerID + ".scope",
+				Files: map[string]string{
+					"hugetlb.2MB.max":      "33554432",
+					"hugetlb.2MB.rsvd.max": "33554432",
+				},
+			},
+		},
+	})
+	cgroupRoot := filepath.Join(root, "sys", "fs", "cgroup")
+	localRun := func(ctx context.Context, nodeName, script string) ([]byte, error) {
+		// the script is made for the real root, point it to the fake tree
+		script = MakeScript(cgroupRoot, testContainerID)
+		return exec.CommandContext(ctx, "/bin/sh", "-c", script).Output()
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "pod",
+		},
+		Spec: v1.PodSpec{
+			NodeName: "worker",
+			Containers: []v1.Container{
+				{Name: "cnt"},
+			},
+		},
+		Status: v1.PodStatus{
+			ContainerStatuses: []v1.ContainerStatus{
+				{
+					Name:        "cnt",
+					ContainerID: "containerd://" + testContainerID,
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+
+	ok, err := HaveContainerHugeTLBLimit(ctx, localRun, "cnt", testPageSize2M, 32<<20).Match(pod)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = HavePodHugeTLBLimit(ctx, localRun, testPageSize2M, 64<<20).Match(pod)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	matcher := HavePodHugeTLBLimit(ctx, localRun, testPageSize2M, 32<<20)
+	ok, err = matcher.Match(pod)
+	require.NoError(t, err)
+	require.False(t, ok)
+	msg := matcher.FailureMessage(pod)
+	require.Contains(t, msg, "max=67108864 rsvd.max=67108864 in "+filepath.Join(cgroupRoot, testPodSlice))
+	require.Contains(t, msg, "expected 2MB max=33554432 rsvd.max=33554432")
+
+	_, err = HaveContainerHugeTLBLimit(ctx, localRun, "missing", testPageSize2M, 32<<20).Match(pod)
+	require.Error(t, err)
+	_, err = HavePodHugeTLBLimit(ctx, localRun, testPageSize1G, 0).Match(pod)
+	require.Error(t, err, "missing hugetlb files")
+}
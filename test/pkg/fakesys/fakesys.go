@@ -0,0 +1,288 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fakesys builds fake sysfs and procfs trees, to be used as the sysRoot or procRoot
+// of the code under test. The trees contain only the files the driver reads, with the
+// same layout and formatting of the kernel.
+package fakesys
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	DefaultBlockSize      int64 = 128 * (1 << 20)
+	DefaultHugepageSizeKB       = 2048
+	localDistance               = 10
+	remoteDistance              = 21
+)
+
+type Spec struct {
+	// BlockSize is the size of the memory blocks. Zero means DefaultBlockSize.
+	BlockSize int64
+	// HugepageSizeKB is the default hugepage size reported in meminfo. Zero means DefaultHugepageSizeKB.
+	HugepageSizeKB int
+	Zones          []Zone
+	// Mounts are the content of the mountinfo of the process, and of the thread, in order.
+	Mounts []Mount
+	// Cgroups are created in the cgroup2 hierarchy, under sys/fs/cgroup.
+	Cgroups []Cgroup
+	// Processes are created in proc, with their cgroup membership.
+	Processes []Process
+}
+
+type Zone struct {
+	ID int
+	// MemoryBlocks is the number of the memory blocks of the zone, all online.
+	MemoryBlocks int
+	// UsableBytes is the MemTotal of the zone. Zero means all the memory blocks.
+	UsableBytes int64
+	FreeBytes   int64
+	// Distances are the distances from all the zones. Empty means the default local and remote distances.
+	Distances []int
+	// CPUs are the IDs of the CPUs of the zone, all on the socket `Socket`.
+	CPUs      []int
+	Socket    int
+	Hugepages []Pool
+}
+
+type Pool struct {
+	SizeKB  int
+	Total   int64
+	Free    int64
+	Surplus int64
+}
+
+// Mount is a line of mountinfo, see proc_pid_mountinfo(5).
+type Mount struct {
+	ID         int
+	ParentID   int
+	Root       string
+	MountPoint string
+	Options    string
+	FSType     string
+	Source     string
+	SuperOpts  string
+}
+
+func (mnt Mount) String() string {
+	return fmt.Sprintf("%d %d 0:%d %s %s %s shared:%d - %s %s %s", mnt.ID, mnt.ParentID, mnt.ID, mnt.Root, mnt.MountPoint, mnt.Options, mnt.ID, mnt.FSType, mnt.Source, mnt.SuperOpts)
+}
+
+type Cgroup struct {
+	// Path is relative to the cgroup2 mountpoint
+	Path string
+	// Files are the interface files of the cgroup, like "cpuset.mems.effective", by name.
+	Files map[string]string
+}
+
+type Process struct {
+	// PID is the process ID, or "self"
+	PID string
+	// CgroupPath is the cgroup2 path of the process, relative to the cgroup2 mountpoint
+	CgroupPath string
+}
+
+// UniformZones returns `zoneCount` zones with `blocks` memory blocks each and the given hugepages pools,
+// with the default distances. One MiB of each zone is not usable, and half of the usable memory is free.
+func UniformZones(zoneCount, blocks int, blockSize int64, pools ...Pool) []Zone {
+	zones := make([]Zone, 0, zoneCount)
+	for zoneID := range zoneCount {
+		usable := int64(blocks)*blockSize - (1 << 20)
+		zones = append(zones, Zone{
+			ID:           zoneID,
+			MemoryBlocks: blocks,
+			UsableBytes:  usable,
+			FreeBytes:    int64(blocks) * blockSize / 2,
+			Hugepages:    pools,
+		})
+	}
+	return zones
+}
+
+// NodeMounts returns the mounts of a typical node, optionally with the cgroup2 hierarchy
+// mounted with the additional mount options `cgroupOpts`, like "memory_hugetlb_accounting".
+func NodeMounts(withCGroup2 bool, cgroupOpts ...string) []Mount {
+	mounts := []Mount{
+		{ID: 74, ParentID: 2, Root: "/", MountPoint: "/", Options: "rw,relatime", FSType: "ext4", Source: "/dev/vda1", SuperOpts: "rw"},
+		{ID: 75, ParentID: 74, Root: "/", MountPoint: "/proc", Options: "rw,nosuid,nodev,noexec,relatime", FSType: "proc", Source: "proc", SuperOpts: "rw"},
+		{ID: 76, ParentID: 74, Root: "/", MountPoint: "/sys", Options: "rw,nosuid,nodev,noexec,relatime", FSType: "sysfs", Source: "sysfs", SuperOpts: "rw"},
+		{ID: 78, ParentID: 74, Root: "/", MountPoint: "/dev/hugepages", Options: "rw,relatime", FSType: "hugetlbfs", Source: "hugetlbfs", SuperOpts: "rw,pagesize=2M"},
+	}
+	if !withCGroup2 {
+		return mounts
+	}
+	return append(mounts, Mount{
+		ID:         77,
+		ParentID:   76,
+		Root:       "/",
+		MountPoint: "/sys/fs/cgroup",
+		Options:    strings.Join(append([]string{"rw,nosuid,nodev,noexec,relatime"}, cgroupOpts...), ","),
+		FSType:     "cgroup2",
+		Source:     "cgroup2",
+		SuperOpts:  "rw,nsdelegate,memory_recursiveprot",
+	})
+}
+
+// Make builds the tree of `spec` in a new temporary directory, and returns it.
+func Make(tb testing.TB, spec Spec) string {
+	tb.Helper()
+	root := tb.TempDir()
+	err := spec.Build(root)
+	if err != nil {
+		tb.Fatalf("cannot build the fake tree: %v", err)
+	}
+	return root
+}
+
+// Build builds the tree of `spec` in the existing directory `root`.
+func (spec Spec) Build(root string) error {
+	bld := builder{root: root}
+	blockSize := spec.BlockSize
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+	hpSizeKB := spec.HugepageSizeKB
+	if hpSizeKB == 0 {
+		hpSizeKB = DefaultHugepageSizeKB
+	}
+
+	var totalBytes int64
+	totalPages := make(map[int]int64)
+	for _, zone := range spec.Zones {
+		totalBytes += int64(zone.MemoryBlocks) * blockSize
+		for _, pool := range zone.Hugepages {
+			totalPages[pool.SizeKB] += pool.Total
+		}
+	}
+	bld.writeFile(strconv.FormatInt(blockSize, 16)+"\n", "sys", "devices", "system", "memory", "block_size_bytes")
+	bld.writeFile(fmt.Sprintf("MemTotal:       %d kB\nHugepagesize:       %d kB\nHugetlb:        0 kB\n", totalBytes/1024, hpSizeKB), "proc", "meminfo")
+	for sizeKB, pages := range totalPages {
+		bld.writeFile(strconv.FormatInt(pages, 10)+"\n", "sys", "kernel", "mm", "hugepages", "hugepages-"+strconv.Itoa(sizeKB)+"kB", "nr_hugepages")
+	}
+
+	blockID := 0
+	for _, zone := range spec.Zones {
+		blockID = bld.buildZone(zone, spec.Zones, blockSize, blockID)
+	}
+
+	if len(spec.Mounts) > 0 {
+		lines := make([]string, 0, len(spec.Mounts))
+		for _, mnt := range spec.Mounts {
+			lines = append(lines, mnt.String())
+		}
+		content := strings.Join(lines, "\n") + "\n"
+		bld.writeFile(content, "proc", "self", "mountinfo")
+		bld.writeFile(content, "proc", "thread-self", "mountinfo")
+	}
+	for _, cg := range spec.Cgroups {
+		bld.mkdir("sys", "fs", "cgroup", cg.Path)
+		for name, content := range cg.Files {
+			bld.writeFile(content, "sys", "fs", "cgroup", cg.Path, name)
+		}
+	}
+	for _, proc := range spec.Processes {
+		bld.writeFile("0::"+proc.CgroupPath+"\n", "proc", proc.PID, "cgroup")
+	}
+	return bld.err
+}
+
+func (bld *builder) buildZone(zone Zone, zones []Zone, blockSize int64, blockID int) int {
+	zoneName := "node" + strconv.Itoa(zone.ID)
+	nodePath := []string{"sys", "devices", "system", "node", zoneName}
+	bld.mkdir(nodePath...)
+
+	distances := make([]string, 0, len(zones))
+	if len(zone.Distances) > 0 {
+		for _, dist := range zone.Distances {
+			distances = append(distances, strconv.Itoa(dist))
+		}
+	} else {
+		for _, other := range zones {
+			if other.ID == zone.ID {
+				distances = append(distances, strconv.Itoa(localDistance))
+			} else {
+				distances = append(distances, strconv.Itoa(remoteDistance))
+			}
+		}
+	}
+	bld.writeFile(strings.Join(distances, " ")+"\n", append(nodePath, "distance")...)
+
+	usable := zone.UsableBytes
+	if usable == 0 {
+		usable = int64(zone.MemoryBlocks) * blockSize
+	}
+	meminfo := fmt.Sprintf("Node %d MemTotal:       %d kB\nNode %d MemFree:        %d kB\n", zone.ID, usable/1024, zone.ID, zone.FreeBytes/1024)
+	bld.writeFile(meminfo, append(nodePath, "meminfo")...)
+
+	for range zone.MemoryBlocks {
+		bld.writeFile("online\n", append(nodePath, "memory"+strconv.Itoa(blockID), "state")...)
+		blockID++
+	}
+
+	cpus := make([]string, 0, len(zone.CPUs))
+	for _, cpuID := range zone.CPUs {
+		cpuName := "cpu" + strconv.Itoa(cpuID)
+		bld.writeFile(strconv.Itoa(zone.Socket)+"\n", "sys", "devices", "system", "cpu", cpuName, "topology", "physical_package_id")
+		bld.symlink(filepath.Join("..", "..", "cpu", cpuName), append(nodePath, cpuName)...)
+		cpus = append(cpus, strconv.Itoa(cpuID))
+	}
+	if len(cpus) > 0 {
+		bld.writeFile(strings.Join(cpus, ",")+"\n", append(nodePath, "cpulist")...)
+	}
+
+	for _, pool := range zone.Hugepages {
+		hpPath := append(nodePath, "hugepages", "hugepages-"+strconv.Itoa(pool.SizeKB)+"kB")
+		bld.writeFile(strconv.FormatInt(pool.Total, 10)+"\n", append(hpPath, "nr_hugepages")...)
+		bld.writeFile(strconv.FormatInt(pool.Free, 10)+"\n", append(hpPath, "free_hugepages")...)
+		bld.writeFile(strconv.FormatInt(pool.Surplus, 10)+"\n", append(hpPath, "surplus_hugepages")...)
+	}
+	return blockID
+}
+
+// builder stops at the first error, so the callers check only once
+type builder struct {
+	root string
+	err  error
+}
+
+func (bld *builder) mkdir(elems ...string) {
+	if bld.err != nil {
+		return
+	}
+	bld.err = os.MkdirAll(filepath.Join(append([]string{bld.root}, elems...)...), 0755)
+}
+
+func (bld *builder) writeFile(content string, elems ...string) {
+	bld.mkdir(elems[:len(elems)-1]...)
+	if bld.err != nil {
+		return
+	}
+	bld.err = os.WriteFile(filepath.Join(append([]string{bld.root}, elems...)...), []byte(content), 0644)
+}
+
+func (bld *builder) symlink(target string, elems ...string) {
+	bld.mkdir(elems[:len(elems)-1]...)
+	if bld.err != nil {
+		return
+	}
+	bld.err = os.Symlink(target, filepath.Join(append([]string{bld.root}, elems...)...))
+}
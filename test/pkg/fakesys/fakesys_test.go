@@ -0,0 +1,89 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakesys
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMake(t *testing.T) {
+	root := Make(t, Spec{
+		Zones: []Zone{
+			{
+				ID:           0,
+				MemoryBlocks: 2,
+				FreeBytes:    1 << 20,
+				CPUs:         []int{0, 1},
+				Socket:       0,
+				Hugepages:    []Pool{{SizeKB: 2048, Total: 8, Free: 4}},
+			},
+			{
+				ID:           1,
+				MemoryBlocks: 2,
+				Hugepages:    []Pool{{SizeKB: 2048, Total: 8, Free: 8}},
+			},
+		},
+		Mounts: NodeMounts(true),
+		Cgroups: []Cgroup{
+			{Path: "kubepods.slice", Files: map[string]string{"cpuset.mems.effective": "0-1\n"}},
+		},
+		Processes: []Process{{PID: "self", CgroupPath: "/kubepods.slice"}},
+	})
+
+	requireFile := func(expected string, elems ...string) {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(append([]string{root}, elems...)...))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+	}
+	requireFile("8000000\n", "sys", "devices", "system", "memory", "block_size_bytes")
+	requireFile("MemTotal:       524288 kB\nHugepagesize:       2048 kB\nHugetlb:        0 kB\n", "proc", "meminfo")
+	requireFile("16\n", "sys", "kernel", "mm", "hugepages", "hugepages-2048kB", "nr_hugepages")
+	requireFile("10 21\n", "sys", "devices", "system", "node", "node0", "distance")
+	requireFile("21 10\n", "sys", "devices", "system", "node", "node1", "distance")
+	requireFile("Node 0 MemTotal:       262144 kB\nNode 0 MemFree:        1024 kB\n", "sys", "devices", "system", "node", "node0", "meminfo")
+	requireFile("online\n", "sys", "devices", "system", "node", "node1", "memory3", "state")
+	requireFile("0,1\n", "sys", "devices", "system", "node", "node0", "cpulist")
+	requireFile("0\n", "sys", "devices", "system", "node", "node0", "cpu1", "topology", "physical_package_id")
+	requireFile("4\n", "sys", "devices", "system", "node", "node0", "hugepages", "hugepages-2048kB", "free_hugepages")
+	requireFile("0-1\n", "sys", "fs", "cgroup", "kubepods.slice", "cpuset.mems.effective")
+	requireFile("0::/kubepods.slice\n", "proc", "self", "cgroup")
+
+	data, err := os.ReadFile(filepath.Join(root, "proc", "thread-self", "mountinfo"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "77 76 0:77 / /sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:77 - cgroup2 cgroup2 rw,nsdelegate,memory_recursiveprot\n")
+}
+
+func TestNodeMounts(t *testing.T) {
+	hasCGroup2 := func(mounts []Mount) bool {
+		for _, mnt := range mounts {
+			if mnt.FSType == "cgroup2" {
+				return true
+			}
+		}
+		return false
+	}
+	require.False(t, hasCGroup2(NodeMounts(false)))
+	mounts := NodeMounts(true, "memory_hugetlb_accounting")
+	require.True(t, hasCGroup2(mounts))
+	require.True(t, strings.HasSuffix(mounts[len(mounts)-1].Options, ",memory_hugetlb_accounting"))
+}
@@ -27,11 +27,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	resourcev1 "k8s.io/api/resource/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
 	"github.com/ffromani/dra-driver-memory/test/pkg/client"
 )
 
@@ -110,7 +110,7 @@ func (fxt *Fixture) NodeHasMemoryResource(ctx context.Context, nodeName, size st
 		return "", "", false
 	}
 	lh.Info("checking resource slices", "count", len(resourceSliceList.Items))
-	desiredQty := *resource.NewQuantity(amount, resource.BinarySI)
+	desiredQty := *unitconv.SizeInBytesToQuantity(amount)
 	for idx := range resourceSliceList.Items {
 		resourceSlice := &resourceSliceList.Items[idx]
 		lh.Info("checking resource slices", "name", resourceSlice.Name)
@@ -134,6 +134,28 @@ func (fxt *Fixture) NodeHasMemoryResource(ctx context.Context, nodeName, size st
 	return "", "", false
 }
 
+// NodeMemoryDevices returns the memory devices of pages of `size` published by the driver for the node `nodeName`.
+func (fxt *Fixture) NodeMemoryDevices(ctx context.Context, nodeName, size string) ([]resourcev1.Device, error) {
+	lh := fxt.Log.WithValues("nodeName", nodeName)
+	resourceSliceList, err := fxt.K8SClientset.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list resourceslices on %q: %w", nodeName, err)
+	}
+	var devs []resourcev1.Device
+	for idx := range resourceSliceList.Items {
+		resourceSlice := &resourceSliceList.Items[idx]
+		for _, rdev := range resourceSlice.Spec.Devices {
+			if !matchesByAttributes(lh.WithValues("deviceName", rdev.Name), rdev.Attributes, size) {
+				continue
+			}
+			devs = append(devs, rdev)
+		}
+	}
+	return devs, nil
+}
+
 func findMemoryDeviceInResourceSlice(lh logr.Logger, resourceSlice *resourcev1.ResourceSlice, size string) *resourcev1.Device {
 	for idx := range resourceSlice.Spec.Devices {
 		rdev := &resourceSlice.Spec.Devices[idx]
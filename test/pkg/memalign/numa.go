@@ -19,6 +19,7 @@ package memalign
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -82,11 +83,79 @@ func NUMANodesByPID(lh logr.Logger, pid int, procRoot string) (cpuset.CPUSet, er
 	return cpuset.New(numaNodes...), nil
 }
 
+// NUMANodesByAddress returns the set of NUMA Nodes from which the memory region
+// starting at `addr` of the process identified by <pid> actually allocated memory.
+// Unlike NUMANodesByPID, file-backed regions are not skipped.
+func NUMANodesByAddress(pid int, procRoot string, addr uintptr) (cpuset.CPUSet, error) {
+	items, err := findRegion(pid, procRoot, addr)
+	if err != nil {
+		return cpuset.CPUSet{}, err
+	}
+	var numaNodes []int
+	for _, attr := range items[2:] {
+		key, _, ok := strings.Cut(attr, "=")
+		if !ok || !strings.HasPrefix(key, "N") {
+			continue
+		}
+		numaNode, err := strconv.Atoi(key[1:])
+		if err != nil {
+			continue // not a node usage, e.g. "Nonexistent"
+		}
+		numaNodes = append(numaNodes, numaNode)
+	}
+	return cpuset.New(numaNodes...), nil
+}
+
+// PageSizeByAddress returns the size in bytes of the pages backing the memory region
+// starting at `addr` of the process identified by <pid>, as reported by the kernel.
+// The region must be already faulted in, otherwise the kernel reports the base page size.
+func PageSizeByAddress(pid int, procRoot string, addr uintptr) (uint64, error) {
+	items, err := findRegion(pid, procRoot, addr)
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range items[2:] {
+		val, ok := strings.CutPrefix(attr, "kernelpagesize_kB=")
+		if !ok {
+			continue
+		}
+		sizeKB, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing attr %q: %w", attr, err)
+		}
+		return sizeKB * 1024, nil
+	}
+	return 0, fmt.Errorf("missing page size for region %s", items[0])
+}
+
+// findRegion returns the fields of the numa_maps line of the region starting at `addr`.
+// There are always at least two fields: the address and the policy.
+func findRegion(pid int, procRoot string, addr uintptr) ([]string, error) {
+	fullPath := filepath.Join(procRoot, makeProcPath(pid))
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	addrStr := strconv.FormatUint(uint64(addr), 16)
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		items := strings.Fields(scanner.Text())
+		if len(items) >= 2 && items[0] == addrStr {
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("region %s not found", addrStr)
+}
+
 func makeProcPath(pid int) string {
+	return makeProcFilePath(pid, "numa_maps")
+}
+
+func makeProcFilePath(pid int, fileName string) string {
 	// we intentionally use self over thread-self
 	pidStr := "self"
 	if pid != PIDSelf {
 		pidStr = strconv.Itoa(pid)
 	}
-	return filepath.Join("proc", pidStr, "numa_maps")
+	return filepath.Join("proc", pidStr, fileName)
 }
@@ -70,6 +70,110 @@ func TestNUMANodesByPID(t *testing.T) {
 	}
 }
 
+func TestNUMANodesByAddress(t *testing.T) {
+	type testcase struct {
+		name        string
+		addr        uintptr
+		expected    cpuset.CPUSet
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "bind",
+			addr:     0x7f0b40000000,
+			expected: cpuset.New(1),
+		},
+		{
+			name:     "interleave",
+			addr:     0x7f0bc0000000,
+			expected: cpuset.New(0, 1),
+		},
+		{
+			name:     "preferred hugepages",
+			addr:     0x7f0bd0000000,
+			expected: cpuset.New(0),
+		},
+		{
+			name:     "region not faulted in",
+			addr:     0x7f0be0000000,
+			expected: cpuset.New(),
+		},
+		{
+			name:        "missing region",
+			addr:        0x7f0bf0000000,
+			expectedErr: true,
+		},
+	}
+
+	tmpDir := t.TempDir()
+	err := setupNUMAMaps(tmpDir, PIDSelf, "numa_maps_mempolicy.01.txt")
+	require.NoError(t, err)
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := NUMANodesByAddress(PIDSelf, tmpDir, tcase.addr)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tcase.expected.Equals(got), "expected NUMA nodes: %q got: %q", tcase.expected.String(), got.String())
+		})
+	}
+}
+
+func TestPageSizeByAddress(t *testing.T) {
+	type testcase struct {
+		name        string
+		addr        uintptr
+		expected    uint64
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "1Gi hugepages",
+			addr:     0x7f0b40000000,
+			expected: 1 << 30,
+		},
+		{
+			name:     "2Mi hugepages",
+			addr:     0x7f0bc0000000,
+			expected: 2 * (1 << 20),
+		},
+		{
+			name:     "regular pages",
+			addr:     0xc000000000,
+			expected: 4 * (1 << 10),
+		},
+		{
+			name:        "region not faulted in",
+			addr:        0x7f0bd0000000,
+			expectedErr: true,
+		},
+		{
+			name:        "missing region",
+			addr:        0x7f0be0000000,
+			expectedErr: true,
+		},
+	}
+
+	tmpDir := t.TempDir()
+	err := setupNUMAMaps(tmpDir, PIDSelf, "numa_maps_hugepages.01.txt")
+	require.NoError(t, err)
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := PageSizeByAddress(PIDSelf, tmpDir, tcase.addr)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
 // setupNUMAMaps creates the proc expected layout in the root
 // directory `tmpDir`. So, if you use tmpDir="temp-foo" and
 // call it with pid 123 it will create `temp-foo/proc/123/numa_maps`.
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memalign
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/cpuset"
+)
+
+// Region is a memory region (VMA) of a process, with its NUMA placement.
+type Region struct {
+	Start uintptr
+	// End is the first address past the region. Equals to Start if the region
+	// vanished between the reads of maps and numa_maps.
+	End uintptr
+	// Policy is the NUMA memory policy, like "default" or "bind:1"
+	Policy string
+	// File is the file backing the region, empty for anonymous memory. Special characters are escaped, like in numa_maps.
+	File string
+	// HugeTLB is true if the region is backed by hugetlbfs hugepages
+	HugeTLB bool
+	// PageSize is the size in bytes of the pages backing the region, zero if not faulted in
+	PageSize uint64
+	// PagesByNode is the number of pages faulted in on each NUMA node
+	PagesByNode map[int]uint64
+}
+
+// Size returns the size in bytes of the region, faulted in or not.
+func (rg Region) Size() uint64 {
+	return uint64(rg.End - rg.Start)
+}
+
+// Contains returns true if the address belongs to the region.
+func (rg Region) Contains(addr uintptr) bool {
+	return addr >= rg.Start && addr < rg.End
+}
+
+// NUMANodes returns the set of NUMA Nodes on which the region has pages faulted in.
+func (rg Region) NUMANodes() cpuset.CPUSet {
+	nodes := make([]int, 0, len(rg.PagesByNode))
+	for node := range rg.PagesByNode {
+		nodes = append(nodes, node)
+	}
+	return cpuset.New(nodes...)
+}
+
+// Regions returns the memory regions of the process identified by <pid>, sorted by address,
+// with their NUMA placement. Unlike NUMANodesByPID, nothing is aggregated nor skipped,
+// so callers can tell the placement of a region apart from the rest of the process.
+func Regions(pid int, procRoot string) ([]Region, error) {
+	ends, err := readRegionEnds(pid, procRoot)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(procRoot, makeProcPath(pid)))
+	if err != nil {
+		return nil, err
+	}
+	var regions []Region
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		items := strings.Fields(scanner.Text())
+		// colums:
+		// <address> <policy> [properties...] [node_usage...]
+		if len(items) < 2 {
+			continue
+		}
+		start, err := strconv.ParseUint(items[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing address %q: %w", items[0], err)
+		}
+		rg := Region{
+			Start:       uintptr(start),
+			End:         uintptr(start),
+			Policy:      items[1],
+			PagesByNode: make(map[int]uint64),
+		}
+		if end, ok := ends[rg.Start]; ok {
+			rg.End = end
+		}
+		err = parseRegionAttrs(&rg, items[2:])
+		if err != nil {
+			return nil, fmt.Errorf("parsing region %s: %w", items[0], err)
+		}
+		regions = append(regions, rg)
+	}
+	return regions, nil
+}
+
+// RegionByAddress returns the region of the process identified by <pid> containing `addr`.
+func RegionByAddress(pid int, procRoot string, addr uintptr) (Region, error) {
+	regions, err := Regions(pid, procRoot)
+	if err != nil {
+		return Region{}, err
+	}
+	for _, rg := range regions {
+		if rg.Start == addr || rg.Contains(addr) {
+			return rg, nil
+		}
+	}
+	return Region{}, fmt.Errorf("region of address %x not found", addr)
+}
+
+func parseRegionAttrs(rg *Region, attrs []string) error {
+	for _, attr := range attrs {
+		key, val, ok := strings.Cut(attr, "=")
+		if !ok {
+			if attr == "huge" {
+				rg.HugeTLB = true
+			}
+			continue
+		}
+		switch {
+		case key == "file":
+			rg.File = val
+		case key == "kernelpagesize_kB":
+			sizeKB, err := strconv.ParseUint(val, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing attr %q: %w", attr, err)
+			}
+			rg.PageSize = sizeKB * 1024
+		case strings.HasPrefix(key, "N"):
+			node, err := strconv.Atoi(key[1:])
+			if err != nil {
+				continue // not a node usage, e.g. "Nonexistent"
+			}
+			pages, err := strconv.ParseUint(val, 10, 64)
+			if err != nil {
+				return fmt.Errorf("parsing attr %q: %w", attr, err)
+			}
+			rg.PagesByNode[node] = pages
+		}
+	}
+	return nil
+}
+
+// readRegionEnds returns the end addresses of the regions in maps, by start address.
+func readRegionEnds(pid int, procRoot string) (map[uintptr]uintptr, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, makeProcFilePath(pid, "maps")))
+	if err != nil {
+		return nil, err
+	}
+	ends := make(map[uintptr]uintptr)
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		// <start>-<end> <perms> <offset> <dev> <inode> [path]
+		addrs, _, _ := strings.Cut(scanner.Text(), " ")
+		startStr, endStr, ok := strings.Cut(addrs, "-")
+		if !ok {
+			continue
+		}
+		start, err := strconv.ParseUint(startStr, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing address %q: %w", startStr, err)
+		}
+		end, err := strconv.ParseUint(endStr, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing address %q: %w", endStr, err)
+		}
+		ends[uintptr(start)] = uintptr(end)
+	}
+	return ends, nil
+}
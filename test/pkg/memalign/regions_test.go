@@ -0,0 +1,102 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memalign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/utils/cpuset"
+)
+
+func TestRegions(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, setupNUMAMaps(tmpDir, PIDSelf, "numa_maps_mempolicy.01.txt"))
+	mapsPath := filepath.Join(tmpDir, makeProcFilePath(PIDSelf, "maps"))
+	data, err := os.ReadFile(filepath.Join("testdata", "maps_mempolicy.01.txt"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(mapsPath, data, 0444))
+
+	regions, err := Regions(PIDSelf, tmpDir)
+	require.NoError(t, err)
+
+	expected := []Region{
+		{
+			Start:       0x400000,
+			End:         0x4ae000,
+			Policy:      "default",
+			File:        "/work/dramemtester",
+			PageSize:    4096,
+			PagesByNode: map[int]uint64{0: 174},
+		},
+		{
+			Start:       0xc000000000,
+			End:         0xc000400000,
+			Policy:      "default",
+			PageSize:    4096,
+			PagesByNode: map[int]uint64{0: 115, 1: 34},
+		},
+		{
+			Start:       0x7f0b40000000,
+			End:         0x7f0b40800000,
+			Policy:      "bind:1",
+			PageSize:    4096,
+			PagesByNode: map[int]uint64{1: 2048},
+		},
+		{
+			Start:       0x7f0bc0000000,
+			End:         0x7f0bc0800000,
+			Policy:      "interleave:0-1",
+			PageSize:    4096,
+			PagesByNode: map[int]uint64{0: 1024, 1: 1024},
+		},
+		{
+			Start:       0x7f0bd0000000,
+			End:         0x7f0bd1000000,
+			Policy:      "prefer:0",
+			File:        `/anon_hugepage\040(deleted)`,
+			HugeTLB:     true,
+			PageSize:    2 * (1 << 20),
+			PagesByNode: map[int]uint64{0: 4},
+		},
+		{
+			Start:       0x7f0be0000000,
+			End:         0x7f0be0200000,
+			Policy:      "default",
+			PagesByNode: map[int]uint64{},
+		},
+	}
+	if diff := cmp.Diff(expected, regions); diff != "" {
+		t.Fatalf("unexpected regions: %s", diff)
+	}
+
+	// only the bound region is pinned, the rest of the process is unaffected
+	rg, err := RegionByAddress(PIDSelf, tmpDir, 0x7f0b40001000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8*(1<<20)), rg.Size())
+	require.True(t, rg.NUMANodes().Equals(cpuset.New(1)))
+	rg, err = RegionByAddress(PIDSelf, tmpDir, 0xc000000000)
+	require.NoError(t, err)
+	require.True(t, rg.NUMANodes().Equals(cpuset.New(0, 1)))
+
+	_, err = RegionByAddress(PIDSelf, tmpDir, 0x7f0bf0000000)
+	require.Error(t, err)
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memalign
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// AnonHugePagesByAddress returns the amount in bytes of the memory region starting at `addr`
+// of the process identified by <pid> backed by transparent hugepages, as reported by the kernel.
+func AnonHugePagesByAddress(pid int, procRoot string, addr uintptr) (uint64, error) {
+	return smapsSizeByAddress(pid, procRoot, addr, "AnonHugePages")
+}
+
+// LockedByAddress returns the amount in bytes of the memory region starting at `addr`
+// of the process identified by <pid> locked in memory, as reported by the kernel.
+// The hugetlb regions are never reported as locked, because they can't be swapped anyway.
+func LockedByAddress(pid int, procRoot string, addr uintptr) (uint64, error) {
+	return smapsSizeByAddress(pid, procRoot, addr, "Locked")
+}
+
+// smapsSizeByAddress returns the size `key` of the memory region starting at `addr` in bytes.
+func smapsSizeByAddress(pid int, procRoot string, addr uintptr, key string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, makeProcFilePath(pid, "smaps")))
+	if err != nil {
+		return 0, err
+	}
+	found := false
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// each region starts with the header line, like in maps:
+		// <start>-<end> <perms> <offset> <dev> <inode> [path]
+		// followed by the "<key>: <value> [unit]" lines
+		lineKey, val, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(lineKey, " ") {
+			if found {
+				break // next region
+			}
+			// the addresses are zero-padded, unlike in numa_maps
+			start, _, _ := strings.Cut(line, "-")
+			startAddr, err := strconv.ParseUint(start, 16, 64)
+			found = (err == nil && startAddr == uint64(addr))
+			continue
+		}
+		if !found || lineKey != key {
+			continue
+		}
+		sizeKB, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(val), " kB"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing line %q: %w", line, err)
+		}
+		return sizeKB * 1024, nil
+	}
+	if !found {
+		return 0, fmt.Errorf("region %x not found", addr)
+	}
+	return 0, fmt.Errorf("missing %s for region %x", key, addr)
+}
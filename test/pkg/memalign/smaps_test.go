@@ -0,0 +1,127 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memalign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonHugePagesByAddress(t *testing.T) {
+	type testcase struct {
+		name        string
+		addr        uintptr
+		expected    uint64
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "THP backed",
+			addr:     0x7f0b40000000,
+			expected: 32 * (1 << 20),
+		},
+		{
+			name:     "not THP backed",
+			addr:     0x7f0b50000000,
+			expected: 0,
+		},
+		{
+			name:     "file backed",
+			addr:     0x400000,
+			expected: 0,
+		},
+		{
+			name:        "missing region",
+			addr:        0x7f0be0000000,
+			expectedErr: true,
+		},
+	}
+
+	tmpDir := t.TempDir()
+	fullPath := filepath.Join(tmpDir, makeProcFilePath(PIDSelf, "smaps"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	data, err := os.ReadFile(filepath.Join("testdata", "smaps_thp.01.txt"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fullPath, data, 0444))
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := AnonHugePagesByAddress(PIDSelf, tmpDir, tcase.addr)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
+
+func TestLockedByAddress(t *testing.T) {
+	type testcase struct {
+		name        string
+		addr        uintptr
+		expected    uint64
+		expectedErr bool
+	}
+
+	testcases := []testcase{
+		{
+			name:     "locked",
+			addr:     0x7f0b50000000,
+			expected: 32 * (1 << 20),
+		},
+		{
+			name:     "not locked",
+			addr:     0x7f0b40000000,
+			expected: 0,
+		},
+		{
+			name:     "hugetlb",
+			addr:     0x7f0b60000000,
+			expected: 0,
+		},
+		{
+			name:        "missing region",
+			addr:        0x7f0be0000000,
+			expectedErr: true,
+		},
+	}
+
+	tmpDir := t.TempDir()
+	fullPath := filepath.Join(tmpDir, makeProcFilePath(PIDSelf, "smaps"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+	data, err := os.ReadFile(filepath.Join("testdata", "smaps_mlock.01.txt"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(fullPath, data, 0444))
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := LockedByAddress(PIDSelf, tmpDir, tcase.addr)
+			if tcase.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tcase.expected, got)
+		})
+	}
+}
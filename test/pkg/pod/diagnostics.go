@@ -0,0 +1,248 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+var (
+	// DriverNamespace and DriverPodSelector locate the driver pods, whose logs are part of the diagnostics.
+	DriverNamespace   = "kube-system"
+	DriverPodSelector = "app=dramemory"
+	// DriverLogLines is how many of the last lines of the driver logs are part of the diagnostics.
+	DriverLogLines int64 = 200
+)
+
+// diagnosticsTimeout bounds the collection, which happens when the caller is already failing.
+const diagnosticsTimeout = 30 * time.Second
+
+// Diagnostics is the context of a pod which failed to reach the desired state, to triage the failure.
+// The collection is best effort: what could not be collected is reported in Errors.
+type Diagnostics struct {
+	Pod    *v1.Pod
+	Events []v1.Event
+	Claims []resourcev1.ResourceClaim
+	// DriverLogs are the last lines of the logs of the driver on the node of the pod, by driver pod name
+	DriverLogs map[string]string
+	Errors     []error
+}
+
+// CollectDiagnostics gathers the status, the events and the claims of the pod, and the logs of the driver running on its node.
+func CollectDiagnostics(ctx context.Context, cs kubernetes.Interface, podNamespace, podName string) *Diagnostics {
+	// the caller's context may be expired already, like when waiting timed out
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	diag := &Diagnostics{
+		DriverLogs: make(map[string]string),
+	}
+	pod, err := cs.CoreV1().Pods(podNamespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		diag.Errors = append(diag.Errors, fmt.Errorf("getting the pod: %w", err))
+	} else {
+		diag.Pod = pod
+	}
+
+	events, err := cs.CoreV1().Events(podNamespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("involvedObject.kind", "Pod"),
+			fields.OneTermEqualSelector("involvedObject.name", podName),
+		).String(),
+	})
+	if err != nil {
+		diag.Errors = append(diag.Errors, fmt.Errorf("listing the events: %w", err))
+	} else {
+		diag.Events = events.Items
+		sort.SliceStable(diag.Events, func(i, j int) bool {
+			return eventTime(&diag.Events[i]).Before(eventTime(&diag.Events[j]))
+		})
+	}
+
+	if diag.Pod == nil {
+		return diag
+	}
+
+	for _, claimSt := range diag.Pod.Status.ResourceClaimStatuses {
+		if claimSt.ResourceClaimName == nil {
+			continue // not needed by the pod
+		}
+		claim, err := cs.ResourceV1().ResourceClaims(podNamespace).Get(ctx, *claimSt.ResourceClaimName, metav1.GetOptions{})
+		if err != nil {
+			diag.Errors = append(diag.Errors, fmt.Errorf("getting the claim %q: %w", *claimSt.ResourceClaimName, err))
+			continue
+		}
+		diag.Claims = append(diag.Claims, *claim)
+	}
+
+	nodeName := diag.Pod.Spec.NodeName
+	if nodeName == "" {
+		return diag // not scheduled, no driver involved yet
+	}
+	driverPods, err := FindOnNode(ctx, cs, DriverNamespace, DriverPodSelector, nodeName)
+	if err != nil {
+		diag.Errors = append(diag.Errors, fmt.Errorf("finding the driver pods on %q: %w", nodeName, err))
+		return diag
+	}
+	for _, driverPod := range driverPods {
+		logs, err := cs.CoreV1().Pods(driverPod.Namespace).GetLogs(driverPod.Name, &v1.PodLogOptions{
+			TailLines: ptr.To(DriverLogLines),
+		}).DoRaw(ctx)
+		if err != nil {
+			diag.Errors = append(diag.Errors, fmt.Errorf("getting the logs of the driver pod %s/%s: %w", driverPod.Namespace, driverPod.Name, err))
+			continue
+		}
+		diag.DriverLogs[driverPod.Name] = string(logs)
+	}
+	return diag
+}
+
+func (diag *Diagnostics) String() string {
+	var sb strings.Builder
+	if pod := diag.Pod; pod != nil {
+		fmt.Fprintf(&sb, "pod %s/%s UID %s on node %q: phase=%s", pod.Namespace, pod.Name, pod.UID, pod.Spec.NodeName, pod.Status.Phase)
+		if pod.Status.Reason != "" {
+			fmt.Fprintf(&sb, " reason=%s", pod.Status.Reason)
+		}
+		if pod.Status.Message != "" {
+			fmt.Fprintf(&sb, " message=%q", pod.Status.Message)
+		}
+		sb.WriteString("\n")
+		for _, cond := range pod.Status.Conditions {
+			fmt.Fprintf(&sb, "  condition %s=%s reason=%s message=%q\n", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+		for _, cntSt := range pod.Status.ContainerStatuses {
+			fmt.Fprintf(&sb, "  container %s: %s\n", cntSt.Name, describeContainerState(cntSt))
+		}
+	}
+	if len(diag.Events) > 0 {
+		sb.WriteString("events:\n")
+		for idx := range diag.Events {
+			ev := &diag.Events[idx]
+			fmt.Fprintf(&sb, "  %s %s %s (%s, x%d): %s\n", eventTime(ev).Format(time.RFC3339), ev.Type, ev.Reason, ev.Source.Component, max(ev.Count, 1), ev.Message)
+		}
+	}
+	for idx := range diag.Claims {
+		fmt.Fprintf(&sb, "claim %s\n", describeClaim(&diag.Claims[idx]))
+	}
+	driverNames := make([]string, 0, len(diag.DriverLogs))
+	for name := range diag.DriverLogs {
+		driverNames = append(driverNames, name)
+	}
+	sort.Strings(driverNames)
+	for _, name := range driverNames {
+		fmt.Fprintf(&sb, "driver pod %s logs (last %d lines):\n%s", name, DriverLogLines, diag.DriverLogs[name])
+		if !strings.HasSuffix(diag.DriverLogs[name], "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	for _, err := range diag.Errors {
+		fmt.Fprintf(&sb, "diagnostics error: %v\n", err)
+	}
+	return sb.String()
+}
+
+// DiagnosticError is the error of a wait on a pod, with the diagnostics collected when it failed.
+type DiagnosticError struct {
+	Err         error
+	Diagnostics *Diagnostics
+}
+
+func (de *DiagnosticError) Error() string {
+	return de.Err.Error() + "\n" + de.Diagnostics.String()
+}
+
+func (de *DiagnosticError) Unwrap() error {
+	return de.Err
+}
+
+// withDiagnostics attaches the diagnostics of the pod to `err`, if any.
+func withDiagnostics(ctx context.Context, cs kubernetes.Interface, podNamespace, podName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &DiagnosticError{
+		Err:         err,
+		Diagnostics: CollectDiagnostics(ctx, cs, podNamespace, podName),
+	}
+}
+
+func describeContainerState(cntSt v1.ContainerStatus) string {
+	state := cntSt.State
+	switch {
+	case state.Waiting != nil:
+		return fmt.Sprintf("waiting reason=%s message=%q restarts=%d", state.Waiting.Reason, state.Waiting.Message, cntSt.RestartCount)
+	case state.Running != nil:
+		return fmt.Sprintf("running since %s ready=%v restarts=%d", state.Running.StartedAt.Format(time.RFC3339), cntSt.Ready, cntSt.RestartCount)
+	case state.Terminated != nil:
+		return fmt.Sprintf("terminated exitCode=%d reason=%s message=%q restarts=%d", state.Terminated.ExitCode, state.Terminated.Reason, state.Terminated.Message, cntSt.RestartCount)
+	default:
+		return "unknown state"
+	}
+}
+
+func describeClaim(claim *resourcev1.ResourceClaim) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s/%s UID %s:", claim.Namespace, claim.Name, claim.UID)
+	alloc := claim.Status.Allocation
+	if alloc == nil {
+		sb.WriteString(" not allocated")
+		return sb.String()
+	}
+	var devices []string
+	for _, res := range alloc.Devices.Results {
+		dev := fmt.Sprintf("%s=%s/%s/%s", res.Request, res.Driver, res.Pool, res.Device)
+		if len(res.ConsumedCapacity) > 0 {
+			var consumed []string
+			for name, qty := range res.ConsumedCapacity {
+				consumed = append(consumed, string(name)+":"+qty.String())
+			}
+			sort.Strings(consumed)
+			dev += "[" + strings.Join(consumed, ",") + "]"
+		}
+		devices = append(devices, dev)
+	}
+	fmt.Fprintf(&sb, " allocated=%s reservedFor=%d", strings.Join(devices, ","), len(claim.Status.ReservedFor))
+	for _, devSt := range claim.Status.Devices {
+		for _, cond := range devSt.Conditions {
+			fmt.Fprintf(&sb, " %s:%s=%s(%s)", devSt.Device, cond.Type, cond.Status, cond.Reason)
+		}
+	}
+	return sb.String()
+}
+
+// eventTime returns the most meaningful time of the event, which depends on the API which emitted it.
+func eventTime(ev *v1.Event) time.Time {
+	if !ev.LastTimestamp.IsZero() {
+		return ev.LastTimestamp.Time
+	}
+	if !ev.EventTime.IsZero() {
+		return ev.EventTime.Time
+	}
+	return ev.FirstTimestamp.Time
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	resourcev1 "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestCollectDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	ts := metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	cli := fake.NewClientset(
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-0", UID: "pod-UID"},
+			Spec:       v1.PodSpec{NodeName: "node-0"},
+			Status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{
+					{
+						Name: "cnt-0",
+						State: v1.ContainerState{
+							Waiting: &v1.ContainerStateWaiting{Reason: "CreateContainerError", Message: "NRI plugin failed"},
+						},
+					},
+				},
+				ResourceClaimStatuses: []v1.PodResourceClaimStatus{
+					{Name: "hp2m", ResourceClaimName: ptr.To("pod-0-hp2m")},
+				},
+			},
+		},
+		&v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "pod-0.1"},
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "pod-0"},
+			Type:           v1.EventTypeWarning,
+			Reason:         "Failed",
+			Message:        "Error: NRI plugin failed",
+			Source:         v1.EventSource{Component: "kubelet"},
+			LastTimestamp:  ts,
+		},
+		&resourcev1.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-0-hp2m", UID: "claim-UID"},
+			Status: resourcev1.ResourceClaimStatus{
+				Allocation: &resourcev1.AllocationResult{
+					Devices: resourcev1.DeviceAllocationResult{
+						Results: []resourcev1.DeviceRequestAllocationResult{
+							{
+								Request: "hp2m",
+								Driver:  "dra.memory",
+								Pool:    "node-0",
+								Device:  "hugepages-2m-0",
+								ConsumedCapacity: map[resourcev1.QualifiedName]resource.Quantity{
+									"size": resource.MustParse("32Mi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: DriverNamespace, Name: "dramemory-abcde", Labels: map[string]string{"app": "dramemory"}},
+			Spec:       v1.PodSpec{NodeName: "node-0"},
+		},
+	)
+
+	diag := CollectDiagnostics(ctx, cli, "ns", "pod-0")
+	require.Empty(t, diag.Errors)
+	require.NotNil(t, diag.Pod)
+	require.Len(t, diag.Events, 1)
+	require.Len(t, diag.Claims, 1)
+	require.Contains(t, diag.DriverLogs, "dramemory-abcde")
+
+	out := diag.String()
+	require.Contains(t, out, "pod ns/pod-0 UID pod-UID on node \"node-0\": phase=Pending")
+	require.Contains(t, out, "container cnt-0: waiting reason=CreateContainerError")
+	require.Contains(t, out, "2026-01-01T00:00:00Z Warning Failed (kubelet, x1): Error: NRI plugin failed")
+	require.Contains(t, out, "claim ns/pod-0-hp2m UID claim-UID: allocated=hp2m=dra.memory/node-0/hugepages-2m-0[size:32Mi] reservedFor=0")
+	require.Contains(t, out, "driver pod dramemory-abcde logs")
+}
+
+func TestCollectDiagnosticsMissingPod(t *testing.T) {
+	diag := CollectDiagnostics(context.Background(), fake.NewClientset(), "ns", "pod-0")
+	require.Nil(t, diag.Pod)
+	require.Len(t, diag.Errors, 1)
+	require.Contains(t, diag.String(), "diagnostics error: getting the pod")
+}
+
+func TestDiagnosticError(t *testing.T) {
+	errWait := errors.New("context deadline exceeded")
+	err := withDiagnostics(context.Background(), fake.NewClientset(), "ns", "pod-0", errWait)
+	require.ErrorIs(t, err, errWait)
+	var diagErr *DiagnosticError
+	require.ErrorAs(t, err, &diagErr)
+	require.Contains(t, err.Error(), "context deadline exceeded\n")
+	require.NoError(t, withDiagnostics(context.Background(), fake.NewClientset(), "ns", "pod-0", nil))
+}
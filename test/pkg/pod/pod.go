@@ -18,6 +18,7 @@ package pod
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -35,13 +36,16 @@ const (
 	PollTimeout  = time.Minute * 2
 )
 
+// ErrTerminalPhase is returned waiting for a phase the pod can't reach anymore.
+var ErrTerminalPhase = errors.New("pod reached a terminal phase")
+
 func CreateSync(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) (*v1.Pod, error) {
 	createdPod, err := cs.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("error creating pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
 	if err = WaitToBeRunning(ctx, cs, createdPod.Namespace, createdPod.Name); err != nil {
-		return nil, err
+		return nil, withDiagnostics(ctx, cs, createdPod.Namespace, createdPod.Name, err)
 	}
 	// Get the newest pod after it becomes running and ready, some status may change after pod created, such as pod ip.
 	return cs.CoreV1().Pods(createdPod.Namespace).Get(ctx, createdPod.Name, metav1.GetOptions{})
@@ -50,11 +54,12 @@ func CreateSync(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) (*v1.
 func RunToCompletion(ctx context.Context, cs kubernetes.Interface, pod *v1.Pod) (*v1.Pod, error) {
 	createdPod, err := cs.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("error creating pod %s/%s: %w", createdPod.Namespace, createdPod.Name, err)
+		return nil, fmt.Errorf("error creating pod %s/%s: %w", pod.Namespace, pod.Name, err)
 	}
-	_, err = WaitForPhase(ctx, cs, createdPod.Namespace, createdPod.Name, v1.PodSucceeded)
+	phase, err := WaitForPhase(ctx, cs, createdPod.Namespace, createdPod.Name, v1.PodSucceeded)
 	if err != nil {
-		return nil, err
+		err = fmt.Errorf("pod=%s/%s did not succeed; phase=%q; %w", createdPod.Namespace, createdPod.Name, phase, err)
+		return nil, withDiagnostics(ctx, cs, createdPod.Namespace, createdPod.Name, err)
 	}
 	// Get the newest pod after it becomes running and ready, some status may change after pod created, such as pod ip.
 	return cs.CoreV1().Pods(createdPod.Namespace).Get(ctx, createdPod.Name, metav1.GetOptions{})
@@ -99,6 +104,10 @@ func WaitForPhase(ctx context.Context, cs kubernetes.Interface, podNamespace, po
 		if podPhase == desiredPhase {
 			return true, nil
 		}
+		if podPhase == v1.PodSucceeded || podPhase == v1.PodFailed {
+			// no point in waiting further
+			return false, ErrTerminalPhase
+		}
 		return false, nil
 	})
 	return podPhase, err
@@ -116,3 +125,24 @@ func GetLogs(cs kubernetes.Interface, ctx context.Context, podNamespace, podName
 	}
 	return string(logs), err
 }
+
+// FindOnNode returns the pods in `podNamespace` matching `labelSelector` which are bound to `nodeName`.
+func FindOnNode(ctx context.Context, cs kubernetes.Interface, podNamespace, labelSelector, nodeName string) ([]v1.Pod, error) {
+	podList, err := cs.CoreV1().Pods(podNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: "spec.nodeName=" + nodeName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+func IsReady(pod *v1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/ffromani/dra-driver-memory/pkg/unitconv"
@@ -28,11 +29,21 @@ import (
 
 const (
 	Prefix = ">>>::RESULT="
+
+	// SchemaVersion is the version of the result format emitted by this package. The changes must be
+	// backward compatible: new fields are optional, existing fields keep name and meaning, so the
+	// consumers can parse results of both older and newer versions.
+	SchemaVersion = 2
+	// schemaVersionUnversioned is the version of the results which predate the versioning.
+	schemaVersionUnversioned = 1
 )
 
 type Result struct {
-	Request Request `json:"request"`
-	Status  Status  `json:"status"`
+	SchemaVersion int     `json:"schemaVersion"`
+	Request       Request `json:"request"`
+	Status        Status  `json:"status"`
+	// Allocation reports the properties of the allocation observed after the memory is faulted in, if any
+	Allocation *Allocation `json:"allocation,omitempty"`
 }
 
 type Request struct {
@@ -40,16 +51,77 @@ type Request struct {
 	SizeInBytes uint64 `json:"sizeInBytes"`
 	HugeTLB     bool   `json:"hugeTLB"`
 	NUMANodes   string `json:"numaNodes"`
+	// HugepageSize is the size of the hugepages requested explicitly, if any
+	HugepageSize string `json:"hugepageSize,omitempty"`
+	// MemPolicy is the NUMA memory policy requested explicitly, if any, like "bind:0-1"
+	MemPolicy string `json:"memPolicy,omitempty"`
+	// Backing is how the memory is obtained, e.g. anonymous mapping or SysV shared memory
+	Backing string `json:"backing,omitempty"`
+	// THP is true if the allocation asked for transparent hugepages
+	THP bool `json:"thp,omitempty"`
+	// Grow is true if the allocation grows in increments of Size until failure
+	Grow bool `json:"grow,omitempty"`
+	// ChurnWorkers is the number of threads of the churn workload, if any
+	ChurnWorkers int `json:"churnWorkers,omitempty"`
+	// Benchmark is true if the bandwidth and the latency of the allocation are measured
+	Benchmark bool `json:"benchmark,omitempty"`
+	// ForkChildren is the number of child processes which allocate Size each, if any
+	ForkChildren int `json:"forkChildren,omitempty"`
+	// MLock is true if the allocation is locked in memory with mlock
+	MLock bool `json:"mlock,omitempty"`
+}
+
+type Allocation struct {
+	// THPSize is the amount of memory backed by transparent hugepages
+	THPSize        string `json:"thpSize,omitempty"`
+	THPSizeInBytes uint64 `json:"thpSizeInBytes,omitempty"`
+	// GrownSize is the total amount of memory allocated by growing the allocation until failure
+	GrownSize        string `json:"grownSize,omitempty"`
+	GrownSizeInBytes uint64 `json:"grownSizeInBytes,omitempty"`
+	// ChurnCycles is the number of allocations completed by the churn workload
+	ChurnCycles uint64 `json:"churnCycles,omitempty"`
+	// ChurnFailures is the number of allocations of the churn workload refused with ENOMEM
+	ChurnFailures uint64 `json:"churnFailures,omitempty"`
+	// BandwidthMBps is the sequential read/write bandwidth measured on the allocation
+	BandwidthMBps float64 `json:"bandwidthMBps,omitempty"`
+	// LatencyNs is the average latency of the random dependent loads measured on the allocation
+	LatencyNs float64 `json:"latencyNs,omitempty"`
+	// Children are the allocations of the child processes, in the order they were forked
+	Children []Child `json:"children,omitempty"`
+	// MemlockLimit is the RLIMIT_MEMLOCK of the process, like "64Ki" or "unlimited", if the allocation is locked
+	MemlockLimit string `json:"memlockLimit,omitempty"`
+	// LockedSize is the amount of memory of the allocation locked, as reported by the kernel.
+	// The hugetlb allocations are never reported as locked.
+	LockedSize        string `json:"lockedSize,omitempty"`
+	LockedSizeInBytes uint64 `json:"lockedSizeInBytes,omitempty"`
+}
+
+// Child is what a child process observed about itself and its allocation.
+type Child struct {
+	PID    int    `json:"pid"`
+	Cgroup string `json:"cgroup"`
+	// MemsAllowed and CPUsAllowed are inherited from the parent, unless something moved the child
+	MemsAllowed string `json:"memsAllowed"`
+	CPUsAllowed string `json:"cpusAllowed"`
+	// NUMANodes are the nodes the allocation comes from, if it succeeded
+	NUMANodes string `json:"numaNodes,omitempty"`
+	// Refused is true if the allocation failed with ENOMEM
+	Refused bool `json:"refused,omitempty"`
+	// Error is any other failure of the child
+	Error string `json:"error,omitempty"`
 }
 
 type Status struct {
-	Code    int    `json:"code"`
-	Reason  Reason `json:"reason"`
-	Message string `json:"message"`
+	Code   int    `json:"code"`
+	Reason Reason `json:"reason"`
+	// ReasonCode is the stable numeric identifier of the reason, missing in the unversioned results
+	ReasonCode ReasonCode `json:"reasonCode,omitempty"`
+	Message    string     `json:"message"`
 }
 
 func New(allocSize uint64, hugeTLB bool, numaNodes string) *Result {
 	return &Result{
+		SchemaVersion: SchemaVersion,
 		Request: Request{
 			Size:        unitconv.SizeInBytesToMinimizedString(allocSize),
 			SizeInBytes: allocSize,
@@ -62,9 +134,10 @@ func New(allocSize uint64, hugeTLB bool, numaNodes string) *Result {
 func (res *Result) Finalize(code int, reason Reason, fmt_ string, args ...any) int {
 	message := fmt.Sprintf(fmt_, args...)
 	res.Status = Status{
-		Code:    code,
-		Reason:  reason,
-		Message: message,
+		Code:       code,
+		Reason:     reason,
+		ReasonCode: reason.Code(),
+		Message:    message,
 	}
 	data, err := json.Marshal(res)
 	if err == nil {
@@ -73,10 +146,36 @@ func (res *Result) Finalize(code int, reason Reason, fmt_ string, args ...any) i
 	return code
 }
 
-func FromString(s string) (st *Result, err error) {
-	st = &Result{}
-	err = json.Unmarshal([]byte(s), st)
-	return
+// WriteFile writes the result as JSON on `path`, like /dev/termination-log, for consumers
+// which can't reliably scrape the logs, e.g. after the container exited.
+func (res *Result) WriteFile(path string) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// FromString parses a result of any schema version. The unknown fields, emitted by newer versions,
+// are ignored. The reason is normalized using the reason code, if known, so the consumers can compare
+// reasons regardless of the version which emitted them.
+func FromString(s string) (*Result, error) {
+	res := &Result{}
+	err := json.Unmarshal([]byte(s), res)
+	if err != nil {
+		return nil, err
+	}
+	if res.SchemaVersion == 0 {
+		res.SchemaVersion = schemaVersionUnversioned
+	}
+	st := &res.Status
+	if st.ReasonCode == ReasonCodeUnknown {
+		st.ReasonCode = st.Reason.Code()
+	} else if reason, ok := ReasonForCode(st.ReasonCode); ok {
+		st.Reason = reason
+	}
+	// else: a reason newer than this package, the consumers can still use the textual reason
+	return res, nil
 }
 
 func FromLogs(logs string) (st *Result, err error) {
@@ -95,12 +194,84 @@ func FromLogs(logs string) (st *Result, err error) {
 type Reason string
 
 const (
-	Succeeded             Reason = "Succeeded"
-	FailureGeneric        Reason = "GenericFailure"
-	FailedAsExpected      Reason = "FailedAsExpected"
-	UnexpectedMMapError   Reason = "UnexpectedMMapError"
-	UnexpectedMMapSuccess Reason = "MMapShouldHaveFailed"
-	CannotCheckAllocation Reason = "CannotCheckAllocation"
-	NUMAOverflown         Reason = "AllocatedOverMultipleNUMANodes"
-	NUMAMismatch          Reason = "AllocatedOverUnexpectedNUMANodes"
+	Succeeded                Reason = "Succeeded"
+	FailureGeneric           Reason = "GenericFailure"
+	FailedAsExpected         Reason = "FailedAsExpected"
+	UnexpectedMMapError      Reason = "UnexpectedMMapError"
+	UnexpectedMMapSuccess    Reason = "MMapShouldHaveFailed"
+	CannotCheckAllocation    Reason = "CannotCheckAllocation"
+	NUMAOverflown            Reason = "AllocatedOverMultipleNUMANodes"
+	NUMAMismatch             Reason = "AllocatedOverUnexpectedNUMANodes"
+	NUMACPUMismatch          Reason = "AllocatedOffTheNUMANodesOfTheCPUs"
+	PageSizeMismatch         Reason = "AllocatedWithUnexpectedPageSize"
+	UnexpectedMemPolicyError Reason = "UnexpectedMemPolicyError"
+	MemPolicyViolated        Reason = "AllocatedAgainstMemPolicy"
+	UnexpectedMAdviseError   Reason = "UnexpectedMAdviseError"
+	UnexpectedChurnError     Reason = "UnexpectedChurnError"
+	UnexpectedForkError      Reason = "UnexpectedForkError"
+	ForkInheritanceViolated  Reason = "ChildNotConstrainedLikeParent"
+	UnexpectedMLockError     Reason = "UnexpectedMLockError"
+	MemlockLimitExceeded     Reason = "AllocationExceedsMemlockLimit"
 )
+
+// ReasonCode is the stable identifier of a Reason, for the consumers which outlive the reason strings.
+type ReasonCode int
+
+// The codes are part of the schema: never change nor reuse a code, only add new ones.
+const (
+	ReasonCodeUnknown                  ReasonCode = 0
+	ReasonCodeSucceeded                ReasonCode = 1
+	ReasonCodeFailureGeneric           ReasonCode = 2
+	ReasonCodeFailedAsExpected         ReasonCode = 3
+	ReasonCodeUnexpectedMMapError      ReasonCode = 4
+	ReasonCodeUnexpectedMMapSuccess    ReasonCode = 5
+	ReasonCodeCannotCheckAllocation    ReasonCode = 6
+	ReasonCodeNUMAOverflown            ReasonCode = 7
+	ReasonCodeNUMAMismatch             ReasonCode = 8
+	ReasonCodeNUMACPUMismatch          ReasonCode = 9
+	ReasonCodePageSizeMismatch         ReasonCode = 10
+	ReasonCodeUnexpectedMemPolicyError ReasonCode = 11
+	ReasonCodeMemPolicyViolated        ReasonCode = 12
+	ReasonCodeUnexpectedMAdviseError   ReasonCode = 13
+	ReasonCodeUnexpectedChurnError     ReasonCode = 14
+	ReasonCodeUnexpectedForkError      ReasonCode = 15
+	ReasonCodeForkInheritanceViolated  ReasonCode = 16
+	ReasonCodeUnexpectedMLockError     ReasonCode = 17
+	ReasonCodeMemlockLimitExceeded     ReasonCode = 18
+)
+
+var reasonCodes = map[Reason]ReasonCode{
+	Succeeded:                ReasonCodeSucceeded,
+	FailureGeneric:           ReasonCodeFailureGeneric,
+	FailedAsExpected:         ReasonCodeFailedAsExpected,
+	UnexpectedMMapError:      ReasonCodeUnexpectedMMapError,
+	UnexpectedMMapSuccess:    ReasonCodeUnexpectedMMapSuccess,
+	CannotCheckAllocation:    ReasonCodeCannotCheckAllocation,
+	NUMAOverflown:            ReasonCodeNUMAOverflown,
+	NUMAMismatch:             ReasonCodeNUMAMismatch,
+	NUMACPUMismatch:          ReasonCodeNUMACPUMismatch,
+	PageSizeMismatch:         ReasonCodePageSizeMismatch,
+	UnexpectedMemPolicyError: ReasonCodeUnexpectedMemPolicyError,
+	MemPolicyViolated:        ReasonCodeMemPolicyViolated,
+	UnexpectedMAdviseError:   ReasonCodeUnexpectedMAdviseError,
+	UnexpectedChurnError:     ReasonCodeUnexpectedChurnError,
+	UnexpectedForkError:      ReasonCodeUnexpectedForkError,
+	ForkInheritanceViolated:  ReasonCodeForkInheritanceViolated,
+	UnexpectedMLockError:     ReasonCodeUnexpectedMLockError,
+	MemlockLimitExceeded:     ReasonCodeMemlockLimitExceeded,
+}
+
+// Code returns the code of the reason, or ReasonCodeUnknown if the reason is unknown to this package.
+func (r Reason) Code() ReasonCode {
+	return reasonCodes[r]
+}
+
+// ReasonForCode returns the reason of the code, if the code is known to this package.
+func ReasonForCode(code ReasonCode) (Reason, bool) {
+	for reason, rc := range reasonCodes {
+		if rc == code {
+			return reason, true
+		}
+	}
+	return "", false
+}
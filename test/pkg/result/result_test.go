@@ -0,0 +1,135 @@
+/*
+ * Copyright 2026 The Kubernetes Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package result
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	res := New(32*(1<<20), true, "0")
+	res.Allocation = &Allocation{
+		GrownSize:        "32Mi",
+		GrownSizeInBytes: 32 * (1 << 20),
+	}
+	code := res.Finalize(4, NUMAMismatch, "expected=%q actual=%q", "0", "1")
+	require.Equal(t, 4, code)
+
+	path := filepath.Join(t.TempDir(), "termination-log")
+	require.NoError(t, res.WriteFile(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	got, err := FromString(string(data))
+	require.NoError(t, err)
+	require.Equal(t, res, got)
+}
+
+func TestFromStringCompatibility(t *testing.T) {
+	type testcase struct {
+		name           string
+		data           string
+		expectedSchema int
+		expectedReason Reason
+		expectedCode   ReasonCode
+	}
+
+	testcases := []testcase{
+		{
+			name:           "unversioned",
+			data:           `{"request":{"size":"32Mi","sizeInBytes":33554432,"hugeTLB":true,"numaNodes":"0"},"status":{"code":0,"reason":"FailedAsExpected","message":"failed"}}`,
+			expectedSchema: 1,
+			expectedReason: FailedAsExpected,
+			expectedCode:   ReasonCodeFailedAsExpected,
+		},
+		{
+			name:           "current",
+			data:           `{"schemaVersion":2,"request":{"size":"32Mi","sizeInBytes":33554432,"hugeTLB":true,"numaNodes":"0"},"status":{"code":0,"reason":"Succeeded","reasonCode":1,"message":"completed"}}`,
+			expectedSchema: 2,
+			expectedReason: Succeeded,
+			expectedCode:   ReasonCodeSucceeded,
+		},
+		{
+			name:           "newer with extra fields and renamed reason",
+			data:           `{"schemaVersion":7,"request":{"size":"32Mi","sizeInBytes":33554432,"hugeTLB":true,"numaNodes":"0","future":"yes"},"status":{"code":4,"reason":"NUMAMismatchRenamed","reasonCode":8,"message":"mismatch","severity":"high"},"extra":{"a":1}}`,
+			expectedSchema: 7,
+			expectedReason: NUMAMismatch,
+			expectedCode:   ReasonCodeNUMAMismatch,
+		},
+		{
+			name:           "newer with unknown reason",
+			data:           `{"schemaVersion":7,"request":{"size":"32Mi","sizeInBytes":33554432,"hugeTLB":true,"numaNodes":"0"},"status":{"code":1,"reason":"SomethingNew","reasonCode":999,"message":"new"}}`,
+			expectedSchema: 7,
+			expectedReason: Reason("SomethingNew"),
+			expectedCode:   ReasonCode(999),
+		},
+		{
+			name:           "unversioned with unknown reason",
+			data:           `{"request":{"size":"32Mi","sizeInBytes":33554432,"hugeTLB":true,"numaNodes":"0"},"status":{"code":1,"reason":"SomethingOld","message":"old"}}`,
+			expectedSchema: 1,
+			expectedReason: Reason("SomethingOld"),
+			expectedCode:   ReasonCodeUnknown,
+		},
+	}
+
+	for _, tcase := range testcases {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := FromString(tcase.data)
+			require.NoError(t, err)
+			require.Equal(t, tcase.expectedSchema, got.SchemaVersion)
+			require.Equal(t, tcase.expectedReason, got.Status.Reason)
+			require.Equal(t, tcase.expectedCode, got.Status.ReasonCode)
+			require.Equal(t, uint64(32*(1<<20)), got.Request.SizeInBytes)
+		})
+	}
+}
+
+func TestReasonCodes(t *testing.T) {
+	seen := make(map[ReasonCode]Reason)
+	for reason, code := range reasonCodes {
+		require.NotEqual(t, ReasonCodeUnknown, code, "reason %q", reason)
+		prev, dup := seen[code]
+		require.False(t, dup, "code %d shared by %q and %q", code, reason, prev)
+		seen[code] = reason
+
+		got, ok := ReasonForCode(code)
+		require.True(t, ok)
+		require.Equal(t, reason, got)
+	}
+	require.Equal(t, ReasonCodeUnknown, Reason("Unknown").Code())
+	_, ok := ReasonForCode(ReasonCodeUnknown)
+	require.False(t, ok)
+}
+
+func TestFromLogs(t *testing.T) {
+	res := New(8*(1<<20), false, "0-1")
+	res.Finalize(0, Succeeded, "completed")
+	data, err := json.Marshal(res)
+	require.NoError(t, err)
+
+	logs := "2026/01/01 00:00:00 main.go:42: \"level\"=0 \"msg\"=\"mmap\"\n" + Prefix + string(data) + "\n"
+	got, err := FromLogs(logs)
+	require.NoError(t, err)
+	require.Equal(t, res, got)
+
+	_, err = FromLogs("no result here\n")
+	require.Error(t, err)
+}
@@ -0,0 +1,211 @@
+// AI-Attribution: AIA PAI Nc Hin R gemini-3.0-pro v1.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeUpdated ChangeKind = "updated"
+)
+
+// Change is a cgroup which differs between two samples of the hierarchy.
+type Change struct {
+	Time time.Time  `json:"time"`
+	Kind ChangeKind `json:"kind"`
+	// Record is the current state, or the last known state if the cgroup was removed
+	Record Record `json:"record"`
+	// Previous is the state in the previous sample, if the cgroup was updated
+	Previous *Record `json:"previous,omitempty"`
+}
+
+// diffRecords returns the changes from `prev` to `curr`: the added and updated cgroups in
+// the order of `curr`, then the removed cgroups in the order of `prev`.
+func diffRecords(prev, curr []Record, now time.Time) []Change {
+	prevByPath := make(map[string]*Record, len(prev))
+	for idx := range prev {
+		prevByPath[prev[idx].Path] = &prev[idx]
+	}
+	currPaths := make(map[string]bool, len(curr))
+	var changes []Change
+	for _, rec := range curr {
+		currPaths[rec.Path] = true
+		old, ok := prevByPath[rec.Path]
+		if !ok {
+			changes = append(changes, Change{Time: now, Kind: ChangeAdded, Record: rec})
+			continue
+		}
+		if reflect.DeepEqual(*old, rec) {
+			continue
+		}
+		changes = append(changes, Change{Time: now, Kind: ChangeUpdated, Record: rec, Previous: old})
+	}
+	for _, rec := range prev {
+		if currPaths[rec.Path] {
+			continue
+		}
+		changes = append(changes, Change{Time: now, Kind: ChangeRemoved, Record: rec})
+	}
+	return changes
+}
+
+func readBaseline(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return nil, fmt.Errorf("malformed baseline %q: %w", path, err)
+	}
+	return records, nil
+}
+
+// runDiff prints the changes against the baseline, if any, and then, if watching, the changes between
+// the consecutive samples until interrupted. Without a baseline, the first sample is the reference.
+func runDiff(rootDir, hbSize, output, baselinePath string, interval time.Duration) error {
+	var prev []Record
+	var err error
+	if baselinePath != "" {
+		prev, err = readBaseline(baselinePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	sample := func(prev []Record) []Record {
+		curr, err := collect(rootDir, hbSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error walking tree: %v\n", err)
+			// cgroups come and go while we walk, we will catch up at the next sample
+		}
+		if prev != nil {
+			if err := writeChanges(os.Stdout, output, diffRecords(prev, curr, time.Now())); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+		return curr
+	}
+
+	prev = sample(prev)
+	if interval <= 0 {
+		return nil
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			prev = sample(prev)
+		}
+	}
+}
+
+func writeChanges(out io.Writer, output string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	switch output {
+	case OutputJSON:
+		// one change per line, to be easy to consume while streaming
+		enc := json.NewEncoder(out)
+		for _, change := range changes {
+			if err := enc.Encode(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputYAML:
+		data, err := yaml.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "---\n%s", data)
+		return err
+	default:
+		return writeChangesTable(out, changes)
+	}
+}
+
+func writeChangesTable(out io.Writer, changes []Change) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\n", changes[0].Time.Format(time.RFC3339))
+	for _, change := range changes {
+		rec := change.Record
+		fmt.Fprintf(w, "%s %s\t%s\n", changeMarker(change.Kind), rec.Path, strings.Join(describeChange(change), " "))
+	}
+	return w.Flush()
+}
+
+func changeMarker(kind ChangeKind) string {
+	switch kind {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// describeChange lists the values of the added and removed records, and only the changed values of the updated ones.
+func describeChange(change Change) []string {
+	var prevValues map[string]string
+	if old := change.Previous; old != nil {
+		prevValues = make(map[string]string)
+		for _, val := range old.Values() {
+			prevValues[val.Name] = val.Value
+		}
+	}
+	var items []string
+	for _, val := range change.Record.Values() {
+		if prevValues != nil {
+			if prev := prevValues[val.Name]; prev != val.Value {
+				items = append(items, fmt.Sprintf("%s=%s->%s", val.Name, orMissing(prev), val.Value))
+			}
+			delete(prevValues, val.Name)
+			continue
+		}
+		items = append(items, fmt.Sprintf("%s=%s", val.Name, val.Value))
+	}
+	if change.Previous == nil {
+		return items
+	}
+	// the values left are gone, e.g. a hugetlb size
+	for _, val := range change.Previous.Values() {
+		if _, ok := prevValues[val.Name]; ok {
+			items = append(items, fmt.Sprintf("%s=%s->-", val.Name, val.Value))
+		}
+	}
+	return items
+}
+
+func orMissing(val string) string {
+	if val == "" {
+		return "-"
+	}
+	return val
+}
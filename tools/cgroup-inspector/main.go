@@ -3,29 +3,120 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
+	"sigs.k8s.io/yaml"
+
 	"github.com/ffromani/dra-driver-memory/pkg/cgroups"
 )
 
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Record holds the memory state of a cgroup, as the driver sees it. The values are verbatim
+// from the interface files, e.g. "max", and are empty if the file doesn't exist.
+type Record struct {
+	// Path is relative to the root of the inspection
+	Path       string          `json:"path"`
+	Depth      int             `json:"depth"`
+	CPUSetMems string          `json:"cpuset_mems,omitempty"`
+	Memory     *MemoryRecord   `json:"memory,omitempty"`
+	Hugetlb    []HugetlbRecord `json:"hugetlb,omitempty"`
+	// Errors are the failures reading the interface files which exist
+	Errors []string `json:"errors,omitempty"`
+}
+
+type MemoryRecord struct {
+	Max     string `json:"max,omitempty"`
+	Current string `json:"current,omitempty"`
+	Peak    string `json:"peak,omitempty"`
+}
+
+type HugetlbRecord struct {
+	Size      string           `json:"size"`
+	RsvdLimit string           `json:"rsvd_limit,omitempty"`
+	Limit     string           `json:"limit,omitempty"`
+	Current   string           `json:"current,omitempty"`
+	Events    map[string]int64 `json:"events,omitempty"`
+	// NUMAStat is where the hugepages are actually faulted, which the limits alone don't tell
+	NUMAStat *NUMAStat `json:"numa_stat,omitempty"`
+}
+
+// NUMAStat is the usage in bytes by NUMA node, of the cgroup itself and including its descendants.
+type NUMAStat struct {
+	Total        map[int]int64 `json:"total,omitempty"`
+	Hierarchical map[int]int64 `json:"hierarchical,omitempty"`
+}
+
 func main() {
 	rootDir := flag.String("root", cgroups.MountPoint, "Root cgroup path to inspect")
-	hbSize := flag.String("size", "2MB", "Hugepage size suffix (e.g., 2MB, 1GB)")
+	hbSize := flag.String("size", "", "Hugepage size suffix (e.g., 2MB, 1GB). Default is all the sizes found.")
+	output := flag.String("o", OutputTable, "Output format: table, json, yaml")
+	watchInterval := flag.Duration("watch", 0, "Sample the hierarchy at this interval until interrupted, and print only the changed cgroups. Zero disables.")
+	baselinePath := flag.String("baseline", "", "Print only the cgroups changed against this baseline, saved with -o json. With -watch, the first sample is compared against it.")
 	flag.Parse()
 
-	// Use tabwriter for aligned output
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	defer w.Flush() //nolint:errcheck
+	if *output != OutputTable && *output != OutputJSON && *output != OutputYAML {
+		fmt.Fprintf(os.Stderr, "Error: unsupported output format %q\n", *output)
+		os.Exit(1)
+	}
+
+	var err error
+	if *watchInterval > 0 || *baselinePath != "" {
+		err = runDiff(*rootDir, *hbSize, *output, *baselinePath, *watchInterval)
+	} else {
+		err = runOnce(*rootDir, *hbSize, *output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runOnce(rootDir, hbSize, output string) error {
+	records, err := collect(rootDir, hbSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error walking tree: %v\n", err)
+		// we still emit what we got
+	}
 
-	fmt.Fprintf(w, "HIERARCHY\tRESERVED LIMIT(%s)\tLIMIT (%s)\tCURRENT\tFAILURES (Events)\n", *hbSize, *hbSize)
-	fmt.Fprintf(w, "---------\t------------------\t----------\t-------\t-----------------\n")
+	switch output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(records)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	default:
+		return writeTable(os.Stdout, records)
+	}
+}
 
-	err := filepath.Walk(*rootDir, func(path string, info os.FileInfo, err error) error {
+// collect walks the hierarchy. If `hbSize` is empty, it reports all the hugetlb sizes found in each cgroup.
+func collect(rootDir, hbSize string) ([]Record, error) {
+	records := []Record{}
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -33,65 +124,286 @@ func main() {
 			return nil
 		}
 
-		// Calculate depth for indentation
-		relPath, _ := filepath.Rel(*rootDir, path)
+		relPath, _ := filepath.Rel(rootDir, path)
+		depth := strings.Count(relPath, string(os.PathSeparator))
 		if relPath == "." {
-			relPath = filepath.Base(*rootDir)
+			relPath = filepath.Base(rootDir)
+			depth = 0
 		}
-		depth := strings.Count(relPath, string(os.PathSeparator))
-		indent := strings.Repeat("  ", depth)
-		nodeName := filepath.Base(path)
-
-		// 2. Read Reserved Limit (rsvd max)
-		rsvdLimitFile := filepath.Join(path, fmt.Sprintf("hugetlb.%s.rsvd.max", *hbSize))
-		rsvdLimitVal := readFileValue(rsvdLimitFile)
-
-		// 2. Read Limit (max)
-		limitFile := filepath.Join(path, fmt.Sprintf("hugetlb.%s.max", *hbSize))
-		limitVal := readFileValue(limitFile)
-
-		// 2. Read Usage (current)
-		currFile := filepath.Join(path, fmt.Sprintf("hugetlb.%s.current", *hbSize))
-		currVal := readFileValue(currFile)
-
-		// 3. Read Events (max hits)
-		eventsFile := filepath.Join(path, fmt.Sprintf("hugetlb.%s.events", *hbSize))
-		eventsVal := readEventsMax(eventsFile)
-
-		// Print the row
-		// If files don't exist (e.g. root vs leaf), values will be "-"
-		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n", indent, nodeName, rsvdLimitVal, limitVal, currVal, eventsVal)
 
+		rec := Record{
+			Path:  relPath,
+			Depth: depth,
+		}
+		rec.CPUSetMems = rec.readValue(filepath.Join(path, "cpuset.mems"))
+		mem := MemoryRecord{
+			Max:     rec.readValue(filepath.Join(path, "memory.max")),
+			Current: rec.readValue(filepath.Join(path, "memory.current")),
+			Peak:    rec.readValue(filepath.Join(path, "memory.peak")),
+		}
+		if mem != (MemoryRecord{}) {
+			rec.Memory = &mem
+		}
+		sizes := []string{hbSize}
+		if hbSize == "" {
+			sizes = findHugetlbSizes(path)
+		}
+		for _, size := range sizes {
+			hb := HugetlbRecord{
+				Size:      size,
+				RsvdLimit: rec.readValue(filepath.Join(path, fmt.Sprintf("hugetlb.%s.rsvd.max", size))),
+				Limit:     rec.readValue(filepath.Join(path, fmt.Sprintf("hugetlb.%s.max", size))),
+				Current:   rec.readValue(filepath.Join(path, fmt.Sprintf("hugetlb.%s.current", size))),
+				Events:    rec.readEvents(filepath.Join(path, fmt.Sprintf("hugetlb.%s.events", size))),
+				NUMAStat:  rec.readNUMAStat(filepath.Join(path, fmt.Sprintf("hugetlb.%s.numa_stat", size))),
+			}
+			if hb.Limit == "" && hb.RsvdLimit == "" && hb.Current == "" && hb.Events == nil && hb.NUMAStat == nil {
+				continue // e.g. the root cgroup
+			}
+			rec.Hugetlb = append(rec.Hugetlb, hb)
+		}
+		records = append(records, rec)
 		return nil
 	})
+	return records, err
+}
 
-	if err != nil {
-		fmt.Printf("Error walking tree: %v\n", err)
+// findHugetlbSizes returns the hugetlb sizes of the cgroup, like "2MB", from the smallest.
+// The root cgroup has none.
+func findHugetlbSizes(path string) []string {
+	// we match both hugetlb.<size>.max and hugetlb.<size>.rsvd.max
+	matches, _ := filepath.Glob(filepath.Join(path, "hugetlb.*.max"))
+	var sizes []string
+	for _, match := range matches {
+		size := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "hugetlb."), ".max")
+		if strings.Contains(size, ".") {
+			continue
+		}
+		sizes = append(sizes, size)
+	}
+	slices.SortFunc(sizes, func(a, b string) int {
+		return int(hugetlbSizeInKB(a) - hugetlbSizeInKB(b))
+	})
+	return sizes
+}
+
+// hugetlbSizeInKB parses the sizes in the names of the hugetlb interface files, like "2MB" or "1GB".
+func hugetlbSizeInKB(size string) int64 {
+	for idx, unit := range []string{"KB", "MB", "GB"} {
+		val, ok := strings.CutSuffix(size, unit)
+		if !ok {
+			continue
+		}
+		num, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return 0
+		}
+		return num << (10 * idx)
 	}
+	return 0
 }
 
-func readFileValue(path string) string {
+func (rec *Record) readValue(path string) string {
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return "-"
+		return ""
 	}
 	if err != nil {
-		return "?"
+		rec.Errors = append(rec.Errors, err.Error())
+		return ""
 	}
 	return strings.TrimSpace(string(data))
 }
 
-func readEventsMax(path string) string {
+// readEvents parses the flat keyed file, like "max 5". Returns nil if the file doesn't exist.
+func (rec *Record) readEvents(path string) map[string]int64 {
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return "-"
+		return nil
 	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		if strings.HasPrefix(line, "max ") {
-			// Return just the number "max 5" -> "5"
-			return strings.TrimPrefix(line, "max ")
+	if err != nil {
+		rec.Errors = append(rec.Errors, err.Error())
+		return nil
+	}
+	events := make(map[string]int64)
+	for line := range strings.Lines(string(data)) {
+		key, val, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			rec.Errors = append(rec.Errors, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		events[key] = count
+	}
+	return events
+}
+
+// readNUMAStat parses the lines like "total=2097152 N0=2097152 N1=0". Returns nil if the file doesn't exist.
+func (rec *Record) readNUMAStat(path string) *NUMAStat {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		rec.Errors = append(rec.Errors, err.Error())
+		return nil
+	}
+	stat := NUMAStat{}
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
 		}
+		usage := make(map[int]int64)
+		for _, field := range fields[1:] {
+			key, val, ok := strings.Cut(field, "=")
+			if !ok || !strings.HasPrefix(key, "N") {
+				continue
+			}
+			node, err := strconv.Atoi(key[1:])
+			if err != nil {
+				rec.Errors = append(rec.Errors, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			amount, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				rec.Errors = append(rec.Errors, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			usage[node] = amount
+		}
+		switch {
+		case strings.HasPrefix(fields[0], "total="):
+			stat.Total = usage
+		case strings.HasPrefix(fields[0], "hierarchical_total="):
+			stat.Hierarchical = usage
+		}
+	}
+	return &stat
+}
+
+// Value is a value of the record, named after its interface file.
+type Value struct {
+	Name  string
+	Value string
+}
+
+// Values flattens the record for the displays. Missing values are "-", unreadable values are "?".
+func (rec *Record) Values() []Value {
+	vals := rec.memoryValues()
+	for _, hb := range rec.Hugetlb {
+		vals = append(vals, rec.hugetlbValues(hb)...)
+	}
+	return vals
+}
+
+func (rec *Record) memoryValues() []Value {
+	mem := rec.Memory
+	if mem == nil {
+		mem = &MemoryRecord{}
+	}
+	return []Value{
+		{Name: "cpuset.mems", Value: rec.tableValue(rec.CPUSetMems)},
+		{Name: "memory.max", Value: rec.tableValue(mem.Max)},
+		{Name: "memory.current", Value: rec.tableValue(mem.Current)},
+		{Name: "memory.peak", Value: rec.tableValue(mem.Peak)},
+	}
+}
+
+func (rec *Record) hugetlbValues(hb HugetlbRecord) []Value {
+	prefix := "hugetlb." + hb.Size
+	return []Value{
+		{Name: prefix + ".max", Value: rec.tableValue(hb.Limit)},
+		{Name: prefix + ".rsvd.max", Value: rec.tableValue(hb.RsvdLimit)},
+		{Name: prefix + ".current", Value: rec.tableValue(hb.Current)},
+		{Name: prefix + ".events", Value: rec.tableEvents(hb.Events)},
+		{Name: prefix + ".numa_stat", Value: rec.tableNUMAStat(hb.NUMAStat)},
+	}
+}
+
+func writeTable(out io.Writer, records []Record) error {
+	// every cgroup below the root has the same hugetlb sizes, the root has none
+	var sizes []string
+	for _, rec := range records {
+		for _, hb := range rec.Hugetlb {
+			if !slices.Contains(sizes, hb.Size) {
+				sizes = append(sizes, hb.Size)
+			}
+		}
+	}
+	slices.SortFunc(sizes, func(a, b string) int {
+		return int(hugetlbSizeInKB(a) - hugetlbSizeInKB(b))
+	})
+
+	// Use tabwriter for aligned output
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	header := []string{"HIERARCHY", "MEMS", "MEM MAX", "MEM CURRENT", "MEM PEAK"}
+	for _, size := range sizes {
+		header = append(header, fmt.Sprintf("HUGETLB %s (LIMIT/RSVD/CURRENT/FAILURES)", size), fmt.Sprintf("HUGETLB %s BY NUMA", size))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	seps := make([]string, 0, len(header))
+	for _, col := range header {
+		seps = append(seps, strings.Repeat("-", len(col)))
+	}
+	fmt.Fprintln(w, strings.Join(seps, "\t"))
+
+	for _, rec := range records {
+		indent := strings.Repeat("  ", rec.Depth)
+		// If files don't exist (e.g. root vs leaf), values will be "-"
+		row := []string{indent + filepath.Base(rec.Path)}
+		for _, val := range rec.memoryValues() {
+			row = append(row, val.Value)
+		}
+		for _, size := range sizes {
+			idx := slices.IndexFunc(rec.Hugetlb, func(hb HugetlbRecord) bool { return hb.Size == size })
+			if idx == -1 {
+				row = append(row, "-", "-")
+				continue
+			}
+			vals := rec.hugetlbValues(rec.Hugetlb[idx])
+			var items []string
+			for _, val := range vals[:len(vals)-1] {
+				items = append(items, val.Value)
+			}
+			row = append(row, strings.Join(items, "/"), vals[len(vals)-1].Value)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func (rec *Record) tableValue(val string) string {
+	if val != "" {
+		return val
+	}
+	if len(rec.Errors) > 0 {
+		return "?"
+	}
+	return "-"
+}
+
+func (rec *Record) tableEvents(events map[string]int64) string {
+	if events == nil {
+		return rec.tableValue("")
+	}
+	// Return just the number "max 5" -> "5"
+	return strconv.FormatInt(events["max"], 10)
+}
+
+// tableNUMAStat shows the hierarchical usage, which is the usage of the pod on the pod cgroups,
+// like "N0=0 N1=2097152".
+func (rec *Record) tableNUMAStat(stat *NUMAStat) string {
+	if stat == nil || len(stat.Hierarchical) == 0 {
+		return rec.tableValue("")
+	}
+	var items []string
+	for _, node := range slices.Sorted(maps.Keys(stat.Hierarchical)) {
+		items = append(items, fmt.Sprintf("N%d=%d", node, stat.Hierarchical[node]))
 	}
-	return "0"
+	return strings.Join(items, " ")
 }
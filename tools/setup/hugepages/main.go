@@ -17,38 +17,163 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"io"
 	"log"
 	"os"
+	"slices"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
 	ghwopt "github.com/jaypipes/ghw/pkg/option"
 	ghwtopology "github.com/jaypipes/ghw/pkg/topology"
 
+	"github.com/ffromani/dra-driver-memory/pkg/adminapi"
+	"github.com/ffromani/dra-driver-memory/pkg/debugapi"
 	"github.com/ffromani/dra-driver-memory/pkg/hugepages/provision"
 )
 
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Report is the outcome of the provisioning, emitted on stdout with the json output
+// to let the harness running the tool, like an init container, export it.
+type Report struct {
+	Results []ConfigResult `json:"results"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// ConfigResult is the outcome of the provisioning of a configuration file.
+type ConfigResult struct {
+	Source string `json:"source"`
+	provision.Result
+	Error string `json:"error,omitempty"`
+}
+
 func main() {
 	var sysRoot string = "/"
+	var output string = OutputText
+	var debugSocket string
+	var adminSocket string
 	setupLogger := stdr.New(log.New(os.Stderr, "", log.Lshortfile))
 	flag.StringVar(&sysRoot, "sysfs-root", sysRoot, "root point where sysfs is mounted.")
+	flag.StringVar(&output, "output", output, "output format: text (logs only), json (report on stdout the requested and achieved pages of each NUMA node, and the errors).")
+	flag.StringVar(&debugSocket, "driver-debug-socket", debugSocket, "debug socket of the running driver, to refuse to shrink the pools below the hugepages allocated to its claims. Set empty to check only the hugepages in use.")
+	flag.StringVar(&adminSocket, "driver-admin-socket", adminSocket, "admin socket of the running driver, to provision through it: the driver checks its claims, writes the pools and publishes the new pools at once, without restarting. Set empty to provision from this process.")
 	flag.Parse()
 
+	if output != OutputText && output != OutputJSON {
+		setupLogger.Error(nil, "unsupported output format", "output", output)
+		os.Exit(1)
+	}
+	report := Report{}
+	if adminSocket != "" {
+		os.Exit(provisionViaDriver(setupLogger, adminSocket, flag.Args(), &report, output == OutputJSON))
+	}
+	os.Exit(provisionAll(setupLogger, sysRoot, debugSocket, flag.Args(), &report, output == OutputJSON))
+}
+
+// provisionAll provisions the configurations in `sources`, stopping at the first failure,
+// and returns the exit code of the program. Emits the report if `emit` is set.
+func provisionAll(setupLogger logr.Logger, sysRoot, debugSocket string, sources []string, report *Report, emit bool) (code int) {
+	if emit {
+		defer func() {
+			if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+				setupLogger.Error(err, "cannot emit the report")
+				code = 8
+			}
+		}()
+	}
+
 	sysinfo, err := ghwtopology.New(ghwopt.WithChroot(sysRoot))
 	if err != nil {
 		setupLogger.Error(err, "cannot discover machine topology")
-		os.Exit(1)
+		report.Error = err.Error()
+		return 1
 	}
-	for _, arg := range flag.Args() {
+	var claimed provision.ClaimedPages
+	if debugSocket != "" {
+		data, err := debugapi.GetClaims(context.Background(), debugSocket)
+		if err != nil {
+			setupLogger.Error(err, "cannot get the claims of the driver")
+			report.Error = err.Error()
+			return 1
+		}
+		claimed = provision.ClaimedPagesFromDebug(data)
+	}
+	for _, arg := range sources {
+		cfgRes := ConfigResult{Source: arg}
 		config, err := provision.ReadConfiguration(arg)
 		if err != nil {
 			setupLogger.Error(err, "cannot read hugepages configuration", "path", arg)
-			os.Exit(2)
+			cfgRes.Error = err.Error()
+			report.Results = append(report.Results, cfgRes)
+			return 2
 		}
-		err = provision.RuntimeHugepages(setupLogger, config, sysRoot, len(sysinfo.Nodes))
+		cfgRes.Result, err = provision.RuntimeHugepagesWithResult(setupLogger, config, sysRoot, len(sysinfo.Nodes), claimed)
 		if err != nil {
 			setupLogger.Error(err, "cannot provision hugepages")
-			os.Exit(4)
+			cfgRes.Error = err.Error()
+			report.Results = append(report.Results, cfgRes)
+			if errors.Is(err, provision.ErrConflict) {
+				return 16
+			}
+			return 4
+		}
+		report.Results = append(report.Results, cfgRes)
+	}
+	return 0
+}
+
+// provisionViaDriver asks the driver to provision the configurations in `sources`, like provisionAll,
+// so the driver publishes the new pools at once. The driver knows its claims, so they are always checked.
+func provisionViaDriver(setupLogger logr.Logger, adminSocket string, sources []string, report *Report, emit bool) (code int) {
+	if emit {
+		defer func() {
+			if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+				setupLogger.Error(err, "cannot emit the report")
+				code = 8
+			}
+		}()
+	}
+
+	for _, arg := range sources {
+		cfgRes := ConfigResult{Source: arg}
+		config, err := readSource(arg)
+		if err == nil {
+			// fail early on the malformed configurations, like provisioning locally
+			_, err = provision.ReadConfigurationFrom(bytes.NewReader(config))
 		}
+		if err != nil {
+			setupLogger.Error(err, "cannot read hugepages configuration", "path", arg)
+			cfgRes.Error = err.Error()
+			report.Results = append(report.Results, cfgRes)
+			return 2
+		}
+		err = adminapi.Provision(context.Background(), adminSocket, config, &cfgRes.Result)
+		if err != nil {
+			setupLogger.Error(err, "cannot provision hugepages through the driver")
+			cfgRes.Error = err.Error()
+			report.Results = append(report.Results, cfgRes)
+			if slices.ContainsFunc(cfgRes.Nodes, func(nodeRes provision.NodeResult) bool { return nodeRes.Conflict }) {
+				return 16
+			}
+			return 4
+		}
+		report.Results = append(report.Results, cfgRes)
+	}
+	return 0
+}
+
+func readSource(source string) ([]byte, error) {
+	if source == "-" {
+		return io.ReadAll(os.Stdin)
 	}
+	return os.ReadFile(source)
 }